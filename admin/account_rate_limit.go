@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const (
+	// EndpointAccountRateLimit is the path pattern of the account rate limit status endpoint.
+	// Example: curl -H "Authorization: Bearer $ADMIN_TOKEN" "localhost:9090/admin/account/<account id>/ratelimit"
+	EndpointAccountRateLimit = "/admin/account/{" + pathValueAccountID + "}/ratelimit"
+
+	pathValueAccountID = "id"
+)
+
+// accountRateLimitStatusGetter returns the current rate limit state of an account.
+type accountRateLimitStatusGetter interface {
+	GetAccountRateLimitStatus(accountID store.AccountID) (ratelimit.AccountRateLimitStatus, bool)
+}
+
+// AccountRateLimitHandler serves the current rate limit state of an account as JSON, for debugging
+// (e.g. "why is this account rate limited").
+//   - Requests must provide the admin token as "Authorization: Bearer <token>"
+//   - Must be served on the EndpointAccountRateLimit pattern, which provides the account ID
+//   - Accounts with no portal app in the store get a 404
+type AccountRateLimitHandler struct {
+	logger polylog.Logger
+	getter accountRateLimitStatusGetter
+	token  string
+}
+
+// NewAccountRateLimitHandler creates a handler serving the account rate limit states of the getter, protected by the admin token.
+func NewAccountRateLimitHandler(logger polylog.Logger, getter accountRateLimitStatusGetter, token string) *AccountRateLimitHandler {
+	return &AccountRateLimitHandler{
+		logger: logger.With("component", "account_rate_limit"),
+		getter: getter,
+		token:  token,
+	}
+}
+
+// accountRateLimitResponse is the rate limit state of an account.
+type accountRateLimitResponse struct {
+	AccountID store.AccountID `json:"account_id"`
+	PlanType  store.PlanType  `json:"plan_type"`
+	// RateLimit is the account's monthly relay limit; 0 if the account is not rate limited by usage.
+	RateLimit int32 `json:"rate_limit"`
+	// Usage is the account's month-to-date usage as of the last rate limit update.
+	Usage       int64              `json:"usage"`
+	Decision    ratelimit.Decision `json:"decision"`
+	RateLimited bool               `json:"rate_limited"`
+}
+
+// ServeHTTP serves the rate limit state of the account of the request path.
+func (h *AccountRateLimitHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !hasBearerToken(r, h.token) {
+		h.logger.Warn().Str("remote_addr", r.RemoteAddr).Msg("🔒 Rejected account rate limit request with a missing or invalid token")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountID := store.AccountID(r.PathValue(pathValueAccountID))
+	if accountID == "" {
+		http.Error(w, "missing account ID", http.StatusBadRequest)
+		return
+	}
+
+	status, ok := h.getter.GetAccountRateLimitStatus(accountID)
+	if !ok {
+		http.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	resp := accountRateLimitResponse{
+		AccountID:   accountID,
+		PlanType:    status.PlanType,
+		RateLimit:   status.RateLimit,
+		Usage:       status.Usage,
+		Decision:    status.Decision,
+		RateLimited: status.Decision == ratelimit.DecisionBlock,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode account rate limit response")
+	}
+}
@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const testAccountRateLimitToken = "test_account_rate_limit_token"
+
+// fakeAccountRateLimitStatusGetter returns fixed account rate limit states.
+type fakeAccountRateLimitStatusGetter map[store.AccountID]ratelimit.AccountRateLimitStatus
+
+func (f fakeAccountRateLimitStatusGetter) GetAccountRateLimitStatus(accountID store.AccountID) (ratelimit.AccountRateLimitStatus, bool) {
+	status, ok := f[accountID]
+	return status, ok
+}
+
+// getAccountRateLimit calls the account rate limit handler through a mux serving its path pattern, returning the response recorder.
+func getAccountRateLimit(method, accountID, token string) *httptest.ResponseRecorder {
+	getter := fakeAccountRateLimitStatusGetter{
+		"account_limited": {
+			PlanType:  "PLAN_FREE",
+			RateLimit: 1_000_000,
+			Usage:     1_500_000,
+			Decision:  ratelimit.DecisionBlock,
+		},
+		"account_unlimited": {
+			PlanType: "PLAN_UNLIMITED",
+			Usage:    25_000_000,
+			Decision: ratelimit.DecisionOK,
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(EndpointAccountRateLimit, NewAccountRateLimitHandler(polyzero.NewLogger(), getter, testAccountRateLimitToken))
+
+	req := httptest.NewRequest(method, "/admin/account/"+accountID+"/ratelimit", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_AccountRateLimit(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		accountID        string
+		token            string
+		expectedStatus   int
+		expectedResponse *accountRateLimitResponse
+	}{
+		{
+			name:           "should return the rate limit state of a limited account",
+			method:         http.MethodGet,
+			accountID:      "account_limited",
+			token:          testAccountRateLimitToken,
+			expectedStatus: http.StatusOK,
+			expectedResponse: &accountRateLimitResponse{
+				AccountID:   "account_limited",
+				PlanType:    "PLAN_FREE",
+				RateLimit:   1_000_000,
+				Usage:       1_500_000,
+				Decision:    ratelimit.DecisionBlock,
+				RateLimited: true,
+			},
+		},
+		{
+			name:           "should return the rate limit state of an unlimited account",
+			method:         http.MethodGet,
+			accountID:      "account_unlimited",
+			token:          testAccountRateLimitToken,
+			expectedStatus: http.StatusOK,
+			expectedResponse: &accountRateLimitResponse{
+				AccountID: "account_unlimited",
+				PlanType:  "PLAN_UNLIMITED",
+				Usage:     25_000_000,
+				Decision:  ratelimit.DecisionOK,
+			},
+		},
+		{
+			name:           "should return not found for an unknown account",
+			method:         http.MethodGet,
+			accountID:      "account_unknown",
+			token:          testAccountRateLimitToken,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "should reject a request without a token",
+			method:         http.MethodGet,
+			accountID:      "account_limited",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "should reject a request with an invalid token",
+			method:         http.MethodGet,
+			accountID:      "account_limited",
+			token:          "invalid_token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "should reject a non-GET request",
+			method:         http.MethodPost,
+			accountID:      "account_limited",
+			token:          testAccountRateLimitToken,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			rec := getAccountRateLimit(test.method, test.accountID, test.token)
+			c.Equal(test.expectedStatus, rec.Code)
+			if test.expectedResponse == nil {
+				return
+			}
+
+			c.Equal("application/json", rec.Header().Get("Content-Type"))
+			var resp accountRateLimitResponse
+			c.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+			c.Equal(*test.expectedResponse, resp)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// adminProtoFile is the name of the proto file describing the admin service.
+const adminProtoFile = "peas/admin/v1/admin.proto"
+
+// init registers the admin service's file descriptor, so the SelfTest RPC
+// can be described and called using gRPC reflection (e.g. with grpcurl).
+//
+// Equivalent proto definition:
+//
+//	syntax = "proto3";
+//	package peas.admin.v1;
+//	service Admin {
+//	  rpc SelfTest(google.protobuf.Empty) returns (google.protobuf.Struct);
+//	}
+func init() {
+	fileDescriptor, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String(adminProtoFile),
+		Package: proto.String("peas.admin.v1"),
+		Syntax:  proto.String("proto3"),
+		Dependency: []string{
+			emptypb.File_google_protobuf_empty_proto.Path(),
+			structpb.File_google_protobuf_struct_proto.Path(),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("Admin"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String(selfTestMethodName),
+						InputType:  proto.String(".google.protobuf.Empty"),
+						OutputType: proto.String(".google.protobuf.Struct"),
+					},
+				},
+			},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := protoregistry.GlobalFiles.RegisterFile(fileDescriptor); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,210 @@
+// The admin package implements admin/debug services for PEAS.
+// Responsibilities:
+// - Smoke testing deployments by running a synthetic Check against a known test portal app
+// - Dumping the portal app store contents, with secrets redacted, over HTTP
+package admin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// The gRPC service and method names of the self-test RPC.
+	// Example: grpcurl -plaintext -H "authorization: Bearer $SELF_TEST_TOKEN" localhost:10001 peas.admin.v1.Admin/SelfTest
+	adminServiceName   = "peas.admin.v1.Admin"
+	selfTestMethodName = "SelfTest"
+
+	// The headers set on the synthetic Check request.
+	// These must match the headers read by the auth package.
+	reqHeaderAuthorization = "Authorization"
+
+	// The gRPC metadata key of the SelfTest caller's token.
+	metadataKeyAuthorization = "authorization"
+
+	// The decisions returned by SelfTest.
+	decisionAuthorized = "authorized"
+	decisionDenied     = "denied"
+)
+
+// checker runs an Envoy External Authorization check.
+//
+// Used for:
+//   - Running the synthetic Check against the auth handler.
+type checker interface {
+	Check(ctx context.Context, checkReq *envoy_auth.CheckRequest) (*envoy_auth.CheckResponse, error)
+}
+
+// adminServer is the server API of the admin service.
+type adminServer interface {
+	SelfTest(ctx context.Context, in *emptypb.Empty) (*structpb.Struct, error)
+}
+
+// SelfTestServer implements the SelfTest RPC.
+//
+// Primary responsibilities:
+//   - Runs a synthetic Check for the configured test portal app
+//   - Returns the decision and latency, verifying the store lookup, auth and rate limit path
+//   - Rejects callers not providing the token, since it is served on the public ext_authz gRPC server
+type SelfTestServer struct {
+	logger polylog.Logger
+
+	// Checker: the auth handler used for the synthetic Check
+	checker checker
+
+	// PortalAppID: the known test portal app to check
+	portalAppID string
+
+	// PathPrefix: the path prefix preceding the portal app ID in the synthetic Check's path (e.g. "/v1/")
+	pathPrefix string
+
+	// APIKey: optional API key of the test portal app, for portal apps requiring API key authorization
+	apiKey string
+
+	// Token: the token callers must provide as "authorization: Bearer <token>" metadata
+	token string
+}
+
+// NewSelfTestServer returns a SelfTestServer that checks the given test portal app.
+func NewSelfTestServer(
+	logger polylog.Logger,
+	checker checker,
+	portalAppID string,
+	apiKey string,
+	pathPrefix string,
+	token string,
+) *SelfTestServer {
+	return &SelfTestServer{
+		logger:      logger,
+		checker:     checker,
+		portalAppID: portalAppID,
+		pathPrefix:  pathPrefix,
+		apiKey:      apiKey,
+		token:       token,
+	}
+}
+
+// SelfTest runs a synthetic Check against the test portal app.
+// Returns a Struct with the following fields:
+//   - portal_app_id: the test portal app ID
+//   - decision: "authorized" or "denied"
+//   - code: the gRPC status code of the Check response (e.g. "OK", "PermissionDenied")
+//   - http_status: the HTTP status code of a denied response, or 200 if authorized
+//   - message: the status message of the Check response
+//   - latency_ms: the latency of the Check in milliseconds
+//
+// Returns an Unauthenticated error if the caller does not provide the token.
+func (s *SelfTestServer) SelfTest(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	if !s.isAuthorized(ctx) {
+		s.logger.Warn().Msg("🔒 Rejected self-test request with a missing or invalid token")
+		return nil, grpcstatus.Error(codes.Unauthenticated, "missing or invalid self-test token")
+	}
+
+	headers := map[string]string{}
+	if s.apiKey != "" {
+		headers[reqHeaderAuthorization] = s.apiKey
+	}
+
+	checkReq := &envoy_auth.CheckRequest{
+		Attributes: &envoy_auth.AttributeContext{
+			Request: &envoy_auth.AttributeContext_Request{
+				Http: &envoy_auth.AttributeContext_HttpRequest{
+					Path:    s.pathPrefix + s.portalAppID,
+					Headers: headers,
+				},
+			},
+		},
+	}
+
+	startTime := time.Now()
+	checkResp, err := s.checker.Check(ctx, checkReq)
+	latency := time.Since(startTime)
+	if err != nil {
+		s.logger.Error().Err(err).Str("portal_app_id", s.portalAppID).Msg("❌ self-test check failed")
+		return nil, grpcstatus.Errorf(codes.Internal, "self-test check failed: %v", err)
+	}
+
+	decision, httpStatus := decisionAuthorized, 200
+	if code := codes.Code(checkResp.GetStatus().GetCode()); code != codes.OK {
+		decision = decisionDenied
+		httpStatus = int(checkResp.GetDeniedResponse().GetStatus().GetCode())
+	}
+
+	s.logger.Info().
+		Str("portal_app_id", s.portalAppID).
+		Str("decision", decision).
+		Dur("latency", latency).
+		Msg("🩺 self-test check completed")
+
+	return structpb.NewStruct(map[string]any{
+		"portal_app_id": s.portalAppID,
+		"decision":      decision,
+		"code":          codes.Code(checkResp.GetStatus().GetCode()).String(),
+		"http_status":   httpStatus,
+		"message":       checkResp.GetStatus().GetMessage(),
+		"latency_ms":    float64(latency.Microseconds()) / 1000,
+	})
+}
+
+// isAuthorized returns true if the caller provides the token as "authorization: Bearer <token>" metadata.
+func (s *SelfTestServer) isAuthorized(ctx context.Context) bool {
+	for _, authorization := range metadata.ValueFromIncomingContext(ctx, metadataKeyAuthorization) {
+		if isBearerToken(authorization, s.token) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterSelfTestServer registers the SelfTest RPC on the gRPC server.
+func RegisterSelfTestServer(s *grpc.Server, srv *SelfTestServer) {
+	s.RegisterService(&adminServiceDesc, srv)
+}
+
+// adminServiceDesc is the gRPC service descriptor of the admin service.
+// Written by hand, since the service only uses well-known request and response types.
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: adminServiceName,
+	HandlerType: (*adminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: selfTestMethodName,
+			Handler:    selfTestHandler,
+		},
+	},
+	Metadata: adminProtoFile,
+}
+
+// selfTestHandler decodes the SelfTest request and calls the server, applying any interceptor.
+func selfTestHandler(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(adminServer).SelfTest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: fmt.Sprintf("/%s/%s", adminServiceName, selfTestMethodName),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(adminServer).SelfTest(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeChecker returns a fixed Check response and records the last Check request.
+type fakeChecker struct {
+	resp    *envoy_auth.CheckResponse
+	err     error
+	lastReq *envoy_auth.CheckRequest
+}
+
+func (f *fakeChecker) Check(_ context.Context, checkReq *envoy_auth.CheckRequest) (*envoy_auth.CheckResponse, error) {
+	f.lastReq = checkReq
+	return f.resp, f.err
+}
+
+func Test_SelfTest(t *testing.T) {
+	tests := []struct {
+		name             string
+		apiKey           string
+		authorization    string
+		checker          *fakeChecker
+		expectedFields   map[string]any
+		expectedHeaders  map[string]string
+		expectedGRPCCode codes.Code
+	}{
+		{
+			name:          "should return authorized decision for OK check response",
+			authorization: "Bearer self_test_token",
+			checker: &fakeChecker{
+				resp: &envoy_auth.CheckResponse{
+					Status: &status.Status{Code: int32(codes.OK), Message: "ok"},
+				},
+			},
+			expectedFields: map[string]any{
+				"portal_app_id": "portal_app_self_test",
+				"decision":      "authorized",
+				"code":          "OK",
+				"http_status":   float64(200),
+				"message":       "ok",
+			},
+			expectedHeaders:  map[string]string{},
+			expectedGRPCCode: codes.OK,
+		},
+		{
+			name:          "should return denied decision and HTTP status for denied check response",
+			apiKey:        "self_test_api_key",
+			authorization: "Bearer self_test_token",
+			checker: &fakeChecker{
+				resp: &envoy_auth.CheckResponse{
+					Status: &status.Status{Code: int32(codes.PermissionDenied), Message: "account is rate limited"},
+					HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+						DeniedResponse: &envoy_auth.DeniedHttpResponse{
+							Status: &envoy_type.HttpStatus{Code: envoy_type.StatusCode_TooManyRequests},
+						},
+					},
+				},
+			},
+			expectedFields: map[string]any{
+				"portal_app_id": "portal_app_self_test",
+				"decision":      "denied",
+				"code":          "PermissionDenied",
+				"http_status":   float64(429),
+				"message":       "account is rate limited",
+			},
+			expectedHeaders:  map[string]string{reqHeaderAuthorization: "self_test_api_key"},
+			expectedGRPCCode: codes.OK,
+		},
+		{
+			name:          "should return internal error if check fails",
+			authorization: "Bearer self_test_token",
+			checker: &fakeChecker{
+				err: errors.New("unexpected failure"),
+			},
+			expectedGRPCCode: codes.Internal,
+		},
+		{
+			name:             "should return unauthenticated error if the token is missing",
+			checker:          &fakeChecker{},
+			expectedGRPCCode: codes.Unauthenticated,
+		},
+		{
+			name:             "should return unauthenticated error if the token is invalid",
+			authorization:    "Bearer wrong_token",
+			checker:          &fakeChecker{},
+			expectedGRPCCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			conn := startInProcessServer(t, NewSelfTestServer(
+				polyzero.NewLogger(), test.checker, "portal_app_self_test", test.apiKey, "/v1/", "self_test_token",
+			))
+
+			ctx := context.Background()
+			if test.authorization != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", test.authorization)
+			}
+
+			resp := new(structpb.Struct)
+			err := conn.Invoke(ctx, "/peas.admin.v1.Admin/SelfTest", &emptypb.Empty{}, resp)
+			c.Equal(test.expectedGRPCCode, grpcstatus.Code(err))
+			if test.expectedGRPCCode == codes.Unauthenticated {
+				// Unauthenticated callers must not trigger the synthetic check
+				c.Nil(test.checker.lastReq)
+				return
+			}
+			if test.expectedGRPCCode != codes.OK {
+				return
+			}
+
+			// The synthetic check must target the test portal app
+			httpReq := test.checker.lastReq.GetAttributes().GetRequest().GetHttp()
+			c.Equal("/v1/portal_app_self_test", httpReq.GetPath())
+			c.Equal(test.expectedHeaders, httpReq.GetHeaders())
+
+			fields := resp.AsMap()
+			c.Contains(fields, "latency_ms")
+			delete(fields, "latency_ms")
+			c.Equal(test.expectedFields, fields)
+		})
+	}
+}
+
+// startInProcessServer serves the SelfTest RPC over an in-memory connection.
+func startInProcessServer(t *testing.T, srv *SelfTestServer) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+
+	grpcServer := grpc.NewServer()
+	RegisterSelfTestServer(grpcServer, srv)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
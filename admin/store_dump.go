@@ -0,0 +1,230 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const (
+	// EndpointStoreDump is the path of the store dump endpoint.
+	// Example: curl -H "Authorization: Bearer $ADMIN_TOKEN" "localhost:9090/store/dump?limit=10"
+	EndpointStoreDump = "/store/dump"
+
+	// The query parameters of the store dump endpoint.
+	//   - limit: maximum number of portal apps per page
+	//   - after: portal app ID to start after, from the next_after field of the previous page
+	//   - portal_app_id: only return the portal app with this ID
+	queryParamLimit       = "limit"
+	queryParamAfter       = "after"
+	queryParamPortalAppID = "portal_app_id"
+
+	defaultStoreDumpLimit = 100
+	maxStoreDumpLimit     = 1000
+
+	bearerPrefix = "Bearer "
+)
+
+// portalAppLister lists the portal apps in the store, sorted by ID.
+type portalAppLister interface {
+	ListPortalApps() []*store.PortalApp
+}
+
+// StoreDumpHandler serves the current contents of the portal app store as JSON, for debugging
+// (e.g. "is this portal app loaded and what is its config").
+//   - Requests must provide the admin token as "Authorization: Bearer <token>"
+//   - Secrets are never included: API keys and HMAC secrets are reported as counts and flags
+//   - Portal apps are paginated by ID, so pages stay consistent across store refreshes
+type StoreDumpHandler struct {
+	logger polylog.Logger
+	lister portalAppLister
+	token  string
+}
+
+// NewStoreDumpHandler creates a handler serving the portal apps of the lister, protected by the admin token.
+func NewStoreDumpHandler(logger polylog.Logger, lister portalAppLister, token string) *StoreDumpHandler {
+	return &StoreDumpHandler{
+		logger: logger.With("component", "store_dump"),
+		lister: lister,
+		token:  token,
+	}
+}
+
+// storeDumpResponse is a page of the store dump.
+type storeDumpResponse struct {
+	// Total is the number of portal apps in the store, or matching the portal_app_id filter.
+	Total      int               `json:"total"`
+	PortalApps []portalAppDump   `json:"portal_apps"`
+	NextAfter  store.PortalAppID `json:"next_after,omitempty"`
+}
+
+// portalAppDump is the redacted representation of a portal app.
+type portalAppDump struct {
+	ID            store.PortalAppID   `json:"id"`
+	AccountID     store.AccountID     `json:"account_id"`
+	PlanType      store.PlanType      `json:"plan_type"`
+	PlanName      string              `json:"plan_name,omitempty"`
+	BillingStatus store.BillingStatus `json:"billing_status,omitempty"`
+	Auth          *authDump           `json:"auth,omitempty"`
+	AccountAuth   *authDump           `json:"account_auth,omitempty"`
+	AllowedCIDRs  []string            `json:"allowed_cidrs,omitempty"`
+	RateLimit     *rateLimitDump      `json:"rate_limit,omitempty"`
+	AuthCacheTTL  string              `json:"auth_cache_ttl,omitempty"`
+}
+
+// authDump is the redacted representation of a portal app's authorization settings.
+type authDump struct {
+	APIKeyCount   int  `json:"api_key_count"`
+	HMACSecretSet bool `json:"hmac_secret_set"`
+}
+
+// rateLimitDump is the representation of a portal app's rate limit settings.
+type rateLimitDump struct {
+	MonthlyUserLimit      int32 `json:"monthly_user_limit"`
+	DailyUserLimit        int32 `json:"daily_user_limit"`
+	FreeMonthlyRelayBonus int32 `json:"free_monthly_relay_bonus"`
+	BurstAllowance        int32 `json:"burst_allowance"`
+	PortalAppMonthlyLimit int32 `json:"portal_app_monthly_limit"`
+}
+
+// ServeHTTP serves a page of the store dump.
+func (h *StoreDumpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.isAuthorized(r) {
+		h.logger.Warn().Str("remote_addr", r.RemoteAddr).Msg("🔒 Rejected store dump request with a missing or invalid token")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultStoreDumpLimit
+	if limitStr := query.Get(queryParamLimit); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 || l > maxStoreDumpLimit {
+			http.Error(w, "invalid limit: must be between 1 and "+strconv.Itoa(maxStoreDumpLimit), http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	resp := getStoreDumpPage(
+		h.lister.ListPortalApps(),
+		store.PortalAppID(query.Get(queryParamPortalAppID)),
+		store.PortalAppID(query.Get(queryParamAfter)),
+		limit,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to encode store dump response")
+	}
+}
+
+// isAuthorized returns true if the request provides the admin token.
+func (h *StoreDumpHandler) isAuthorized(r *http.Request) bool {
+	return hasBearerToken(r, h.token)
+}
+
+// hasBearerToken returns true if the request provides the token as "Authorization: Bearer <token>".
+//   - Always returns false if the token is empty
+//   - The token is compared in constant time, so it cannot be guessed from response timings
+func hasBearerToken(r *http.Request, token string) bool {
+	return isBearerToken(r.Header.Get("Authorization"), token)
+}
+
+// isBearerToken returns true if the authorization value is "Bearer <token>".
+//   - Always returns false if the token is empty
+//   - The token is compared in constant time, so it cannot be guessed from response timings
+func isBearerToken(authorization string, token string) bool {
+	requestToken, ok := strings.CutPrefix(authorization, bearerPrefix)
+	if !ok || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(requestToken), []byte(token)) == 1
+}
+
+// getStoreDumpPage returns the page of up to limit portal apps with an ID after the given ID.
+//   - portalApps must be sorted by ID
+//   - If portalAppID is set, only the portal app with that ID is returned
+func getStoreDumpPage(portalApps []*store.PortalApp, portalAppID, after store.PortalAppID, limit int) storeDumpResponse {
+	if portalAppID != "" {
+		portalApps = filterPortalApp(portalApps, portalAppID)
+	}
+
+	resp := storeDumpResponse{
+		Total:      len(portalApps),
+		PortalApps: []portalAppDump{},
+	}
+
+	start := sort.Search(len(portalApps), func(i int) bool {
+		return portalApps[i].ID > after
+	})
+	end := min(start+limit, len(portalApps))
+
+	for _, portalApp := range portalApps[start:end] {
+		resp.PortalApps = append(resp.PortalApps, newPortalAppDump(portalApp))
+	}
+	if end < len(portalApps) {
+		resp.NextAfter = portalApps[end-1].ID
+	}
+	return resp
+}
+
+// filterPortalApp returns the portal app with the given ID, if any.
+func filterPortalApp(portalApps []*store.PortalApp, portalAppID store.PortalAppID) []*store.PortalApp {
+	for _, portalApp := range portalApps {
+		if portalApp.ID == portalAppID {
+			return []*store.PortalApp{portalApp}
+		}
+	}
+	return nil
+}
+
+// newPortalAppDump returns the redacted representation of the portal app.
+func newPortalAppDump(portalApp *store.PortalApp) portalAppDump {
+	dump := portalAppDump{
+		ID:            portalApp.ID,
+		AccountID:     portalApp.AccountID,
+		PlanType:      portalApp.PlanType,
+		PlanName:      portalApp.PlanName,
+		BillingStatus: portalApp.BillingStatus,
+		Auth:          newAuthDump(portalApp.Auth),
+		AccountAuth:   newAuthDump(portalApp.AccountAuth),
+		AllowedCIDRs:  portalApp.AllowedCIDRs,
+	}
+	if rateLimit := portalApp.RateLimit; rateLimit != nil {
+		dump.RateLimit = &rateLimitDump{
+			MonthlyUserLimit:      rateLimit.MonthlyUserLimit,
+			DailyUserLimit:        rateLimit.DailyUserLimit,
+			FreeMonthlyRelayBonus: rateLimit.FreeMonthlyRelayBonus,
+			BurstAllowance:        rateLimit.BurstAllowance,
+			PortalAppMonthlyLimit: rateLimit.PortalAppMonthlyLimit,
+		}
+	}
+	if portalApp.AuthCacheTTL != nil {
+		dump.AuthCacheTTL = portalApp.AuthCacheTTL.String()
+	}
+	return dump
+}
+
+// newAuthDump returns the redacted representation of the authorization settings, or nil if there are none.
+func newAuthDump(auth *store.Auth) *authDump {
+	if auth == nil {
+		return nil
+	}
+	return &authDump{
+		APIKeyCount:   len(auth.APIKeys),
+		HMACSecretSet: auth.HMACSecret != "",
+	}
+}
@@ -0,0 +1,311 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const (
+	testStoreDumpToken = "test_admin_token"
+
+	// leakMarker prefixes every secret of the test portal apps, so a leaked secret is detected in any response
+	leakMarker = "do_not_leak"
+)
+
+// fakePortalAppLister returns a fixed list of portal apps.
+type fakePortalAppLister []*store.PortalApp
+
+func (f fakePortalAppLister) ListPortalApps() []*store.PortalApp {
+	return f
+}
+
+// newTestPortalApps returns n portal apps sorted by ID, each with an API key and an HMAC secret.
+func newTestPortalApps(n int) fakePortalAppLister {
+	portalApps := make(fakePortalAppLister, n)
+	for i := range portalApps {
+		portalApps[i] = &store.PortalApp{
+			ID:        store.PortalAppID(fmt.Sprintf("portal_app_%d", i)),
+			AccountID: store.AccountID(fmt.Sprintf("account_%d", i)),
+			PlanType:  "PLAN_FREE",
+			Auth: &store.Auth{
+				APIKeys:    []string{fmt.Sprintf("do_not_leak_api_key_%d", i), fmt.Sprintf("do_not_leak_rotated_api_key_%d", i)},
+				HMACSecret: fmt.Sprintf("do_not_leak_hmac_secret_%d", i),
+			},
+			AccountAuth: &store.Auth{APIKeys: []string{fmt.Sprintf("do_not_leak_account_api_key_%d", i)}},
+		}
+	}
+	return portalApps
+}
+
+// getStoreDump calls the store dump handler, returning the response recorder.
+func getStoreDump(lister portalAppLister, method, query, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, EndpointStoreDump+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	NewStoreDumpHandler(polyzero.NewLogger(), lister, testStoreDumpToken).ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_StoreDump_RedactsSecrets(t *testing.T) {
+	c := require.New(t)
+
+	authCacheTTL := 5 * time.Minute
+	portalApps := newTestPortalApps(3)
+	portalApps[1].Auth = nil
+	portalApps[2].RateLimit = &store.RateLimit{MonthlyUserLimit: 1000}
+	portalApps[2].AllowedCIDRs = []string{"203.0.113.0/24"}
+	portalApps[2].AuthCacheTTL = &authCacheTTL
+
+	rec := getStoreDump(portalApps, http.MethodGet, "", testStoreDumpToken)
+	c.Equal(http.StatusOK, rec.Code)
+	c.Equal("application/json", rec.Header().Get("Content-Type"))
+
+	// No API key or HMAC secret is in the response
+	c.NotContains(rec.Body.String(), leakMarker)
+
+	var resp storeDumpResponse
+	c.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+	c.Equal(3, resp.Total)
+	c.Equal([]portalAppDump{
+		{
+			ID:          "portal_app_0",
+			AccountID:   "account_0",
+			PlanType:    "PLAN_FREE",
+			Auth:        &authDump{APIKeyCount: 2, HMACSecretSet: true},
+			AccountAuth: &authDump{APIKeyCount: 1},
+		},
+		{
+			ID:          "portal_app_1",
+			AccountID:   "account_1",
+			PlanType:    "PLAN_FREE",
+			AccountAuth: &authDump{APIKeyCount: 1},
+		},
+		{
+			ID:           "portal_app_2",
+			AccountID:    "account_2",
+			PlanType:     "PLAN_FREE",
+			Auth:         &authDump{APIKeyCount: 2, HMACSecretSet: true},
+			AccountAuth:  &authDump{APIKeyCount: 1},
+			AllowedCIDRs: []string{"203.0.113.0/24"},
+			RateLimit:    &rateLimitDump{MonthlyUserLimit: 1000},
+			AuthCacheTTL: "5m0s",
+		},
+	}, resp.PortalApps)
+	c.Empty(resp.NextAfter)
+
+	// The store's portal apps are not modified
+	c.Equal("do_not_leak_api_key_0", portalApps[0].Auth.APIKey())
+}
+
+func Test_StoreDump_Pagination(t *testing.T) {
+	tests := []struct {
+		name              string
+		portalApps        int
+		query             string
+		expectedIDs       []store.PortalAppID
+		expectedTotal     int
+		expectedNextAfter store.PortalAppID
+	}{
+		{
+			name:              "should return the first page",
+			portalApps:        5,
+			query:             "?limit=2",
+			expectedIDs:       []store.PortalAppID{"portal_app_0", "portal_app_1"},
+			expectedTotal:     5,
+			expectedNextAfter: "portal_app_1",
+		},
+		{
+			name:              "should return the page after the given portal app ID",
+			portalApps:        5,
+			query:             "?limit=2&after=portal_app_1",
+			expectedIDs:       []store.PortalAppID{"portal_app_2", "portal_app_3"},
+			expectedTotal:     5,
+			expectedNextAfter: "portal_app_3",
+		},
+		{
+			name:          "should return the last page without a next page",
+			portalApps:    5,
+			query:         "?limit=2&after=portal_app_3",
+			expectedIDs:   []store.PortalAppID{"portal_app_4"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "should return a page after a portal app ID no longer in the store",
+			portalApps:    5,
+			query:         "?limit=2&after=portal_app_3_deleted",
+			expectedIDs:   []store.PortalAppID{"portal_app_4"},
+			expectedTotal: 5,
+		},
+		{
+			name:          "should return an empty page after the last portal app",
+			portalApps:    5,
+			query:         "?after=portal_app_4",
+			expectedIDs:   []store.PortalAppID{},
+			expectedTotal: 5,
+		},
+		{
+			name:              "should return the default number of portal apps per page",
+			portalApps:        defaultStoreDumpLimit + 1,
+			expectedTotal:     defaultStoreDumpLimit + 1,
+			expectedNextAfter: store.PortalAppID(fmt.Sprintf("portal_app_%d", defaultStoreDumpLimit-1)),
+		},
+		{
+			name:          "should only return the portal app with the given ID",
+			portalApps:    5,
+			query:         "?portal_app_id=portal_app_3",
+			expectedIDs:   []store.PortalAppID{"portal_app_3"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "should return no portal apps if the given ID is not in the store",
+			portalApps:    5,
+			query:         "?portal_app_id=portal_app_missing",
+			expectedIDs:   []store.PortalAppID{},
+			expectedTotal: 0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			rec := getStoreDump(newTestPortalApps(test.portalApps), http.MethodGet, test.query, testStoreDumpToken)
+			c.Equal(http.StatusOK, rec.Code)
+
+			var resp storeDumpResponse
+			c.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+			c.Equal(test.expectedTotal, resp.Total)
+			c.Equal(test.expectedNextAfter, resp.NextAfter)
+
+			gotIDs := make([]store.PortalAppID, len(resp.PortalApps))
+			for i, portalApp := range resp.PortalApps {
+				gotIDs[i] = portalApp.ID
+			}
+			if test.expectedIDs != nil {
+				c.Equal(test.expectedIDs, gotIDs)
+			} else {
+				c.Len(gotIDs, defaultStoreDumpLimit)
+			}
+		})
+	}
+}
+
+func Test_StoreDump_Pagination_AllPages(t *testing.T) {
+	c := require.New(t)
+
+	portalApps := newTestPortalApps(7)
+
+	// Following next_after visits every portal app exactly once
+	var gotIDs []store.PortalAppID
+	after := store.PortalAppID("")
+	for page := 0; ; page++ {
+		c.Less(page, len(portalApps), "pagination did not terminate")
+
+		rec := getStoreDump(portalApps, http.MethodGet, "?limit=3&after="+string(after), testStoreDumpToken)
+		c.Equal(http.StatusOK, rec.Code)
+
+		var resp storeDumpResponse
+		c.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+		for _, portalApp := range resp.PortalApps {
+			gotIDs = append(gotIDs, portalApp.ID)
+		}
+		if resp.NextAfter == "" {
+			break
+		}
+		after = resp.NextAfter
+	}
+
+	c.Len(gotIDs, len(portalApps))
+	for i, portalApp := range portalApps {
+		c.Equal(portalApp.ID, gotIDs[i])
+	}
+}
+
+func Test_StoreDump_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		query        string
+		token        string
+		expectedCode int
+	}{
+		{
+			name:         "should reject a request without a token",
+			method:       http.MethodGet,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject a request with an invalid token",
+			method:       http.MethodGet,
+			token:        "invalid_token",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject a request with a prefix of the token",
+			method:       http.MethodGet,
+			token:        testStoreDumpToken[:4],
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "should reject a non-GET request",
+			method:       http.MethodPost,
+			token:        testStoreDumpToken,
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "should reject a non-numeric limit",
+			method:       http.MethodGet,
+			query:        "?limit=all",
+			token:        testStoreDumpToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should reject a limit of 0",
+			method:       http.MethodGet,
+			query:        "?limit=0",
+			token:        testStoreDumpToken,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should reject a limit over the maximum",
+			method:       http.MethodGet,
+			query:        fmt.Sprintf("?limit=%d", maxStoreDumpLimit+1),
+			token:        testStoreDumpToken,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			rec := getStoreDump(newTestPortalApps(3), test.method, test.query, test.token)
+			c.Equal(test.expectedCode, rec.Code)
+			c.NotContains(rec.Body.String(), leakMarker)
+			c.False(strings.HasPrefix(rec.Body.String(), "{"))
+		})
+	}
+}
+
+func Test_StoreDump_EmptyToken(t *testing.T) {
+	c := require.New(t)
+
+	// A handler without a token rejects every request, including ones with an empty bearer token
+	req := httptest.NewRequest(http.MethodGet, EndpointStoreDump, nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	NewStoreDumpHandler(polyzero.NewLogger(), newTestPortalApps(1), "").ServeHTTP(rec, req)
+	c.Equal(http.StatusUnauthorized, rec.Code)
+}
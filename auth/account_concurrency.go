@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// accountConcurrencyExceededMessage is the gRPC status message returned when an account has too many Check requests in flight.
+const accountConcurrencyExceededMessage = "too many concurrent auth checks for account"
+
+// accountConcurrencyLimiter tracks the number of in-flight Check requests per account.
+type accountConcurrencyLimiter struct {
+	maxConcurrent int
+
+	inFlight   map[store.AccountID]int
+	inFlightMu sync.Mutex
+}
+
+func newAccountConcurrencyLimiter(maxConcurrent int) *accountConcurrencyLimiter {
+	return &accountConcurrencyLimiter{
+		maxConcurrent: maxConcurrent,
+		inFlight:      make(map[store.AccountID]int),
+	}
+}
+
+// acquire reserves an in-flight slot for the account.
+//   - Returns false if the account already has the maximum number of requests in flight.
+func (l *accountConcurrencyLimiter) acquire(accountID store.AccountID) bool {
+	l.inFlightMu.Lock()
+	defer l.inFlightMu.Unlock()
+
+	if l.inFlight[accountID] >= l.maxConcurrent {
+		return false
+	}
+	l.inFlight[accountID]++
+	return true
+}
+
+// release frees an in-flight slot reserved by acquire.
+func (l *accountConcurrencyLimiter) release(accountID store.AccountID) {
+	l.inFlightMu.Lock()
+	defer l.inFlightMu.Unlock()
+
+	l.inFlight[accountID]--
+	if l.inFlight[accountID] <= 0 {
+		delete(l.inFlight, accountID)
+	}
+}
+
+// AccountConcurrencyInterceptor returns a gRPC unary interceptor enforcing the per-account cap
+// set by WithMaxConcurrentChecksPerAccount on concurrent Check requests.
+//
+//   - The account is resolved from the request's portal app, the same way as in Check
+//   - Requests over the cap are rejected with a ResourceExhausted gRPC status, without calling Check
+//   - Requests whose account cannot be resolved, and all other RPCs, are passed through
+//   - Passes all requests through if no cap is configured
+func (a *authHandler) AccountConcurrencyInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		checkReq, ok := req.(*envoy_auth.CheckRequest)
+		if !ok || a.accountConcurrency == nil {
+			return handler(ctx, req)
+		}
+
+		startTime := time.Now()
+		portalApp, ok := a.getCheckRequestPortalApp(checkReq)
+		if !ok || portalApp.AccountID == "" {
+			return handler(ctx, req)
+		}
+
+		if !a.accountConcurrency.acquire(portalApp.AccountID) {
+			a.logger.Warn().
+				Str("portal_app_id", string(portalApp.ID)).
+				Str("account_id", string(portalApp.AccountID)).
+				Int("max_concurrent_checks", a.accountConcurrency.maxConcurrent).
+				Msg("🚫 account exceeded concurrent auth check cap: rejecting the request.")
+			metrics.RecordAuthRequest(
+				ctx,
+				string(portalApp.ID),
+				string(portalApp.AccountID),
+				metrics.AuthDecisionDenied,
+				metrics.AuthRequestErrorTypeAccountConcurrencyExceeded,
+				time.Since(startTime).Seconds(),
+			)
+			return nil, grpcstatus.Error(codes.ResourceExhausted, accountConcurrencyExceededMessage)
+		}
+		defer a.accountConcurrency.release(portalApp.AccountID)
+
+		return handler(ctx, req)
+	}
+}
+
+// getCheckRequestPortalApp resolves the PortalApp of a Check request.
+//   - Returns false if the request has no portal app ID or the portal app is not found.
+func (a *authHandler) getCheckRequestPortalApp(checkReq *envoy_auth.CheckRequest) (*store.PortalApp, bool) {
+	req := checkReq.GetAttributes().GetRequest().GetHttp()
+	if req == nil {
+		return nil, false
+	}
+
+	portalAppID, err := a.resolvePortalAppID(convertMapToHeader(req.GetHeaders()), req.GetPath())
+	if err != nil {
+		return nil, false
+	}
+	return a.getPortalApp(portalAppID)
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_accountConcurrencyLimiter(t *testing.T) {
+	c := require.New(t)
+
+	limiter := newAccountConcurrencyLimiter(2)
+
+	// Accounts may have up to the maximum number of requests in flight
+	c.True(limiter.acquire("account_1"))
+	c.True(limiter.acquire("account_1"))
+	c.False(limiter.acquire("account_1"))
+
+	// Other accounts are capped independently
+	c.True(limiter.acquire("account_2"))
+
+	// Releasing a slot allows another request
+	limiter.release("account_1")
+	c.True(limiter.acquire("account_1"))
+
+	// Accounts with no requests in flight are removed
+	limiter.release("account_2")
+	c.NotContains(limiter.inFlight, store.AccountID("account_2"))
+}
+
+func Test_AccountConcurrencyInterceptor(t *testing.T) {
+	portalApps := map[store.PortalAppID]*store.PortalApp{
+		"portal_app_1": {ID: "portal_app_1", AccountID: "account_1"},
+		"portal_app_2": {ID: "portal_app_2", AccountID: "account_1"},
+		"portal_app_3": {ID: "portal_app_3", AccountID: "account_2"},
+	}
+
+	tests := []struct {
+		name          string
+		maxConcurrent int
+		// inFlight are the portal apps of requests in flight when the request is made
+		inFlight     []store.PortalAppID
+		req          any
+		expectedCode codes.Code
+	}{
+		{
+			name:          "should allow request if account is under the cap",
+			maxConcurrent: 2,
+			inFlight:      []store.PortalAppID{"portal_app_1"},
+			req:           newTestCheckRequest("/v1/portal_app_1"),
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "should reject request with ResourceExhausted if account is at the cap",
+			maxConcurrent: 2,
+			inFlight:      []store.PortalAppID{"portal_app_1", "portal_app_1"},
+			req:           newTestCheckRequest("/v1/portal_app_1"),
+			expectedCode:  codes.ResourceExhausted,
+		},
+		{
+			name:          "should apply the cap across all portal apps of the account",
+			maxConcurrent: 1,
+			inFlight:      []store.PortalAppID{"portal_app_1"},
+			req:           newTestCheckRequest("/v1/portal_app_2"),
+			expectedCode:  codes.ResourceExhausted,
+		},
+		{
+			name:          "should allow request if another account is at the cap",
+			maxConcurrent: 1,
+			inFlight:      []store.PortalAppID{"portal_app_1"},
+			req:           newTestCheckRequest("/v1/portal_app_3"),
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "should pass through request if portal app is not found",
+			maxConcurrent: 1,
+			inFlight:      []store.PortalAppID{"portal_app_1"},
+			req:           newTestCheckRequest("/v1/portal_app_unknown"),
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "should pass through request with no portal app ID",
+			maxConcurrent: 1,
+			req:           newTestCheckRequest("/v1/"),
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "should pass through all requests if no cap is configured",
+			maxConcurrent: 0,
+			inFlight:      []store.PortalAppID{"portal_app_1", "portal_app_1"},
+			req:           newTestCheckRequest("/v1/portal_app_1"),
+			expectedCode:  codes.OK,
+		},
+		{
+			name:          "should pass through requests to other RPCs",
+			maxConcurrent: 1,
+			inFlight:      []store.PortalAppID{"portal_app_1"},
+			req:           "not a check request",
+			expectedCode:  codes.OK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(gomock.Any()).DoAndReturn(
+				func(portalAppID store.PortalAppID) (*store.PortalApp, bool) {
+					portalApp, ok := portalApps[portalAppID]
+					return portalApp, ok
+				},
+			).AnyTimes()
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithMaxConcurrentChecksPerAccount(test.maxConcurrent),
+			)
+			interceptor := authHandler.AccountConcurrencyInterceptor()
+			info := &grpc.UnaryServerInfo{FullMethod: "/envoy.service.auth.v3.Authorization/Check"}
+
+			// Hold requests in flight in handlers blocked until the test ends
+			release := make(chan struct{})
+			defer close(release)
+			for _, portalAppID := range test.inFlight {
+				entered := make(chan struct{})
+				go func() {
+					_, _ = interceptor(context.Background(), newTestCheckRequest("/v1/"+string(portalAppID)), info,
+						func(ctx context.Context, req any) (any, error) {
+							close(entered)
+							<-release
+							return nil, nil
+						},
+					)
+				}()
+				<-entered
+			}
+
+			handlerCalled := false
+			_, err := interceptor(context.Background(), test.req, info, func(ctx context.Context, req any) (any, error) {
+				handlerCalled = true
+				return nil, nil
+			})
+
+			c.Equal(test.expectedCode, grpcstatus.Code(err))
+			c.Equal(test.expectedCode == codes.OK, handlerCalled)
+		})
+	}
+}
+
+// newTestCheckRequest returns a CheckRequest for the given path.
+func newTestCheckRequest(path string) *envoy_auth.CheckRequest {
+	return &envoy_auth.CheckRequest{
+		Attributes: &envoy_auth.AttributeContext{
+			Request: &envoy_auth.AttributeContext_Request{
+				Http: &envoy_auth.AttributeContext_HttpRequest{
+					Path: path,
+				},
+			},
+		},
+	}
+}
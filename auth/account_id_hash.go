@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// reqHeaderAccountHash is optionally set on authorized requests, instead of or alongside "Portal-Account-ID".
+// Value is the hex-encoded HMAC-SHA256 of the account ID, keyed by the configured salt.
+// Privacy-sensitive downstreams may use it as a stable account identifier without seeing the raw account ID.
+const reqHeaderAccountHash = "Portal-Account-Hash"
+
+// AccountIDHeaderMode determines which account ID headers are set on authorized requests.
+type AccountIDHeaderMode string
+
+const (
+	// AccountIDHeaderRaw sets only the raw "Portal-Account-ID" header.
+	AccountIDHeaderRaw AccountIDHeaderMode = "raw"
+	// AccountIDHeaderHashed sets only the salted "Portal-Account-Hash" header.
+	AccountIDHeaderHashed AccountIDHeaderMode = "hashed"
+	// AccountIDHeaderBoth sets both the "Portal-Account-ID" and "Portal-Account-Hash" headers.
+	AccountIDHeaderBoth AccountIDHeaderMode = "both"
+)
+
+// defaultAccountIDHeaderMode preserves the original behavior of only setting the raw account ID header.
+const defaultAccountIDHeaderMode = AccountIDHeaderRaw
+
+// ParseAccountIDHeaderMode parses an AccountIDHeaderMode.
+//   - Valid values are "raw", "hashed" and "both"
+func ParseAccountIDHeaderMode(s string) (AccountIDHeaderMode, error) {
+	switch mode := AccountIDHeaderMode(s); mode {
+	case AccountIDHeaderRaw, AccountIDHeaderHashed, AccountIDHeaderBoth:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid account ID header mode %q: must be one of raw, hashed, both", s)
+	}
+}
+
+// getAccountIDHeaders returns the account ID headers for the portal app, per the account ID header mode.
+//   - Only the account ID headers are affected: rate limit headers (e.g. "Rl-Plan-Free") still carry the raw account ID
+func (a *authHandler) getAccountIDHeaders(portalApp *store.PortalApp) []*envoy_core.HeaderValueOption {
+	var headers []*envoy_core.HeaderValueOption
+	if a.accountIDHeaderMode != AccountIDHeaderHashed {
+		headers = append(headers, a.newHeaderValueOption(a.accountIDHeader, string(portalApp.AccountID)))
+	}
+	if a.accountIDHeaderMode == AccountIDHeaderHashed || a.accountIDHeaderMode == AccountIDHeaderBoth {
+		headers = append(headers, a.newHeaderValueOption(reqHeaderAccountHash, hashAccountID(a.accountIDHashSalt, portalApp.AccountID)))
+	}
+	return headers
+}
+
+// hashAccountID returns the hex-encoded HMAC-SHA256 of the account ID, keyed by the salt.
+//   - The hash is stable for the same salt, so downstreams can correlate requests by account
+//   - Without the salt, the hash cannot be reversed by hashing known account IDs
+func hashAccountID(salt string, accountID store.AccountID) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(accountID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseAccountIDHeaderMode(t *testing.T) {
+	c := require.New(t)
+
+	for _, mode := range []AccountIDHeaderMode{AccountIDHeaderRaw, AccountIDHeaderHashed, AccountIDHeaderBoth} {
+		parsed, err := ParseAccountIDHeaderMode(string(mode))
+		c.NoError(err)
+		c.Equal(mode, parsed)
+	}
+
+	_, err := ParseAccountIDHeaderMode("sha256")
+	c.Error(err)
+}
+
+func Test_hashAccountID(t *testing.T) {
+	c := require.New(t)
+
+	hash := hashAccountID("salt_1", "account_1")
+
+	// Stable: the same salt and account ID always produce the same hash.
+	c.Equal(hash, hashAccountID("salt_1", "account_1"))
+	c.Len(hash, 64)
+
+	// Salted: a different salt produces a different hash of the same account ID.
+	c.NotEqual(hash, hashAccountID("salt_2", "account_1"))
+	c.NotEqual(hash, hashAccountID("", "account_1"))
+
+	// Distinct: different account IDs produce different hashes for the same salt.
+	c.NotEqual(hash, hashAccountID("salt_1", "account_2"))
+}
+
+func Test_getHTTPHeaders_AccountIDHeaderMode(t *testing.T) {
+	portalApp := &store.PortalApp{ID: "portal_app_1", AccountID: "account_1", PlanType: grovedb.PlanUnlimited_DatabaseType}
+
+	tests := []struct {
+		name            string
+		opts            []AuthHandlerOption
+		expectedHeaders map[string]string
+	}{
+		{
+			name: "should only add the raw account ID header by default",
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_1",
+				reqHeaderAccountID:   "account_1",
+			},
+		},
+		{
+			name: "should only add the account hash header in hashed mode",
+			opts: []AuthHandlerOption{WithAccountIDHeaderMode(AccountIDHeaderHashed, "salt_1")},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_1",
+				reqHeaderAccountHash: hashAccountID("salt_1", "account_1"),
+			},
+		},
+		{
+			name: "should add both account ID headers in both mode",
+			opts: []AuthHandlerOption{WithAccountIDHeaderMode(AccountIDHeaderBoth, "salt_1")},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_1",
+				reqHeaderAccountID:   "account_1",
+				reqHeaderAccountHash: hashAccountID("salt_1", "account_1"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, test.opts...)
+
+			headers := authHandler.getHTTPHeaders(portalApp, ratelimit.DecisionOK, 1)
+
+			gotHeaders := make(map[string]string, len(headers))
+			for _, header := range headers {
+				gotHeaders[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+			}
+			c.Equal(test.expectedHeaders, gotHeaders)
+		})
+	}
+}
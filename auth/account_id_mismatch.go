@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// AccountIDMismatchPolicy determines how requests are handled if they carry an account ID header
+// that differs from the account of the resolved portal app (e.g. a spoofed header or a routing bug).
+type AccountIDMismatchPolicy string
+
+const (
+	// AccountIDMismatchOverwrite logs the mismatch and overwrites the header with the portal app's account ID.
+	AccountIDMismatchOverwrite AccountIDMismatchPolicy = "overwrite"
+	// AccountIDMismatchDeny denies the request with a 403.
+	AccountIDMismatchDeny AccountIDMismatchPolicy = "deny"
+)
+
+// defaultAccountIDMismatchPolicy preserves the original behavior of overwriting the account ID header.
+const defaultAccountIDMismatchPolicy = AccountIDMismatchOverwrite
+
+// errAccountIDMismatch is returned when the request's account ID header differs from the portal app's account ID.
+var errAccountIDMismatch = errors.New("account ID does not match the portal app's account")
+
+// ParseAccountIDMismatchPolicy parses an AccountIDMismatchPolicy.
+//   - Valid values are "overwrite" and "deny"
+func ParseAccountIDMismatchPolicy(s string) (AccountIDMismatchPolicy, error) {
+	switch policy := AccountIDMismatchPolicy(s); policy {
+	case AccountIDMismatchOverwrite, AccountIDMismatchDeny:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid account ID mismatch policy %q: must be one of overwrite, deny", s)
+	}
+}
+
+// checkAccountIDMismatch applies the account ID mismatch policy to the request's account ID header.
+//   - Requests without an account ID header, or with the portal app's account ID, always pass
+//   - Returns errAccountIDMismatch if the header differs and the policy is AccountIDMismatchDeny
+func (a *authHandler) checkAccountIDMismatch(logger polylog.Logger, headers http.Header, portalApp *store.PortalApp) error {
+	requestAccountID := headers.Get(a.accountIDHeader)
+	if requestAccountID == "" || store.AccountID(requestAccountID) == portalApp.AccountID {
+		return nil
+	}
+
+	if a.accountIDMismatchPolicy == AccountIDMismatchDeny {
+		return errAccountIDMismatch
+	}
+
+	logger.Warn().
+		Str("request_account_id", requestAccountID).
+		Msg("⚠️ request account ID header does not match the portal app's account: overwriting it.")
+	return nil
+}
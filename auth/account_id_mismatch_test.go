@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseAccountIDMismatchPolicy(t *testing.T) {
+	c := require.New(t)
+
+	for _, valid := range []AccountIDMismatchPolicy{AccountIDMismatchOverwrite, AccountIDMismatchDeny} {
+		policy, err := ParseAccountIDMismatchPolicy(string(valid))
+		c.NoError(err)
+		c.Equal(valid, policy)
+	}
+
+	for _, invalid := range []string{"", "strict", "DENY"} {
+		_, err := ParseAccountIDMismatchPolicy(invalid)
+		c.Error(err, "policy %q", invalid)
+	}
+}
+
+func Test_Check_AccountIDMismatch(t *testing.T) {
+	portalApp := &store.PortalApp{
+		ID:        "portal_app_1",
+		AccountID: "account_1",
+		PlanType:  grovedb.PlanUnlimited_DatabaseType,
+	}
+
+	tests := []struct {
+		name              string
+		opts              []AuthHandlerOption
+		requestHeaders    map[string]string
+		expectedCode      envoy_type.StatusCode
+		expectedAccountID string
+	}{
+		{
+			name:              "should allow a request without an account ID header",
+			opts:              []AuthHandlerOption{WithAccountIDMismatchPolicy(AccountIDMismatchDeny)},
+			expectedCode:      envoy_type.StatusCode_OK,
+			expectedAccountID: "account_1",
+		},
+		{
+			name:              "should allow a request whose account ID header matches the portal app's account",
+			opts:              []AuthHandlerOption{WithAccountIDMismatchPolicy(AccountIDMismatchDeny)},
+			requestHeaders:    map[string]string{reqHeaderAccountID: "account_1"},
+			expectedCode:      envoy_type.StatusCode_OK,
+			expectedAccountID: "account_1",
+		},
+		{
+			name:              "should overwrite a mismatched account ID header by default",
+			requestHeaders:    map[string]string{reqHeaderAccountID: "account_2"},
+			expectedCode:      envoy_type.StatusCode_OK,
+			expectedAccountID: "account_1",
+		},
+		{
+			name:              "should overwrite a mismatched account ID header if the policy is overwrite",
+			opts:              []AuthHandlerOption{WithAccountIDMismatchPolicy(AccountIDMismatchOverwrite)},
+			requestHeaders:    map[string]string{reqHeaderAccountID: "account_2"},
+			expectedCode:      envoy_type.StatusCode_OK,
+			expectedAccountID: "account_1",
+		},
+		{
+			name:           "should deny a mismatched account ID header if the policy is deny",
+			opts:           []AuthHandlerOption{WithAccountIDMismatchPolicy(AccountIDMismatchDeny)},
+			requestHeaders: map[string]string{reqHeaderAccountID: "account_2"},
+			expectedCode:   envoy_type.StatusCode_Forbidden,
+		},
+		{
+			name: "should deny a mismatched custom account ID header if the policy is deny",
+			opts: []AuthHandlerOption{
+				WithAccountIDHeader("X-Portal-Account-ID"),
+				WithAccountIDMismatchPolicy(AccountIDMismatchDeny),
+			},
+			requestHeaders: map[string]string{"X-Portal-Account-ID": "account_2"},
+			expectedCode:   envoy_type.StatusCode_Forbidden,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(portalApp.ID).Return(portalApp, true)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), mockPortalAppStore, newTestRateLimitStore(ctrl), []Authorizer{&AuthorizerAPIKey{}}, test.opts...)
+
+			req := newTestCheckRequest("/v1/" + string(portalApp.ID))
+			req.Attributes.Request.Http.Headers = test.requestHeaders
+
+			resp, err := authHandler.Check(context.Background(), req)
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+
+			if test.expectedCode == envoy_type.StatusCode_OK {
+				c.Equal(test.expectedAccountID, getResponseHeaderValues(resp)[authHandler.accountIDHeader])
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// accountRequestCeilingExceededMessage is the body message returned when an account exceeds the per-second request ceiling.
+const accountRequestCeilingExceededMessage = "This account has exceeded the maximum number of requests per second. Please retry shortly."
+
+// accountRequestCeilingPruneInterval is how often idle accounts are removed from the request ceiling's buckets.
+const accountRequestCeilingPruneInterval = time.Minute
+
+// errAccountRequestCeilingExceeded is returned when the account has exceeded the per-second request ceiling.
+var errAccountRequestCeilingExceeded = errors.New("account exceeded the per-second request ceiling")
+
+// accountRequestCeiling is a per-account token bucket limiting the requests per second of each account.
+//   - Each bucket holds up to one second of requests and refills continuously at the ceiling rate
+//   - Buckets of idle accounts are pruned periodically, as a full bucket is equivalent to no bucket
+type accountRequestCeiling struct {
+	requestsPerSecond float64
+
+	// now returns the current time; overridden in tests
+	now func() time.Time
+
+	buckets   map[store.AccountID]*tokenBucket
+	lastPrune time.Time
+	bucketsMu sync.Mutex
+}
+
+// tokenBucket holds an account's remaining requests as of the last update.
+type tokenBucket struct {
+	tokens     float64
+	lastUpdate time.Time
+}
+
+func newAccountRequestCeiling(requestsPerSecond int) *accountRequestCeiling {
+	return &accountRequestCeiling{
+		requestsPerSecond: float64(requestsPerSecond),
+		now:               time.Now,
+		buckets:           make(map[store.AccountID]*tokenBucket),
+	}
+}
+
+// allow takes a token from the account's bucket.
+//   - Returns false if the account's bucket is empty, i.e. it exceeded the ceiling.
+func (c *accountRequestCeiling) allow(accountID store.AccountID) bool {
+	c.bucketsMu.Lock()
+	defer c.bucketsMu.Unlock()
+
+	now := c.now()
+	c.pruneIdleBuckets(now)
+
+	bucket, ok := c.buckets[accountID]
+	if !ok {
+		bucket = &tokenBucket{tokens: c.requestsPerSecond, lastUpdate: now}
+		c.buckets[accountID] = bucket
+	}
+
+	bucket.tokens = c.refill(bucket, now)
+	bucket.lastUpdate = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// refill returns the bucket's tokens at the given time, capped at one second of requests.
+func (c *accountRequestCeiling) refill(bucket *tokenBucket, now time.Time) float64 {
+	tokens := bucket.tokens + now.Sub(bucket.lastUpdate).Seconds()*c.requestsPerSecond
+	return min(tokens, c.requestsPerSecond)
+}
+
+// pruneIdleBuckets removes the buckets that have refilled completely, at most once per prune interval.
+//   - Must be called with bucketsMu held.
+func (c *accountRequestCeiling) pruneIdleBuckets(now time.Time) {
+	if now.Sub(c.lastPrune) < accountRequestCeilingPruneInterval {
+		return
+	}
+	c.lastPrune = now
+
+	for accountID, bucket := range c.buckets {
+		if c.refill(bucket, now) >= c.requestsPerSecond {
+			delete(c.buckets, accountID)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_accountRequestCeiling(t *testing.T) {
+	c := require.New(t)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ceiling := newAccountRequestCeiling(2)
+	ceiling.now = func() time.Time { return now }
+
+	// Accounts may burst up to one second of requests
+	c.True(ceiling.allow("account_1"))
+	c.True(ceiling.allow("account_1"))
+	c.False(ceiling.allow("account_1"))
+
+	// Other accounts are limited independently
+	c.True(ceiling.allow("account_2"))
+
+	// The bucket refills continuously at the ceiling rate
+	now = now.Add(500 * time.Millisecond)
+	c.True(ceiling.allow("account_1"))
+	c.False(ceiling.allow("account_1"))
+
+	// The bucket refills up to one second of requests, however long the account is idle
+	now = now.Add(10 * time.Second)
+	c.True(ceiling.allow("account_1"))
+	c.True(ceiling.allow("account_1"))
+	c.False(ceiling.allow("account_1"))
+
+	// Buckets of idle accounts are pruned once they have refilled
+	now = now.Add(accountRequestCeilingPruneInterval)
+	c.True(ceiling.allow("account_3"))
+	c.NotContains(ceiling.buckets, store.AccountID("account_1"))
+	c.NotContains(ceiling.buckets, store.AccountID("account_2"))
+	c.Contains(ceiling.buckets, store.AccountID("account_3"))
+}
+
+func Test_Check_AccountRequestCeiling(t *testing.T) {
+	tests := []struct {
+		name          string
+		ceiling       int
+		portalApp     *store.PortalApp
+		expectedCodes []envoy_type.StatusCode
+	}{
+		{
+			name:    "should deny requests from an unlimited account over the ceiling",
+			ceiling: 2,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			expectedCodes: []envoy_type.StatusCode{envoy_type.StatusCode_OK, envoy_type.StatusCode_OK, envoy_type.StatusCode_TooManyRequests},
+		},
+		{
+			name:    "should deny requests from an account with no rate limit over the ceiling",
+			ceiling: 1,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+			},
+			expectedCodes: []envoy_type.StatusCode{envoy_type.StatusCode_OK, envoy_type.StatusCode_TooManyRequests},
+		},
+		{
+			name:    "should allow all requests if the ceiling is disabled",
+			ceiling: 0,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			expectedCodes: []envoy_type.StatusCode{envoy_type.StatusCode_OK, envoy_type.StatusCode_OK, envoy_type.StatusCode_OK},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true).AnyTimes()
+
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			mockRateLimitStore.EXPECT().GetAccountRateLimitDecision(test.portalApp.AccountID).Return(ratelimit.DecisionOK).AnyTimes()
+			mockRateLimitStore.EXPECT().IsAvailable().Return(true).AnyTimes()
+			mockRateLimitStore.EXPECT().HasLoaded().Return(true).AnyTimes()
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithAccountRequestCeiling(test.ceiling),
+			)
+			if authHandler.accountRequestCeiling != nil {
+				// Freeze the clock so the bucket does not refill between requests
+				now := time.Now()
+				authHandler.accountRequestCeiling.now = func() time.Time { return now }
+			}
+
+			for _, expectedCode := range test.expectedCodes {
+				resp, err := authHandler.Check(context.Background(), newTestCheckRequest("/v1/"+string(test.portalApp.ID)))
+				c.NoError(err)
+				c.Equal(int32(expectedCode), getHTTPStatusCode(resp))
+			}
+		})
+	}
+}
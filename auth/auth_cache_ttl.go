@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// reqHeaderAuthCacheTTL is optionally set on authorized requests.
+// Value is the number of seconds GUARD may cache the portal app's authorization decision (e.g. "300").
+const reqHeaderAuthCacheTTL = "Portal-Auth-Cache-TTL"
+
+// getAuthCacheTTLHeader returns the "Portal-Auth-Cache-TTL" header for the portal app.
+//   - The TTL is the portal app's own TTL from the data source, if set
+//   - Otherwise, the default TTL for public or API key protected portal apps
+//   - Returns false if the resulting TTL is zero
+func (a *authHandler) getAuthCacheTTLHeader(portalApp *store.PortalApp) (*envoy_core.HeaderValueOption, bool) {
+	ttl := a.getAuthCacheTTL(portalApp)
+	if ttl <= 0 {
+		return nil, false
+	}
+
+	return a.newHeaderValueOption(reqHeaderAuthCacheTTL, strconv.FormatInt(int64(ttl/time.Second), 10)), true
+}
+
+// getAuthCacheTTL returns the TTL GUARD may cache the portal app's authorization decision for.
+func (a *authHandler) getAuthCacheTTL(portalApp *store.PortalApp) time.Duration {
+	if portalApp.AuthCacheTTL != nil {
+		return *portalApp.AuthCacheTTL
+	}
+
+	if requiresAPIKey(portalApp) {
+		return a.authCacheTTLAPIKey
+	}
+	return a.authCacheTTLPublic
+}
+
+// requiresAPIKey returns true if requests for the portal app must provide an API key,
+// either the portal app's own API key or its account's API key.
+func requiresAPIKey(portalApp *store.PortalApp) bool {
+	return getAuthType(portalApp) != authTypeNone
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_getHTTPHeaders_AuthCacheTTL(t *testing.T) {
+	zeroTTL := time.Duration(0)
+	appTTL := 10 * time.Minute
+
+	tests := []struct {
+		name        string
+		publicTTL   time.Duration
+		apiKeyTTL   time.Duration
+		portalApp   *store.PortalApp
+		expectedTTL string
+	}{
+		{
+			name:        "should set the public TTL for a portal app without an API key",
+			publicTTL:   5 * time.Minute,
+			apiKeyTTL:   30 * time.Second,
+			portalApp:   &store.PortalApp{ID: "portal_app_public", AccountID: "account_1"},
+			expectedTTL: "300",
+		},
+		{
+			name:      "should set the API key TTL for a portal app with an API key",
+			publicTTL: 5 * time.Minute,
+			apiKeyTTL: 30 * time.Second,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_key",
+				AccountID: "account_1",
+				Auth:      &store.Auth{APIKeys: []string{"api_key"}},
+			},
+			expectedTTL: "30",
+		},
+		{
+			name:      "should set the API key TTL for a portal app with an account API key",
+			publicTTL: 5 * time.Minute,
+			apiKeyTTL: 30 * time.Second,
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key",
+				AccountID:   "account_1",
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			expectedTTL: "30",
+		},
+		{
+			name:      "should set the portal app's own TTL over the default TTL",
+			publicTTL: 5 * time.Minute,
+			apiKeyTTL: 30 * time.Second,
+			portalApp: &store.PortalApp{
+				ID:           "portal_app_key",
+				AccountID:    "account_1",
+				Auth:         &store.Auth{APIKeys: []string{"api_key"}},
+				AuthCacheTTL: &appTTL,
+			},
+			expectedTTL: "600",
+		},
+		{
+			name: "should set the portal app's own TTL even if the default TTLs are zero",
+			portalApp: &store.PortalApp{
+				ID:           "portal_app_public",
+				AccountID:    "account_1",
+				AuthCacheTTL: &appTTL,
+			},
+			expectedTTL: "600",
+		},
+		{
+			name:      "should not set the header if the portal app's own TTL is zero",
+			publicTTL: 5 * time.Minute,
+			portalApp: &store.PortalApp{
+				ID:           "portal_app_public",
+				AccountID:    "account_1",
+				AuthCacheTTL: &zeroTTL,
+			},
+		},
+		{
+			name:      "should not set the header if the default TTL for the portal app is zero",
+			publicTTL: 5 * time.Minute,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_key",
+				AccountID: "account_1",
+				Auth:      &store.Auth{APIKeys: []string{"api_key"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, WithAuthCacheTTL(test.publicTTL, test.apiKeyTTL))
+
+			headers := authHandler.getHTTPHeaders(test.portalApp, ratelimit.DecisionOK, 1)
+
+			gotHeaders := make(map[string]string, len(headers))
+			for _, header := range headers {
+				gotHeaders[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+			}
+			ttl, ok := gotHeaders[reqHeaderAuthCacheTTL]
+			if test.expectedTTL == "" {
+				c.False(ok)
+				return
+			}
+			c.True(ok)
+			c.Equal(test.expectedTTL, ttl)
+		})
+	}
+}
@@ -7,8 +7,12 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"regexp"
+	"strconv"
 	"time"
 
 	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -19,23 +23,71 @@ import (
 	"google.golang.org/grpc/codes"
 
 	"github.com/buildwithgrove/path-external-auth-server/metrics"
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
 	"github.com/buildwithgrove/path-external-auth-server/store"
 )
 
-const accountRateLimitMessage = "This account is rate limited. To upgrade your plan or modify your account settings, log in to your account at https://portal.grove.city/"
+const (
+	accountRateLimitMessage     = "This account is rate limited. To upgrade your plan or modify your account settings, log in to your account at https://portal.grove.city/"
+	rateLimitUnavailableMessage = "rate limit status is temporarily unavailable, please try again later"
+	rateLimitColdStartMessage   = "rate limit status is loading, please try again shortly"
+	internalErrorMessage        = "internal server error"
+
+	// defaultBillingDelinquentMessage is the body message returned for billing-delinquent accounts, unless overridden.
+	defaultBillingDelinquentMessage = "This account has an outstanding balance. To restore access, update your payment details at https://portal.grove.city/"
+)
+
+var (
+	// errAccountRateLimited is returned when the account has crossed its block threshold,
+	// or the portal app has crossed the block threshold of its own monthly limit.
+	errAccountRateLimited = errors.New("account is rate limited")
+	// errAccountBillingDelinquent is returned when the account has an outstanding balance.
+	errAccountBillingDelinquent = errors.New("account is billing-delinquent")
+	// errRateLimitStoreUnavailable is returned when the rate limit store is unavailable and the handler fails closed.
+	errRateLimitStoreUnavailable = errors.New("rate limit store is unavailable")
+	// errRateLimitStoreColdStart is returned when the rate limit store has not loaded yet and cold start denial is enabled.
+	errRateLimitStoreColdStart = errors.New("rate limit store has not loaded yet")
+)
 
 const (
 	// TODO_TECHDEBT(@commoddity): This path segment should be configurable via a single source of truth.
 	// - Referred to in multiple places (e.g. GUARD Helm charts, PATH's router.go, and here)
-	pathPrefix = "/v1/"
+	// - Overridden by WithPathPrefixes (PATH_PREFIX or PATH_PREFIXES), which must match PATH's base paths
+	defaultPathPrefix = "/v1/"
 
-	// The portal app and account id must match PATH's expected HTTP headers.
+	// The default portal app and account id headers must match PATH's expected HTTP headers.
+	// Overridden by WithPortalAppIDHeader and WithAccountIDHeader, which must match PATH's configured headers.
 	// Reference:
 	// https://github.com/buildwithgrove/path/blob/1e7b2d83294e8c406479ae5e480f4dca97414cee/gateway/observation.go#L16-L18
 	reqHeaderPortalAppID = "Portal-Application-ID" // Set on all service requests
 	reqHeaderAccountID   = "Portal-Account-ID"     // Set on all service requests
 
+	// Set on requests from accounts that crossed a warn or throttle threshold.
+	// GUARD may use this header to apply soft throttling for the account.
+	reqHeaderRateLimitStatus = "Portal-RateLimit-Status"
+
+	// Optionally set on authorized requests and rate limited (429) responses.
+	// Value is the account's rate limit decision and a TTL hint in seconds (e.g. "ok; ttl=30").
+	// Envoy/GUARD may cache the decision for the TTL to reduce load on PEAS.
+	reqHeaderRateLimitDecision = "Portal-RateLimit-Decision"
+
+	// Optionally set on authorized requests for downstream analytics.
+	// Value is a stable label of the portal app's plan and limit combination (e.g. "free", "unlimited-limited").
+	reqHeaderRateLimitTier = "Portal-RateLimit-Tier"
+
+	// Optionally set on authorized requests for support dashboards.
+	// Value is the human-readable name of the portal app's plan (e.g. "Free").
+	reqHeaderPlanName = "Portal-Plan-Name"
+
+	// Set on all responses while the rate limit store is unavailable, if the failure mode is fail_open_stale.
+	// Downstream may log or alert on responses served from stale rate limit data.
+	reqHeaderAuthStale = "Portal-Auth-Stale"
+
 	errBody = `{"code": %d, "message": "%s"}`
+
+	// defaultHeaderAppendAction is set explicitly on all injected headers so behavior
+	// does not depend on the Envoy version's default append semantics.
+	defaultHeaderAppendAction = envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD
 )
 
 // portalAppStore interface provides an in-memory store of PortalApps.
@@ -44,14 +96,24 @@ const (
 //   - Fast lookups of authorization data for PATH when processing requests.
 type portalAppStore interface {
 	GetPortalApp(portalAppID store.PortalAppID) (*store.PortalApp, bool)
+	// GetPortalAppIDByAPIKey returns false if the store's API key index is disabled.
+	GetPortalAppIDByAPIKey(apiKey string) (store.PortalAppID, bool)
 }
 
-// rateLimitStore interface provides an in-memory store of rate limited accounts.
+// rateLimitStore interface provides an in-memory store of rate limit decisions for accounts and portal apps.
 //
 // Used for:
-//   - Fast lookups of rate limited accounts for PATH when processing requests.
+//   - Fast lookups of rate limited accounts and portal apps for PATH when processing requests.
 type rateLimitStore interface {
-	IsAccountRateLimited(accountID store.AccountID) bool
+	GetAccountRateLimitDecision(accountID store.AccountID) ratelimit.Decision
+	// IsAccountRateLimitable returns true if the portal app's account is subject to monthly rate limit enforcement.
+	IsAccountRateLimitable(portalApp *store.PortalApp) bool
+	// IsPortalAppRateLimited returns true if the portal app is over its own monthly limit, regardless of its account.
+	IsPortalAppRateLimited(portalAppID store.PortalAppID) bool
+	// IsAvailable returns false if the store's rate limit data is missing or stale.
+	IsAvailable() bool
+	// HasLoaded returns false until the store's first rate limit data load succeeds.
+	HasLoaded() bool
 }
 
 // authHandler processes requests from Envoy.
@@ -67,42 +129,548 @@ type authHandler struct {
 	// RateLimitStore: in-memory store of rate limited accounts
 	rateLimitStore rateLimitStore
 
-	// APIKeyAuthorizer: used for request authorization
-	apiKeyAuthorizer Authorizer
+	// Authorizers: used for request authorization, tried in order until one authorizes the request
+	authorizers []Authorizer
+	// IPAllowlistAuthorizer: used for authorization of portal apps with allowed CIDRs, before any other authorizer
+	ipAllowlistAuthorizer Authorizer
+
+	// DenialMessages: optional localized denial messages, selected by the Accept-Language header
+	denialMessages LocalizedDenialMessages
+
+	// HeaderAppendAction: Envoy append action set on all headers injected into authorized requests
+	headerAppendAction envoy_core.HeaderValueOption_HeaderAppendAction
+
+	// RateLimitFailureMode: how rate-limit-eligible requests are handled when the rate limit store is unavailable
+	rateLimitFailureMode RateLimitFailureMode
+	// RateLimitColdStartDeny: whether rate-limit-eligible requests are denied until the rate limit store first loads
+	rateLimitColdStartDeny bool
+
+	// RelayCosts: optional per-app/per-method relay cost multipliers, emitted as "Rl-Cost-<n>" headers
+	relayCosts *RelayCosts
+
+	// RateLimitDecisionHeaderTTL: TTL hint of the "Portal-RateLimit-Decision" header; the header is omitted if zero
+	rateLimitDecisionHeaderTTL time.Duration
+	// RateLimitDecisionTTLOverrides: optional per-app TTL hints, overriding rateLimitDecisionHeaderTTL
+	rateLimitDecisionTTLOverrides RateLimitDecisionTTLOverrides
+
+	// AuthCacheTTLPublic/AuthCacheTTLAPIKey: default TTL hints of the "Portal-Auth-Cache-TTL" header for
+	// public and API key protected portal apps; overridden by the portal app's own TTL, and omitted if zero
+	authCacheTTLPublic time.Duration
+	authCacheTTLAPIKey time.Duration
+
+	// PathPrefixes: path prefixes preceding the portal app ID in request paths (e.g. "/v1/"); the longest match wins
+	pathPrefixes []string
+
+	// PortalAppIDHeader/AccountIDHeader: names of the portal app ID and account ID headers (e.g. "Portal-Application-ID")
+	portalAppIDHeader string
+	accountIDHeader   string
+
+	// MissingPortalAppIDStatusCode: HTTP status code returned for requests with no portal app ID (e.g. "/v1/")
+	missingPortalAppIDStatusCode envoy_type.StatusCode
+	// MissingPortalAppIDMessage: optional JSON-escaped body message returned for requests with no portal app ID
+	missingPortalAppIDMessage string
+
+	// HealthCheckBypass: optional matcher for internal health check requests that bypass rate limiting
+	healthCheckBypass *HealthCheckBypass
+
+	// ProbePaths: optional request paths answered with a canned 200, skipping every check
+	probePaths ProbePaths
+
+	// PlanHeaders: optional plan-level default headers, keyed by plan type
+	planHeaders PlanHeaders
+
+	// RequireAuthority: whether requests with no Host/:authority header are denied
+	requireAuthority bool
+
+	// DenyPathTraversal: whether requests whose path contains a plain or encoded ".." sequence are denied
+	denyPathTraversal bool
+
+	// HTTPSRequirement: optional set of portal apps that may only be requested over HTTPS
+	httpsRequirement *HTTPSRequirement
+
+	// RateLimitTierHeaderEnabled: whether the "Portal-RateLimit-Tier" header is set on authorized requests
+	rateLimitTierHeaderEnabled bool
+
+	// PlanNameHeaderEnabled: whether the "Portal-Plan-Name" header is set on authorized requests
+	planNameHeaderEnabled bool
+
+	// BurstAllowanceHeaderEnabled: whether the "Rl-Burst-<n>" header is set for portal apps with a burst allowance
+	burstAllowanceHeaderEnabled bool
+
+	// AccountIDHeaderMode: whether the raw "Portal-Account-ID" and/or salted "Portal-Account-Hash" header is set
+	accountIDHeaderMode AccountIDHeaderMode
+	// AccountIDHashSalt: salt of the "Portal-Account-Hash" header
+	accountIDHashSalt string
+
+	// AccountIDMismatchPolicy: whether requests whose account ID header differs from the portal app's account are denied
+	accountIDMismatchPolicy AccountIDMismatchPolicy
+
+	// RateLimitResetHeaderEnabled: whether the "Portal-RateLimit-Reset-Seconds" header is set for rate-limit-eligible portal apps
+	rateLimitResetHeaderEnabled bool
+
+	// APIKeyLookupEnabled: whether requests with no portal app ID are resolved to a portal app by their API key
+	apiKeyLookupEnabled bool
+
+	// DenialRequestIDEnabled: whether denial bodies include the request ID as a "request_id" field
+	denialRequestIDEnabled bool
+
+	// DenyMisconfiguredPortalApps: whether requests to misconfigured portal apps are denied (see checkPortalAppMisconfigured)
+	denyMisconfiguredPortalApps bool
+
+	// AuthRequiredPlans: optional plan types whose portal apps are misconfigured if they require no authorization
+	authRequiredPlans AuthRequiredPlans
+
+	// PortalAppIDFormat: optional format that portal app IDs must match; malformed IDs are denied before the store lookup
+	portalAppIDFormat *regexp.Regexp
+
+	// QueryParamStrictMode: whether requests carrying query parameters outside QueryParamAllowlist are logged and counted
+	queryParamStrictMode bool
+	// QueryParamAllowlist: query parameter names expected on requests, if strict mode is enabled
+	queryParamAllowlist QueryParamAllowlist
+
+	// BillingDelinquentMessage: JSON-escaped body message returned with a 402 for billing-delinquent accounts
+	billingDelinquentMessage string
+
+	// AccountConcurrency: optional cap on concurrent Check requests per account, enforced by AccountConcurrencyInterceptor
+	accountConcurrency *accountConcurrencyLimiter
+
+	// AccountRequestCeiling: optional per-account requests per second ceiling, enforced regardless of plan
+	accountRequestCeiling *accountRequestCeiling
+
+	// SlowCheckThreshold: optional duration above which a Check is logged at warn level; disabled if zero
+	slowCheckThreshold time.Duration
+
+	// ClientIPResolver: optional ordered client IP resolution strategy; the client IP is not resolved if nil
+	clientIPResolver *ClientIPResolver
 }
 
+// AuthHandlerOption configures optional authHandler behavior.
+type AuthHandlerOption func(*authHandler)
+
+// WithLocalizedDenialMessages sets the localized messages used in 401/404/429 denial bodies.
+// Requests without a matching language receive the default English messages.
+func WithLocalizedDenialMessages(messages LocalizedDenialMessages) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.denialMessages = messages
+	}
+}
+
+// WithHeaderAppendAction sets the Envoy append action used for all injected request headers.
+// Defaults to OVERWRITE_IF_EXISTS_OR_ADD.
+func WithHeaderAppendAction(action envoy_core.HeaderValueOption_HeaderAppendAction) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.headerAppendAction = action
+	}
+}
+
+// WithRateLimitFailureMode sets how requests from rate-limit-eligible accounts are
+// handled when the rate limit store is unavailable. Defaults to RateLimitFailOpen.
+func WithRateLimitFailureMode(mode RateLimitFailureMode) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.rateLimitFailureMode = mode
+	}
+}
+
+// WithRateLimitColdStartDeny denies requests from rate-limit-eligible accounts with a 429
+// from process start until the rate limit store's first successful load, when rate limiting is otherwise off.
+// Takes precedence over the fail_closed failure mode during the cold start.
+func WithRateLimitColdStartDeny(deny bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.rateLimitColdStartDeny = deny
+	}
+}
+
+// WithRelayCosts sets the relay cost multipliers used to emit "Rl-Cost-<n>" headers.
+// Requests with no matching cost count as one relay and receive no cost header.
+func WithRelayCosts(relayCosts *RelayCosts) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.relayCosts = relayCosts
+	}
+}
+
+// WithRateLimitDecisionHeader enables the "Portal-RateLimit-Decision" header on authorized
+// requests and rate limited (429) responses, with the given TTL hint for caching the decision.
+// The header is omitted if the TTL is zero.
+func WithRateLimitDecisionHeader(ttl time.Duration) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.rateLimitDecisionHeaderTTL = ttl
+	}
+}
+
+// WithRateLimitDecisionHeaderTTLOverrides sets per-app TTL hints of the "Portal-RateLimit-Decision" header,
+// overriding the TTL set by WithRateLimitDecisionHeader for the listed portal apps.
+//   - An override of zero omits the header for the portal app
+//   - A positive override sets the header for the portal app, even if the default TTL is zero
+func WithRateLimitDecisionHeaderTTLOverrides(overrides RateLimitDecisionTTLOverrides) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.rateLimitDecisionTTLOverrides = overrides
+	}
+}
+
+// WithAuthCacheTTL enables the "Portal-Auth-Cache-TTL" header on authorized requests,
+// hinting how long GUARD may cache the portal app's authorization decision.
+//   - publicTTL applies to portal apps that do not require an API key, apiKeyTTL to those that do
+//   - A portal app's own TTL from the data source takes precedence over both
+//   - The header is omitted if the portal app's TTL is zero
+func WithAuthCacheTTL(publicTTL, apiKeyTTL time.Duration) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.authCacheTTLPublic = publicTTL
+		a.authCacheTTLAPIKey = apiKeyTTL
+	}
+}
+
+// WithPathPrefixes sets the path prefixes preceding the portal app ID in request paths (e.g. "/v1/" and "/relay/").
+// Each prefix must start and end with "/" (see ParsePathPrefix); the longest prefix a path starts with is used.
+// No prefixes keeps the default "/v1/".
+func WithPathPrefixes(prefixes []string) AuthHandlerOption {
+	return func(a *authHandler) {
+		if len(prefixes) > 0 {
+			a.pathPrefixes = prefixes
+		}
+	}
+}
+
+// WithMissingPortalAppIDResponse sets the HTTP status code and body message returned for
+// requests with no portal app ID in the header or path (e.g. a request to exactly "/v1/").
+// An empty message keeps the default "portal app ID not provided in header or path" message.
+func WithMissingPortalAppIDResponse(httpCode envoy_type.StatusCode, message string) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.missingPortalAppIDStatusCode = httpCode
+		a.missingPortalAppIDMessage = escapeJSONString(message)
+	}
+}
+
+// WithBillingDelinquentMessage sets the body message returned with a 402 Payment Required
+// for requests from billing-delinquent accounts.
+// An empty message keeps the default message, which links to the Grove Portal.
+func WithBillingDelinquentMessage(message string) AuthHandlerOption {
+	return func(a *authHandler) {
+		if message != "" {
+			a.billingDelinquentMessage = escapeJSONString(message)
+		}
+	}
+}
+
+// WithHealthCheckBypass sets the matcher for internal health check requests that bypass rate limiting.
+// Matching requests must still pass authorization.
+func WithHealthCheckBypass(bypass *HealthCheckBypass) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.healthCheckBypass = bypass
+	}
+}
+
+// WithProbePaths sets the request paths (e.g. "/v1") answered with a canned 200 and an empty body,
+// so probes traversing the ext_authz filter are not denied. Probe requests skip auth and rate limiting.
+func WithProbePaths(probePaths ProbePaths) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.probePaths = probePaths
+	}
+}
+
+// WithPlanHeaders sets the headers set on all authorized requests from portal apps on each plan type.
+// Plan types without a header receive no plan header.
+func WithPlanHeaders(planHeaders PlanHeaders) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.planHeaders = planHeaders
+	}
+}
+
+// WithRateLimitTierHeader enables the "Portal-RateLimit-Tier" header on authorized requests,
+// labelling the portal app's plan and limit combination for downstream analytics.
+func WithRateLimitTierHeader(enabled bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.rateLimitTierHeaderEnabled = enabled
+	}
+}
+
+// WithPlanNameHeader enables the "Portal-Plan-Name" header on authorized requests,
+// carrying the human-readable name of the portal app's plan for support dashboards.
+// The header is omitted for portal apps with no plan name.
+func WithPlanNameHeader(enabled bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.planNameHeaderEnabled = enabled
+	}
+}
+
+// WithBurstAllowanceHeader enables the "Rl-Burst-<n>" header on authorized requests from portal apps
+// with a burst allowance, so GUARD's local rate limiter can allow short bursts of n relays.
+// The header is omitted for portal apps with no burst allowance.
+func WithBurstAllowanceHeader(enabled bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.burstAllowanceHeaderEnabled = enabled
+	}
+}
+
+// WithAccountIDHeaderMode sets which account ID headers are set on authorized requests:
+// the raw "Portal-Account-ID", the "Portal-Account-Hash" salted with salt, or both.
+// The salt should be secret and stable, as changing it changes every account's hash.
+func WithAccountIDHeaderMode(mode AccountIDHeaderMode, salt string) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.accountIDHeaderMode = mode
+		a.accountIDHashSalt = salt
+	}
+}
+
+// WithAccountIDMismatchPolicy sets how requests carrying an account ID header that differs from the
+// resolved portal app's account are handled: overwritten and logged, or denied with a 403.
+// Defaults to AccountIDMismatchOverwrite.
+func WithAccountIDMismatchPolicy(policy AccountIDMismatchPolicy) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.accountIDMismatchPolicy = policy
+	}
+}
+
+// WithRateLimitResetHeader enables the "Portal-RateLimit-Reset-Seconds" header on authorized requests
+// and rate limited (429) responses for rate-limit-eligible portal apps, counting down to the monthly usage reset.
+func WithRateLimitResetHeader(enabled bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.rateLimitResetHeaderEnabled = enabled
+	}
+}
+
+// WithRequireAuthority denies requests with no Host/:authority header (e.g. malformed or
+// direct-IP requests) with a 400, so they cannot bypass host-based routing assumptions.
+func WithRequireAuthority(required bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.requireAuthority = required
+	}
+}
+
+// WithDenyPathTraversal denies requests whose path contains a plain or percent-encoded ".."
+// sequence (e.g. "/v1/../admin") with a 400, before the portal app ID is extracted from the path.
+func WithDenyPathTraversal(deny bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.denyPathTraversal = deny
+	}
+}
+
+// WithHTTPSRequirement denies plaintext requests to portal apps that may only be requested over HTTPS with a 426.
+func WithHTTPSRequirement(requirement *HTTPSRequirement) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.httpsRequirement = requirement
+	}
+}
+
+// WithAPIKeyLookup resolves requests with no portal app ID in the header or path to the portal
+// app using the API key in the Authorization header. Requires the portal app store's API key index.
+func WithAPIKeyLookup(enabled bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.apiKeyLookupEnabled = enabled
+	}
+}
+
+// WithDenialRequestID includes the request ID in denial bodies as a "request_id" field, so customers
+// can quote it to support. Uses the X-Request-Id header if set, otherwise a generated ID.
+func WithDenialRequestID(enabled bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.denialRequestIDEnabled = enabled
+	}
+}
+
+// WithPortalAppIDFormat denies requests whose portal app ID does not match the format with a 400,
+// before looking up the portal app. Defaults to nil (any portal app ID is looked up).
+func WithPortalAppIDFormat(format *regexp.Regexp) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.portalAppIDFormat = format
+	}
+}
+
+// WithDenyMisconfiguredPortalApps denies all requests to misconfigured portal apps: portal apps that
+// require API key auth but have an empty API key, or whose plan requires auth but that require none
+// (see WithAuthRequiredPlans). Defaults to false (requests are allowed, logging an error).
+func WithDenyMisconfiguredPortalApps(deny bool) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.denyMisconfiguredPortalApps = deny
+	}
+}
+
+// WithAuthRequiredPlans treats portal apps on the plan types that require no authorization
+// (no Auth and no AccountAuth) as misconfigured. Defaults to nil (any portal app may be public).
+func WithAuthRequiredPlans(plans AuthRequiredPlans) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.authRequiredPlans = plans
+	}
+}
+
+// WithQueryParamStrictMode logs and counts requests carrying query parameters outside the allowlist,
+// in the unexpected query params metric. Requests are never denied.
+// Defaults to false (query parameters are not checked).
+func WithQueryParamStrictMode(enabled bool, allowlist QueryParamAllowlist) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.queryParamStrictMode = enabled
+		a.queryParamAllowlist = allowlist
+	}
+}
+
+// WithMaxConcurrentChecksPerAccount caps the number of Check requests each account may have in flight,
+// enforced by AccountConcurrencyInterceptor. Protects PEAS itself from an account flooding it with auth checks.
+// A maximum of 0 disables the cap.
+func WithMaxConcurrentChecksPerAccount(maxConcurrent int) AuthHandlerOption {
+	return func(a *authHandler) {
+		if maxConcurrent > 0 {
+			a.accountConcurrency = newAccountConcurrencyLimiter(maxConcurrent)
+		}
+	}
+}
+
+// WithAccountRequestCeiling sets a global ceiling on the requests per second of each account, enforced by
+// checkAccountRateLimited regardless of plan type, including unlimited plans and portal apps with no rate limit.
+// A platform safety valve preventing a single account from overwhelming the gateway, rather than a plan limit.
+// A ceiling of 0 disables it.
+func WithAccountRequestCeiling(requestsPerSecond int) AuthHandlerOption {
+	return func(a *authHandler) {
+		if requestsPerSecond > 0 {
+			a.accountRequestCeiling = newAccountRequestCeiling(requestsPerSecond)
+		}
+	}
+}
+
+// WithSlowCheckLogging logs each Check taking longer than the threshold at warn level,
+// with its duration and decision, to surface individual latency outliers hidden by the duration histogram.
+// A threshold of 0 disables the logging.
+func WithSlowCheckLogging(threshold time.Duration) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.slowCheckThreshold = threshold
+	}
+}
+
+// WithClientIPResolver sets the strategy used to resolve each request's client IP,
+// which is then included as "client_ip" in request logs. A nil resolver disables client IP resolution.
+func WithClientIPResolver(resolver *ClientIPResolver) AuthHandlerOption {
+	return func(a *authHandler) {
+		a.clientIPResolver = resolver
+	}
+}
+
+// ParseDenialStatusCode parses an HTTP status code for a denied response.
+//   - Example: "404"
+//   - Must be a 4xx status code known to Envoy
+func ParseDenialStatusCode(s string) (envoy_type.StatusCode, error) {
+	code, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid denial status code %q: %w", s, err)
+	}
+	if _, ok := envoy_type.StatusCode_name[int32(code)]; !ok || code < 400 || code > 499 {
+		return 0, fmt.Errorf("invalid denial status code %d: must be a 4xx status code", code)
+	}
+	return envoy_type.StatusCode(code), nil
+}
+
+// ParseHeaderAppendAction parses an Envoy header append action from its enum name.
+//   - Example: "OVERWRITE_IF_EXISTS_OR_ADD"
+//   - Valid values are "APPEND_IF_EXISTS_OR_ADD", "ADD_IF_ABSENT", "OVERWRITE_IF_EXISTS_OR_ADD" and "OVERWRITE_IF_EXISTS"
+func ParseHeaderAppendAction(s string) (envoy_core.HeaderValueOption_HeaderAppendAction, error) {
+	action, ok := envoy_core.HeaderValueOption_HeaderAppendAction_value[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid header append action %q", s)
+	}
+	return envoy_core.HeaderValueOption_HeaderAppendAction(action), nil
+}
+
+// NewAuthHandler creates a new auth handler, authorizing requests with the authorizers tried in order
+// (e.g. AuthorizerAPIKey, then AuthorizerHMAC). A request is authorized by the first authorizer accepting it.
 func NewAuthHandler(
 	logger polylog.Logger,
 	portalAppStore portalAppStore,
 	rateLimitStore rateLimitStore,
-	apiKeyAuthorizer Authorizer,
+	authorizers []Authorizer,
+	opts ...AuthHandlerOption,
 ) *authHandler {
-	return &authHandler{
-		logger:           logger,
-		portalAppStore:   portalAppStore,
-		rateLimitStore:   rateLimitStore,
-		apiKeyAuthorizer: apiKeyAuthorizer,
+	a := &authHandler{
+		logger:                logger,
+		portalAppStore:        portalAppStore,
+		rateLimitStore:        rateLimitStore,
+		authorizers:           authorizers,
+		ipAllowlistAuthorizer: &AuthorizerIPAllowlist{},
+		headerAppendAction:    defaultHeaderAppendAction,
+
+		rateLimitFailureMode:         defaultRateLimitFailureMode,
+		accountIDHeaderMode:          defaultAccountIDHeaderMode,
+		accountIDMismatchPolicy:      defaultAccountIDMismatchPolicy,
+		pathPrefixes:                 []string{defaultPathPrefix},
+		portalAppIDHeader:            reqHeaderPortalAppID,
+		accountIDHeader:              reqHeaderAccountID,
+		missingPortalAppIDStatusCode: envoy_type.StatusCode_BadRequest,
+		billingDelinquentMessage:     defaultBillingDelinquentMessage,
+	}
+
+	for _, opt := range opts {
+		opt(a)
 	}
+
+	return a
 }
 
 // Check implements the Envoy External Authorization gRPC service.
 // Steps performed:
+//   - Answer probe requests with a canned 200
 //   - Extract Portal Application ID from the path
 //   - Extract Account ID from headers
 //   - Fetch Portal Application from the database
 //   - Check if the Portal Application is authorized
+//   - Check if the request's Account ID header matches the Portal Application's account
+//   - Check if the Account is billing-delinquent
 //   - Check if the Account is rate limited
 //   - Return an OK or Denied response with HTTP headers set
 func (a *authHandler) Check(
 	ctx context.Context,
 	checkReq *envoy_auth.CheckRequest,
-) (*envoy_auth.CheckResponse, error) {
+) (checkResp *envoy_auth.CheckResponse, err error) {
 	startTime := time.Now()
 
+	// Use the trace context of the request being authorized, so its metrics can be linked to its trace
+	ctx = withRequestTraceContext(ctx, checkReq.GetAttributes().GetRequest().GetHttp())
+
+	// Record the HTTP status code of the final response, including the internal error response set on panic,
+	// and log the request if it was slow. Deferred first so it runs after the panic recovery below.
+	defer func() {
+		metrics.RecordAuthHTTPResponse(getHTTPStatusCode(checkResp))
+		a.logSlowCheck(checkReq, checkResp, time.Since(startTime))
+	}()
+
+	// Add the request ID to the body of denied responses, if enabled.
+	// Deferred before the panic recovery below so internal error responses also include it.
+	defer func() {
+		if a.denialRequestIDEnabled && checkResp.GetDeniedResponse() != nil {
+			requestID := getRequestID(checkReq.GetAttributes().GetRequest().GetHttp())
+			setDeniedResponseRequestID(checkResp, requestID)
+			a.logger.Debug().Str("request_id", requestID).Str("reason", checkResp.GetStatus().GetMessage()).Msg("🪪 added request ID to denied response")
+		}
+	}()
+
+	// Set the stale header on all responses except probe responses while the rate limit store is unavailable, if the failure mode is fail_open_stale.
+	// Deferred before the panic recovery below so internal error responses also include it.
+	defer func() {
+		if a.rateLimitFailureMode == RateLimitFailOpenStale && !isProbeCheckResponse(checkResp) && !a.rateLimitStore.IsAvailable() {
+			setStaleHeader(checkResp, a.newHeaderValueOption(reqHeaderAuthStale, "true"))
+		}
+	}()
+
+	// Recover from any panic while handling the request and return an internal error response,
+	// rather than a denial which clients may cache or interpret as their fault.
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger.Error().
+				Str("panic", fmt.Sprintf("%v", r)).
+				Str("path", checkReq.GetAttributes().GetRequest().GetHttp().GetPath()).
+				Msg("🔥 recovered from panic while handling check request: returning internal error.")
+			metrics.RecordAuthRequest(
+				ctx,
+				"", // portalAppID may not be available
+				"", // accountID may not be available
+				metrics.AuthDecisionError,
+				metrics.AuthRequestErrorTypeInternalError,
+				time.Since(startTime).Seconds(),
+			)
+			checkResp, err = getInternalErrorCheckResponse(), nil
+		}
+	}()
+
 	// Get the HTTP request
 	req := checkReq.GetAttributes().GetRequest().GetHttp()
 	if req == nil {
 		metrics.RecordAuthRequest(
+			ctx,
 			"", // portalAppID not available yet
 			"", // accountID not available yet
 			metrics.AuthDecisionDenied,
@@ -116,6 +684,7 @@ func (a *authHandler) Check(
 	path := req.GetPath()
 	if path == "" {
 		metrics.RecordAuthRequest(
+			ctx,
 			"", // portalAppID not available yet
 			"", // accountID not available yet
 			metrics.AuthDecisionDenied,
@@ -125,24 +694,79 @@ func (a *authHandler) Check(
 		return getDeniedCheckResponse("path not provided", envoy_type.StatusCode_BadRequest), nil
 	}
 
+	// Answer probe requests with a canned 200, before any other check
+	if a.probePaths.matches(path) {
+		a.logger.Debug().Str("path", path).Msg("🩺 request is a probe: returning a canned 200.")
+		return getProbeCheckResponse(), nil
+	}
+
+	// Deny requests with no authority, if required
+	if a.requireAuthority && !hasAuthority(req) {
+		a.logger.Debug().Str("path", path).Msg("🚫 request has no Host/:authority header: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			"", // portalAppID not available yet
+			"", // accountID not available yet
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeInvalidRequestNoAuthority,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse("host or authority not provided", envoy_type.StatusCode_BadRequest), nil
+	}
+
+	// Deny requests whose path contains traversal sequences, if enabled, so an unexpected
+	// path segment is never extracted as the portal app ID
+	if a.denyPathTraversal && hasPathTraversal(path) {
+		a.logger.Debug().Str("path", path).Msg("🚫 request path contains a path traversal sequence: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			"", // portalAppID not available yet
+			"", // accountID not available yet
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeInvalidRequestPathTraversal,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse("path traversal is not allowed", envoy_type.StatusCode_BadRequest), nil
+	}
+
 	// Get the request headers as a http.Header
 	headers := convertMapToHeader(req.GetHeaders())
 
 	// Extract the Portal Application ID from the request
 	// It may be extracted from the URL path or the headers
-	portalAppID, err := extractPortalAppID(headers, path)
+	portalAppID, err := a.resolvePortalAppID(headers, path)
+	if errors.Is(err, errMalformedPortalAppID) {
+		a.logger.Debug().Msg("🚫 portal app ID does not match the configured format: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			"", // portalAppID is not recorded, as it is untrusted client input
+			"", // accountID not available yet
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeInvalidRequestMalformedPortalAppID,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse(err.Error(), envoy_type.StatusCode_BadRequest), nil
+	}
 	if err != nil {
 		a.logger.Debug().Err(err).Msg("🚫 unable to extract portal app ID from request")
 		metrics.RecordAuthRequest(
+			ctx,
 			"", // portalAppID not available yet
 			"", // accountID not available yet
 			metrics.AuthDecisionDenied,
 			metrics.AuthRequestErrorTypeInvalidRequestNoPortalAppID,
 			time.Since(startTime).Seconds(),
 		)
-		return getDeniedCheckResponse(err.Error(), envoy_type.StatusCode_BadRequest), nil
+		return a.getMissingPortalAppIDCheckResponse(err.Error()), nil
 	}
 	logger := a.logger.With("portal_app_id", portalAppID)
+	clientIP, ok := a.clientIPResolver.resolve(checkReq)
+	if ok {
+		logger = logger.With("client_ip", clientIP.String())
+	} else if a.clientIPResolver == nil {
+		// Only used by IP allowlists, so it is not logged (see WithClientIPResolver)
+		clientIP, _ = defaultClientIPResolver.resolve(checkReq)
+	}
 
 	// If we get here, we have a valid Portal Application ID.
 	logger.Debug().Msg("🔍 handling check request")
@@ -152,48 +776,165 @@ func (a *authHandler) Check(
 	if !ok {
 		logger.Debug().Msg("🚫 specified portal app not found: rejecting the request.")
 		metrics.RecordAuthRequest(
+			ctx,
 			string(portalAppID),
 			"", // accountID not available yet
 			metrics.AuthDecisionDenied,
 			metrics.AuthRequestErrorTypePortalAppNotFound,
 			time.Since(startTime).Seconds(),
 		)
-		return getDeniedCheckResponse("portal app not found", envoy_type.StatusCode_NotFound), nil
+		return a.getLocalizedDeniedCheckResponse(
+			headers, metrics.AuthRequestErrorTypePortalAppNotFound, "portal app not found", envoy_type.StatusCode_NotFound,
+		), nil
 	}
 	logger = logger.With("account_id", portalApp.AccountID)
 
+	// Log and count unexpected query parameters, if strict mode is enabled
+	a.checkUnexpectedQueryParams(logger, portalApp, path)
+
+	// Check if the Portal Application must be requested over HTTPS
+	if !a.httpsRequirement.isSatisfied(portalAppID, req.GetScheme()) {
+		logger.Debug().Str("scheme", req.GetScheme()).Msg("🚫 portal app requires HTTPS: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			string(portalAppID),
+			string(portalApp.AccountID),
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeHTTPSRequired,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse("HTTPS is required for this portal app", envoy_type.StatusCode_UpgradeRequired), nil
+	}
+
 	// Check if the Portal Application is authorized
-	if err := a.checkPortalAppAuthorized(headers, portalApp); err != nil {
+	err = a.checkPortalAppAuthorized(headers, path, clientIP, portalApp)
+	if errors.Is(err, errClientIPNotAllowed) {
+		logger.Debug().Str("client_ip", clientIP.String()).Msg("🚫 client IP is not in the portal app's allowed CIDRs: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			string(portalAppID),
+			string(portalApp.AccountID),
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeClientIPNotAllowed,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse(err.Error(), envoy_type.StatusCode_Forbidden), nil
+	}
+	if err != nil {
 		logger.Debug().Err(err).Msg("🚫 request failed authorization: rejecting the request.")
 		metrics.RecordAuthRequest(
+			ctx,
 			string(portalAppID),
 			string(portalApp.AccountID),
 			metrics.AuthDecisionDenied,
 			metrics.AuthRequestErrorTypeUnauthorized,
 			time.Since(startTime).Seconds(),
 		)
-		return getDeniedCheckResponse(err.Error(), envoy_type.StatusCode_Unauthorized), nil
+		return a.getLocalizedDeniedCheckResponse(
+			headers, metrics.AuthRequestErrorTypeUnauthorized, err.Error(), envoy_type.StatusCode_Unauthorized,
+		), nil
+	}
+
+	// Check if the request's account ID header matches the Portal Application's account
+	err = a.checkAccountIDMismatch(logger, headers, portalApp)
+	if err != nil {
+		logger.Debug().Msg("🚫 request account ID header does not match the portal app's account: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			string(portalAppID),
+			string(portalApp.AccountID),
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeAccountIDMismatch,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse(err.Error(), envoy_type.StatusCode_Forbidden), nil
+	}
+
+	// Check if the Account is billing-delinquent, before the rate limit check so delinquent
+	// accounts receive a payment link rather than a rate limit message
+	if portalApp.BillingStatus == store.BillingStatusDelinquent {
+		logger.Debug().Msg("🚫 account is billing-delinquent: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			string(portalAppID),
+			string(portalApp.AccountID),
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeBillingDelinquent,
+			time.Since(startTime).Seconds(),
+		)
+		return a.getBillingDelinquentCheckResponse(), nil
 	}
 
 	// Check if the Account is rate limited
-	if err := a.checkAccountRateLimited(portalApp); err != nil {
+	rateLimitCheckStartTime := time.Now()
+	rateLimitDecision, err := a.checkAccountRateLimited(headers, portalApp)
+	metrics.RecordRateLimitCheckDuration(string(portalApp.PlanType), time.Since(rateLimitCheckStartTime).Seconds())
+	if errors.Is(err, errRateLimitStoreUnavailable) {
+		logger.Warn().Msg("🚫 rate limit store is unavailable and failure mode is fail_closed: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			string(portalAppID),
+			string(portalApp.AccountID),
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeRateLimitStoreUnavailable,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse(rateLimitUnavailableMessage, envoy_type.StatusCode_ServiceUnavailable), nil
+	}
+	if errors.Is(err, errAccountRequestCeilingExceeded) {
+		logger.Debug().Msg("🚫 account exceeded the per-second request ceiling: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			string(portalAppID),
+			string(portalApp.AccountID),
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeAccountRequestCeilingExceeded,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse(accountRequestCeilingExceededMessage, envoy_type.StatusCode_TooManyRequests), nil
+	}
+	if errors.Is(err, errRateLimitStoreColdStart) {
+		logger.Debug().Msg("🚫 rate limit store has not loaded yet and cold start denial is enabled: rejecting the request.")
+		metrics.RecordAuthRequest(
+			ctx,
+			string(portalAppID),
+			string(portalApp.AccountID),
+			metrics.AuthDecisionDenied,
+			metrics.AuthRequestErrorTypeRateLimitStoreColdStart,
+			time.Since(startTime).Seconds(),
+		)
+		return getDeniedCheckResponse(rateLimitColdStartMessage, envoy_type.StatusCode_TooManyRequests), nil
+	}
+	if err != nil {
 		logger.Debug().Msg("🚫 account is rate limited: rejecting the request.")
 		metrics.RecordAuthRequest(
+			ctx,
 			string(portalAppID),
 			string(portalApp.AccountID),
 			metrics.AuthDecisionDenied,
 			metrics.AuthRequestErrorTypeRateLimited,
 			time.Since(startTime).Seconds(),
 		)
-		return getDeniedCheckResponse(accountRateLimitMessage, envoy_type.StatusCode_TooManyRequests), nil
+		resp := a.getLocalizedDeniedCheckResponse(
+			headers, metrics.AuthRequestErrorTypeRateLimited, accountRateLimitMessage, envoy_type.StatusCode_TooManyRequests,
+		)
+		if decisionHeader, ok := a.getRateLimitDecisionHeader(portalAppID, rateLimitDecision); ok {
+			resp.GetDeniedResponse().Headers = append(resp.GetDeniedResponse().Headers, decisionHeader)
+		}
+		if resetHeader, ok := a.getRateLimitResetHeader(portalApp); ok {
+			resp.GetDeniedResponse().Headers = append(resp.GetDeniedResponse().Headers, resetHeader)
+		}
+		return resp, nil
 	}
 
 	// Add Portal Application ID and Account ID to the headers
 	// to be passed upstream along the filter chain to the rate limiter.
-	httpHeaders := a.getHTTPHeaders(portalApp)
+	relayCost := a.relayCosts.getRelayCost(portalAppID, path, a.pathPrefixes, req.GetBody())
+	httpHeaders := a.getHTTPHeaders(portalApp, rateLimitDecision, relayCost)
 
 	// Record successful authorization
 	metrics.RecordAuthRequest(
+		ctx,
 		string(portalAppID),
 		string(portalApp.AccountID),
 		metrics.AuthDecisionAuthorized,
@@ -217,70 +958,301 @@ func convertMapToHeader(headersMap map[string]string) http.Header {
 	return httpHeaders
 }
 
+// resolvePortalAppID extracts the Portal Application ID from the request path or headers.
+//   - Falls back to resolving the Portal Application ID from the API key, if API key lookup is enabled
+//     and no Portal Application ID was provided.
+func (a *authHandler) resolvePortalAppID(headers http.Header, path string) (store.PortalAppID, error) {
+	portalAppID, err := extractPortalAppID(headers, a.portalAppIDHeader, path, a.pathPrefixes, a.portalAppIDFormat)
+	if err != nil && !errors.Is(err, errMalformedPortalAppID) && a.apiKeyLookupEnabled {
+		if apiKeyPortalAppID, ok := a.portalAppStore.GetPortalAppIDByAPIKey(extractAPIKey(headers)); ok {
+			return apiKeyPortalAppID, nil
+		}
+	}
+	return portalAppID, err
+}
+
 // getPortalApp fetches the PortalApp from the portal app store.
 //   - Returns the PortalApp and a bool indicating if it was found.
 func (a *authHandler) getPortalApp(portalAppID store.PortalAppID) (*store.PortalApp, bool) {
 	return a.portalAppStore.GetPortalApp(portalAppID)
 }
 
-// checkPortalAppAuthorized performs all configured authorization checks on the request.
-//   - Returns nil if no authorization is required (Auth is nil or APIKey is empty)
-//   - Otherwise, performs API Key authorization
-func (a *authHandler) checkPortalAppAuthorized(headers http.Header, portalApp *store.PortalApp) error {
-	// If portal app does not require API key authorization, portalApp.Auth will be nil
-	// and no authorization will be performed by PEAS
-	if portalApp.Auth == nil || portalApp.Auth.APIKey == "" {
+// checkPortalAppAuthorized performs the authorization check for the portal app's auth type.
+//   - Returns errClientIPNotAllowed if the portal app has allowed CIDRs that do not contain the client IP
+//   - Returns nil if no authorization is required (Auth and AccountAuth are nil), unless the plan requires auth
+//   - Treats required auth with an empty API key, or a plan requiring auth for a portal app with no auth,
+//     as a misconfiguration (see checkPortalAppMisconfigured)
+//   - Returns errUnauthorized if no authorizer is configured
+//   - Otherwise, tries each authorizer in order, and returns errUnauthorized if none authorizes the request
+func (a *authHandler) checkPortalAppAuthorized(
+	headers http.Header,
+	path string,
+	clientIP netip.Addr,
+	portalApp *store.PortalApp,
+) error {
+	// The IP allowlist is checked first, so requests from outside it cannot tell whether their credentials are valid
+	if err := a.ipAllowlistAuthorizer.authorizeRequest(headers, path, clientIP, portalApp); err != nil {
+		return err
+	}
+
+	authType := getAuthType(portalApp)
+	switch authType {
+	case authTypeNone:
+		if a.authRequiredPlans.requiresAuth(portalApp) {
+			return a.checkPortalAppMisconfigured(portalApp, metrics.PortalAppMisconfiguredReasonPlanRequiresAuth)
+		}
 		return nil
+	case authTypeMisconfigured:
+		return a.checkPortalAppMisconfigured(portalApp, metrics.PortalAppMisconfiguredReasonEmptyAPIKey)
+	}
+
+	if len(a.authorizers) == 0 {
+		// The portal app cannot be authorized, so the request is denied regardless of DenyMisconfiguredPortalApps
+		metrics.RecordPortalAppMisconfigured(string(portalApp.ID), metrics.PortalAppMisconfiguredReasonNoAuthorizer)
+		a.logger.Error().
+			Str("portal_app_id", string(portalApp.ID)).
+			Str("auth_type", string(authType)).
+			Msg("🚨 no authorizer configured: rejecting the request")
+		return errUnauthorized
+	}
+
+	// Each authorizer only accepts requests for the auth type it evaluates (e.g. AuthorizerAPIKey rejects HMAC portal apps),
+	// so the order only determines how many authorizers are tried.
+	for _, authorizer := range a.authorizers {
+		if err := authorizer.authorizeRequest(headers, path, clientIP, portalApp); err == nil {
+			return nil
+		}
 	}
+	return errUnauthorized
+}
+
+// checkPortalAppMisconfigured handles a misconfigured portal app that would otherwise silently become public:
+//   - PortalAppMisconfiguredReasonEmptyAPIKey: the portal app requires API key auth but has an empty API key
+//   - PortalAppMisconfiguredReasonPlanRequiresAuth: the portal app's plan requires auth but the portal app requires none
+//
+// Records the misconfiguration and logs an error.
+// Returns errUnauthorized if misconfigured portal apps are denied, otherwise nil.
+func (a *authHandler) checkPortalAppMisconfigured(portalApp *store.PortalApp, reason string) error {
+	metrics.RecordPortalAppMisconfigured(string(portalApp.ID), reason)
+	a.logger.Error().
+		Str("portal_app_id", string(portalApp.ID)).
+		Str("account_id", string(portalApp.AccountID)).
+		Str("plan_type", string(portalApp.PlanType)).
+		Str("reason", reason).
+		Bool("denied", a.denyMisconfiguredPortalApps).
+		Msg("🚨 portal app is misconfigured and would be public: check the portal app's configuration")
 
-	// Otherwise, perform API Key authorization
-	return a.apiKeyAuthorizer.authorizeRequest(headers, portalApp)
+	if a.denyMisconfiguredPortalApps {
+		return errUnauthorized
+	}
+	return nil
 }
 
 // checkAccountRateLimited checks if the account is rate limited.
-//   - Returns nil if the account is not eligible for rate limiting.
-//   - Returns an error if the account is rate limited.
-func (a *authHandler) checkAccountRateLimited(portalApp *store.PortalApp) error {
-	// If no rate limit is configured for this portal app, allow the request
-	if portalApp.RateLimit == nil {
+//   - Returns errAccountRequestCeilingExceeded if the account exceeded the per-second request ceiling, regardless of plan.
+//   - Returns DecisionOK if the account is not eligible for rate limiting (see rateLimitStore.IsAccountRateLimitable).
+//   - Returns DecisionOK if the request is an internal health check that bypasses rate limiting.
+//   - Returns DecisionWarn or DecisionThrottle if the account is approaching or over its soft limit.
+//   - Returns errAccountRateLimited if the account is rate limited (blocked).
+//   - Returns errAccountRateLimited if the portal app is over its own monthly limit, even if the account is not,
+//     or if the account is not rate-limitable: cold start and failure mode denials do not apply to such portal apps.
+//   - Returns errRateLimitStoreColdStart if the store has not loaded yet and cold start denial is enabled.
+//   - Returns errRateLimitStoreUnavailable if the store is unavailable and the failure mode is fail_closed.
+func (a *authHandler) checkAccountRateLimited(headers http.Header, portalApp *store.PortalApp) (ratelimit.Decision, error) {
+	// If the account exceeded the per-second request ceiling, deny the request regardless of its plan
+	if a.accountRequestCeiling != nil && !a.accountRequestCeiling.allow(portalApp.AccountID) {
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), string(portalApp.PlanType), "request_ceiling_exceeded")
+		return ratelimit.DecisionBlock, errAccountRequestCeilingExceeded
+	}
+
+	// If neither the account nor the portal app is subject to monthly rate limits, allow the request.
+	// A rate limit only enforced outside of PEAS (e.g. a daily user limit) does not enable cold start or failure mode denials.
+	accountRateLimitable := a.rateLimitStore.IsAccountRateLimitable(portalApp)
+	if !accountRateLimitable && !portalApp.HasPortalAppMonthlyLimit() {
 		metrics.RecordRateLimitCheck(string(portalApp.AccountID), "", "no_limit_configured")
-		return nil
+		return ratelimit.DecisionOK, nil
 	}
 
-	// Check if the account has exceeded their rate limit
 	planType := string(portalApp.PlanType)
-	if a.rateLimitStore.IsAccountRateLimited(portalApp.AccountID) {
+
+	// If the request is an internal health check, allow the request regardless of the account's usage
+	if a.healthCheckBypass.matches(headers, portalApp.AccountID) {
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "health_bypass")
+		return ratelimit.DecisionOK, nil
+	}
+
+	// If only the portal app has a monthly limit of its own, only check it:
+	// cold start and failure mode denials apply to rate-limitable accounts only.
+	if !accountRateLimitable {
+		return a.checkPortalAppRateLimited(portalApp)
+	}
+
+	// If the rate limit store has not loaded yet, rate limits cannot be enforced: deny the request if configured
+	if a.rateLimitColdStartDeny && !a.rateLimitStore.HasLoaded() {
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "store_cold_start")
+		return ratelimit.DecisionBlock, errRateLimitStoreColdStart
+	}
+
+	// If the rate limit store's data is missing or stale, apply the configured failure mode
+	if a.rateLimitFailureMode == RateLimitFailClosed && !a.rateLimitStore.IsAvailable() {
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "store_unavailable")
+		return ratelimit.DecisionOK, errRateLimitStoreUnavailable
+	}
+
+	// Check if the account has crossed any of its rate limit thresholds
+	decision := a.rateLimitStore.GetAccountRateLimitDecision(portalApp.AccountID)
+
+	// Check if the portal app is over its own monthly limit, so a single portal app cannot consume the whole account budget
+	if decision != ratelimit.DecisionBlock &&
+		portalApp.HasPortalAppMonthlyLimit() &&
+		a.rateLimitStore.IsPortalAppRateLimited(portalApp.ID) {
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "portal_app_rate_limited")
+		return ratelimit.DecisionBlock, errAccountRateLimited
+	}
+
+	switch decision {
+	case ratelimit.DecisionBlock:
 		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "rate_limited")
-		return fmt.Errorf("account is rate limited")
+		return decision, errAccountRateLimited
+
+	case ratelimit.DecisionThrottle:
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "throttled")
+		return decision, nil
+
+	case ratelimit.DecisionWarn:
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "warned")
+		return decision, nil
+
+	default:
+		// Account is within rate limits, allow the request
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "allowed")
+		return ratelimit.DecisionOK, nil
 	}
+}
 
-	// Account is within rate limits, allow the request
-	metrics.RecordRateLimitCheck(string(portalApp.AccountID), planType, "allowed")
-	return nil
+// checkPortalAppRateLimited checks if the portal app is over its own monthly limit, for a portal app whose account is not rate-limitable.
+//   - Returns errAccountRateLimited if the portal app is rate limited (blocked).
+func (a *authHandler) checkPortalAppRateLimited(portalApp *store.PortalApp) (ratelimit.Decision, error) {
+	if a.rateLimitStore.IsPortalAppRateLimited(portalApp.ID) {
+		metrics.RecordRateLimitCheck(string(portalApp.AccountID), string(portalApp.PlanType), "portal_app_rate_limited")
+		return ratelimit.DecisionBlock, errAccountRateLimited
+	}
+
+	metrics.RecordRateLimitCheck(string(portalApp.AccountID), string(portalApp.PlanType), "allowed")
+	return ratelimit.DecisionOK, nil
 }
 
 // getHTTPHeaders sets all HTTP headers required by the PATH service on the request being forwarded.
-//   - Adds portal app ID header on all requests ("Portal-Application-ID: <id>")
-//   - Adds account ID header on all requests ("Portal-Account-ID: <id>")
-func (a *authHandler) getHTTPHeaders(portalApp *store.PortalApp) []*envoy_core.HeaderValueOption {
+//   - Adds portal app ID header on all requests ("Portal-Application-ID: <id>", or the configured name)
+//   - Adds account ID header on all requests ("Portal-Account-ID: <id>", or the configured name)
+//   - Adds rate limit status header for warned or throttled accounts ("Portal-RateLimit-Status: <warn|throttle>")
+//   - Adds relay cost header for requests that count as more than one relay ("Rl-Cost-<n>: <account id>")
+//   - Adds plan header if one is configured for the portal app's plan type (e.g. "Rl-Plan-Pro: <account id>")
+//   - Adds rate limit plan descriptor header for rate-limit-eligible portal apps ("Rl-Plan-Free" or "Rl-User-Limit-<n>": <account id>)
+//   - Adds rate limit decision header if enabled ("Portal-RateLimit-Decision: <decision>; ttl=<seconds>")
+//   - Adds auth cache TTL header if the portal app's TTL is positive ("Portal-Auth-Cache-TTL: <seconds>")
+//   - Adds rate limit reset header for rate-limit-eligible portal apps if enabled ("Portal-RateLimit-Reset-Seconds: <seconds>")
+//   - Adds plan name header for portal apps with a plan name if enabled ("Portal-Plan-Name: <plan name>")
+//   - Sets the configured append action on every header
+func (a *authHandler) getHTTPHeaders(
+	portalApp *store.PortalApp,
+	rateLimitDecision ratelimit.Decision,
+	relayCost int32,
+) []*envoy_core.HeaderValueOption {
 	headers := []*envoy_core.HeaderValueOption{
-		{
-			Header: &envoy_core.HeaderValue{
-				Key:   reqHeaderPortalAppID,
-				Value: string(portalApp.ID),
-			},
-		},
-		{
-			Header: &envoy_core.HeaderValue{
-				Key:   reqHeaderAccountID,
-				Value: string(portalApp.AccountID),
-			},
-		},
+		a.newHeaderValueOption(a.portalAppIDHeader, string(portalApp.ID)),
+	}
+	headers = append(headers, a.getAccountIDHeaders(portalApp)...)
+
+	if rateLimitDecision == ratelimit.DecisionWarn || rateLimitDecision == ratelimit.DecisionThrottle {
+		headers = append(headers, a.newHeaderValueOption(reqHeaderRateLimitStatus, string(rateLimitDecision)))
+	}
+
+	if relayCost > 1 {
+		headers = append(headers, a.newHeaderValueOption(
+			fmt.Sprintf("%s%d", reqHeaderRelayCostPrefix, relayCost),
+			string(portalApp.AccountID),
+		))
+	}
+
+	if planHeader, ok := a.planHeaders[portalApp.PlanType]; ok {
+		headers = append(headers, a.newHeaderValueOption(planHeader, string(portalApp.AccountID)))
+	}
+
+	headers = append(headers, a.getRateLimitRequestHeaders(portalApp)...)
+
+	if decisionHeader, ok := a.getRateLimitDecisionHeader(portalApp.ID, rateLimitDecision); ok {
+		headers = append(headers, decisionHeader)
+	}
+
+	if cacheTTLHeader, ok := a.getAuthCacheTTLHeader(portalApp); ok {
+		headers = append(headers, cacheTTLHeader)
+	}
+
+	if a.rateLimitTierHeaderEnabled {
+		if tier, ok := getRateLimitTier(portalApp); ok {
+			headers = append(headers, a.newHeaderValueOption(reqHeaderRateLimitTier, tier))
+		}
+	}
+
+	if resetHeader, ok := a.getRateLimitResetHeader(portalApp); ok {
+		headers = append(headers, resetHeader)
+	}
+
+	if a.planNameHeaderEnabled && portalApp.PlanName != "" {
+		headers = append(headers, a.newHeaderValueOption(reqHeaderPlanName, portalApp.PlanName))
 	}
 
 	return headers
 }
 
+// getRateLimitDecisionHeader returns the "Portal-RateLimit-Decision" header for the portal app's decision.
+//   - The TTL hint is the portal app's override, if any, or the default TTL.
+//   - Returns false if the rate limit decision header is not enabled for the portal app.
+func (a *authHandler) getRateLimitDecisionHeader(
+	portalAppID store.PortalAppID,
+	rateLimitDecision ratelimit.Decision,
+) (*envoy_core.HeaderValueOption, bool) {
+	ttl := a.rateLimitDecisionTTLOverrides.getTTL(portalAppID, a.rateLimitDecisionHeaderTTL)
+	if ttl <= 0 {
+		return nil, false
+	}
+
+	value := fmt.Sprintf("%s; ttl=%d", rateLimitDecision, int64(ttl/time.Second))
+	return a.newHeaderValueOption(reqHeaderRateLimitDecision, value), true
+}
+
+// getRateLimitResetHeader returns the "Portal-RateLimit-Reset-Seconds" header for the portal app.
+//   - Returns false if the rate limit reset header is not enabled or the portal app is not rate-limit-eligible.
+func (a *authHandler) getRateLimitResetHeader(portalApp *store.PortalApp) (*envoy_core.HeaderValueOption, bool) {
+	if !a.rateLimitResetHeaderEnabled || portalApp.RateLimit == nil {
+		return nil, false
+	}
+
+	value := strconv.FormatInt(getRateLimitResetSeconds(time.Now()), 10)
+	return a.newHeaderValueOption(reqHeaderRateLimitResetSeconds, value), true
+}
+
+// newHeaderValueOption returns a HeaderValueOption with the configured append action set.
+func (a *authHandler) newHeaderValueOption(key, value string) *envoy_core.HeaderValueOption {
+	return &envoy_core.HeaderValueOption{
+		Header: &envoy_core.HeaderValue{
+			Key:   key,
+			Value: value,
+		},
+		AppendAction: a.headerAppendAction,
+	}
+}
+
+// hasAuthority returns true if the request has a non-empty Host or :authority header.
+func hasAuthority(req *envoy_auth.AttributeContext_HttpRequest) bool {
+	if req.GetHost() != "" {
+		return true
+	}
+	headers := req.GetHeaders()
+	return headers[":authority"] != "" || headers["host"] != ""
+}
+
 // getDeniedCheckResponse returns a CheckResponse with denied status and error message.
 //   - Sets PermissionDenied code and error message in response.
 func getDeniedCheckResponse(err string, httpCode envoy_type.StatusCode) *envoy_auth.CheckResponse {
@@ -300,6 +1272,74 @@ func getDeniedCheckResponse(err string, httpCode envoy_type.StatusCode) *envoy_a
 	}
 }
 
+// getLocalizedDeniedCheckResponse returns a denied CheckResponse with a localized body message.
+//   - Body message is selected using the request's Accept-Language header, falling back to English.
+//   - Status message is always left in English so it remains consistent in Envoy logs.
+func (a *authHandler) getLocalizedDeniedCheckResponse(
+	headers http.Header,
+	errorType string,
+	err string,
+	httpCode envoy_type.StatusCode,
+) *envoy_auth.CheckResponse {
+	resp := getDeniedCheckResponse(err, httpCode)
+
+	if message, ok := a.denialMessages.localize(headers.Get(reqHeaderAcceptLanguage), errorType); ok {
+		resp.GetDeniedResponse().Body = fmt.Sprintf(errBody, httpCode, message)
+	}
+
+	return resp
+}
+
+// getMissingPortalAppIDCheckResponse returns a denied CheckResponse for a request with no portal app ID.
+//   - Uses the configured status code (default 400) and body message, if set.
+//   - Status message is always left as the original error so it remains consistent in Envoy logs.
+func (a *authHandler) getMissingPortalAppIDCheckResponse(err string) *envoy_auth.CheckResponse {
+	resp := getDeniedCheckResponse(err, a.missingPortalAppIDStatusCode)
+
+	if a.missingPortalAppIDMessage != "" {
+		resp.GetDeniedResponse().Body = fmt.Sprintf(errBody, a.missingPortalAppIDStatusCode, a.missingPortalAppIDMessage)
+	}
+
+	return resp
+}
+
+// getBillingDelinquentCheckResponse returns a 402 Payment Required CheckResponse for a billing-delinquent account.
+//   - Body message is the configured billing-delinquent message.
+//   - Status message is always left as the original error so it remains consistent in Envoy logs.
+func (a *authHandler) getBillingDelinquentCheckResponse() *envoy_auth.CheckResponse {
+	resp := getDeniedCheckResponse(errAccountBillingDelinquent.Error(), envoy_type.StatusCode_PaymentRequired)
+	resp.GetDeniedResponse().Body = fmt.Sprintf(errBody, envoy_type.StatusCode_PaymentRequired, a.billingDelinquentMessage)
+	return resp
+}
+
+// getInternalErrorCheckResponse returns a CheckResponse for an unexpected internal error.
+//   - Sets Internal code and a generic HTTP 500 body that does not leak error details.
+func getInternalErrorCheckResponse() *envoy_auth.CheckResponse {
+	return &envoy_auth.CheckResponse{
+		Status: &status.Status{
+			Code:    int32(codes.Internal),
+			Message: internalErrorMessage,
+		},
+		HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+			DeniedResponse: &envoy_auth.DeniedHttpResponse{
+				Status: &envoy_type.HttpStatus{
+					Code: envoy_type.StatusCode_InternalServerError,
+				},
+				Body: fmt.Sprintf(errBody, envoy_type.StatusCode_InternalServerError, internalErrorMessage),
+			},
+		},
+	}
+}
+
+// getHTTPStatusCode returns the HTTP status code returned to the client for a CheckResponse.
+//   - Returns the denied response status code, or 200 for OK responses.
+func getHTTPStatusCode(resp *envoy_auth.CheckResponse) int32 {
+	if deniedResponse := resp.GetDeniedResponse(); deniedResponse != nil {
+		return int32(deniedResponse.GetStatus().GetCode())
+	}
+	return int32(envoy_type.StatusCode_OK)
+}
+
 // getOKCheckResponse returns a CheckResponse with OK status and provided headers.
 //   - Sets OK code and attaches provided headers to response.
 func getOKCheckResponse(headers []*envoy_core.HeaderValueOption) *envoy_auth.CheckResponse {
@@ -12,6 +12,7 @@ package auth
 import (
 	reflect "reflect"
 
+	ratelimit "github.com/buildwithgrove/path-external-auth-server/ratelimit"
 	store "github.com/buildwithgrove/path-external-auth-server/store"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -20,7 +21,6 @@ import (
 type MockportalAppStore struct {
 	ctrl     *gomock.Controller
 	recorder *MockportalAppStoreMockRecorder
-	isgomock struct{}
 }
 
 // MockportalAppStoreMockRecorder is the mock recorder for MockportalAppStore.
@@ -55,11 +55,25 @@ func (mr *MockportalAppStoreMockRecorder) GetPortalApp(portalAppID any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPortalApp", reflect.TypeOf((*MockportalAppStore)(nil).GetPortalApp), portalAppID)
 }
 
+// GetPortalAppIDByAPIKey mocks base method.
+func (m *MockportalAppStore) GetPortalAppIDByAPIKey(apiKey string) (store.PortalAppID, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPortalAppIDByAPIKey", apiKey)
+	ret0, _ := ret[0].(store.PortalAppID)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// GetPortalAppIDByAPIKey indicates an expected call of GetPortalAppIDByAPIKey.
+func (mr *MockportalAppStoreMockRecorder) GetPortalAppIDByAPIKey(apiKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPortalAppIDByAPIKey", reflect.TypeOf((*MockportalAppStore)(nil).GetPortalAppIDByAPIKey), apiKey)
+}
+
 // MockrateLimitStore is a mock of rateLimitStore interface.
 type MockrateLimitStore struct {
 	ctrl     *gomock.Controller
 	recorder *MockrateLimitStoreMockRecorder
-	isgomock struct{}
 }
 
 // MockrateLimitStoreMockRecorder is the mock recorder for MockrateLimitStore.
@@ -79,16 +93,72 @@ func (m *MockrateLimitStore) EXPECT() *MockrateLimitStoreMockRecorder {
 	return m.recorder
 }
 
-// IsAccountRateLimited mocks base method.
-func (m *MockrateLimitStore) IsAccountRateLimited(accountID store.AccountID) bool {
+// GetAccountRateLimitDecision mocks base method.
+func (m *MockrateLimitStore) GetAccountRateLimitDecision(accountID store.AccountID) ratelimit.Decision {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountRateLimitDecision", accountID)
+	ret0, _ := ret[0].(ratelimit.Decision)
+	return ret0
+}
+
+// GetAccountRateLimitDecision indicates an expected call of GetAccountRateLimitDecision.
+func (mr *MockrateLimitStoreMockRecorder) GetAccountRateLimitDecision(accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountRateLimitDecision", reflect.TypeOf((*MockrateLimitStore)(nil).GetAccountRateLimitDecision), accountID)
+}
+
+// HasLoaded mocks base method.
+func (m *MockrateLimitStore) HasLoaded() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HasLoaded")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// HasLoaded indicates an expected call of HasLoaded.
+func (mr *MockrateLimitStoreMockRecorder) HasLoaded() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasLoaded", reflect.TypeOf((*MockrateLimitStore)(nil).HasLoaded))
+}
+
+// IsAccountRateLimitable mocks base method.
+func (m *MockrateLimitStore) IsAccountRateLimitable(portalApp *store.PortalApp) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAccountRateLimitable", portalApp)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsAccountRateLimitable indicates an expected call of IsAccountRateLimitable.
+func (mr *MockrateLimitStoreMockRecorder) IsAccountRateLimitable(portalApp any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAccountRateLimitable", reflect.TypeOf((*MockrateLimitStore)(nil).IsAccountRateLimitable), portalApp)
+}
+
+// IsAvailable mocks base method.
+func (m *MockrateLimitStore) IsAvailable() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAvailable")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsAvailable indicates an expected call of IsAvailable.
+func (mr *MockrateLimitStoreMockRecorder) IsAvailable() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAvailable", reflect.TypeOf((*MockrateLimitStore)(nil).IsAvailable))
+}
+
+// IsPortalAppRateLimited mocks base method.
+func (m *MockrateLimitStore) IsPortalAppRateLimited(portalAppID store.PortalAppID) bool {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsAccountRateLimited", accountID)
+	ret := m.ctrl.Call(m, "IsPortalAppRateLimited", portalAppID)
 	ret0, _ := ret[0].(bool)
 	return ret0
 }
 
-// IsAccountRateLimited indicates an expected call of IsAccountRateLimited.
-func (mr *MockrateLimitStoreMockRecorder) IsAccountRateLimited(accountID any) *gomock.Call {
+// IsPortalAppRateLimited indicates an expected call of IsPortalAppRateLimited.
+func (mr *MockrateLimitStoreMockRecorder) IsPortalAppRateLimited(portalAppID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAccountRateLimited", reflect.TypeOf((*MockrateLimitStore)(nil).IsAccountRateLimited), accountID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsPortalAppRateLimited", reflect.TypeOf((*MockrateLimitStore)(nil).IsPortalAppRateLimited), portalAppID)
 }
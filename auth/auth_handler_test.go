@@ -3,21 +3,35 @@ package auth
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"testing"
+	"time"
 
 	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
 	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/codes"
 
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
 	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
 	"github.com/buildwithgrove/path-external-auth-server/store"
 )
 
+// testDenialMessages are the localized denial messages used by Check test cases.
+var testDenialMessages = LocalizedDenialMessages{
+	"es": {
+		"portal_app_not_found": "aplicación del portal no encontrada",
+		"unauthorized":         "no autorizado",
+		"rate_limited":         "Esta cuenta tiene un límite de uso.",
+	},
+}
+
 func Test_Check(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -25,6 +39,21 @@ func Test_Check(t *testing.T) {
 		expectedResp        *envoy_auth.CheckResponse
 		portalAppID         store.PortalAppID
 		mockPortalAppReturn *store.PortalApp
+		rateLimitDecision   ratelimit.Decision
+		denialMessages      LocalizedDenialMessages
+		// Rate limit store availability is only checked when the failure mode is fail_closed
+		rateLimitFailureMode       RateLimitFailureMode
+		rateLimitStoreUnavailable  bool
+		relayCosts                 *RelayCosts
+		rateLimitDecisionHeaderTTL time.Duration
+		// The missing portal app ID response is only configured if the status code is set
+		missingPortalAppIDStatusCode envoy_type.StatusCode
+		missingPortalAppIDMessage    string
+		// Rate limit store is not checked for requests matching the health check bypass
+		healthCheckBypass     *HealthCheckBypass
+		expectRateLimitBypass bool
+		requireAuthority      bool
+		httpsRequirement      *HTTPSRequirement
 	}{
 		{
 			name: "should return OK check response if check request is valid and user is authorized to access portal app with rate limit headers set",
@@ -45,8 +74,9 @@ func Test_Check(t *testing.T) {
 				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
 					OkResponse: &envoy_auth.OkHttpResponse{
 						Headers: []*envoy_core.HeaderValueOption{
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}},
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
 						},
 					},
 				},
@@ -82,8 +112,8 @@ func Test_Check(t *testing.T) {
 				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
 					OkResponse: &envoy_auth.OkHttpResponse{
 						Headers: []*envoy_core.HeaderValueOption{
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_unlimited"}},
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_2"}},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_unlimited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_2"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
 						},
 					},
 				},
@@ -93,7 +123,7 @@ func Test_Check(t *testing.T) {
 				ID:        "portal_app_unlimited",
 				AccountID: "account_2",
 				Auth: &store.Auth{
-					APIKey: "api_key_good",
+					APIKeys: []string{"api_key_good"},
 				},
 				RateLimit: nil, // No rate limiting
 			},
@@ -120,8 +150,8 @@ func Test_Check(t *testing.T) {
 				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
 					OkResponse: &envoy_auth.OkHttpResponse{
 						Headers: []*envoy_core.HeaderValueOption{
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_api_key"}},
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_3"}},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_api_key"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_3"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
 						},
 					},
 				},
@@ -131,7 +161,7 @@ func Test_Check(t *testing.T) {
 				ID:        "portal_app_api_key",
 				AccountID: "account_3",
 				Auth: &store.Auth{
-					APIKey: "api_key_good",
+					APIKeys: []string{"api_key_good"},
 				},
 			},
 		},
@@ -154,8 +184,8 @@ func Test_Check(t *testing.T) {
 				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
 					OkResponse: &envoy_auth.OkHttpResponse{
 						Headers: []*envoy_core.HeaderValueOption{
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_public"}},
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_4"}},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_public"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_4"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
 						},
 					},
 				},
@@ -189,8 +219,8 @@ func Test_Check(t *testing.T) {
 				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
 					OkResponse: &envoy_auth.OkHttpResponse{
 						Headers: []*envoy_core.HeaderValueOption{
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_id_from_header"}},
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_5"}},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_id_from_header"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_5"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
 						},
 					},
 				},
@@ -262,7 +292,7 @@ func Test_Check(t *testing.T) {
 			mockPortalAppReturn: &store.PortalApp{
 				ID: "portal_app_api_key",
 				Auth: &store.Auth{
-					APIKey: "api_key_not_this_one",
+					APIKeys: []string{"api_key_not_this_one"},
 				},
 			},
 		},
@@ -299,14 +329,15 @@ func Test_Check(t *testing.T) {
 				Auth:      nil,
 				RateLimit: &store.RateLimit{},
 			},
+			rateLimitDecision: ratelimit.DecisionBlock,
 		},
 		{
-			name: "should return OK check response for unlimited plan with no specific limit",
+			name: "should return OK check response with warn status header if account crossed the warn threshold",
 			checkReq: &envoy_auth.CheckRequest{
 				Attributes: &envoy_auth.AttributeContext{
 					Request: &envoy_auth.AttributeContext_Request{
 						Http: &envoy_auth.AttributeContext_HttpRequest{
-							Path: "/v1/portal_app_unlimited_no_limit",
+							Path: "/v1/portal_app_warned",
 						},
 					},
 				},
@@ -319,58 +350,2883 @@ func Test_Check(t *testing.T) {
 				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
 					OkResponse: &envoy_auth.OkHttpResponse{
 						Headers: []*envoy_core.HeaderValueOption{
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_unlimited_no_limit"}},
-							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_unlimited"}},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_warned"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_warned"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitStatus, Value: "warn"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_warned"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
 						},
 					},
 				},
 			},
-			portalAppID: "portal_app_unlimited_no_limit",
+			portalAppID: "portal_app_warned",
 			mockPortalAppReturn: &store.PortalApp{
-				ID:        "portal_app_unlimited_no_limit",
-				AccountID: "account_unlimited",
-				PlanType:  grovedb.PlanUnlimited_DatabaseType,
-				Auth:      nil,
-				RateLimit: &store.RateLimit{
-					MonthlyUserLimit: 0, // No specific limit
+				ID:        "portal_app_warned",
+				AccountID: "account_warned",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionWarn,
+		},
+		{
+			name: "should return OK check response with throttle status header if account crossed the throttle threshold",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_throttled",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_throttled"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_throttled"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitStatus, Value: "throttle"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_throttled"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
 				},
 			},
+			portalAppID: "portal_app_throttled",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_throttled",
+				AccountID: "account_throttled",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionThrottle,
 		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			c := require.New(t)
-
-			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
-
-			mockPortalAppStore := NewMockportalAppStore(ctrl)
-			mockRateLimitStore := NewMockrateLimitStore(ctrl)
-			if test.portalAppID != "" {
-				mockPortalAppStore.EXPECT().GetPortalApp(test.portalAppID).Return(test.mockPortalAppReturn, test.mockPortalAppReturn != nil)
-			}
-
-			// Set up rate limit store expectations
-			if test.mockPortalAppReturn != nil && test.mockPortalAppReturn.RateLimit != nil {
-				// Determine if account should be rate limited based on test case
-				isRateLimited := false
-				if test.name == "should return denied check response if account is rate limited" {
-					isRateLimited = true
-				}
-				mockRateLimitStore.EXPECT().IsAccountRateLimited(test.mockPortalAppReturn.AccountID).Return(isRateLimited)
-			}
-
-			authHandler := NewAuthHandler(
-				polyzero.NewLogger(),
-				mockPortalAppStore,
-				mockRateLimitStore,
-				&AuthorizerAPIKey{},
-			)
-
-			resp, err := authHandler.Check(context.Background(), test.checkReq)
-			c.NoError(err)
-			c.Equal(test.expectedResp, resp)
-		})
-	}
+		{
+			name: "should return localized denied check response body if portal app not found and Accept-Language matches",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_not_found",
+							Headers: map[string]string{
+								reqHeaderAcceptLanguage: "es-ES,es;q=0.9,en;q=0.8",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "portal app not found",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_NotFound,
+						},
+						Body: `{"code": 404, "message": "aplicación del portal no encontrada"}`,
+					},
+				},
+			},
+			portalAppID:         "portal_app_not_found",
+			mockPortalAppReturn: nil,
+			denialMessages:      testDenialMessages,
+		},
+		{
+			name: "should return localized denied check response body if user is not authorized and Accept-Language matches",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_api_key",
+							Headers: map[string]string{
+								authHeaderKey:           "api_key_123",
+								reqHeaderAcceptLanguage: "es",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: errUnauthorized.Error(),
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_Unauthorized,
+						},
+						Body: `{"code": 401, "message": "no autorizado"}`,
+					},
+				},
+			},
+			portalAppID: "portal_app_api_key",
+			mockPortalAppReturn: &store.PortalApp{
+				ID: "portal_app_api_key",
+				Auth: &store.Auth{
+					APIKeys: []string{"api_key_not_this_one"},
+				},
+			},
+			denialMessages: testDenialMessages,
+		},
+		{
+			name: "should return localized denied check response body if account is rate limited and Accept-Language matches",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+							Headers: map[string]string{
+								reqHeaderAcceptLanguage: "es-MX",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: accountRateLimitMessage,
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_TooManyRequests,
+						},
+						Body: `{"code": 429, "message": "Esta cuenta tiene un límite de uso."}`,
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_rate_limited",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionBlock,
+			denialMessages:    testDenialMessages,
+		},
+		{
+			name: "should fall back to English denied check response body if Accept-Language has no localized message",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+							Headers: map[string]string{
+								reqHeaderAcceptLanguage: "fr-FR,fr;q=0.9",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: accountRateLimitMessage,
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_TooManyRequests,
+						},
+						Body: fmt.Sprintf(`{"code": 429, "message": "%s"}`, accountRateLimitMessage),
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_rate_limited",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionBlock,
+			denialMessages:    testDenialMessages,
+		},
+		{
+			name: "should return OK check response if rate limit store is unavailable and failure mode is fail_open",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitFailureMode:      RateLimitFailOpen,
+			rateLimitStoreUnavailable: true,
+		},
+		{
+			name: "should return service unavailable denied check response if rate limit store is unavailable and failure mode is fail_closed",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: rateLimitUnavailableMessage,
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_ServiceUnavailable,
+						},
+						Body: fmt.Sprintf(`{"code": 503, "message": "%s"}`, rateLimitUnavailableMessage),
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitFailureMode:      RateLimitFailClosed,
+			rateLimitStoreUnavailable: true,
+		},
+		{
+			name: "should return OK check response if rate limit store is available and failure mode is fail_closed",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitFailureMode: RateLimitFailClosed,
+		},
+		{
+			name: "should return OK check response if rate limit store is unavailable and failure mode is fail_closed but account has no rate limit",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_public",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_public"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_4"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_public",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_public",
+				AccountID: "account_4",
+				RateLimit: nil, // No rate limiting
+			},
+			rateLimitFailureMode:      RateLimitFailClosed,
+			rateLimitStoreUnavailable: true,
+		},
+		{
+			name: "should return OK check response with relay cost header for a JSON-RPC method with a configured cost",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+							Body: `{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}`,
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: "Rl-Cost-5", Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			relayCosts: &RelayCosts{
+				Default: map[string]int32{"eth_getLogs": 5},
+			},
+		},
+		{
+			name: "should return OK check response without relay cost header for a JSON-RPC method without a configured cost",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+							Body: `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`,
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			relayCosts: &RelayCosts{
+				Default: map[string]int32{"eth_getLogs": 5},
+			},
+		},
+		{
+			name: "should return OK check response with rate limit decision header if enabled",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitDecision, Value: "ok; ttl=30"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecisionHeaderTTL: 30 * time.Second,
+		},
+		{
+			name: "should return OK check response with rate limit status and decision headers for warned account if enabled",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitStatus, Value: "warn"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitDecision, Value: "warn; ttl=30"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision:          ratelimit.DecisionWarn,
+			rateLimitDecisionHeaderTTL: 30 * time.Second,
+		},
+		{
+			name: "should return denied check response with rate limit decision header for rate limited account if enabled",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: accountRateLimitMessage,
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_TooManyRequests,
+						},
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitDecision, Value: "block; ttl=60"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+						Body: fmt.Sprintf(`{"code": 429, "message": "%s"}`, accountRateLimitMessage),
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_rate_limited",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision:          ratelimit.DecisionBlock,
+			rateLimitDecisionHeaderTTL: time.Minute,
+		},
+		{
+			name: "should return bad request check response if HTTP request is not provided",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "HTTP request not found",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_BadRequest,
+						},
+						Body: `{"code": 400, "message": "HTTP request not found"}`,
+					},
+				},
+			},
+		},
+		{
+			name: "should return bad request check response if path is not provided",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "path not provided",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_BadRequest,
+						},
+						Body: `{"code": 400, "message": "path not provided"}`,
+					},
+				},
+			},
+		},
+		{
+			name: "should return 400 denied check response by default for root path with no portal app ID",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "portal app ID not provided in header or path",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_BadRequest,
+						},
+						Body: `{"code": 400, "message": "portal app ID not provided in header or path"}`,
+					},
+				},
+			},
+		},
+		{
+			name: "should return configured denied check response for root path with no portal app ID",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "portal app ID not provided in header or path",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_NotFound,
+						},
+						Body: `{"code": 404, "message": "No portal app ID provided. See \"https://docs.grove.city\" to get started."}`,
+					},
+				},
+			},
+			missingPortalAppIDStatusCode: envoy_type.StatusCode_NotFound,
+			missingPortalAppIDMessage:    `No portal app ID provided. See "https://docs.grove.city" to get started.`,
+		},
+		{
+			name: "should keep default body message if only the status code is configured for missing portal app ID",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "portal app ID not provided in header or path",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_NotFound,
+						},
+						Body: `{"code": 404, "message": "portal app ID not provided in header or path"}`,
+					},
+				},
+			},
+			missingPortalAppIDStatusCode: envoy_type.StatusCode_NotFound,
+		},
+		{
+			name: "should return bad request check response if authority is required and not provided",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "host or authority not provided",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_BadRequest,
+						},
+						Body: `{"code": 400, "message": "host or authority not provided"}`,
+					},
+				},
+			},
+			requireAuthority: true,
+		},
+		{
+			name: "should return OK check response if authority is required and provided as the request host",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+							Host: "eth.rpc.grove.city",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			requireAuthority: true,
+		},
+		{
+			name: "should return OK check response if authority is required and provided as the :authority header",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_free",
+							Headers: map[string]string{
+								":authority": "eth.rpc.grove.city",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_free"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_1"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_free",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			requireAuthority: true,
+		},
+		{
+			name: "should return upgrade required check response for http request to portal app requiring HTTPS",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:   "/v1/portal_app_https",
+							Scheme: "http",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: "HTTPS is required for this portal app",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_UpgradeRequired,
+						},
+						Body: `{"code": 426, "message": "HTTPS is required for this portal app"}`,
+					},
+				},
+			},
+			portalAppID: "portal_app_https",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_https",
+				AccountID: "account_https",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+			},
+			httpsRequirement: &HTTPSRequirement{PortalAppIDs: map[store.PortalAppID]bool{"portal_app_https": true}},
+		},
+		{
+			name: "should return OK check response for https request to portal app requiring HTTPS",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:   "/v1/portal_app_https",
+							Scheme: "https",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_https"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_https"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_https",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_https",
+				AccountID: "account_https",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+			},
+			httpsRequirement: &HTTPSRequirement{PortalAppIDs: map[store.PortalAppID]bool{"portal_app_https": true}},
+		},
+		{
+			name: "should return OK check response for rate limited account if User-Agent matches health check bypass",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+							Headers: map[string]string{
+								reqHeaderUserAgent: "Grove-Healthcheck/1.2",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_rate_limited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_rate_limited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_rate_limited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_rate_limited",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionBlock,
+			healthCheckBypass: &HealthCheckBypass{
+				UserAgentPrefixes: []string{"Grove-Healthcheck/"},
+				Header:            "X-Health-Check",
+				HeaderValue:       "health_check_secret",
+				AccountIDs:        map[store.AccountID]bool{"account_rate_limited": true},
+			},
+			expectRateLimitBypass: true,
+		},
+		{
+			name: "should return OK check response for rate limited account if header matches health check bypass",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+							Headers: map[string]string{
+								"X-Health-Check": "health_check_secret",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_rate_limited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_rate_limited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderRateLimitPlanFree, Value: "account_rate_limited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_rate_limited",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionBlock,
+			healthCheckBypass: &HealthCheckBypass{
+				UserAgentPrefixes: []string{"Grove-Healthcheck/"},
+				Header:            "X-Health-Check",
+				HeaderValue:       "health_check_secret",
+				AccountIDs:        map[store.AccountID]bool{"account_rate_limited": true},
+			},
+			expectRateLimitBypass: true,
+		},
+		{
+			name: "should return denied check response for rate limited account if User-Agent does not match health check bypass",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+							Headers: map[string]string{
+								reqHeaderUserAgent: "curl/8.5.0",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: accountRateLimitMessage,
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_TooManyRequests,
+						},
+						Body: fmt.Sprintf(`{"code": 429, "message": "%s"}`, accountRateLimitMessage),
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_rate_limited",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionBlock,
+			healthCheckBypass: &HealthCheckBypass{
+				UserAgentPrefixes: []string{"Grove-Healthcheck/"},
+				Header:            "X-Health-Check",
+				HeaderValue:       "health_check_secret",
+				AccountIDs:        map[store.AccountID]bool{"account_rate_limited": true},
+			},
+			expectRateLimitBypass: false,
+		},
+		{
+			name: "should return denied check response for rate limited account if header value does not match health check bypass",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+							Headers: map[string]string{
+								"X-Health-Check": "wrong_secret",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: accountRateLimitMessage,
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_TooManyRequests,
+						},
+						Body: fmt.Sprintf(`{"code": 429, "message": "%s"}`, accountRateLimitMessage),
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_rate_limited",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionBlock,
+			healthCheckBypass: &HealthCheckBypass{
+				UserAgentPrefixes: []string{"Grove-Healthcheck/"},
+				Header:            "X-Health-Check",
+				HeaderValue:       "health_check_secret",
+				AccountIDs:        map[store.AccountID]bool{"account_rate_limited": true},
+			},
+			expectRateLimitBypass: false,
+		},
+		{
+			name: "should return denied check response if account is not allowed to use health check bypass",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_rate_limited",
+							Headers: map[string]string{
+								reqHeaderUserAgent: "Grove-Healthcheck/1.2",
+							},
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.PermissionDenied),
+					Message: accountRateLimitMessage,
+				},
+				HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+					DeniedResponse: &envoy_auth.DeniedHttpResponse{
+						Status: &envoy_type.HttpStatus{
+							Code: envoy_type.StatusCode_TooManyRequests,
+						},
+						Body: fmt.Sprintf(`{"code": 429, "message": "%s"}`, accountRateLimitMessage),
+					},
+				},
+			},
+			portalAppID: "portal_app_rate_limited",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_rate_limited",
+				AccountID: "account_other",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			rateLimitDecision: ratelimit.DecisionBlock,
+			healthCheckBypass: &HealthCheckBypass{
+				UserAgentPrefixes: []string{"Grove-Healthcheck/"},
+				Header:            "X-Health-Check",
+				HeaderValue:       "health_check_secret",
+				AccountIDs:        map[store.AccountID]bool{"account_rate_limited": true},
+			},
+			expectRateLimitBypass: false,
+		},
+		{
+			name: "should return OK check response for unlimited plan with no specific limit",
+			checkReq: &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_unlimited_no_limit",
+						},
+					},
+				},
+			},
+			expectedResp: &envoy_auth.CheckResponse{
+				Status: &status.Status{
+					Code:    int32(codes.OK),
+					Message: "ok",
+				},
+				HttpResponse: &envoy_auth.CheckResponse_OkResponse{
+					OkResponse: &envoy_auth.OkHttpResponse{
+						Headers: []*envoy_core.HeaderValueOption{
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderPortalAppID, Value: "portal_app_unlimited_no_limit"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+							{Header: &envoy_core.HeaderValue{Key: reqHeaderAccountID, Value: "account_unlimited"}, AppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD},
+						},
+					},
+				},
+			},
+			portalAppID: "portal_app_unlimited_no_limit",
+			mockPortalAppReturn: &store.PortalApp{
+				ID:        "portal_app_unlimited_no_limit",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				Auth:      nil,
+				RateLimit: &store.RateLimit{
+					MonthlyUserLimit: 0, // No specific limit
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			if test.portalAppID != "" {
+				mockPortalAppStore.EXPECT().GetPortalApp(test.portalAppID).Return(test.mockPortalAppReturn, test.mockPortalAppReturn != nil)
+			}
+
+			// Set up rate limit store expectations
+			if test.mockPortalAppReturn != nil && test.mockPortalAppReturn.RateLimit != nil {
+				failClosed := test.rateLimitFailureMode == RateLimitFailClosed
+				if failClosed {
+					mockRateLimitStore.EXPECT().IsAvailable().Return(!test.rateLimitStoreUnavailable)
+				}
+
+				// Accounts are within their rate limits unless the test case specifies otherwise
+				if (!failClosed || !test.rateLimitStoreUnavailable) && !test.expectRateLimitBypass {
+					rateLimitDecision := ratelimit.DecisionOK
+					if test.rateLimitDecision != "" {
+						rateLimitDecision = test.rateLimitDecision
+					}
+					mockRateLimitStore.EXPECT().GetAccountRateLimitDecision(test.mockPortalAppReturn.AccountID).Return(rateLimitDecision)
+				}
+			}
+
+			// Use the default failure mode unless the test case specifies otherwise
+			opts := []AuthHandlerOption{
+				WithLocalizedDenialMessages(test.denialMessages),
+				WithRelayCosts(test.relayCosts),
+				WithRateLimitDecisionHeader(test.rateLimitDecisionHeaderTTL),
+				WithRequireAuthority(test.requireAuthority),
+				WithHTTPSRequirement(test.httpsRequirement),
+			}
+			if test.rateLimitFailureMode != "" {
+				opts = append(opts, WithRateLimitFailureMode(test.rateLimitFailureMode))
+			}
+			if test.healthCheckBypass != nil {
+				opts = append(opts, WithHealthCheckBypass(test.healthCheckBypass))
+			}
+			if test.missingPortalAppIDStatusCode != 0 {
+				opts = append(opts, WithMissingPortalAppIDResponse(test.missingPortalAppIDStatusCode, test.missingPortalAppIDMessage))
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				opts...,
+			)
+
+			// Every response is counted once under the HTTP status code returned to the client
+			expectedCode := getHTTPStatusCode(test.expectedResp)
+			responseCountBefore := getAuthHTTPResponseCount(t, expectedCode)
+
+			resp, err := authHandler.Check(context.Background(), test.checkReq)
+			c.NoError(err)
+			c.Equal(test.expectedResp, resp)
+			c.Equal(responseCountBefore+1, getAuthHTTPResponseCount(t, expectedCode))
+		})
+	}
+}
+
+func Test_Check_InternalError(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Simulate an unexpected failure while handling the request
+	mockPortalAppStore := NewMockportalAppStore(ctrl)
+	mockPortalAppStore.EXPECT().GetPortalApp(store.PortalAppID("portal_app_panic")).
+		DoAndReturn(func(store.PortalAppID) (*store.PortalApp, bool) {
+			panic("unexpected store failure")
+		})
+
+	authHandler := NewAuthHandler(
+		polyzero.NewLogger(),
+		mockPortalAppStore,
+		newTestRateLimitStore(ctrl),
+		[]Authorizer{&AuthorizerAPIKey{}},
+	)
+
+	internalErrorCountBefore := getAuthHTTPResponseCount(t, int32(envoy_type.StatusCode_InternalServerError))
+
+	resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+		Attributes: &envoy_auth.AttributeContext{
+			Request: &envoy_auth.AttributeContext_Request{
+				Http: &envoy_auth.AttributeContext_HttpRequest{
+					Path: "/v1/portal_app_panic",
+				},
+			},
+		},
+	})
+	c.NoError(err)
+	c.Equal(&envoy_auth.CheckResponse{
+		Status: &status.Status{
+			Code:    int32(codes.Internal),
+			Message: internalErrorMessage,
+		},
+		HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+			DeniedResponse: &envoy_auth.DeniedHttpResponse{
+				Status: &envoy_type.HttpStatus{
+					Code: envoy_type.StatusCode_InternalServerError,
+				},
+				Body: `{"code": 500, "message": "internal server error"}`,
+			},
+		},
+	}, resp)
+	c.Equal(internalErrorCountBefore+1, getAuthHTTPResponseCount(t, int32(envoy_type.StatusCode_InternalServerError)))
+}
+
+func Test_Check_RecordsRateLimitCheckDuration(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Unknown plan types are recorded under the "other" plan type label
+	portalApp := &store.PortalApp{
+		ID:        "portal_app_metrics",
+		AccountID: "account_metrics",
+		PlanType:  "PLAN_RATE_LIMIT_CHECK_DURATION_TEST",
+		RateLimit: &store.RateLimit{},
+	}
+
+	mockPortalAppStore := NewMockportalAppStore(ctrl)
+	mockPortalAppStore.EXPECT().GetPortalApp(portalApp.ID).Return(portalApp, true)
+	mockRateLimitStore := newTestRateLimitStore(ctrl)
+	mockRateLimitStore.EXPECT().GetAccountRateLimitDecision(portalApp.AccountID).Return(ratelimit.DecisionOK)
+
+	authHandler := NewAuthHandler(polyzero.NewLogger(), mockPortalAppStore, mockRateLimitStore, []Authorizer{&AuthorizerAPIKey{}})
+
+	sampleCountBefore := getRateLimitCheckDurationSampleCount(t, metrics.PlanTypeOther)
+
+	_, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+		Attributes: &envoy_auth.AttributeContext{
+			Request: &envoy_auth.AttributeContext_Request{
+				Http: &envoy_auth.AttributeContext_HttpRequest{
+					Path: "/v1/portal_app_metrics",
+				},
+			},
+		},
+	})
+	c.NoError(err)
+
+	c.Equal(sampleCountBefore+1, getRateLimitCheckDurationSampleCount(t, metrics.PlanTypeOther))
+}
+
+// getRateLimitCheckDurationSampleCount returns the number of rate limit check durations observed for the plan type.
+func getRateLimitCheckDurationSampleCount(t *testing.T, planType string) uint64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_rate_limit_check_duration_seconds" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "plan_type" && label.GetValue() == planType {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// getAuthHTTPResponseCount returns the number of authorization requests counted under the HTTP status code.
+func getAuthHTTPResponseCount(t *testing.T, code int32) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_auth_http_responses_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "code" && label.GetValue() == strconv.Itoa(int(code)) {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func Test_getHTTPHeaders(t *testing.T) {
+	tests := []struct {
+		name                 string
+		opts                 []AuthHandlerOption
+		rateLimitDecision    ratelimit.Decision
+		relayCost            int32
+		expectedAppendAction envoy_core.HeaderValueOption_HeaderAppendAction
+		expectedHeaderCount  int
+	}{
+		{
+			name:                 "should set OVERWRITE_IF_EXISTS_OR_ADD on each injected header by default",
+			rateLimitDecision:    ratelimit.DecisionOK,
+			expectedAppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			expectedHeaderCount:  2,
+		},
+		{
+			name:                 "should set OVERWRITE_IF_EXISTS_OR_ADD on the rate limit status header by default",
+			rateLimitDecision:    ratelimit.DecisionThrottle,
+			expectedAppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			expectedHeaderCount:  3,
+		},
+		{
+			name:                 "should set the configured append action on each injected header",
+			opts:                 []AuthHandlerOption{WithHeaderAppendAction(envoy_core.HeaderValueOption_ADD_IF_ABSENT)},
+			rateLimitDecision:    ratelimit.DecisionWarn,
+			expectedAppendAction: envoy_core.HeaderValueOption_ADD_IF_ABSENT,
+			expectedHeaderCount:  3,
+		},
+		{
+			name:                 "should set OVERWRITE_IF_EXISTS_OR_ADD on the relay cost header by default",
+			rateLimitDecision:    ratelimit.DecisionOK,
+			relayCost:            5,
+			expectedAppendAction: envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			expectedHeaderCount:  3,
+		},
+		{
+			name:                 "should set the configured append action on the rate limit decision header",
+			opts:                 []AuthHandlerOption{WithHeaderAppendAction(envoy_core.HeaderValueOption_ADD_IF_ABSENT), WithRateLimitDecisionHeader(time.Minute)},
+			rateLimitDecision:    ratelimit.DecisionOK,
+			expectedAppendAction: envoy_core.HeaderValueOption_ADD_IF_ABSENT,
+			expectedHeaderCount:  3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, test.opts...)
+
+			headers := authHandler.getHTTPHeaders(
+				&store.PortalApp{ID: "portal_app_1", AccountID: "account_1"},
+				test.rateLimitDecision,
+				test.relayCost,
+			)
+			c.Len(headers, test.expectedHeaderCount)
+			for _, header := range headers {
+				c.Equal(test.expectedAppendAction, header.GetAppendAction(), "header %s", header.GetHeader().GetKey())
+			}
+		})
+	}
+}
+
+func Test_getHTTPHeaders_PlanHeaders(t *testing.T) {
+	planHeaders := PlanHeaders{
+		grovedb.PlanFree_DatabaseType: "Rl-Plan-Free",
+		"PLAN_PRO":                    "Rl-Plan-Pro",
+	}
+
+	tests := []struct {
+		name              string
+		portalApp         *store.PortalApp
+		rateLimitDecision ratelimit.Decision
+		relayCost         int32
+		expectedHeaders   map[string]string
+	}{
+		{
+			name: "should add plan header for plan with a configured header",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_pro",
+				AccountID: "account_pro",
+				PlanType:  "PLAN_PRO",
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_pro",
+				reqHeaderAccountID:   "account_pro",
+				"Rl-Plan-Pro":        "account_pro",
+			},
+		},
+		{
+			name: "should add plan header for plan with a per-account monthly limit",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_pro_limit",
+				AccountID: "account_pro_limit",
+				PlanType:  "PLAN_PRO",
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 1_000_000},
+			},
+			rateLimitDecision: ratelimit.DecisionWarn,
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID:     "portal_app_pro_limit",
+				reqHeaderAccountID:       "account_pro_limit",
+				reqHeaderRateLimitStatus: "warn",
+				"Rl-Plan-Pro":            "account_pro_limit",
+			},
+		},
+		{
+			name: "should add plan header alongside relay cost header",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_free",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			relayCost: 5,
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_free",
+				reqHeaderAccountID:   "account_free",
+				"Rl-Cost-5":          "account_free",
+				"Rl-Plan-Free":       "account_free",
+			},
+		},
+		{
+			name: "should not add plan header for plan without a configured header",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 10_000_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+				"Rl-User-Limit-10":   "account_unlimited",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, WithPlanHeaders(planHeaders))
+
+			headers := authHandler.getHTTPHeaders(test.portalApp, test.rateLimitDecision, test.relayCost)
+
+			gotHeaders := make(map[string]string, len(headers))
+			for _, header := range headers {
+				gotHeaders[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+			}
+			c.Equal(test.expectedHeaders, gotHeaders)
+		})
+	}
+}
+
+func Test_getHTTPHeaders_RateLimitRequestHeader(t *testing.T) {
+	tests := []struct {
+		name            string
+		planHeaders     PlanHeaders
+		portalApp       *store.PortalApp
+		expectedHeaders map[string]string
+	}{
+		{
+			name: "should add free plan header for PLAN_FREE portal app",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_free",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_free",
+				reqHeaderAccountID:   "account_free",
+				"Rl-Plan-Free":       "account_free",
+			},
+		},
+		{
+			name: "should add user limit header for PLAN_UNLIMITED portal app with a 40M monthly user limit",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 40_000_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+				"Rl-User-Limit-40":   "account_unlimited",
+			},
+		},
+		{
+			name: "should add daily user limit header for portal app with only a daily user limit",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{DailyUserLimit: 100_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID:      "portal_app_unlimited",
+				reqHeaderAccountID:        "account_unlimited",
+				"Rl-User-Daily-Limit-100": "account_unlimited",
+			},
+		},
+		{
+			name: "should add both monthly and daily user limit headers for portal app with both windows",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 40_000_000, DailyUserLimit: 2_500_500},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID:       "portal_app_unlimited",
+				reqHeaderAccountID:         "account_unlimited",
+				"Rl-User-Limit-40":         "account_unlimited",
+				"Rl-User-Daily-Limit-2500": "account_unlimited",
+			},
+		},
+		{
+			name: "should add both free plan and daily user limit headers for PLAN_FREE portal app with a daily user limit",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_free",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{DailyUserLimit: 50_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID:     "portal_app_free",
+				reqHeaderAccountID:       "account_free",
+				"Rl-Plan-Free":           "account_free",
+				"Rl-User-Daily-Limit-50": "account_free",
+			},
+		},
+		{
+			name: "should not add daily user limit header for daily user limit under one thousand",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{DailyUserLimit: 999},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+			},
+		},
+		{
+			name: "should round the user limit header down to whole millions",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 2_500_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+				"Rl-User-Limit-2":    "account_unlimited",
+			},
+		},
+		{
+			name: "should not add user limit header for monthly user limit under one million",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 500_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+			},
+		},
+		{
+			name: "should not add user limit header for PLAN_UNLIMITED portal app without a monthly user limit",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+			},
+		},
+		{
+			name: "should only add portal app and account ID headers for portal app without a rate limit",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_free",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: nil,
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_free",
+				reqHeaderAccountID:   "account_free",
+			},
+		},
+		{
+			name:        "should use the configured plan header instead of the free plan header",
+			planHeaders: PlanHeaders{grovedb.PlanFree_DatabaseType: "Rl-Plan-Free-Tier"},
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_free",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_free",
+				reqHeaderAccountID:   "account_free",
+				"Rl-Plan-Free-Tier":  "account_free",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, WithPlanHeaders(test.planHeaders))
+
+			headers := authHandler.getHTTPHeaders(test.portalApp, ratelimit.DecisionOK, 1)
+
+			gotHeaders := make(map[string]string, len(headers))
+			for _, header := range headers {
+				gotHeaders[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+			}
+			c.Equal(test.expectedHeaders, gotHeaders)
+		})
+	}
+}
+
+func Test_getHTTPHeaders_BurstAllowance(t *testing.T) {
+	tests := []struct {
+		name            string
+		enabled         bool
+		portalApp       *store.PortalApp
+		expectedHeaders map[string]string
+	}{
+		{
+			name:    "should add burst header for portal app with a burst allowance if enabled",
+			enabled: true,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{BurstAllowance: 50},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+				"Rl-Burst-50":        "account_unlimited",
+			},
+		},
+		{
+			name:    "should add burst header alongside the monthly and daily headers if enabled",
+			enabled: true,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_free",
+				AccountID: "account_free",
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{DailyUserLimit: 50_000, BurstAllowance: 1_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID:     "portal_app_free",
+				reqHeaderAccountID:       "account_free",
+				"Rl-Plan-Free":           "account_free",
+				"Rl-User-Daily-Limit-50": "account_free",
+				"Rl-Burst-1000":          "account_free",
+			},
+		},
+		{
+			name:    "should not add burst header for portal app without a burst allowance if enabled",
+			enabled: true,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 10_000_000},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+				"Rl-User-Limit-10":   "account_unlimited",
+			},
+		},
+		{
+			name:    "should not add burst header for portal app without a rate limit if enabled",
+			enabled: true,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+			},
+		},
+		{
+			name:    "should not add burst header for portal app with a burst allowance if disabled",
+			enabled: false,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_unlimited",
+				AccountID: "account_unlimited",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{BurstAllowance: 50},
+			},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, WithBurstAllowanceHeader(test.enabled))
+
+			headers := authHandler.getHTTPHeaders(test.portalApp, ratelimit.DecisionOK, 1)
+
+			gotHeaders := make(map[string]string, len(headers))
+			for _, header := range headers {
+				gotHeaders[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+			}
+			c.Equal(test.expectedHeaders, gotHeaders)
+		})
+	}
+}
+
+func Test_getHTTPHeaders_RateLimitTier(t *testing.T) {
+	portalApp := &store.PortalApp{
+		ID:        "portal_app_unlimited",
+		AccountID: "account_unlimited",
+		PlanType:  grovedb.PlanUnlimited_DatabaseType,
+		RateLimit: &store.RateLimit{MonthlyUserLimit: 10_000_000},
+	}
+
+	tests := []struct {
+		name            string
+		enabled         bool
+		expectedHeaders map[string]string
+	}{
+		{
+			name:    "should add rate limit tier header if enabled",
+			enabled: true,
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID:   "portal_app_unlimited",
+				reqHeaderAccountID:     "account_unlimited",
+				reqHeaderRateLimitTier: "unlimited-limited",
+				"Rl-User-Limit-10":     "account_unlimited",
+			},
+		},
+		{
+			name:    "should not add rate limit tier header if disabled",
+			enabled: false,
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_unlimited",
+				reqHeaderAccountID:   "account_unlimited",
+				"Rl-User-Limit-10":   "account_unlimited",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, WithRateLimitTierHeader(test.enabled))
+
+			headers := authHandler.getHTTPHeaders(portalApp, ratelimit.DecisionOK, 1)
+
+			gotHeaders := make(map[string]string, len(headers))
+			for _, header := range headers {
+				gotHeaders[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+			}
+			c.Equal(test.expectedHeaders, gotHeaders)
+		})
+	}
+}
+
+func Test_getHTTPHeaders_PlanName(t *testing.T) {
+	tests := []struct {
+		name            string
+		enabled         bool
+		portalApp       *store.PortalApp
+		expectedHeaders map[string]string
+	}{
+		{
+			name:      "should add plan name header if enabled",
+			enabled:   true,
+			portalApp: &store.PortalApp{ID: "portal_app_free", AccountID: "account_free", PlanType: grovedb.PlanFree_DatabaseType, PlanName: "Free"},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_free",
+				reqHeaderAccountID:   "account_free",
+				reqHeaderPlanName:    "Free",
+			},
+		},
+		{
+			name:      "should not add plan name header if disabled",
+			enabled:   false,
+			portalApp: &store.PortalApp{ID: "portal_app_free", AccountID: "account_free", PlanType: grovedb.PlanFree_DatabaseType, PlanName: "Free"},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_free",
+				reqHeaderAccountID:   "account_free",
+			},
+		},
+		{
+			name:      "should not add plan name header for portal app with no plan name",
+			enabled:   true,
+			portalApp: &store.PortalApp{ID: "portal_app_pro", AccountID: "account_pro", PlanType: "PLAN_PRO"},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_pro",
+				reqHeaderAccountID:   "account_pro",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}}, WithPlanNameHeader(test.enabled))
+
+			headers := authHandler.getHTTPHeaders(test.portalApp, ratelimit.DecisionOK, 1)
+
+			gotHeaders := make(map[string]string, len(headers))
+			for _, header := range headers {
+				gotHeaders[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+			}
+			c.Equal(test.expectedHeaders, gotHeaders)
+		})
+	}
+}
+
+func Test_ParseRateLimitFailureMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    RateLimitFailureMode
+		wantErr bool
+	}{
+		{
+			name:  "should parse fail_open",
+			input: "fail_open",
+			want:  RateLimitFailOpen,
+		},
+		{
+			name:  "should parse fail_closed",
+			input: "fail_closed",
+			want:  RateLimitFailClosed,
+		},
+		{
+			name:  "should parse fail_open_stale",
+			input: "fail_open_stale",
+			want:  RateLimitFailOpenStale,
+		},
+		{
+			name:    "should error on unknown failure mode",
+			input:   "closed",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			got, err := ParseRateLimitFailureMode(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, got)
+		})
+	}
+}
+
+func Test_ParseHeaderAppendAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    envoy_core.HeaderValueOption_HeaderAppendAction
+		wantErr bool
+	}{
+		{
+			name:  "should parse OVERWRITE_IF_EXISTS_OR_ADD",
+			input: "OVERWRITE_IF_EXISTS_OR_ADD",
+			want:  envoy_core.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		},
+		{
+			name:  "should parse APPEND_IF_EXISTS_OR_ADD",
+			input: "APPEND_IF_EXISTS_OR_ADD",
+			want:  envoy_core.HeaderValueOption_APPEND_IF_EXISTS_OR_ADD,
+		},
+		{
+			name:    "should error on unknown append action",
+			input:   "overwrite",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			got, err := ParseHeaderAppendAction(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, got)
+		})
+	}
+}
+
+func Test_ParseDenialStatusCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    envoy_type.StatusCode
+		wantErr bool
+	}{
+		{
+			name:  "should parse 400",
+			input: "400",
+			want:  envoy_type.StatusCode_BadRequest,
+		},
+		{
+			name:  "should parse 404",
+			input: "404",
+			want:  envoy_type.StatusCode_NotFound,
+		},
+		{
+			name:    "should error on non-numeric status code",
+			input:   "not_found",
+			wantErr: true,
+		},
+		{
+			name:    "should error on non-4xx status code",
+			input:   "302",
+			wantErr: true,
+		},
+		{
+			name:    "should error on unknown 4xx status code",
+			input:   "499",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			got, err := ParseDenialStatusCode(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, got)
+		})
+	}
+}
+
+func Test_Check_APIKeyLookup(t *testing.T) {
+	portalApp := &store.PortalApp{
+		ID:        "portal_app_api_key_lookup",
+		AccountID: "account_api_key_lookup",
+		PlanType:  "PLAN_UNLIMITED",
+		Auth:      &store.Auth{APIKeys: []string{"api_key_lookup"}},
+	}
+
+	tests := []struct {
+		name               string
+		apiKeyLookup       bool
+		apiKey             string
+		indexedPortalAppID store.PortalAppID
+		indexed            bool
+		expectedCode       codes.Code
+	}{
+		{
+			name:               "should authorize request with no portal app ID using the API key",
+			apiKeyLookup:       true,
+			apiKey:             "api_key_lookup",
+			indexedPortalAppID: portalApp.ID,
+			indexed:            true,
+			expectedCode:       codes.OK,
+		},
+		{
+			name:         "should reject request with no portal app ID if the API key is not indexed",
+			apiKeyLookup: true,
+			apiKey:       "unknown_api_key",
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "should reject request with no portal app ID if API key lookup is disabled",
+			apiKeyLookup: false,
+			apiKey:       "api_key_lookup",
+			expectedCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			if test.apiKeyLookup {
+				mockPortalAppStore.EXPECT().GetPortalAppIDByAPIKey(test.apiKey).Return(test.indexedPortalAppID, test.indexed)
+			}
+			if test.indexed {
+				mockPortalAppStore.EXPECT().GetPortalApp(test.indexedPortalAppID).Return(portalApp, true)
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithAPIKeyLookup(test.apiKeyLookup),
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:    "/v1",
+							Headers: map[string]string{"authorization": "Bearer " + test.apiKey},
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), resp.GetStatus().GetCode())
+		})
+	}
+}
+
+func Test_Check_DenialRequestID(t *testing.T) {
+	tests := []struct {
+		name             string
+		denialRequestID  bool
+		path             string
+		portalApp        *store.PortalApp
+		expectedBody     string
+		expectedOKStatus bool
+	}{
+		{
+			name:            "should include request ID in denial body if enabled",
+			denialRequestID: true,
+			path:            "/v1/portal_app_request_id",
+			expectedBody:    `{"code": 404, "message": "portal app not found", "request_id": "request_1"}`,
+		},
+		{
+			name:            "should not include request ID in denial body if disabled",
+			denialRequestID: false,
+			path:            "/v1/portal_app_request_id",
+			expectedBody:    `{"code": 404, "message": "portal app not found"}`,
+		},
+		{
+			name:            "should include request ID in denial body for requests with no portal app ID if enabled",
+			denialRequestID: true,
+			path:            "/v1/",
+			expectedBody:    `{"code": 400, "message": "portal app ID not provided in header or path", "request_id": "request_1"}`,
+		},
+		{
+			name:             "should not modify OK response if enabled",
+			denialRequestID:  true,
+			path:             "/v1/portal_app_request_id",
+			portalApp:        &store.PortalApp{ID: "portal_app_request_id", AccountID: "account_request_id"},
+			expectedOKStatus: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			if test.path != "/v1/" {
+				mockPortalAppStore.EXPECT().GetPortalApp(store.PortalAppID("portal_app_request_id")).Return(test.portalApp, test.portalApp != nil)
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithDenialRequestID(test.denialRequestID),
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:    test.path,
+							Headers: map[string]string{"x-request-id": "request_1"},
+						},
+					},
+				},
+			})
+			c.NoError(err)
+
+			if test.expectedOKStatus {
+				c.NotNil(resp.GetOkResponse())
+				return
+			}
+			c.Equal(test.expectedBody, resp.GetDeniedResponse().GetBody())
+		})
+	}
+}
+
+func Test_Check_StaleHeader(t *testing.T) {
+	tests := []struct {
+		name                 string
+		failureMode          RateLimitFailureMode
+		rateLimitAvailable   bool
+		portalApp            *store.PortalApp
+		expectedStaleHeader  bool
+		expectedDeniedStatus bool
+	}{
+		{
+			name:                "should set stale header on OK response if store is unavailable",
+			failureMode:         RateLimitFailOpenStale,
+			rateLimitAvailable:  false,
+			portalApp:           &store.PortalApp{ID: "portal_app_stale", AccountID: "account_stale"},
+			expectedStaleHeader: true,
+		},
+		{
+			name:                 "should set stale header on denied response if store is unavailable",
+			failureMode:          RateLimitFailOpenStale,
+			rateLimitAvailable:   false,
+			expectedStaleHeader:  true,
+			expectedDeniedStatus: true,
+		},
+		{
+			name:               "should not set stale header if store is available",
+			failureMode:        RateLimitFailOpenStale,
+			rateLimitAvailable: true,
+			portalApp:          &store.PortalApp{ID: "portal_app_stale", AccountID: "account_stale"},
+		},
+		{
+			name:               "should not set stale header for fail_open if store is unavailable",
+			failureMode:        RateLimitFailOpen,
+			rateLimitAvailable: false,
+			portalApp:          &store.PortalApp{ID: "portal_app_stale", AccountID: "account_stale"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(store.PortalAppID("portal_app_stale")).Return(test.portalApp, test.portalApp != nil)
+
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			mockRateLimitStore.EXPECT().IsAvailable().Return(test.rateLimitAvailable).AnyTimes()
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithRateLimitFailureMode(test.failureMode),
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_stale",
+						},
+					},
+				},
+			})
+			c.NoError(err)
+
+			headers := resp.GetOkResponse().GetHeaders()
+			if test.expectedDeniedStatus {
+				c.NotNil(resp.GetDeniedResponse())
+				headers = resp.GetDeniedResponse().GetHeaders()
+			} else {
+				c.NotNil(resp.GetOkResponse())
+			}
+
+			staleHeaderSet := false
+			for _, header := range headers {
+				if header.GetHeader().GetKey() == reqHeaderAuthStale {
+					c.Equal("true", header.GetHeader().GetValue())
+					staleHeaderSet = true
+				}
+			}
+			c.Equal(test.expectedStaleHeader, staleHeaderSet)
+		})
+	}
+}
+
+func Test_Check_MisconfiguredPortalApp(t *testing.T) {
+	tests := []struct {
+		name                  string
+		denyMisconfigured     bool
+		portalApp             *store.PortalApp
+		authHeader            string
+		expectedOKStatus      bool
+		expectedMisconfigured bool
+	}{
+		{
+			name:                  "should allow request to portal app with required auth but empty API key by default",
+			portalApp:             &store.PortalApp{ID: "portal_app_misconfigured", AccountID: "account_1", Auth: &store.Auth{}},
+			expectedOKStatus:      true,
+			expectedMisconfigured: true,
+		},
+		{
+			name:                  "should deny request to portal app with required auth but empty API key if enabled",
+			denyMisconfigured:     true,
+			portalApp:             &store.PortalApp{ID: "portal_app_misconfigured", AccountID: "account_1", Auth: &store.Auth{}},
+			expectedMisconfigured: true,
+		},
+		{
+			name:                  "should deny request with an API key to portal app with required auth but empty API key if enabled",
+			denyMisconfigured:     true,
+			portalApp:             &store.PortalApp{ID: "portal_app_misconfigured", AccountID: "account_1", Auth: &store.Auth{}},
+			authHeader:            "api_key_1",
+			expectedMisconfigured: true,
+		},
+		{
+			name:              "should not record portal app with no required auth as misconfigured",
+			denyMisconfigured: true,
+			portalApp:         &store.PortalApp{ID: "portal_app_misconfigured", AccountID: "account_1"},
+			expectedOKStatus:  true,
+		},
+		{
+			name:              "should not record portal app with required auth and an API key as misconfigured",
+			denyMisconfigured: true,
+			portalApp:         &store.PortalApp{ID: "portal_app_misconfigured", AccountID: "account_1", Auth: &store.Auth{APIKeys: []string{"api_key_1"}}},
+			authHeader:        "api_key_1",
+			expectedOKStatus:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithDenyMisconfiguredPortalApps(test.denyMisconfigured),
+			)
+
+			headers := map[string]string{}
+			if test.authHeader != "" {
+				headers["authorization"] = test.authHeader
+			}
+
+			misconfiguredCountBefore := getPortalAppMisconfiguredCount(t, test.portalApp.ID)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:    "/v1/" + string(test.portalApp.ID),
+							Headers: headers,
+						},
+					},
+				},
+			})
+			c.NoError(err)
+
+			if test.expectedOKStatus {
+				c.NotNil(resp.GetOkResponse())
+			} else {
+				c.Equal(envoy_type.StatusCode_Unauthorized, resp.GetDeniedResponse().GetStatus().GetCode())
+			}
+
+			misconfiguredCount := getPortalAppMisconfiguredCount(t, test.portalApp.ID) - misconfiguredCountBefore
+			if test.expectedMisconfigured {
+				c.Equal(float64(1), misconfiguredCount)
+			} else {
+				c.Zero(misconfiguredCount)
+			}
+		})
+	}
+}
+
+// getPortalAppMisconfiguredCount returns the number of requests counted to the misconfigured portal app.
+func getPortalAppMisconfiguredCount(t *testing.T, portalAppID store.PortalAppID) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_portal_app_misconfigured_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "portal_app_id" && label.GetValue() == string(portalAppID) {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func Test_Check_PortalAppIDFormat(t *testing.T) {
+	portalApp := &store.PortalApp{ID: "1a2b3c4d", AccountID: "account_1"}
+
+	tests := []struct {
+		name         string
+		format       string
+		apiKeyLookup bool
+		path         string
+		portalApp    *store.PortalApp
+		expectedCode envoy_type.StatusCode
+		expectedBody string
+	}{
+		{
+			name:         "should look up portal app ID matching the format",
+			format:       "[0-9a-f]{8}",
+			path:         "/v1/1a2b3c4d",
+			portalApp:    portalApp,
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name:         "should deny malformed portal app ID with 400 without looking it up",
+			format:       "[0-9a-f]{8}",
+			path:         "/v1/1a2b3c4d'%20OR%201=1",
+			expectedCode: envoy_type.StatusCode_BadRequest,
+			expectedBody: `{"code": 400, "message": "malformed portal app ID"}`,
+		},
+		{
+			name:         "should not fall back to API key lookup for malformed portal app ID",
+			format:       "[0-9a-f]{8}",
+			apiKeyLookup: true,
+			path:         "/v1/not_a_portal_app_id",
+			expectedCode: envoy_type.StatusCode_BadRequest,
+			expectedBody: `{"code": 400, "message": "malformed portal app ID"}`,
+		},
+		{
+			name:         "should look up any portal app ID if no format is set",
+			path:         "/v1/not_a_portal_app_id",
+			expectedCode: envoy_type.StatusCode_NotFound,
+			expectedBody: `{"code": 404, "message": "portal app not found"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			if test.expectedCode != envoy_type.StatusCode_BadRequest {
+				mockPortalAppStore.EXPECT().GetPortalApp(gomock.Any()).Return(test.portalApp, test.portalApp != nil)
+			}
+
+			opts := []AuthHandlerOption{WithAPIKeyLookup(test.apiKeyLookup)}
+			if test.format != "" {
+				format, err := ParsePortalAppIDFormat(test.format)
+				c.NoError(err)
+				opts = append(opts, WithPortalAppIDFormat(format))
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				opts...,
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: test.path,
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+			if test.expectedBody != "" {
+				c.Equal(test.expectedBody, resp.GetDeniedResponse().GetBody())
+			}
+		})
+	}
+}
+
+func Test_Check_BillingDelinquent(t *testing.T) {
+	tests := []struct {
+		name         string
+		message      string
+		portalApp    *store.PortalApp
+		expectedCode envoy_type.StatusCode
+		expectedBody string
+	}{
+		{
+			name:         "should allow request from account with no billing status",
+			portalApp:    &store.PortalApp{ID: "portal_app_billing", AccountID: "account_1"},
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name:         "should allow request from account with current billing status",
+			portalApp:    &store.PortalApp{ID: "portal_app_billing", AccountID: "account_1", BillingStatus: "current"},
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name:         "should deny request from delinquent account with 402 and the default message",
+			portalApp:    &store.PortalApp{ID: "portal_app_billing", AccountID: "account_1", BillingStatus: store.BillingStatusDelinquent},
+			expectedCode: envoy_type.StatusCode_PaymentRequired,
+			expectedBody: `{"code": 402, "message": "` + defaultBillingDelinquentMessage + `"}`,
+		},
+		{
+			name:         "should deny request from delinquent account with 402 and the configured message",
+			message:      `Payment "required"`,
+			portalApp:    &store.PortalApp{ID: "portal_app_billing", AccountID: "account_1", BillingStatus: store.BillingStatusDelinquent},
+			expectedCode: envoy_type.StatusCode_PaymentRequired,
+			expectedBody: `{"code": 402, "message": "Payment \"required\""}`,
+		},
+		{
+			name: "should deny request from delinquent account before checking the rate limit",
+			portalApp: &store.PortalApp{
+				ID:            "portal_app_billing",
+				AccountID:     "account_1",
+				PlanType:      "PLAN_FREE",
+				BillingStatus: store.BillingStatusDelinquent,
+				RateLimit:     &store.RateLimit{},
+			},
+			expectedCode: envoy_type.StatusCode_PaymentRequired,
+			expectedBody: `{"code": 402, "message": "` + defaultBillingDelinquentMessage + `"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+
+			// No rate limit store calls are expected: delinquent accounts are denied before the rate limit check
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithBillingDelinquentMessage(test.message),
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/" + string(test.portalApp.ID),
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+			if test.expectedBody != "" {
+				c.Equal(test.expectedBody, resp.GetDeniedResponse().GetBody())
+				c.Equal(errAccountBillingDelinquent.Error(), resp.GetStatus().GetMessage())
+			}
+		})
+	}
+}
+
+func Test_Check_AccountAPIKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		portalApp    *store.PortalApp
+		apiKey       string
+		expectedCode envoy_type.StatusCode
+	}{
+		{
+			name: "should authorize request with the account API key if the portal app has no auth",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key",
+				AccountID:   "account_1",
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			apiKey:       "account_api_key",
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name: "should authorize request with the account API key for any of the account's portal apps",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key_other",
+				AccountID:   "account_1",
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			apiKey:       "Bearer account_api_key",
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name: "should deny request with the wrong account API key",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key",
+				AccountID:   "account_1",
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			apiKey:       "another_account_api_key",
+			expectedCode: envoy_type.StatusCode_Unauthorized,
+		},
+		{
+			name: "should deny request with no API key if the account has an API key",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key",
+				AccountID:   "account_1",
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			expectedCode: envoy_type.StatusCode_Unauthorized,
+		},
+		{
+			name: "should require the portal app API key rather than the account API key if the portal app has auth",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key",
+				AccountID:   "account_1",
+				Auth:        &store.Auth{APIKeys: []string{"portal_app_api_key"}},
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			apiKey:       "account_api_key",
+			expectedCode: envoy_type.StatusCode_Unauthorized,
+		},
+		{
+			name: "should authorize request with the portal app API key if the portal app has auth",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key",
+				AccountID:   "account_1",
+				Auth:        &store.Auth{APIKeys: []string{"portal_app_api_key"}},
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			apiKey:       "portal_app_api_key",
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name: "should not require an API key if the account API key is empty",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_account_key",
+				AccountID:   "account_1",
+				AccountAuth: &store.Auth{},
+			},
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+
+			// No rate limit store calls are expected: the portal apps are not rate limited
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+			)
+
+			headers := map[string]string{}
+			if test.apiKey != "" {
+				headers[authHeaderKey] = test.apiKey
+			}
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:    "/v1/" + string(test.portalApp.ID),
+							Headers: headers,
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+		})
+	}
+}
+
+func Test_Check_PortalAppRateLimited(t *testing.T) {
+	limitedPortalApp := &store.PortalApp{
+		ID:        "portal_app_limited",
+		AccountID: "account_portal_app_limited",
+		PlanType:  grovedb.PlanFree_DatabaseType,
+		RateLimit: &store.RateLimit{PortalAppMonthlyLimit: 1_000},
+	}
+	siblingPortalApp := &store.PortalApp{
+		ID:        "portal_app_sibling",
+		AccountID: "account_portal_app_limited",
+		PlanType:  grovedb.PlanFree_DatabaseType,
+		RateLimit: &store.RateLimit{},
+	}
+	// The account of a portal app with only a limit of its own is not rate-limitable
+	onlyPortalAppLimitedPortalApp := &store.PortalApp{
+		ID:        "portal_app_only_limited",
+		AccountID: "account_portal_app_only_limited",
+		PlanType:  grovedb.PlanUnlimited_DatabaseType,
+		RateLimit: &store.RateLimit{PortalAppMonthlyLimit: 1_000},
+	}
+
+	tests := []struct {
+		name                    string
+		portalApp               *store.PortalApp
+		accountNotRateLimitable bool
+		coldStartDeny           bool
+		accountDecision         ratelimit.Decision
+		portalAppRateLimited    bool
+		expectedCode            envoy_type.StatusCode
+		expectedRateLimitCheck  string
+	}{
+		{
+			name:                   "should deny request for portal app over its own limit while its account is within its limit",
+			portalApp:              limitedPortalApp,
+			accountDecision:        ratelimit.DecisionOK,
+			portalAppRateLimited:   true,
+			expectedCode:           envoy_type.StatusCode_TooManyRequests,
+			expectedRateLimitCheck: "portal_app_rate_limited",
+		},
+		{
+			name:                   "should allow request for portal app within its own limit while its account is within its limit",
+			portalApp:              limitedPortalApp,
+			accountDecision:        ratelimit.DecisionOK,
+			expectedCode:           envoy_type.StatusCode_OK,
+			expectedRateLimitCheck: "allowed",
+		},
+		{
+			name:                   "should deny request for portal app within its own limit while its account is rate limited",
+			portalApp:              limitedPortalApp,
+			accountDecision:        ratelimit.DecisionBlock,
+			expectedCode:           envoy_type.StatusCode_TooManyRequests,
+			expectedRateLimitCheck: "rate_limited",
+		},
+		{
+			name:                   "should allow request for portal app with no limit of its own while its account is within its limit",
+			portalApp:              siblingPortalApp,
+			accountDecision:        ratelimit.DecisionOK,
+			expectedCode:           envoy_type.StatusCode_OK,
+			expectedRateLimitCheck: "allowed",
+		},
+		{
+			name:                    "should deny request for portal app over its own limit while its account is not rate-limitable",
+			portalApp:               onlyPortalAppLimitedPortalApp,
+			accountNotRateLimitable: true,
+			portalAppRateLimited:    true,
+			expectedCode:            envoy_type.StatusCode_TooManyRequests,
+			expectedRateLimitCheck:  "portal_app_rate_limited",
+		},
+		{
+			name:                    "should allow request for portal app within its own limit before the first load while its account is not rate-limitable",
+			portalApp:               onlyPortalAppLimitedPortalApp,
+			accountNotRateLimitable: true,
+			coldStartDeny:           true,
+			expectedCode:            envoy_type.StatusCode_OK,
+			expectedRateLimitCheck:  "allowed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+
+			mockRateLimitStore := NewMockrateLimitStore(ctrl)
+			mockRateLimitStore.EXPECT().IsAccountRateLimitable(test.portalApp).Return(!test.accountNotRateLimitable)
+			mockRateLimitStore.EXPECT().HasLoaded().Return(false).AnyTimes()
+			// Only rate-limitable accounts are looked up
+			if !test.accountNotRateLimitable {
+				mockRateLimitStore.EXPECT().GetAccountRateLimitDecision(test.portalApp.AccountID).Return(test.accountDecision)
+			}
+			// Only portal apps with a limit of their own are looked up, unless the account is already rate limited
+			if test.portalApp.RateLimit.PortalAppMonthlyLimit > 0 && test.accountDecision != ratelimit.DecisionBlock {
+				mockRateLimitStore.EXPECT().IsPortalAppRateLimited(test.portalApp.ID).Return(test.portalAppRateLimited)
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithRateLimitColdStartDeny(test.coldStartDeny),
+			)
+
+			checksBefore := getRateLimitCheckCount(t, test.portalApp.AccountID, test.expectedRateLimitCheck)
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/" + string(test.portalApp.ID),
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+			if test.expectedCode == envoy_type.StatusCode_TooManyRequests {
+				c.Equal(accountRateLimitMessage, resp.GetStatus().GetMessage())
+			}
+			c.Equal(float64(1), getRateLimitCheckCount(t, test.portalApp.AccountID, test.expectedRateLimitCheck)-checksBefore)
+		})
+	}
+}
+
+// getRateLimitCheckCount returns the number of rate limit checks of the account counted with the decision.
+func getRateLimitCheckCount(t *testing.T, accountID store.AccountID, decision string) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_rate_limit_checks_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["account_id"] == string(accountID) && labels["decision"] == decision {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func Test_Check_RateLimitColdStartDeny(t *testing.T) {
+	rateLimitedPortalApp := &store.PortalApp{
+		ID:        "portal_app_cold_start",
+		AccountID: "account_1",
+		PlanType:  grovedb.PlanFree_DatabaseType,
+		RateLimit: &store.RateLimit{},
+	}
+	unlimitedPortalApp := &store.PortalApp{
+		ID:        "portal_app_cold_start_unlimited",
+		AccountID: "account_2",
+		PlanType:  "PLAN_UNLIMITED",
+	}
+
+	tests := []struct {
+		name                 string
+		coldStartDeny        bool
+		rateLimitFailureMode RateLimitFailureMode
+		portalApp            *store.PortalApp
+		storeLoaded          bool
+		storeAvailable       bool
+		expectedCode         envoy_type.StatusCode
+		expectedMessage      string
+	}{
+		{
+			name:            "should deny rate-limit-eligible request with 429 before the first load if enabled",
+			coldStartDeny:   true,
+			portalApp:       rateLimitedPortalApp,
+			storeLoaded:     false,
+			expectedCode:    envoy_type.StatusCode_TooManyRequests,
+			expectedMessage: rateLimitColdStartMessage,
+		},
+		{
+			name:           "should allow rate-limit-eligible request after the first load if enabled",
+			coldStartDeny:  true,
+			portalApp:      rateLimitedPortalApp,
+			storeLoaded:    true,
+			storeAvailable: true,
+			expectedCode:   envoy_type.StatusCode_OK,
+		},
+		{
+			name:          "should allow request not eligible for rate limiting before the first load if enabled",
+			coldStartDeny: true,
+			portalApp:     unlimitedPortalApp,
+			storeLoaded:   false,
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+		{
+			name:          "should allow rate-limit-eligible request before the first load if disabled",
+			coldStartDeny: false,
+			portalApp:     rateLimitedPortalApp,
+			storeLoaded:   false,
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+		{
+			name:                 "should deny with 429 rather than 503 before the first load if fail_closed is also set",
+			coldStartDeny:        true,
+			rateLimitFailureMode: RateLimitFailClosed,
+			portalApp:            rateLimitedPortalApp,
+			storeLoaded:          false,
+			expectedCode:         envoy_type.StatusCode_TooManyRequests,
+			expectedMessage:      rateLimitColdStartMessage,
+		},
+		{
+			name:                 "should apply fail_closed rather than cold start denial once the store has loaded and gone stale",
+			coldStartDeny:        true,
+			rateLimitFailureMode: RateLimitFailClosed,
+			portalApp:            rateLimitedPortalApp,
+			storeLoaded:          true,
+			storeAvailable:       false,
+			expectedCode:         envoy_type.StatusCode_ServiceUnavailable,
+			expectedMessage:      rateLimitUnavailableMessage,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			mockRateLimitStore.EXPECT().HasLoaded().Return(test.storeLoaded).AnyTimes()
+			mockRateLimitStore.EXPECT().IsAvailable().Return(test.storeAvailable).AnyTimes()
+			mockRateLimitStore.EXPECT().GetAccountRateLimitDecision(test.portalApp.AccountID).Return(ratelimit.DecisionOK).AnyTimes()
+
+			opts := []AuthHandlerOption{WithRateLimitColdStartDeny(test.coldStartDeny)}
+			if test.rateLimitFailureMode != "" {
+				opts = append(opts, WithRateLimitFailureMode(test.rateLimitFailureMode))
+			}
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				opts...,
+			)
+
+			countBefore := getAuthRequestErrorTypeCount(t, metrics.AuthRequestErrorTypeRateLimitStoreColdStart)
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/" + string(test.portalApp.ID),
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+			if test.expectedMessage != "" {
+				c.Equal(test.expectedMessage, resp.GetStatus().GetMessage())
+			}
+
+			// Only cold start denials are counted with the cold start error type
+			expectedCount := float64(0)
+			if test.expectedMessage == rateLimitColdStartMessage {
+				expectedCount = 1
+			}
+			c.Equal(expectedCount, getAuthRequestErrorTypeCount(t, metrics.AuthRequestErrorTypeRateLimitStoreColdStart)-countBefore)
+		})
+	}
+}
+
+// newTestRateLimitStore returns a mock rate limit store for which portal apps with a RateLimit are rate-limitable.
+func newTestRateLimitStore(ctrl *gomock.Controller) *MockrateLimitStore {
+	mockRateLimitStore := NewMockrateLimitStore(ctrl)
+	mockRateLimitStore.EXPECT().
+		IsAccountRateLimitable(gomock.Any()).
+		DoAndReturn(func(portalApp *store.PortalApp) bool { return portalApp.RateLimit != nil }).
+		AnyTimes()
+	return mockRateLimitStore
 }
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// AuthRequiredPlans is a set of plan types whose portal apps must require authorization.
+//
+//   - A portal app on such a plan with no Auth and no AccountAuth is treated as misconfigured,
+//     rather than silently served as a public portal app
+//   - Misconfigured portal apps are denied if WithDenyMisconfiguredPortalApps is set
+type AuthRequiredPlans map[store.PlanType]bool
+
+// ParseAuthRequiredPlans parses AuthRequiredPlans from a comma-separated list of plan types.
+//
+//   - Example: ParseAuthRequiredPlans("PLAN_UNLIMITED,PLAN_ENTERPRISE")
+//   - Returns nil if no plan types are provided
+func ParseAuthRequiredPlans(s string) AuthRequiredPlans {
+	var plans AuthRequiredPlans
+	for _, planType := range splitAndTrim(s) {
+		if plans == nil {
+			plans = make(AuthRequiredPlans)
+		}
+		plans[store.PlanType(planType)] = true
+	}
+	return plans
+}
+
+// requiresAuth returns true if the portal app's plan requires authorization.
+func (p AuthRequiredPlans) requiresAuth(portalApp *store.PortalApp) bool {
+	return p[portalApp.PlanType]
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseAuthRequiredPlans(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(AuthRequiredPlans{"PLAN_UNLIMITED": true, "PLAN_ENTERPRISE": true}, ParseAuthRequiredPlans(" PLAN_UNLIMITED, PLAN_ENTERPRISE ,"))
+	c.Nil(ParseAuthRequiredPlans(""))
+}
+
+func Test_Check_AuthRequiredPlans(t *testing.T) {
+	authRequiredPlans := AuthRequiredPlans{grovedb.PlanUnlimited_DatabaseType: true}
+
+	tests := []struct {
+		name                  string
+		denyMisconfigured     bool
+		portalApp             *store.PortalApp
+		authHeader            string
+		expectedCode          envoy_type.StatusCode
+		expectedMisconfigured bool
+	}{
+		{
+			name:                  "should allow request to public portal app whose plan requires auth by default",
+			portalApp:             &store.PortalApp{ID: "portal_app_plan_public_allowed", AccountID: "account_1", PlanType: grovedb.PlanUnlimited_DatabaseType},
+			expectedCode:          envoy_type.StatusCode_OK,
+			expectedMisconfigured: true,
+		},
+		{
+			name:                  "should deny request to public portal app whose plan requires auth if enabled",
+			denyMisconfigured:     true,
+			portalApp:             &store.PortalApp{ID: "portal_app_plan_public_denied", AccountID: "account_1", PlanType: grovedb.PlanUnlimited_DatabaseType},
+			expectedCode:          envoy_type.StatusCode_Unauthorized,
+			expectedMisconfigured: true,
+		},
+		{
+			name:              "should not record public portal app whose plan does not require auth as misconfigured",
+			denyMisconfigured: true,
+			portalApp:         &store.PortalApp{ID: "portal_app_plan_free", AccountID: "account_1", PlanType: grovedb.PlanFree_DatabaseType},
+			expectedCode:      envoy_type.StatusCode_OK,
+		},
+		{
+			name:              "should not record portal app with an API key whose plan requires auth as misconfigured",
+			denyMisconfigured: true,
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_plan_api_key",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				Auth:      &store.Auth{APIKeys: []string{"api_key_1"}},
+			},
+			authHeader:   "api_key_1",
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name:              "should not record portal app with an account API key whose plan requires auth as misconfigured",
+			denyMisconfigured: true,
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_plan_account_api_key",
+				AccountID:   "account_1",
+				PlanType:    grovedb.PlanUnlimited_DatabaseType,
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key_1"}},
+			},
+			authHeader:   "account_api_key_1",
+			expectedCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name: "should deny HMAC portal app with no HMAC authorizer configured, even with a valid API key",
+			portalApp: &store.PortalApp{
+				ID:        "portal_app_plan_no_authorizer",
+				AccountID: "account_1",
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				Auth:      &store.Auth{APIKeys: []string{"api_key_1"}, HMACSecret: "hmac_secret_1"},
+			},
+			authHeader:   "api_key_1",
+			expectedCode: envoy_type.StatusCode_Unauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			mockRateLimitStore.EXPECT().IsAvailable().Return(true).AnyTimes()
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithAuthRequiredPlans(authRequiredPlans),
+				WithDenyMisconfiguredPortalApps(test.denyMisconfigured),
+			)
+
+			req := newTestCheckRequest("/v1/" + string(test.portalApp.ID))
+			if test.authHeader != "" {
+				req.Attributes.Request.Http.Headers = map[string]string{"authorization": test.authHeader}
+			}
+
+			misconfiguredCountBefore := getPortalAppMisconfiguredCount(t, test.portalApp.ID)
+
+			resp, err := authHandler.Check(context.Background(), req)
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+
+			misconfiguredCount := getPortalAppMisconfiguredCount(t, test.portalApp.ID) - misconfiguredCountBefore
+			if test.expectedMisconfigured {
+				c.Equal(float64(1), misconfiguredCount)
+			} else {
+				c.Zero(misconfiguredCount)
+			}
+		})
+	}
+}
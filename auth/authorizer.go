@@ -3,6 +3,7 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"net/netip"
 
 	"github.com/buildwithgrove/path-external-auth-server/store"
 )
@@ -13,6 +14,42 @@ var errUnauthorized = fmt.Errorf("unauthorized")
 
 // Authorizer is an interface for authorizing requests against a PortalApp.
 type Authorizer interface {
-	// authorizeRequest authorizes a request using the provided headers and a PortalApp.
-	authorizeRequest(headers http.Header, portalApp *store.PortalApp) error
+	// authorizeRequest authorizes a request using the provided headers, request path, client IP and a PortalApp.
+	//   - The client IP is invalid if it could not be resolved (see ClientIPResolver)
+	authorizeRequest(headers http.Header, path string, clientIP netip.Addr, portalApp *store.PortalApp) error
+}
+
+// authType is the type of authorization a PortalApp is configured with.
+// Each Authorizer only accepts requests for the authType it evaluates (see authHandler.checkPortalAppAuthorized).
+type authType string
+
+const (
+	// authTypeNone: the PortalApp requires no authorization.
+	authTypeNone authType = "none"
+	// authTypeAPIKey: requests must provide one of the PortalApp's API keys.
+	authTypeAPIKey authType = "api_key"
+	// authTypeHMAC: requests must be signed with the PortalApp's HMAC secret (see AuthorizerHMAC).
+	authTypeHMAC authType = "hmac"
+	// authTypeAccountAPIKey: the PortalApp has no Auth, so requests must provide one of its account's API keys.
+	authTypeAccountAPIKey authType = "account_api_key"
+	// authTypeMisconfigured: the PortalApp requires API key auth but has no API key.
+	authTypeMisconfigured authType = "misconfigured"
+)
+
+// getAuthType returns the type of authorization the PortalApp is configured with.
+//   - The PortalApp's own Auth takes precedence over its account's AccountAuth
+//   - An HMAC secret takes precedence over API keys
+func getAuthType(portalApp *store.PortalApp) authType {
+	switch {
+	case portalApp.Auth != nil && portalApp.Auth.HMACSecret != "":
+		return authTypeHMAC
+	case portalApp.Auth != nil && portalApp.Auth.HasAPIKey():
+		return authTypeAPIKey
+	case portalApp.Auth != nil:
+		return authTypeMisconfigured
+	case portalApp.AccountAuth.HasAPIKey():
+		return authTypeAccountAPIKey
+	default:
+		return authTypeNone
+	}
 }
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
 	"net/http"
+	"net/netip"
 
 	"github.com/buildwithgrove/path-external-auth-server/store"
 )
@@ -19,33 +22,66 @@ var _ Authorizer = (*AuthorizerAPIKey)(nil)
 // AuthorizerAPIKey
 //
 // - Authorizes a request using an API key
-// - Compares the API key in the request headers with the API key in the PortalApp
+// - Compares the API key in the request headers with the API keys in the PortalApp
 type AuthorizerAPIKey struct{}
 
 // authorizeRequest
 //
 // - Authorizes a request using an API key
-// - Returns errUnauthorized if the API key is missing or does not match
+// - Compares against the PortalApp's API keys, or its account's API keys if the PortalApp has no Auth
+// - Returns errUnauthorized for a PortalApp with an HMAC secret, which takes precedence over its API keys (see AuthorizerHMAC)
+// - Returns errUnauthorized if the API key is missing or matches none of the API keys
 func (a *AuthorizerAPIKey) authorizeRequest(
 	headers http.Header,
+	_ string,
+	_ netip.Addr,
 	portalApp *store.PortalApp,
 ) error {
-	// Extract the API key from the Authorization header (case-insensitive lookup)
-	headerValue := headers.Get(authHeaderKey)
-	if headerValue == "" {
+	if getAuthType(portalApp) == authTypeHMAC {
 		return errUnauthorized
 	}
 
-	// Remove the "Bearer " prefix from the API key if present
-	apiKey := headerValue
-	if len(headerValue) > len(apiKeyPrefix) && headerValue[:len(apiKeyPrefix)] == apiKeyPrefix {
-		apiKey = headerValue[len(apiKeyPrefix):]
+	apiKey := extractAPIKey(headers)
+	if apiKey == "" {
+		return errUnauthorized
 	}
 
-	// Compare the API key with the expected value
-	if apiKey != portalApp.Auth.APIKey {
+	// Compare the API key with the expected values
+	expectedAuth := portalApp.Auth
+	if expectedAuth == nil {
+		expectedAuth = portalApp.AccountAuth
+	}
+	if expectedAuth == nil || !matchesAnyAPIKey(apiKey, expectedAuth.APIKeys) {
 		return errUnauthorized
 	}
 
 	return nil
 }
+
+// matchesAnyAPIKey returns true if the API key matches any of the expected API keys.
+//   - Compares SHA-256 hashes in constant time against every expected key, without returning early,
+//     so the response time leaks neither the keys' lengths nor which key matched.
+//   - The API key must not be empty, so that an empty expected API key never matches.
+func matchesAnyAPIKey(apiKey string, expectedAPIKeys []string) bool {
+	apiKeyHash := sha256.Sum256([]byte(apiKey))
+
+	matched := 0
+	for _, expectedAPIKey := range expectedAPIKeys {
+		expectedHash := sha256.Sum256([]byte(expectedAPIKey))
+		matched |= subtle.ConstantTimeCompare(apiKeyHash[:], expectedHash[:])
+	}
+	return matched == 1
+}
+
+// extractAPIKey returns the API key from the Authorization header, with any "Bearer " prefix removed.
+//   - Returns an empty string if the header is not set.
+func extractAPIKey(headers http.Header) string {
+	// Extract the API key from the Authorization header (case-insensitive lookup)
+	headerValue := headers.Get(authHeaderKey)
+
+	// Remove the "Bearer " prefix from the API key if present
+	if len(headerValue) > len(apiKeyPrefix) && headerValue[:len(apiKeyPrefix)] == apiKeyPrefix {
+		return headerValue[len(apiKeyPrefix):]
+	}
+	return headerValue
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_AuthorizerAPIKey_authorizeRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiKeys     []string
+		apiKey      string
+		expectedErr error
+	}{
+		{
+			name:        "should reject any API key if no API keys are configured",
+			apiKeys:     nil,
+			apiKey:      "api_key_1",
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:        "should reject an empty API key if an empty API key is configured",
+			apiKeys:     []string{""},
+			apiKey:      "",
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:    "should authorize the API key if one API key is configured",
+			apiKeys: []string{"api_key_1"},
+			apiKey:  "api_key_1",
+		},
+		{
+			name:        "should reject a different API key if one API key is configured",
+			apiKeys:     []string{"api_key_1"},
+			apiKey:      "api_key_2",
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:    "should authorize the old API key while rotating to a new API key",
+			apiKeys: []string{"api_key_new", "api_key_old"},
+			apiKey:  "api_key_old",
+		},
+		{
+			name:    "should authorize the new API key while rotating from an old API key",
+			apiKeys: []string{"api_key_new", "api_key_old"},
+			apiKey:  "Bearer api_key_new",
+		},
+		{
+			name:        "should reject a rotated-out API key",
+			apiKeys:     []string{"api_key_newest", "api_key_new"},
+			apiKey:      "api_key_old",
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:        "should reject a prefix of a configured API key",
+			apiKeys:     []string{"api_key_new", "api_key_old"},
+			apiKey:      "api_key",
+			expectedErr: errUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			headers := http.Header{}
+			if test.apiKey != "" {
+				headers.Set(authHeaderKey, test.apiKey)
+			}
+			portalApp := &store.PortalApp{
+				ID:   "portal_app_1",
+				Auth: &store.Auth{APIKeys: test.apiKeys},
+			}
+
+			err := (&AuthorizerAPIKey{}).authorizeRequest(headers, "/v1/portal_app_1", netip.Addr{}, portalApp)
+			c.Equal(test.expectedErr, err)
+		})
+	}
+}
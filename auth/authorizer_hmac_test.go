@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_AuthorizerHMAC_authorizeRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		hmacSecret  string
+		path        string
+		signature   string
+		expectedErr error
+	}{
+		{
+			name:       "should authorize a valid signature of the request path",
+			hmacSecret: "hmac_secret",
+			path:       "/v1/portal_app_1",
+			signature:  "sha256=" + signPath("hmac_secret", "/v1/portal_app_1"),
+		},
+		{
+			name:       "should authorize a valid signature of the request path including its query string",
+			hmacSecret: "hmac_secret",
+			path:       "/v1/portal_app_1?foo=bar",
+			signature:  "sha256=" + signPath("hmac_secret", "/v1/portal_app_1?foo=bar"),
+		},
+		{
+			name:        "should reject a signature of a different request path",
+			hmacSecret:  "hmac_secret",
+			path:        "/v1/portal_app_1/tampered",
+			signature:   "sha256=" + signPath("hmac_secret", "/v1/portal_app_1"),
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:        "should reject a signature keyed by a different secret",
+			hmacSecret:  "hmac_secret",
+			path:        "/v1/portal_app_1",
+			signature:   "sha256=" + signPath("other_secret", "/v1/portal_app_1"),
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:        "should reject a missing signature",
+			hmacSecret:  "hmac_secret",
+			path:        "/v1/portal_app_1",
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:        "should reject a signature without the sha256= prefix",
+			hmacSecret:  "hmac_secret",
+			path:        "/v1/portal_app_1",
+			signature:   signPath("hmac_secret", "/v1/portal_app_1"),
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:        "should reject a signature that is not hex-encoded",
+			hmacSecret:  "hmac_secret",
+			path:        "/v1/portal_app_1",
+			signature:   "sha256=not_hex",
+			expectedErr: errUnauthorized,
+		},
+		{
+			name:        "should reject any signature if no HMAC secret is configured",
+			path:        "/v1/portal_app_1",
+			signature:   "sha256=" + signPath("", "/v1/portal_app_1"),
+			expectedErr: errUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			headers := http.Header{}
+			if test.signature != "" {
+				headers.Set(signatureHeaderKey, test.signature)
+			}
+			portalApp := &store.PortalApp{
+				ID:   "portal_app_1",
+				Auth: &store.Auth{HMACSecret: test.hmacSecret},
+			}
+
+			err := (&AuthorizerHMAC{}).authorizeRequest(headers, test.path, netip.Addr{}, portalApp)
+			c.Equal(test.expectedErr, err)
+		})
+	}
+}
+
+// signPath returns the hex-encoded HMAC-SHA256 of the path, as a client would sign it.
+func signPath(secret, path string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(path))
+	return hex.EncodeToString(mac.Sum(nil))
+}
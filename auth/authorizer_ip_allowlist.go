@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// errClientIPNotAllowed is returned when the client IP is not in any of the PortalApp's allowed CIDRs.
+var errClientIPNotAllowed = errors.New("client IP is not allowed")
+
+var _ Authorizer = (*AuthorizerIPAllowlist)(nil)
+
+// AuthorizerIPAllowlist
+//
+// - Authorizes a request by its client IP, for PortalApps with allowed CIDRs
+// - Evaluated before, and in addition to, the PortalApp's API key or HMAC auth
+// - The client IP is resolved from the configured client IP sources, or the source address if none are configured
+type AuthorizerIPAllowlist struct{}
+
+// authorizeRequest
+//
+// - Allows any client IP if the PortalApp has no allowed CIDRs
+// - Returns errClientIPNotAllowed if the client IP could not be resolved or is in none of the allowed CIDRs
+func (a *AuthorizerIPAllowlist) authorizeRequest(
+	_ http.Header,
+	_ string,
+	clientIP netip.Addr,
+	portalApp *store.PortalApp,
+) error {
+	if len(portalApp.AllowedCIDRs) == 0 {
+		return nil
+	}
+	if !clientIP.IsValid() {
+		return errClientIPNotAllowed
+	}
+
+	for _, cidr := range portalApp.AllowedCIDRs {
+		// Invalid CIDRs are rejected by the data source, but never allow a request through one
+		prefix, err := store.ParseAllowedCIDR(cidr)
+		if err == nil && prefix.Contains(clientIP) {
+			return nil
+		}
+	}
+	return errClientIPNotAllowed
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"testing"
+
+	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_AuthorizerIPAllowlist_authorizeRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowedCIDRs []string
+		clientIP     string
+		expectedErr  error
+	}{
+		{
+			name:     "should allow any client IP if the portal app has no allowed CIDRs",
+			clientIP: "198.51.100.4",
+		},
+		{
+			name: "should allow an unresolved client IP if the portal app has no allowed CIDRs",
+		},
+		{
+			name:         "should allow an IPv4 client IP in an allowed CIDR",
+			allowedCIDRs: []string{"203.0.113.0/24"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "should deny an IPv4 client IP outside the allowed CIDRs",
+			allowedCIDRs: []string{"203.0.113.0/24"},
+			clientIP:     "203.0.114.7",
+			expectedErr:  errClientIPNotAllowed,
+		},
+		{
+			name:         "should allow a client IP in any of the allowed CIDRs",
+			allowedCIDRs: []string{"10.0.0.0/8", "203.0.113.0/24"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "should allow a client IP matching an allowed single IP",
+			allowedCIDRs: []string{"203.0.113.7"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "should deny a client IP not matching an allowed single IP",
+			allowedCIDRs: []string{"203.0.113.7"},
+			clientIP:     "203.0.113.8",
+			expectedErr:  errClientIPNotAllowed,
+		},
+		{
+			name:         "should allow an IPv6 client IP in an allowed CIDR",
+			allowedCIDRs: []string{"2001:db8::/32"},
+			clientIP:     "2001:db8:1234::1",
+		},
+		{
+			name:         "should deny an IPv6 client IP outside the allowed CIDRs",
+			allowedCIDRs: []string{"2001:db8::/32"},
+			clientIP:     "2001:db9::1",
+			expectedErr:  errClientIPNotAllowed,
+		},
+		{
+			name:         "should deny an IPv4 client IP for an IPv6 allowed CIDR",
+			allowedCIDRs: []string{"::/0"},
+			clientIP:     "203.0.113.7",
+			expectedErr:  errClientIPNotAllowed,
+		},
+		{
+			name:         "should allow a client IP in a non-canonical allowed CIDR",
+			allowedCIDRs: []string{"203.0.113.99/24"},
+			clientIP:     "203.0.113.7",
+		},
+		{
+			name:         "should deny an unresolved client IP if the portal app has allowed CIDRs",
+			allowedCIDRs: []string{"0.0.0.0/0"},
+			expectedErr:  errClientIPNotAllowed,
+		},
+		{
+			name:         "should never allow a client IP through an invalid allowed CIDR",
+			allowedCIDRs: []string{"not_a_cidr"},
+			clientIP:     "203.0.113.7",
+			expectedErr:  errClientIPNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			var clientIP netip.Addr
+			if test.clientIP != "" {
+				clientIP = netip.MustParseAddr(test.clientIP)
+			}
+			portalApp := &store.PortalApp{ID: "portal_app_1", AllowedCIDRs: test.allowedCIDRs}
+
+			err := (&AuthorizerIPAllowlist{}).authorizeRequest(http.Header{}, "/v1/portal_app_1", clientIP, portalApp)
+			c.Equal(test.expectedErr, err)
+		})
+	}
+}
+
+func Test_Check_IPAllowlist(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []AuthHandlerOption
+		portalApp     *store.PortalApp
+		sourceAddress string
+		headers       map[string]string
+		expectedCode  envoy_type.StatusCode
+	}{
+		{
+			name:          "should allow a request from a source address in the allowed CIDRs",
+			portalApp:     &store.PortalApp{ID: "portal_app_1", AccountID: "account_1", AllowedCIDRs: []string{"198.51.100.0/24"}},
+			sourceAddress: "198.51.100.4",
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+		{
+			name:          "should deny a request from a source address outside the allowed CIDRs",
+			portalApp:     &store.PortalApp{ID: "portal_app_1", AccountID: "account_1", AllowedCIDRs: []string{"198.51.100.0/24"}},
+			sourceAddress: "192.0.2.1",
+			expectedCode:  envoy_type.StatusCode_Forbidden,
+		},
+		{
+			name:          "should ignore X-Forwarded-For if no client IP sources are configured, as it may be spoofed",
+			portalApp:     &store.PortalApp{ID: "portal_app_1", AccountID: "account_1", AllowedCIDRs: []string{"203.0.113.0/24"}},
+			sourceAddress: "192.0.2.1",
+			headers:       map[string]string{"x-forwarded-for": "203.0.113.7"},
+			expectedCode:  envoy_type.StatusCode_Forbidden,
+		},
+		{
+			name: "should allow a request whose X-Forwarded-For client IP is in the allowed CIDRs",
+			opts: []AuthHandlerOption{WithClientIPResolver(NewClientIPResolver(
+				[]ClientIPSource{ClientIPSourceXForwardedFor}, 1,
+			))},
+			portalApp:     &store.PortalApp{ID: "portal_app_1", AccountID: "account_1", AllowedCIDRs: []string{"2001:db8::/32"}},
+			sourceAddress: "10.0.0.2",
+			headers:       map[string]string{"x-forwarded-for": "203.0.113.7, 2001:db8::1, 10.0.0.1"},
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+		{
+			name: "should deny a request whose X-Forwarded-For client IP is outside the allowed CIDRs, skipping trusted proxy hops",
+			opts: []AuthHandlerOption{WithClientIPResolver(NewClientIPResolver(
+				[]ClientIPSource{ClientIPSourceXForwardedFor}, 1,
+			))},
+			portalApp:     &store.PortalApp{ID: "portal_app_1", AccountID: "account_1", AllowedCIDRs: []string{"10.0.0.0/8"}},
+			sourceAddress: "10.0.0.2",
+			headers:       map[string]string{"x-forwarded-for": "203.0.113.7, 10.0.0.1"},
+			expectedCode:  envoy_type.StatusCode_Forbidden,
+		},
+		{
+			name: "should deny a request from outside the allowed CIDRs before checking its API key",
+			portalApp: &store.PortalApp{
+				ID:           "portal_app_1",
+				AccountID:    "account_1",
+				Auth:         &store.Auth{APIKeys: []string{"api_key_1"}},
+				AllowedCIDRs: []string{"198.51.100.0/24"},
+			},
+			sourceAddress: "192.0.2.1",
+			expectedCode:  envoy_type.StatusCode_Forbidden,
+		},
+		{
+			name: "should require the API key of a request from inside the allowed CIDRs",
+			portalApp: &store.PortalApp{
+				ID:           "portal_app_1",
+				AccountID:    "account_1",
+				Auth:         &store.Auth{APIKeys: []string{"api_key_1"}},
+				AllowedCIDRs: []string{"198.51.100.0/24"},
+			},
+			sourceAddress: "198.51.100.4",
+			expectedCode:  envoy_type.StatusCode_Unauthorized,
+		},
+		{
+			name: "should allow a request from inside the allowed CIDRs with a valid API key",
+			portalApp: &store.PortalApp{
+				ID:           "portal_app_1",
+				AccountID:    "account_1",
+				Auth:         &store.Auth{APIKeys: []string{"api_key_1"}},
+				AllowedCIDRs: []string{"198.51.100.0/24"},
+			},
+			sourceAddress: "198.51.100.4",
+			headers:       map[string]string{"authorization": "api_key_1"},
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			test.portalApp.PlanType = grovedb.PlanUnlimited_DatabaseType
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), mockPortalAppStore, newTestRateLimitStore(ctrl), []Authorizer{&AuthorizerAPIKey{}}, test.opts...)
+
+			req := newTestCheckRequest("/v1/" + string(test.portalApp.ID))
+			req.Attributes.Request.Http.Headers = test.headers
+			req.Attributes.Source = &envoy_auth.AttributeContext_Peer{
+				Address: &envoy_core.Address{
+					Address: &envoy_core.Address_SocketAddress{
+						SocketAddress: &envoy_core.SocketAddress{Address: test.sourceAddress},
+					},
+				},
+			}
+
+			resp, err := authHandler.Check(context.Background(), req)
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// countingAuthorizer counts the requests it evaluates, to assert which authorizers were tried.
+type countingAuthorizer struct {
+	err   error
+	calls int
+}
+
+func (a *countingAuthorizer) authorizeRequest(http.Header, string, netip.Addr, *store.PortalApp) error {
+	a.calls++
+	return a.err
+}
+
+func Test_getAuthType(t *testing.T) {
+	tests := []struct {
+		name             string
+		portalApp        *store.PortalApp
+		expectedAuthType authType
+	}{
+		{
+			name:             "should return none for a portal app without auth",
+			portalApp:        &store.PortalApp{ID: "portal_app_public"},
+			expectedAuthType: authTypeNone,
+		},
+		{
+			name:             "should return api_key for a portal app with an API key",
+			portalApp:        &store.PortalApp{ID: "portal_app_key", Auth: &store.Auth{APIKeys: []string{"api_key"}}},
+			expectedAuthType: authTypeAPIKey,
+		},
+		{
+			name: "should return api_key for a portal app with an API key and an account API key",
+			portalApp: &store.PortalApp{
+				ID:          "portal_app_key",
+				Auth:        &store.Auth{APIKeys: []string{"api_key"}},
+				AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}},
+			},
+			expectedAuthType: authTypeAPIKey,
+		},
+		{
+			name:             "should return account_api_key for a portal app without auth whose account has an API key",
+			portalApp:        &store.PortalApp{ID: "portal_app_account_key", AccountAuth: &store.Auth{APIKeys: []string{"account_api_key"}}},
+			expectedAuthType: authTypeAccountAPIKey,
+		},
+		{
+			name:             "should return none for a portal app without auth whose account has an empty API key",
+			portalApp:        &store.PortalApp{ID: "portal_app_public", AccountAuth: &store.Auth{APIKeys: []string{""}}},
+			expectedAuthType: authTypeNone,
+		},
+		{
+			name:             "should return misconfigured for a portal app requiring auth without an API key",
+			portalApp:        &store.PortalApp{ID: "portal_app_misconfigured", Auth: &store.Auth{}},
+			expectedAuthType: authTypeMisconfigured,
+		},
+		{
+			name: "should return hmac for a portal app with an HMAC secret, taking precedence over its API key",
+			portalApp: &store.PortalApp{
+				ID:   "portal_app_hmac",
+				Auth: &store.Auth{APIKeys: []string{"api_key"}, HMACSecret: "hmac_secret"},
+			},
+			expectedAuthType: authTypeHMAC,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expectedAuthType, getAuthType(test.portalApp))
+		})
+	}
+}
+
+func Test_checkPortalAppAuthorized_AuthorizerChain(t *testing.T) {
+	portalAppWithAPIKey := &store.PortalApp{ID: "portal_app_key", Auth: &store.Auth{APIKeys: []string{"api_key"}}}
+
+	tests := []struct {
+		name                    string
+		portalApp               *store.PortalApp
+		authorizerErrs          []error
+		expectedErr             error
+		expectedAuthorizerCalls []int
+	}{
+		{
+			name:                    "should stop at the first authorizer authorizing the request",
+			portalApp:               portalAppWithAPIKey,
+			authorizerErrs:          []error{nil, nil},
+			expectedAuthorizerCalls: []int{1, 0},
+		},
+		{
+			name:                    "should try the next authorizer if one does not authorize the request",
+			portalApp:               portalAppWithAPIKey,
+			authorizerErrs:          []error{errUnauthorized, nil},
+			expectedAuthorizerCalls: []int{1, 1},
+		},
+		{
+			name:                    "should deny the request if no authorizer authorizes it",
+			portalApp:               portalAppWithAPIKey,
+			authorizerErrs:          []error{errUnauthorized, errUnauthorized},
+			expectedErr:             errUnauthorized,
+			expectedAuthorizerCalls: []int{1, 1},
+		},
+		{
+			name:                    "should deny the request if no authorizer is configured",
+			portalApp:               portalAppWithAPIKey,
+			expectedErr:             errUnauthorized,
+			expectedAuthorizerCalls: []int{},
+		},
+		{
+			name:                    "should not evaluate any authorizer for a portal app without auth",
+			portalApp:               &store.PortalApp{ID: "portal_app_public"},
+			authorizerErrs:          []error{errUnauthorized},
+			expectedAuthorizerCalls: []int{0},
+		},
+		{
+			name:                    "should not evaluate any authorizer for a misconfigured portal app",
+			portalApp:               &store.PortalApp{ID: "portal_app_misconfigured", Auth: &store.Auth{}},
+			authorizerErrs:          []error{nil},
+			expectedAuthorizerCalls: []int{0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			countingAuthorizers := make([]*countingAuthorizer, 0, len(test.authorizerErrs))
+			authorizers := make([]Authorizer, 0, len(test.authorizerErrs))
+			for _, err := range test.authorizerErrs {
+				authorizer := &countingAuthorizer{err: err}
+				countingAuthorizers = append(countingAuthorizers, authorizer)
+				authorizers = append(authorizers, authorizer)
+			}
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, authorizers)
+
+			err := authHandler.checkPortalAppAuthorized(http.Header{}, "/v1/"+string(test.portalApp.ID), netip.Addr{}, test.portalApp)
+			if test.expectedErr != nil {
+				c.ErrorIs(err, test.expectedErr)
+			} else {
+				c.NoError(err)
+			}
+
+			calls := make([]int, 0, len(countingAuthorizers))
+			for _, authorizer := range countingAuthorizers {
+				calls = append(calls, authorizer.calls)
+			}
+			c.Equal(test.expectedAuthorizerCalls, calls)
+		})
+	}
+}
+
+func Test_checkPortalAppAuthorized_HMACPrecedence(t *testing.T) {
+	c := require.New(t)
+
+	portalApp := &store.PortalApp{
+		ID:   "portal_app_hmac",
+		Auth: &store.Auth{APIKeys: []string{"api_key"}, HMACSecret: "hmac_secret"},
+	}
+	headers := http.Header{}
+	headers.Set("Authorization", "api_key")
+
+	// The API key of a portal app with an HMAC secret does not authorize the request, whatever the authorizer order.
+	authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}, &AuthorizerHMAC{}})
+	c.ErrorIs(authHandler.checkPortalAppAuthorized(headers, "/v1/portal_app_hmac", netip.Addr{}, portalApp), errUnauthorized)
+
+	// Without an HMAC authorizer, HMAC portal apps are denied rather than falling back to API key auth.
+	authHandler = NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}})
+	c.ErrorIs(authHandler.checkPortalAppAuthorized(headers, "/v1/portal_app_hmac", netip.Addr{}, portalApp), errUnauthorized)
+}
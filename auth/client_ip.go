@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+)
+
+const (
+	// reqHeaderXForwardedFor is the comma-separated list of addresses appended by each proxy the request passed through.
+	reqHeaderXForwardedFor = "X-Forwarded-For"
+	// reqHeaderXRealIP is the single client address set by some proxies (e.g. NGINX).
+	reqHeaderXRealIP = "X-Real-IP"
+)
+
+// ClientIPSource is a location of the client IP in the CheckRequest.
+type ClientIPSource string
+
+const (
+	// ClientIPSourceSourceAddress uses the address of the peer connected to Envoy (source.address).
+	ClientIPSourceSourceAddress ClientIPSource = "source_address"
+	// ClientIPSourceXForwardedFor uses the X-Forwarded-For header, skipping the trusted proxy hops.
+	ClientIPSourceXForwardedFor ClientIPSource = "x_forwarded_for"
+	// ClientIPSourceXRealIP uses the X-Real-IP header.
+	ClientIPSourceXRealIP ClientIPSource = "x_real_ip"
+)
+
+// ClientIPResolver resolves the client IP of a request from an ordered list of sources,
+// as the client IP's location depends on the topology in front of Envoy.
+//   - Each source is tried in order; the first source with a valid IP is used
+//   - For X-Forwarded-For, the client IP is the (trustedProxyHops+1)th address from the right,
+//     as the rightmost trustedProxyHops addresses were appended by trusted proxies
+type ClientIPResolver struct {
+	sources          []ClientIPSource
+	trustedProxyHops int
+}
+
+// NewClientIPResolver returns a ClientIPResolver trying the sources in order.
+//   - Returns nil if no sources are given, disabling client IP resolution
+func NewClientIPResolver(sources []ClientIPSource, trustedProxyHops int) *ClientIPResolver {
+	if len(sources) == 0 {
+		return nil
+	}
+	return &ClientIPResolver{
+		sources:          sources,
+		trustedProxyHops: trustedProxyHops,
+	}
+}
+
+// defaultClientIPResolver resolves the client IP for IP allowlists if no client IP sources are configured.
+// Only the source address is used, as headers may be set by the client unless a trusted proxy overwrites them.
+var defaultClientIPResolver = NewClientIPResolver([]ClientIPSource{ClientIPSourceSourceAddress}, 0)
+
+// ParseClientIPSources parses a comma-separated, ordered list of client IP sources.
+//   - Example: "x_forwarded_for,x_real_ip,source_address"
+//   - Valid sources are "source_address", "x_forwarded_for" and "x_real_ip"
+func ParseClientIPSources(s string) ([]ClientIPSource, error) {
+	var sources []ClientIPSource
+
+	seen := make(map[ClientIPSource]bool)
+	for _, name := range splitAndTrim(s) {
+		source := ClientIPSource(name)
+		switch source {
+		case ClientIPSourceSourceAddress, ClientIPSourceXForwardedFor, ClientIPSourceXRealIP:
+		default:
+			return nil, fmt.Errorf("invalid client IP source %q: must be one of source_address, x_forwarded_for, x_real_ip", name)
+		}
+		if seen[source] {
+			return nil, fmt.Errorf("duplicate client IP source %q", name)
+		}
+		seen[source] = true
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+// resolve returns the client IP of the request from the first source with a valid IP.
+//   - Returns false if client IP resolution is disabled or no source has a valid IP.
+func (r *ClientIPResolver) resolve(checkReq *envoy_auth.CheckRequest) (netip.Addr, bool) {
+	if r == nil {
+		return netip.Addr{}, false
+	}
+
+	headers := checkReq.GetAttributes().GetRequest().GetHttp().GetHeaders()
+	for _, source := range r.sources {
+		var value string
+		switch source {
+		case ClientIPSourceSourceAddress:
+			value = checkReq.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress()
+		case ClientIPSourceXForwardedFor:
+			value = getForwardedForClientIP(headers[strings.ToLower(reqHeaderXForwardedFor)], r.trustedProxyHops)
+		case ClientIPSourceXRealIP:
+			value = headers[strings.ToLower(reqHeaderXRealIP)]
+		}
+
+		if ip, err := netip.ParseAddr(strings.TrimSpace(value)); err == nil {
+			return ip.Unmap(), true
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+// getForwardedForClientIP returns the (trustedProxyHops+1)th address from the right of an X-Forwarded-For header.
+//   - Returns an empty string if the header has too few addresses, so the next source is tried.
+//
+// Example, with 1 trusted proxy hop:
+//
+//	"203.0.113.7, 10.0.0.1" -> "203.0.113.7"
+func getForwardedForClientIP(xForwardedFor string, trustedProxyHops int) string {
+	if xForwardedFor == "" {
+		return ""
+	}
+
+	addresses := strings.Split(xForwardedFor, ",")
+	index := len(addresses) - 1 - trustedProxyHops
+	if index < 0 {
+		return ""
+	}
+	return strings.TrimSpace(addresses[index])
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"testing"
+
+	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseClientIPSources(t *testing.T) {
+	c := require.New(t)
+
+	sources, err := ParseClientIPSources("x_forwarded_for, x_real_ip,source_address")
+	c.NoError(err)
+	c.Equal([]ClientIPSource{ClientIPSourceXForwardedFor, ClientIPSourceXRealIP, ClientIPSourceSourceAddress}, sources)
+
+	_, err = ParseClientIPSources("x_forwarded_for,forwarded")
+	c.Error(err)
+
+	_, err = ParseClientIPSources("x_real_ip,x_real_ip")
+	c.Error(err)
+}
+
+func Test_ClientIPResolver_resolve(t *testing.T) {
+	tests := []struct {
+		name             string
+		sources          []ClientIPSource
+		trustedProxyHops int
+		sourceAddress    string
+		headers          map[string]string
+		expectedIP       string
+	}{
+		{
+			name:          "should resolve the client IP from the source address",
+			sources:       []ClientIPSource{ClientIPSourceSourceAddress},
+			sourceAddress: "198.51.100.4",
+			headers:       map[string]string{"x-forwarded-for": "203.0.113.7"},
+			expectedIP:    "198.51.100.4",
+		},
+		{
+			name:       "should resolve the client IP from the X-Real-IP header",
+			sources:    []ClientIPSource{ClientIPSourceXRealIP},
+			headers:    map[string]string{"x-real-ip": "203.0.113.7"},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:       "should resolve the rightmost X-Forwarded-For address with no trusted proxy hops",
+			sources:    []ClientIPSource{ClientIPSourceXForwardedFor},
+			headers:    map[string]string{"x-forwarded-for": "192.0.2.1, 203.0.113.7"},
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:             "should skip the addresses appended by trusted proxy hops",
+			sources:          []ClientIPSource{ClientIPSourceXForwardedFor},
+			trustedProxyHops: 2,
+			headers:          map[string]string{"x-forwarded-for": "192.0.2.1, 203.0.113.7, 10.0.0.1, 10.0.0.2"},
+			expectedIP:       "203.0.113.7",
+		},
+		{
+			name:             "should fall through to the next source if X-Forwarded-For has too few addresses for the trusted proxy hops",
+			sources:          []ClientIPSource{ClientIPSourceXForwardedFor, ClientIPSourceSourceAddress},
+			trustedProxyHops: 2,
+			sourceAddress:    "198.51.100.4",
+			headers:          map[string]string{"x-forwarded-for": "203.0.113.7, 10.0.0.1"},
+			expectedIP:       "198.51.100.4",
+		},
+		{
+			name:          "should fall through to the next source if the header is missing",
+			sources:       []ClientIPSource{ClientIPSourceXRealIP, ClientIPSourceSourceAddress},
+			sourceAddress: "198.51.100.4",
+			expectedIP:    "198.51.100.4",
+		},
+		{
+			name:          "should fall through to the next source if the header is not a valid IP",
+			sources:       []ClientIPSource{ClientIPSourceXRealIP, ClientIPSourceSourceAddress},
+			sourceAddress: "198.51.100.4",
+			headers:       map[string]string{"x-real-ip": "unknown"},
+			expectedIP:    "198.51.100.4",
+		},
+		{
+			name:       "should resolve IPv6 addresses",
+			sources:    []ClientIPSource{ClientIPSourceXForwardedFor},
+			headers:    map[string]string{"x-forwarded-for": "2001:db8::1"},
+			expectedIP: "2001:db8::1",
+		},
+		{
+			name:          "should unmap IPv4-mapped IPv6 addresses",
+			sources:       []ClientIPSource{ClientIPSourceSourceAddress},
+			sourceAddress: "::ffff:198.51.100.4",
+			expectedIP:    "198.51.100.4",
+		},
+		{
+			name:    "should not resolve the client IP if no source has a valid IP",
+			sources: []ClientIPSource{ClientIPSourceXForwardedFor, ClientIPSourceXRealIP},
+		},
+		{
+			name:          "should not resolve the client IP if resolution is disabled",
+			sources:       nil,
+			sourceAddress: "198.51.100.4",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			checkReq := &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:    "/v1/portal_app_1",
+							Headers: test.headers,
+						},
+					},
+				},
+			}
+			if test.sourceAddress != "" {
+				checkReq.Attributes.Source = &envoy_auth.AttributeContext_Peer{
+					Address: &envoy_core.Address{
+						Address: &envoy_core.Address_SocketAddress{
+							SocketAddress: &envoy_core.SocketAddress{Address: test.sourceAddress, PortSpecifier: &envoy_core.SocketAddress_PortValue{PortValue: 54321}},
+						},
+					},
+				}
+			}
+
+			ip, ok := NewClientIPResolver(test.sources, test.trustedProxyHops).resolve(checkReq)
+			if test.expectedIP == "" {
+				c.False(ok)
+				return
+			}
+			c.True(ok)
+			c.Equal(test.expectedIP, ip.String())
+		})
+	}
+}
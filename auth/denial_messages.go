@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+const (
+	// reqHeaderAcceptLanguage is used to select the language of denial response bodies.
+	reqHeaderAcceptLanguage = "Accept-Language"
+
+	// defaultDenialLanguage is the language of the built-in denial messages.
+	// Requests preferring this language always receive the built-in messages.
+	defaultDenialLanguage = "en"
+)
+
+// localizableDenialErrorTypes are the denial error types whose body messages may be localized.
+var localizableDenialErrorTypes = map[string]bool{
+	metrics.AuthRequestErrorTypePortalAppNotFound: true, // 404
+	metrics.AuthRequestErrorTypeUnauthorized:      true, // 401
+	metrics.AuthRequestErrorTypeRateLimited:       true, // 429
+}
+
+// LocalizedDenialMessages maps a language tag to localized denial messages keyed by error type.
+//
+//   - Language tags are matched case-insensitively (e.g. "es", "pt-br")
+//   - Error types are "portal_app_not_found", "unauthorized" and "rate_limited"
+//   - English is always the fallback when no localized message matches
+//
+// Example JSON file contents:
+//
+//	{
+//	  "es": {
+//	    "portal_app_not_found": "aplicación del portal no encontrada",
+//	    "unauthorized": "no autorizado",
+//	    "rate_limited": "Esta cuenta tiene un límite de uso."
+//	  }
+//	}
+type LocalizedDenialMessages map[string]map[string]string
+
+// LoadLocalizedDenialMessages reads and validates localized denial messages from a JSON file.
+//   - Messages are JSON-escaped on load so they can be embedded in the denial response body.
+func LoadLocalizedDenialMessages(path string) (LocalizedDenialMessages, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read denial messages file: %w", err)
+	}
+
+	var raw map[string]map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse denial messages file: %w", err)
+	}
+
+	messages := make(LocalizedDenialMessages, len(raw))
+	for language, languageMessages := range raw {
+		language = strings.ToLower(strings.TrimSpace(language))
+		if language == "" {
+			return nil, fmt.Errorf("denial messages file contains an empty language tag")
+		}
+
+		messages[language] = make(map[string]string, len(languageMessages))
+		for errorType, message := range languageMessages {
+			if !localizableDenialErrorTypes[errorType] {
+				return nil, fmt.Errorf("invalid denial error type %q for language %q: must be one of portal_app_not_found, unauthorized, rate_limited", errorType, language)
+			}
+			if message == "" {
+				return nil, fmt.Errorf("empty denial message for error type %q and language %q", errorType, language)
+			}
+			messages[language][errorType] = escapeJSONString(message)
+		}
+	}
+
+	return messages, nil
+}
+
+// localize returns the localized message for the error type, selected using the Accept-Language header value.
+//   - Languages are tried in order of preference (q-value), falling back to the primary subtag (e.g. "pt-br" -> "pt")
+//   - Returns false if English is preferred or no localized message matches
+func (m LocalizedDenialMessages) localize(acceptLanguage, errorType string) (string, bool) {
+	if len(m) == 0 || acceptLanguage == "" {
+		return "", false
+	}
+
+	for _, language := range parseAcceptLanguage(acceptLanguage) {
+		primary, _, _ := strings.Cut(language, "-")
+
+		for _, candidate := range []string{language, primary} {
+			if message, ok := m[candidate][errorType]; ok {
+				return message, true
+			}
+		}
+
+		// The built-in messages are English, so an English preference stops the search.
+		if primary == defaultDenialLanguage {
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+// parseAcceptLanguage returns the lowercased language tags of an Accept-Language header value,
+// ordered from most to least preferred.
+//   - Example: "pt-BR,pt;q=0.9,en;q=0.8" -> ["pt-br", "pt", "en"]
+//   - Wildcards and tags with a q-value of 0 are ignored
+func parseAcceptLanguage(acceptLanguage string) []string {
+	type weightedLanguage struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []weightedLanguage
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if qStr, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(qStr, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		languages = append(languages, weightedLanguage{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, len(languages))
+	for i, language := range languages {
+		tags[i] = language.tag
+	}
+	return tags
+}
+
+// escapeJSONString escapes a string for embedding inside a JSON string literal.
+func escapeJSONString(s string) string {
+	escaped, _ := json.Marshal(s)
+	return string(escaped[1 : len(escaped)-1])
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           []string
+	}{
+		{
+			name:           "should return a single language",
+			acceptLanguage: "es",
+			want:           []string{"es"},
+		},
+		{
+			name:           "should order languages by q-value and lowercase tags",
+			acceptLanguage: "en;q=0.5, pt-BR, pt;q=0.9",
+			want:           []string{"pt-br", "pt", "en"},
+		},
+		{
+			name:           "should preserve header order for equal q-values",
+			acceptLanguage: "fr;q=0.8,de;q=0.8",
+			want:           []string{"fr", "de"},
+		},
+		{
+			name:           "should ignore wildcards, zero q-values and invalid q-values",
+			acceptLanguage: "*, ja;q=0, ko;q=abc, es;q=0.1",
+			want:           []string{"es"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.want, parseAcceptLanguage(test.acceptLanguage))
+		})
+	}
+}
+
+func Test_localize(t *testing.T) {
+	messages := LocalizedDenialMessages{
+		"es": {
+			"portal_app_not_found": "aplicación del portal no encontrada",
+			"rate_limited":         "cuenta limitada",
+		},
+		"pt-br": {
+			"unauthorized": "não autorizado (BR)",
+		},
+		"pt": {
+			"unauthorized": "não autorizado",
+		},
+	}
+
+	tests := []struct {
+		name           string
+		messages       LocalizedDenialMessages
+		acceptLanguage string
+		errorType      string
+		wantMessage    string
+		wantOK         bool
+	}{
+		{
+			name:           "should select message for exact language match",
+			messages:       messages,
+			acceptLanguage: "es",
+			errorType:      "portal_app_not_found",
+			wantMessage:    "aplicación del portal no encontrada",
+			wantOK:         true,
+		},
+		{
+			name:           "should select region-specific message before primary language",
+			messages:       messages,
+			acceptLanguage: "pt-BR",
+			errorType:      "unauthorized",
+			wantMessage:    "não autorizado (BR)",
+			wantOK:         true,
+		},
+		{
+			name:           "should fall back to primary language subtag",
+			messages:       messages,
+			acceptLanguage: "es-MX",
+			errorType:      "rate_limited",
+			wantMessage:    "cuenta limitada",
+			wantOK:         true,
+		},
+		{
+			name:           "should fall back to next preferred language",
+			messages:       messages,
+			acceptLanguage: "fr, es;q=0.5",
+			errorType:      "rate_limited",
+			wantMessage:    "cuenta limitada",
+			wantOK:         true,
+		},
+		{
+			name:           "should use English if English is preferred over a localized language",
+			messages:       messages,
+			acceptLanguage: "en-US, es;q=0.5",
+			errorType:      "rate_limited",
+			wantOK:         false,
+		},
+		{
+			name:           "should use English if the language has no message for the error type",
+			messages:       messages,
+			acceptLanguage: "es",
+			errorType:      "unauthorized",
+			wantOK:         false,
+		},
+		{
+			name:           "should use English if no Accept-Language header is set",
+			messages:       messages,
+			acceptLanguage: "",
+			errorType:      "rate_limited",
+			wantOK:         false,
+		},
+		{
+			name:           "should use English if no localized messages are configured",
+			messages:       nil,
+			acceptLanguage: "es",
+			errorType:      "rate_limited",
+			wantOK:         false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			message, ok := test.messages.localize(test.acceptLanguage, test.errorType)
+			c.Equal(test.wantOK, ok)
+			c.Equal(test.wantMessage, message)
+		})
+	}
+}
+
+func Test_LoadLocalizedDenialMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     LocalizedDenialMessages
+		wantErr  bool
+	}{
+		{
+			name:     "should load messages and lowercase language tags",
+			contents: `{"ES": {"rate_limited": "cuenta limitada"}, "pt-BR": {"unauthorized": "não autorizado"}}`,
+			want: LocalizedDenialMessages{
+				"es":    {"rate_limited": "cuenta limitada"},
+				"pt-br": {"unauthorized": "não autorizado"},
+			},
+		},
+		{
+			name:     "should escape messages for embedding in the JSON denial body",
+			contents: `{"de": {"unauthorized": "nicht \"autorisiert\""}}`,
+			want: LocalizedDenialMessages{
+				"de": {"unauthorized": `nicht \"autorisiert\"`},
+			},
+		},
+		{
+			name:     "should error on unknown error type",
+			contents: `{"es": {"internal_error": "error interno"}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "should error on empty message",
+			contents: `{"es": {"rate_limited": ""}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "should error on invalid JSON",
+			contents: `{"es": `,
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			path := filepath.Join(t.TempDir(), "denial_messages.json")
+			c.NoError(os.WriteFile(path, []byte(test.contents), 0o600))
+
+			got, err := LoadLocalizedDenialMessages(path)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, got)
+		})
+	}
+}
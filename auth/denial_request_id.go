@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/google/uuid"
+)
+
+const (
+	// reqHeaderRequestID is the request ID header set by Envoy on every request.
+	reqHeaderRequestID = "X-Request-Id"
+
+	// errBodyRequestIDField is inserted before the closing brace of denial bodies when request IDs are enabled.
+	errBodyRequestIDField = `, "request_id": "%s"`
+)
+
+// getRequestID returns the ID of the request, for correlating a customer-reported denial with logs.
+//   - Uses the X-Request-Id header, falling back to the request ID set by Envoy
+//   - Generates a new UUID if the request has no ID
+func getRequestID(req *envoy_auth.AttributeContext_HttpRequest) string {
+	if requestID := req.GetHeaders()[strings.ToLower(reqHeaderRequestID)]; requestID != "" {
+		return requestID
+	}
+	if requestID := req.GetId(); requestID != "" {
+		return requestID
+	}
+	return uuid.NewString()
+}
+
+// setDeniedResponseRequestID adds a "request_id" field to the JSON body of a denied CheckResponse.
+//   - Does nothing for OK responses.
+func setDeniedResponseRequestID(resp *envoy_auth.CheckResponse, requestID string) {
+	deniedResponse := resp.GetDeniedResponse()
+	if deniedResponse == nil {
+		return
+	}
+
+	body, ok := strings.CutSuffix(deniedResponse.Body, "}")
+	if !ok {
+		return
+	}
+	deniedResponse.Body = body + fmt.Sprintf(errBodyRequestIDField, escapeJSONString(requestID)) + "}"
+}
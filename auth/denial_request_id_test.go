@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *envoy_auth.AttributeContext_HttpRequest
+		expected string
+	}{
+		{
+			name: "should use the X-Request-Id header",
+			req: &envoy_auth.AttributeContext_HttpRequest{
+				Id:      "envoy_request_id",
+				Headers: map[string]string{"x-request-id": "header_request_id"},
+			},
+			expected: "header_request_id",
+		},
+		{
+			name: "should fall back to the Envoy request ID",
+			req: &envoy_auth.AttributeContext_HttpRequest{
+				Id: "envoy_request_id",
+			},
+			expected: "envoy_request_id",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expected, getRequestID(test.req))
+		})
+	}
+
+	t.Run("should generate a UUID if the request has no ID", func(t *testing.T) {
+		c := require.New(t)
+
+		_, err := uuid.Parse(getRequestID(&envoy_auth.AttributeContext_HttpRequest{}))
+		c.NoError(err)
+	})
+}
+
+func Test_setDeniedResponseRequestID(t *testing.T) {
+	tests := []struct {
+		name         string
+		resp         *envoy_auth.CheckResponse
+		requestID    string
+		expectedBody string
+	}{
+		{
+			name:         "should add request ID field to denied response body",
+			resp:         getDeniedCheckResponse("portal app not found", envoy_type.StatusCode_NotFound),
+			requestID:    "request_1",
+			expectedBody: `{"code": 404, "message": "portal app not found", "request_id": "request_1"}`,
+		},
+		{
+			name:         "should escape request ID",
+			resp:         getDeniedCheckResponse("unauthorized", envoy_type.StatusCode_Unauthorized),
+			requestID:    `request_"1"`,
+			expectedBody: `{"code": 401, "message": "unauthorized", "request_id": "request_\"1\""}`,
+		},
+		{
+			name:         "should add request ID field to internal error response body",
+			resp:         getInternalErrorCheckResponse(),
+			requestID:    "request_1",
+			expectedBody: `{"code": 500, "message": "internal server error", "request_id": "request_1"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			setDeniedResponseRequestID(test.resp, test.requestID)
+			c.Equal(test.expectedBody, test.resp.GetDeniedResponse().GetBody())
+		})
+	}
+
+	t.Run("should not modify OK responses", func(t *testing.T) {
+		c := require.New(t)
+
+		resp := getOKCheckResponse(nil)
+		setDeniedResponseRequestID(resp, "request_1")
+		c.Equal(getOKCheckResponse(nil), resp)
+	})
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// reqHeaderUserAgent is used to identify internal health check requests.
+const reqHeaderUserAgent = "User-Agent"
+
+// HealthCheckBypass identifies internal health check requests (e.g. uptime checks) that bypass rate limiting.
+//
+//   - Requests match if their User-Agent starts with one of UserAgentPrefixes,
+//     or if Header is set and the request's Header value equals HeaderValue
+//   - If AccountIDs is non-empty, only requests for those accounts may bypass rate limiting
+//   - Matching requests must still pass authorization
+type HealthCheckBypass struct {
+	UserAgentPrefixes []string
+	Header            string
+	HeaderValue       string
+	AccountIDs        map[store.AccountID]bool
+}
+
+// ParseHealthCheckBypass parses a HealthCheckBypass from its comma-separated
+// User-Agent prefixes, `<header>=<value>` header and comma-separated account IDs.
+//
+//   - Example: ParseHealthCheckBypass("UptimeRobot/,Grove-Healthcheck/", "X-Health-Check=secret", "account_1")
+//   - Returns nil if neither User-Agent prefixes nor a header are provided
+func ParseHealthCheckBypass(userAgentPrefixes, header, accountIDs string) (*HealthCheckBypass, error) {
+	bypass := &HealthCheckBypass{
+		UserAgentPrefixes: splitAndTrim(userAgentPrefixes),
+	}
+
+	if header != "" {
+		name, value, ok := strings.Cut(header, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid health check bypass header %q: expected <header>=<value>", header)
+		}
+		bypass.Header, bypass.HeaderValue = name, value
+	}
+
+	if len(bypass.UserAgentPrefixes) == 0 && bypass.Header == "" {
+		if accountIDs != "" {
+			return nil, fmt.Errorf("health check bypass account IDs require a User-Agent prefix or header")
+		}
+		return nil, nil
+	}
+
+	for _, accountID := range splitAndTrim(accountIDs) {
+		if bypass.AccountIDs == nil {
+			bypass.AccountIDs = make(map[store.AccountID]bool)
+		}
+		bypass.AccountIDs[store.AccountID(accountID)] = true
+	}
+
+	return bypass, nil
+}
+
+// matches returns true if the request for the account should bypass rate limiting.
+//   - Returns false if no health check bypass is configured.
+func (b *HealthCheckBypass) matches(headers http.Header, accountID store.AccountID) bool {
+	if b == nil {
+		return false
+	}
+
+	if len(b.AccountIDs) > 0 && !b.AccountIDs[accountID] {
+		return false
+	}
+
+	if b.Header != "" && headers.Get(b.Header) == b.HeaderValue {
+		return true
+	}
+
+	userAgent := headers.Get(reqHeaderUserAgent)
+	if userAgent == "" {
+		return false
+	}
+	for _, prefix := range b.UserAgentPrefixes {
+		if strings.HasPrefix(userAgent, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitAndTrim splits a comma-separated list, dropping empty entries.
+func splitAndTrim(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
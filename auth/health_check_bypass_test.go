@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseHealthCheckBypass(t *testing.T) {
+	tests := []struct {
+		name              string
+		userAgentPrefixes string
+		header            string
+		accountIDs        string
+		want              *HealthCheckBypass
+		wantErr           bool
+	}{
+		{
+			name: "should return nil if nothing is configured",
+			want: nil,
+		},
+		{
+			name:              "should parse User-Agent prefixes, header and account IDs",
+			userAgentPrefixes: "UptimeRobot/, Grove-Healthcheck/",
+			header:            "X-Health-Check = secret",
+			accountIDs:        "account_1,account_2",
+			want: &HealthCheckBypass{
+				UserAgentPrefixes: []string{"UptimeRobot/", "Grove-Healthcheck/"},
+				Header:            "X-Health-Check",
+				HeaderValue:       "secret",
+				AccountIDs:        map[store.AccountID]bool{"account_1": true, "account_2": true},
+			},
+		},
+		{
+			name:   "should parse header without User-Agent prefixes",
+			header: "X-Health-Check=secret",
+			want: &HealthCheckBypass{
+				Header:      "X-Health-Check",
+				HeaderValue: "secret",
+			},
+		},
+		{
+			name:    "should error on header without value",
+			header:  "X-Health-Check",
+			wantErr: true,
+		},
+		{
+			name:    "should error on header with empty value",
+			header:  "X-Health-Check=",
+			wantErr: true,
+		},
+		{
+			name:       "should error on account IDs without User-Agent prefixes or header",
+			accountIDs: "account_1",
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			got, err := ParseHealthCheckBypass(test.userAgentPrefixes, test.header, test.accountIDs)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, got)
+		})
+	}
+}
+
+func Test_HealthCheckBypass_matches(t *testing.T) {
+	bypass := &HealthCheckBypass{
+		UserAgentPrefixes: []string{"Grove-Healthcheck/"},
+		Header:            "X-Health-Check",
+		HeaderValue:       "secret",
+	}
+
+	tests := []struct {
+		name      string
+		bypass    *HealthCheckBypass
+		headers   http.Header
+		accountID store.AccountID
+		want      bool
+	}{
+		{
+			name:      "should match User-Agent prefix",
+			bypass:    bypass,
+			headers:   http.Header{"User-Agent": []string{"Grove-Healthcheck/1.0"}},
+			accountID: "account_1",
+			want:      true,
+		},
+		{
+			name:      "should match header value",
+			bypass:    bypass,
+			headers:   http.Header{"X-Health-Check": []string{"secret"}},
+			accountID: "account_1",
+			want:      true,
+		},
+		{
+			name:      "should not match User-Agent containing but not starting with prefix",
+			bypass:    bypass,
+			headers:   http.Header{"User-Agent": []string{"curl Grove-Healthcheck/1.0"}},
+			accountID: "account_1",
+			want:      false,
+		},
+		{
+			name:      "should not match wrong header value",
+			bypass:    bypass,
+			headers:   http.Header{"X-Health-Check": []string{"not_secret"}},
+			accountID: "account_1",
+			want:      false,
+		},
+		{
+			name:      "should not match request without bypass headers",
+			bypass:    bypass,
+			headers:   http.Header{},
+			accountID: "account_1",
+			want:      false,
+		},
+		{
+			name: "should not match account not in account IDs",
+			bypass: &HealthCheckBypass{
+				UserAgentPrefixes: []string{"Grove-Healthcheck/"},
+				AccountIDs:        map[store.AccountID]bool{"account_2": true},
+			},
+			headers:   http.Header{"User-Agent": []string{"Grove-Healthcheck/1.0"}},
+			accountID: "account_1",
+			want:      false,
+		},
+		{
+			name:      "should not match if no bypass is configured",
+			bypass:    nil,
+			headers:   http.Header{"User-Agent": []string{"Grove-Healthcheck/1.0"}},
+			accountID: "account_1",
+			want:      false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.want, test.bypass.matches(test.headers, test.accountID))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// schemeHTTPS is the request scheme required by the HTTPS requirement.
+const schemeHTTPS = "https"
+
+// HTTPSRequirement configures which portal apps may only be requested over HTTPS.
+//
+//   - Plaintext requests to a portal app requiring HTTPS are denied with a 426
+//   - The request scheme is read from the Envoy AttributeContext
+type HTTPSRequirement struct {
+	// All requires HTTPS for every portal app
+	All bool
+	// PortalAppIDs requires HTTPS for specific portal apps; ignored if All is set
+	PortalAppIDs map[store.PortalAppID]bool
+}
+
+// ParseHTTPSRequirement parses an HTTPSRequirement from a global flag and comma-separated portal app IDs.
+//
+//   - Example: ParseHTTPSRequirement(false, "1a2b3c4d,5e6f7g8h")
+//   - Returns nil if HTTPS is not required for any portal app
+func ParseHTTPSRequirement(all bool, portalAppIDs string) *HTTPSRequirement {
+	requirement := &HTTPSRequirement{All: all}
+
+	for _, portalAppID := range splitAndTrim(portalAppIDs) {
+		if requirement.PortalAppIDs == nil {
+			requirement.PortalAppIDs = make(map[store.PortalAppID]bool)
+		}
+		requirement.PortalAppIDs[store.PortalAppID(portalAppID)] = true
+	}
+
+	if !requirement.All && len(requirement.PortalAppIDs) == 0 {
+		return nil
+	}
+	return requirement
+}
+
+// isSatisfied returns false if the portal app requires HTTPS and the request scheme is not HTTPS.
+func (r *HTTPSRequirement) isSatisfied(portalAppID store.PortalAppID, scheme string) bool {
+	if r == nil || (!r.All && !r.PortalAppIDs[portalAppID]) {
+		return true
+	}
+	return strings.EqualFold(scheme, schemeHTTPS)
+}
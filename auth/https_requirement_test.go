@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseHTTPSRequirement(t *testing.T) {
+	tests := []struct {
+		name         string
+		all          bool
+		portalAppIDs string
+		want         *HTTPSRequirement
+	}{
+		{
+			name: "should return nil if HTTPS is not required",
+			want: nil,
+		},
+		{
+			name: "should require HTTPS for all portal apps",
+			all:  true,
+			want: &HTTPSRequirement{All: true},
+		},
+		{
+			name:         "should require HTTPS for specific portal apps",
+			portalAppIDs: "portal_app_1, portal_app_2",
+			want: &HTTPSRequirement{
+				PortalAppIDs: map[store.PortalAppID]bool{"portal_app_1": true, "portal_app_2": true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.want, ParseHTTPSRequirement(test.all, test.portalAppIDs))
+		})
+	}
+}
+
+func Test_HTTPSRequirement_isSatisfied(t *testing.T) {
+	tests := []struct {
+		name        string
+		requirement *HTTPSRequirement
+		portalAppID store.PortalAppID
+		scheme      string
+		want        bool
+	}{
+		{
+			name:        "should be satisfied if no requirement is configured",
+			requirement: nil,
+			portalAppID: "portal_app_1",
+			scheme:      "http",
+			want:        true,
+		},
+		{
+			name:        "should be satisfied by https request if required for all portal apps",
+			requirement: &HTTPSRequirement{All: true},
+			portalAppID: "portal_app_1",
+			scheme:      "https",
+			want:        true,
+		},
+		{
+			name:        "should match scheme case-insensitively",
+			requirement: &HTTPSRequirement{All: true},
+			portalAppID: "portal_app_1",
+			scheme:      "HTTPS",
+			want:        true,
+		},
+		{
+			name:        "should not be satisfied by http request if required for all portal apps",
+			requirement: &HTTPSRequirement{All: true},
+			portalAppID: "portal_app_1",
+			scheme:      "http",
+			want:        false,
+		},
+		{
+			name:        "should not be satisfied by http request to a portal app requiring HTTPS",
+			requirement: &HTTPSRequirement{PortalAppIDs: map[store.PortalAppID]bool{"portal_app_1": true}},
+			portalAppID: "portal_app_1",
+			scheme:      "http",
+			want:        false,
+		},
+		{
+			name:        "should be satisfied by http request to a portal app not requiring HTTPS",
+			requirement: &HTTPSRequirement{PortalAppIDs: map[store.PortalAppID]bool{"portal_app_1": true}},
+			portalAppID: "portal_app_2",
+			scheme:      "http",
+			want:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.want, test.requirement.isSatisfied(test.portalAppID, test.scheme))
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package auth
+
+import "strings"
+
+// pathTraversalSequences are the lowercase plain and percent-encoded forms of ".." denied in request paths.
+//   - "%252e" is a double-encoded "." and is denied on its own, as it has no legitimate use in a request path
+var pathTraversalSequences = []string{"..", "%2e%2e", ".%2e", "%2e.", "%252e"}
+
+// hasPathTraversal returns true if the path contains a ".." sequence, in plain or percent-encoded form.
+//   - Only the path is checked; the query string may legitimately contain ".."
+//   - Encoded sequences are matched case-insensitively (e.g. "%2E%2E")
+func hasPathTraversal(path string) bool {
+	path, _, _ = strings.Cut(path, "?")
+	path = strings.ToLower(path)
+
+	for _, sequence := range pathTraversalSequences {
+		if strings.Contains(path, sequence) {
+			return true
+		}
+	}
+	return false
+}
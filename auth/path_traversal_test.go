@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_hasPathTraversal(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "should detect plain traversal",
+			path:     "/v1/../admin/config",
+			expected: true,
+		},
+		{
+			name:     "should detect trailing traversal",
+			path:     "/v1/1a2b3c4d/..",
+			expected: true,
+		},
+		{
+			name:     "should detect encoded traversal",
+			path:     "/v1/%2e%2e/admin",
+			expected: true,
+		},
+		{
+			name:     "should detect uppercase encoded traversal",
+			path:     "/v1/%2E%2E/admin",
+			expected: true,
+		},
+		{
+			name:     "should detect partially encoded traversal",
+			path:     "/v1/.%2e/admin",
+			expected: true,
+		},
+		{
+			name:     "should detect traversal with an encoded separator",
+			path:     "/v1/..%2fadmin",
+			expected: true,
+		},
+		{
+			name:     "should detect double-encoded traversal",
+			path:     "/v1/%252e%252e/admin",
+			expected: true,
+		},
+		{
+			name:     "should detect traversal with backslash separators",
+			path:     `/v1/..\admin`,
+			expected: true,
+		},
+		{
+			name:     "should allow benign path",
+			path:     "/v1/1a2b3c4d",
+			expected: false,
+		},
+		{
+			name:     "should allow path with single dots",
+			path:     "/v1/1a2b3c4d/./file.json",
+			expected: false,
+		},
+		{
+			name:     "should allow path with other encoded characters",
+			path:     "/v1/1a2b3c4d/%2ejson%20file",
+			expected: false,
+		},
+		{
+			name:     "should not check the query string",
+			path:     "/v1/1a2b3c4d?range=1..10",
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expected, hasPathTraversal(test.path))
+		})
+	}
+}
+
+func Test_Check_DenyPathTraversal(t *testing.T) {
+	portalApp := &store.PortalApp{ID: "1a2b3c4d", AccountID: "account_1"}
+
+	tests := []struct {
+		name           string
+		deny           bool
+		path           string
+		expectedLookup store.PortalAppID
+		expectedCode   envoy_type.StatusCode
+		expectedBody   string
+	}{
+		{
+			name:         "should deny path traversal with 400 without looking up the portal app if enabled",
+			deny:         true,
+			path:         "/v1/%2e%2e/1a2b3c4d",
+			expectedCode: envoy_type.StatusCode_BadRequest,
+			expectedBody: `{"code": 400, "message": "path traversal is not allowed"}`,
+		},
+		{
+			name:           "should allow benign path if enabled",
+			deny:           true,
+			path:           "/v1/1a2b3c4d",
+			expectedLookup: portalApp.ID,
+			expectedCode:   envoy_type.StatusCode_OK,
+		},
+		{
+			name:           "should extract the traversal sequence as the portal app ID if disabled",
+			deny:           false,
+			path:           "/v1/%2e%2e/1a2b3c4d",
+			expectedLookup: "%2e%2e",
+			expectedCode:   envoy_type.StatusCode_NotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			if test.expectedLookup != "" {
+				found := test.expectedLookup == portalApp.ID
+				mockPortalAppStore.EXPECT().GetPortalApp(test.expectedLookup).Return(portalApp, found)
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithDenyPathTraversal(test.deny),
+			)
+
+			deniedCountBefore := getAuthRequestErrorTypeCount(t, metrics.AuthRequestErrorTypeInvalidRequestPathTraversal)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: test.path,
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+
+			deniedCount := getAuthRequestErrorTypeCount(t, metrics.AuthRequestErrorTypeInvalidRequestPathTraversal) - deniedCountBefore
+			if test.expectedBody != "" {
+				c.Equal(test.expectedBody, resp.GetDeniedResponse().GetBody())
+			}
+			if test.expectedCode == envoy_type.StatusCode_BadRequest {
+				c.Equal(float64(1), deniedCount)
+			} else {
+				c.Zero(deniedCount)
+			}
+		})
+	}
+}
+
+// getAuthRequestErrorTypeCount returns the number of auth requests counted with the error type, across all other labels.
+func getAuthRequestErrorTypeCount(t *testing.T, errorType string) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	var count float64
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_auth_requests_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "error_type" && label.GetValue() == errorType {
+					count += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// headerNameRegex matches valid HTTP header names for configurable headers (e.g. plan headers).
+var headerNameRegex = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// PlanHeaders maps a plan type to a header set on all authorized requests from portal apps on that plan.
+//
+//   - The header value is the account ID, matching the "Rl-Cost-<n>" header
+//   - GUARD may use plan headers to apply plan-level default throughput limits
+//   - Plan headers are set alongside any other rate limit headers for the request
+type PlanHeaders map[store.PlanType]string
+
+// ParsePlanHeaders parses a comma-separated list of `<plan type>:<header>` pairs.
+//   - Example: "PLAN_FREE:Rl-Plan-Free,PLAN_PRO:Rl-Plan-Pro"
+func ParsePlanHeaders(s string) (PlanHeaders, error) {
+	planHeaders := make(PlanHeaders)
+
+	for _, pair := range strings.Split(s, ",") {
+		planTypeStr, header, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid plan header %q: expected <plan type>:<header>", pair)
+		}
+
+		planType := store.PlanType(strings.TrimSpace(planTypeStr))
+		if planType == "" {
+			return nil, fmt.Errorf("invalid plan header %q: empty plan type", pair)
+		}
+		if _, exists := planHeaders[planType]; exists {
+			return nil, fmt.Errorf("duplicate plan header for plan type %q", planType)
+		}
+
+		header = strings.TrimSpace(header)
+		if !headerNameRegex.MatchString(header) {
+			return nil, fmt.Errorf("invalid plan header name %q for plan type %q", header, planType)
+		}
+
+		planHeaders[planType] = header
+	}
+
+	return planHeaders, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParsePlanHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    PlanHeaders
+		wantErr bool
+	}{
+		{
+			name:  "should parse a single plan header",
+			input: "PLAN_PRO:Rl-Plan-Pro",
+			want:  PlanHeaders{"PLAN_PRO": "Rl-Plan-Pro"},
+		},
+		{
+			name:  "should parse multiple plan headers with whitespace",
+			input: "PLAN_FREE:Rl-Plan-Free, PLAN_PRO : Rl-Plan-Pro",
+			want: PlanHeaders{
+				"PLAN_FREE": "Rl-Plan-Free",
+				"PLAN_PRO":  "Rl-Plan-Pro",
+			},
+		},
+		{
+			name:    "should error on missing header",
+			input:   "PLAN_PRO",
+			wantErr: true,
+		},
+		{
+			name:    "should error on empty plan type",
+			input:   ":Rl-Plan-Pro",
+			wantErr: true,
+		},
+		{
+			name:    "should error on invalid header name",
+			input:   "PLAN_PRO:Rl Plan Pro",
+			wantErr: true,
+		},
+		{
+			name:    "should error on duplicate plan type",
+			input:   "PLAN_PRO:Rl-Plan-Pro,PLAN_PRO:Rl-Pro",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			got, err := ParsePlanHeaders(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, got)
+		})
+	}
+}
@@ -1,30 +1,103 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/buildwithgrove/path-external-auth-server/store"
 )
 
+// errMalformedPortalAppID is returned when the portal app ID does not match the configured format.
+// The malformed ID is intentionally not included, as it is untrusted client input.
+var errMalformedPortalAppID = errors.New("malformed portal app ID")
+
+// ParsePortalAppIDFormat compiles a portal app ID format regex.
+//   - The regex is anchored to match the entire portal app ID.
+//   - Example: "[0-9a-f]{8}"
+func ParsePortalAppIDFormat(s string) (*regexp.Regexp, error) {
+	format, err := regexp.Compile(`^(?:` + s + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid portal app ID format %q: %w", s, err)
+	}
+	return format, nil
+}
+
+// ParsePathPrefix validates a path prefix preceding the portal app ID in request paths.
+//   - The prefix must start and end with "/"
+//   - Example: "/v1/", "/api/v2/"
+func ParsePathPrefix(s string) (string, error) {
+	if !strings.HasPrefix(s, "/") || !strings.HasSuffix(s, "/") {
+		return "", fmt.Errorf("invalid path prefix %q: must start and end with \"/\"", s)
+	}
+	return s, nil
+}
+
+// ParsePathPrefixes parses a comma-separated list of path prefixes (see ParsePathPrefix).
+//   - Example: "/v1/,/relay/"
+func ParsePathPrefixes(s string) ([]string, error) {
+	var prefixes []string
+
+	seen := make(map[string]bool)
+	for _, prefix := range splitAndTrim(s) {
+		if _, err := ParsePathPrefix(prefix); err != nil {
+			return nil, err
+		}
+		if seen[prefix] {
+			return nil, fmt.Errorf("duplicate path prefix %q", prefix)
+		}
+		seen[prefix] = true
+
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes, nil
+}
+
+// matchPathPrefix returns the longest of the path prefixes the path starts with.
+//   - The longest prefix wins when prefixes overlap (e.g. "/v1/beta/" over "/v1/" for "/v1/beta/1a2b3c4d")
+//   - Returns false if the path starts with none of the prefixes
+func matchPathPrefix(path string, pathPrefixes []string) (string, bool) {
+	var match string
+	for _, prefix := range pathPrefixes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	return match, match != ""
+}
+
 // extractPortalAppID extracts the portal app ID from an HTTP request.
 //
 // Extraction order:
-// - Try to extract from the header first
+// - Try to extract from the portal app ID header first
 // - If not found, try to extract from the URL path
 // - If neither method succeeds, return an error
-func extractPortalAppID(headers http.Header, path string) (store.PortalAppID, error) {
-	if id := extractPortalAppIDFromHeader(headers); id != "" {
-		return id, nil
+// - If a format is set and the extracted ID does not match it, return errMalformedPortalAppID
+func extractPortalAppID(
+	headers http.Header,
+	headerName string,
+	path string,
+	pathPrefixes []string,
+	format *regexp.Regexp,
+) (store.PortalAppID, error) {
+	id := extractPortalAppIDFromHeader(headers, headerName)
+	if id == "" {
+		id = extractPortalAppIDFromPath(path, pathPrefixes)
 	}
-	if id := extractPortalAppIDFromPath(path); id != "" {
-		return id, nil
+	if id == "" {
+		return "", fmt.Errorf("portal app ID not provided in header or path")
+	}
+
+	if format != nil && !format.MatchString(string(id)) {
+		return "", errMalformedPortalAppID
 	}
-	return "", fmt.Errorf("portal app ID not provided in header or path")
+	return id, nil
 }
 
-// extractPortalAppIDFromHeader gets the portal app ID from HTTP headers.
+// extractPortalAppIDFromHeader gets the portal app ID from the named HTTP header.
 //
 // - Returns the portal app ID if present and non-empty
 // - Returns an empty string if not found
@@ -33,9 +106,9 @@ func extractPortalAppID(headers http.Header, path string) (store.PortalAppID, er
 //
 //	Header: "Portal-Application-ID: 1a2b3c4d"
 //	Returns: "1a2b3c4d"
-func extractPortalAppIDFromHeader(headers http.Header) store.PortalAppID {
+func extractPortalAppIDFromHeader(headers http.Header, headerName string) store.PortalAppID {
 	// Use http.Header's Get method which is case-insensitive
-	portalAppID := headers.Get(reqHeaderPortalAppID)
+	portalAppID := headers.Get(headerName)
 	if portalAppID == "" {
 		return ""
 	}
@@ -45,16 +118,16 @@ func extractPortalAppIDFromHeader(headers http.Header) store.PortalAppID {
 
 // extractPortalAppIDFromPath gets the portal app ID from the URL path.
 //
-// - Expects path to start with "/v1/"
-// - Returns the first segment after the prefix as the portal app ID
+// - Expects path to start with one of the path prefixes (e.g. "/v1/" or "/relay/")
+// - Returns the first segment after the longest matching prefix as the portal app ID
 // - Returns an empty string if not found
 //
 // Example:
 //
 //	Path: "/v1/1a2b3c4d"
 //	Returns: "1a2b3c4d"
-func extractPortalAppIDFromPath(path string) store.PortalAppID {
-	if strings.HasPrefix(path, pathPrefix) {
+func extractPortalAppIDFromPath(path string, pathPrefixes []string) store.PortalAppID {
+	if pathPrefix, ok := matchPathPrefix(path, pathPrefixes); ok {
 		segments := strings.Split(strings.TrimPrefix(path, pathPrefix), "/")
 		if len(segments) > 0 && segments[0] != "" {
 			return store.PortalAppID(segments[0])
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"net/http"
+	"regexp"
+	"slices"
 	"testing"
 
 	"github.com/buildwithgrove/path-external-auth-server/store"
@@ -12,6 +14,7 @@ func Test_extractPortalAppID(t *testing.T) {
 		name    string
 		headers http.Header
 		path    string
+		format  string
 		want    store.PortalAppID
 		wantErr bool
 	}{
@@ -45,11 +48,63 @@ func Test_extractPortalAppID(t *testing.T) {
 			want:    "",
 			wantErr: true,
 		},
+		{
+			name:    "should extract portal app ID matching the format",
+			headers: http.Header{},
+			path:    "/v1/1a2b3c4d",
+			format:  "[0-9a-f]{8}",
+			want:    "1a2b3c4d",
+			wantErr: false,
+		},
+		{
+			name: "should extract header portal app ID matching the format",
+			headers: convertMapToHeader(map[string]string{
+				reqHeaderPortalAppID: "1a2b3c4d",
+			}),
+			path:    "/v1/",
+			format:  "[0-9a-f]{8}",
+			want:    "1a2b3c4d",
+			wantErr: false,
+		},
+		{
+			name:    "should error on SQL-injection-looking portal app ID",
+			headers: http.Header{},
+			path:    "/v1/1'%20OR%20'1'='1",
+			format:  "[0-9a-f]{8}",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "should error on portal app ID longer than the format",
+			headers: http.Header{},
+			path:    "/v1/1a2b3c4d1a2b3c4d1a2b3c4d",
+			format:  "[0-9a-f]{8}",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name: "should error on header portal app ID with control characters",
+			headers: convertMapToHeader(map[string]string{
+				reqHeaderPortalAppID: "1a2b\x003c4d",
+			}),
+			path:    "/v1/1a2b3c4d",
+			format:  "[0-9a-f]{8}",
+			want:    "",
+			wantErr: true,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got, err := extractPortalAppID(test.headers, test.path)
+			var format *regexp.Regexp
+			if test.format != "" {
+				var err error
+				if format, err = ParsePortalAppIDFormat(test.format); err != nil {
+					t.Fatalf("ParsePortalAppIDFormat() error = %v", err)
+				}
+			}
+
+			got, err := extractPortalAppID(test.headers, reqHeaderPortalAppID, test.path, []string{defaultPathPrefix}, format)
 			if (err != nil) != test.wantErr {
 				t.Errorf("extractPortalAppID() error = %v, wantErr %v", err, test.wantErr)
 				return
@@ -63,34 +118,54 @@ func Test_extractPortalAppID(t *testing.T) {
 
 func Test_extractFromHeader(t *testing.T) {
 	tests := []struct {
-		name    string
-		headers http.Header
-		want    store.PortalAppID
+		name       string
+		headerName string
+		headers    http.Header
+		want       store.PortalAppID
 	}{
 		{
-			name: "should extract portal app ID from header",
+			name:       "should extract portal app ID from header",
+			headerName: reqHeaderPortalAppID,
 			headers: convertMapToHeader(map[string]string{
 				reqHeaderPortalAppID: "1a2b3c4d",
 			}),
 			want: "1a2b3c4d",
 		},
 		{
-			name:    "should return empty when header is missing",
-			headers: http.Header{},
-			want:    "",
+			name:       "should return empty when header is missing",
+			headerName: reqHeaderPortalAppID,
+			headers:    http.Header{},
+			want:       "",
 		},
 		{
-			name: "should return empty when header is empty",
+			name:       "should return empty when header is empty",
+			headerName: reqHeaderPortalAppID,
 			headers: http.Header{
 				reqHeaderPortalAppID: []string{""},
 			},
 			want: "",
 		},
+		{
+			name:       "should extract portal app ID from a custom header case-insensitively",
+			headerName: "X-Portal-App-ID",
+			headers: convertMapToHeader(map[string]string{
+				"x-portal-app-id": "1a2b3c4d",
+			}),
+			want: "1a2b3c4d",
+		},
+		{
+			name:       "should ignore the default header when a custom header is configured",
+			headerName: "X-Portal-App-ID",
+			headers: convertMapToHeader(map[string]string{
+				reqHeaderPortalAppID: "1a2b3c4d",
+			}),
+			want: "",
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got := extractPortalAppIDFromHeader(test.headers)
+			got := extractPortalAppIDFromHeader(test.headers, test.headerName)
 			if got != test.want {
 				t.Errorf("extractFromHeader() = %v, want %v", got, test.want)
 			}
@@ -100,33 +175,186 @@ func Test_extractFromHeader(t *testing.T) {
 
 func Test_extractFromPath(t *testing.T) {
 	tests := []struct {
-		name string
-		path string
-		want store.PortalAppID
+		name         string
+		pathPrefixes []string
+		path         string
+		want         store.PortalAppID
 	}{
 		{
-			name: "should extract portal app ID from valid path",
-			path: "/v1/1a2b3c4d",
-			want: "1a2b3c4d",
+			name:         "should extract portal app ID from valid path",
+			pathPrefixes: []string{"/v1/"},
+			path:         "/v1/1a2b3c4d",
+			want:         "1a2b3c4d",
 		},
 		{
-			name: "should return empty for path without portal app ID",
-			path: "/v1/",
-			want: "",
+			name:         "should return empty for path without portal app ID",
+			pathPrefixes: []string{"/v1/"},
+			path:         "/v1/",
+			want:         "",
 		},
 		{
-			name: "should return empty for invalid path",
-			path: "/invalid/1a2b3c4d",
-			want: "",
+			name:         "should return empty for invalid path",
+			pathPrefixes: []string{"/v1/"},
+			path:         "/invalid/1a2b3c4d",
+			want:         "",
+		},
+		{
+			name:         "should extract portal app ID from path with a configured prefix",
+			pathPrefixes: []string{"/api/v2/"},
+			path:         "/api/v2/1a2b3c4d/cosmos/tx",
+			want:         "1a2b3c4d",
+		},
+		{
+			name:         "should return empty for path with the default prefix if a different prefix is configured",
+			pathPrefixes: []string{"/api/v2/"},
+			path:         "/v1/1a2b3c4d",
+			want:         "",
+		},
+		{
+			name:         "should extract portal app ID from the first segment with a root prefix",
+			pathPrefixes: []string{"/"},
+			path:         "/1a2b3c4d/cosmos/tx",
+			want:         "1a2b3c4d",
+		},
+		{
+			name:         "should extract portal app ID from path with any of multiple prefixes",
+			pathPrefixes: []string{"/v1/", "/relay/"},
+			path:         "/relay/1a2b3c4d",
+			want:         "1a2b3c4d",
+		},
+		{
+			name:         "should return empty for path with none of multiple prefixes",
+			pathPrefixes: []string{"/v1/", "/relay/"},
+			path:         "/v2/1a2b3c4d",
+			want:         "",
+		},
+		{
+			name:         "should use the longest prefix when prefixes overlap",
+			pathPrefixes: []string{"/v1/", "/v1/beta/"},
+			path:         "/v1/beta/1a2b3c4d",
+			want:         "1a2b3c4d",
+		},
+		{
+			name:         "should use the longest prefix when prefixes overlap regardless of their order",
+			pathPrefixes: []string{"/v1/beta/", "/v1/"},
+			path:         "/v1/beta/1a2b3c4d",
+			want:         "1a2b3c4d",
+		},
+		{
+			name:         "should use the shorter prefix if the path does not start with the longer overlapping prefix",
+			pathPrefixes: []string{"/v1/", "/v1/beta/"},
+			path:         "/v1/1a2b3c4d",
+			want:         "1a2b3c4d",
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got := extractPortalAppIDFromPath(test.path)
+			got := extractPortalAppIDFromPath(test.path, test.pathPrefixes)
 			if got != test.want {
 				t.Errorf("extractFromPath() = %v, want %v", got, test.want)
 			}
 		})
 	}
 }
+
+func Test_ParsePathPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		wantErr bool
+	}{
+		{name: "should accept the default prefix", prefix: "/v1/"},
+		{name: "should accept a nested prefix", prefix: "/api/v2/"},
+		{name: "should accept the root prefix", prefix: "/"},
+		{name: "should reject a prefix without a leading slash", prefix: "v1/", wantErr: true},
+		{name: "should reject a prefix without a trailing slash", prefix: "/v1", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefix, err := ParsePathPrefix(test.prefix)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParsePathPrefix() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && prefix != test.prefix {
+				t.Errorf("ParsePathPrefix() = %v, want %v", prefix, test.prefix)
+			}
+		})
+	}
+}
+
+func Test_ParsePathPrefixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "should parse comma-separated prefixes in order", prefixes: "/v1/, /relay/", want: []string{"/v1/", "/relay/"}},
+		{name: "should parse overlapping prefixes", prefixes: "/v1/,/v1/beta/", want: []string{"/v1/", "/v1/beta/"}},
+		{name: "should reject an invalid prefix", prefixes: "/v1/,relay", wantErr: true},
+		{name: "should reject a duplicate prefix", prefixes: "/v1/,/v1/", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefixes, err := ParsePathPrefixes(test.prefixes)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParsePathPrefixes() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && !slices.Equal(prefixes, test.want) {
+				t.Errorf("ParsePathPrefixes() = %v, want %v", prefixes, test.want)
+			}
+		})
+	}
+}
+
+func Test_ParsePortalAppIDFormat(t *testing.T) {
+	tests := []struct {
+		name         string
+		format       string
+		portalAppIDs map[string]bool
+		wantErr      bool
+	}{
+		{
+			name:   "should match the entire portal app ID",
+			format: "[0-9a-f]{8}",
+			portalAppIDs: map[string]bool{
+				"1a2b3c4d":   true,
+				"1a2b3c4":    false,
+				"1a2b3c4d5e": false,
+				"x1a2b3c4d":  false,
+			},
+		},
+		{
+			name:   "should anchor alternations to the entire portal app ID",
+			format: "[0-9a-f]{8}|app_[a-z]+",
+			portalAppIDs: map[string]bool{
+				"1a2b3c4d":        true,
+				"app_test":        true,
+				"1a2b3c4d_suffix": false,
+				"prefix_app_test": false,
+			},
+		},
+		{
+			name:    "should error on invalid regex",
+			format:  "[0-9a-f",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := ParsePortalAppIDFormat(test.format)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParsePortalAppIDFormat() error = %v, wantErr %v", err, test.wantErr)
+			}
+			for portalAppID, want := range test.portalAppIDs {
+				if got := format.MatchString(portalAppID); got != want {
+					t.Errorf("format.MatchString(%q) = %v, want %v", portalAppID, got, want)
+				}
+			}
+		})
+	}
+}
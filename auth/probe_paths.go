@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// probeStatusMessage is the gRPC status message of probe responses, shown in Envoy logs.
+const probeStatusMessage = "probe path"
+
+// ProbePaths is a set of request paths (e.g. "/v1", "/healthz") answered with a canned 200 and an empty body,
+// so probes traversing the ext_authz filter (e.g. load balancer health checks) are not denied.
+//
+//   - Paths match exactly, ignoring any query string
+//   - Probe requests skip every check: no portal app is looked up, and no auth or rate limiting is applied
+//   - The response is served by Envoy directly, so probe requests are not forwarded upstream
+type ProbePaths map[string]bool
+
+// ParseProbePaths parses ProbePaths from a comma-separated list of paths.
+//
+//   - Example: ParseProbePaths("/v1,/healthz")
+//   - Each path must start with "/" and must not contain a query string
+//   - Returns nil if no paths are provided
+func ParseProbePaths(s string) (ProbePaths, error) {
+	var probePaths ProbePaths
+	for _, path := range splitAndTrim(s) {
+		if !strings.HasPrefix(path, "/") || strings.Contains(path, "?") {
+			return nil, fmt.Errorf("invalid probe path %q: must start with / and must not contain a query string", path)
+		}
+		if probePaths == nil {
+			probePaths = make(ProbePaths)
+		}
+		probePaths[path] = true
+	}
+	return probePaths, nil
+}
+
+// matches returns true if the request path, ignoring any query string, is a probe path.
+func (p ProbePaths) matches(path string) bool {
+	if len(p) == 0 {
+		return false
+	}
+	path, _, _ = strings.Cut(path, "?")
+	return p[path]
+}
+
+// getProbeCheckResponse returns the canned response for probe requests: a 200 with an empty body and no headers.
+//   - Returned as a denied response, since only a denied response is served by Envoy rather than forwarded upstream
+func getProbeCheckResponse() *envoy_auth.CheckResponse {
+	return &envoy_auth.CheckResponse{
+		Status: &status.Status{
+			Code:    int32(codes.PermissionDenied),
+			Message: probeStatusMessage,
+		},
+		HttpResponse: &envoy_auth.CheckResponse_DeniedResponse{
+			DeniedResponse: &envoy_auth.DeniedHttpResponse{
+				Status: &envoy_type.HttpStatus{
+					Code: envoy_type.StatusCode_OK,
+				},
+			},
+		},
+	}
+}
+
+// isProbeCheckResponse returns true if the response is the canned response for probe requests.
+func isProbeCheckResponse(resp *envoy_auth.CheckResponse) bool {
+	return resp.GetDeniedResponse().GetStatus().GetCode() == envoy_type.StatusCode_OK
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseProbePaths(t *testing.T) {
+	c := require.New(t)
+
+	probePaths, err := ParseProbePaths(" /v1, /healthz ,")
+	c.NoError(err)
+	c.Equal(ProbePaths{"/v1": true, "/healthz": true}, probePaths)
+
+	probePaths, err = ParseProbePaths("")
+	c.NoError(err)
+	c.Nil(probePaths)
+
+	for _, invalid := range []string{"v1", "/v1,healthz", "/healthz?full=true"} {
+		_, err := ParseProbePaths(invalid)
+		c.Error(err, "probe paths %q", invalid)
+	}
+}
+
+func Test_Check_ProbePaths(t *testing.T) {
+	portalApp := &store.PortalApp{
+		ID:        "portal_app_1",
+		AccountID: "account_1",
+		PlanType:  grovedb.PlanUnlimited_DatabaseType,
+	}
+	probePaths := ProbePaths{"/v1": true, "/healthz": true}
+
+	tests := []struct {
+		name                 string
+		path                 string
+		expectedProbe        bool
+		expectedCode         envoy_type.StatusCode
+		expectedPortalAppHit bool
+	}{
+		{
+			name:          "should answer a probe path with a canned 200",
+			path:          "/healthz",
+			expectedProbe: true,
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+		{
+			name:          "should answer a probe path with no portal app ID with a canned 200",
+			path:          "/v1",
+			expectedProbe: true,
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+		{
+			name:          "should answer a probe path with a query string with a canned 200",
+			path:          "/healthz?verbose=true",
+			expectedProbe: true,
+			expectedCode:  envoy_type.StatusCode_OK,
+		},
+		{
+			name:                 "should authorize a real path under a probe path",
+			path:                 "/v1/portal_app_1",
+			expectedCode:         envoy_type.StatusCode_OK,
+			expectedPortalAppHit: true,
+		},
+		{
+			name:         "should deny a real path with no portal app ID",
+			path:         "/v1/",
+			expectedCode: envoy_type.StatusCode_BadRequest,
+		},
+		{
+			name:         "should deny a path that only starts with a probe path",
+			path:         "/healthz/full",
+			expectedCode: envoy_type.StatusCode_BadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			if test.expectedPortalAppHit {
+				mockPortalAppStore.EXPECT().GetPortalApp(portalApp.ID).Return(portalApp, true)
+			}
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			if !test.expectedProbe {
+				mockRateLimitStore.EXPECT().IsAvailable().Return(true).AnyTimes()
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithProbePaths(probePaths),
+				WithRateLimitFailureMode(RateLimitFailOpenStale),
+			)
+
+			resp, err := authHandler.Check(context.Background(), newTestCheckRequest(test.path))
+			c.NoError(err)
+			c.Equal(int32(test.expectedCode), getHTTPStatusCode(resp))
+			c.Equal(test.expectedProbe, isProbeCheckResponse(resp))
+
+			if test.expectedProbe {
+				c.Empty(resp.GetDeniedResponse().GetBody())
+				c.Empty(resp.GetDeniedResponse().GetHeaders())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// QueryParamAllowlist is the set of query parameter names expected on requests.
+//   - Names are matched exactly (case-sensitive)
+type QueryParamAllowlist map[string]bool
+
+// ParseQueryParamAllowlist parses a comma-separated list of expected query parameter names.
+//   - Example: "network,debug"
+//   - Returns an empty allowlist if s is empty, so every query parameter is unexpected
+func ParseQueryParamAllowlist(s string) QueryParamAllowlist {
+	allowlist := make(QueryParamAllowlist)
+	for _, name := range splitAndTrim(s) {
+		allowlist[name] = true
+	}
+	return allowlist
+}
+
+// getUnexpectedQueryParams returns the sorted, deduplicated names of the path's query parameters not in the allowlist.
+//   - Returns nil if the path has no query string.
+func (l QueryParamAllowlist) getUnexpectedQueryParams(path string) []string {
+	_, rawQuery, ok := strings.Cut(path, "?")
+	if !ok || rawQuery == "" {
+		return nil
+	}
+
+	// Parse errors are ignored: parameters preceding an invalid one are still returned
+	query, _ := url.ParseQuery(rawQuery)
+
+	var unexpected []string
+	for name := range query {
+		if !l[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+	slices.Sort(unexpected)
+	return unexpected
+}
+
+// checkUnexpectedQueryParams logs and counts requests carrying query parameters outside the allowlist, if strict mode is enabled.
+//   - Requests are never denied: strict mode is an observability aid for API hygiene.
+//   - Only parameter names are logged, as values may contain sensitive client input.
+func (a *authHandler) checkUnexpectedQueryParams(logger polylog.Logger, portalApp *store.PortalApp, path string) {
+	if !a.queryParamStrictMode {
+		return
+	}
+
+	unexpected := a.queryParamAllowlist.getUnexpectedQueryParams(path)
+	if len(unexpected) == 0 {
+		return
+	}
+
+	metrics.RecordUnexpectedQueryParams(string(portalApp.ID))
+	logger.Info().
+		Str("unexpected_query_params", strings.Join(unexpected, ",")).
+		Msg("🧐 request has unexpected query parameters")
+}
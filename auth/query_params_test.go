@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseQueryParamAllowlist(t *testing.T) {
+	c := require.New(t)
+
+	c.Equal(QueryParamAllowlist{"network": true, "debug": true}, ParseQueryParamAllowlist("network, debug,"))
+	c.Empty(ParseQueryParamAllowlist(""))
+}
+
+func Test_getUnexpectedQueryParams(t *testing.T) {
+	allowlist := QueryParamAllowlist{"network": true, "debug": true}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{
+			name:     "should return nothing for path with no query string",
+			path:     "/v1/1a2b3c4d",
+			expected: nil,
+		},
+		{
+			name:     "should return nothing for path with an empty query string",
+			path:     "/v1/1a2b3c4d?",
+			expected: nil,
+		},
+		{
+			name:     "should return nothing for recognized query parameters",
+			path:     "/v1/1a2b3c4d?network=eth&debug",
+			expected: nil,
+		},
+		{
+			name:     "should return unrecognized query parameters sorted and deduplicated",
+			path:     "/v1/1a2b3c4d?network=eth&token=abc&admin=1&token=def",
+			expected: []string{"admin", "token"},
+		},
+		{
+			name:     "should match query parameter names case-sensitively",
+			path:     "/v1/1a2b3c4d?Network=eth",
+			expected: []string{"Network"},
+		},
+		{
+			name:     "should return unrecognized query parameters preceding an invalid one",
+			path:     "/v1/1a2b3c4d?token=abc&bad=%zz",
+			expected: []string{"token"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expected, allowlist.getUnexpectedQueryParams(test.path))
+		})
+	}
+}
+
+func Test_Check_UnexpectedQueryParams(t *testing.T) {
+	tests := []struct {
+		name          string
+		strictMode    bool
+		portalAppID   store.PortalAppID
+		path          string
+		expectedCount float64
+	}{
+		{
+			name:          "should not count unexpected query parameters if strict mode is disabled",
+			strictMode:    false,
+			portalAppID:   "portal_app_query_disabled",
+			path:          "/v1?token=abc",
+			expectedCount: 0,
+		},
+		{
+			name:          "should not count recognized query parameters in strict mode",
+			strictMode:    true,
+			portalAppID:   "portal_app_query_recognized",
+			path:          "/v1?network=eth",
+			expectedCount: 0,
+		},
+		{
+			name:          "should count but not deny unexpected query parameters in strict mode",
+			strictMode:    true,
+			portalAppID:   "portal_app_query_unexpected",
+			path:          "/v1?network=eth&token=abc",
+			expectedCount: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			portalApp := &store.PortalApp{ID: test.portalAppID, AccountID: "account_1"}
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalAppID).Return(portalApp, true)
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithQueryParamStrictMode(test.strictMode, QueryParamAllowlist{"network": true}),
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path:    test.path,
+							Headers: map[string]string{reqHeaderPortalAppID: string(test.portalAppID)},
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(codes.OK), resp.GetStatus().GetCode())
+			c.Equal(test.expectedCount, getUnexpectedQueryParamsCount(t, test.portalAppID))
+		})
+	}
+}
+
+// getUnexpectedQueryParamsCount returns the number of requests with unexpected query parameters counted for the portal app.
+func getUnexpectedQueryParamsCount(t *testing.T, portalAppID store.PortalAppID) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_unexpected_query_params_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "portal_app_id" && label.GetValue() == string(portalAppID) {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// RateLimitDecisionTTLOverrides maps a portal app ID to the TTL hint of its "Portal-RateLimit-Decision" header,
+// overriding the default TTL for portal apps whose decisions should be cached for less (or more) time.
+//
+//   - A TTL of 0 omits the header for the portal app, so its decisions are never cached downstream
+//   - A positive TTL sets the header for the portal app, even if the default TTL is 0
+type RateLimitDecisionTTLOverrides map[store.PortalAppID]time.Duration
+
+// ParseRateLimitDecisionTTLOverrides parses a comma-separated list of `<portal app ID>:<ttl>` pairs.
+//   - Example: "1a2b3c4d:5s,5e6f7g8h:0s"
+//   - Each TTL must be a non-negative whole number of seconds
+func ParseRateLimitDecisionTTLOverrides(s string) (RateLimitDecisionTTLOverrides, error) {
+	overrides := make(RateLimitDecisionTTLOverrides)
+
+	for _, pair := range splitAndTrim(s) {
+		portalAppID, ttlStr, ok := strings.Cut(pair, ":")
+		portalAppID, ttlStr = strings.TrimSpace(portalAppID), strings.TrimSpace(ttlStr)
+		if !ok || portalAppID == "" {
+			return nil, fmt.Errorf("invalid rate limit decision TTL override %q: expected <portal app ID>:<ttl>", pair)
+		}
+
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil || ttl < 0 || ttl%time.Second != 0 {
+			return nil, fmt.Errorf("invalid rate limit decision TTL %q for portal app %q: must be a non-negative whole number of seconds", ttlStr, portalAppID)
+		}
+
+		if _, exists := overrides[store.PortalAppID(portalAppID)]; exists {
+			return nil, fmt.Errorf("duplicate rate limit decision TTL override for portal app %q", portalAppID)
+		}
+		overrides[store.PortalAppID(portalAppID)] = ttl
+	}
+
+	return overrides, nil
+}
+
+// getTTL returns the portal app's TTL override, or the default TTL if the portal app has no override.
+func (o RateLimitDecisionTTLOverrides) getTTL(portalAppID store.PortalAppID, defaultTTL time.Duration) time.Duration {
+	if ttl, ok := o[portalAppID]; ok {
+		return ttl
+	}
+	return defaultTTL
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseRateLimitDecisionTTLOverrides(t *testing.T) {
+	tests := []struct {
+		name              string
+		input             string
+		expectedOverrides RateLimitDecisionTTLOverrides
+		wantErr           bool
+	}{
+		{
+			name:  "should parse per-app TTL overrides",
+			input: "1a2b3c4d:5s, 5e6f7g8h:0s,9i0j1k2l:2m",
+			expectedOverrides: RateLimitDecisionTTLOverrides{
+				"1a2b3c4d": 5 * time.Second,
+				"5e6f7g8h": 0,
+				"9i0j1k2l": 2 * time.Minute,
+			},
+		},
+		{
+			name:              "should return no overrides for an empty string",
+			input:             "",
+			expectedOverrides: RateLimitDecisionTTLOverrides{},
+		},
+		{
+			name:    "should reject a pair without a TTL",
+			input:   "1a2b3c4d",
+			wantErr: true,
+		},
+		{
+			name:    "should reject a pair without a portal app ID",
+			input:   ":5s",
+			wantErr: true,
+		},
+		{
+			name:    "should reject a negative TTL",
+			input:   "1a2b3c4d:-5s",
+			wantErr: true,
+		},
+		{
+			name:    "should reject a TTL that is not a whole number of seconds",
+			input:   "1a2b3c4d:1500ms",
+			wantErr: true,
+		},
+		{
+			name:    "should reject a duplicate portal app ID",
+			input:   "1a2b3c4d:5s,1a2b3c4d:10s",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			overrides, err := ParseRateLimitDecisionTTLOverrides(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.expectedOverrides, overrides)
+		})
+	}
+}
+
+func Test_getHTTPHeaders_RateLimitDecisionTTLOverrides(t *testing.T) {
+	overrides := RateLimitDecisionTTLOverrides{
+		"portal_app_short": 5 * time.Second,
+		"portal_app_none":  0,
+	}
+
+	tests := []struct {
+		name           string
+		defaultTTL     time.Duration
+		portalAppID    store.PortalAppID
+		expectedHeader string
+	}{
+		{
+			name:           "should use the default TTL for a portal app without an override",
+			defaultTTL:     30 * time.Second,
+			portalAppID:    "portal_app_default",
+			expectedHeader: "ok; ttl=30",
+		},
+		{
+			name:           "should use the portal app's TTL override",
+			defaultTTL:     30 * time.Second,
+			portalAppID:    "portal_app_short",
+			expectedHeader: "ok; ttl=5",
+		},
+		{
+			name:        "should omit the header for a portal app with a zero TTL override",
+			defaultTTL:  30 * time.Second,
+			portalAppID: "portal_app_none",
+		},
+		{
+			name:           "should set the header for a portal app with an override if the default TTL is zero",
+			defaultTTL:     0,
+			portalAppID:    "portal_app_short",
+			expectedHeader: "ok; ttl=5",
+		},
+		{
+			name:        "should omit the header for a portal app without an override if the default TTL is zero",
+			defaultTTL:  0,
+			portalAppID: "portal_app_default",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), nil, nil, []Authorizer{&AuthorizerAPIKey{}},
+				WithRateLimitDecisionHeader(test.defaultTTL),
+				WithRateLimitDecisionHeaderTTLOverrides(overrides),
+			)
+
+			headers := authHandler.getHTTPHeaders(
+				&store.PortalApp{ID: test.portalAppID, AccountID: "account_1"},
+				ratelimit.DecisionOK,
+				1,
+			)
+
+			var decisionHeader string
+			for _, header := range headers {
+				if header.GetHeader().GetKey() == reqHeaderRateLimitDecision {
+					decisionHeader = header.GetHeader().GetValue()
+				}
+			}
+			c.Equal(test.expectedHeader, decisionHeader)
+		})
+	}
+}
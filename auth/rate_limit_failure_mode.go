@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"fmt"
+
+	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+)
+
+// RateLimitFailureMode determines how requests from rate-limit-eligible accounts
+// are handled when the rate limit store is unavailable (e.g. data warehouse outage).
+type RateLimitFailureMode string
+
+const (
+	// RateLimitFailOpen allows requests when rate limit data is unavailable.
+	RateLimitFailOpen RateLimitFailureMode = "fail_open"
+	// RateLimitFailClosed rejects requests from rate-limit-eligible accounts when rate limit data is unavailable.
+	RateLimitFailClosed RateLimitFailureMode = "fail_closed"
+	// RateLimitFailOpenStale allows requests when rate limit data is unavailable, serving the last fetched
+	// rate limit decisions and setting the "Portal-Auth-Stale: true" header on all responses so downstream can log and alert.
+	RateLimitFailOpenStale RateLimitFailureMode = "fail_open_stale"
+)
+
+// defaultRateLimitFailureMode preserves the original behavior of allowing
+// requests when the rate limit store has no usage data.
+const defaultRateLimitFailureMode = RateLimitFailOpen
+
+// ParseRateLimitFailureMode parses a RateLimitFailureMode.
+//   - Valid values are "fail_open", "fail_closed" and "fail_open_stale"
+func ParseRateLimitFailureMode(s string) (RateLimitFailureMode, error) {
+	switch mode := RateLimitFailureMode(s); mode {
+	case RateLimitFailOpen, RateLimitFailClosed, RateLimitFailOpenStale:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid rate limit failure mode %q: must be one of fail_open, fail_closed, fail_open_stale", s)
+	}
+}
+
+// setStaleHeader adds the stale header to an OK or denied CheckResponse.
+func setStaleHeader(resp *envoy_auth.CheckResponse, staleHeader *envoy_core.HeaderValueOption) {
+	if okResponse := resp.GetOkResponse(); okResponse != nil {
+		okResponse.Headers = append(okResponse.Headers, staleHeader)
+		return
+	}
+	if deniedResponse := resp.GetDeniedResponse(); deniedResponse != nil {
+		deniedResponse.Headers = append(deniedResponse.Headers, staleHeader)
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"fmt"
+
+	envoy_core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const (
+	// reqHeaderRateLimitPlanFree is set on requests from rate-limit-eligible PLAN_FREE portal apps.
+	// The Envoy global rate limiter uses it as the descriptor for the free plan limit.
+	reqHeaderRateLimitPlanFree = "Rl-Plan-Free"
+
+	// reqHeaderRateLimitUserLimitPrefix is set on requests from PLAN_UNLIMITED portal apps with a monthly user limit.
+	// The suffix is the limit in millions of relays (e.g. "Rl-User-Limit-40" for a 40M limit).
+	reqHeaderRateLimitUserLimitPrefix = "Rl-User-Limit-"
+
+	// userLimitHeaderUnit is the number of relays in one unit of the "Rl-User-Limit-<n>" header suffix.
+	userLimitHeaderUnit = 1_000_000
+
+	// reqHeaderRateLimitDailyUserLimitPrefix is set on requests from portal apps with a daily user limit, of any plan type.
+	// The suffix is the limit in thousands of relays (e.g. "Rl-User-Daily-Limit-100" for a 100K limit).
+	reqHeaderRateLimitDailyUserLimitPrefix = "Rl-User-Daily-Limit-"
+
+	// dailyUserLimitHeaderUnit is the number of relays in one unit of the "Rl-User-Daily-Limit-<n>" header suffix.
+	dailyUserLimitHeaderUnit = 1_000
+
+	// reqHeaderRateLimitBurstPrefix is set on requests from portal apps with a burst allowance, if enabled.
+	// The suffix is the burst allowance in relays (e.g. "Rl-Burst-50" for a burst of 50 relays).
+	reqHeaderRateLimitBurstPrefix = "Rl-Burst-"
+)
+
+// getRateLimitRequestHeaders returns the Envoy global rate limiter descriptor headers for the portal app,
+// one for each rate limit window configured for it, so GUARD can enforce all windows at once.
+//   - Monthly window: see getMonthlyRateLimitRequestHeader
+//   - Daily window: see getDailyRateLimitRequestHeader
+//   - Burst allowance: see getBurstRateLimitRequestHeader
+//   - Returns no headers if the portal app has no rate limit configured
+func (a *authHandler) getRateLimitRequestHeaders(portalApp *store.PortalApp) []*envoy_core.HeaderValueOption {
+	if portalApp.RateLimit == nil {
+		return nil
+	}
+
+	var headers []*envoy_core.HeaderValueOption
+	if monthlyHeader, ok := a.getMonthlyRateLimitRequestHeader(portalApp); ok {
+		headers = append(headers, monthlyHeader)
+	}
+	if dailyHeader, ok := a.getDailyRateLimitRequestHeader(portalApp); ok {
+		headers = append(headers, dailyHeader)
+	}
+	if burstHeader, ok := a.getBurstRateLimitRequestHeader(portalApp); ok {
+		headers = append(headers, burstHeader)
+	}
+	return headers
+}
+
+// getMonthlyRateLimitRequestHeader returns the monthly plan descriptor header for the portal app.
+//   - PLAN_FREE: "Rl-Plan-Free: <account id>"
+//   - PLAN_UNLIMITED with a monthly user limit: "Rl-User-Limit-<millions>: <account id>", rounded down
+//   - Returns false if its monthly limit is under one million relays,
+//     or a plan header is already configured for its plan type (see PlanHeaders).
+func (a *authHandler) getMonthlyRateLimitRequestHeader(portalApp *store.PortalApp) (*envoy_core.HeaderValueOption, bool) {
+	if _, ok := a.planHeaders[portalApp.PlanType]; ok {
+		return nil, false
+	}
+
+	switch portalApp.PlanType {
+	case grovedb.PlanFree_DatabaseType:
+		return a.newHeaderValueOption(reqHeaderRateLimitPlanFree, string(portalApp.AccountID)), true
+
+	case grovedb.PlanUnlimited_DatabaseType:
+		userLimit := portalApp.RateLimit.MonthlyUserLimit / userLimitHeaderUnit
+		if userLimit <= 0 {
+			return nil, false
+		}
+		return a.newHeaderValueOption(
+			fmt.Sprintf("%s%d", reqHeaderRateLimitUserLimitPrefix, userLimit),
+			string(portalApp.AccountID),
+		), true
+
+	default:
+		return nil, false
+	}
+}
+
+// getDailyRateLimitRequestHeader returns the daily user limit descriptor header for the portal app.
+//   - Any plan type with a daily user limit: "Rl-User-Daily-Limit-<thousands>: <account id>", rounded down
+//   - Returns false if the portal app has no daily user limit, or it is under one thousand relays.
+//   - Daily limits are only enforced by the Envoy global rate limiter: PEAS does not track daily usage.
+func (a *authHandler) getDailyRateLimitRequestHeader(portalApp *store.PortalApp) (*envoy_core.HeaderValueOption, bool) {
+	dailyUserLimit := portalApp.RateLimit.DailyUserLimit / dailyUserLimitHeaderUnit
+	if dailyUserLimit <= 0 {
+		return nil, false
+	}
+	return a.newHeaderValueOption(
+		fmt.Sprintf("%s%d", reqHeaderRateLimitDailyUserLimitPrefix, dailyUserLimit),
+		string(portalApp.AccountID),
+	), true
+}
+
+// getBurstRateLimitRequestHeader returns the burst allowance descriptor header for the portal app,
+// so GUARD's local rate limiter can allow short bursts.
+//   - Any plan type with a burst allowance: "Rl-Burst-<relays>: <account id>"
+//   - Returns false if the burst allowance header is disabled (see WithBurstAllowanceHeader),
+//     or the portal app has no burst allowance.
+func (a *authHandler) getBurstRateLimitRequestHeader(portalApp *store.PortalApp) (*envoy_core.HeaderValueOption, bool) {
+	if !a.burstAllowanceHeaderEnabled || portalApp.RateLimit.BurstAllowance <= 0 {
+		return nil, false
+	}
+	return a.newHeaderValueOption(
+		fmt.Sprintf("%s%d", reqHeaderRateLimitBurstPrefix, portalApp.RateLimit.BurstAllowance),
+		string(portalApp.AccountID),
+	), true
+}
@@ -0,0 +1,22 @@
+package auth
+
+import "time"
+
+// Optionally set on authorized requests and rate limited (429) responses for rate-limit-eligible portal apps.
+// Value is the number of seconds until the account's monthly usage resets (e.g. "86400").
+const reqHeaderRateLimitResetSeconds = "Portal-RateLimit-Reset-Seconds"
+
+// getRateLimitResetSeconds returns the number of seconds from now until monthly usage resets.
+//   - Monthly usage resets at the start of each calendar month in UTC, matching the data warehouse's month-to-date usage.
+//   - Rounds up, so the value is never 0 before the reset.
+func getRateLimitResetSeconds(now time.Time) int64 {
+	now = now.UTC()
+	nextMonthStart := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+
+	untilReset := nextMonthStart.Sub(now)
+	seconds := int64(untilReset / time.Second)
+	if untilReset%time.Second != 0 {
+		seconds++
+	}
+	return seconds
+}
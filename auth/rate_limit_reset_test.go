@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_getRateLimitResetSeconds(t *testing.T) {
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantSeconds int64
+	}{
+		{
+			name:        "should return the full month at the start of the month",
+			now:         time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC),
+			wantSeconds: 31 * 24 * 60 * 60,
+		},
+		{
+			name:        "should return the remaining time in the middle of the month",
+			now:         time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC),
+			wantSeconds: 17*24*60*60 + 12*60*60,
+		},
+		{
+			name:        "should return one second at the last second of the month",
+			now:         time.Date(2026, time.October, 31, 23, 59, 59, 0, time.UTC),
+			wantSeconds: 1,
+		},
+		{
+			name:        "should round up partial seconds",
+			now:         time.Date(2026, time.October, 31, 23, 59, 58, 500_000_000, time.UTC),
+			wantSeconds: 2,
+		},
+		{
+			name:        "should reset at the start of January in December",
+			now:         time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC),
+			wantSeconds: 24 * 60 * 60,
+		},
+		{
+			name:        "should account for leap years in February",
+			now:         time.Date(2028, time.February, 28, 0, 0, 0, 0, time.UTC),
+			wantSeconds: 2 * 24 * 60 * 60,
+		},
+		{
+			// 20:00 on October 31st in UTC-5 is 01:00 on November 1st in UTC
+			name:        "should use the UTC month for times in other time zones",
+			now:         time.Date(2026, time.October, 31, 20, 0, 0, 0, time.FixedZone("UTC-5", -5*60*60)),
+			wantSeconds: 30*24*60*60 - 60*60,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.wantSeconds, getRateLimitResetSeconds(test.now))
+		})
+	}
+}
+
+func Test_Check_RateLimitResetHeader(t *testing.T) {
+	tests := []struct {
+		name                string
+		resetHeaderEnabled  bool
+		portalApp           *store.PortalApp
+		rateLimitDecision   ratelimit.Decision
+		expectedStatusCode  envoy_type.StatusCode
+		expectedResetHeader bool
+	}{
+		{
+			name:                "should set reset header on authorized request for rate-limit-eligible portal app",
+			resetHeaderEnabled:  true,
+			portalApp:           &store.PortalApp{ID: "portal_app_reset", AccountID: "account_reset", PlanType: "PLAN_FREE", RateLimit: &store.RateLimit{}},
+			rateLimitDecision:   ratelimit.DecisionOK,
+			expectedStatusCode:  envoy_type.StatusCode_OK,
+			expectedResetHeader: true,
+		},
+		{
+			name:                "should set reset header on rate limited response",
+			resetHeaderEnabled:  true,
+			portalApp:           &store.PortalApp{ID: "portal_app_reset", AccountID: "account_reset", PlanType: "PLAN_FREE", RateLimit: &store.RateLimit{}},
+			rateLimitDecision:   ratelimit.DecisionBlock,
+			expectedStatusCode:  envoy_type.StatusCode_TooManyRequests,
+			expectedResetHeader: true,
+		},
+		{
+			name:               "should not set reset header for portal app that is not rate-limit-eligible",
+			resetHeaderEnabled: true,
+			portalApp:          &store.PortalApp{ID: "portal_app_reset", AccountID: "account_reset", PlanType: "PLAN_UNLIMITED"},
+			expectedStatusCode: envoy_type.StatusCode_OK,
+		},
+		{
+			name:               "should not set reset header if disabled",
+			resetHeaderEnabled: false,
+			portalApp:          &store.PortalApp{ID: "portal_app_reset", AccountID: "account_reset", PlanType: "PLAN_FREE", RateLimit: &store.RateLimit{}},
+			rateLimitDecision:  ratelimit.DecisionOK,
+			expectedStatusCode: envoy_type.StatusCode_OK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(test.portalApp.ID).Return(test.portalApp, true)
+
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			if test.portalApp.RateLimit != nil {
+				mockRateLimitStore.EXPECT().GetAccountRateLimitDecision(test.portalApp.AccountID).Return(test.rateLimitDecision)
+			}
+
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(),
+				mockPortalAppStore,
+				mockRateLimitStore,
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithRateLimitResetHeader(test.resetHeaderEnabled),
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/" + string(test.portalApp.ID),
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.Equal(int32(test.expectedStatusCode), getHTTPStatusCode(resp))
+
+			headers := resp.GetOkResponse().GetHeaders()
+			if test.expectedStatusCode != envoy_type.StatusCode_OK {
+				headers = resp.GetDeniedResponse().GetHeaders()
+			}
+
+			resetHeaderSet := false
+			for _, header := range headers {
+				if header.GetHeader().GetKey() != reqHeaderRateLimitResetSeconds {
+					continue
+				}
+				resetHeaderSet = true
+
+				seconds, err := strconv.ParseInt(header.GetHeader().GetValue(), 10, 64)
+				c.NoError(err)
+				c.Positive(seconds)
+				c.LessOrEqual(seconds, int64(31*24*60*60))
+			}
+			c.Equal(test.expectedResetHeader, resetHeaderSet)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"strings"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const (
+	// rateLimitTierFree: PLAN_FREE portal apps, limited to the free monthly relays.
+	rateLimitTierFree = "free"
+	// rateLimitTierUnlimitedLimited: PLAN_UNLIMITED portal apps with a monthly user limit.
+	rateLimitTierUnlimitedLimited = "unlimited-limited"
+	// rateLimitTierUnlimitedUnlimited: PLAN_UNLIMITED portal apps without a monthly user limit.
+	rateLimitTierUnlimitedUnlimited = "unlimited-unlimited"
+)
+
+// getRateLimitTier returns a stable rate limit tier label for the portal app's plan and limit combination.
+//   - Other plan types are labelled by their lowercased name without the "PLAN_" prefix (e.g. "PLAN_PRO" -> "pro").
+//   - Returns false if the portal app has no plan type.
+func getRateLimitTier(portalApp *store.PortalApp) (string, bool) {
+	switch portalApp.PlanType {
+	case "":
+		return "", false
+
+	case grovedb.PlanFree_DatabaseType:
+		return rateLimitTierFree, true
+
+	case grovedb.PlanUnlimited_DatabaseType:
+		if portalApp.RateLimit != nil && portalApp.RateLimit.MonthlyUserLimit > 0 {
+			return rateLimitTierUnlimitedLimited, true
+		}
+		return rateLimitTierUnlimitedUnlimited, true
+
+	default:
+		return strings.ToLower(strings.TrimPrefix(string(portalApp.PlanType), "PLAN_")), true
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_getRateLimitTier(t *testing.T) {
+	tests := []struct {
+		name      string
+		portalApp *store.PortalApp
+		wantTier  string
+		wantOK    bool
+	}{
+		{
+			name: "should map free plan to free tier",
+			portalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			wantTier: "free",
+			wantOK:   true,
+		},
+		{
+			name: "should map free plan with bonus relays to free tier",
+			portalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{FreeMonthlyRelayBonus: 500_000},
+			},
+			wantTier: "free",
+			wantOK:   true,
+		},
+		{
+			name: "should map unlimited plan with monthly user limit to unlimited-limited tier",
+			portalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 10_000_000},
+			},
+			wantTier: "unlimited-limited",
+			wantOK:   true,
+		},
+		{
+			name: "should map unlimited plan without monthly user limit to unlimited-unlimited tier",
+			portalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			wantTier: "unlimited-unlimited",
+			wantOK:   true,
+		},
+		{
+			name: "should map unlimited plan without rate limit to unlimited-unlimited tier",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanUnlimited_DatabaseType,
+			},
+			wantTier: "unlimited-unlimited",
+			wantOK:   true,
+		},
+		{
+			name: "should label other plans by their lowercased name",
+			portalApp: &store.PortalApp{
+				PlanType: "PLAN_PRO",
+			},
+			wantTier: "pro",
+			wantOK:   true,
+		},
+		{
+			name:      "should return false for portal app without plan type",
+			portalApp: &store.PortalApp{},
+			wantOK:    false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			tier, ok := getRateLimitTier(test.portalApp)
+			c.Equal(test.wantOK, ok)
+			c.Equal(test.wantTier, tier)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// reqHeaderRelayCostPrefix is the prefix of the header set on requests that cost more than one relay.
+// The full header key is "Rl-Cost-<n>", where n is the number of relays the request counts as.
+// GUARD may use this header to apply the cost multiplier to the account's usage.
+const reqHeaderRelayCostPrefix = "Rl-Cost-"
+
+// RelayCosts configures how many relays a request counts as toward usage.
+//
+//   - Keys starting with "/" match the request path, after the "<path prefix><portal app id>" prefix (e.g. "/v1/1a2b3c4d"), by longest prefix
+//   - All other keys match the JSON-RPC method in the request body (requires Envoy to forward the request body)
+//   - The request body is only parsed if a JSON-RPC method cost is configured for the default or the portal app
+//   - A JSON-RPC method match takes precedence over a path match
+//   - Portal app specific costs take precedence over default costs
+//   - Requests with no matching cost count as one relay and receive no cost header
+//
+// Example JSON file contents:
+//
+//	{
+//	  "default": {
+//	    "eth_getLogs": 5,
+//	    "/cosmos/tx": 2
+//	  },
+//	  "portal_apps": {
+//	    "1a2b3c4d": {
+//	      "eth_getLogs": 10
+//	    }
+//	  }
+//	}
+type RelayCosts struct {
+	Default    map[string]int32                       `json:"default"`
+	PortalApps map[store.PortalAppID]map[string]int32 `json:"portal_apps"`
+}
+
+// LoadRelayCosts reads and validates relay costs from a JSON file.
+func LoadRelayCosts(path string) (*RelayCosts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relay costs file: %w", err)
+	}
+
+	var relayCosts RelayCosts
+	if err := json.Unmarshal(data, &relayCosts); err != nil {
+		return nil, fmt.Errorf("failed to parse relay costs file: %w", err)
+	}
+
+	if err := validateRelayCosts(relayCosts.Default); err != nil {
+		return nil, fmt.Errorf("invalid default relay costs: %w", err)
+	}
+	for portalAppID, costs := range relayCosts.PortalApps {
+		if err := validateRelayCosts(costs); err != nil {
+			return nil, fmt.Errorf("invalid relay costs for portal app %q: %w", portalAppID, err)
+		}
+	}
+
+	return &relayCosts, nil
+}
+
+// validateRelayCosts ensures every cost counts as at least one relay.
+func validateRelayCosts(costs map[string]int32) error {
+	for key, cost := range costs {
+		if key == "" {
+			return fmt.Errorf("empty method or path")
+		}
+		if cost < 1 {
+			return fmt.Errorf("cost for %q must be at least 1, got %d", key, cost)
+		}
+	}
+	return nil
+}
+
+// getRelayCost returns the number of relays the request counts as.
+//   - Returns 1 if no relay costs are configured or no cost matches the request.
+func (r *RelayCosts) getRelayCost(portalAppID store.PortalAppID, path string, pathPrefixes []string, body string) int32 {
+	if r == nil {
+		return 1
+	}
+
+	portalAppCosts := r.PortalApps[portalAppID]
+
+	// The body is only parsed if a JSON-RPC method cost could match,
+	// so a body forwarded by Envoy is ignored when only path costs are configured.
+	var method string
+	if hasMethodCosts(portalAppCosts) || hasMethodCosts(r.Default) {
+		method = extractJSONRPCMethod(body)
+	}
+	relayPath := getRelayPath(portalAppID, path, pathPrefixes)
+
+	for _, costs := range []map[string]int32{portalAppCosts, r.Default} {
+		if cost, ok := matchRelayCost(costs, method, relayPath); ok {
+			return cost
+		}
+	}
+
+	return 1
+}
+
+// hasMethodCosts returns true if any of the costs matches a JSON-RPC method rather than a path.
+func hasMethodCosts(costs map[string]int32) bool {
+	for key := range costs {
+		if !strings.HasPrefix(key, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRelayCost returns the cost for the JSON-RPC method, or else the longest matching path prefix.
+func matchRelayCost(costs map[string]int32, method, relayPath string) (int32, bool) {
+	if method != "" {
+		if cost, ok := costs[method]; ok {
+			return cost, true
+		}
+	}
+
+	var matchedPrefix string
+	var matchedCost int32
+	for key, cost := range costs {
+		if strings.HasPrefix(key, "/") && strings.HasPrefix(relayPath, key) && len(key) > len(matchedPrefix) {
+			matchedPrefix, matchedCost = key, cost
+		}
+	}
+	return matchedCost, matchedPrefix != ""
+}
+
+// extractJSONRPCMethod returns the method of a single JSON-RPC request body.
+//   - Returns an empty string if the body is empty, not JSON, or a batch request.
+func extractJSONRPCMethod(body string) string {
+	if body == "" {
+		return ""
+	}
+
+	var jsonRPCRequest struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal([]byte(body), &jsonRPCRequest); err != nil {
+		return ""
+	}
+	return jsonRPCRequest.Method
+}
+
+// getRelayPath returns the request path with the "<path prefix><portal app id>" prefix removed,
+// using the longest path prefix the path starts with.
+//
+// Examples:
+//
+//	"/v1/1a2b3c4d/cosmos/tx" -> "/cosmos/tx"
+//	"/v1/cosmos/tx" (portal app ID passed via header) -> "/cosmos/tx"
+func getRelayPath(portalAppID store.PortalAppID, path string, pathPrefixes []string) string {
+	pathPrefix, _ := matchPathPrefix(path, pathPrefixes)
+	relayPath, ok := strings.CutPrefix(path, pathPrefix+string(portalAppID))
+	if !ok || (relayPath != "" && !strings.HasPrefix(relayPath, "/")) {
+		relayPath = strings.TrimPrefix(path, strings.TrimSuffix(pathPrefix, "/"))
+	}
+	if !strings.HasPrefix(relayPath, "/") {
+		relayPath = "/" + relayPath
+	}
+	return relayPath
+}
@@ -0,0 +1,294 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_getRelayCost(t *testing.T) {
+	relayCosts := &RelayCosts{
+		Default: map[string]int32{
+			"eth_getLogs":   5,
+			"/cosmos":       2,
+			"/cosmos/tx":    3,
+			"debug_traceTx": 20,
+		},
+		PortalApps: map[store.PortalAppID]map[string]int32{
+			"portal_app_custom": {
+				"eth_getLogs": 10,
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		relayCosts   *RelayCosts
+		portalAppID  store.PortalAppID
+		path         string
+		body         string
+		expectedCost int32
+	}{
+		{
+			name:         "should return default cost for JSON-RPC method",
+			relayCosts:   relayCosts,
+			portalAppID:  "portal_app_1",
+			path:         "/v1/portal_app_1",
+			body:         `{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}`,
+			expectedCost: 5,
+		},
+		{
+			name:         "should return portal app specific cost over default cost",
+			relayCosts:   relayCosts,
+			portalAppID:  "portal_app_custom",
+			path:         "/v1/portal_app_custom",
+			body:         `{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}`,
+			expectedCost: 10,
+		},
+		{
+			name:         "should fall back to default cost if portal app has no cost for the method",
+			relayCosts:   relayCosts,
+			portalAppID:  "portal_app_custom",
+			path:         "/v1/portal_app_custom",
+			body:         `{"jsonrpc":"2.0","id":1,"method":"debug_traceTx","params":[]}`,
+			expectedCost: 20,
+		},
+		{
+			name:         "should return 1 for JSON-RPC method without a configured cost",
+			relayCosts:   relayCosts,
+			portalAppID:  "portal_app_1",
+			path:         "/v1/portal_app_1",
+			body:         `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`,
+			expectedCost: 1,
+		},
+		{
+			name:         "should return longest matching path prefix cost",
+			relayCosts:   relayCosts,
+			portalAppID:  "portal_app_1",
+			path:         "/v1/portal_app_1/cosmos/tx/v1beta1/txs",
+			expectedCost: 3,
+		},
+		{
+			name:         "should match path cost when portal app ID is passed via header",
+			relayCosts:   relayCosts,
+			portalAppID:  "portal_app_1",
+			path:         "/v1/cosmos/base/tendermint",
+			expectedCost: 2,
+		},
+		{
+			name:         "should return 1 for batch JSON-RPC requests",
+			relayCosts:   relayCosts,
+			portalAppID:  "portal_app_1",
+			path:         "/v1/portal_app_1",
+			body:         `[{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}]`,
+			expectedCost: 1,
+		},
+		{
+			name:         "should return 1 if no relay costs are configured",
+			relayCosts:   nil,
+			portalAppID:  "portal_app_1",
+			path:         "/v1/portal_app_1",
+			body:         `{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}`,
+			expectedCost: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expectedCost, test.relayCosts.getRelayCost(test.portalAppID, test.path, []string{defaultPathPrefix}, test.body))
+		})
+	}
+}
+
+func Test_getRelayCost_BodyNotParsedWithoutMethodCosts(t *testing.T) {
+	body := newLargeJSONRPCBody()
+
+	tests := []struct {
+		name         string
+		relayCosts   *RelayCosts
+		expectedCost int32
+	}{
+		{
+			name:         "should not parse the body if no relay costs are configured",
+			relayCosts:   nil,
+			expectedCost: 1,
+		},
+		{
+			name:         "should not parse the body if only path costs are configured",
+			relayCosts:   &RelayCosts{Default: map[string]int32{"/cosmos": 2}},
+			expectedCost: 1,
+		},
+		{
+			name: "should not parse the body if method costs are only configured for another portal app",
+			relayCosts: &RelayCosts{
+				Default:    map[string]int32{"/cosmos": 2},
+				PortalApps: map[store.PortalAppID]map[string]int32{"portal_app_other": {"eth_getLogs": 10}},
+			},
+			expectedCost: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			// Parsing the body allocates, so zero allocations confirms it was ignored
+			allocs := testing.AllocsPerRun(100, func() {
+				c.Equal(test.expectedCost, test.relayCosts.getRelayCost("portal_app_1", "/v1/portal_app_1", []string{defaultPathPrefix}, body))
+			})
+			c.Zero(allocs)
+		})
+	}
+}
+
+func Benchmark_getRelayCost(b *testing.B) {
+	body := newLargeJSONRPCBody()
+
+	benchmarks := []struct {
+		name       string
+		relayCosts *RelayCosts
+	}{
+		{name: "no_relay_costs", relayCosts: nil},
+		{name: "path_costs", relayCosts: &RelayCosts{Default: map[string]int32{"/cosmos": 2}}},
+		{name: "method_costs", relayCosts: &RelayCosts{Default: map[string]int32{"eth_getLogs": 5}}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				bm.relayCosts.getRelayCost("portal_app_1", "/v1/portal_app_1", []string{defaultPathPrefix}, body)
+			}
+		})
+	}
+}
+
+// newLargeJSONRPCBody returns a JSON-RPC request body of roughly 64KB, e.g. a large eth_sendRawTransaction.
+func newLargeJSONRPCBody() string {
+	return `{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x` + strings.Repeat("ab", 32*1024) + `"]}`
+}
+
+func Test_getRelayPath(t *testing.T) {
+	tests := []struct {
+		name         string
+		portalAppID  store.PortalAppID
+		pathPrefixes []string
+		path         string
+		want         string
+	}{
+		{
+			name:         "should strip portal app ID prefix from path",
+			portalAppID:  "1a2b3c4d",
+			pathPrefixes: []string{"/v1/"},
+			path:         "/v1/1a2b3c4d/cosmos/tx",
+			want:         "/cosmos/tx",
+		},
+		{
+			name:         "should return root path if path only contains the portal app ID",
+			portalAppID:  "1a2b3c4d",
+			pathPrefixes: []string{"/v1/"},
+			path:         "/v1/1a2b3c4d",
+			want:         "/",
+		},
+		{
+			name:         "should strip only the version prefix if portal app ID is passed via header",
+			portalAppID:  "1a2b3c4d",
+			pathPrefixes: []string{"/v1/"},
+			path:         "/v1/cosmos/tx",
+			want:         "/cosmos/tx",
+		},
+		{
+			name:         "should not strip a path segment that only starts with the portal app ID",
+			portalAppID:  "1a2b",
+			pathPrefixes: []string{"/v1/"},
+			path:         "/v1/1a2b3c4d",
+			want:         "/1a2b3c4d",
+		},
+		{
+			name:         "should strip a configured path prefix and portal app ID from path",
+			portalAppID:  "1a2b3c4d",
+			pathPrefixes: []string{"/api/v2/"},
+			path:         "/api/v2/1a2b3c4d/cosmos/tx",
+			want:         "/cosmos/tx",
+		},
+		{
+			name:         "should strip only a configured path prefix if portal app ID is passed via header",
+			portalAppID:  "1a2b3c4d",
+			pathPrefixes: []string{"/api/v2/"},
+			path:         "/api/v2/cosmos/tx",
+			want:         "/cosmos/tx",
+		},
+		{
+			name:         "should strip the longest of overlapping path prefixes and portal app ID from path",
+			portalAppID:  "1a2b3c4d",
+			pathPrefixes: []string{"/v1/", "/v1/beta/"},
+			path:         "/v1/beta/1a2b3c4d/cosmos/tx",
+			want:         "/cosmos/tx",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.want, getRelayPath(test.portalAppID, test.path, test.pathPrefixes))
+		})
+	}
+}
+
+func Test_LoadRelayCosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     *RelayCosts
+		wantErr  bool
+	}{
+		{
+			name:     "should load default and portal app relay costs",
+			contents: `{"default": {"eth_getLogs": 5}, "portal_apps": {"portal_app_1": {"/cosmos": 2}}}`,
+			want: &RelayCosts{
+				Default: map[string]int32{"eth_getLogs": 5},
+				PortalApps: map[store.PortalAppID]map[string]int32{
+					"portal_app_1": {"/cosmos": 2},
+				},
+			},
+		},
+		{
+			name:     "should error on cost less than 1",
+			contents: `{"default": {"eth_getLogs": 0}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "should error on empty method or path",
+			contents: `{"portal_apps": {"portal_app_1": {"": 2}}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "should error on invalid JSON",
+			contents: `{"default": `,
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			path := filepath.Join(t.TempDir(), "relay_costs.json")
+			c.NoError(os.WriteFile(path, []byte(test.contents), 0o600))
+
+			got, err := LoadRelayCosts(path)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, got)
+		})
+	}
+}
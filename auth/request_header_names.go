@@ -0,0 +1,34 @@
+package auth
+
+import "fmt"
+
+// ParseRequestHeaderName validates the name of a header set on authorized requests.
+//   - The name may only contain letters, digits and "-"
+//   - Example: "X-Portal-App-ID"
+func ParseRequestHeaderName(s string) (string, error) {
+	if !headerNameRegex.MatchString(s) {
+		return "", fmt.Errorf("invalid request header name %q: may only contain letters, digits and \"-\"", s)
+	}
+	return s, nil
+}
+
+// WithPortalAppIDHeader sets the name of the portal app ID header, which is both read from
+// requests to identify the portal app and set on authorized requests for PATH.
+// An empty name keeps the default "Portal-Application-ID"; PATH must be configured with the same name.
+func WithPortalAppIDHeader(name string) AuthHandlerOption {
+	return func(a *authHandler) {
+		if name != "" {
+			a.portalAppIDHeader = name
+		}
+	}
+}
+
+// WithAccountIDHeader sets the name of the account ID header set on authorized requests for PATH.
+// An empty name keeps the default "Portal-Account-ID"; PATH must be configured with the same name.
+func WithAccountIDHeader(name string) AuthHandlerOption {
+	return func(a *authHandler) {
+		if name != "" {
+			a.accountIDHeader = name
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseRequestHeaderName(t *testing.T) {
+	c := require.New(t)
+
+	name, err := ParseRequestHeaderName("X-Portal-App-ID")
+	c.NoError(err)
+	c.Equal("X-Portal-App-ID", name)
+
+	for _, invalid := range []string{"", "X Portal App ID", "X-Portal-App-ID:", "X_Portal_App_ID"} {
+		_, err := ParseRequestHeaderName(invalid)
+		c.Error(err, "header name %q", invalid)
+	}
+}
+
+func Test_Check_RequestHeaderNames(t *testing.T) {
+	portalApp := &store.PortalApp{
+		ID:        "portal_app_1",
+		AccountID: "account_1",
+		PlanType:  grovedb.PlanUnlimited_DatabaseType,
+	}
+
+	tests := []struct {
+		name                 string
+		opts                 []AuthHandlerOption
+		requestHeaders       map[string]string
+		expectedHeaders      map[string]string
+		expectedPortalAppHit bool
+	}{
+		{
+			name:           "should read and set the default header names",
+			requestHeaders: map[string]string{reqHeaderPortalAppID: "portal_app_1"},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_1",
+				reqHeaderAccountID:   "account_1",
+			},
+			expectedPortalAppHit: true,
+		},
+		{
+			name: "should read and set the custom header names",
+			opts: []AuthHandlerOption{
+				WithPortalAppIDHeader("X-Portal-App-ID"),
+				WithAccountIDHeader("X-Portal-Account-ID"),
+			},
+			requestHeaders: map[string]string{"X-Portal-App-ID": "portal_app_1"},
+			expectedHeaders: map[string]string{
+				"X-Portal-App-ID":     "portal_app_1",
+				"X-Portal-Account-ID": "account_1",
+			},
+			expectedPortalAppHit: true,
+		},
+		{
+			name:           "should keep the default header names if the custom names are empty",
+			opts:           []AuthHandlerOption{WithPortalAppIDHeader(""), WithAccountIDHeader("")},
+			requestHeaders: map[string]string{reqHeaderPortalAppID: "portal_app_1"},
+			expectedHeaders: map[string]string{
+				reqHeaderPortalAppID: "portal_app_1",
+				reqHeaderAccountID:   "account_1",
+			},
+			expectedPortalAppHit: true,
+		},
+		{
+			name:           "should not read the default portal app ID header if a custom name is set",
+			opts:           []AuthHandlerOption{WithPortalAppIDHeader("X-Portal-App-ID")},
+			requestHeaders: map[string]string{reqHeaderPortalAppID: "portal_app_1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockRateLimitStore := newTestRateLimitStore(ctrl)
+			if test.expectedPortalAppHit {
+				mockPortalAppStore.EXPECT().GetPortalApp(portalApp.ID).Return(portalApp, true)
+			}
+
+			authHandler := NewAuthHandler(polyzero.NewLogger(), mockPortalAppStore, mockRateLimitStore, []Authorizer{&AuthorizerAPIKey{}}, test.opts...)
+
+			// The path has no portal app ID, so it can only be read from the request headers
+			req := newTestCheckRequest("/v1")
+			req.Attributes.Request.Http.Headers = test.requestHeaders
+
+			resp, err := authHandler.Check(context.Background(), req)
+			c.NoError(err)
+
+			if !test.expectedPortalAppHit {
+				c.Nil(resp.GetOkResponse())
+				return
+			}
+
+			gotHeaders := getResponseHeaderValues(resp)
+			c.Equal(test.expectedHeaders, gotHeaders)
+		})
+	}
+}
+
+// getResponseHeaderValues returns the headers set on an OK response, by header key.
+func getResponseHeaderValues(resp *envoy_auth.CheckResponse) map[string]string {
+	headers := resp.GetOkResponse().GetHeaders()
+	values := make(map[string]string, len(headers))
+	for _, header := range headers {
+		values[header.GetHeader().GetKey()] = header.GetHeader().GetValue()
+	}
+	return values
+}
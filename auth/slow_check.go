@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"time"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// logSlowCheck logs a Check that took longer than the slow check threshold, if enabled.
+//   - Logs the duration and decision of the request, so individual outliers can be correlated with their cause
+//   - Does nothing if the threshold is zero or the Check was not slow
+func (a *authHandler) logSlowCheck(
+	checkReq *envoy_auth.CheckRequest,
+	checkResp *envoy_auth.CheckResponse,
+	duration time.Duration,
+) {
+	if a.slowCheckThreshold <= 0 || duration <= a.slowCheckThreshold {
+		return
+	}
+
+	event := a.logger.Warn().
+		Dur("duration", duration).
+		Dur("threshold", a.slowCheckThreshold).
+		Str("decision", getCheckDecision(checkResp)).
+		Int("status_code", int(getHTTPStatusCode(checkResp))).
+		Str("reason", checkResp.GetStatus().GetMessage()).
+		Str("path", checkReq.GetAttributes().GetRequest().GetHttp().GetPath())
+	if clientIP, ok := a.clientIPResolver.resolve(checkReq); ok {
+		event = event.Str("client_ip", clientIP.String())
+	}
+	event.Msg("🐢 slow check request")
+}
+
+// getCheckDecision returns the metrics decision of a CheckResponse.
+//   - Internal error responses are reported as errors rather than denials
+func getCheckDecision(resp *envoy_auth.CheckResponse) string {
+	switch {
+	case resp.GetOkResponse() != nil:
+		return metrics.AuthDecisionAuthorized
+	case getHTTPStatusCode(resp) == int32(envoy_type.StatusCode_InternalServerError):
+		return metrics.AuthDecisionError
+	default:
+		return metrics.AuthDecisionDenied
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_Check_SlowCheckLogging(t *testing.T) {
+	tests := []struct {
+		name           string
+		threshold      time.Duration
+		lookupDelay    time.Duration
+		expectedLogged bool
+	}{
+		{
+			name:           "should log a check slower than the threshold",
+			threshold:      10 * time.Millisecond,
+			lookupDelay:    20 * time.Millisecond,
+			expectedLogged: true,
+		},
+		{
+			name:        "should not log a check faster than the threshold",
+			threshold:   time.Second,
+			lookupDelay: 0,
+		},
+		{
+			name:        "should not log a slow check if the threshold is zero",
+			threshold:   0,
+			lookupDelay: 20 * time.Millisecond,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// The portal app store lookup is delayed to simulate a slow check
+			portalApp := &store.PortalApp{ID: "portal_app_slow", AccountID: "account_1"}
+			mockPortalAppStore := NewMockportalAppStore(ctrl)
+			mockPortalAppStore.EXPECT().GetPortalApp(portalApp.ID).DoAndReturn(func(store.PortalAppID) (*store.PortalApp, bool) {
+				time.Sleep(test.lookupDelay)
+				return portalApp, true
+			})
+
+			var logs bytes.Buffer
+			authHandler := NewAuthHandler(
+				polyzero.NewLogger(polyzero.WithOutput(&logs)),
+				mockPortalAppStore,
+				newTestRateLimitStore(ctrl),
+				[]Authorizer{&AuthorizerAPIKey{}},
+				WithSlowCheckLogging(test.threshold),
+			)
+
+			resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+				Attributes: &envoy_auth.AttributeContext{
+					Request: &envoy_auth.AttributeContext_Request{
+						Http: &envoy_auth.AttributeContext_HttpRequest{
+							Path: "/v1/portal_app_slow",
+						},
+					},
+				},
+			})
+			c.NoError(err)
+			c.NotNil(resp.GetOkResponse())
+
+			if !test.expectedLogged {
+				c.NotContains(logs.String(), "slow check request")
+				return
+			}
+			c.Contains(logs.String(), "slow check request")
+			c.Contains(logs.String(), `"level":"warn"`)
+			c.Contains(logs.String(), `"decision":"authorized"`)
+			c.Contains(logs.String(), `"status_code":200`)
+		})
+	}
+}
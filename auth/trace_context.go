@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"context"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceContextPropagator extracts the W3C trace context ("traceparent" and "tracestate" headers) of a request.
+var traceContextPropagator = propagation.TraceContext{}
+
+// withRequestTraceContext returns ctx carrying the trace context of the request being authorized.
+//   - Envoy forwards the request's headers in the CheckRequest, so its "traceparent" header is available
+//     without instrumenting the gRPC server or configuring a tracer provider.
+//   - Returns ctx unchanged if it already carries a valid span context, or the request has no valid trace context.
+//
+// Used to attach the request's trace as an exemplar to its metrics (see metrics.RecordAuthRequest).
+func withRequestTraceContext(ctx context.Context, req *envoy_auth.AttributeContext_HttpRequest) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return traceContextPropagator.Extract(ctx, propagation.MapCarrier(req.GetHeaders()))
+}
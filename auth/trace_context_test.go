@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	envoy_type "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/mock/gomock"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const (
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID      = "00f067aa0ba902b7"
+	testTraceparent = "00-" + testTraceID + "-" + testSpanID + "-01"
+)
+
+func Test_withRequestTraceContext(t *testing.T) {
+	existingSpanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01},
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		headers         map[string]string
+		expectedTraceID string
+		expectedSampled bool
+	}{
+		{
+			name:            "should extract the sampled trace context of the request",
+			ctx:             context.Background(),
+			headers:         map[string]string{"traceparent": testTraceparent},
+			expectedTraceID: testTraceID,
+			expectedSampled: true,
+		},
+		{
+			name:            "should extract the trace context of the request if it is not sampled",
+			ctx:             context.Background(),
+			headers:         map[string]string{"traceparent": "00-" + testTraceID + "-" + testSpanID + "-00"},
+			expectedTraceID: testTraceID,
+		},
+		{
+			name:    "should not set a trace context if the request has an invalid traceparent header",
+			ctx:     context.Background(),
+			headers: map[string]string{"traceparent": "invalid"},
+		},
+		{
+			name: "should not set a trace context if the request has no traceparent header",
+			ctx:  context.Background(),
+		},
+		{
+			name:            "should keep the span context already carried by the context",
+			ctx:             trace.ContextWithSpanContext(context.Background(), existingSpanContext),
+			headers:         map[string]string{"traceparent": testTraceparent},
+			expectedTraceID: existingSpanContext.TraceID().String(),
+			expectedSampled: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctx := withRequestTraceContext(test.ctx, &envoy_auth.AttributeContext_HttpRequest{Headers: test.headers})
+
+			spanContext := trace.SpanContextFromContext(ctx)
+			if test.expectedTraceID == "" {
+				c.False(spanContext.IsValid())
+				return
+			}
+			c.Equal(test.expectedTraceID, spanContext.TraceID().String())
+			c.Equal(test.expectedSampled, spanContext.IsSampled())
+		})
+	}
+}
+
+func Test_Check_TraceExemplar(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	portalApp := &store.PortalApp{ID: "portal_app_trace_exemplar", AccountID: "account_1"}
+
+	mockPortalAppStore := NewMockportalAppStore(ctrl)
+	mockPortalAppStore.EXPECT().GetPortalApp(portalApp.ID).Return(portalApp, true)
+
+	authHandler := NewAuthHandler(
+		polyzero.NewLogger(),
+		mockPortalAppStore,
+		newTestRateLimitStore(ctrl),
+		[]Authorizer{&AuthorizerAPIKey{}},
+	)
+
+	// The trace context is only carried by the request's headers, as forwarded by Envoy
+	resp, err := authHandler.Check(context.Background(), &envoy_auth.CheckRequest{
+		Attributes: &envoy_auth.AttributeContext{
+			Request: &envoy_auth.AttributeContext_Request{
+				Http: &envoy_auth.AttributeContext_HttpRequest{
+					Path:    "/v1/" + string(portalApp.ID),
+					Headers: map[string]string{"traceparent": testTraceparent},
+				},
+			},
+		},
+	})
+	c.NoError(err)
+	c.Equal(int32(envoy_type.StatusCode_OK), getHTTPStatusCode(resp))
+
+	c.Equal(map[string]string{
+		"trace_id": testTraceID,
+		"span_id":  testSpanID,
+	}, getAuthRequestDurationExemplar(t, portalApp.ID))
+}
+
+// getAuthRequestDurationExemplar returns the labels of the exemplar recorded for the portal app's authorization request durations.
+func getAuthRequestDurationExemplar(t *testing.T, portalAppID store.PortalAppID) map[string]string {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	exemplarLabels := make(map[string]string)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_auth_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "portal_app_id" || label.GetValue() != string(portalAppID) {
+					continue
+				}
+				for _, bucket := range metric.GetHistogram().GetBucket() {
+					for _, exemplarLabel := range bucket.GetExemplar().GetLabel() {
+						exemplarLabels[exemplarLabel.GetName()] = exemplarLabel.GetValue()
+					}
+				}
+			}
+		}
+	}
+	return exemplarLabels
+}
@@ -0,0 +1,112 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// defaultQueryTimeout bounds each usage query attempt if WithQueryTimeout is not set,
+// so a hung ClickHouse connection cannot block rate limit refreshes indefinitely.
+const defaultQueryTimeout = 5 * time.Minute
+
+// retryableStatusCodes are the HTTP status codes of transient ClickHouse errors.
+// 500 is not retried, as ClickHouse responds with it to most query exceptions (e.g. a missing table).
+var retryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// queryStatusError is returned when ClickHouse responds to a query with a non-200 status.
+type queryStatusError struct {
+	statusCode int
+	body       string
+}
+
+func (e *queryStatusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.statusCode, e.body)
+}
+
+// WithQueryTimeout cancels each usage query attempt, from sending the query to reading the last row,
+// after timeout. Defaults to 5m; non-positive values keep the default.
+func WithQueryTimeout(timeout time.Duration) DriverOption {
+	return func(d *Driver) {
+		if timeout > 0 {
+			d.queryTimeout = timeout
+		}
+	}
+}
+
+// WithQueryRetry retries a usage query failing with a transient ClickHouse error
+// (e.g. a connection error, 503) up to maxAttempts times in total, doubling the delay between
+// attempts from baseDelay. Defaults to a single attempt.
+//
+//   - Each retried attempt is counted by the data source refresh errors metric
+//   - An attempt canceled by the query timeout is not retried, so a slow query does not hold the refresh for every attempt
+//   - Returns the last error once every attempt failed, or on the first non-retryable error
+func WithQueryRetry(maxAttempts int, baseDelay time.Duration) DriverOption {
+	return func(d *Driver) {
+		d.queryMaxAttempts = maxAttempts
+		d.queryRetryBaseDelay = baseDelay
+	}
+}
+
+// queryUsageWithRetry runs the usage query, retrying transient errors as configured by WithQueryRetry.
+// Each attempt is bounded by the query timeout (see WithQueryTimeout).
+func (d *Driver) queryUsageWithRetry(ctx context.Context, query string, params url.Values) ([]monthlyUsageRow, error) {
+	delay := d.queryRetryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		rows, err := d.queryUsageWithTimeout(ctx, query, params)
+		if err == nil {
+			return rows, nil
+		}
+
+		if attempt >= d.queryMaxAttempts || ctx.Err() != nil || !isRetryableQueryError(err) {
+			return nil, err
+		}
+
+		// The final failed attempt is recorded by the caller, as for any other refresh error
+		metrics.RecordDataSourceRefreshError(metrics.RateLimitStoreSourceType, metrics.ClickhouseErrorType)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("usage query canceled after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// queryUsageWithTimeout runs a single usage query attempt, canceled after the query timeout.
+func (d *Driver) queryUsageWithTimeout(ctx context.Context, query string, params url.Values) ([]monthlyUsageRow, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, d.queryTimeout)
+	defer cancel()
+
+	return d.queryUsage(queryCtx, query, params)
+}
+
+// isRetryableQueryError returns true if the error is a transient ClickHouse error:
+// a retryable HTTP status, or a network error other than the query timeout elapsing.
+func isRetryableQueryError(err error) bool {
+	var statusErr *queryStatusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(retryableStatusCodes, statusErr.statusCode)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,113 @@
+package clickhouse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
+)
+
+func Test_GetMonthToMomentUsage_QueryRetry(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxAttempts     int
+		failures        int32
+		failureStatus   int
+		expectedErr     bool
+		expectedQueries int32
+	}{
+		{
+			name:            "should not retry a successful query",
+			maxAttempts:     3,
+			expectedQueries: 1,
+		},
+		{
+			name:            "should retry a transient error until the query succeeds",
+			maxAttempts:     3,
+			failures:        2,
+			failureStatus:   http.StatusServiceUnavailable,
+			expectedQueries: 3,
+		},
+		{
+			name:            "should return the last error once every attempt failed",
+			maxAttempts:     2,
+			failures:        3,
+			failureStatus:   http.StatusServiceUnavailable,
+			expectedErr:     true,
+			expectedQueries: 2,
+		},
+		{
+			name:            "should not retry a query exception",
+			maxAttempts:     3,
+			failures:        1,
+			failureStatus:   http.StatusInternalServerError,
+			expectedErr:     true,
+			expectedQueries: 1,
+		},
+		{
+			name:            "should not retry with a single attempt",
+			maxAttempts:     1,
+			failures:        1,
+			failureStatus:   http.StatusServiceUnavailable,
+			expectedErr:     true,
+			expectedQueries: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			var queries atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if queries.Add(1) <= test.failures {
+					w.WriteHeader(test.failureStatus)
+					return
+				}
+				_, _ = w.Write([]byte(`{"account_id":"account_1","successful_relays":100,"failed_relays":5}` + "\n"))
+			}))
+			defer server.Close()
+
+			d, err := NewDriver(server.URL, WithQueryRetry(test.maxAttempts, time.Millisecond))
+			c.NoError(err)
+
+			usage, err := d.GetMonthToMomentUsage(context.Background(), 0, nil)
+			c.Equal(test.expectedQueries, queries.Load())
+			if test.expectedErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(map[string]dwh.AccountUsage{"account_1": {SuccessfulRelays: 100, FailedRelays: 5}}, usage)
+		})
+	}
+}
+
+func Test_GetMonthToMomentUsage_QueryTimeout(t *testing.T) {
+	c := require.New(t)
+
+	var queries atomic.Int32
+	released := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		<-released
+	}))
+	defer server.Close()
+	defer close(released)
+
+	d, err := NewDriver(server.URL, WithQueryTimeout(50*time.Millisecond), WithQueryRetry(3, time.Millisecond))
+	c.NoError(err)
+
+	// The attempt is canceled by the query timeout, and not retried
+	start := time.Now()
+	_, err = d.GetMonthToMomentUsage(context.Background(), 0, nil)
+	c.ErrorIs(err, context.DeadlineExceeded)
+	c.Less(time.Since(start), 5*time.Second)
+	c.Equal(int32(1), queries.Load())
+}
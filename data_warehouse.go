@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+
+	"github.com/buildwithgrove/path-external-auth-server/clickhouse"
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// dataWarehouseDriver is the data warehouse the rate limit store queries account usage from.
+// Implemented by dwh.Driver (BigQuery) and clickhouse.Driver.
+type dataWarehouseDriver interface {
+	GetMonthToMomentUsage(ctx context.Context, minRelayThreshold int64, accountIDs []string) (map[string]dwh.AccountUsage, error)
+	GetPortalAppMonthToMomentUsage(ctx context.Context, portalAppIDs []string) (map[string]dwh.AccountUsage, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// newDataWarehouseDriver creates the data warehouse driver of the configured DWH_TYPE.
+func newDataWarehouseDriver(ctx context.Context, env envVars) (dataWarehouseDriver, error) {
+	if env.dwhType == dwhTypeClickHouse {
+		return clickhouse.NewDriver(env.clickhouseConnectionString,
+			clickhouse.WithRelaysTable(env.dwhDataset, env.dwhTable),
+			clickhouse.WithQueryRetry(env.dwhQueryMaxAttempts, env.dwhQueryRetryBaseDelay),
+			clickhouse.WithQueryTimeout(env.dwhReadTimeout),
+		)
+	}
+
+	return dwh.NewDriver(ctx, env.gcpProjectID,
+		dwh.WithQueryLabels(env.bigqueryQueryLabels),
+		dwh.WithUsageCacheTTL(env.dwhCacheTTL),
+		dwh.WithRelaysTable(env.dwhDataset, env.dwhTable),
+		dwh.WithQueryRetry(env.dwhQueryMaxAttempts, env.dwhQueryRetryBaseDelay),
+		dwh.WithResultLimits(env.dwhMaxRows, env.dwhReadTimeout),
+	)
+}
+
+// dataWarehouseErrorType returns the error type data warehouse errors of the DWH_TYPE are counted with
+// in the data source refresh errors metric.
+func dataWarehouseErrorType(dwhType string) string {
+	if dwhType == dwhTypeClickHouse {
+		return metrics.ClickhouseErrorType
+	}
+	return metrics.BigqueryErrorType
+}
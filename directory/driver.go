@@ -0,0 +1,267 @@
+package directory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// DirectoryDriver implements the store.DataSource interface
+// to provide data from a directory of portal app files for the portal app store.
+var _ store.DataSource = &DirectoryDriver{}
+
+type (
+	// DirectoryDriver implements the store.DataSource interface
+	// to provide data from a directory containing one JSON file per portal app.
+	//
+	// Intended for GitOps deployments that mount portal apps as files
+	// (e.g. a Kubernetes Secret or ConfigMap volume), where each file may be
+	// managed by a separate pipeline.
+	DirectoryDriver struct {
+		logger polylog.Logger
+		dir    string
+
+		// files: last loaded portal app files by file name, used to only re-parse changed files
+		files   map[string]portalAppFileState
+		filesMu sync.Mutex
+
+		// fieldNames: keys portal app fields are read from, if they differ from the default keys
+		fieldNames FieldNames
+
+		// watchInterval: interval at which the directory is checked for changes; 0 disables watching
+		watchInterval time.Duration
+
+		// Called when a change to the directory is detected
+		changeHandler   func()
+		changeHandlerMu sync.RWMutex
+
+		stopWatching chan struct{}
+		closeOnce    sync.Once
+	}
+
+	// DirectoryDriverOption configures optional DirectoryDriver behavior.
+	DirectoryDriverOption func(*DirectoryDriver)
+
+	// portalAppFileState is a loaded portal app file and the file info it was loaded with.
+	portalAppFileState struct {
+		modTime   time.Time
+		size      int64
+		portalApp *store.PortalApp
+	}
+)
+
+// WithWatchInterval checks the directory for added, changed or removed files at the given
+// interval, calling the registered change handler when a change is detected.
+// Defaults to 0 (no watching; changes are only picked up by the portal app store refresh).
+func WithWatchInterval(interval time.Duration) DirectoryDriverOption {
+	return func(d *DirectoryDriver) {
+		d.watchInterval = interval
+	}
+}
+
+// WithFieldNames reads portal app fields from the given keys, for files whose keys
+// differ from the Grove Portal database column names.
+// Defaults to no mapping (every field is read from its default key).
+func WithFieldNames(fieldNames FieldNames) DirectoryDriverOption {
+	return func(d *DirectoryDriver) {
+		d.fieldNames = fieldNames
+	}
+}
+
+/*
+NewDirectoryDriver returns a directory data source that implements the store.DataSource interface.
+
+The data source reads portal apps from a directory and:
+1. Loads every file in the directory as a single portal app
+2. Only re-parses files that changed since the last load
+3. Optionally watches the directory for changes
+*/
+func NewDirectoryDriver(
+	logger polylog.Logger,
+	dir string,
+	opts ...DirectoryDriverOption,
+) (*DirectoryDriver, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat portal apps directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("portal apps path %q is not a directory", dir)
+	}
+
+	d := &DirectoryDriver{
+		logger:       logger.With("component", "portal_app_directory_driver"),
+		dir:          dir,
+		files:        make(map[string]portalAppFileState),
+		stopWatching: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if err := d.fieldNames.validate(); err != nil {
+		return nil, fmt.Errorf("invalid portal app field names: %w", err)
+	}
+
+	if d.watchInterval > 0 {
+		go d.watch()
+	}
+
+	return d, nil
+}
+
+// GetPortalApps loads all portal apps from the directory.
+//   - Hidden files and subdirectories are skipped (e.g. the "..data" links of Kubernetes volume mounts).
+//   - Returns an error if any file fails to load or two files have the same portal app ID,
+//     so a partially written directory never replaces the previously loaded portal apps.
+func (d *DirectoryDriver) GetPortalApps() (map[store.PortalAppID]*store.PortalApp, error) {
+	d.filesMu.Lock()
+	defer d.filesMu.Unlock()
+
+	fileInfos, err := d.listFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	newFiles := make(map[string]portalAppFileState, len(fileInfos))
+	portalApps := make(map[store.PortalAppID]*store.PortalApp, len(fileInfos))
+	portalAppFileNames := make(map[store.PortalAppID]string, len(fileInfos))
+
+	for name, info := range fileInfos {
+		state, unchanged := d.files[name]
+		if !unchanged || !state.matches(info) {
+			portalApp, err := loadPortalAppFile(filepath.Join(d.dir, name), d.fieldNames)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load portal app file %q: %w", name, err)
+			}
+			state = portalAppFileState{modTime: info.ModTime(), size: info.Size(), portalApp: portalApp}
+		}
+
+		if existingName, ok := portalAppFileNames[state.portalApp.ID]; ok {
+			return nil, fmt.Errorf("portal app ID %q is defined in both %q and %q", state.portalApp.ID, existingName, name)
+		}
+		portalAppFileNames[state.portalApp.ID] = name
+
+		newFiles[name] = state
+		portalApps[state.portalApp.ID] = state.portalApp
+	}
+
+	d.files = newFiles
+	return portalApps, nil
+}
+
+// SetChangeHandler registers a handler called when the watcher detects a change to the directory.
+//
+// Used to immediately refresh the portal app store, rather than waiting for the next refresh.
+func (d *DirectoryDriver) SetChangeHandler(handler func()) {
+	d.changeHandlerMu.Lock()
+	defer d.changeHandlerMu.Unlock()
+	d.changeHandler = handler
+}
+
+// Close stops watching the directory.
+func (d *DirectoryDriver) Close() {
+	d.closeOnce.Do(func() {
+		close(d.stopWatching)
+	})
+}
+
+// watch periodically checks the directory for changes until the driver is closed.
+func (d *DirectoryDriver) watch() {
+	d.logger.Info().
+		Str("dir", d.dir).
+		Dur("watch_interval", d.watchInterval).
+		Msg("👀 Watching portal apps directory for changes")
+
+	ticker := time.NewTicker(d.watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopWatching:
+			return
+		case <-ticker.C:
+			changed, err := d.hasChanged()
+			if err != nil {
+				d.logger.Error().Err(err).Msg("Failed to check portal apps directory for changes")
+				continue
+			}
+			if changed {
+				d.logger.Info().Str("dir", d.dir).Msg("📂 Detected portal apps directory change")
+				d.notifyChange()
+			}
+		}
+	}
+}
+
+// hasChanged returns true if any file was added, changed or removed since the last load.
+func (d *DirectoryDriver) hasChanged() (bool, error) {
+	d.filesMu.Lock()
+	defer d.filesMu.Unlock()
+
+	fileInfos, err := d.listFiles()
+	if err != nil {
+		return false, err
+	}
+
+	if len(fileInfos) != len(d.files) {
+		return true, nil
+	}
+	for name, info := range fileInfos {
+		state, ok := d.files[name]
+		if !ok || !state.matches(info) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// notifyChange calls the registered change handler, if any.
+func (d *DirectoryDriver) notifyChange() {
+	d.changeHandlerMu.RLock()
+	handler := d.changeHandler
+	d.changeHandlerMu.RUnlock()
+
+	if handler != nil {
+		handler()
+	}
+}
+
+// listFiles returns the file info of every portal app file in the directory, by file name.
+//   - Symlinks are followed, so files mounted from Kubernetes volumes are loaded.
+func (d *DirectoryDriver) listFiles() (map[string]os.FileInfo, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read portal apps directory: %w", err)
+	}
+
+	fileInfos := make(map[string]os.FileInfo, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(d.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat portal app file %q: %w", entry.Name(), err)
+		}
+		if info.IsDir() {
+			continue
+		}
+		fileInfos[entry.Name()] = info
+	}
+
+	return fileInfos, nil
+}
+
+// matches returns true if the file is unchanged since it was loaded.
+func (s portalAppFileState) matches(info os.FileInfo) bool {
+	return s.modTime.Equal(info.ModTime()) && s.size == info.Size()
+}
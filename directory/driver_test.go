@@ -0,0 +1,330 @@
+package directory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_GetPortalApps(t *testing.T) {
+	tests := []struct {
+		name               string
+		files              map[string]string
+		expectedPortalApps map[store.PortalAppID]*store.PortalApp
+		wantErr            bool
+	}{
+		{
+			name: "should load one portal app per file",
+			files: map[string]string{
+				"portal_app_1.json": `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_FREE", "secret_key": "api_key_1", "secret_keys": ["api_key_1_next"], "secret_key_required": true}`,
+				"portal_app_2.json": `{"id": "portal_app_2", "account_id": "account_2", "plan": "PLAN_UNLIMITED", "monthly_relay_limit": 500, "daily_relay_limit": 50, "billing_status": "delinquent"}`,
+				"portal_app_3.json": `{"id": "portal_app_3", "account_id": "account_3", "plan": "PLAN_UNLIMITED", "plan_name": "Pro", "secret_key": "api_key_3", "account_secret_key": "account_api_key_3"}`,
+				"portal_app_4.json": `{"id": "portal_app_4", "account_id": "account_4", "plan": "PLAN_UNLIMITED", "hmac_secret": "hmac_secret_4"}`,
+			},
+			expectedPortalApps: map[store.PortalAppID]*store.PortalApp{
+				"portal_app_1": {
+					ID:        "portal_app_1",
+					AccountID: "account_1",
+					PlanType:  "PLAN_FREE",
+					Auth:      &store.Auth{APIKeys: []string{"api_key_1", "api_key_1_next"}},
+					RateLimit: &store.RateLimit{},
+				},
+				"portal_app_2": {
+					ID:            "portal_app_2",
+					AccountID:     "account_2",
+					PlanType:      "PLAN_UNLIMITED",
+					BillingStatus: store.BillingStatusDelinquent,
+					RateLimit:     &store.RateLimit{MonthlyUserLimit: 500, DailyUserLimit: 50},
+				},
+				"portal_app_3": {
+					ID:          "portal_app_3",
+					AccountID:   "account_3",
+					PlanType:    "PLAN_UNLIMITED",
+					PlanName:    "Pro",
+					AccountAuth: &store.Auth{APIKeys: []string{"account_api_key_3"}},
+				},
+				"portal_app_4": {
+					ID:        "portal_app_4",
+					AccountID: "account_4",
+					PlanType:  "PLAN_UNLIMITED",
+					Auth:      &store.Auth{HMACSecret: "hmac_secret_4"},
+				},
+			},
+		},
+		{
+			name: "should default portal app ID to the file name without its extension",
+			files: map[string]string{
+				"portal_app_1.json": `{"account_id": "account_1", "plan": "PLAN_FREE", "free_monthly_relay_bonus": 100}`,
+				"portal_app_2":      `{"account_id": "account_2", "plan": "PLAN_UNLIMITED", "auth_cache_ttl_seconds": 300}`,
+				"portal_app_3.json": `{"account_id": "account_3", "plan": "PLAN_UNLIMITED", "allowed_cidrs": ["203.0.113.0/24", "2001:db8::1"]}`,
+				"portal_app_4.json": `{"account_id": "account_4", "plan": "PLAN_UNLIMITED", "burst_allowance": 50}`,
+				"portal_app_5.json": `{"account_id": "account_5", "plan": "PLAN_UNLIMITED", "portal_app_monthly_relay_limit": 1000}`,
+			},
+			expectedPortalApps: map[store.PortalAppID]*store.PortalApp{
+				"portal_app_1": {
+					ID:        "portal_app_1",
+					AccountID: "account_1",
+					PlanType:  "PLAN_FREE",
+					RateLimit: &store.RateLimit{FreeMonthlyRelayBonus: 100},
+				},
+				"portal_app_2": {
+					ID:           "portal_app_2",
+					AccountID:    "account_2",
+					PlanType:     "PLAN_UNLIMITED",
+					AuthCacheTTL: durationPtr(300 * time.Second),
+				},
+				"portal_app_3": {
+					ID:           "portal_app_3",
+					AccountID:    "account_3",
+					PlanType:     "PLAN_UNLIMITED",
+					AllowedCIDRs: []string{"203.0.113.0/24", "2001:db8::1"},
+				},
+				"portal_app_4": {
+					ID:        "portal_app_4",
+					AccountID: "account_4",
+					PlanType:  "PLAN_UNLIMITED",
+					RateLimit: &store.RateLimit{BurstAllowance: 50},
+				},
+				"portal_app_5": {
+					ID:        "portal_app_5",
+					AccountID: "account_5",
+					PlanType:  "PLAN_UNLIMITED",
+					RateLimit: &store.RateLimit{PortalAppMonthlyLimit: 1000},
+				},
+			},
+		},
+		{
+			name: "should skip hidden files and subdirectories",
+			files: map[string]string{
+				"portal_app_1.json":            `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`,
+				".hidden.json":                 `not json`,
+				"subdir/portal_app_2.json":     `{"id": "portal_app_2", "account_id": "account_2", "plan": "PLAN_UNLIMITED"}`,
+				"..2026_10_14/portal_app.json": `not json`,
+			},
+			expectedPortalApps: map[store.PortalAppID]*store.PortalApp{
+				"portal_app_1": {
+					ID:        "portal_app_1",
+					AccountID: "account_1",
+					PlanType:  "PLAN_UNLIMITED",
+				},
+			},
+		},
+		{
+			name:               "should return no portal apps for an empty directory",
+			files:              map[string]string{},
+			expectedPortalApps: map[store.PortalAppID]*store.PortalApp{},
+		},
+		{
+			name: "should error on invalid portal app file",
+			files: map[string]string{
+				"portal_app_1.json": `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`,
+				"portal_app_2.json": `{"id": `,
+			},
+			wantErr: true,
+		},
+		{
+			name: "should load a portal app file missing its account ID and plan",
+			files: map[string]string{
+				"portal_app_1.json": `{"id": "portal_app_1"}`,
+				"portal_app_2.json": `{"id": "portal_app_2", "account_id": "account_2", "plan": "PLAN_UNLIMITED"}`,
+			},
+			expectedPortalApps: map[store.PortalAppID]*store.PortalApp{
+				"portal_app_1": {
+					ID: "portal_app_1",
+				},
+				"portal_app_2": {
+					ID:        "portal_app_2",
+					AccountID: "account_2",
+					PlanType:  "PLAN_UNLIMITED",
+				},
+			},
+		},
+		{
+			name: "should error on portal app file with a negative auth cache TTL",
+			files: map[string]string{
+				"portal_app_1.json": `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED", "auth_cache_ttl_seconds": -1}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error on portal app file with an invalid allowed CIDR",
+			files: map[string]string{
+				"portal_app_1.json": `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED", "allowed_cidrs": ["203.0.113.0/33"]}`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error on portal app ID defined in multiple files",
+			files: map[string]string{
+				"portal_app_1.json": `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`,
+				"portal_app_1_copy": `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			dir := t.TempDir()
+			for name, contents := range test.files {
+				writeTestFile(t, dir, name, contents)
+			}
+
+			driver, err := NewDirectoryDriver(polyzero.NewLogger(), dir)
+			c.NoError(err)
+			defer driver.Close()
+
+			portalApps, err := driver.GetPortalApps()
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.expectedPortalApps, portalApps)
+		})
+	}
+}
+
+func Test_NewDirectoryDriver_InvalidDirectory(t *testing.T) {
+	c := require.New(t)
+
+	dir := t.TempDir()
+
+	_, err := NewDirectoryDriver(polyzero.NewLogger(), filepath.Join(dir, "missing"))
+	c.Error(err)
+
+	writeTestFile(t, dir, "portal_app_1.json", `{}`)
+	_, err = NewDirectoryDriver(polyzero.NewLogger(), filepath.Join(dir, "portal_app_1.json"))
+	c.Error(err)
+}
+
+func Test_GetPortalApps_ChangeDetection(t *testing.T) {
+	c := require.New(t)
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "portal_app_1.json", `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`)
+	writeTestFile(t, dir, "portal_app_2.json", `{"id": "portal_app_2", "account_id": "account_2", "plan": "PLAN_UNLIMITED"}`)
+
+	driver, err := NewDirectoryDriver(polyzero.NewLogger(), dir)
+	c.NoError(err)
+	defer driver.Close()
+
+	_, err = driver.GetPortalApps()
+	c.NoError(err)
+
+	changed, err := driver.hasChanged()
+	c.NoError(err)
+	c.False(changed)
+
+	// Change a file, add a file and remove a file
+	writeTestFile(t, dir, "portal_app_1.json", `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_FREE"}`)
+	c.NoError(os.Chtimes(filepath.Join(dir, "portal_app_1.json"), time.Now(), time.Now().Add(time.Minute)))
+	writeTestFile(t, dir, "portal_app_3.json", `{"id": "portal_app_3", "account_id": "account_3", "plan": "PLAN_UNLIMITED"}`)
+	c.NoError(os.Remove(filepath.Join(dir, "portal_app_2.json")))
+
+	changed, err = driver.hasChanged()
+	c.NoError(err)
+	c.True(changed)
+
+	portalApps, err := driver.GetPortalApps()
+	c.NoError(err)
+	c.Len(portalApps, 2)
+	c.Equal(store.PlanType("PLAN_FREE"), portalApps["portal_app_1"].PlanType)
+	c.NotNil(portalApps["portal_app_3"])
+	c.Nil(portalApps["portal_app_2"])
+
+	// No change is detected once the changes are loaded
+	changed, err = driver.hasChanged()
+	c.NoError(err)
+	c.False(changed)
+}
+
+func Test_GetPortalApps_ReusesUnchangedFiles(t *testing.T) {
+	c := require.New(t)
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "portal_app_1.json", `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`)
+
+	driver, err := NewDirectoryDriver(polyzero.NewLogger(), dir)
+	c.NoError(err)
+	defer driver.Close()
+
+	firstLoad, err := driver.GetPortalApps()
+	c.NoError(err)
+
+	secondLoad, err := driver.GetPortalApps()
+	c.NoError(err)
+
+	// Unchanged files are not re-parsed, so the same portal app is returned
+	c.Same(firstLoad["portal_app_1"], secondLoad["portal_app_1"])
+}
+
+func Test_GetPortalApps_KubernetesVolumeMount(t *testing.T) {
+	c := require.New(t)
+
+	// Kubernetes volume mounts expose each file as a symlink into a hidden "..data" directory
+	dir := t.TempDir()
+	writeTestFile(t, dir, "..2026_10_14/portal_app_1.json", `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`)
+	c.NoError(os.Symlink("..2026_10_14", filepath.Join(dir, "..data")))
+	c.NoError(os.Symlink(filepath.Join("..data", "portal_app_1.json"), filepath.Join(dir, "portal_app_1.json")))
+
+	driver, err := NewDirectoryDriver(polyzero.NewLogger(), dir)
+	c.NoError(err)
+	defer driver.Close()
+
+	portalApps, err := driver.GetPortalApps()
+	c.NoError(err)
+	c.Len(portalApps, 1)
+	c.Equal(store.AccountID("account_1"), portalApps["portal_app_1"].AccountID)
+}
+
+func Test_Watch(t *testing.T) {
+	c := require.New(t)
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "portal_app_1.json", `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`)
+
+	driver, err := NewDirectoryDriver(polyzero.NewLogger(), dir, WithWatchInterval(10*time.Millisecond))
+	c.NoError(err)
+	defer driver.Close()
+
+	_, err = driver.GetPortalApps()
+	c.NoError(err)
+
+	changes := make(chan struct{}, 1)
+	driver.SetChangeHandler(func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+
+	// Adding a file triggers the change handler
+	writeTestFile(t, dir, "portal_app_2.json", `{"id": "portal_app_2", "account_id": "account_2", "plan": "PLAN_UNLIMITED"}`)
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		c.Fail("change handler was not called after a file was added")
+	}
+}
+
+// writeTestFile writes a file in dir, creating any parent directories.
+func writeTestFile(t *testing.T, dir, name, contents string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+}
+
+// durationPtr returns a pointer to the duration, for expected optional PortalApp fields.
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,112 @@
+package directory
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// FieldNames maps a portal app file field (e.g. "secret_key") to the key used for it in the files.
+//
+//   - Allows loading files whose keys differ from the Grove Portal database column names
+//   - Fields with no mapping use their default key
+//   - Keys are matched exactly (case-sensitive)
+type FieldNames map[string]string
+
+// ParseFieldNames parses a comma-separated list of `<field>:<key>` pairs.
+//   - Example: "account_id:accountId,secret_key:apiKey,secret_key_required:apiKeyRequired"
+func ParseFieldNames(s string) (FieldNames, error) {
+	fieldNames := make(FieldNames)
+
+	for _, pair := range strings.Split(s, ",") {
+		field, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid field name mapping %q: expected <field>:<key>", pair)
+		}
+
+		field = strings.TrimSpace(field)
+		if _, exists := fieldNames[field]; exists {
+			return nil, fmt.Errorf("duplicate field name mapping for field %q", field)
+		}
+		fieldNames[field] = strings.TrimSpace(key)
+	}
+
+	if err := fieldNames.validate(); err != nil {
+		return nil, err
+	}
+
+	return fieldNames, nil
+}
+
+// validate ensures every mapping targets a known field and no two fields are read from the same key.
+func (f FieldNames) validate() error {
+	fields := getPortalAppFileFields()
+
+	for field, key := range f {
+		if _, ok := fields[field]; !ok {
+			return fmt.Errorf("invalid field name mapping for unknown field %q: must be one of %s", field, strings.Join(slices.Sorted(maps.Keys(fields)), ", "))
+		}
+		if key == "" {
+			return fmt.Errorf("invalid field name mapping for field %q: empty key", field)
+		}
+	}
+
+	keyFields := make(map[string]string, len(fields))
+	for _, field := range slices.Sorted(maps.Keys(fields)) {
+		key := f.key(field)
+		if existingField, ok := keyFields[key]; ok {
+			return fmt.Errorf("fields %q and %q are both mapped to key %q", existingField, field, key)
+		}
+		keyFields[key] = field
+	}
+
+	return nil
+}
+
+// key returns the key a field is read from in portal app files.
+func (f FieldNames) key(field string) string {
+	if key, ok := f[field]; ok {
+		return key
+	}
+	return field
+}
+
+// decode parses portal app file contents into a portalAppFile, reading each field from its mapped key.
+//   - Fields whose key is missing keep their zero value, as without a mapping
+//     (e.g. a missing account ID is flagged by the portal app store rather than failing the load).
+//   - Returns an error naming the file key if a field has an invalid value.
+func (f FieldNames) decode(data []byte, file *portalAppFile) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	fileValue := reflect.ValueOf(file).Elem()
+	for field, index := range getPortalAppFileFields() {
+		value, ok := raw[f.key(field)]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(value, fileValue.Field(index).Addr().Interface()); err != nil {
+			return fmt.Errorf("invalid value for field %q: %w", f.key(field), err)
+		}
+	}
+
+	return nil
+}
+
+// getPortalAppFileFields returns the index of each portalAppFile struct field, by its JSON tag.
+func getPortalAppFileFields() map[string]int {
+	fileType := reflect.TypeOf(portalAppFile{})
+
+	fields := make(map[string]int, fileType.NumField())
+	for i := range fileType.NumField() {
+		if tag := fileType.Field(i).Tag.Get("json"); tag != "" {
+			fields[tag] = i
+		}
+	}
+	return fields
+}
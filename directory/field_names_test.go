@@ -0,0 +1,164 @@
+package directory
+
+import (
+	"testing"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseFieldNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    FieldNames
+		wantErr bool
+	}{
+		{
+			name:  "should parse field name mappings",
+			input: "account_id:accountId, secret_key:apiKey",
+			want:  FieldNames{"account_id": "accountId", "secret_key": "apiKey"},
+		},
+		{
+			name:  "should allow swapping the keys of two fields",
+			input: "id:account_id,account_id:id",
+			want:  FieldNames{"id": "account_id", "account_id": "id"},
+		},
+		{
+			name:    "should error on missing separator",
+			input:   "account_id",
+			wantErr: true,
+		},
+		{
+			name:    "should error on unknown field",
+			input:   "accountId:account_id",
+			wantErr: true,
+		},
+		{
+			name:    "should error on empty key",
+			input:   "account_id:",
+			wantErr: true,
+		},
+		{
+			name:    "should error on duplicate field",
+			input:   "account_id:accountId,account_id:account",
+			wantErr: true,
+		},
+		{
+			name:    "should error on two fields mapped to the same key",
+			input:   "account_id:owner,plan:owner",
+			wantErr: true,
+		},
+		{
+			name:    "should error on field mapped to the default key of another field",
+			input:   "id:account_id",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			fieldNames, err := ParseFieldNames(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, fieldNames)
+		})
+	}
+}
+
+func Test_GetPortalApps_FieldNames(t *testing.T) {
+	fieldNames := FieldNames{
+		"id":                  "appId",
+		"account_id":          "accountId",
+		"plan":                "planType",
+		"secret_key":          "apiKey",
+		"secret_key_required": "apiKeyRequired",
+		"monthly_relay_limit": "monthlyLimit",
+	}
+
+	tests := []struct {
+		name              string
+		contents          string
+		expectedPortalApp *store.PortalApp
+		expectedErr       string
+	}{
+		{
+			name:     "should load portal app with alternate field names",
+			contents: `{"appId": "portal_app_1", "accountId": "account_1", "planType": "PLAN_UNLIMITED", "apiKey": "api_key_1", "apiKeyRequired": true, "monthlyLimit": 500}`,
+			expectedPortalApp: &store.PortalApp{
+				ID:        "portal_app_1",
+				AccountID: "account_1",
+				PlanType:  "PLAN_UNLIMITED",
+				Auth:      &store.Auth{APIKeys: []string{"api_key_1"}},
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 500},
+			},
+		},
+		{
+			name:     "should read unmapped fields from their default key",
+			contents: `{"appId": "portal_app_1", "accountId": "account_1", "planType": "PLAN_FREE", "free_monthly_relay_bonus": 100}`,
+			expectedPortalApp: &store.PortalApp{
+				ID:        "portal_app_1",
+				AccountID: "account_1",
+				PlanType:  "PLAN_FREE",
+				RateLimit: &store.RateLimit{FreeMonthlyRelayBonus: 100},
+			},
+		},
+		{
+			name:     "should ignore default keys of mapped fields",
+			contents: `{"appId": "portal_app_1", "accountId": "account_1", "planType": "PLAN_UNLIMITED", "account_id": "account_2"}`,
+			expectedPortalApp: &store.PortalApp{
+				ID:        "portal_app_1",
+				AccountID: "account_1",
+				PlanType:  "PLAN_UNLIMITED",
+			},
+		},
+		{
+			name:     "should leave a field empty if its mapped key is missing",
+			contents: `{"appId": "portal_app_1", "account_id": "account_1", "planType": "PLAN_UNLIMITED"}`,
+			expectedPortalApp: &store.PortalApp{
+				ID:       "portal_app_1",
+				PlanType: "PLAN_UNLIMITED",
+			},
+		},
+		{
+			name:        "should error naming the mapped key of a field with an invalid value",
+			contents:    `{"appId": "portal_app_1", "accountId": "account_1", "planType": "PLAN_UNLIMITED", "monthlyLimit": "500"}`,
+			expectedErr: `invalid value for field "monthlyLimit"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			dir := t.TempDir()
+			writeTestFile(t, dir, "portal_app.json", test.contents)
+
+			driver, err := NewDirectoryDriver(polyzero.NewLogger(), dir, WithFieldNames(fieldNames))
+			c.NoError(err)
+			defer driver.Close()
+
+			portalApps, err := driver.GetPortalApps()
+			if test.expectedErr != "" {
+				c.ErrorContains(err, test.expectedErr)
+				return
+			}
+			c.NoError(err)
+			c.Equal(map[store.PortalAppID]*store.PortalApp{test.expectedPortalApp.ID: test.expectedPortalApp}, portalApps)
+		})
+	}
+}
+
+func Test_NewDirectoryDriver_InvalidFieldNames(t *testing.T) {
+	c := require.New(t)
+
+	_, err := NewDirectoryDriver(polyzero.NewLogger(), t.TempDir(), WithFieldNames(FieldNames{"accountId": "account_id"}))
+	c.Error(err)
+}
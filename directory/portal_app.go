@@ -0,0 +1,166 @@
+package directory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+const planFree store.PlanType = "PLAN_FREE"
+
+// portalAppFile is the contents of a single portal app file.
+// Its fields match the columns of the Grove Portal database, so files can be exported from it directly.
+// The key of each field may be overridden using FieldNames.
+//
+// Example file contents:
+//
+//	{
+//	  "id": "1a2b3c4d",
+//	  "account_id": "account_1",
+//	  "plan": "PLAN_FREE",
+//	  "secret_key": "4c352139ec5ca9288126300271d08867",
+//	  "secret_key_required": true
+//	}
+type portalAppFile struct {
+	ID                string         `json:"id"`                  // Defaults to the file name without its extension
+	AccountID         string         `json:"account_id"`          // Maps to PortalApp.AccountID
+	SecretKey         string         `json:"secret_key"`          // Maps to the first of PortalApp.Auth.APIKeys
+	SecretKeys        []string       `json:"secret_keys"`         // Additional PortalApp.Auth.APIKeys, e.g. while rotating keys
+	SecretKeyRequired bool           `json:"secret_key_required"` // Determines whether the portal app requires API key auth
+	AccountSecretKey  string         `json:"account_secret_key"`  // Maps to PortalApp.AccountAuth.APIKeys
+	HMACSecret        string         `json:"hmac_secret"`         // Maps to PortalApp.Auth.HMACSecret
+	MonthlyUserLimit  int32          `json:"monthly_relay_limit"` // Maps to PortalApp.RateLimit.MonthlyUserLimit
+	DailyUserLimit    int32          `json:"daily_relay_limit"`   // Maps to PortalApp.RateLimit.DailyUserLimit
+	Plan              store.PlanType `json:"plan"`                // Maps to PortalApp.PlanType
+	PlanName          string         `json:"plan_name"`           // Maps to PortalApp.PlanName
+
+	BillingStatus store.BillingStatus `json:"billing_status"` // Maps to PortalApp.BillingStatus
+
+	FreeMonthlyRelayBonus int32 `json:"free_monthly_relay_bonus"` // Added to the PLAN_FREE monthly relay limit
+
+	AuthCacheTTLSeconds *int32 `json:"auth_cache_ttl_seconds"` // Maps to PortalApp.AuthCacheTTL
+
+	AllowedCIDRs []string `json:"allowed_cidrs"` // Maps to PortalApp.AllowedCIDRs
+
+	BurstAllowance int32 `json:"burst_allowance"` // Maps to PortalApp.RateLimit.BurstAllowance
+
+	PortalAppMonthlyLimit int32 `json:"portal_app_monthly_relay_limit"` // Maps to PortalApp.RateLimit.PortalAppMonthlyLimit
+}
+
+// loadPortalAppFile reads and parses a single portal app file, reading each field from its mapped key.
+func loadPortalAppFile(path string, fieldNames FieldNames) (*store.PortalApp, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read portal app file: %w", err)
+	}
+
+	var file portalAppFile
+	if err := fieldNames.decode(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse portal app file: %w", err)
+	}
+
+	if file.AuthCacheTTLSeconds != nil && *file.AuthCacheTTLSeconds < 0 {
+		return nil, fmt.Errorf("invalid %q: must be a non-negative number of seconds", fieldNames.key("auth_cache_ttl_seconds"))
+	}
+
+	for _, cidr := range file.AllowedCIDRs {
+		if _, err := store.ParseAllowedCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("invalid %q: %w", fieldNames.key("allowed_cidrs"), err)
+		}
+	}
+
+	if file.ID == "" {
+		name := filepath.Base(path)
+		file.ID = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	return file.convertToPortalApp(), nil
+}
+
+func (f *portalAppFile) convertToPortalApp() *store.PortalApp {
+	return &store.PortalApp{
+		ID:            store.PortalAppID(f.ID),
+		AccountID:     store.AccountID(f.AccountID),
+		PlanType:      f.Plan,
+		PlanName:      f.PlanName,
+		BillingStatus: f.BillingStatus,
+		Auth:          f.getAuthDetails(),
+		AccountAuth:   f.getAccountAuthDetails(),
+		RateLimit:     f.getRateLimitDetails(),
+		AuthCacheTTL:  f.getAuthCacheTTL(),
+		AllowedCIDRs:  f.AllowedCIDRs,
+	}
+}
+
+// getAuthDetails returns the portal app's API key or HMAC auth, if required.
+//   - Any of secret_key and secret_keys is a valid API key; empty keys are not added
+//   - hmac_secret requires HMAC signed requests, taking precedence over secret_key_required
+func (f *portalAppFile) getAuthDetails() *store.Auth {
+	if f.SecretKeyRequired || f.HMACSecret != "" {
+		var apiKeys []string
+		for _, apiKey := range append([]string{f.SecretKey}, f.SecretKeys...) {
+			if apiKey != "" {
+				apiKeys = append(apiKeys, apiKey)
+			}
+		}
+		return &store.Auth{
+			APIKeys:    apiKeys,
+			HMACSecret: f.HMACSecret,
+		}
+	}
+
+	return nil
+}
+
+// getAccountAuthDetails returns the account API key, valid for all of the account's portal apps.
+//   - Each of the account's portal app files should set the same account_secret_key
+//   - Only used for portal apps that do not require their own API key
+func (f *portalAppFile) getAccountAuthDetails() *store.Auth {
+	if f.AccountSecretKey != "" {
+		return &store.Auth{
+			APIKeys: []string{f.AccountSecretKey},
+		}
+	}
+
+	return nil
+}
+
+// getAuthCacheTTL returns the portal app's auth cache TTL hint, or nil if the file does not set one.
+func (f *portalAppFile) getAuthCacheTTL() *time.Duration {
+	if f.AuthCacheTTLSeconds == nil {
+		return nil
+	}
+
+	ttl := time.Duration(*f.AuthCacheTTLSeconds) * time.Second
+	return &ttl
+}
+
+// getRateLimitDetails applies the same rules as the Grove Portal database driver:
+//   - PLAN_FREE is rate limited
+//   - Any plan with a user-specified monthly user limit is rate limited
+//   - Any plan with a daily user limit has a rate limit, only enforced by the Envoy global rate limiter
+//   - Any plan with a burst allowance has a rate limit, only enforced by GUARD's local rate limiter
+//   - Neither the daily user limit nor the burst allowance makes the account monthly rate limited by PEAS
+//   - Any plan with a portal app monthly relay limit is rate limited, per portal app only
+//   - The portal app monthly relay limit is only supported by portal app files, the Grove Portal database has no such column
+func (f *portalAppFile) getRateLimitDetails() *store.RateLimit {
+	if f.Plan == planFree || f.MonthlyUserLimit > 0 || f.DailyUserLimit > 0 || f.BurstAllowance > 0 || f.PortalAppMonthlyLimit > 0 {
+		rateLimit := &store.RateLimit{
+			MonthlyUserLimit:      f.MonthlyUserLimit,
+			DailyUserLimit:        f.DailyUserLimit,
+			BurstAllowance:        f.BurstAllowance,
+			PortalAppMonthlyLimit: f.PortalAppMonthlyLimit,
+		}
+		// Bonus relays only apply to the PLAN_FREE monthly relay limit
+		if f.Plan == planFree {
+			rateLimit.FreeMonthlyRelayBonus = f.FreeMonthlyRelayBonus
+		}
+		return rateLimit
+	}
+
+	return nil
+}
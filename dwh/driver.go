@@ -3,21 +3,152 @@ package dwh
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"google.golang.org/api/iterator"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// Default dataset and table of the relays table queried for usage.
+const (
+	DefaultDataset = "API"
+	DefaultTable   = "relays"
 )
 
 // Driver handles BigQuery operations for data warehouse queries
 type Driver struct {
 	clientBQ  *bigquery.Client
 	projectID string
+
+	// dataset/table: dataset and table of the relays table queried for usage (e.g. "API" and "relays")
+	dataset string
+	table   string
+
+	// queryLabels: BigQuery job labels set on every query, for attributing warehouse cost to PEAS
+	queryLabels map[string]string
+
+	// usageCache: optional cache of monthly usage query results; nil if disabled
+	usageCache *usageCache
+
+	// queryMaxAttempts/queryRetryBaseDelay: retries of monthly usage queries failing with a transient error (see WithQueryRetry)
+	queryMaxAttempts    int
+	queryRetryBaseDelay time.Duration
+
+	// queryMaxRows/queryReadTimeout: optional bounds of reading a monthly usage query result (see WithResultLimits)
+	queryMaxRows     int
+	queryReadTimeout time.Duration
+
+	// runMonthlyUsageQuery runs the monthly usage query; overridden in tests.
+	runMonthlyUsageQuery func(ctx context.Context, minRelayThreshold int64, accountIDs []string) (map[string]AccountUsage, error)
+
+	// readMonthlyUsageQuery executes the monthly usage query and returns its rows; overridden in tests.
+	readMonthlyUsageQuery func(ctx context.Context, minRelayThreshold int64, accountIDs []string) (rowIterator, error)
+
+	// readPortalAppMonthlyUsageQuery executes the portal app monthly usage query and returns its rows; overridden in tests.
+	readPortalAppMonthlyUsageQuery func(ctx context.Context, portalAppIDs []string) (rowIterator, error)
+}
+
+// DriverOption configures optional Driver behavior.
+type DriverOption func(*Driver)
+
+// WithQueryLabels sets BigQuery job labels on every query (e.g. "service": "peas"),
+// so warehouse spend can be attributed to PEAS. Defaults to no labels.
+func WithQueryLabels(labels map[string]string) DriverOption {
+	return func(d *Driver) {
+		d.queryLabels = labels
+	}
+}
+
+// WithRelaysTable sets the dataset and table of the relays table queried for usage,
+// for warehouses with a different naming. Empty values keep the defaults of "API" and "relays".
+// Both must be valid identifiers (see ValidateIdentifier).
+func WithRelaysTable(dataset, table string) DriverOption {
+	return func(d *Driver) {
+		if dataset != "" {
+			d.dataset = dataset
+		}
+		if table != "" {
+			d.table = table
+		}
+	}
+}
+
+// identifierRegex matches the dataset and table identifiers allowed in queries.
+var identifierRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ValidateIdentifier returns an error if the dataset or table identifier contains anything but letters, digits and underscores.
+//   - Identifiers are interpolated into the query, so this guards against SQL injection
+func ValidateIdentifier(identifier string) error {
+	if !identifierRegex.MatchString(identifier) {
+		return fmt.Errorf("invalid identifier %q: must only contain letters, digits and underscores", identifier)
+	}
+	return nil
+}
+
+// queryLabelKeyRegex and queryLabelValueRegex match valid BigQuery label keys and values.
+// Reference: https://cloud.google.com/bigquery/docs/labels-intro#requirements
+var (
+	queryLabelKeyRegex   = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	queryLabelValueRegex = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+)
+
+// ParseQueryLabels parses a comma-separated list of `<key>:<value>` BigQuery job labels.
+//   - Example: "service:peas,env:prod"
+//   - Keys must start with a lowercase letter; keys and values may only contain
+//     lowercase letters, digits, underscores and dashes, up to 63 characters.
+func ParseQueryLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid query label %q: expected <key>:<value>", pair)
+		}
+
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !queryLabelKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid query label key %q", key)
+		}
+		if !queryLabelValueRegex.MatchString(value) {
+			return nil, fmt.Errorf("invalid query label value %q for key %q", value, key)
+		}
+		if _, exists := labels[key]; exists {
+			return nil, fmt.Errorf("duplicate query label key %q", key)
+		}
+
+		labels[key] = value
+	}
+
+	return labels, nil
 }
 
 // monthlyUsageRow represents a row from the monthly usage query
 type monthlyUsageRow struct {
-	AccountID   string `bigquery:"account_id"`
-	TotalRelays int64  `bigquery:"total_relays"`
+	AccountID        string `bigquery:"account_id"`
+	SuccessfulRelays int64  `bigquery:"successful_relays"`
+	FailedRelays     int64  `bigquery:"failed_relays"`
+}
+
+// portalAppMonthlyUsageRow represents a row from the portal app monthly usage query
+type portalAppMonthlyUsageRow struct {
+	PortalAppID      string `bigquery:"portal_application_id"`
+	SuccessfulRelays int64  `bigquery:"successful_relays"`
+	FailedRelays     int64  `bigquery:"failed_relays"`
+}
+
+// AccountUsage is an account's (or portal app's) month-to-date relay usage, broken down by relay outcome.
+type AccountUsage struct {
+	SuccessfulRelays int64
+	FailedRelays     int64
+}
+
+// TotalRelays returns the sum of successful and failed relays.
+func (u AccountUsage) TotalRelays() int64 {
+	return u.SuccessfulRelays + u.FailedRelays
 }
 
 // ===========================================================================================
@@ -25,16 +156,50 @@ type monthlyUsageRow struct {
 // ===========================================================================================
 
 // NewDriver creates a new BigQuery Driver instance
-func NewDriver(ctx context.Context, projectID string) (*Driver, error) {
+func NewDriver(ctx context.Context, projectID string, opts ...DriverOption) (*Driver, error) {
+	d := &Driver{
+		projectID: projectID,
+		dataset:   DefaultDataset,
+		table:     DefaultTable,
+
+		queryMaxAttempts: 1,
+	}
+	d.runMonthlyUsageQuery = d.queryMonthlyUsageWithRetry
+	d.readMonthlyUsageQuery = d.readMonthlyUsage
+	d.readPortalAppMonthlyUsageQuery = d.readPortalAppMonthlyUsage
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	// Validate the identifiers interpolated into the query before connecting
+	for _, identifier := range []string{d.dataset, d.table} {
+		if err := ValidateIdentifier(identifier); err != nil {
+			return nil, fmt.Errorf("invalid relays table: %w", err)
+		}
+	}
+
 	clientBQ, err := bigquery.NewClient(ctx, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to bigQuery: %w", err)
 	}
+	d.clientBQ = clientBQ
 
-	return &Driver{
-		clientBQ:  clientBQ,
-		projectID: projectID,
-	}, nil
+	return d, nil
+}
+
+// Ping verifies the data warehouse is reachable by running a trivial query.
+//
+// Used to wait for BigQuery to become reachable on startup, before the rate limit store is initialized.
+func (d *Driver) Ping(ctx context.Context) error {
+	query := d.clientBQ.Query("SELECT 1")
+	if len(d.queryLabels) > 0 {
+		query.Labels = d.queryLabels
+	}
+
+	if _, err := query.Read(ctx); err != nil {
+		return fmt.Errorf("failed to ping bigQuery: %w", err)
+	}
+	return nil
 }
 
 // close releases BigQuery client resources
@@ -49,68 +214,252 @@ func (d *Driver) Close() {
 // GetMonthToMomentUsage returns monthly usage totals for accounts above the threshold.
 //
 // The query aggregates relay counts from the first day of the current month through today,
-// grouped by account_id. Only returns accounts with relay activity above minRelayThreshold.
+// grouped by account_id. Only returns accounts with total relay activity (successful + failed)
+// above minRelayThreshold.
+//
+// If accountIDs is not nil, only the given accounts are queried, filtering server-side to reduce
+// scanned data; an empty, non-nil accountIDs returns no usage without querying.
 //
-// Returns a map of account_id -> total relay count for month-to-date usage.
+// If the usage cache is enabled (see WithUsageCacheTTL), a fresh cached result is returned without querying,
+// unless the context was returned by ContextWithUsageCacheBypass.
+//
+// Returns a map of account_id -> successful and failed relay counts for month-to-date usage.
 func (d *Driver) GetMonthToMomentUsage(
 	ctx context.Context,
 	minRelayThreshold int64,
-) (map[string]int64, error) {
-	// Execute query with project ID and threshold
-	query := getMonthlyUsageQuery(d.projectID, minRelayThreshold)
-	it, err := d.clientBQ.Query(query).Read(ctx)
+	accountIDs []string,
+) (map[string]AccountUsage, error) {
+	if accountIDs != nil && len(accountIDs) == 0 {
+		return map[string]AccountUsage{}, nil
+	}
+
+	if d.usageCache == nil {
+		return d.runMonthlyUsageQuery(ctx, minRelayThreshold, accountIDs)
+	}
+
+	key := d.usageCache.newKey(minRelayThreshold, accountIDs)
+	if !isUsageCacheBypassed(ctx) {
+		if usage, ok := d.usageCache.get(key); ok {
+			return usage, nil
+		}
+	}
+
+	usage, err := d.runMonthlyUsageQuery(ctx, minRelayThreshold, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+	d.usageCache.set(key, usage)
+	return usage, nil
+}
+
+// queryMonthlyUsage runs the monthly usage query on BigQuery.
+//   - Truncates the result if it exceeds the result limits (see WithResultLimits)
+func (d *Driver) queryMonthlyUsage(
+	ctx context.Context,
+	minRelayThreshold int64,
+	accountIDs []string,
+) (map[string]AccountUsage, error) {
+	readCtx, cancel := d.withQueryReadTimeout(ctx)
+	defer cancel()
+
+	// Execute query with project ID, threshold and optional account filter
+	it, err := d.readMonthlyUsageQuery(readCtx, minRelayThreshold, accountIDs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute monthly usage query: %w", err)
 	}
 
 	// Process results
-	results := make(map[string]int64)
+	results := make(map[string]AccountUsage)
 	for {
 		var row monthlyUsageRow
 		err := it.Next(&row)
 		if err == iterator.Done {
 			break
 		}
+		// Keep the rows read so far if the read timeout elapsed while paging through the result
+		if err != nil && len(results) > 0 && isQueryReadTimeout(ctx, readCtx) {
+			metrics.RecordUsageResultsTruncated(metrics.UsageResultsTruncatedReasonReadTimeout)
+			break
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to read query result: %w", err)
 		}
 
-		results[row.AccountID] = row.TotalRelays
+		// Only count the result as truncated if it has a row past the max rows
+		if d.queryMaxRows > 0 && len(results) >= d.queryMaxRows {
+			metrics.RecordUsageResultsTruncated(metrics.UsageResultsTruncatedReasonMaxRows)
+			break
+		}
+
+		results[row.AccountID] = AccountUsage{
+			SuccessfulRelays: row.SuccessfulRelays,
+			FailedRelays:     row.FailedRelays,
+		}
 	}
 
 	return results, nil
 }
 
+// GetPortalAppMonthToMomentUsage returns monthly usage totals for the given portal apps,
+// for enforcing portal app monthly limits in addition to account limits.
+//
+// The query aggregates relay counts from the first day of the current month through today,
+// grouped by portal_application_id. Portal apps with no relays this month are not returned;
+// an empty portalAppIDs returns no usage without querying.
+//
+// Unlike GetMonthToMomentUsage, results are not cached nor bounded by the result limits:
+// only portal apps with a limit of their own are queried. Transient errors are retried (see WithQueryRetry).
+//
+// Returns a map of portal_application_id -> successful and failed relay counts for month-to-date usage.
+func (d *Driver) GetPortalAppMonthToMomentUsage(
+	ctx context.Context,
+	portalAppIDs []string,
+) (map[string]AccountUsage, error) {
+	if len(portalAppIDs) == 0 {
+		return map[string]AccountUsage{}, nil
+	}
+
+	return d.withQueryRetry(ctx, func() (map[string]AccountUsage, error) {
+		return d.queryPortalAppMonthlyUsage(ctx, portalAppIDs)
+	})
+}
+
+// queryPortalAppMonthlyUsage runs the portal app monthly usage query on BigQuery.
+func (d *Driver) queryPortalAppMonthlyUsage(ctx context.Context, portalAppIDs []string) (map[string]AccountUsage, error) {
+	it, err := d.readPortalAppMonthlyUsageQuery(ctx, portalAppIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute portal app monthly usage query: %w", err)
+	}
+
+	results := make(map[string]AccountUsage)
+	for {
+		var row portalAppMonthlyUsageRow
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read query result: %w", err)
+		}
+
+		results[row.PortalAppID] = AccountUsage{
+			SuccessfulRelays: row.SuccessfulRelays,
+			FailedRelays:     row.FailedRelays,
+		}
+	}
+
+	return results, nil
+}
+
+// readMonthlyUsage executes the monthly usage query on BigQuery and returns its rows.
+func (d *Driver) readMonthlyUsage(ctx context.Context, minRelayThreshold int64, accountIDs []string) (rowIterator, error) {
+	return d.newMonthlyUsageQuery(minRelayThreshold, accountIDs).Read(ctx)
+}
+
+// newMonthlyUsageQuery returns the monthly usage query, with the account filter parameter and job labels set.
+func (d *Driver) newMonthlyUsageQuery(minRelayThreshold int64, accountIDs []string) *bigquery.Query {
+	filterAccounts := accountIDs != nil
+
+	query := d.clientBQ.Query(getMonthlyUsageQuery(d.projectID, d.dataset, d.table, minRelayThreshold, filterAccounts))
+	if filterAccounts {
+		query.Parameters = []bigquery.QueryParameter{
+			{Name: accountIDsQueryParameter, Value: accountIDs},
+		}
+	}
+	if len(d.queryLabels) > 0 {
+		query.Labels = d.queryLabels
+	}
+
+	return query
+}
+
+// readPortalAppMonthlyUsage executes the portal app monthly usage query on BigQuery and returns its rows.
+func (d *Driver) readPortalAppMonthlyUsage(ctx context.Context, portalAppIDs []string) (rowIterator, error) {
+	query := d.clientBQ.Query(getPortalAppMonthlyUsageQuery(d.projectID, d.dataset, d.table))
+	query.Parameters = []bigquery.QueryParameter{
+		{Name: portalAppIDsQueryParameter, Value: portalAppIDs},
+	}
+	if len(d.queryLabels) > 0 {
+		query.Labels = d.queryLabels
+	}
+
+	return query.Read(ctx)
+}
+
+// Names of the query parameters holding the account and portal app IDs to filter usage by.
+const (
+	accountIDsQueryParameter   = "account_ids"
+	portalAppIDsQueryParameter = "portal_app_ids"
+)
+
 // getMonthlyUsageQuery returns the BigQuery SQL for monthly usage aggregation.
 //
 // The query performs month-to-date filtering using BigQuery's date functions:
 // - DATE_TRUNC(CURRENT_DATE(), MONTH) gets the first day of current month
 // - CURRENT_DATE() gets today's date
-// - Only includes accounts above the specified relay threshold
+// - Successful (txs_cnt) and failed (errs_cnt) relays are returned separately
+// - Only includes accounts whose total relays are above the specified relay threshold
 // - Results are ordered by total relay count (highest first)
 //
 // Parameters:
 // - projectID: GCP project containing the dataset
+// - dataset/table: dataset and table of the relays table; must be valid identifiers (see ValidateIdentifier)
 // - minRelayThreshold: minimum relay count to include accounts
+// - filterAccounts: only include accounts in the @account_ids array query parameter
 func getMonthlyUsageQuery(
 	projectID string,
+	dataset string,
+	table string,
 	minRelayThreshold int64,
+	filterAccounts bool,
 ) string {
+	var accountFilter string
+	if filterAccounts {
+		accountFilter = fmt.Sprintf("\n\t\t\tAND account_id IN UNNEST(@%s)", accountIDsQueryParameter)
+	}
+
 	return fmt.Sprintf(`
 		SELECT
 			account_id,
-			SUM(COALESCE(txs_cnt, 0) + COALESCE(errs_cnt, 0)) AS total_relays
+			SUM(COALESCE(txs_cnt, 0)) AS successful_relays,
+			SUM(COALESCE(errs_cnt, 0)) AS failed_relays
 		FROM
-			`+"`%s.API.relays`"+`
+			`+"`%s.%s.%s`"+`
 		WHERE
 			DATE(ts) >= DATE_TRUNC(CURRENT_DATE(), MONTH)
 			AND DATE(ts) <= CURRENT_DATE()
-			AND account_id IS NOT NULL
+			AND account_id IS NOT NULL%s
 		GROUP BY
 			account_id
 		HAVING
 			SUM(COALESCE(txs_cnt, 0) + COALESCE(errs_cnt, 0)) >= %d
 		ORDER BY
-			total_relays DESC, account_id;
-	`, projectID, minRelayThreshold)
+			SUM(COALESCE(txs_cnt, 0) + COALESCE(errs_cnt, 0)) DESC, account_id;
+	`, projectID, dataset, table, accountFilter, minRelayThreshold)
+}
+
+// getPortalAppMonthlyUsageQuery returns the BigQuery SQL for monthly usage aggregation per portal app.
+//
+// Matches the month-to-date filtering of getMonthlyUsageQuery, but:
+// - Groups relays by portal_application_id rather than account_id
+// - Only includes portal apps in the @portal_app_ids array query parameter, with no relay threshold
+//
+// Parameters:
+// - projectID: GCP project containing the dataset
+// - dataset/table: dataset and table of the relays table; must be valid identifiers (see ValidateIdentifier)
+func getPortalAppMonthlyUsageQuery(projectID string, dataset string, table string) string {
+	return fmt.Sprintf(`
+		SELECT
+			portal_application_id,
+			SUM(COALESCE(txs_cnt, 0)) AS successful_relays,
+			SUM(COALESCE(errs_cnt, 0)) AS failed_relays
+		FROM
+			`+"`%s.%s.%s`"+`
+		WHERE
+			DATE(ts) >= DATE_TRUNC(CURRENT_DATE(), MONTH)
+			AND DATE(ts) <= CURRENT_DATE()
+			AND portal_application_id IN UNNEST(@%s)
+		GROUP BY
+			portal_application_id;
+	`, projectID, dataset, table, portalAppIDsQueryParameter)
 }
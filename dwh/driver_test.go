@@ -0,0 +1,317 @@
+package dwh
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+func Test_getMonthlyUsageQuery(t *testing.T) {
+	tests := []struct {
+		name                string
+		filterAccounts      bool
+		expectAccountFilter bool
+	}{
+		{
+			name:                "should query all accounts if not filtering",
+			filterAccounts:      false,
+			expectAccountFilter: false,
+		},
+		{
+			name:                "should filter accounts by the account IDs query parameter",
+			filterAccounts:      true,
+			expectAccountFilter: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			query := getMonthlyUsageQuery("test-project", DefaultDataset, DefaultTable, 1_000_000, test.filterAccounts)
+
+			c.Contains(query, "`test-project.API.relays`")
+			c.Contains(query, ">= 1000000")
+			c.Equal(test.expectAccountFilter, strings.Contains(query, "AND account_id IN UNNEST(@account_ids)"))
+
+			// The account filter must be part of the WHERE clause, before grouping
+			if test.expectAccountFilter {
+				c.Less(strings.Index(query, "UNNEST(@account_ids)"), strings.Index(query, "GROUP BY"))
+			}
+		})
+	}
+}
+
+func Test_getMonthlyUsageQuery_RelaysTable(t *testing.T) {
+	tests := []struct {
+		name          string
+		dataset       string
+		table         string
+		expectedTable string
+	}{
+		{
+			name:          "should query the default relays table",
+			expectedTable: "`test-project.API.relays`",
+		},
+		{
+			name:          "should query a custom dataset and table",
+			dataset:       "analytics_v2",
+			table:         "Relays_2025",
+			expectedTable: "`test-project.analytics_v2.Relays_2025`",
+		},
+		{
+			name:          "should query the default dataset with a custom table",
+			table:         "relays_v2",
+			expectedTable: "`test-project.API.relays_v2`",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			d := &Driver{projectID: "test-project", dataset: DefaultDataset, table: DefaultTable}
+			WithRelaysTable(test.dataset, test.table)(d)
+
+			query := getMonthlyUsageQuery(d.projectID, d.dataset, d.table, 1_000_000, false)
+			c.Contains(query, "FROM\n\t\t\t"+test.expectedTable+"\n")
+		})
+	}
+}
+
+func Test_getPortalAppMonthlyUsageQuery(t *testing.T) {
+	c := require.New(t)
+
+	query := getPortalAppMonthlyUsageQuery("test-project", DefaultDataset, DefaultTable)
+
+	c.Contains(query, "FROM\n\t\t\t`test-project.API.relays`\n")
+	c.Contains(query, "GROUP BY\n\t\t\tportal_application_id")
+
+	// The portal app filter must be part of the WHERE clause, before grouping
+	c.Contains(query, "AND portal_application_id IN UNNEST(@portal_app_ids)")
+	c.Less(strings.Index(query, "UNNEST(@portal_app_ids)"), strings.Index(query, "GROUP BY"))
+}
+
+// fakePortalAppRowIterator returns the rows, then iterator.Done.
+type fakePortalAppRowIterator struct {
+	rows []portalAppMonthlyUsageRow
+}
+
+func (f *fakePortalAppRowIterator) Next(dst any) error {
+	if len(f.rows) == 0 {
+		return iterator.Done
+	}
+	*dst.(*portalAppMonthlyUsageRow) = f.rows[0]
+	f.rows = f.rows[1:]
+	return nil
+}
+
+func Test_GetPortalAppMonthToMomentUsage(t *testing.T) {
+	tests := []struct {
+		name          string
+		portalAppIDs  []string
+		failures      int
+		expectedUsage map[string]AccountUsage
+		expectedErr   bool
+		expectedReads int
+	}{
+		{
+			name:         "should return the usage of each portal app",
+			portalAppIDs: []string{"portal_app_1", "portal_app_2"},
+			expectedUsage: map[string]AccountUsage{
+				"portal_app_1": {SuccessfulRelays: 100, FailedRelays: 5},
+				"portal_app_2": {SuccessfulRelays: 20},
+			},
+			expectedReads: 1,
+		},
+		{
+			name:         "should retry a transient error until the query succeeds",
+			portalAppIDs: []string{"portal_app_1", "portal_app_2"},
+			failures:     1,
+			expectedUsage: map[string]AccountUsage{
+				"portal_app_1": {SuccessfulRelays: 100, FailedRelays: 5},
+				"portal_app_2": {SuccessfulRelays: 20},
+			},
+			expectedReads: 2,
+		},
+		{
+			name:          "should return an error once every attempt failed",
+			portalAppIDs:  []string{"portal_app_1"},
+			failures:      3,
+			expectedErr:   true,
+			expectedReads: 2,
+		},
+		{
+			name:          "should not query if there are no portal apps",
+			portalAppIDs:  []string{},
+			expectedUsage: map[string]AccountUsage{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			var reads int
+			d := &Driver{}
+			WithQueryRetry(2, time.Millisecond)(d)
+			d.readPortalAppMonthlyUsageQuery = func(_ context.Context, portalAppIDs []string) (rowIterator, error) {
+				reads++
+				c.Equal(test.portalAppIDs, portalAppIDs)
+				if reads <= test.failures {
+					return nil, &googleapi.Error{Code: http.StatusServiceUnavailable}
+				}
+				return &fakePortalAppRowIterator{rows: []portalAppMonthlyUsageRow{
+					{PortalAppID: "portal_app_1", SuccessfulRelays: 100, FailedRelays: 5},
+					{PortalAppID: "portal_app_2", SuccessfulRelays: 20},
+				}}, nil
+			}
+
+			usage, err := d.GetPortalAppMonthToMomentUsage(context.Background(), test.portalAppIDs)
+			c.Equal(test.expectedReads, reads)
+			if test.expectedErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.expectedUsage, usage)
+		})
+	}
+}
+
+func Test_ValidateIdentifier(t *testing.T) {
+	c := require.New(t)
+
+	for _, valid := range []string{"API", "relays", "relays_v2", "Relays2025"} {
+		c.NoError(ValidateIdentifier(valid), "identifier %q", valid)
+	}
+
+	invalid := []string{
+		"",
+		"relays-v2",
+		"API.relays",
+		"relays`",
+		"relays` WHERE 1=1; --",
+		"relays; DROP TABLE relays",
+		"relays v2",
+	}
+	for _, identifier := range invalid {
+		c.Error(ValidateIdentifier(identifier), "identifier %q", identifier)
+	}
+}
+
+func Test_NewDriver_InvalidRelaysTable(t *testing.T) {
+	c := require.New(t)
+
+	// Rejected before connecting, so no credentials are needed
+	_, err := NewDriver(context.Background(), "test-project", WithRelaysTable("API", "relays`; --"))
+	c.ErrorContains(err, "invalid relays table")
+}
+
+func Test_ParseQueryLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "should parse query labels",
+			input: "service:peas, env:prod",
+			want:  map[string]string{"service": "peas", "env": "prod"},
+		},
+		{
+			name:  "should allow empty label values",
+			input: "service:peas,team:",
+			want:  map[string]string{"service": "peas", "team": ""},
+		},
+		{
+			name:    "should error on missing separator",
+			input:   "service",
+			wantErr: true,
+		},
+		{
+			name:    "should error on uppercase label key",
+			input:   "Service:peas",
+			wantErr: true,
+		},
+		{
+			name:    "should error on label key not starting with a letter",
+			input:   "1service:peas",
+			wantErr: true,
+		},
+		{
+			name:    "should error on invalid label value characters",
+			input:   "env:prod.us",
+			wantErr: true,
+		},
+		{
+			name:    "should error on duplicate label key",
+			input:   "env:prod,env:staging",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			labels, err := ParseQueryLabels(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, labels)
+		})
+	}
+}
+
+func Test_newMonthlyUsageQuery_Labels(t *testing.T) {
+	tests := []struct {
+		name        string
+		queryLabels map[string]string
+		accountIDs  []string
+		wantLabels  map[string]string
+	}{
+		{
+			name:        "should set query labels on the query",
+			queryLabels: map[string]string{"service": "peas", "env": "prod"},
+			wantLabels:  map[string]string{"service": "peas", "env": "prod"},
+		},
+		{
+			name:        "should set query labels on the account filtered query",
+			queryLabels: map[string]string{"service": "peas"},
+			accountIDs:  []string{"account_1"},
+			wantLabels:  map[string]string{"service": "peas"},
+		},
+		{
+			name: "should not set query labels if none are configured",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			clientBQ, err := bigquery.NewClient(context.Background(), "test-project", option.WithoutAuthentication())
+			c.NoError(err)
+			defer clientBQ.Close()
+
+			d := &Driver{clientBQ: clientBQ, projectID: "test-project"}
+			WithQueryLabels(test.queryLabels)(d)
+
+			query := d.newMonthlyUsageQuery(1_000_000, test.accountIDs)
+			c.Equal(test.wantLabels, query.Labels)
+			c.Equal(test.accountIDs != nil, len(query.Parameters) == 1)
+		})
+	}
+}
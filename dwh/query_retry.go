@@ -0,0 +1,117 @@
+package dwh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// retryableStatusCodes are the HTTP status codes of transient BigQuery errors.
+var retryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryableReasons are the reasons of transient BigQuery errors.
+// Reference: https://cloud.google.com/bigquery/docs/error-messages
+var retryableReasons = []string{
+	"backendError",
+	"internalError",
+	"jobBackendError",
+	"jobInternalError",
+	"quotaExceeded",
+	"rateLimitExceeded",
+}
+
+// WithQueryRetry retries a monthly usage query failing with a transient BigQuery error
+// (e.g. quota exceeded, 503) up to maxAttempts times in total, doubling the delay between
+// attempts from baseDelay. Defaults to a single attempt.
+//
+//   - Each retried attempt is counted by the data source refresh errors metric
+//   - Returns the last error once every attempt failed, or on the first non-retryable error
+func WithQueryRetry(maxAttempts int, baseDelay time.Duration) DriverOption {
+	return func(d *Driver) {
+		d.queryMaxAttempts = maxAttempts
+		d.queryRetryBaseDelay = baseDelay
+	}
+}
+
+// rowIterator iterates over the rows of a query result (e.g. *bigquery.RowIterator).
+type rowIterator interface {
+	Next(dst any) error
+}
+
+// queryMonthlyUsageWithRetry runs the monthly usage query, retrying transient errors
+// as configured by WithQueryRetry.
+func (d *Driver) queryMonthlyUsageWithRetry(
+	ctx context.Context,
+	minRelayThreshold int64,
+	accountIDs []string,
+) (map[string]AccountUsage, error) {
+	return d.withQueryRetry(ctx, func() (map[string]AccountUsage, error) {
+		return d.queryMonthlyUsage(ctx, minRelayThreshold, accountIDs)
+	})
+}
+
+// withQueryRetry runs the usage query, retrying transient errors as configured by WithQueryRetry.
+func (d *Driver) withQueryRetry(
+	ctx context.Context,
+	runQuery func() (map[string]AccountUsage, error),
+) (map[string]AccountUsage, error) {
+	delay := d.queryRetryBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		usage, err := runQuery()
+		if err == nil {
+			return usage, nil
+		}
+
+		if attempt >= d.queryMaxAttempts || !isRetryableQueryError(err) {
+			return nil, err
+		}
+
+		// The final failed attempt is recorded by the caller, as for any other refresh error
+		metrics.RecordDataSourceRefreshError(metrics.RateLimitStoreSourceType, metrics.BigqueryErrorType)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("monthly usage query canceled after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// isRetryableQueryError returns true if the error is a transient BigQuery error,
+// by its HTTP status code or reason.
+func isRetryableQueryError(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		if slices.Contains(retryableStatusCodes, apiErr.Code) {
+			return true
+		}
+		for _, item := range apiErr.Errors {
+			if slices.Contains(retryableReasons, item.Reason) {
+				return true
+			}
+		}
+	}
+
+	var jobErr *bigquery.Error
+	if errors.As(err, &jobErr) {
+		return slices.Contains(retryableReasons, jobErr.Reason)
+	}
+
+	return false
+}
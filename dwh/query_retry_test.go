@@ -0,0 +1,230 @@
+package dwh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// fakeRowIterator returns the rows, then iterator.Done.
+type fakeRowIterator struct {
+	rows []monthlyUsageRow
+}
+
+func (f *fakeRowIterator) Next(dst any) error {
+	if len(f.rows) == 0 {
+		return iterator.Done
+	}
+	*dst.(*monthlyUsageRow) = f.rows[0]
+	f.rows = f.rows[1:]
+	return nil
+}
+
+// fakeQueryReader fails the first failures reads with err, then returns the rows.
+type fakeQueryReader struct {
+	failures int
+	err      error
+	rows     []monthlyUsageRow
+	reads    int
+}
+
+func (f *fakeQueryReader) read(_ context.Context, _ int64, _ []string) (rowIterator, error) {
+	f.reads++
+	if f.reads <= f.failures {
+		return nil, f.err
+	}
+	return &fakeRowIterator{rows: f.rows}, nil
+}
+
+// newTestRetryingDriver returns a Driver reading monthly usage from the reader, with the query retry options.
+func newTestRetryingDriver(reader *fakeQueryReader, maxAttempts int) *Driver {
+	d := &Driver{queryMaxAttempts: 1}
+	WithQueryRetry(maxAttempts, time.Millisecond)(d)
+	d.runMonthlyUsageQuery = d.queryMonthlyUsageWithRetry
+	d.readMonthlyUsageQuery = reader.read
+	return d
+}
+
+func Test_GetMonthToMomentUsage_QueryRetry(t *testing.T) {
+	unavailableErr := &googleapi.Error{Code: http.StatusServiceUnavailable}
+	rows := []monthlyUsageRow{{AccountID: "account_1", SuccessfulRelays: 100, FailedRelays: 5}}
+
+	tests := []struct {
+		name                 string
+		maxAttempts          int
+		failures             int
+		err                  error
+		expectedErr          bool
+		expectedReads        int
+		expectedRetryRecords float64
+	}{
+		{
+			name:          "should not retry a successful query",
+			maxAttempts:   3,
+			expectedReads: 1,
+		},
+		{
+			name:                 "should retry a transient error until the query succeeds",
+			maxAttempts:          3,
+			failures:             2,
+			err:                  unavailableErr,
+			expectedReads:        3,
+			expectedRetryRecords: 2,
+		},
+		{
+			name:                 "should return the last error after exhausting retries",
+			maxAttempts:          3,
+			failures:             3,
+			err:                  unavailableErr,
+			expectedErr:          true,
+			expectedReads:        3,
+			expectedRetryRecords: 2,
+		},
+		{
+			name:          "should not retry by default",
+			maxAttempts:   1,
+			failures:      1,
+			err:           unavailableErr,
+			expectedErr:   true,
+			expectedReads: 1,
+		},
+		{
+			name:          "should not retry a non-retryable error",
+			maxAttempts:   3,
+			failures:      1,
+			err:           &googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "invalidQuery"}}},
+			expectedErr:   true,
+			expectedReads: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			reader := &fakeQueryReader{failures: test.failures, err: test.err, rows: rows}
+			d := newTestRetryingDriver(reader, test.maxAttempts)
+
+			refreshErrorsBefore := getBigqueryRefreshErrorCount(t)
+
+			usage, err := d.GetMonthToMomentUsage(context.Background(), 0, nil)
+			if test.expectedErr {
+				c.Error(err)
+				c.ErrorIs(err, test.err)
+			} else {
+				c.NoError(err)
+				c.Equal(map[string]AccountUsage{"account_1": {SuccessfulRelays: 100, FailedRelays: 5}}, usage)
+			}
+			c.Equal(test.expectedReads, reader.reads)
+			c.Equal(test.expectedRetryRecords, getBigqueryRefreshErrorCount(t)-refreshErrorsBefore)
+		})
+	}
+}
+
+func Test_GetMonthToMomentUsage_QueryRetryContextCanceled(t *testing.T) {
+	c := require.New(t)
+
+	reader := &fakeQueryReader{failures: 3, err: &googleapi.Error{Code: http.StatusServiceUnavailable}}
+	d := newTestRetryingDriver(reader, 3)
+	WithQueryRetry(3, time.Hour)(d)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := d.GetMonthToMomentUsage(ctx, 0, nil)
+	c.Error(err)
+	c.Equal(1, reader.reads)
+}
+
+func Test_isRetryableQueryError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "should retry a 503",
+			err:      &googleapi.Error{Code: http.StatusServiceUnavailable},
+			expected: true,
+		},
+		{
+			name:     "should retry a 429",
+			err:      &googleapi.Error{Code: http.StatusTooManyRequests},
+			expected: true,
+		},
+		{
+			name:     "should retry a quota exceeded error",
+			err:      &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}}},
+			expected: true,
+		},
+		{
+			name:     "should retry a wrapped transient error",
+			err:      fmt.Errorf("failed to execute monthly usage query: %w", &googleapi.Error{Code: http.StatusBadGateway}),
+			expected: true,
+		},
+		{
+			name:     "should retry a transient job error",
+			err:      &bigquery.Error{Reason: "backendError"},
+			expected: true,
+		},
+		{
+			name: "should not retry an invalid query",
+			err:  &googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "invalidQuery"}}},
+		},
+		{
+			name: "should not retry a permission denied error",
+			err:  &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "accessDenied"}}},
+		},
+		{
+			name: "should not retry an invalid job",
+			err:  &bigquery.Error{Reason: "invalid"},
+		},
+		{
+			name: "should not retry a canceled context",
+			err:  context.Canceled,
+		},
+		{
+			name: "should not retry an unknown error",
+			err:  errors.New("unknown"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.New(t).Equal(test.expected, isRetryableQueryError(test.err))
+		})
+	}
+}
+
+// getBigqueryRefreshErrorCount returns the number of BigQuery errors counted for the rate limit store.
+func getBigqueryRefreshErrorCount(t *testing.T) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_data_source_refresh_errors_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["source_type"] == metrics.RateLimitStoreSourceType && labels["error_type"] == metrics.BigqueryErrorType {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package dwh
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WithResultLimits bounds reading a monthly usage query result, which BigQuery pages for large results,
+// so a huge result cannot hold a refresh (and its memory) for minutes. Defaults to no limits.
+//
+//   - maxRows: the result is truncated after maxRows rows; 0 disables the limit
+//   - readTimeout: each query attempt, from executing the query to reading the last page, is canceled after
+//     readTimeout, and the rows read so far are returned; 0 disables the timeout
+//
+// Rows are ordered by total relays, so a truncated result keeps the highest-usage accounts.
+// Accounts dropped from a truncated result are not rate limited until a later refresh returns them.
+// Each truncated result is counted by the usage results truncated metric.
+func WithResultLimits(maxRows int, readTimeout time.Duration) DriverOption {
+	return func(d *Driver) {
+		d.queryMaxRows = maxRows
+		d.queryReadTimeout = readTimeout
+	}
+}
+
+// withQueryReadTimeout returns the context a monthly usage query attempt is read with,
+// canceled after the read timeout if set (see WithResultLimits).
+func (d *Driver) withQueryReadTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryReadTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.queryReadTimeout)
+}
+
+// isQueryReadTimeout returns true if reading the query result failed because the read timeout elapsed,
+// rather than the parent context being canceled or the query failing.
+func isQueryReadTimeout(ctx, readCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(readCtx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,175 @@
+package dwh
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// pagingRowIterator returns rows rows, ordered by decreasing usage, then iterator.Done.
+// If blockAfter is positive, it blocks after blockAfter rows until ctx is done, like a page fetch that never returns.
+type pagingRowIterator struct {
+	ctx        context.Context
+	rows       int
+	blockAfter int
+	next       int
+}
+
+func (it *pagingRowIterator) Next(dst any) error {
+	if it.blockAfter > 0 && it.next >= it.blockAfter {
+		<-it.ctx.Done()
+		return it.ctx.Err()
+	}
+	if it.next >= it.rows {
+		return iterator.Done
+	}
+	*dst.(*monthlyUsageRow) = monthlyUsageRow{
+		AccountID:        fmt.Sprintf("account_%d", it.next),
+		SuccessfulRelays: int64(it.rows - it.next),
+	}
+	it.next++
+	return nil
+}
+
+// newTestPagingDriver returns a Driver reading a result of rows rows, blocking after blockAfter rows if positive.
+func newTestPagingDriver(rows, blockAfter int, opts ...DriverOption) *Driver {
+	d := &Driver{queryMaxAttempts: 1}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.runMonthlyUsageQuery = d.queryMonthlyUsageWithRetry
+	d.readMonthlyUsageQuery = func(ctx context.Context, _ int64, _ []string) (rowIterator, error) {
+		return &pagingRowIterator{ctx: ctx, rows: rows, blockAfter: blockAfter}, nil
+	}
+	return d
+}
+
+func Test_GetMonthToMomentUsage_ResultLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		rows              int
+		blockAfter        int
+		blockRead         bool
+		maxRows           int
+		readTimeout       time.Duration
+		expectedRows      int
+		expectedErr       bool
+		expectedTruncated string
+	}{
+		{
+			name:         "should read every row of a large result without limits",
+			rows:         100_000,
+			expectedRows: 100_000,
+		},
+		{
+			name:         "should read every row of a result of exactly the max rows",
+			rows:         1_000,
+			maxRows:      1_000,
+			expectedRows: 1_000,
+		},
+		{
+			name:              "should truncate a result with more rows than the max rows",
+			rows:              100_000,
+			maxRows:           1_000,
+			expectedRows:      1_000,
+			expectedTruncated: metrics.UsageResultsTruncatedReasonMaxRows,
+		},
+		{
+			name:              "should truncate a result if the read timeout elapses while paging",
+			rows:              100_000,
+			blockAfter:        5_000,
+			readTimeout:       10 * time.Millisecond,
+			expectedRows:      5_000,
+			expectedTruncated: metrics.UsageResultsTruncatedReasonReadTimeout,
+		},
+		{
+			name:        "should return an error if the read timeout elapses before any row is read",
+			blockRead:   true,
+			readTimeout: 10 * time.Millisecond,
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			d := newTestPagingDriver(test.rows, test.blockAfter, WithResultLimits(test.maxRows, test.readTimeout))
+			if test.blockRead {
+				// The query job never completes
+				d.readMonthlyUsageQuery = func(ctx context.Context, _ int64, _ []string) (rowIterator, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+			}
+
+			maxRowsBefore := getUsageResultsTruncatedCount(t, metrics.UsageResultsTruncatedReasonMaxRows)
+			readTimeoutBefore := getUsageResultsTruncatedCount(t, metrics.UsageResultsTruncatedReasonReadTimeout)
+
+			usage, err := d.GetMonthToMomentUsage(context.Background(), 0, nil)
+			if test.expectedErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Len(usage, test.expectedRows)
+
+			// The highest-usage accounts are kept
+			c.Contains(usage, "account_0")
+
+			maxRowsTruncated := getUsageResultsTruncatedCount(t, metrics.UsageResultsTruncatedReasonMaxRows) - maxRowsBefore
+			readTimeoutTruncated := getUsageResultsTruncatedCount(t, metrics.UsageResultsTruncatedReasonReadTimeout) - readTimeoutBefore
+			switch test.expectedTruncated {
+			case metrics.UsageResultsTruncatedReasonMaxRows:
+				c.Equal(float64(1), maxRowsTruncated)
+				c.Zero(readTimeoutTruncated)
+			case metrics.UsageResultsTruncatedReasonReadTimeout:
+				c.Zero(maxRowsTruncated)
+				c.Equal(float64(1), readTimeoutTruncated)
+			default:
+				c.Zero(maxRowsTruncated)
+				c.Zero(readTimeoutTruncated)
+			}
+		})
+	}
+}
+
+func Test_GetMonthToMomentUsage_ResultLimitsContextCanceled(t *testing.T) {
+	c := require.New(t)
+
+	d := newTestPagingDriver(100_000, 5_000, WithResultLimits(0, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// A canceled refresh is not truncated, even if rows were read
+	_, err := d.GetMonthToMomentUsage(ctx, 0, nil)
+	c.Error(err)
+}
+
+// getUsageResultsTruncatedCount returns the number of usage query results truncated for the reason.
+func getUsageResultsTruncatedCount(t *testing.T, reason string) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_usage_results_truncated_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "reason" && label.GetValue() == reason {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
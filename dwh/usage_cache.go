@@ -0,0 +1,115 @@
+package dwh
+
+import (
+	"context"
+	"crypto/sha256"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// usageCacheBypassKey is the context key marking a GetMonthToMomentUsage call that bypasses the usage cache.
+type usageCacheBypassKey struct{}
+
+// ContextWithUsageCacheBypass returns a context for which GetMonthToMomentUsage always queries BigQuery,
+// even if a fresh result is cached, and caches the new result.
+//
+// Used to force a refresh when stale usage is not acceptable (e.g. on startup or SIGHUP).
+func ContextWithUsageCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usageCacheBypassKey{}, true)
+}
+
+// isUsageCacheBypassed returns true if the context was returned by ContextWithUsageCacheBypass.
+func isUsageCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(usageCacheBypassKey{}).(bool)
+	return bypass
+}
+
+// WithUsageCacheTTL caches GetMonthToMomentUsage results for up to ttl, so repeated rate limit
+// refreshes do not each run a full BigQuery scan. Defaults to 0 (disabled).
+//
+// Results are cached per relay threshold, account filter and UTC hour, so a new hour
+// (and with it a new day or month) is always queried, regardless of the TTL.
+func WithUsageCacheTTL(ttl time.Duration) DriverOption {
+	return func(d *Driver) {
+		if ttl <= 0 {
+			d.usageCache = nil
+			return
+		}
+		d.usageCache = &usageCache{
+			ttl:     ttl,
+			now:     time.Now,
+			entries: make(map[usageCacheKey]usageCacheEntry),
+		}
+	}
+}
+
+// usageCache is an in-process cache of monthly usage query results.
+type usageCache struct {
+	ttl time.Duration
+
+	// now returns the current time; overridden in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[usageCacheKey]usageCacheEntry
+}
+
+// usageCacheKey identifies the monthly usage query a result was returned for.
+type usageCacheKey struct {
+	minRelayThreshold int64
+	// hour is the UTC hour the result was queried in, as a Unix timestamp
+	hour int64
+	// accountIDsHash is the SHA-256 of the sorted account IDs filter; zero if not filtering
+	accountIDsHash [sha256.Size]byte
+	filterAccounts bool
+}
+
+// usageCacheEntry is a cached monthly usage query result.
+type usageCacheEntry struct {
+	usage     map[string]AccountUsage
+	fetchedAt time.Time
+}
+
+// newKey returns the cache key of a monthly usage query run now.
+func (c *usageCache) newKey(minRelayThreshold int64, accountIDs []string) usageCacheKey {
+	key := usageCacheKey{
+		minRelayThreshold: minRelayThreshold,
+		hour:              c.now().UTC().Truncate(time.Hour).Unix(),
+		filterAccounts:    accountIDs != nil,
+	}
+	if key.filterAccounts {
+		sorted := slices.Clone(accountIDs)
+		slices.Sort(sorted)
+		key.accountIDsHash = sha256.Sum256([]byte(strings.Join(sorted, "\x00")))
+	}
+	return key
+}
+
+// get returns a copy of the cached result for the key, if it was fetched less than the TTL ago.
+func (c *usageCache) get(key usageCacheKey) (map[string]AccountUsage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.fetchedAt) >= c.ttl {
+		return nil, false
+	}
+	return maps.Clone(entry.usage), true
+}
+
+// set caches a copy of the result for the key, and evicts expired entries.
+func (c *usageCache) set(key usageCacheKey, usage map[string]AccountUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for existingKey, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.entries, existingKey)
+		}
+	}
+	c.entries[key] = usageCacheEntry{usage: maps.Clone(usage), fetchedAt: now}
+}
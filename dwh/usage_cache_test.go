@@ -0,0 +1,194 @@
+package dwh
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUsageQueryRunner counts monthly usage queries, returning the account IDs as usage.
+type fakeUsageQueryRunner struct {
+	queries int
+	err     error
+}
+
+func (f *fakeUsageQueryRunner) run(_ context.Context, minRelayThreshold int64, accountIDs []string) (map[string]AccountUsage, error) {
+	f.queries++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	usage := map[string]AccountUsage{"account_all": {SuccessfulRelays: minRelayThreshold}}
+	if accountIDs != nil {
+		usage = make(map[string]AccountUsage, len(accountIDs))
+		for _, accountID := range accountIDs {
+			usage[accountID] = AccountUsage{SuccessfulRelays: minRelayThreshold}
+		}
+	}
+	return usage, nil
+}
+
+// newTestCachingDriver returns a Driver with the usage cache enabled, reading the time from now.
+func newTestCachingDriver(runner *fakeUsageQueryRunner, ttl time.Duration, now *time.Time) *Driver {
+	d := &Driver{runMonthlyUsageQuery: runner.run}
+	WithUsageCacheTTL(ttl)(d)
+	if d.usageCache != nil {
+		d.usageCache.now = func() time.Time { return *now }
+	}
+	return d
+}
+
+func Test_GetMonthToMomentUsage_Cache(t *testing.T) {
+	start := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		ttl             time.Duration
+		secondCallAfter time.Duration
+		secondThreshold int64
+		secondAccounts  []string
+		secondCtx       func(context.Context) context.Context
+		expectedQueries int
+	}{
+		{
+			name:            "should query on every call if the cache is disabled",
+			secondCallAfter: time.Minute,
+			expectedQueries: 2,
+		},
+		{
+			name:            "should return the cached result within the TTL",
+			ttl:             15 * time.Minute,
+			secondCallAfter: 14 * time.Minute,
+			expectedQueries: 1,
+		},
+		{
+			name:            "should query again once the TTL expires",
+			ttl:             15 * time.Minute,
+			secondCallAfter: 15 * time.Minute,
+			expectedQueries: 2,
+		},
+		{
+			name:            "should query again in a new hour, even within the TTL",
+			ttl:             2 * time.Hour,
+			secondCallAfter: time.Hour,
+			expectedQueries: 2,
+		},
+		{
+			name:            "should query again for a different relay threshold",
+			ttl:             15 * time.Minute,
+			secondCallAfter: time.Minute,
+			secondThreshold: 2_000_000,
+			expectedQueries: 2,
+		},
+		{
+			name:            "should query again for an account filter",
+			ttl:             15 * time.Minute,
+			secondCallAfter: time.Minute,
+			secondAccounts:  []string{"account_1"},
+			expectedQueries: 2,
+		},
+		{
+			name:            "should query again if the cache is bypassed",
+			ttl:             15 * time.Minute,
+			secondCallAfter: time.Minute,
+			secondCtx:       ContextWithUsageCacheBypass,
+			expectedQueries: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			now := start
+			runner := &fakeUsageQueryRunner{}
+			d := newTestCachingDriver(runner, test.ttl, &now)
+
+			firstUsage, err := d.GetMonthToMomentUsage(context.Background(), 1_000_000, nil)
+			c.NoError(err)
+
+			now = now.Add(test.secondCallAfter)
+			secondThreshold := int64(1_000_000)
+			if test.secondThreshold != 0 {
+				secondThreshold = test.secondThreshold
+			}
+			ctx := context.Background()
+			if test.secondCtx != nil {
+				ctx = test.secondCtx(ctx)
+			}
+			secondUsage, err := d.GetMonthToMomentUsage(ctx, secondThreshold, test.secondAccounts)
+			c.NoError(err)
+
+			c.Equal(test.expectedQueries, runner.queries)
+			if test.expectedQueries == 1 {
+				c.Equal(firstUsage, secondUsage)
+			}
+		})
+	}
+}
+
+func Test_GetMonthToMomentUsage_CacheAccountFilter(t *testing.T) {
+	c := require.New(t)
+
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	runner := &fakeUsageQueryRunner{}
+	d := newTestCachingDriver(runner, 15*time.Minute, &now)
+
+	usage, err := d.GetMonthToMomentUsage(context.Background(), 1_000_000, []string{"account_2", "account_1"})
+	c.NoError(err)
+	c.Len(usage, 2)
+
+	// The same account IDs in a different order hit the cache
+	usage, err = d.GetMonthToMomentUsage(context.Background(), 1_000_000, []string{"account_1", "account_2"})
+	c.NoError(err)
+	c.Len(usage, 2)
+	c.Equal(1, runner.queries)
+
+	// A different account filter misses the cache
+	usage, err = d.GetMonthToMomentUsage(context.Background(), 1_000_000, []string{"account_1"})
+	c.NoError(err)
+	c.Equal(map[string]AccountUsage{"account_1": {SuccessfulRelays: 1_000_000}}, usage)
+	c.Equal(2, runner.queries)
+}
+
+func Test_GetMonthToMomentUsage_CacheErrorsAndCopies(t *testing.T) {
+	c := require.New(t)
+
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	runner := &fakeUsageQueryRunner{err: errors.New("bigquery unavailable")}
+	d := newTestCachingDriver(runner, 15*time.Minute, &now)
+
+	// Errors are not cached
+	_, err := d.GetMonthToMomentUsage(context.Background(), 1_000_000, nil)
+	c.Error(err)
+	runner.err = nil
+	usage, err := d.GetMonthToMomentUsage(context.Background(), 1_000_000, nil)
+	c.NoError(err)
+	c.Equal(2, runner.queries)
+
+	// Modifying a returned result does not modify the cached result
+	delete(usage, "account_all")
+	usage, err = d.GetMonthToMomentUsage(context.Background(), 1_000_000, nil)
+	c.NoError(err)
+	c.Contains(usage, "account_all")
+	c.Equal(2, runner.queries)
+}
+
+func Test_usageCache_set_EvictsExpiredEntries(t *testing.T) {
+	c := require.New(t)
+
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	d := newTestCachingDriver(&fakeUsageQueryRunner{}, 15*time.Minute, &now)
+
+	for i := range 3 {
+		now = now.Add(time.Hour)
+		_, err := d.GetMonthToMomentUsage(context.Background(), int64(i), nil)
+		c.NoError(err)
+	}
+
+	// Only the latest hour's entry is kept
+	c.Len(d.usageCache.entries, 1)
+}
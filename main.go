@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
 	"github.com/pokt-network/poktroll/pkg/polylog"
@@ -11,8 +14,9 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/buildwithgrove/path-external-auth-server/admin"
 	"github.com/buildwithgrove/path-external-auth-server/auth"
-	"github.com/buildwithgrove/path-external-auth-server/dwh"
+	"github.com/buildwithgrove/path-external-auth-server/directory"
 	"github.com/buildwithgrove/path-external-auth-server/metrics"
 	"github.com/buildwithgrove/path-external-auth-server/postgres/grove"
 	"github.com/buildwithgrove/path-external-auth-server/ratelimit"
@@ -35,58 +39,197 @@ func main() {
 	logger.Info().Str("logger_level", env.loggerLevel).
 		Msg("🫛 Starting PEAS (Path External Auth Server) ...")
 
-	// Create context for graceful shutdown
-	ctx := context.Background()
+	// Set the auth request duration histogram buckets, before any request is recorded
+	if env.authRequestDurationBuckets != nil {
+		if err := metrics.SetAuthRequestDurationBuckets(env.authRequestDurationBuckets); err != nil {
+			panic(err)
+		}
+		logger.Info().Str("buckets", fmt.Sprint(env.authRequestDurationBuckets)).Msg("📊 Using custom auth request duration buckets")
+	}
 
-	// Create a new postgres data source
-	postgresDataSource, err := grove.NewGrovePostgresDriver(
-		logger, env.postgresConnectionString,
-	)
-	if err != nil {
-		panic(fmt.Sprintf("failed to connect to postgres: %v", err))
+	// Create the root context, canceled on SIGINT or SIGTERM to gracefully shut down
+	// the auth server and stop the background goroutines of the stores.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Create a new portal app data source: a directory of portal app files if configured, otherwise postgres
+	var dataSource store.DataSource
+	var directoryDataSource *directory.DirectoryDriver
+	var postgresDataSource *grove.GrovePostgresDriver
+	if env.portalAppsDirectory != "" {
+		directoryDataSource, err = directory.NewDirectoryDriver(
+			logger, env.portalAppsDirectory,
+			directory.WithWatchInterval(env.portalAppsDirectoryWatchInterval),
+			directory.WithFieldNames(env.portalAppsDirectoryFieldNames),
+		)
+		if err != nil {
+			panic(fmt.Sprintf("failed to open portal apps directory: %v", err))
+		}
+		dataSource = directoryDataSource
+		metrics.SetDataSourceType(metrics.DataSourceTypeDirectory)
+		logger.Info().Str("data_source_type", metrics.DataSourceTypeDirectory).Str("dir", env.portalAppsDirectory).
+			Msg("📂 Successfully opened portal apps directory as a data source")
+	} else {
+		// Wait for postgres to become reachable, if a startup dependency wait timeout is set
+		err = waitForDependency(ctx, logger, "postgres", env.startupDependencyWaitTimeout, env.startupDependencyWaitInterval,
+			func(ctx context.Context) error {
+				var err error
+				postgresDataSource, err = grove.NewGrovePostgresDriver(
+					ctx, logger, env.postgresConnectionString,
+					grove.WithPortalAppsView(env.postgresPortalAppsView),
+					grove.WithStreamingLoad(env.postgresStreamPortalApps),
+					grove.WithDuplicatePortalAppIDResolution(env.postgresDuplicatePortalAppIDResolution),
+					grove.WithExcludeEmptySecretKeys(env.postgresExcludeEmptySecretKeys),
+					grove.WithAuthQueryTimeout(env.postgresAuthQueryTimeout),
+					grove.WithLazyAuth(env.portalAppStoreLazyAuthEnabled),
+				)
+				return err
+			},
+		)
+		if err != nil {
+			panic(fmt.Sprintf("failed to connect to postgres: %v", err))
+		}
+		dataSource = postgresDataSource
+		metrics.SetDataSourceType(metrics.DataSourceTypePostgres)
+		logger.Info().Str("data_source_type", metrics.DataSourceTypePostgres).Msg("🐘 Successfully connected to postgres as a data source")
 	}
-	defer postgresDataSource.Close()
-	logger.Info().Msg("🐘 Successfully connected to postgres as a data source")
+	defer dataSource.Close()
 
-	// Create a new data warehouse driver
-	dataWarehouseDriver, err := dwh.NewDriver(context.Background(), env.gcpProjectID)
+	// Create a new data warehouse driver: ClickHouse if configured, otherwise BigQuery
+	dataWarehouseDriver, err := newDataWarehouseDriver(ctx, env)
 	if err != nil {
 		panic(err)
 	}
 	defer dataWarehouseDriver.Close()
-	logger.Info().Msg("💽 Successfully connected to data warehouse as a data source")
+
+	// Wait for the data warehouse to become reachable, if a startup dependency wait timeout is set
+	if env.startupDependencyWaitTimeout > 0 {
+		err := waitForDependency(ctx, logger, env.dwhType, env.startupDependencyWaitTimeout, env.startupDependencyWaitInterval,
+			dataWarehouseDriver.Ping,
+		)
+		if err != nil {
+			panic(fmt.Sprintf("failed to reach data warehouse: %v", err))
+		}
+	}
+	logger.Info().Str("dwh_type", env.dwhType).Msg("💽 Successfully connected to data warehouse as a data source")
 
 	// Create a new portal app store
 	portalAppStore, err := store.NewPortalAppStore(
+		ctx,
 		logger,
-		postgresDataSource,
+		dataSource,
 		env.portalAppStoreRefreshInterval,
+		store.WithExcludeMissingAccountID(env.portalAppStoreExcludeMissingAccountID),
+		store.WithAccountPlanResolution(env.portalAppStoreAccountPlanResolution),
+		store.WithMaxPortalApps(env.portalAppStoreMaxPortalApps),
+		store.WithRejectEmptyRefresh(env.portalAppStoreRejectEmptyRefresh),
+		store.WithAPIKeyIndex(env.apiKeyLookupEnabled),
+		store.WithLazyAuth(env.portalAppStoreLazyAuthEnabled),
 	)
 	if err != nil {
 		panic(err)
 	}
 	logger.Info().Msg("✅ Successfully initialized portal app store")
 
+	// Refresh the portal app store immediately when the portal apps directory changes
+	if directoryDataSource != nil {
+		directoryDataSource.SetChangeHandler(func() {
+			if err := portalAppStore.Refresh(); err != nil {
+				logger.Error().Err(err).Msg("Failed to refresh portal app store after portal apps directory change")
+			}
+		})
+	}
+
 	// Create a new rate limit store
+	rateLimitStoreOpts := []ratelimit.RateLimitStoreOption{
+		ratelimit.WithThresholds(env.rateLimitThresholds),
+		ratelimit.WithFailedRelayWeights(env.rateLimitFailedRelayWeights),
+		ratelimit.WithRateLimitableAccountFilter(env.rateLimitFilterRateLimitableAccounts),
+		ratelimit.WithAllAccountUsageMetrics(env.rateLimitRecordAllAccountUsage),
+		ratelimit.WithStrictUnknownPlans(env.rateLimitStrictUnknownPlans),
+		ratelimit.WithEnforcementRollout(env.rateLimitEnforcementRolloutStart, env.rateLimitEnforcementRolloutWindow),
+		ratelimit.WithWarmup(env.rateLimitStoreWarmupTimeout, env.rateLimitStoreWarmupRetryInterval),
+		ratelimit.WithDataWarehouseErrorType(dataWarehouseErrorType(env.dwhType)),
+		ratelimit.WithInitialLoadRetry(
+			env.rateLimitStoreInitialLoadMaxAttempts,
+			env.rateLimitStoreInitialLoadBackoff,
+			env.rateLimitStoreInitialLoadMaxBackoff,
+		),
+		ratelimit.WithDecisionCacheTTL(env.rateLimitStoreDecisionCacheTTL),
+	}
+	// Load plan limits from postgres, if enabled
+	if env.postgresPlanLimitsEnabled {
+		rateLimitStoreOpts = append(rateLimitStoreOpts, ratelimit.WithPlanLimits(postgresDataSource))
+		logger.Info().Msg("📋 Loading plan limits from postgres")
+	}
 	rateLimitStore, err := ratelimit.NewRateLimitStore(
+		ctx,
 		logger,
 		dataWarehouseDriver,
 		portalAppStore,
 		env.rateLimitStoreRefreshInterval,
+		rateLimitStoreOpts...,
 	)
 	if err != nil {
 		panic(err)
 	}
 	logger.Info().Msg("✅ Successfully initialized rate limit store")
 
-	// Setup and start observability servers
-	// TODO_MONITORING: Consider adding graceful shutdown for metrics and pprof servers
-	if err := metrics.ServeMetrics(logger, fmt.Sprintf(":%d", env.metricsPort), env.imageTag); err != nil {
-		panic(fmt.Sprintf("failed to start metrics server: %v", err))
+	// Re-evaluate rate limits immediately when an account's plan or limit changes
+	portalAppStore.SetAccountPlanChangeHandler(rateLimitStore.ReevaluateAccounts)
+
+	// Refresh the portal app and rate limit stores immediately on SIGHUP, if enabled
+	if env.reloadOnSIGHUP {
+		reloadSignals := make(chan os.Signal, 1)
+		signal.Notify(reloadSignals, syscall.SIGHUP)
+		go handleReloadSignals(logger, reloadSignals, debounceReload(func() error {
+			return reloadStores(portalAppStore, rateLimitStore)
+		}, env.reloadMinInterval))
+		logger.Info().Msg("🔁 Reloading stores on SIGHUP")
+	}
+
+	// Emit counters to statsd in addition to Prometheus, if an address is set
+	if env.statsdAddress != "" {
+		closeStatsd, err := metrics.EnableStatsd(env.statsdAddress, env.statsdPrefix)
+		if err != nil {
+			panic(err)
+		}
+		defer closeStatsd()
+		logger.Info().Str("address", env.statsdAddress).Str("prefix", env.statsdPrefix).Msg("📡 Emitting metrics to statsd")
+	}
+
+	// Setup and start observability servers, shut down gracefully with the auth server
+	httpServerOpts := []metrics.ServerOption{
+		metrics.WithGzipCompression(env.httpGzipCompressionEnabled),
+		metrics.WithMetricsFormat(env.metricsFormat),
+		metrics.WithShutdownTimeout(env.shutdownTimeout),
+	}
+	metricsServerOpts := httpServerOpts
+	// Serve the store dump admin endpoint on the metrics server, if a token is set
+	if env.adminStoreDumpToken != "" {
+		metricsServerOpts = append(metricsServerOpts, metrics.WithHandler(
+			admin.EndpointStoreDump,
+			admin.NewStoreDumpHandler(logger, portalAppStore, env.adminStoreDumpToken),
+		))
+		logger.Info().Str("path", admin.EndpointStoreDump).Msg("🗃️ Serving store dump admin endpoint")
+	}
+	// Serve the account rate limit admin endpoint on the metrics server, if a token is set
+	if env.adminAccountRateLimitToken != "" {
+		metricsServerOpts = append(metricsServerOpts, metrics.WithHandler(
+			admin.EndpointAccountRateLimit,
+			admin.NewAccountRateLimitHandler(logger, rateLimitStore, env.adminAccountRateLimitToken),
+		))
+		logger.Info().Str("path", admin.EndpointAccountRateLimit).Msg("🔎 Serving account rate limit admin endpoint")
+	}
+	if err := metrics.ServeMetrics(ctx, logger, fmt.Sprintf(":%d", env.metricsPort), env.imageTag, metricsServerOpts...); err != nil {
+		if env.metricsBindFailureMode == metrics.BindFailureModeFatal {
+			panic(fmt.Sprintf("failed to start metrics server: %v", err))
+		}
+		logger.Error().Err(err).Msg("⚠️ Failed to start metrics server: continuing without metrics")
 	}
 
 	// Setup the pprof server
-	metrics.ServePprof(ctx, logger, fmt.Sprintf(":%d", env.pprofPort))
+	metrics.ServePprof(ctx, logger, fmt.Sprintf(":%d", env.pprofPort), httpServerOpts...)
 
 	// Create a new listener to listen for requests from GUARD
 	listen, err := net.Listen("tcp", fmt.Sprintf(":%d", env.port))
@@ -99,7 +242,42 @@ func main() {
 		logger,
 		portalAppStore,
 		rateLimitStore,
-		&auth.AuthorizerAPIKey{},
+		[]auth.Authorizer{&auth.AuthorizerAPIKey{}, &auth.AuthorizerHMAC{}},
+		auth.WithLocalizedDenialMessages(env.denialMessages),
+		auth.WithDenialRequestID(env.denialRequestIDEnabled),
+		auth.WithDenyMisconfiguredPortalApps(env.denyMisconfiguredPortalApps),
+		auth.WithAuthRequiredPlans(env.authRequiredPlans),
+		auth.WithHeaderAppendAction(env.headerAppendAction),
+		auth.WithRateLimitFailureMode(env.rateLimitFailureMode),
+		auth.WithRateLimitColdStartDeny(env.rateLimitColdStartDeny),
+		auth.WithRelayCosts(env.relayCosts),
+		auth.WithPlanHeaders(env.planHeaders),
+		auth.WithRateLimitTierHeader(env.rateLimitTierHeaderEnabled),
+		auth.WithRateLimitResetHeader(env.rateLimitResetHeaderEnabled),
+		auth.WithPlanNameHeader(env.planNameHeaderEnabled),
+		auth.WithBurstAllowanceHeader(env.burstAllowanceHeaderEnabled),
+		auth.WithAccountIDHeaderMode(env.accountIDHeaderMode, env.accountIDHashSalt),
+		auth.WithAccountIDMismatchPolicy(env.accountIDMismatchPolicy),
+		auth.WithRateLimitDecisionHeader(env.rateLimitDecisionHeaderTTL),
+		auth.WithRateLimitDecisionHeaderTTLOverrides(env.rateLimitDecisionHeaderTTLOverrides),
+		auth.WithAuthCacheTTL(env.authCacheTTLPublic, env.authCacheTTLAPIKey),
+		auth.WithMissingPortalAppIDResponse(env.missingPortalAppIDStatusCode, env.missingPortalAppIDMessage),
+		auth.WithBillingDelinquentMessage(env.billingDelinquentMessage),
+		auth.WithPortalAppIDFormat(env.portalAppIDFormat),
+		auth.WithPathPrefixes(env.pathPrefixes),
+		auth.WithPortalAppIDHeader(env.portalAppIDHeader),
+		auth.WithAccountIDHeader(env.portalAccountIDHeader),
+		auth.WithRequireAuthority(env.requireAuthority),
+		auth.WithDenyPathTraversal(env.denyPathTraversal),
+		auth.WithMaxConcurrentChecksPerAccount(env.maxConcurrentChecksPerAccount),
+		auth.WithAccountRequestCeiling(env.accountRequestCeilingPerSecond),
+		auth.WithSlowCheckLogging(env.slowCheckLogThreshold),
+		auth.WithClientIPResolver(env.clientIPResolver),
+		auth.WithHTTPSRequirement(env.httpsRequirement),
+		auth.WithHealthCheckBypass(env.healthCheckBypass),
+		auth.WithProbePaths(env.probePaths),
+		auth.WithQueryParamStrictMode(env.queryParamStrictMode, env.queryParamAllowlist),
+		auth.WithAPIKeyLookup(env.apiKeyLookupEnabled),
 	)
 
 	// Create a new gRPC server for handling auth requests from GUARD
@@ -108,11 +286,22 @@ func main() {
 	// See:
 	//    - https://gateway.envoyproxy.io/docs/tasks/security/ext-auth/
 	//    - https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/ext_authz_filter
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authHandler.AccountConcurrencyInterceptor()),
+	)
 
 	// Register proto server
 	envoy_auth.RegisterAuthorizationServer(grpcServer, authHandler)
 
+	// Register the SelfTest RPC for smoke testing deployments (if a test portal app is configured).
+	// It shares the public ext_authz port, so callers must provide SELF_TEST_TOKEN.
+	if env.selfTestPortalAppID != "" {
+		admin.RegisterSelfTestServer(grpcServer, admin.NewSelfTestServer(
+			logger, authHandler, env.selfTestPortalAppID, env.selfTestAPIKey, env.pathPrefixes[0], env.selfTestToken,
+		))
+		logger.Info().Str("portal_app_id", env.selfTestPortalAppID).Msg("🩺 Registered SelfTest RPC")
+	}
+
 	// Enable gRPC reflection for easy lookup of Portal Application/Account auth status.
 	//
 	// See README.md section: "Getting Portal App Auth & Rate Limit Status" for more details.
@@ -122,8 +311,10 @@ func main() {
 	//    https://github.com/envoyproxy/envoy/blob/main/api/envoy/service/auth/v3/external_auth.proto
 	// 2. The API specification is fully documented in Envoy's official documentation:
 	//    https://www.envoyproxy.io/docs/envoy/latest/configuration/http/http_filters/ext_authz_filter
-	// 3. Reflection only exposes the standard CheckRequest/CheckResponse schema, not our
-	//    business logic, database structure, or implementation details.
+	// 3. Reflection only exposes the standard CheckRequest/CheckResponse schema and, if
+	//    registered, the peas.admin.v1.Admin/SelfTest schema (Empty in, Struct out), not our
+	//    business logic, database structure, or implementation details. The SelfTest RPC
+	//    itself rejects callers without SELF_TEST_TOKEN.
 	// 4. This enables easy testing of auth/rate limit status for Portal accounts using
 	//    grpcurl without requiring proto files or protosets.
 	reflection.Register(grpcServer)
@@ -131,7 +322,8 @@ func main() {
 	logger.Info().Int("port", env.port).
 		Msg("✅ PEAS started successfully!")
 
-	if err = grpcServer.Serve(listen); err != nil {
+	// Serve until SIGINT or SIGTERM, then stop gracefully; the deferred calls close the data source and data warehouse drivers
+	if err = serveUntilShutdown(ctx, logger, grpcServer, listen, env.shutdownTimeout); err != nil {
 		panic(err)
 	}
 }
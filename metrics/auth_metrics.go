@@ -1,10 +1,16 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TODO_TESTING: Add comprehensive unit tests for metrics recording functions
@@ -19,9 +25,14 @@ const (
 	// Authorization request metrics
 	authRequestsTotalMetricName          = "auth_requests_total"
 	authRequestDurationSecondsMetricName = "auth_request_duration_seconds"
+	authHTTPResponsesTotalMetricName     = "auth_http_responses_total"
 
 	// Rate limiting metrics
-	rateLimitChecksTotalMetricName = "rate_limit_checks_total"
+	rateLimitChecksTotalMetricName          = "rate_limit_checks_total"
+	rateLimitCheckDurationSecondsMetricName = "rate_limit_check_duration_seconds"
+
+	// Unknown plan type tracking
+	unknownPlanRateLimitedAccountsMetricName = "unknown_plan_rate_limited_accounts"
 
 	// Store size metrics
 	storeSizeTotalMetricName = "store_size_total"
@@ -29,45 +40,103 @@ const (
 	// Account usage tracking
 	accountUsageTotalMetricName = "account_usage_total"
 
+	// Portal app misconfiguration tracking
+	portalAppMisconfiguredTotalMetricName = "portal_app_misconfigured_total"
+
+	// Unexpected query parameter tracking
+	unexpectedQueryParamsTotalMetricName = "unexpected_query_params_total"
+
+	// Reason constants for portal app misconfigurations
+	PortalAppMisconfiguredReasonEmptyAPIKey      = "empty_api_key"
+	PortalAppMisconfiguredReasonPlanRequiresAuth = "plan_requires_auth"
+	PortalAppMisconfiguredReasonNoAuthorizer     = "no_authorizer"
+
 	// Data source refresh error tracking
 	dataSourceRefreshErrorsTotalMetricName = "data_source_refresh_errors_total"
 
+	// Duplicate portal app ID tracking
+	duplicatePortalAppIDTotalMetricName = "duplicate_portal_app_id_total"
+
+	// Truncated usage query result tracking
+	usageResultsTruncatedTotalMetricName = "usage_results_truncated_total"
+
+	// Reason constants for truncated usage query results
+	UsageResultsTruncatedReasonMaxRows     = "max_rows"
+	UsageResultsTruncatedReasonReadTimeout = "read_timeout"
+
 	// Source type constants for data source refresh errors
 	PortalAppStoreSourceType = "portal_app_store"
 	RateLimitStoreSourceType = "rate_limit_store"
 
 	// Error type constants for data source refresh errors
-	PostgresErrorType = "postgres_error"
-	BigqueryErrorType = "bigquery_error"
+	PostgresErrorType   = "postgres_error"
+	BigqueryErrorType   = "bigquery_error"
+	ClickhouseErrorType = "clickhouse_error"
+
+	MaxPortalAppsExceededErrorType = "max_portal_apps_exceeded"
+	EmptyRefreshErrorType          = "empty_refresh"
 
 	// Store type constants for store size metrics
 	PortalAppsStoreType               = "portal_apps"
 	AccountsStoreType                 = "accounts"
 	RateLimitedAccountsStoreType      = "rate_limited_accounts"
+	ThrottledAccountsStoreType        = "throttled_accounts"
+	WarnedAccountsStoreType           = "warned_accounts"
 	AccountsOverMonthlyLimitStoreType = "accounts_over_monthly_limit"
+	RateLimitedPortalAppsStoreType    = "rate_limited_portal_apps"
+
+	PortalAppsMissingAccountIDStoreType = "portal_apps_missing_account_id"
+	PortalAppsEmptySecretKeyStoreType   = "portal_apps_empty_secret_key"
+	AccountsConflictingPlansStoreType   = "accounts_conflicting_plans"
+
+	// Plan type constants for plan_type labels
+	//   - Any other plan type is recorded as PlanTypeOther, so a typo'd or renamed plan does not create new series
+	PlanTypeFree      = "PLAN_FREE"
+	PlanTypeUnlimited = "PLAN_UNLIMITED"
+	PlanTypeOther     = "other"
 
 	// Auth Decision type constants
 	AuthDecisionAuthorized = "authorized"
 	AuthDecisionDenied     = "denied"
+	AuthDecisionError      = "error"
 
 	// Error type constants for auth requests
-	AuthRequestErrorTypePortalAppNotFound                 = "portal_app_not_found"
-	AuthRequestErrorTypeUnauthorized                      = "unauthorized"
-	AuthRequestErrorTypeRateLimited                       = "rate_limited"
-	AuthRequestErrorTypeInvalidRequestHTTPRequestNotFound = "invalid_request_http_request_not_found"
-	AuthRequestErrorTypeInvalidRequestPathNotProvided     = "invalid_request_path_not_provided"
-	AuthRequestErrorTypeInvalidRequestNoPortalAppID       = "invalid_request_no_portal_app_id"
-	AuthRequestErrorTypeInternalError                     = "internal_error"
+	AuthRequestErrorTypePortalAppNotFound                  = "portal_app_not_found"
+	AuthRequestErrorTypeUnauthorized                       = "unauthorized"
+	AuthRequestErrorTypeRateLimited                        = "rate_limited"
+	AuthRequestErrorTypeInvalidRequestHTTPRequestNotFound  = "invalid_request_http_request_not_found"
+	AuthRequestErrorTypeInvalidRequestPathNotProvided      = "invalid_request_path_not_provided"
+	AuthRequestErrorTypeInvalidRequestNoPortalAppID        = "invalid_request_no_portal_app_id"
+	AuthRequestErrorTypeInvalidRequestMalformedPortalAppID = "invalid_request_malformed_portal_app_id"
+	AuthRequestErrorTypeInvalidRequestNoAuthority          = "invalid_request_no_authority"
+	AuthRequestErrorTypeInvalidRequestPathTraversal        = "invalid_request_path_traversal"
+	AuthRequestErrorTypeInternalError                      = "internal_error"
+	AuthRequestErrorTypeRateLimitStoreUnavailable          = "rate_limit_store_unavailable"
+	AuthRequestErrorTypeRateLimitStoreColdStart            = "rate_limit_store_cold_start"
+	AuthRequestErrorTypeHTTPSRequired                      = "https_required"
+	AuthRequestErrorTypeAccountConcurrencyExceeded         = "account_concurrency_exceeded"
+	AuthRequestErrorTypeAccountRequestCeilingExceeded      = "account_request_ceiling_exceeded"
+	AuthRequestErrorTypeBillingDelinquent                  = "billing_delinquent"
+	AuthRequestErrorTypeClientIPNotAllowed                 = "client_ip_not_allowed"
+	AuthRequestErrorTypeAccountIDMismatch                  = "account_id_mismatch"
 )
 
 func init() {
 	prometheus.MustRegister(authRequestsTotal)
-	prometheus.MustRegister(authRequestDurationSeconds)
+	authRequestDurationSeconds.Store(newAuthRequestDurationSeconds(DefaultAuthRequestDurationBuckets))
+	prometheus.MustRegister(authRequestDurationSeconds.Load())
+	prometheus.MustRegister(authHTTPResponsesTotal)
 	prometheus.MustRegister(rateLimitChecksTotal)
+	prometheus.MustRegister(rateLimitCheckDurationSeconds)
 	prometheus.MustRegister(storeSizeTotal)
 	prometheus.MustRegister(accountUsageTotal)
 	prometheus.MustRegister(rateLimitedAccountsTotal)
+	prometheus.MustRegister(unknownPlanRateLimitedAccounts)
 	prometheus.MustRegister(dataSourceRefreshErrorsTotal)
+	prometheus.MustRegister(portalAppMisconfiguredTotal)
+	prometheus.MustRegister(unexpectedQueryParamsTotal)
+	prometheus.MustRegister(duplicatePortalAppIDTotal)
+	prometheus.MustRegister(usageResultsTruncatedTotal)
 }
 
 var (
@@ -76,7 +145,7 @@ var (
 	//   - portal_app_id: Portal app making the request
 	//   - account_id: Account associated with the portal app
 	//   - status: "authorized", "denied", "error"
-	//   - error_type: "portal_app_not_found", "unauthorized", "rate_limited", "invalid_request", "internal_error", or empty for success
+	//   - error_type: "portal_app_not_found", "unauthorized", "rate_limited", "rate_limit_store_unavailable", "https_required", "account_concurrency_exceeded", "billing_delinquent", "invalid_request", "internal_error", or empty for success
 	//
 	// Usage:
 	// - Monitor total authorization load per portal app and account
@@ -91,32 +160,27 @@ var (
 		[]string{"portal_app_id", "account_id", "status", "error_type"},
 	)
 
-	// authRequestDurationSeconds measures authorization request processing duration.
-	// Histogram buckets from 100ns to 10ms capture performance from fast in-memory lookups to slower operations.
+	// authHTTPResponsesTotal tracks the HTTP status code returned to the client for each authorization request.
+	// Increment on each Check request with labels:
+	//   - code: HTTP status code returned to the client, e.g. "200", "401", "404", "429", "500"
 	//
 	// Usage:
-	// - Monitor authorization latency SLAs
-	// - Identify performance issues per portal app
-	// - Track impact of rate limiting checks on response time
-	authRequestDurationSeconds = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+	// - Correlate PEAS decisions with gateway-side HTTP response metrics
+	// - Track denials by status code, including configurable codes (e.g. the missing portal app ID response)
+	authHTTPResponsesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
 			Subsystem: peasProcess,
-			Name:      authRequestDurationSecondsMetricName,
-			Help:      "Histogram of authorization request processing time in seconds",
-			// Buckets optimized for very fast in-memory operations (100ns to 10ms)
-			Buckets: []float64{
-				0.0000001, 0.0000005, 0.000001, 0.000005, 0.00001,
-				0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01,
-			},
+			Name:      authHTTPResponsesTotalMetricName,
+			Help:      "Total authorization requests by the HTTP status code returned to the client.",
 		},
-		[]string{"portal_app_id", "status"},
+		[]string{"code"},
 	)
 
 	// rateLimitChecksTotal tracks rate limiting decisions made by PEAS.
 	// Increment for each rate limit check with labels:
 	//   - account_id: Account being checked
-	//   - plan_type: "PLAN_FREE", "PLAN_UNLIMITED"
-	//   - decision: "allowed", "rate_limited", "no_limit_configured"
+	//   - plan_type: "PLAN_FREE", "PLAN_UNLIMITED", "other"
+	//   - decision: "allowed", "warned", "throttled", "rate_limited", "portal_app_rate_limited", "no_limit_configured", "store_unavailable", "health_bypass"
 	//
 	// Usage:
 	// - Monitor rate limiting effectiveness by plan type
@@ -131,9 +195,31 @@ var (
 		[]string{"account_id", "plan_type", "decision"},
 	)
 
+	// rateLimitCheckDurationSeconds measures the time spent in the rate limit check of an authorization request.
+	// Isolated from authRequestDurationSeconds so slower future rate limit sources can be identified.
+	// Histogram buckets from 100ns to 10ms match the default authRequestDurationSeconds buckets.
+	//   - plan_type: "PLAN_FREE", "PLAN_UNLIMITED", "other"
+	//
+	// Usage:
+	// - Monitor the share of authorization latency spent checking rate limits
+	// - Track impact of rate limit store changes on response time
+	rateLimitCheckDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: peasProcess,
+			Name:      rateLimitCheckDurationSecondsMetricName,
+			Help:      "Histogram of rate limit check processing time in seconds, labeled by plan type.",
+			// Buckets optimized for very fast in-memory operations (100ns to 10ms)
+			Buckets: []float64{
+				0.0000001, 0.0000005, 0.000001, 0.000005, 0.00001,
+				0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01,
+			},
+		},
+		[]string{"plan_type"},
+	)
+
 	// storeSizeTotal tracks the current size of in-memory stores.
 	// Set as gauge with labels:
-	//   - store_type: "accounts", "portal_apps", "rate_limited_accounts", "accounts_over_monthly_limit"
+	//   - store_type: "accounts", "portal_apps", "rate_limited_accounts", "throttled_accounts", "warned_accounts", "accounts_over_monthly_limit", "portal_apps_missing_account_id", "rate_limited_portal_apps"
 	//
 	// Usage:
 	// - Monitor store growth over time
@@ -151,8 +237,10 @@ var (
 	// accountUsageTotal tracks monthly usage for accounts that exceed their monthly limit.
 	// Set as gauge with labels:
 	//   - account_id: Account ID that is over the monthly limit
-	//   - plan_type: "PLAN_FREE", "PLAN_UNLIMITED"
-	//   - rate_limit: monthly rate limit for the account
+	//   - plan_type: "PLAN_FREE", "PLAN_UNLIMITED", "other"
+	//   - rate_limit: monthly rate limit for the account, or "0" for accounts with no limit
+	//
+	// Accounts with no limit are only recorded if RATE_LIMIT_RECORD_ALL_ACCOUNT_USAGE is enabled.
 	//
 	// Note: Usage values only increase during the month and reset at month boundaries.
 	// Use Grafana queries with time-based filtering to show current month data only.
@@ -173,7 +261,7 @@ var (
 	// rateLimitedAccountsTotal tracks accounts that are currently rate limited.
 	// Set as gauge with labels:
 	//   - account_id: Account ID that is rate limited
-	//   - plan_type: "PLAN_FREE", "PLAN_UNLIMITED"
+	//   - plan_type: "PLAN_FREE", "PLAN_UNLIMITED", "other"
 	//   - monthly_usage: Current monthly usage
 	//   - rate_limit: monthly rate limit for the account
 	//
@@ -189,10 +277,24 @@ var (
 		[]string{"account_id", "plan_type", "monthly_usage", "rate_limit"},
 	)
 
+	// unknownPlanRateLimitedAccounts tracks accounts rate limited because their plan type is unknown.
+	// Set on each rate limit update, only if RATE_LIMIT_STRICT_UNKNOWN_PLANS is enabled; the account IDs are logged, not used as labels.
+	//
+	// Usage:
+	// - Alert on plans missing from the plan limits source, whose accounts are blocked regardless of usage
+	// - Distinguish fail-closed blocks from accounts over their monthly limit
+	unknownPlanRateLimitedAccounts = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: peasProcess,
+			Name:      unknownPlanRateLimitedAccountsMetricName,
+			Help:      "Accounts currently rate limited because their plan type is unknown.",
+		},
+	)
+
 	// dataSourceRefreshErrorsTotal tracks errors during data source refresh operations.
 	// Increment on refresh errors with labels:
 	//   - source_type: "portal_app_store", "rate_limit_store"
-	//   - error_type: "postgres_error", "bigquery_error", "connection_error", "timeout_error"
+	//   - error_type: "postgres_error", "bigquery_error", "clickhouse_error", "connection_error", "timeout_error", "max_portal_apps_exceeded"
 	//
 	// Usage:
 	// - Monitor data source health and reliability
@@ -206,10 +308,165 @@ var (
 		},
 		[]string{"source_type", "error_type"},
 	)
+
+	// portalAppMisconfiguredTotal tracks requests to misconfigured portal apps.
+	// Increment on each Check request to a misconfigured portal app with labels:
+	//   - portal_app_id: Misconfigured portal app
+	//   - reason: One of
+	//     - "empty_api_key" (API key auth is required but the API key is empty)
+	//     - "plan_requires_auth" (the plan requires auth but the portal app requires none)
+	//     - "no_authorizer" (no authorizer is configured for the portal app's auth type, so requests are always denied)
+	//
+	// Usage:
+	// - Alert on portal apps whose configuration silently changes their auth behavior
+	// - Find portal apps to fix in the data source
+	portalAppMisconfiguredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: peasProcess,
+			Name:      portalAppMisconfiguredTotalMetricName,
+			Help:      "Total authorization requests to misconfigured portal apps.",
+		},
+		[]string{"portal_app_id", "reason"},
+	)
+
+	// unexpectedQueryParamsTotal tracks requests carrying query parameters outside the expected set.
+	// Only recorded if query parameter strict mode is enabled.
+	// Increment on each such request with labels:
+	//   - portal_app_id: Portal app making the request
+	//
+	// Parameter names are not used as labels, as they are unbounded client input.
+	//
+	// Usage:
+	// - Identify clients sending unexpected query parameters
+	// - Detect client confusion or probing before deciding to deny such requests
+	unexpectedQueryParamsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: peasProcess,
+			Name:      unexpectedQueryParamsTotalMetricName,
+			Help:      "Total authorization requests carrying unexpected query parameters.",
+		},
+		[]string{"portal_app_id"},
+	)
+
+	// duplicatePortalAppIDTotal tracks rows returned by the data source with an already loaded portal app ID.
+	// Increment once per duplicate row on each portal app load; the portal app IDs are logged, not used as labels.
+	//
+	// Usage:
+	// - Alert on data issues that silently drop portal apps from the store
+	// - Verify a data fix removed the duplicate rows
+	duplicatePortalAppIDTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: peasProcess,
+			Name:      duplicatePortalAppIDTotalMetricName,
+			Help:      "Total rows returned by the data source with a duplicate portal app ID.",
+		},
+	)
+
+	// usageResultsTruncatedTotal tracks data warehouse usage query results truncated by a result limit.
+	// Increment once per truncated query with labels:
+	//   - reason: "max_rows" (the result has more rows than the max rows) or "read_timeout" (reading the result timed out)
+	//
+	// Usage:
+	// - Alert on refreshes that drop the lowest-usage accounts from rate limiting
+	// - Tune the max rows and read timeout to the size of the result
+	usageResultsTruncatedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: peasProcess,
+			Name:      usageResultsTruncatedTotalMetricName,
+			Help:      "Total data warehouse usage query results truncated by a result limit.",
+		},
+		[]string{"reason"},
+	)
+)
+
+var (
+	// authRequestDurationSeconds measures authorization request processing duration.
+	// Histogram buckets default to 100ns to 10ms, capturing performance from fast in-memory lookups to slower operations;
+	// overridden by SetAuthRequestDurationBuckets, so it is swapped atomically rather than registered with the metrics above.
+	//
+	// Usage:
+	// - Monitor authorization latency SLAs
+	// - Identify performance issues per portal app
+	// - Track impact of rate limiting checks on response time
+	authRequestDurationSeconds atomic.Pointer[prometheus.HistogramVec]
+
+	// authRequestRecorded is set by the first RecordAuthRequest, after which the buckets can no longer be set.
+	authRequestRecorded atomic.Bool
+	// authRequestDurationBucketsMu serializes SetAuthRequestDurationBuckets calls.
+	authRequestDurationBucketsMu sync.Mutex
 )
 
+// DefaultAuthRequestDurationBuckets are the auth_request_duration_seconds histogram buckets,
+// optimized for very fast in-memory operations (100ns to 10ms).
+var DefaultAuthRequestDurationBuckets = []float64{
+	0.0000001, 0.0000005, 0.000001, 0.000005, 0.00001,
+	0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01,
+}
+
+// newAuthRequestDurationSeconds returns the auth_request_duration_seconds histogram with the given buckets.
+func newAuthRequestDurationSeconds(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: peasProcess,
+			Name:      authRequestDurationSecondsMetricName,
+			Help:      "Histogram of authorization request processing time in seconds",
+			Buckets:   buckets,
+		},
+		[]string{"portal_app_id", "status"},
+	)
+}
+
+// SetAuthRequestDurationBuckets replaces the auth_request_duration_seconds histogram with one using the given buckets,
+// for deployments where slower request paths (e.g. lazy auth lookups) exceed the default 10ms upper bucket.
+//   - Buckets apply to every plan: a Prometheus histogram has a single bucket set for all of its label values,
+//     so per-plan buckets would require a separate metric per plan
+//   - Returns an error once an authorization request has been recorded, so no observations are discarded:
+//     call it on startup, before serving requests
+func SetAuthRequestDurationBuckets(buckets []float64) error {
+	authRequestDurationBucketsMu.Lock()
+	defer authRequestDurationBucketsMu.Unlock()
+
+	if authRequestRecorded.Load() {
+		return fmt.Errorf("auth request duration buckets must be set before any auth request is recorded")
+	}
+	return replaceAuthRequestDurationSeconds(buckets)
+}
+
+// replaceAuthRequestDurationSeconds registers a histogram with the given buckets in place of the current one.
+func replaceAuthRequestDurationSeconds(buckets []float64) error {
+	histogram := newAuthRequestDurationSeconds(buckets)
+
+	prometheus.Unregister(authRequestDurationSeconds.Load())
+	if err := prometheus.Register(histogram); err != nil {
+		return fmt.Errorf("failed to register auth request duration histogram: %w", err)
+	}
+	authRequestDurationSeconds.Store(histogram)
+	return nil
+}
+
+// ParseHistogramBuckets parses a comma-separated list of histogram bucket upper bounds, in seconds.
+//   - Example: "0.00001,0.0001,0.001,0.01,0.1"
+//   - Bounds must be positive and strictly increasing
+func ParseHistogramBuckets(s string) ([]float64, error) {
+	var buckets []float64
+	for _, boundStr := range strings.Split(s, ",") {
+		boundStr = strings.TrimSpace(boundStr)
+		bound, err := strconv.ParseFloat(boundStr, 64)
+		if err != nil || bound <= 0 || math.IsInf(bound, 0) {
+			return nil, fmt.Errorf("invalid histogram bucket %q: must be a positive number of seconds", boundStr)
+		}
+		if len(buckets) > 0 && bound <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("invalid histogram bucket %q: buckets must be strictly increasing", boundStr)
+		}
+		buckets = append(buckets, bound)
+	}
+	return buckets, nil
+}
+
 // RecordAuthRequest records an authorization request with all relevant labels.
+//   - If ctx carries a sampled trace, the duration observation is recorded with its trace and span IDs as an exemplar.
 func RecordAuthRequest(
+	ctx context.Context,
 	portalAppID string,
 	accountID string,
 	status string,
@@ -222,11 +479,70 @@ func RecordAuthRequest(
 		"status":        status,
 		"error_type":    errorType,
 	}).Inc()
+	if errorType == "" {
+		emitStatsdCounter("auth_requests", status)
+	} else {
+		emitStatsdCounter("auth_requests", status, errorType)
+	}
 
-	authRequestDurationSeconds.With(prometheus.Labels{
+	if !authRequestRecorded.Load() {
+		authRequestRecorded.Store(true)
+	}
+	observer := authRequestDurationSeconds.Load().With(prometheus.Labels{
 		"portal_app_id": portalAppID,
 		"status":        status,
-	}).Observe(duration)
+	})
+	observeWithTraceExemplar(ctx, observer, duration)
+}
+
+// RecordAuthHTTPResponse records the HTTP status code returned to the client for an authorization request.
+func RecordAuthHTTPResponse(code int32) {
+	authHTTPResponsesTotal.With(prometheus.Labels{
+		"code": strconv.FormatInt(int64(code), 10),
+	}).Inc()
+}
+
+// RecordPortalAppMisconfigured records a request to a misconfigured portal app.
+func RecordPortalAppMisconfigured(portalAppID string, reason string) {
+	portalAppMisconfiguredTotal.With(prometheus.Labels{
+		"portal_app_id": portalAppID,
+		"reason":        reason,
+	}).Inc()
+}
+
+// RecordUnexpectedQueryParams records a request carrying unexpected query parameters.
+func RecordUnexpectedQueryParams(portalAppID string) {
+	unexpectedQueryParamsTotal.With(prometheus.Labels{
+		"portal_app_id": portalAppID,
+	}).Inc()
+}
+
+// RecordDuplicatePortalAppID records a row returned by the data source with a duplicate portal app ID.
+func RecordDuplicatePortalAppID() {
+	duplicatePortalAppIDTotal.Inc()
+}
+
+// RecordUsageResultsTruncated records a data warehouse usage query result truncated by a result limit.
+func RecordUsageResultsTruncated(reason string) {
+	usageResultsTruncatedTotal.With(prometheus.Labels{
+		"reason": reason,
+	}).Inc()
+}
+
+// observeWithTraceExemplar observes the value, attaching the trace and span IDs of the
+// sampled trace in ctx as an exemplar so dashboards can link the observation to its trace.
+func observeWithTraceExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !ok || !spanContext.IsValid() || !spanContext.IsSampled() {
+		observer.Observe(value)
+		return
+	}
+
+	exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{
+		"trace_id": spanContext.TraceID().String(),
+		"span_id":  spanContext.SpanID().String(),
+	})
 }
 
 // RecordRateLimitCheck records a rate limit check decision.
@@ -237,9 +553,20 @@ func RecordRateLimitCheck(
 ) {
 	rateLimitChecksTotal.With(prometheus.Labels{
 		"account_id": accountID,
-		"plan_type":  planType,
+		"plan_type":  normalizePlanType(planType),
 		"decision":   decision,
 	}).Inc()
+	emitStatsdCounter("rate_limit_checks", normalizePlanType(planType), decision)
+}
+
+// RecordRateLimitCheckDuration records the time spent in a rate limit check.
+func RecordRateLimitCheckDuration(
+	planType string,
+	duration float64,
+) {
+	rateLimitCheckDurationSeconds.With(prometheus.Labels{
+		"plan_type": normalizePlanType(planType),
+	}).Observe(duration)
 }
 
 // UpdateStoreSize updates the current size of a store.
@@ -261,7 +588,7 @@ func UpdateAccountUsage(
 ) {
 	accountUsageTotal.With(prometheus.Labels{
 		"account_id": accountID,
-		"plan_type":  planType,
+		"plan_type":  normalizePlanType(planType),
 		"rate_limit": strconv.FormatInt(int64(rateLimit), 10),
 	}).Set(monthlyUsage)
 }
@@ -275,12 +602,28 @@ func UpdateRateLimitedAccounts(
 ) {
 	rateLimitedAccountsTotal.With(prometheus.Labels{
 		"account_id":    accountID,
-		"plan_type":     planType,
+		"plan_type":     normalizePlanType(planType),
 		"monthly_usage": fmt.Sprintf("%.2f", monthlyUsage),
 		"rate_limit":    strconv.FormatInt(int64(rateLimit), 10),
 	}).Set(monthlyUsage)
 }
 
+// UpdateUnknownPlanRateLimitedAccounts updates the number of accounts rate limited because their plan type is unknown.
+func UpdateUnknownPlanRateLimitedAccounts(numAccounts float64) {
+	unknownPlanRateLimitedAccounts.Set(numAccounts)
+}
+
+// normalizePlanType returns the plan_type label value for a plan type.
+//   - Plan types outside the known set are mapped to PlanTypeOther to bound label cardinality.
+func normalizePlanType(planType string) string {
+	switch planType {
+	case PlanTypeFree, PlanTypeUnlimited:
+		return planType
+	default:
+		return PlanTypeOther
+	}
+}
+
 // RecordDataSourceRefreshError records an error during data source refresh.
 func RecordDataSourceRefreshError(
 	sourceType string,
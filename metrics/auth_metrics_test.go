@@ -0,0 +1,258 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_RecordAuthRequest_Exemplar(t *testing.T) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanID := trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+
+	tests := []struct {
+		name             string
+		ctx              context.Context
+		portalAppID      string
+		expectedExemplar map[string]string
+	}{
+		{
+			name: "should attach exemplar if a sampled trace context is present",
+			ctx: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     spanID,
+				TraceFlags: trace.FlagsSampled,
+			})),
+			portalAppID: "portal_app_exemplar_sampled",
+			expectedExemplar: map[string]string{
+				"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"span_id":  "00f067aa0ba902b7",
+			},
+		},
+		{
+			name: "should not attach exemplar if the trace context is not sampled",
+			ctx: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: traceID,
+				SpanID:  spanID,
+			})),
+			portalAppID: "portal_app_exemplar_not_sampled",
+		},
+		{
+			name:        "should not attach exemplar if no trace context is present",
+			ctx:         context.Background(),
+			portalAppID: "portal_app_exemplar_no_trace",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			RecordAuthRequest(test.ctx, test.portalAppID, "account_1", AuthDecisionAuthorized, "", 0.000002)
+
+			observer, err := authRequestDurationSeconds.Load().GetMetricWithLabelValues(test.portalAppID, AuthDecisionAuthorized)
+			c.NoError(err)
+
+			var metric dto.Metric
+			c.NoError(observer.(prometheus.Metric).Write(&metric))
+			c.Equal(uint64(1), metric.GetHistogram().GetSampleCount())
+
+			exemplarLabels := make(map[string]string)
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				for _, label := range bucket.GetExemplar().GetLabel() {
+					exemplarLabels[label.GetName()] = label.GetValue()
+				}
+			}
+
+			if test.expectedExemplar == nil {
+				c.Empty(exemplarLabels)
+				return
+			}
+			c.Equal(test.expectedExemplar, exemplarLabels)
+		})
+	}
+}
+
+func Test_normalizePlanType(t *testing.T) {
+	tests := []struct {
+		name     string
+		planType string
+		expected string
+	}{
+		{
+			name:     "should keep PLAN_FREE",
+			planType: "PLAN_FREE",
+			expected: PlanTypeFree,
+		},
+		{
+			name:     "should keep PLAN_UNLIMITED",
+			planType: "PLAN_UNLIMITED",
+			expected: PlanTypeUnlimited,
+		},
+		{
+			name:     "should map unknown plan type to other",
+			planType: "PLAN_ENTERPRISE",
+			expected: PlanTypeOther,
+		},
+		{
+			name:     "should map plan type with different casing to other",
+			planType: "plan_free",
+			expected: PlanTypeOther,
+		},
+		{
+			name:     "should map empty plan type to other",
+			planType: "",
+			expected: PlanTypeOther,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expected, normalizePlanType(test.planType))
+		})
+	}
+}
+
+func Test_RecordRateLimitCheck_UnknownPlanType(t *testing.T) {
+	c := require.New(t)
+
+	RecordRateLimitCheck("account_unknown_plan", "PLAN_TYPO", "allowed")
+
+	counter, err := rateLimitChecksTotal.GetMetricWithLabelValues("account_unknown_plan", PlanTypeOther, "allowed")
+	c.NoError(err)
+
+	var metric dto.Metric
+	c.NoError(counter.Write(&metric))
+	c.Equal(float64(1), metric.GetCounter().GetValue())
+}
+
+func Test_UpdateAccountUsage_UnknownPlanType(t *testing.T) {
+	c := require.New(t)
+
+	UpdateAccountUsage("account_unknown_plan", "PLAN_TYPO", 1500, 1000)
+
+	gauge, err := accountUsageTotal.GetMetricWithLabelValues("account_unknown_plan", PlanTypeOther, "1000")
+	c.NoError(err)
+
+	var metric dto.Metric
+	c.NoError(gauge.Write(&metric))
+	c.Equal(float64(1500), metric.GetGauge().GetValue())
+}
+
+func Test_ParseHistogramBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{
+			name:  "should parse increasing buckets",
+			input: "0.00001, 0.0001,0.001,0.01,0.1",
+			want:  []float64{0.00001, 0.0001, 0.001, 0.01, 0.1},
+		},
+		{
+			name:  "should parse a single bucket",
+			input: "1",
+			want:  []float64{1},
+		},
+		{
+			name:    "should error on a non-numeric bucket",
+			input:   "0.001,10ms",
+			wantErr: true,
+		},
+		{
+			name:    "should error on a non-positive bucket",
+			input:   "0,0.001",
+			wantErr: true,
+		},
+		{
+			name:    "should error on an infinite bucket",
+			input:   "0.001,+Inf",
+			wantErr: true,
+		},
+		{
+			name:    "should error on decreasing buckets",
+			input:   "0.01,0.001",
+			wantErr: true,
+		},
+		{
+			name:    "should error on duplicate buckets",
+			input:   "0.001,0.001",
+			wantErr: true,
+		},
+		{
+			name:    "should error on an empty bucket",
+			input:   "0.001,,0.01",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			buckets, err := ParseHistogramBuckets(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.want, buckets)
+		})
+	}
+}
+
+func Test_SetAuthRequestDurationBuckets(t *testing.T) {
+	c := require.New(t)
+
+	t.Cleanup(func() {
+		c.NoError(replaceAuthRequestDurationSeconds(DefaultAuthRequestDurationBuckets))
+	})
+
+	// Buckets cannot be changed once an auth request has been recorded, as its observations would be discarded
+	RecordAuthRequest(context.Background(), "portal_app_default_buckets", "account_1", AuthDecisionAuthorized, "", 0.000002)
+	c.Error(SetAuthRequestDurationBuckets([]float64{0.1}))
+
+	// Simulate startup, before any auth request is recorded
+	authRequestRecorded.Store(false)
+
+	buckets := []float64{0.001, 0.01, 0.1, 1}
+	c.NoError(SetAuthRequestDurationBuckets(buckets))
+
+	// A 50ms request, above the default 10ms upper bucket, is observed in the custom 100ms bucket
+	RecordAuthRequest(context.Background(), "portal_app_custom_buckets", "account_1", AuthDecisionAuthorized, "", 0.05)
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	c.NoError(err)
+
+	var histogram *dto.Histogram
+	for _, family := range families {
+		if family.GetName() != peasProcess+"_"+authRequestDurationSecondsMetricName {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "portal_app_id" && label.GetValue() == "portal_app_custom_buckets" {
+					histogram = metric.GetHistogram()
+				}
+			}
+		}
+	}
+	c.NotNil(histogram, "custom bucket histogram is not registered")
+
+	var upperBounds []float64
+	cumulativeCounts := make(map[float64]uint64)
+	for _, bucket := range histogram.GetBucket() {
+		upperBounds = append(upperBounds, bucket.GetUpperBound())
+		cumulativeCounts[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+	}
+	c.Equal(buckets, upperBounds)
+	c.Equal(uint64(0), cumulativeCounts[0.01])
+	c.Equal(uint64(1), cumulativeCounts[0.1])
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+	headerVary            = "Vary"
+
+	encodingGzip = "gzip"
+)
+
+type (
+	// serverConfig contains optional settings shared by the HTTP servers in this package.
+	serverConfig struct {
+		// gzipCompression: whether responses are gzip-compressed for clients that accept it
+		gzipCompression bool
+		// metricsFormat: exposition format served by the /metrics endpoint
+		metricsFormat MetricsFormat
+		// handlers: additional handlers served by the metrics server, by path
+		handlers map[string]http.Handler
+		// shutdownTimeout: maximum time to wait for in-flight requests once the server's context is canceled
+		shutdownTimeout time.Duration
+	}
+
+	// ServerOption configures optional HTTP server behavior.
+	ServerOption func(*serverConfig)
+)
+
+// WithGzipCompression gzip-compresses responses for requests whose Accept-Encoding header accepts gzip.
+//   - Responses already encoded by the handler (e.g. by promhttp) are passed through unchanged
+//   - Defaults to false (only the /metrics endpoint is compressed, by promhttp)
+func WithGzipCompression(enabled bool) ServerOption {
+	return func(c *serverConfig) {
+		c.gzipCompression = enabled
+	}
+}
+
+// newServerConfig applies the given options to a default serverConfig.
+func newServerConfig(opts []ServerOption) serverConfig {
+	config := serverConfig{metricsFormat: defaultMetricsFormat, shutdownTimeout: DefaultShutdownTimeout}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// wrapHandler wraps the handler with the configured middleware.
+func (c serverConfig) wrapHandler(handler http.Handler) http.Handler {
+	if c.gzipCompression {
+		handler = gzipHandler(handler)
+	}
+	return handler
+}
+
+// gzipHandler gzip-compresses responses for requests that accept gzip.
+func gzipHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add(headerVary, headerAcceptEncoding)
+
+		if !acceptsGzip(r.Header.Get(headerAcceptEncoding)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip returns true if the Accept-Encoding header value accepts gzip with a non-zero quality.
+//   - Example: "gzip, deflate, br" or "gzip;q=0.8"
+//   - The "*" wildcard is not treated as accepting gzip, to avoid compressing for clients that did not ask for it
+func acceptsGzip(acceptEncoding string) bool {
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(encoding, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encodingGzip) {
+			continue
+		}
+
+		quality, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(quality), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the response body, unless the handler already set a Content-Encoding.
+//   - The decision is made when the response header is written, so handlers may still set headers beforehand
+type gzipResponseWriter struct {
+	http.ResponseWriter
+
+	// gz: compressing writer, nil until the header is written and only set if the response is compressed
+	gz            *gzip.Writer
+	headerWritten bool
+}
+
+// WriteHeader enables compression if the response is not already encoded and may have a body.
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.headerWritten {
+		return
+	}
+	w.headerWritten = true
+
+	header := w.Header()
+	if header.Get(headerContentEncoding) == "" && code != http.StatusNoContent && code != http.StatusNotModified {
+		header.Set(headerContentEncoding, encodingGzip)
+		header.Del(headerContentLength)
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write compresses the body, writing a 200 header first if none was written.
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes any compressed data, so streaming handlers (e.g. pprof traces) are not buffered.
+func (w *gzipResponseWriter) Flush() {
+	if !w.headerWritten {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close writes the gzip footer, if the response was compressed.
+func (w *gzipResponseWriter) close() {
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_acceptsGzip(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		expected       bool
+	}{
+		{name: "should accept gzip", acceptEncoding: "gzip", expected: true},
+		{name: "should accept gzip among other encodings", acceptEncoding: "deflate, GZIP, br", expected: true},
+		{name: "should accept gzip with a non-zero quality", acceptEncoding: "gzip;q=0.5", expected: true},
+		{name: "should not accept gzip with a zero quality", acceptEncoding: "gzip;q=0", expected: false},
+		{name: "should not accept other encodings", acceptEncoding: "deflate, br", expected: false},
+		{name: "should not accept the wildcard", acceptEncoding: "*", expected: false},
+		{name: "should not accept an empty header", acceptEncoding: "", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expected, acceptsGzip(test.acceptEncoding))
+		})
+	}
+}
+
+func Test_ServeMetrics_GzipCompression(t *testing.T) {
+	tests := []struct {
+		name               string
+		compression        bool
+		path               string
+		acceptEncoding     string
+		expectedCompressed bool
+	}{
+		{
+			name:               "should compress health response if requested",
+			compression:        true,
+			path:               endpointHealth,
+			acceptEncoding:     "gzip",
+			expectedCompressed: true,
+		},
+		{
+			name:           "should not compress health response if not requested",
+			compression:    true,
+			path:           endpointHealth,
+			acceptEncoding: "",
+		},
+		{
+			name:           "should not compress health response if compression is disabled",
+			compression:    false,
+			path:           endpointHealth,
+			acceptEncoding: "gzip",
+		},
+		{
+			// promhttp compresses the response itself, so it must not be compressed twice
+			name:               "should compress metrics response once if requested",
+			compression:        true,
+			path:               endpointMetrics,
+			acceptEncoding:     "gzip",
+			expectedCompressed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			config := newServerConfig([]ServerOption{WithGzipCompression(test.compression)})
+			server := httptest.NewServer(config.wrapHandler(newMetricsMux(polyzero.NewLogger(), "v1.0.0", config.metricsFormat)))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+test.path, nil)
+			c.NoError(err)
+			if test.acceptEncoding != "" {
+				req.Header.Set(headerAcceptEncoding, test.acceptEncoding)
+			} else {
+				// Prevent the transport from requesting and transparently decompressing gzip
+				req.Header.Set(headerAcceptEncoding, "identity")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			c.NoError(err)
+			defer resp.Body.Close()
+			c.Equal(http.StatusOK, resp.StatusCode)
+
+			body := io.Reader(resp.Body)
+			if test.expectedCompressed {
+				c.Equal(encodingGzip, resp.Header.Get(headerContentEncoding))
+				gz, err := gzip.NewReader(resp.Body)
+				c.NoError(err)
+				defer gz.Close()
+				body = gz
+			} else {
+				c.Empty(resp.Header.Get(headerContentEncoding))
+			}
+
+			data, err := io.ReadAll(body)
+			c.NoError(err)
+
+			if test.path == endpointHealth {
+				var health HealthResponse
+				c.NoError(json.Unmarshal(data, &health))
+				c.Equal("healthy", health.Status)
+				c.Equal("v1.0.0", health.Version)
+			} else {
+				c.Contains(string(data), "# HELP")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	dataSourceTypeInfoMetricName = "data_source_type_info"
+
+	// Data source type constants for the active data source info metric
+	DataSourceTypePostgres  = "postgres"
+	DataSourceTypeDirectory = "directory"
+)
+
+var (
+	// dataSourceTypeInfo reports which portal app data source type is currently active.
+	// Set as an info gauge (value 1) with labels:
+	//   - type: "postgres", "directory"
+	//
+	// Usage:
+	// - Identify the active data source in multi-source deployments
+	// - Track data source changes over time (e.g. on failover)
+	dataSourceTypeInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: peasProcess,
+			Name:      dataSourceTypeInfoMetricName,
+			Help:      "Currently active portal app data source type; the value is always 1.",
+		},
+		[]string{"type"},
+	)
+
+	// activeDataSourceType is the last data source type set, reported by the health endpoint.
+	activeDataSourceType   string
+	activeDataSourceTypeMu sync.RWMutex
+)
+
+func init() {
+	prometheus.MustRegister(dataSourceTypeInfo)
+}
+
+// SetDataSourceType sets the currently active data source type.
+// Any previously active type is removed so only one type is reported at a time.
+func SetDataSourceType(dataSourceType string) {
+	activeDataSourceTypeMu.Lock()
+	defer activeDataSourceTypeMu.Unlock()
+
+	dataSourceTypeInfo.Reset()
+	dataSourceTypeInfo.With(prometheus.Labels{"type": dataSourceType}).Set(1)
+	activeDataSourceType = dataSourceType
+}
+
+// getDataSourceType returns the currently active data source type, or an empty string if none was set.
+func getDataSourceType() string {
+	activeDataSourceTypeMu.RLock()
+	defer activeDataSourceTypeMu.RUnlock()
+	return activeDataSourceType
+}
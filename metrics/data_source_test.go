@@ -0,0 +1,23 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetDataSourceType(t *testing.T) {
+	c := require.New(t)
+
+	SetDataSourceType(DataSourceTypePostgres)
+	c.Equal(1, testutil.CollectAndCount(dataSourceTypeInfo))
+	c.Equal(float64(1), testutil.ToFloat64(dataSourceTypeInfo.WithLabelValues(DataSourceTypePostgres)))
+	c.Equal(DataSourceTypePostgres, getDataSourceType())
+
+	// Changing the active type (e.g. on failover) replaces the previous type
+	SetDataSourceType("test_source")
+	c.Equal(1, testutil.CollectAndCount(dataSourceTypeInfo))
+	c.Equal(float64(1), testutil.ToFloat64(dataSourceTypeInfo.WithLabelValues("test_source")))
+	c.Equal("test_source", getDataSourceType())
+}
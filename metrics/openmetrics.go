@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	headerAccept = "Accept"
+
+	// acceptOpenMetrics is the Accept header value requesting OpenMetrics from promhttp
+	acceptOpenMetrics = "application/openmetrics-text; version=1.0.0"
+)
+
+// MetricsFormat determines the exposition format served by the /metrics endpoint.
+type MetricsFormat string
+
+const (
+	// MetricsFormatNegotiate serves OpenMetrics to scrapers whose Accept header requests it,
+	// and the Prometheus text format otherwise.
+	MetricsFormatNegotiate MetricsFormat = "negotiate"
+	// MetricsFormatOpenMetrics always serves OpenMetrics, regardless of the Accept header.
+	MetricsFormatOpenMetrics MetricsFormat = "openmetrics"
+	// MetricsFormatText always serves the Prometheus text format, regardless of the Accept header.
+	MetricsFormatText MetricsFormat = "text"
+)
+
+// defaultMetricsFormat preserves the original behavior of negotiating
+// OpenMetrics, so exemplars are exposed to scrapers that request it.
+const defaultMetricsFormat = MetricsFormatNegotiate
+
+// ParseMetricsFormat parses a MetricsFormat.
+//   - Valid values are "negotiate", "openmetrics" and "text"
+func ParseMetricsFormat(s string) (MetricsFormat, error) {
+	switch format := MetricsFormat(s); format {
+	case MetricsFormatNegotiate, MetricsFormatOpenMetrics, MetricsFormatText:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid metrics format %q: must be one of negotiate, openmetrics, text", s)
+	}
+}
+
+// WithMetricsFormat sets the exposition format served by the /metrics endpoint.
+//   - Defaults to "negotiate"
+//   - Has no effect on the pprof server
+func WithMetricsFormat(format MetricsFormat) ServerOption {
+	return func(c *serverConfig) {
+		c.metricsFormat = format
+	}
+}
+
+// newMetricsHandler returns the handler of the /metrics endpoint, serving the given format.
+func newMetricsHandler(format MetricsFormat) http.Handler {
+	handler := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: format != MetricsFormatText,
+	})
+
+	if format == MetricsFormatOpenMetrics {
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Request OpenMetrics on behalf of the scraper, so promhttp negotiates it
+			r = r.Clone(r.Context())
+			r.Header.Set(headerAccept, acceptOpenMetrics)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler)
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseMetricsFormat(t *testing.T) {
+	c := require.New(t)
+
+	for _, s := range []string{"negotiate", "openmetrics", "text"} {
+		format, err := ParseMetricsFormat(s)
+		c.NoError(err)
+		c.Equal(MetricsFormat(s), format)
+	}
+
+	_, err := ParseMetricsFormat("protobuf")
+	c.Error(err)
+}
+
+func Test_ServeMetrics_MetricsFormat(t *testing.T) {
+	tests := []struct {
+		name                string
+		opts                []ServerOption
+		accept              string
+		expectedOpenMetrics bool
+	}{
+		{
+			name:                "should serve OpenMetrics if requested by default",
+			accept:              acceptOpenMetrics,
+			expectedOpenMetrics: true,
+		},
+		{
+			name:   "should serve the text format if OpenMetrics is not requested by default",
+			accept: "",
+		},
+		{
+			name:                "should serve OpenMetrics without an Accept header if openmetrics is set",
+			opts:                []ServerOption{WithMetricsFormat(MetricsFormatOpenMetrics)},
+			accept:              "",
+			expectedOpenMetrics: true,
+		},
+		{
+			name:                "should serve OpenMetrics to a text scraper if openmetrics is set",
+			opts:                []ServerOption{WithMetricsFormat(MetricsFormatOpenMetrics)},
+			accept:              "text/plain;version=0.0.4",
+			expectedOpenMetrics: true,
+		},
+		{
+			name:   "should serve the text format even if OpenMetrics is requested if text is set",
+			opts:   []ServerOption{WithMetricsFormat(MetricsFormatText)},
+			accept: acceptOpenMetrics,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			config := newServerConfig(test.opts)
+			server := httptest.NewServer(config.wrapHandler(newMetricsMux(polyzero.NewLogger(), "v1.0.0", config.metricsFormat)))
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+endpointMetrics, nil)
+			c.NoError(err)
+			if test.accept != "" {
+				req.Header.Set(headerAccept, test.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			c.NoError(err)
+			defer resp.Body.Close()
+			c.Equal(http.StatusOK, resp.StatusCode)
+
+			data, err := io.ReadAll(resp.Body)
+			c.NoError(err)
+			body := strings.TrimSpace(string(data))
+
+			contentType := resp.Header.Get("Content-Type")
+			if test.expectedOpenMetrics {
+				c.True(strings.HasPrefix(contentType, "application/openmetrics-text"), "content type %q", contentType)
+				// OpenMetrics expositions must end with an EOF marker
+				c.True(strings.HasSuffix(body, "# EOF"))
+			} else {
+				c.True(strings.HasPrefix(contentType, "text/plain"), "content type %q", contentType)
+				c.False(strings.HasSuffix(body, "# EOF"))
+			}
+		})
+	}
+}
@@ -9,7 +9,8 @@ import (
 )
 
 // ServePprof starts a pprof server on the given address.
-func ServePprof(ctx context.Context, logger polylog.Logger, addr string) {
+//   - The server is shut down once the context is canceled (see WithShutdownTimeout)
+func ServePprof(ctx context.Context, logger polylog.Logger, addr string, opts ...ServerOption) {
 	pprofMux := http.NewServeMux()
 	pprofMux.HandleFunc("/debug/pprof/", pprof.Index)
 	pprofMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -17,9 +18,10 @@ func ServePprof(ctx context.Context, logger polylog.Logger, addr string) {
 	pprofMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	pprofMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 
+	config := newServerConfig(opts)
 	server := &http.Server{
 		Addr:    addr,
-		Handler: pprofMux,
+		Handler: config.wrapHandler(pprofMux),
 	}
 
 	// Start the server in a new goroutine
@@ -31,11 +33,5 @@ func ServePprof(ctx context.Context, logger polylog.Logger, addr string) {
 	}()
 
 	// Handle graceful shutdown
-	go func() {
-		<-ctx.Done()
-		logger.Info().Str("pprof_addr", addr).Msg("Stopping pprof server")
-		if err := server.Shutdown(ctx); err != nil {
-			logger.Error().Err(err).Msg("Error stopping pprof server")
-		}
-	}()
+	go shutdownOnDone(ctx, logger, server, addr, config.shutdownTimeout)
 }
@@ -1,11 +1,15 @@
 package metrics
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/pokt-network/poktroll/pkg/polylog"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 const (
@@ -13,20 +17,127 @@ const (
 	endpointHealth  = "/healthz"
 )
 
+// DefaultShutdownTimeout is the maximum time to wait for in-flight requests on shutdown,
+// below the default Kubernetes terminationGracePeriodSeconds of 30s.
+const DefaultShutdownTimeout = 25 * time.Second
+
 // HealthResponse represents the JSON response for the health endpoint.
 type HealthResponse struct {
 	Status  string `json:"status"`
 	Service string `json:"service"`
 	Version string `json:"version,omitempty"`
+	// DataSourceType is the currently active portal app data source type (e.g. "postgres")
+	DataSourceType string `json:"data_source_type,omitempty"`
+}
+
+// BindFailureMode determines how PEAS handles a failure to bind the metrics server address (e.g. the port is in use).
+type BindFailureMode string
+
+const (
+	// BindFailureModeFatal fails startup, so a port conflict is surfaced instead of PEAS running without metrics.
+	BindFailureModeFatal BindFailureMode = "fatal"
+	// BindFailureModeLog logs the failure and continues startup without the metrics server.
+	BindFailureModeLog BindFailureMode = "log"
+)
+
+// ParseBindFailureMode parses a BindFailureMode.
+//   - Valid values are "fatal" and "log"
+func ParseBindFailureMode(s string) (BindFailureMode, error) {
+	switch mode := BindFailureMode(s); mode {
+	case BindFailureModeFatal, BindFailureModeLog:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid bind failure mode %q: must be one of fatal, log", s)
+	}
 }
 
 // ServeMetrics starts a Prometheus metrics server with health endpoint on the given address.
-func ServeMetrics(logger polylog.Logger, addr, version string) error {
+//   - The address is bound before returning, so bind failures (e.g. the port is in use) are returned rather than only logged
+//   - Failures after the address is bound are logged
+//   - The server is shut down once the context is canceled (see WithShutdownTimeout)
+func ServeMetrics(ctx context.Context, logger polylog.Logger, addr, version string, opts ...ServerOption) error {
+	config := newServerConfig(opts)
+	mux := newMetricsMux(logger, version, config.metricsFormat)
+	for path, handler := range config.handlers {
+		mux.Handle(path, handler)
+	}
+	handler := config.wrapHandler(mux)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to bind metrics server address %q: %w", addr, err)
+	}
+
+	server := &http.Server{Handler: handler}
+
+	// Start the server in a new goroutine
+	go func() {
+		logger.Info().Str("metrics_addr", listener.Addr().String()).Msg("📊 Starting Prometheus metrics server with health endpoint")
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Error().Err(err).Msg("Prometheus metrics server failed")
+			return
+		}
+	}()
+
+	// Handle graceful shutdown
+	go shutdownOnDone(ctx, logger, server, listener.Addr().String(), config.shutdownTimeout)
+
+	return nil
+}
+
+// WithShutdownTimeout sets the maximum time to wait for in-flight requests once the server's context is canceled,
+// after which the remaining connections are closed. Defaults to DefaultShutdownTimeout.
+//   - Non-positive values keep the default
+func WithShutdownTimeout(timeout time.Duration) ServerOption {
+	return func(c *serverConfig) {
+		if timeout > 0 {
+			c.shutdownTimeout = timeout
+		}
+	}
+}
+
+// shutdownOnDone gracefully shuts down the server once the context is canceled.
+//   - In-flight requests are waited for at most timeout, then their connections are closed.
+func shutdownOnDone(ctx context.Context, logger polylog.Logger, server *http.Server, addr string, timeout time.Duration) {
+	<-ctx.Done()
+	logger.Info().Str("addr", addr).Msg("🛑 Stopping HTTP server")
+
+	// The server's context is already canceled, so the shutdown deadline is derived from a fresh context
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			logger.Warn().Str("addr", addr).Dur("shutdown_timeout", timeout).
+				Msg("⚠️ HTTP server requests did not complete before the shutdown timeout: closing their connections")
+		} else {
+			logger.Error().Err(err).Str("addr", addr).Msg("Error stopping HTTP server")
+		}
+		if err := server.Close(); err != nil {
+			logger.Error().Err(err).Str("addr", addr).Msg("Error closing HTTP server")
+		}
+	}
+}
+
+// WithHandler serves the handler on the given path of the metrics server (e.g. an admin endpoint).
+//   - Has no effect on the pprof server
+func WithHandler(path string, handler http.Handler) ServerOption {
+	return func(c *serverConfig) {
+		if c.handlers == nil {
+			c.handlers = make(map[string]http.Handler)
+		}
+		c.handlers[path] = handler
+	}
+}
+
+// newMetricsMux returns the handler of the metrics and health endpoints.
+func newMetricsMux(logger polylog.Logger, version string, metricsFormat MetricsFormat) *http.ServeMux {
 	// Create a new mux to handle multiple endpoints
 	mux := http.NewServeMux()
 
 	// Add metrics endpoint
-	mux.Handle(endpointMetrics, promhttp.Handler())
+	// OpenMetrics exposes exemplars linking observations to traces, unless the format is "text"
+	mux.Handle(endpointMetrics, newMetricsHandler(metricsFormat))
 
 	// Add health endpoint
 	mux.HandleFunc(endpointHealth, func(w http.ResponseWriter, r *http.Request) {
@@ -37,6 +148,8 @@ func ServeMetrics(logger polylog.Logger, addr, version string) error {
 			Status:  "healthy",
 			Service: "peas",
 			Version: version,
+
+			DataSourceType: getDataSourceType(),
 		}
 
 		if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -45,14 +158,5 @@ func ServeMetrics(logger polylog.Logger, addr, version string) error {
 		}
 	})
 
-	// Start the server in a new goroutine
-	go func() {
-		logger.Info().Str("metrics_addr", addr).Msg("📊 Starting Prometheus metrics server with health endpoint")
-		if err := http.ListenAndServe(addr, mux); err != nil {
-			logger.Error().Err(err).Msg("Prometheus metrics server failed")
-			return
-		}
-	}()
-
-	return nil
+	return mux
 }
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseBindFailureMode(t *testing.T) {
+	c := require.New(t)
+
+	for _, s := range []string{"fatal", "log"} {
+		mode, err := ParseBindFailureMode(s)
+		c.NoError(err)
+		c.Equal(BindFailureMode(s), mode)
+	}
+
+	_, err := ParseBindFailureMode("ignore")
+	c.Error(err)
+}
+
+func Test_ServeMetrics_BindFailure(t *testing.T) {
+	c := require.New(t)
+
+	// Occupy a port, so the metrics server cannot bind it
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.NoError(err)
+	defer listener.Close()
+
+	err = ServeMetrics(context.Background(), polyzero.NewLogger(), listener.Addr().String(), "test")
+	c.Error(err)
+	c.ErrorContains(err, listener.Addr().String())
+}
+
+func Test_ServeMetrics_Bound(t *testing.T) {
+	c := require.New(t)
+
+	// Reserve a free port, then release it for the metrics server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.NoError(err)
+	addr := listener.Addr().String()
+	c.NoError(listener.Close())
+
+	c.NoError(ServeMetrics(context.Background(), polyzero.NewLogger(), addr, "test"))
+
+	// The address is bound when ServeMetrics returns, so the server is immediately reachable
+	resp, err := http.Get("http://" + addr + endpointHealth)
+	c.NoError(err)
+	defer resp.Body.Close()
+	c.Equal(http.StatusOK, resp.StatusCode)
+}
+
+func Test_ServeMetrics_WithHandler(t *testing.T) {
+	c := require.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.NoError(err)
+	addr := listener.Addr().String()
+	c.NoError(listener.Close())
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	c.NoError(ServeMetrics(context.Background(), polyzero.NewLogger(), addr, "test", WithHandler("/admin", handler)))
+
+	// The additional handler is served alongside the metrics and health endpoints
+	resp, err := http.Get("http://" + addr + "/admin")
+	c.NoError(err)
+	defer resp.Body.Close()
+	c.Equal(http.StatusTeapot, resp.StatusCode)
+}
+
+func Test_ServeMetrics_Shutdown(t *testing.T) {
+	c := require.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.NoError(err)
+	addr := listener.Addr().String()
+	c.NoError(listener.Close())
+
+	// A request that never completes on its own, e.g. a slow client
+	inFlight := make(chan struct{})
+	released := make(chan struct{})
+	defer close(released)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-released
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.NoError(ServeMetrics(ctx, polyzero.NewLogger(), addr, "test",
+		WithHandler("/slow", handler),
+		WithShutdownTimeout(100*time.Millisecond),
+	))
+
+	requestErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		requestErr <- err
+	}()
+	<-inFlight
+
+	// The in-flight request's connection is closed once the shutdown timeout elapses
+	cancel()
+	select {
+	case err := <-requestErr:
+		c.Error(err)
+	case <-time.After(5 * time.Second):
+		c.FailNow("in-flight request was not closed after the shutdown timeout")
+	}
+
+	// The server no longer accepts connections
+	_, err = net.Dial("tcp", addr)
+	c.Error(err)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// statsdNameInvalidCharsRegex matches characters not allowed in a statsd metric name component,
+// including the '.', ':' and '|' separators of the statsd protocol.
+var statsdNameInvalidCharsRegex = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// statsdEmitter sends counters to a statsd server over UDP, in addition to the Prometheus registry.
+type statsdEmitter struct {
+	conn   net.Conn
+	prefix string
+}
+
+// statsd is the enabled statsd emitter; nil if statsd is disabled (see EnableStatsd).
+var statsd atomic.Pointer[statsdEmitter]
+
+// EnableStatsd emits the auth request and rate limit check counters to the statsd server at the
+// address ("host:port", over UDP), in addition to Prometheus, with metric names prefixed by the prefix (e.g. "peas").
+// Returns a function that stops emitting and closes the connection.
+//
+//   - Counters are sent fire-and-forget: an unreachable statsd server never blocks or fails a request
+//   - Counters are named by their label values, without the high-cardinality portal app and account IDs:
+//     "<prefix>.auth_requests.<status>[.<error_type>]" and "<prefix>.rate_limit_checks.<plan_type>.<decision>"
+func EnableStatsd(address, prefix string) (func() error, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd at %q: %w", address, err)
+	}
+
+	emitter := &statsdEmitter{conn: conn, prefix: prefix}
+	statsd.Store(emitter)
+
+	return func() error {
+		statsd.CompareAndSwap(emitter, nil)
+		return conn.Close()
+	}, nil
+}
+
+// emitStatsdCounter increments the statsd counter named by the components, if statsd is enabled.
+func emitStatsdCounter(components ...string) {
+	emitter := statsd.Load()
+	if emitter == nil {
+		return
+	}
+
+	// Write errors (e.g. no statsd server listening) are ignored, as for any UDP statsd client
+	_, _ = emitter.conn.Write([]byte(emitter.counterName(components...) + ":1|c"))
+}
+
+// counterName returns the prefixed statsd metric name of the components, replacing invalid characters with '_'.
+func (e *statsdEmitter) counterName(components ...string) string {
+	names := make([]string, 0, len(components)+1)
+	if e.prefix != "" {
+		names = append(names, e.prefix)
+	}
+	for _, component := range components {
+		names = append(names, statsdNameInvalidCharsRegex.ReplaceAllString(component, "_"))
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EnableStatsd(t *testing.T) {
+	c := require.New(t)
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	c.NoError(err)
+	defer listener.Close()
+
+	closeStatsd, err := EnableStatsd(listener.LocalAddr().String(), "peas")
+	c.NoError(err)
+	defer closeStatsd()
+
+	readPacket := func() string {
+		buf := make([]byte, 1024)
+		c.NoError(listener.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := listener.ReadFrom(buf)
+		c.NoError(err)
+		return string(buf[:n])
+	}
+
+	RecordAuthRequest(t.Context(), "portal_app_statsd", "account_statsd", AuthDecisionAuthorized, "", 0.01)
+	c.Equal("peas.auth_requests.authorized:1|c", readPacket())
+
+	RecordAuthRequest(t.Context(), "portal_app_statsd", "account_statsd", AuthDecisionDenied, AuthRequestErrorTypePortalAppNotFound, 0.01)
+	c.Equal("peas.auth_requests.denied.portal_app_not_found:1|c", readPacket())
+
+	RecordRateLimitCheck("account_statsd", PlanTypeFree, "allowed")
+	c.Equal("peas.rate_limit_checks.PLAN_FREE.allowed:1|c", readPacket())
+
+	// No counters are emitted once statsd is closed
+	c.NoError(closeStatsd())
+	RecordRateLimitCheck("account_statsd", PlanTypeFree, "allowed")
+	c.NoError(listener.SetReadDeadline(time.Now().Add(50 * time.Millisecond)))
+	_, _, err = listener.ReadFrom(make([]byte, 1024))
+	c.Error(err)
+}
+
+func Test_statsdEmitter_counterName(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		components []string
+		expected   string
+	}{
+		{
+			name:       "should prefix the components",
+			prefix:     "peas",
+			components: []string{"auth_requests", "authorized"},
+			expected:   "peas.auth_requests.authorized",
+		},
+		{
+			name:       "should not prefix the components with an empty prefix",
+			components: []string{"auth_requests", "authorized"},
+			expected:   "auth_requests.authorized",
+		},
+		{
+			name:       "should replace statsd protocol separators in components",
+			prefix:     "peas",
+			components: []string{"auth_requests", "denied.bad:type|c"},
+			expected:   "peas.auth_requests.denied_bad_type_c",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			e := &statsdEmitter{prefix: test.prefix}
+			c.Equal(test.expected, e.counterName(test.components...))
+		})
+	}
+}
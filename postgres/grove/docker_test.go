@@ -27,6 +27,7 @@ const (
 	connStringFormat   = "postgres://%s:%s@%s/%s?sslmode=disable"
 	schemaLocation     = "./sqlc/grove_schema.sql"
 	seedTestDBLocation = "./testdata/seed-test-db.sql"
+	viewLocation       = "./testdata/portal-apps-view.sql"
 	dockerEntrypoint   = ":/docker-entrypoint-initdb.d/init_%s.sql"
 	timeOut            = 1200
 )
@@ -37,6 +38,7 @@ var (
 	containerEnvDB       = fmt.Sprintf("POSTGRES_DB=%s", dbName)
 	schemaDockerPath     = filepath.Join(os.Getenv("PWD"), schemaLocation) + fmt.Sprintf(dockerEntrypoint, "1")
 	seedTestDBDockerPath = filepath.Join(os.Getenv("PWD"), seedTestDBLocation) + fmt.Sprintf(dockerEntrypoint, "2")
+	viewDockerPath       = filepath.Join(os.Getenv("PWD"), viewLocation) + fmt.Sprintf(dockerEntrypoint, "3")
 )
 
 func setupPostgresDocker() (*dockertest.Pool, *dockertest.Resource, string) {
@@ -45,7 +47,7 @@ func setupPostgresDocker() (*dockertest.Pool, *dockertest.Resource, string) {
 		Repository: containerRepo,
 		Tag:        containerTag,
 		Env:        []string{containerEnvUser, containerEnvPassword, containerEnvDB},
-		Mounts:     []string{schemaDockerPath, seedTestDBDockerPath},
+		Mounts:     []string{schemaDockerPath, seedTestDBDockerPath, viewDockerPath},
 	}
 
 	pool, err := dockertest.NewPool("")
@@ -17,14 +17,43 @@ import (
 // to provide data from Grove's Postgres database for the portal app store.
 var _ store.DataSource = &GrovePostgresDriver{}
 
+// GrovePostgresDriver implements the store.AuthSource interface
+// to fetch a single portal app's auth settings on demand.
+var _ store.AuthSource = &GrovePostgresDriver{}
+
 type (
 	// GrovePostgresDriver implements the store.DataSource interface
 	// to provide data from a Postgres database for the portal app store.
 	GrovePostgresDriver struct {
 		logger polylog.Logger
 		driver *postgresDriver
+
+		// portalAppsView: optional table or view to select portal apps from, instead of the base tables
+		portalAppsView *PortalAppsView
+
+		// streamPortalApps: convert each row into the portal apps map as it is scanned, instead of loading all rows first
+		streamPortalApps bool
+
+		// duplicateResolution: which row is kept when more than one row has the same portal app ID
+		duplicateResolution DuplicatePortalAppIDResolution
+
+		// excludeEmptySecretKeys: exclude portal apps that require a secret key but have an empty one, instead of only flagging them
+		excludeEmptySecretKeys bool
+
+		// lazyAuth: load portal apps without their secret keys, which are fetched on demand by GetAuth instead
+		lazyAuth bool
+
+		// authQueryTimeout: deadline of each GetAuth query, which runs on the request path when lazy auth is enabled
+		authQueryTimeout time.Duration
+
+		// ctx: context of every query, canceled by Close so in-flight queries are aborted rather than awaited
+		ctx    context.Context
+		cancel context.CancelFunc
 	}
 
+	// GrovePostgresDriverOption configures optional GrovePostgresDriver behavior.
+	GrovePostgresDriverOption func(*GrovePostgresDriver)
+
 	// The postgresDriver struct wraps the SQLC generated queries and the pgxpool.Pool.
 	// See: https://docs.sqlc.dev/en/latest/tutorials/getting-started-postgresql.html
 	postgresDriver struct {
@@ -33,8 +62,65 @@ type (
 	}
 )
 
+// WithPortalAppsView selects portal apps from the given table or view, using its column mapping,
+// instead of the base Grove Portal tables. An empty view name keeps the base tables.
+func WithPortalAppsView(view PortalAppsView) GrovePostgresDriverOption {
+	return func(d *GrovePostgresDriver) {
+		if view.Name != "" {
+			d.portalAppsView = &view
+		}
+	}
+}
+
+// WithStreamingLoad converts each row into the portal apps map as it is scanned,
+// rather than first loading every row into a slice.
+// Caps peak memory during refresh for very large portal databases.
+func WithStreamingLoad(enabled bool) GrovePostgresDriverOption {
+	return func(d *GrovePostgresDriver) {
+		d.streamPortalApps = enabled
+	}
+}
+
+// WithDuplicatePortalAppIDResolution sets which row is kept when more than one row
+// has the same portal app ID. Defaults to DuplicatePortalAppIDLastWins.
+func WithDuplicatePortalAppIDResolution(resolution DuplicatePortalAppIDResolution) GrovePostgresDriverOption {
+	return func(d *GrovePostgresDriver) {
+		d.duplicateResolution = resolution
+	}
+}
+
+// WithExcludeEmptySecretKeys excludes portal apps whose secret key is required but empty in the database,
+// so their requests are rejected as portal app not found. Defaults to false (they are only flagged, see validateSecretKeys).
+func WithExcludeEmptySecretKeys(exclude bool) GrovePostgresDriverOption {
+	return func(d *GrovePostgresDriver) {
+		d.excludeEmptySecretKeys = exclude
+	}
+}
+
+// WithLazyAuth loads portal apps without their secret keys, so they are never held in memory by a load;
+// only a hash of each secret key is loaded, as each portal app's AuthFingerprint.
+// Must be used with store.WithLazyAuth, which fetches the secret keys on demand with GetAuth.
+func WithLazyAuth(enabled bool) GrovePostgresDriverOption {
+	return func(d *GrovePostgresDriver) {
+		d.lazyAuth = enabled
+	}
+}
+
+// WithAuthQueryTimeout sets the deadline of each GetAuth query, so a slow database
+// fails lazy auth requests instead of holding them. Defaults to 1s; non-positive values keep the default.
+func WithAuthQueryTimeout(timeout time.Duration) GrovePostgresDriverOption {
+	return func(d *GrovePostgresDriver) {
+		if timeout > 0 {
+			d.authQueryTimeout = timeout
+		}
+	}
+}
+
 /* ---------- Postgres Connection Funcs ---------- */
 
+// defaultAuthQueryTimeout bounds each GetAuth query if WithAuthQueryTimeout is not set.
+const defaultAuthQueryTimeout = time.Second
+
 // Regular expression to match a valid PostgreSQL connection string
 var postgresConnectionStringRegex = regexp.MustCompile(`^postgres(?:ql)?:\/\/[^:]+:[^@]+@[^:]+:\d+\/[^?]+(?:\?.+)?$`)
 
@@ -45,10 +131,15 @@ The data source connects to a PostgreSQL database and:
 1. Provides methods to fetch initial portal app data
 2. Provides a channel for receiving portal app updates
 3. Listens for changes from the database
+
+The context bounds the initial connection check only (e.g. a startup dependency wait timeout):
+the driver's queries run until Close, regardless of the context.
 */
 func NewGrovePostgresDriver(
+	ctx context.Context,
 	logger polylog.Logger,
 	connectionString string,
+	opts ...GrovePostgresDriverOption,
 ) (*GrovePostgresDriver, error) {
 	if !isValidPostgresConnectionString(connectionString) {
 		return nil, fmt.Errorf("invalid postgres connection string")
@@ -65,9 +156,9 @@ func NewGrovePostgresDriver(
 	}
 
 	// Verify connection immediately
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	if err := pool.Ping(ctx); err != nil {
+	if err := pool.Ping(pingCtx); err != nil {
 		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
@@ -78,8 +169,15 @@ func NewGrovePostgresDriver(
 	}
 
 	dataSource := &GrovePostgresDriver{
-		logger: logger,
-		driver: driver,
+		logger:              logger,
+		driver:              driver,
+		duplicateResolution: defaultDuplicatePortalAppIDResolution,
+		authQueryTimeout:    defaultAuthQueryTimeout,
+	}
+	dataSource.ctx, dataSource.cancel = context.WithCancel(context.Background())
+
+	for _, opt := range opts {
+		opt(dataSource)
 	}
 
 	return dataSource, nil
@@ -94,8 +192,11 @@ func isValidPostgresConnectionString(s string) bool {
 
 // GetPortalApps loads the full set of PortalApps from the Postgres database.
 func (d *GrovePostgresDriver) GetPortalApps() (map[store.PortalAppID]*store.PortalApp, error) {
-	d.logger.Info().Msg("💾 Executing SelectPortalApps query...")
-	rows, err := d.driver.SelectPortalApps(context.Background())
+	if d.streamPortalApps {
+		return d.streamPortalAppsFromDB(d.ctx)
+	}
+
+	rows, err := d.selectPortalApps(d.ctx)
 	if err != nil {
 		d.logger.Error().Err(err).Msg("failed to fetch portal applications from database")
 		return nil, fmt.Errorf("failed to fetch portal applications: %w", err)
@@ -103,11 +204,115 @@ func (d *GrovePostgresDriver) GetPortalApps() (map[store.PortalAppID]*store.Port
 
 	d.logger.Info().Int("num_rows", len(rows)).Msg("✅ Successfully fetched Portal Applications from Postgres")
 
-	return sqlcPortalAppsToPortalApps(rows), nil
+	portalApps, duplicates := sqlcPortalAppsToPortalApps(rows, d.duplicateResolution)
+	d.reportDuplicatePortalAppIDs(duplicates)
+
+	return d.validateSecretKeys(portalApps), nil
+}
+
+// selectPortalApps selects portal apps from the configured view, or from the base tables if no view is configured.
+func (d *GrovePostgresDriver) selectPortalApps(ctx context.Context) ([]sqlc.SelectPortalAppsRow, error) {
+	if d.portalAppsView != nil {
+		d.logger.Info().Str("view", d.portalAppsView.Name).Msg("💾 Executing SelectPortalApps query against view...")
+		return d.driver.selectPortalAppsFromView(ctx, d.portalAppsView.buildQuery(!d.lazyAuth))
+	}
+
+	d.logger.Info().Msg("💾 Executing SelectPortalApps query...")
+	return d.driver.SelectPortalApps(ctx, !d.lazyAuth)
+}
+
+// GetAuth fetches the auth settings of a single portal app from the Postgres database.
+//   - Selects from the configured view, if any, so the result matches GetPortalApps.
+//   - Duplicate rows are resolved the same way as in GetPortalApps, but are not reported.
+//   - Each query is bounded by the auth query timeout, as it runs on the request path.
+func (d *GrovePostgresDriver) GetAuth(portalAppID store.PortalAppID) (*store.Auth, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.authQueryTimeout)
+	defer cancel()
+
+	portalApps := make(map[store.PortalAppID]*store.PortalApp, 1)
+	addPortalApp := func(row sqlc.SelectPortalAppsRow) error {
+		addSQLCPortalApp(portalApps, row, d.duplicateResolution)
+		return nil
+	}
+
+	var err error
+	if d.portalAppsView != nil {
+		err = d.driver.streamPortalAppsFromView(ctx, d.portalAppsView.buildPortalAppQuery(), addPortalApp, string(portalAppID))
+	} else {
+		var rows []sqlc.SelectPortalAppAuthRow
+		rows, err = d.driver.SelectPortalAppAuth(ctx, string(portalAppID))
+		for _, row := range rows {
+			addSQLCPortalApp(portalApps, sqlcPortalAppAuthToPortalAppsRow(row), d.duplicateResolution)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch portal application auth: %w", err)
+	}
+
+	portalApp, ok := portalApps[portalAppID]
+	if !ok {
+		return nil, nil
+	}
+	return portalApp.Auth, nil
+}
+
+// streamPortalAppsFromDB loads the full set of PortalApps, converting each row as it is scanned.
+func (d *GrovePostgresDriver) streamPortalAppsFromDB(ctx context.Context) (map[store.PortalAppID]*store.PortalApp, error) {
+	portalApps := make(map[store.PortalAppID]*store.PortalApp)
+	var duplicates []store.PortalAppID
+	addPortalApp := func(row sqlc.SelectPortalAppsRow) error {
+		if addSQLCPortalApp(portalApps, row, d.duplicateResolution) {
+			duplicates = append(duplicates, store.PortalAppID(row.ID))
+		}
+		return nil
+	}
+
+	var err error
+	if d.portalAppsView != nil {
+		d.logger.Info().Str("view", d.portalAppsView.Name).Msg("💾 Streaming SelectPortalApps query against view...")
+		err = d.driver.streamPortalAppsFromView(ctx, d.portalAppsView.buildQuery(!d.lazyAuth), addPortalApp)
+	} else {
+		d.logger.Info().Msg("💾 Streaming SelectPortalApps query...")
+		err = d.driver.StreamPortalApps(ctx, !d.lazyAuth, addPortalApp)
+	}
+	if err != nil {
+		d.logger.Error().Err(err).Msg("failed to stream portal applications from database")
+		return nil, fmt.Errorf("failed to fetch portal applications: %w", err)
+	}
+
+	d.logger.Info().Int("num_rows", len(portalApps)).Msg("✅ Successfully streamed Portal Applications from Postgres")
+	d.reportDuplicatePortalAppIDs(duplicates)
+
+	return d.validateSecretKeys(portalApps), nil
+}
+
+// GetPlanLimits loads the default monthly relay limit of each plan type from the `plans` table.
+//
+// Used to set plan limits in the rate limit store (see ratelimit.WithPlanLimits).
+// Plan types with no row in the table are not included.
+func (d *GrovePostgresDriver) GetPlanLimits() (map[store.PlanType]int32, error) {
+	rows, err := d.driver.SelectPlanLimits(d.ctx)
+	if err != nil {
+		d.logger.Error().Err(err).Msg("failed to fetch plan limits from database")
+		return nil, fmt.Errorf("failed to fetch plan limits: %w", err)
+	}
+
+	planLimits := make(map[store.PlanType]int32, len(rows))
+	for _, row := range rows {
+		planLimits[store.PlanType(row.PlanType)] = row.MonthlyLimit
+	}
+
+	d.logger.Debug().Int("num_rows", len(rows)).Msg("✅ Successfully fetched plan limits from Postgres")
+
+	return planLimits, nil
 }
 
 // Close cleans up resources used by the data source.
+//   - In-flight queries (e.g. a long portal app load during shutdown) are canceled,
+//     as closing the DB pool otherwise waits for them to complete.
 func (d *GrovePostgresDriver) Close() {
+	d.cancel()
+
 	// The listener doesn't have a Close method, but when
 	// we close the DB pool it will close the connections
 	d.driver.DB.Close()
@@ -1,9 +1,11 @@
 package grove
 
 import (
+	"context"
 	"flag"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
 	"github.com/stretchr/testify/require"
@@ -15,8 +17,10 @@ var connectionString string
 
 func TestMain(m *testing.M) {
 	flag.Parse()
+
+	// Integration tests skip themselves in short mode, so no database is needed
 	if testing.Short() {
-		return
+		os.Exit(m.Run())
 	}
 
 	// Initialize the ephemeral postgres docker container
@@ -36,62 +40,87 @@ func Test_Integration_GetPortalApps(t *testing.T) {
 		t.Skip("skipping driver integration test")
 	}
 
+	expected := map[store.PortalAppID]*store.PortalApp{
+		"portal_app_1_no_auth": {
+			ID:        "portal_app_1_no_auth",
+			AccountID: "account_1",
+			PlanType:  PlanFree_DatabaseType,
+			PlanName:  "Free",
+			Auth:      nil, // No auth required
+			RateLimit: &store.RateLimit{},
+		},
+		"portal_app_2_static_key": {
+			ID:        "portal_app_2_static_key",
+			AccountID: "account_2",
+			PlanType:  PlanUnlimited_DatabaseType,
+			PlanName:  "Unlimited",
+			Auth: &store.Auth{
+				APIKeys: []string{"secret_key_2"},
+			},
+		},
+		"portal_app_3_static_key": {
+			ID:        "portal_app_3_static_key",
+			AccountID: "account_3",
+			PlanType:  PlanFree_DatabaseType,
+			PlanName:  "Free",
+			Auth: &store.Auth{
+				APIKeys: []string{"secret_key_3"},
+			},
+			RateLimit: &store.RateLimit{},
+		},
+		"portal_app_4_no_auth": {
+			ID:        "portal_app_4_no_auth",
+			AccountID: "account_1",
+			PlanType:  PlanFree_DatabaseType,
+			PlanName:  "Free",
+			Auth:      nil, // No auth required
+			RateLimit: &store.RateLimit{},
+		},
+		"portal_app_5_static_key": {
+			ID:        "portal_app_5_static_key",
+			AccountID: "account_2",
+			PlanType:  PlanUnlimited_DatabaseType,
+			PlanName:  "Unlimited",
+			Auth: &store.Auth{
+				APIKeys: []string{"secret_key_5"},
+			},
+		},
+		"portal_app_6_user_limit": {
+			ID:        "portal_app_6_user_limit",
+			AccountID: "account_4",
+			PlanType:  PlanUnlimited_DatabaseType,
+			PlanName:  "Unlimited",
+			Auth:      nil, // No auth required
+			RateLimit: &store.RateLimit{
+				MonthlyUserLimit: 10_000_000,
+			},
+		},
+	}
+
 	tests := []struct {
 		name     string
+		opts     []GrovePostgresDriverOption
 		expected map[store.PortalAppID]*store.PortalApp
 	}{
 		{
-			name: "should retrieve all portal appsdata correctly",
-			expected: map[store.PortalAppID]*store.PortalApp{
-				"portal_app_1_no_auth": {
-					ID:        "portal_app_1_no_auth",
-					AccountID: "account_1",
-					PlanType:  PlanFree_DatabaseType,
-					Auth:      nil, // No auth required
-					RateLimit: &store.RateLimit{},
-				},
-				"portal_app_2_static_key": {
-					ID:        "portal_app_2_static_key",
-					AccountID: "account_2",
-					PlanType:  PlanUnlimited_DatabaseType,
-					Auth: &store.Auth{
-						APIKey: "secret_key_2",
-					},
-				},
-				"portal_app_3_static_key": {
-					ID:        "portal_app_3_static_key",
-					AccountID: "account_3",
-					PlanType:  PlanFree_DatabaseType,
-					Auth: &store.Auth{
-						APIKey: "secret_key_3",
-					},
-					RateLimit: &store.RateLimit{},
-				},
-				"portal_app_4_no_auth": {
-					ID:        "portal_app_4_no_auth",
-					AccountID: "account_1",
-					PlanType:  PlanFree_DatabaseType,
-					Auth:      nil, // No auth required
-					RateLimit: &store.RateLimit{},
-				},
-				"portal_app_5_static_key": {
-					ID:        "portal_app_5_static_key",
-					AccountID: "account_2",
-					PlanType:  PlanUnlimited_DatabaseType,
-					Auth: &store.Auth{
-						APIKey: "secret_key_5",
-					},
-				},
-				"portal_app_6_user_limit": {
-					ID:        "portal_app_6_user_limit",
-					AccountID: "account_4",
-					PlanType:  PlanUnlimited_DatabaseType,
-					Auth:      nil, // No auth required
-					RateLimit: &store.RateLimit{
-						MonthlyUserLimit: 10_000_000,
+			name:     "should retrieve all portal appsdata correctly",
+			expected: expected,
+		},
+		{
+			name: "should retrieve all portal apps data correctly from a view with different column names",
+			opts: []GrovePostgresDriverOption{
+				WithPortalAppsView(PortalAppsView{
+					Name: "reporting.portal_apps",
+					Columns: PortalAppsViewColumns{
+						ID:                "app_id",
+						SecretKey:         "api_key",
+						SecretKeyRequired: "api_key_required",
+						Plan:              "plan_name",
+						MonthlyUserLimit:  "relay_limit",
 					},
-				},
+				}),
 			},
+			expected: expected,
 		},
 	}
 
@@ -99,12 +128,150 @@ func Test_Integration_GetPortalApps(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			c := require.New(t)
 
-			dataSource, err := NewGrovePostgresDriver(polyzero.NewLogger(), connectionString)
+			dataSource, err := NewGrovePostgresDriver(context.Background(), polyzero.NewLogger(), connectionString, test.opts...)
 			c.NoError(err)
 
 			authData, err := dataSource.GetPortalApps()
 			c.NoError(err)
+
+			// The auth fingerprint is derived from a database hash of the secret key, so it is only asserted to be set
+			for portalAppID, portalApp := range authData {
+				c.NotEmpty(portalApp.AuthFingerprint, "portal app %s", portalAppID)
+				portalApp.AuthFingerprint = ""
+			}
 			c.Equal(test.expected, authData)
 		})
 	}
 }
+
+func Test_Integration_GetPlanLimits(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping driver integration test")
+	}
+
+	c := require.New(t)
+
+	dataSource, err := NewGrovePostgresDriver(context.Background(), polyzero.NewLogger(), connectionString)
+	c.NoError(err)
+	defer dataSource.Close()
+
+	planLimits, err := dataSource.GetPlanLimits()
+	c.NoError(err)
+	c.Equal(map[store.PlanType]int32{
+		PlanFree_DatabaseType:      500_000,
+		PlanUnlimited_DatabaseType: 0,
+		"PLAN_PRO":                 5_000_000,
+	}, planLimits)
+}
+
+func Test_Integration_CloseCancelsInFlightQueries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping driver integration test")
+	}
+
+	c := require.New(t)
+
+	dataSource, err := NewGrovePostgresDriver(context.Background(), polyzero.NewLogger(), connectionString)
+	c.NoError(err)
+
+	// A query running with the driver's context, as every portal app and plan limit load does
+	queryErr := make(chan error, 1)
+	go func() {
+		_, err := dataSource.driver.DB.Exec(dataSource.ctx, "SELECT pg_sleep(30)")
+		queryErr <- err
+	}()
+
+	// Give the query time to start before closing
+	time.Sleep(200 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		dataSource.Close()
+		close(closed)
+	}()
+
+	select {
+	case err := <-queryErr:
+		c.ErrorIs(err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.FailNow("in-flight query was not canceled on Close")
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		c.FailNow("Close did not return once the in-flight query was canceled")
+	}
+}
+
+func Test_Integration_GetAuth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping driver integration test")
+	}
+
+	c := require.New(t)
+
+	dataSource, err := NewGrovePostgresDriver(context.Background(), polyzero.NewLogger(), connectionString)
+	c.NoError(err)
+	defer dataSource.Close()
+
+	portalApps, err := dataSource.GetPortalApps()
+	c.NoError(err)
+
+	// The auth fetched on demand matches the auth loaded with every portal app
+	for portalAppID, portalApp := range portalApps {
+		auth, err := dataSource.GetAuth(portalAppID)
+		c.NoError(err)
+		c.Equal(portalApp.Auth, auth, "portal app %s", portalAppID)
+	}
+
+	auth, err := dataSource.GetAuth("portal_app_unknown")
+	c.NoError(err)
+	c.Nil(auth)
+}
+
+func Test_Integration_GetAuthTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping driver integration test")
+	}
+
+	c := require.New(t)
+
+	dataSource, err := NewGrovePostgresDriver(context.Background(), polyzero.NewLogger(), connectionString, WithAuthQueryTimeout(time.Nanosecond))
+	c.NoError(err)
+	defer dataSource.Close()
+
+	// The query deadline elapses before the database responds, so the fetch fails rather than blocking the request
+	_, err = dataSource.GetAuth("portal_app_1_no_auth")
+	c.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func Test_Integration_GetPortalAppsLazyAuth(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping driver integration test")
+	}
+
+	c := require.New(t)
+
+	eagerDataSource, err := NewGrovePostgresDriver(context.Background(), polyzero.NewLogger(), connectionString)
+	c.NoError(err)
+	defer eagerDataSource.Close()
+
+	lazyDataSource, err := NewGrovePostgresDriver(context.Background(), polyzero.NewLogger(), connectionString, WithLazyAuth(true))
+	c.NoError(err)
+	defer lazyDataSource.Close()
+
+	eagerPortalApps, err := eagerDataSource.GetPortalApps()
+	c.NoError(err)
+	lazyPortalApps, err := lazyDataSource.GetPortalApps()
+	c.NoError(err)
+	c.Len(lazyPortalApps, len(eagerPortalApps))
+
+	// Portal apps are loaded without their API keys, but with the same auth fingerprint
+	for portalAppID, lazyPortalApp := range lazyPortalApps {
+		if lazyPortalApp.Auth != nil {
+			c.Empty(lazyPortalApp.Auth.APIKeys, "portal app %s", portalAppID)
+		}
+		c.Equal(eagerPortalApps[portalAppID].AuthFingerprint, lazyPortalApp.AuthFingerprint, "portal app %s", portalAppID)
+	}
+}
@@ -0,0 +1,45 @@
+package grove
+
+import (
+	"fmt"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// DuplicatePortalAppIDResolution determines which row is kept when the data source
+// returns more than one row with the same portal app ID (e.g. a view joining duplicate settings rows).
+type DuplicatePortalAppIDResolution string
+
+const (
+	// DuplicatePortalAppIDLastWins keeps the last row returned for a portal app ID.
+	DuplicatePortalAppIDLastWins DuplicatePortalAppIDResolution = "last_wins"
+	// DuplicatePortalAppIDFirstWins keeps the first row returned for a portal app ID.
+	DuplicatePortalAppIDFirstWins DuplicatePortalAppIDResolution = "first_wins"
+)
+
+// defaultDuplicatePortalAppIDResolution preserves the original behavior of
+// the portal apps map keeping the last row for each portal app ID.
+const defaultDuplicatePortalAppIDResolution = DuplicatePortalAppIDLastWins
+
+// ParseDuplicatePortalAppIDResolution parses a DuplicatePortalAppIDResolution.
+//   - Valid values are "last_wins" and "first_wins"
+func ParseDuplicatePortalAppIDResolution(s string) (DuplicatePortalAppIDResolution, error) {
+	switch resolution := DuplicatePortalAppIDResolution(s); resolution {
+	case DuplicatePortalAppIDLastWins, DuplicatePortalAppIDFirstWins:
+		return resolution, nil
+	default:
+		return "", fmt.Errorf("invalid duplicate portal app ID resolution %q: must be one of last_wins, first_wins", s)
+	}
+}
+
+// reportDuplicatePortalAppIDs logs and counts each duplicate portal app ID returned by the data source.
+func (d *GrovePostgresDriver) reportDuplicatePortalAppIDs(duplicates []store.PortalAppID) {
+	for _, portalAppID := range duplicates {
+		metrics.RecordDuplicatePortalAppID()
+		d.logger.Warn().
+			Str("portal_app_id", string(portalAppID)).
+			Str("resolution", string(d.duplicateResolution)).
+			Msg("⚠️ data source returned duplicate portal app ID")
+	}
+}
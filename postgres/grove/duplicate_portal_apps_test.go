@@ -0,0 +1,100 @@
+package grove
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/postgres/grove/sqlc"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func Test_ParseDuplicatePortalAppIDResolution(t *testing.T) {
+	c := require.New(t)
+
+	resolution, err := ParseDuplicatePortalAppIDResolution("first_wins")
+	c.NoError(err)
+	c.Equal(DuplicatePortalAppIDFirstWins, resolution)
+
+	resolution, err = ParseDuplicatePortalAppIDResolution("last_wins")
+	c.NoError(err)
+	c.Equal(DuplicatePortalAppIDLastWins, resolution)
+
+	_, err = ParseDuplicatePortalAppIDResolution("newest_wins")
+	c.Error(err)
+}
+
+func Test_sqlcPortalAppsToPortalApps_DuplicatePortalAppIDs(t *testing.T) {
+	rows := []sqlc.SelectPortalAppsRow{
+		{ID: "portal_app_1", AccountID: pgtype.Text{String: "account_first", Valid: true}},
+		{ID: "portal_app_2", AccountID: pgtype.Text{String: "account_2", Valid: true}},
+		{ID: "portal_app_1", AccountID: pgtype.Text{String: "account_second", Valid: true}},
+		{ID: "portal_app_1", AccountID: pgtype.Text{String: "account_last", Valid: true}},
+	}
+
+	tests := []struct {
+		name              string
+		resolution        DuplicatePortalAppIDResolution
+		expectedAccountID store.AccountID
+	}{
+		{
+			name:              "should keep the last row with last_wins",
+			resolution:        DuplicatePortalAppIDLastWins,
+			expectedAccountID: "account_last",
+		},
+		{
+			name:              "should keep the first row with first_wins",
+			resolution:        DuplicatePortalAppIDFirstWins,
+			expectedAccountID: "account_first",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			portalApps, duplicates := sqlcPortalAppsToPortalApps(rows, test.resolution)
+			c.Len(portalApps, 2)
+			c.Equal(test.expectedAccountID, portalApps["portal_app_1"].AccountID)
+			c.Equal(store.AccountID("account_2"), portalApps["portal_app_2"].AccountID)
+
+			// Each duplicate row is reported, regardless of the resolution
+			c.Equal([]store.PortalAppID{"portal_app_1", "portal_app_1"}, duplicates)
+		})
+	}
+}
+
+func Test_reportDuplicatePortalAppIDs(t *testing.T) {
+	c := require.New(t)
+
+	driver := &GrovePostgresDriver{
+		logger:              polyzero.NewLogger(),
+		duplicateResolution: DuplicatePortalAppIDFirstWins,
+	}
+
+	countBefore := getDuplicatePortalAppIDCount(t)
+	driver.reportDuplicatePortalAppIDs([]store.PortalAppID{"portal_app_1", "portal_app_1", "portal_app_2"})
+	c.Equal(float64(3), getDuplicatePortalAppIDCount(t)-countBefore)
+
+	driver.reportDuplicatePortalAppIDs(nil)
+	c.Equal(float64(3), getDuplicatePortalAppIDCount(t)-countBefore)
+}
+
+// getDuplicatePortalAppIDCount returns the number of duplicate portal app ID rows counted.
+func getDuplicatePortalAppIDCount(t *testing.T) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_duplicate_portal_app_id_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
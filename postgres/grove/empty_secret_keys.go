@@ -0,0 +1,49 @@
+package grove
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// validateSecretKeys flags portal apps whose secret key is required but empty in the database.
+// They are converted with an Auth holding no API key (see getAuthDetails), so they would silently
+// become public unless requests to misconfigured portal apps are denied by the auth handler.
+//   - Updates the count of portal apps with an empty secret key metric.
+//   - Removes them from the portal apps map if excludeEmptySecretKeys is set.
+func (d *GrovePostgresDriver) validateSecretKeys(portalApps map[store.PortalAppID]*store.PortalApp) map[store.PortalAppID]*store.PortalApp {
+	var emptySecretKeyIDs []string
+	for portalAppID, portalApp := range portalApps {
+		if !hasEmptySecretKey(portalApp) {
+			continue
+		}
+		emptySecretKeyIDs = append(emptySecretKeyIDs, string(portalAppID))
+		if d.excludeEmptySecretKeys {
+			delete(portalApps, portalAppID)
+		}
+	}
+
+	metrics.UpdateStoreSize(metrics.PortalAppsEmptySecretKeyStoreType, float64(len(emptySecretKeyIDs)))
+
+	if len(emptySecretKeyIDs) > 0 {
+		sort.Strings(emptySecretKeyIDs)
+		d.logger.Error().
+			Int("portal_app_count", len(emptySecretKeyIDs)).
+			Str("portal_app_ids", strings.Join(emptySecretKeyIDs, ",")).
+			Bool("excluded", d.excludeEmptySecretKeys).
+			Msg("🚨 Found portal apps requiring a secret key with an empty secret key")
+	}
+
+	return portalApps
+}
+
+// hasEmptySecretKey returns true if the converted portal app requires a secret key but has none.
+//   - Grove Portal apps only have an Auth if their secret key is required, and no HMAC secret.
+//   - Portal apps loaded without their secret keys (see WithLazyAuth) have no API key either,
+//     so an empty secret key is told apart by its auth fingerprint.
+func hasEmptySecretKey(portalApp *store.PortalApp) bool {
+	return portalApp.Auth != nil && len(portalApp.Auth.APIKeys) == 0 &&
+		portalApp.AuthFingerprint == authFingerprintEmptySecretKey
+}
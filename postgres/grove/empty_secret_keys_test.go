@@ -0,0 +1,112 @@
+package grove
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/postgres/grove/sqlc"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// getEmptySecretKeyTestRows returns rows with a secret key, a secret key loaded only as its hash (see WithLazyAuth),
+// no secret key required, and a required but empty or NULL secret key.
+func getEmptySecretKeyTestRows() []sqlc.SelectPortalAppsRow {
+	return []sqlc.SelectPortalAppsRow{
+		{
+			ID:                "portal_app_static_key",
+			SecretKeyRequired: pgtype.Bool{Bool: true, Valid: true},
+			SecretKey:         pgtype.Text{String: "secret_key", Valid: true},
+			SecretKeyHash:     pgtype.Int8{Int64: 1234567890, Valid: true},
+		},
+		{
+			ID:                "portal_app_lazy_secret_key",
+			SecretKeyRequired: pgtype.Bool{Bool: true, Valid: true},
+			SecretKeyHash:     pgtype.Int8{Int64: 1234567890, Valid: true},
+		},
+		{
+			ID:                "portal_app_no_auth",
+			SecretKeyRequired: pgtype.Bool{Bool: false, Valid: true},
+		},
+		{
+			ID:                "portal_app_empty_secret_key",
+			SecretKeyRequired: pgtype.Bool{Bool: true, Valid: true},
+			SecretKey:         pgtype.Text{String: "", Valid: true},
+		},
+		{
+			ID:                "portal_app_null_secret_key",
+			SecretKeyRequired: pgtype.Bool{Bool: true, Valid: true},
+		},
+	}
+}
+
+func Test_validateSecretKeys(t *testing.T) {
+	tests := []struct {
+		name                   string
+		excludeEmptySecretKeys bool
+		expectedPortalAppIDs   []store.PortalAppID
+	}{
+		{
+			name:                   "should keep portal apps with an empty secret key if exclusion is disabled",
+			excludeEmptySecretKeys: false,
+			expectedPortalAppIDs:   []store.PortalAppID{"portal_app_static_key", "portal_app_lazy_secret_key", "portal_app_no_auth", "portal_app_empty_secret_key", "portal_app_null_secret_key"},
+		},
+		{
+			name:                   "should exclude portal apps with an empty secret key if exclusion is enabled",
+			excludeEmptySecretKeys: true,
+			expectedPortalAppIDs:   []store.PortalAppID{"portal_app_static_key", "portal_app_lazy_secret_key", "portal_app_no_auth"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			driver := &GrovePostgresDriver{
+				logger:                 polyzero.NewLogger(),
+				excludeEmptySecretKeys: test.excludeEmptySecretKeys,
+			}
+
+			portalApps, _ := sqlcPortalAppsToPortalApps(getEmptySecretKeyTestRows(), DuplicatePortalAppIDLastWins)
+			portalApps = driver.validateSecretKeys(portalApps)
+
+			var portalAppIDs []store.PortalAppID
+			for portalAppID := range portalApps {
+				portalAppIDs = append(portalAppIDs, portalAppID)
+			}
+			c.ElementsMatch(test.expectedPortalAppIDs, portalAppIDs)
+
+			// Flagged portal apps are counted whether or not they are excluded
+			c.Equal(float64(2), getEmptySecretKeyStoreSize(t))
+
+			// Flagged portal apps are loaded with no API key
+			if !test.excludeEmptySecretKeys {
+				c.Equal(&store.Auth{}, portalApps["portal_app_empty_secret_key"].Auth)
+				c.Equal(&store.Auth{}, portalApps["portal_app_null_secret_key"].Auth)
+			}
+		})
+	}
+}
+
+// getEmptySecretKeyStoreSize returns the recorded count of portal apps with an empty secret key.
+func getEmptySecretKeyStoreSize(t *testing.T) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_store_size_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "store_type" && label.GetValue() == "portal_apps_empty_secret_key" {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -1,15 +1,33 @@
 package grove
 
 import (
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
 	"github.com/buildwithgrove/path-external-auth-server/postgres/grove/sqlc"
 	"github.com/buildwithgrove/path-external-auth-server/store"
 )
 
+// The auth fingerprints of portal apps not requiring a secret key, and requiring one with an empty secret key.
+// Any other portal app's fingerprint is the hash of its secret key.
+const (
+	authFingerprintNoAuth         = "no_auth"
+	authFingerprintEmptySecretKey = "empty_secret_key"
+)
+
 const (
 	PlanFree_DatabaseType      store.PlanType = "PLAN_FREE"
 	PlanUnlimited_DatabaseType store.PlanType = "PLAN_UNLIMITED"
 )
 
+// planNames are the human-readable names of the Grove Portal plan types.
+// The Grove Portal database has no plan name column, so names are only known for these plan types.
+var planNames = map[store.PlanType]string{
+	PlanFree_DatabaseType:      "Free",
+	PlanUnlimited_DatabaseType: "Unlimited",
+}
+
 // portalApplicationRow is a struct that represents a row from the portal_applications table
 // in the existing Grove Portal Database. It is necessary to convert the existing `portal_applications`
 // table schema to the new `PortalApp` struct expected by the PATH Go External Authorization Server.
@@ -18,6 +36,7 @@ type portalApplicationRow struct {
 	AccountID         string         `json:"account_id"`          // The PortalApp AccountID maps to the PortalApp.Metadata.AccountId
 	SecretKey         string         `json:"secret_key"`          // The PortalApp SecretKey maps to the PortalApp.Auth.AuthType.StaticApiKey.ApiKey
 	SecretKeyRequired bool           `json:"secret_key_required"` // The PortalApp SecretKeyRequired determines whether the auth type is StaticApiKey or NoAuth
+	SecretKeyHash     string         `json:"secret_key_hash"`     // The PortalApp SecretKeyHash maps to the PortalApp.AuthFingerprint; empty if the secret key is empty
 	MonthlyUserLimit  int32          `json:"monthly_relay_limit"` // The PortalApp MonthlyUserLimit maps to the PortalApp.Metadata.MonthlyUserLimit
 	Plan              store.PlanType `json:"plan"`                // The PortalApp Plan maps to the PortalApp.Metadata.PlanType
 }
@@ -32,31 +51,75 @@ func sqlcPortalAppsToPortalAppRow(r sqlc.SelectPortalAppsRow) *portalApplication
 		AccountID:         r.AccountID.String,
 		SecretKey:         r.SecretKey.String,
 		SecretKeyRequired: r.SecretKeyRequired.Bool,
+		SecretKeyHash:     getSecretKeyHash(r.SecretKeyHash),
 		Plan:              store.PlanType(r.Plan.String),
 		MonthlyUserLimit:  r.MonthlyUserLimit.Int32,
 	}
 }
 
+// sqlcPortalAppAuthToPortalAppsRow converts a row from the `SelectPortalAppAuth` query
+// to a `SelectPortalAppsRow` with only its auth columns set, so it is converted like a full row.
+func sqlcPortalAppAuthToPortalAppsRow(r sqlc.SelectPortalAppAuthRow) sqlc.SelectPortalAppsRow {
+	return sqlc.SelectPortalAppsRow{
+		ID:                r.ID,
+		SecretKey:         r.SecretKey,
+		SecretKeyRequired: r.SecretKeyRequired,
+	}
+}
+
+// convertToPortalApp converts the row to a store.PortalApp.
+//   - AllowedCIDRs is deliberately left unset: the Grove Portal database has no allowed CIDRs column,
+//     so IP allowlists are only supported by the PORTAL_APPS_DIRECTORY data source (see README.md).
 func (r *portalApplicationRow) convertToPortalApp() *store.PortalApp {
 	return &store.PortalApp{
 		ID:        store.PortalAppID(r.ID),
 		AccountID: store.AccountID(r.AccountID),
 		PlanType:  store.PlanType(r.Plan),
+		PlanName:  planNames[r.Plan],
 		Auth:      r.getAuthDetails(),
 		RateLimit: r.getRateLimitDetails(),
+
+		AuthFingerprint: r.getAuthFingerprint(),
 	}
 }
 
+// getAuthDetails returns the portal app's API key auth, if required.
+//   - The Grove Portal database has a single secret key per portal app, so APIKeys has at most one key.
+//   - An empty secret key is not added, so the portal app is detected as misconfigured.
 func (r *portalApplicationRow) getAuthDetails() *store.Auth {
 	if r.SecretKeyRequired {
-		return &store.Auth{
-			APIKey: r.SecretKey,
+		auth := &store.Auth{}
+		if r.SecretKey != "" {
+			auth.APIKeys = []string{r.SecretKey}
 		}
+		return auth
 	}
 
 	return nil
 }
 
+// getAuthFingerprint returns the fingerprint of the portal app's auth, which changes whenever
+// the secret key or whether it is required does, so lazily fetched auth is only evicted on change.
+//   - The secret key hash is loaded even when the secret key is not (see WithLazyAuth).
+func (r *portalApplicationRow) getAuthFingerprint() string {
+	switch {
+	case !r.SecretKeyRequired:
+		return authFingerprintNoAuth
+	case r.SecretKeyHash == "":
+		return authFingerprintEmptySecretKey
+	default:
+		return r.SecretKeyHash
+	}
+}
+
+// getSecretKeyHash formats the secret key hash, or returns an empty string if the secret key is empty.
+func getSecretKeyHash(secretKeyHash pgtype.Int8) string {
+	if !secretKeyHash.Valid {
+		return ""
+	}
+	return strconv.FormatInt(secretKeyHash.Int64, 10)
+}
+
 func (r *portalApplicationRow) getRateLimitDetails() *store.RateLimit {
 	// The following scenarios are rate limited:
 	// 		- PLAN_FREE
@@ -70,12 +133,37 @@ func (r *portalApplicationRow) getRateLimitDetails() *store.RateLimit {
 	return nil
 }
 
-func sqlcPortalAppsToPortalApps(rows []sqlc.SelectPortalAppsRow) map[store.PortalAppID]*store.PortalApp {
+// sqlcPortalAppsToPortalApps converts rows from the `SelectPortalAppsRow` query to a portal apps map.
+// Returns the portal app ID of every row whose ID was already converted, once per duplicate row.
+func sqlcPortalAppsToPortalApps(
+	rows []sqlc.SelectPortalAppsRow,
+	resolution DuplicatePortalAppIDResolution,
+) (map[store.PortalAppID]*store.PortalApp, []store.PortalAppID) {
 	portalApps := make(map[store.PortalAppID]*store.PortalApp, len(rows))
+	var duplicates []store.PortalAppID
 	for _, row := range rows {
-		portalAppRow := sqlcPortalAppsToPortalAppRow(row)
-		portalApps[store.PortalAppID(portalAppRow.ID)] = portalAppRow.convertToPortalApp()
+		if addSQLCPortalApp(portalApps, row, resolution) {
+			duplicates = append(duplicates, store.PortalAppID(row.ID))
+		}
+	}
+
+	return portalApps, duplicates
+}
+
+// addSQLCPortalApp converts a row from the `SelectPortalAppsRow` query and adds it to the portal apps map.
+//   - If the portal app ID is already in the map, the resolution determines which portal app is kept
+//   - Returns true if the portal app ID was already in the map
+func addSQLCPortalApp(
+	portalApps map[store.PortalAppID]*store.PortalApp,
+	row sqlc.SelectPortalAppsRow,
+	resolution DuplicatePortalAppIDResolution,
+) bool {
+	portalAppID := store.PortalAppID(row.ID)
+	_, duplicate := portalApps[portalAppID]
+	if duplicate && resolution == DuplicatePortalAppIDFirstWins {
+		return true
 	}
 
-	return portalApps
+	portalApps[portalAppID] = sqlcPortalAppsToPortalAppRow(row).convertToPortalApp()
+	return duplicate
 }
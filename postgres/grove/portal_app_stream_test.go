@@ -0,0 +1,162 @@
+package grove
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/postgres/grove/sqlc"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// fakePortalAppsRows implements pgx.Rows, returning numRows SelectPortalApps rows.
+// Row values are pre-allocated so only the loading under test allocates.
+type fakePortalAppsRows struct {
+	ids        []string
+	accountIDs []string
+	current    int
+}
+
+var _ pgx.Rows = &fakePortalAppsRows{}
+
+func newFakePortalAppsRows(numRows int) *fakePortalAppsRows {
+	rows := &fakePortalAppsRows{
+		ids:        make([]string, numRows),
+		accountIDs: make([]string, numRows),
+		current:    -1,
+	}
+	for i := range numRows {
+		rows.ids[i] = fmt.Sprintf("portal_app_%d", i)
+		rows.accountIDs[i] = fmt.Sprintf("account_%d", i%1000)
+	}
+	return rows
+}
+
+func (r *fakePortalAppsRows) Next() bool {
+	r.current++
+	return r.current < len(r.ids)
+}
+
+func (r *fakePortalAppsRows) Scan(dest ...any) error {
+	if len(dest) != 7 {
+		return fmt.Errorf("expected 7 scan destinations, got %d", len(dest))
+	}
+	*dest[0].(*string) = r.ids[r.current]
+	*dest[1].(*pgtype.Text) = pgtype.Text{String: "secret_key", Valid: true}
+	*dest[2].(*pgtype.Bool) = pgtype.Bool{Bool: r.current%2 == 0, Valid: true}
+	*dest[3].(*pgtype.Text) = pgtype.Text{String: r.accountIDs[r.current], Valid: true}
+	*dest[4].(*pgtype.Text) = pgtype.Text{String: string(PlanFree_DatabaseType), Valid: true}
+	*dest[5].(*pgtype.Int4) = pgtype.Int4{}
+	*dest[6].(*pgtype.Int8) = pgtype.Int8{Int64: int64(r.current), Valid: true}
+	return nil
+}
+
+func (r *fakePortalAppsRows) Close()                                       {}
+func (r *fakePortalAppsRows) Err() error                                   { return nil }
+func (r *fakePortalAppsRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakePortalAppsRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakePortalAppsRows) Values() ([]any, error)                       { return nil, nil }
+func (r *fakePortalAppsRows) RawValues() [][]byte                          { return nil }
+func (r *fakePortalAppsRows) Conn() *pgx.Conn                              { return nil }
+
+// loadPortalAppsBuffered mirrors the default load path: all rows are loaded into a slice, then converted.
+func loadPortalAppsBuffered(rows pgx.Rows) (map[store.PortalAppID]*store.PortalApp, error) {
+	var items []sqlc.SelectPortalAppsRow
+	if err := sqlc.ScanPortalAppsRows(rows, func(row sqlc.SelectPortalAppsRow) error {
+		items = append(items, row)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	portalApps, _ := sqlcPortalAppsToPortalApps(items, DuplicatePortalAppIDLastWins)
+	return portalApps, nil
+}
+
+// loadPortalAppsStreamed mirrors the streaming load path: each row is converted as it is scanned.
+func loadPortalAppsStreamed(rows pgx.Rows) (map[store.PortalAppID]*store.PortalApp, error) {
+	portalApps := make(map[store.PortalAppID]*store.PortalApp)
+	if err := sqlc.ScanPortalAppsRows(rows, func(row sqlc.SelectPortalAppsRow) error {
+		addSQLCPortalApp(portalApps, row, DuplicatePortalAppIDLastWins)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return portalApps, nil
+}
+
+func Test_StreamingLoad(t *testing.T) {
+	const numRows = 50_000
+
+	t.Run("should load the same portal apps as the buffered load path", func(t *testing.T) {
+		c := require.New(t)
+
+		buffered, err := loadPortalAppsBuffered(newFakePortalAppsRows(numRows))
+		c.NoError(err)
+		streamed, err := loadPortalAppsStreamed(newFakePortalAppsRows(numRows))
+		c.NoError(err)
+
+		c.Len(streamed, numRows)
+		c.Equal(buffered, streamed)
+	})
+
+	t.Run("should allocate less memory than the buffered load path", func(t *testing.T) {
+		c := require.New(t)
+
+		bufferedBytes := measureAllocatedBytes(t, loadPortalAppsBuffered, newFakePortalAppsRows(numRows))
+		streamedBytes := measureAllocatedBytes(t, loadPortalAppsStreamed, newFakePortalAppsRows(numRows))
+
+		t.Logf("buffered: %d bytes, streamed: %d bytes", bufferedBytes, streamedBytes)
+		c.Less(streamedBytes, bufferedBytes)
+	})
+}
+
+// measureAllocatedBytes returns the bytes allocated while loading portal apps from rows.
+func measureAllocatedBytes(
+	t *testing.T,
+	load func(pgx.Rows) (map[store.PortalAppID]*store.PortalApp, error),
+	rows pgx.Rows,
+) uint64 {
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	portalApps, err := load(rows)
+	runtime.ReadMemStats(&after)
+
+	require.NoError(t, err)
+	runtime.KeepAlive(portalApps)
+
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func Benchmark_LoadPortalApps(b *testing.B) {
+	const numRows = 100_000
+
+	benchmarks := []struct {
+		name string
+		load func(pgx.Rows) (map[store.PortalAppID]*store.PortalApp, error)
+	}{
+		{name: "buffered", load: loadPortalAppsBuffered},
+		{name: "streamed", load: loadPortalAppsStreamed},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				b.StopTimer()
+				rows := newFakePortalAppsRows(numRows)
+				b.StartTimer()
+
+				if _, err := bm.load(rows); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ func Test_sqlcPortalAppsToProto(t *testing.T) {
 					},
 					SecretKeyRequired: pgtype.Bool{Bool: true, Valid: true},
 					SecretKey:         pgtype.Text{String: "secret_key_1", Valid: true},
+					SecretKeyHash:     pgtype.Int8{Int64: -1234567890, Valid: true},
 				},
 				{
 					ID:        "portal_app_2_no_auth",
@@ -40,22 +41,46 @@ func Test_sqlcPortalAppsToProto(t *testing.T) {
 					SecretKeyRequired: pgtype.Bool{Bool: false, Valid: true},
 					SecretKey:         pgtype.Text{String: "secret_key_2", Valid: true},
 				},
+				{
+					ID:        "portal_app_5_empty_secret_key",
+					AccountID: pgtype.Text{String: "account_5", Valid: true},
+					Plan: pgtype.Text{
+						String: string(PlanUnlimited_DatabaseType),
+						Valid:  true,
+					},
+					SecretKeyRequired: pgtype.Bool{Bool: true, Valid: true},
+					SecretKey:         pgtype.Text{String: "", Valid: true},
+				},
 			},
 			expected: map[store.PortalAppID]*store.PortalApp{
 				"portal_app_1_static_key": {
 					ID:        "portal_app_1_static_key",
 					AccountID: "account_1",
 					PlanType:  PlanUnlimited_DatabaseType,
+					PlanName:  "Unlimited",
 					Auth: &store.Auth{
-						APIKey: "secret_key_1",
+						APIKeys: []string{"secret_key_1"},
 					},
+					AuthFingerprint: "-1234567890",
 				},
 				"portal_app_2_no_auth": {
 					ID:        "portal_app_2_no_auth",
 					AccountID: "account_2",
 					PlanType:  PlanFree_DatabaseType,
+					PlanName:  "Free",
 					Auth:      nil, // No auth required
-					RateLimit: &store.RateLimit{},
+
+					AuthFingerprint: authFingerprintNoAuth,
+					RateLimit:       &store.RateLimit{},
+				},
+				"portal_app_5_empty_secret_key": {
+					ID:        "portal_app_5_empty_secret_key",
+					AccountID: "account_5",
+					PlanType:  PlanUnlimited_DatabaseType,
+					PlanName:  "Unlimited",
+					Auth:      &store.Auth{}, // Auth required, but no API key
+
+					AuthFingerprint: authFingerprintEmptySecretKey,
 				},
 			},
 			wantErr: false,
@@ -64,8 +89,9 @@ func Test_sqlcPortalAppsToProto(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			result := sqlcPortalAppsToPortalApps(test.rows)
+			result, duplicates := sqlcPortalAppsToPortalApps(test.rows, DuplicatePortalAppIDLastWins)
 			require.Equal(t, test.expected, result)
+			require.Empty(t, duplicates)
 		})
 	}
 }
@@ -0,0 +1,159 @@
+package grove
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/buildwithgrove/path-external-auth-server/postgres/grove/sqlc"
+)
+
+// PortalAppsView configures the driver to select portal apps from a single table or view,
+// rather than joining the base Grove Portal tables in SelectPortalApps.
+//   - The view must have one row per portal app and exclude deleted portal apps
+//   - Column types must be compatible with the base tables (see sqlc/grove_schema.sql)
+type PortalAppsView struct {
+	// Name of the table or view, optionally schema-qualified (e.g. "reporting.portal_apps")
+	Name string
+	// Columns maps each SelectPortalApps column to a column of the view
+	Columns PortalAppsViewColumns
+}
+
+// PortalAppsViewColumns maps each column selected by SelectPortalApps to a column of the view.
+// Empty fields default to the SelectPortalApps column name (e.g. "secret_key").
+type PortalAppsViewColumns struct {
+	ID                string
+	SecretKey         string
+	SecretKeyRequired string
+	AccountID         string
+	Plan              string
+	MonthlyUserLimit  string
+}
+
+// ParsePortalAppsViewColumns parses a comma-separated list of `<column>:<view column>` pairs.
+//
+//   - Example: "id:app_id,plan:plan_name,secret_key_required:requires_key"
+//   - Valid columns are "id", "secret_key", "secret_key_required", "account_id",
+//     "plan" and "monthly_user_limit"
+func ParsePortalAppsViewColumns(s string) (PortalAppsViewColumns, error) {
+	var columns PortalAppsViewColumns
+	fields := columns.fields()
+
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(s, ",") {
+		column, viewColumn, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return PortalAppsViewColumns{}, fmt.Errorf("invalid column mapping %q: expected <column>:<view column>", pair)
+		}
+
+		column, viewColumn = strings.TrimSpace(column), strings.TrimSpace(viewColumn)
+		field, ok := fields[column]
+		if !ok {
+			return PortalAppsViewColumns{}, fmt.Errorf("invalid column %q: must be one of %s", column, strings.Join(portalAppsViewColumnNames, ", "))
+		}
+		if viewColumn == "" {
+			return PortalAppsViewColumns{}, fmt.Errorf("invalid column mapping %q: empty view column", pair)
+		}
+		if seen[column] {
+			return PortalAppsViewColumns{}, fmt.Errorf("duplicate column mapping for %q", column)
+		}
+		seen[column] = true
+
+		*field = viewColumn
+	}
+
+	return columns, nil
+}
+
+// portalAppsViewColumnNames are the columns selected by SelectPortalApps, in scan order.
+// The secret_key_hash column is selected last, computed from the secret_key column.
+var portalAppsViewColumnNames = []string{
+	"id",
+	"secret_key",
+	"secret_key_required",
+	"account_id",
+	"plan",
+	"monthly_user_limit",
+}
+
+// fields returns a pointer to each field, keyed by its SelectPortalApps column name.
+func (c *PortalAppsViewColumns) fields() map[string]*string {
+	return map[string]*string{
+		"id":                  &c.ID,
+		"secret_key":          &c.SecretKey,
+		"secret_key_required": &c.SecretKeyRequired,
+		"account_id":          &c.AccountID,
+		"plan":                &c.Plan,
+		"monthly_user_limit":  &c.MonthlyUserLimit,
+	}
+}
+
+// buildQuery returns the query selecting portal apps from the view.
+//   - Identifiers are quoted, so the view and column names are matched case-sensitively.
+//   - Each column is aliased to its SelectPortalApps column name.
+//   - The secret key is selected as NULL unless includeSecretKeys is set; its hash is always selected, as in SelectPortalApps.
+//
+// Example:
+//
+//	SELECT "app_id" AS id, "secret_key" AS secret_key, ..., hashtextextended(NULLIF("secret_key", ''), 0) AS secret_key_hash FROM "reporting"."portal_apps"
+func (v PortalAppsView) buildQuery(includeSecretKeys bool) string {
+	selectColumns := make([]string, 0, len(portalAppsViewColumnNames)+1)
+	for _, column := range portalAppsViewColumnNames {
+		if column == "secret_key" && !includeSecretKeys {
+			selectColumns = append(selectColumns, "NULL::text AS secret_key")
+			continue
+		}
+		selectColumns = append(selectColumns, fmt.Sprintf("%s AS %s", v.viewColumn(column), column))
+	}
+	selectColumns = append(selectColumns, fmt.Sprintf("hashtextextended(NULLIF(%s, ''), 0) AS secret_key_hash", v.viewColumn("secret_key")))
+
+	return fmt.Sprintf(
+		"SELECT %s FROM %s",
+		strings.Join(selectColumns, ", "),
+		pgx.Identifier(strings.Split(v.Name, ".")).Sanitize(),
+	)
+}
+
+// buildPortalAppQuery returns the query selecting a single portal app, including its secret key, from the view, by the portal app ID argument.
+//
+// Example:
+//
+//	SELECT "app_id" AS id, ... FROM "reporting"."portal_apps" WHERE "app_id" = $1
+func (v PortalAppsView) buildPortalAppQuery() string {
+	return fmt.Sprintf("%s WHERE %s = $1", v.buildQuery(true), v.viewColumn("id"))
+}
+
+// viewColumn returns the quoted view column mapped to the SelectPortalApps column,
+// defaulting to the SelectPortalApps column name.
+func (v PortalAppsView) viewColumn(column string) string {
+	viewColumn := *v.Columns.fields()[column]
+	if viewColumn == "" {
+		viewColumn = column
+	}
+	return pgx.Identifier{viewColumn}.Sanitize()
+}
+
+// selectPortalAppsFromView runs the view query, scanning rows into the same
+// row type as SelectPortalApps so the existing conversion can be reused.
+func (d *postgresDriver) selectPortalAppsFromView(ctx context.Context, query string) ([]sqlc.SelectPortalAppsRow, error) {
+	var items []sqlc.SelectPortalAppsRow
+	err := d.streamPortalAppsFromView(ctx, query, func(row sqlc.SelectPortalAppsRow) error {
+		items = append(items, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// streamPortalAppsFromView runs the view query with its arguments, calling fn for each row as it is scanned.
+func (d *postgresDriver) streamPortalAppsFromView(ctx context.Context, query string, fn func(sqlc.SelectPortalAppsRow) error, args ...any) error {
+	rows, err := d.DB.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return sqlc.ScanPortalAppsRows(rows, fn)
+}
@@ -0,0 +1,122 @@
+package grove
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParsePortalAppsViewColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected PortalAppsViewColumns
+		wantErr  bool
+	}{
+		{
+			name:  "should parse column mappings with whitespace",
+			input: "id:app_id, plan : plan_name,secret_key_required:api_key_required",
+			expected: PortalAppsViewColumns{
+				ID:                "app_id",
+				Plan:              "plan_name",
+				SecretKeyRequired: "api_key_required",
+			},
+		},
+		{
+			name:    "should error on unknown column",
+			input:   "deleted:is_deleted",
+			wantErr: true,
+		},
+		{
+			name:    "should error on missing view column",
+			input:   "id",
+			wantErr: true,
+		},
+		{
+			name:    "should error on empty view column",
+			input:   "id:",
+			wantErr: true,
+		},
+		{
+			name:    "should error on duplicate column",
+			input:   "id:app_id,id:portal_app_id",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			columns, err := ParsePortalAppsViewColumns(test.input)
+			if test.wantErr {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.expected, columns)
+		})
+	}
+}
+
+func Test_PortalAppsView_buildQuery(t *testing.T) {
+	tests := []struct {
+		name              string
+		view              PortalAppsView
+		includeSecretKeys bool
+		expected          string
+	}{
+		{
+			name:              "should default unmapped columns to the SelectPortalApps column names",
+			view:              PortalAppsView{Name: "portal_apps"},
+			includeSecretKeys: true,
+			expected: `SELECT "id" AS id, "secret_key" AS secret_key, "secret_key_required" AS secret_key_required, ` +
+				`"account_id" AS account_id, "plan" AS plan, "monthly_user_limit" AS monthly_user_limit, ` +
+				`hashtextextended(NULLIF("secret_key", ''), 0) AS secret_key_hash FROM "portal_apps"`,
+		},
+		{
+			name: "should select mapped columns from a schema-qualified view",
+			view: PortalAppsView{
+				Name: "reporting.portal_apps",
+				Columns: PortalAppsViewColumns{
+					ID:   "app_id",
+					Plan: "Plan Name",
+				},
+			},
+			includeSecretKeys: true,
+			expected: `SELECT "app_id" AS id, "secret_key" AS secret_key, "secret_key_required" AS secret_key_required, ` +
+				`"account_id" AS account_id, "Plan Name" AS plan, "monthly_user_limit" AS monthly_user_limit, ` +
+				`hashtextextended(NULLIF("secret_key", ''), 0) AS secret_key_hash FROM "reporting"."portal_apps"`,
+		},
+		{
+			name: "should select only the hash of the mapped secret key column when excluding secret keys",
+			view: PortalAppsView{
+				Name:    "portal_apps",
+				Columns: PortalAppsViewColumns{SecretKey: "api_key"},
+			},
+			expected: `SELECT "id" AS id, NULL::text AS secret_key, "secret_key_required" AS secret_key_required, ` +
+				`"account_id" AS account_id, "plan" AS plan, "monthly_user_limit" AS monthly_user_limit, ` +
+				`hashtextextended(NULLIF("api_key", ''), 0) AS secret_key_hash FROM "portal_apps"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expected, test.view.buildQuery(test.includeSecretKeys))
+		})
+	}
+}
+
+func Test_PortalAppsView_buildPortalAppQuery(t *testing.T) {
+	c := require.New(t)
+
+	view := PortalAppsView{
+		Name:    "reporting.portal_apps",
+		Columns: PortalAppsViewColumns{ID: "app_id"},
+	}
+	c.Equal(view.buildQuery(true)+` WHERE "app_id" = $1`, view.buildPortalAppQuery())
+
+	view = PortalAppsView{Name: "portal_apps"}
+	c.Equal(view.buildQuery(true)+` WHERE "id" = $1`, view.buildPortalAppQuery())
+}
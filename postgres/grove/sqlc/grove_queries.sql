@@ -5,11 +5,12 @@
 -- name: SelectPortalApps :many
 SELECT 
     pa.id,
-    pas.secret_key,
+    CASE WHEN sqlc.arg(include_secret_keys)::boolean THEN pas.secret_key END AS secret_key,
     pas.secret_key_required,
     pa.account_id,
     a.plan_type AS plan,
-    a.monthly_user_limit
+    a.monthly_user_limit,
+    hashtextextended(NULLIF(pas.secret_key, ''), 0) AS secret_key_hash
 FROM portal_applications pa
 LEFT JOIN portal_application_settings pas
     ON pa.id = pas.application_id
@@ -22,3 +23,24 @@ GROUP BY
     pas.secret_key_required,
     a.plan_type,
     a.monthly_user_limit;
+
+-- name: SelectPortalAppAuth :many
+-- Selects the auth settings of a single portal app, fetched on demand when lazy auth is enabled.
+-- More than one row is returned if the portal app has duplicate settings rows.
+SELECT
+    pa.id,
+    pas.secret_key,
+    pas.secret_key_required
+FROM portal_applications pa
+LEFT JOIN portal_application_settings pas
+    ON pa.id = pas.application_id
+WHERE pa.deleted = false
+    AND pa.id = $1
+GROUP BY
+    pa.id,
+    pas.secret_key,
+    pas.secret_key_required;
+
+-- name: SelectPlanLimits :many
+SELECT plan_type, monthly_limit
+FROM plans;
@@ -11,15 +11,85 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const selectPlanLimits = `-- name: SelectPlanLimits :many
+SELECT plan_type, monthly_limit
+FROM plans
+`
+
+func (q *Queries) SelectPlanLimits(ctx context.Context) ([]Plan, error) {
+	rows, err := q.db.Query(ctx, selectPlanLimits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Plan
+	for rows.Next() {
+		var i Plan
+		if err := rows.Scan(&i.PlanType, &i.MonthlyLimit); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const selectPortalAppAuth = `-- name: SelectPortalAppAuth :many
+SELECT
+    pa.id,
+    pas.secret_key,
+    pas.secret_key_required
+FROM portal_applications pa
+LEFT JOIN portal_application_settings pas
+    ON pa.id = pas.application_id
+WHERE pa.deleted = false
+    AND pa.id = $1
+GROUP BY
+    pa.id,
+    pas.secret_key,
+    pas.secret_key_required
+`
+
+type SelectPortalAppAuthRow struct {
+	ID                string      `json:"id"`
+	SecretKey         pgtype.Text `json:"secret_key"`
+	SecretKeyRequired pgtype.Bool `json:"secret_key_required"`
+}
+
+// Selects the auth settings of a single portal app, fetched on demand when lazy auth is enabled.
+// More than one row is returned if the portal app has duplicate settings rows.
+func (q *Queries) SelectPortalAppAuth(ctx context.Context, id string) ([]SelectPortalAppAuthRow, error) {
+	rows, err := q.db.Query(ctx, selectPortalAppAuth, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SelectPortalAppAuthRow
+	for rows.Next() {
+		var i SelectPortalAppAuthRow
+		if err := rows.Scan(&i.ID, &i.SecretKey, &i.SecretKeyRequired); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const selectPortalApps = `-- name: SelectPortalApps :many
 
 SELECT 
     pa.id,
-    pas.secret_key,
+    CASE WHEN $1::boolean THEN pas.secret_key END AS secret_key,
     pas.secret_key_required,
     pa.account_id,
     a.plan_type AS plan,
-    a.monthly_user_limit
+    a.monthly_user_limit,
+    hashtextextended(NULLIF(pas.secret_key, ''), 0) AS secret_key_hash
 FROM portal_applications pa
 LEFT JOIN portal_application_settings pas
     ON pa.id = pas.application_id
@@ -41,13 +111,14 @@ type SelectPortalAppsRow struct {
 	AccountID         pgtype.Text `json:"account_id"`
 	Plan              pgtype.Text `json:"plan"`
 	MonthlyUserLimit  pgtype.Int4 `json:"monthly_user_limit"`
+	SecretKeyHash     pgtype.Int8 `json:"secret_key_hash"`
 }
 
 // This file is used by SQLC to autogenerate the Go code needed by the database driver.
 // It contains all queries used for fetching user data by the Gateway.
 // See: https://docs.sqlc.dev/en/latest/tutorials/getting-started-postgresql.html#schema-and-queries
-func (q *Queries) SelectPortalApps(ctx context.Context) ([]SelectPortalAppsRow, error) {
-	rows, err := q.db.Query(ctx, selectPortalApps)
+func (q *Queries) SelectPortalApps(ctx context.Context, includeSecretKeys bool) ([]SelectPortalAppsRow, error) {
+	rows, err := q.db.Query(ctx, selectPortalApps, includeSecretKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +133,7 @@ func (q *Queries) SelectPortalApps(ctx context.Context) ([]SelectPortalAppsRow,
 			&i.AccountID,
 			&i.Plan,
 			&i.MonthlyUserLimit,
+			&i.SecretKeyHash,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,47 @@
+package sqlc
+
+// This file is NOT generated by SQLC.
+// It extends the generated SelectPortalApps query with a streaming variant,
+// which scans rows one at a time rather than loading every row into a slice.
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StreamPortalApps runs the SelectPortalApps query, calling fn for each row as it is scanned.
+//   - Rows are not retained, capping peak memory for very large portal databases.
+//   - Iteration stops at the first error returned by fn.
+func (q *Queries) StreamPortalApps(ctx context.Context, includeSecretKeys bool, fn func(SelectPortalAppsRow) error) error {
+	rows, err := q.db.Query(ctx, selectPortalApps, includeSecretKeys)
+	if err != nil {
+		return err
+	}
+	return ScanPortalAppsRows(rows, fn)
+}
+
+// ScanPortalAppsRows scans rows with the SelectPortalApps columns, calling fn for each row.
+//   - Used for any query selecting the SelectPortalApps columns in order (e.g. from a compatible view).
+//   - Closes rows before returning.
+func ScanPortalAppsRows(rows pgx.Rows, fn func(SelectPortalAppsRow) error) error {
+	defer rows.Close()
+	for rows.Next() {
+		var i SelectPortalAppsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SecretKey,
+			&i.SecretKeyRequired,
+			&i.AccountID,
+			&i.Plan,
+			&i.MonthlyUserLimit,
+			&i.SecretKeyHash,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
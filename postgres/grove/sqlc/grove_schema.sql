@@ -34,3 +34,11 @@ CREATE TABLE portal_application_settings (
     secret_key VARCHAR(64), -- PortalApp.Auth.APIKey
     secret_key_required BOOLEAN
 );
+
+-- Plans Table
+-- OPTIONAL - Unlike the tables above, this table is only read if plan limits are enabled (POSTGRES_PLAN_LIMITS_ENABLED).
+-- Sets the default monthly relay limit of each plan type; plan types with no row keep the built-in defaults.
+CREATE TABLE plans (
+    plan_type VARCHAR(25) PRIMARY KEY, -- PortalApp.PlanType
+    monthly_limit INT NOT NULL -- Default monthly relay limit of the plan type
+);
@@ -3,3 +3,8 @@
 //   sqlc v1.28.0
 
 package sqlc
+
+type Plan struct {
+	PlanType     string `json:"plan_type"`
+	MonthlyLimit int32  `json:"monthly_limit"`
+}
@@ -0,0 +1,17 @@
+-- This file creates a view in the ephemeral Docker Postgres test database initialized in postgres/docker_test.go
+-- that exposes portal app data with different column names than the base tables, to test the driver's
+-- support for selecting portal apps from a compatible view.
+
+CREATE SCHEMA reporting;
+
+CREATE VIEW reporting.portal_apps AS
+SELECT pa.id AS app_id,
+    pas.secret_key AS api_key,
+    pas.secret_key_required AS api_key_required,
+    pa.account_id,
+    a.plan_type AS plan_name,
+    a.monthly_user_limit AS relay_limit
+FROM portal_applications pa
+    LEFT JOIN portal_application_settings pas ON pa.id = pas.application_id
+    LEFT JOIN accounts a ON pa.account_id = a.id
+WHERE pa.deleted = false;
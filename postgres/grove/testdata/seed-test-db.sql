@@ -24,4 +24,10 @@ VALUES ('portal_app_1_no_auth', FALSE, NULL),
     ('portal_app_3_static_key', TRUE, 'secret_key_3'),
     ('portal_app_4_no_auth', FALSE, NULL),
     ('portal_app_5_static_key', TRUE, 'secret_key_5'),
-    ('portal_app_6_user_limit', FALSE, NULL);
\ No newline at end of file
+    ('portal_app_6_user_limit', FALSE, NULL);
+
+-- Insert into the 'plans' table
+INSERT INTO plans (plan_type, monthly_limit)
+VALUES ('PLAN_FREE', 500000),
+    ('PLAN_UNLIMITED', 0),
+    ('PLAN_PRO', 5000000);
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// WithDecisionCacheTTL caches each account's Decision for up to ttl in front of the account decisions map,
+// so repeated checks of an account within the TTL return the same Decision. Defaults to 0 (disabled).
+//
+// The cache is invalidated whenever decisions change (on every rate limit update and ReevaluateAccounts),
+// so a cached Decision is never older than the decisions it was read from.
+func WithDecisionCacheTTL(ttl time.Duration) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		if ttl <= 0 {
+			rls.decisionCache = nil
+			return
+		}
+		rls.decisionCache = &decisionCache{
+			ttl:     ttl,
+			now:     time.Now,
+			entries: make(map[store.AccountID]decisionCacheEntry),
+		}
+	}
+}
+
+// decisionCache is a short-TTL cache of account Decisions.
+type decisionCache struct {
+	ttl time.Duration
+
+	// now returns the current time; overridden in tests.
+	now func() time.Time
+
+	// mu is read-locked by get, so concurrent checks of cached Decisions do not serialize
+	mu      sync.RWMutex
+	entries map[store.AccountID]decisionCacheEntry
+	// generation is incremented on every invalidation, so a Decision read before an invalidation is not cached after it
+	generation uint64
+}
+
+// decisionCacheEntry is a cached account Decision.
+type decisionCacheEntry struct {
+	decision  Decision
+	expiresAt time.Time
+}
+
+// get returns the cached Decision of the account, if it has not expired.
+//   - Always returns the current generation, to pass to set on a miss.
+func (c *decisionCache) get(accountID store.AccountID) (Decision, uint64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[accountID]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return "", c.generation, false
+	}
+	return entry.decision, c.generation, true
+}
+
+// set caches the Decision of the account, unless the cache was invalidated since the generation was returned by get.
+func (c *decisionCache) set(accountID store.AccountID, decision Decision, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.entries[accountID] = decisionCacheEntry{decision: decision, expiresAt: c.now().Add(c.ttl)}
+}
+
+// invalidate removes every cached Decision.
+func (c *decisionCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	clear(c.entries)
+}
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// newTestDecisionCacheStore returns a rate limit store with the decision cache enabled and a controllable clock.
+func newTestDecisionCacheStore(ttl time.Duration, now *time.Time) *rateLimitStore {
+	rls := &rateLimitStore{
+		logger:           polyzero.NewLogger(),
+		thresholds:       DefaultThresholds,
+		accountDecisions: map[store.AccountID]Decision{"account_blocked": DecisionBlock},
+	}
+	WithDecisionCacheTTL(ttl)(rls)
+	rls.decisionCache.now = func() time.Time { return *now }
+	return rls
+}
+
+func Test_GetAccountRateLimitDecision_DecisionCache(t *testing.T) {
+	c := require.New(t)
+
+	now := time.Now()
+	rls := newTestDecisionCacheStore(time.Second, &now)
+
+	// Miss: the Decision is read from the account decisions map and cached
+	c.Equal(DecisionBlock, rls.GetAccountRateLimitDecision("account_blocked"))
+	c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("account_ok"))
+
+	// Hit: the cached Decisions are returned within the TTL, even if the map changed
+	rls.accountDecisionsMu.Lock()
+	rls.accountDecisions = map[store.AccountID]Decision{"account_ok": DecisionWarn}
+	rls.accountDecisionsMu.Unlock()
+
+	now = now.Add(999 * time.Millisecond)
+	c.Equal(DecisionBlock, rls.GetAccountRateLimitDecision("account_blocked"))
+	c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("account_ok"))
+
+	// Expired: the Decisions are read from the map again
+	now = now.Add(time.Millisecond)
+	c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("account_blocked"))
+	c.Equal(DecisionWarn, rls.GetAccountRateLimitDecision("account_ok"))
+}
+
+func Test_GetAccountRateLimitDecision_DecisionCacheInvalidatedOnRefresh(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDWH := NewMockdataWarehouseDriver(ctrl)
+	mockAccountStore := newTestAccountPortalAppStore(ctrl)
+
+	now := time.Now()
+	rls := newTestDecisionCacheStore(time.Hour, &now)
+	rls.dataWarehouseDriver = mockDWH
+	rls.accountPortalAppStore = mockAccountStore
+
+	c.Equal(DecisionBlock, rls.GetAccountRateLimitDecision("account_blocked"))
+	c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("account_free"))
+
+	// The refresh un-limits account_blocked and blocks account_free
+	mockDWH.EXPECT().
+		GetMonthToMomentUsage(gomock.Any(), gomock.Any(), nil).
+		Return(map[string]dwh.AccountUsage{"account_free": {SuccessfulRelays: FreeMonthlyRelays * 2}}, nil)
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(store.AccountID("account_free")).
+		Return(&store.PortalApp{PlanType: grovedb.PlanFree_DatabaseType, RateLimit: &store.RateLimit{}}, true)
+
+	c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+
+	// The refreshed Decisions are returned well within the TTL
+	c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("account_blocked"))
+	c.Equal(DecisionBlock, rls.GetAccountRateLimitDecision("account_free"))
+}
+
+func Test_decisionCache_set(t *testing.T) {
+	c := require.New(t)
+
+	cache := &decisionCache{ttl: time.Hour, now: time.Now, entries: make(map[store.AccountID]decisionCacheEntry)}
+
+	// A Decision read before an invalidation is not cached after it
+	_, generation, ok := cache.get("account_1")
+	c.False(ok)
+	cache.invalidate()
+	cache.set("account_1", DecisionBlock, generation)
+	_, _, ok = cache.get("account_1")
+	c.False(ok)
+
+	// A Decision read after the invalidation is cached
+	_, generation, _ = cache.get("account_1")
+	cache.set("account_1", DecisionBlock, generation)
+	decision, _, ok := cache.get("account_1")
+	c.True(ok)
+	c.Equal(DecisionBlock, decision)
+}
+
+func Test_WithDecisionCacheTTL_Disabled(t *testing.T) {
+	c := require.New(t)
+
+	rls := &rateLimitStore{}
+	WithDecisionCacheTTL(0)(rls)
+	c.Nil(rls.decisionCache)
+}
+
+func Benchmark_GetAccountRateLimitDecision(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		ttl  time.Duration
+	}{
+		{name: "decision_cache_disabled", ttl: 0},
+		{name: "decision_cache_enabled", ttl: time.Hour},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			rls := &rateLimitStore{
+				logger:           polyzero.NewLogger(),
+				thresholds:       DefaultThresholds,
+				accountDecisions: map[store.AccountID]Decision{"account_blocked": DecisionBlock},
+			}
+			WithDecisionCacheTTL(bm.ttl)(rls)
+
+			// Concurrent checks of the same account, as for an account sending many requests at once
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					rls.GetAccountRateLimitDecision("account_blocked")
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// defaultFailedRelayWeight preserves the original behavior of counting
+// failed relays fully toward an account's usage.
+const defaultFailedRelayWeight = 1.0
+
+// FailedRelayWeights maps a plan type to the weight applied to failed relays
+// when computing usage for the plan's accounts.
+//
+//   - 1.0 counts failed relays fully, 0 excludes them
+//   - Plan types not present in the map use a weight of 1.0
+type FailedRelayWeights map[store.PlanType]float64
+
+// ParseFailedRelayWeights parses a comma-separated list of `<plan type>:<weight>` pairs.
+//
+//   - Example: "PLAN_FREE:1.0,PLAN_UNLIMITED:0"
+//   - Weights must be between 0 and 1, since accounts are fetched from the
+//     data warehouse based on their unweighted total relays
+func ParseFailedRelayWeights(s string) (FailedRelayWeights, error) {
+	weights := make(FailedRelayWeights)
+
+	for _, pair := range strings.Split(s, ",") {
+		planTypeStr, weightStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid failed relay weight %q: expected <plan type>:<weight>", pair)
+		}
+
+		planType := store.PlanType(strings.TrimSpace(planTypeStr))
+		if planType == "" {
+			return nil, fmt.Errorf("invalid failed relay weight %q: empty plan type", pair)
+		}
+		if _, exists := weights[planType]; exists {
+			return nil, fmt.Errorf("duplicate failed relay weight for plan type %q", planType)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if err != nil || weight < 0 || weight > 1 {
+			return nil, fmt.Errorf("invalid failed relay weight %q: must be a number between 0 and 1", weightStr)
+		}
+
+		weights[planType] = weight
+	}
+
+	return weights, nil
+}
+
+// weightedUsage returns the account's usage with failed relays weighted by its plan type.
+func (w FailedRelayWeights) weightedUsage(planType store.PlanType, usage dwh.AccountUsage) int64 {
+	weight, ok := w[planType]
+	if !ok {
+		weight = defaultFailedRelayWeight
+	}
+	return usage.SuccessfulRelays + int64(math.Round(float64(usage.FailedRelays)*weight))
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func TestParseFailedRelayWeights(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           string
+		expectedWeights FailedRelayWeights
+		expectError     bool
+	}{
+		{
+			name:  "should parse a single weight",
+			input: "PLAN_FREE:0.5",
+			expectedWeights: FailedRelayWeights{
+				"PLAN_FREE": 0.5,
+			},
+		},
+		{
+			name:  "should parse multiple weights with whitespace",
+			input: "PLAN_FREE:1.0, PLAN_UNLIMITED:0 ",
+			expectedWeights: FailedRelayWeights{
+				"PLAN_FREE":      1.0,
+				"PLAN_UNLIMITED": 0,
+			},
+		},
+		{
+			name:        "should error on missing weight",
+			input:       "PLAN_FREE",
+			expectError: true,
+		},
+		{
+			name:        "should error on empty plan type",
+			input:       ":0.5",
+			expectError: true,
+		},
+		{
+			name:        "should error on weight above 1",
+			input:       "PLAN_FREE:1.5",
+			expectError: true,
+		},
+		{
+			name:        "should error on negative weight",
+			input:       "PLAN_FREE:-0.5",
+			expectError: true,
+		},
+		{
+			name:        "should error on duplicate plan type",
+			input:       "PLAN_FREE:0.5,PLAN_FREE:1.0",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			weights, err := ParseFailedRelayWeights(test.input)
+			if test.expectError {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.expectedWeights, weights)
+		})
+	}
+}
+
+func TestWeightedUsage(t *testing.T) {
+	weights := FailedRelayWeights{
+		"PLAN_FREE":      0.5,
+		"PLAN_UNLIMITED": 0,
+	}
+	usage := dwh.AccountUsage{SuccessfulRelays: 1000, FailedRelays: 301}
+
+	tests := []struct {
+		name          string
+		weights       FailedRelayWeights
+		planType      store.PlanType
+		expectedUsage int64
+	}{
+		{
+			name:          "should weight failed relays for configured plan",
+			weights:       weights,
+			planType:      "PLAN_FREE",
+			expectedUsage: 1151,
+		},
+		{
+			name:          "should exclude failed relays for plan with zero weight",
+			weights:       weights,
+			planType:      "PLAN_UNLIMITED",
+			expectedUsage: 1000,
+		},
+		{
+			name:          "should count failed relays fully for unconfigured plan",
+			weights:       weights,
+			planType:      "PLAN_UNKNOWN",
+			expectedUsage: 1301,
+		},
+		{
+			name:          "should count failed relays fully when no weights are configured",
+			weights:       nil,
+			planType:      "PLAN_FREE",
+			expectedUsage: 1301,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expectedUsage, test.weights.weightedUsage(test.planType, usage))
+		})
+	}
+}
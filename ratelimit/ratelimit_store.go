@@ -3,11 +3,13 @@ package ratelimit
 import (
 	"context"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/pokt-network/poktroll/pkg/polylog"
 
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
 	"github.com/buildwithgrove/path-external-auth-server/metrics"
 	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
 	"github.com/buildwithgrove/path-external-auth-server/store"
@@ -15,20 +17,33 @@ import (
 
 // 💡IMPORTANT💡: This value is used to determine the PLAN_FREE monthly relay limit.
 //
-// TODO_TECHDEBT(@commoddity): When the Portal DB is implemented,
-// this value should be fetched from the Postgres database.
+// Overridden by the PLAN_FREE limit of the plan limits source, if one is configured (see WithPlanLimits).
 //
 // Once PLAN_FREE accounts hit this limit, they are rate limited until the start of the next month.
 const FreeMonthlyRelays = 1_000_000
 
+// staleIntervalMultiplier determines how many missed refresh intervals
+// the rate limit store tolerates before it is considered unavailable.
+const staleIntervalMultiplier = 3
+
 // accountPortalAppStore interface provides an in-memory store of account portal apps.
 type accountPortalAppStore interface {
 	GetAccountPortalApp(accountID store.AccountID) (*store.PortalApp, bool)
+	GetRateLimitableAccountIDs(isRateLimitable func(*store.PortalApp) bool) []store.AccountID
+	GetPortalAppsWithMonthlyLimit() []*store.PortalApp
 }
 
 // dataWarehouseDriver interface provides a driver for fetching monthly usage data from the data warehouse.
 type dataWarehouseDriver interface {
-	GetMonthToMomentUsage(ctx context.Context, minRelayThreshold int64) (map[string]int64, error)
+	// GetMonthToMomentUsage only queries the given accounts if accountIDs is not nil.
+	GetMonthToMomentUsage(ctx context.Context, minRelayThreshold int64, accountIDs []string) (map[string]dwh.AccountUsage, error)
+	// GetPortalAppMonthToMomentUsage only queries the given portal apps.
+	GetPortalAppMonthToMomentUsage(ctx context.Context, portalAppIDs []string) (map[string]dwh.AccountUsage, error)
+}
+
+// planLimitsSource interface provides the default monthly relay limit of each plan type.
+type planLimitsSource interface {
+	GetPlanLimits() (map[store.PlanType]int32, error)
 }
 
 // rateLimitStore provides an in-memory store of rate limited accounts.
@@ -38,15 +53,167 @@ type rateLimitStore struct {
 	dataWarehouseDriver   dataWarehouseDriver
 	accountPortalAppStore accountPortalAppStore
 
-	rateLimitedAccounts   map[store.AccountID]bool
-	rateLimitedAccountsMu sync.RWMutex
+	// thresholds determine the Decision for an account based on its usage, sorted by ascending usage ratio.
+	thresholds []Threshold
+
+	// failedRelayWeights determine how much failed relays count toward usage for each plan type.
+	failedRelayWeights FailedRelayWeights
+
+	// filterRateLimitableAccounts restricts data warehouse queries to accounts with a rate limit configured.
+	filterRateLimitableAccounts bool
+
+	// recordAllAccountUsage records the usage metric for every fetched account, including accounts with no rate limit.
+	recordAllAccountUsage bool
+
+	// planLimitsSource, if set, provides the default monthly relay limit of each plan type.
+	// planLimits holds the last loaded limits; plan types with no limit keep the built-in defaults.
+	planLimitsSource planLimitsSource
+	planLimits       map[store.PlanType]int32
+	planLimitsMu     sync.RWMutex
+
+	// strictUnknownPlans rate limits accounts whose plan type has no known limit, instead of not rate limiting them.
+	strictUnknownPlans bool
+
+	// enforcementRollout, if set, enforces DecisionBlock for a growing subset of accounts; nil enforces it for all.
+	enforcementRollout *enforcementRollout
+
+	// accountDecisions holds the Decision for every account that crossed at least one threshold.
+	// Accounts not present in the map are DecisionOK.
+	accountDecisions map[store.AccountID]Decision
+	// accountUsage holds the last fetched monthly usage for every account over the minimum relay threshold.
+	// Used to re-evaluate an account's Decision without querying the data warehouse.
+	accountUsage map[store.AccountID]dwh.AccountUsage
+	// rateLimitedPortalApps holds every portal app over its own monthly limit (see store.RateLimit.PortalAppMonthlyLimit),
+	// regardless of its account's Decision.
+	rateLimitedPortalApps map[store.PortalAppID]struct{}
+	// lastUpdated is the time of the last successful rate limit update; zero if none has succeeded.
+	lastUpdated        time.Time
+	accountDecisionsMu sync.RWMutex
+
+	// decisionCache, if set, caches account Decisions for a short TTL in front of accountDecisions; nil if disabled.
+	decisionCache *decisionCache
+
+	// staleAfter is the duration after the last successful update at which the store is considered unavailable.
+	staleAfter time.Duration
+
+	// warmupTimeout, if set, blocks store creation until the first successful update or the timeout elapses.
+	warmupTimeout       time.Duration
+	warmupRetryInterval time.Duration
+
+	// initialLoadMaxAttempts bounds the attempts of the initial rate limit update when warm-up is disabled.
+	// Retries back off exponentially from initialLoadInitialBackoff, capped at initialLoadMaxBackoff.
+	initialLoadMaxAttempts    int
+	initialLoadInitialBackoff time.Duration
+	initialLoadMaxBackoff     time.Duration
+
+	// dataWarehouseErrorType is the error type of data warehouse errors in the data source refresh errors metric.
+	dataWarehouseErrorType string
+}
+
+// RateLimitStoreOption configures optional rateLimitStore behavior.
+type RateLimitStoreOption func(*rateLimitStore)
+
+// WithThresholds sets the usage thresholds used to decide whether an account
+// should be warned, throttled or blocked. Defaults to DefaultThresholds.
+func WithThresholds(thresholds []Threshold) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.thresholds = append([]Threshold(nil), thresholds...)
+		sortThresholds(rls.thresholds)
+	}
+}
+
+// WithFailedRelayWeights sets the weight applied to failed relays when computing usage for each plan type.
+// Plan types without a weight count failed relays fully.
+func WithFailedRelayWeights(weights FailedRelayWeights) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.failedRelayWeights = weights
+	}
+}
+
+// WithRateLimitableAccountFilter restricts data warehouse usage queries to the accounts with a
+// rate limit configured in the portal app store, filtering server-side to reduce scanned data.
+func WithRateLimitableAccountFilter(enabled bool) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.filterRateLimitableAccounts = enabled
+	}
+}
+
+// WithAllAccountUsageMetrics records the account usage metric for every account returned by the
+// data warehouse, including accounts with no rate limit (e.g. PLAN_UNLIMITED with no limit set),
+// so total usage is visible in dashboards. Accounts with no rate limit are recorded with a rate limit of 0.
+//
+// Adds one series per such account; defaults to false (only accounts with a rate limit are recorded).
+// Has no effect on accounts excluded from the query by WithRateLimitableAccountFilter.
+func WithAllAccountUsageMetrics(enabled bool) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.recordAllAccountUsage = enabled
+	}
+}
+
+// WithPlanLimits loads the default monthly relay limit of each plan type from the given source
+// on startup and on every rate limit update.
+//
+// Plan types without a limit in the source keep the built-in defaults (FreeMonthlyRelays for PLAN_FREE).
+// If loading fails, the previously loaded limits are kept.
+func WithPlanLimits(source planLimitsSource) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.planLimitsSource = source
+	}
+}
+
+// WithStrictUnknownPlans rate limits every account whose plan type is unknown,
+// i.e. neither PLAN_FREE, PLAN_UNLIMITED nor a plan type with a loaded plan limit (see WithPlanLimits).
+// An account with an unknown plan type and an explicit monthly user limit is limited to it instead.
+//
+// Fails closed, so a misconfigured or newly added paid plan cannot bypass rate limiting.
+// Defaults to false: accounts with an unknown plan type are not rate limited.
+func WithStrictUnknownPlans(enabled bool) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.strictUnknownPlans = enabled
+	}
+}
+
+// WithWarmup blocks NewRateLimitStore until the first rate limit update succeeds,
+// retrying every retryInterval and returning an error once timeout elapses.
+//
+// Prevents PEAS from serving requests with rate limiting effectively disabled
+// while the initial data warehouse load is failing.
+func WithWarmup(timeout, retryInterval time.Duration) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.warmupTimeout = timeout
+		rls.warmupRetryInterval = retryInterval
+	}
+}
+
+// WithInitialLoadRetry retries a failed initial rate limit update up to maxAttempts times in total,
+// doubling the backoff between attempts from initialBackoff up to maxBackoff.
+//
+// Unlike WithWarmup, the store is still created if every attempt fails; rate limiting is then
+// effectively disabled until the next successful periodic update.
+// Ignored if WithWarmup is set. Defaults to a single attempt.
+func WithInitialLoadRetry(maxAttempts int, initialBackoff, maxBackoff time.Duration) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.initialLoadMaxAttempts = maxAttempts
+		rls.initialLoadInitialBackoff = initialBackoff
+		rls.initialLoadMaxBackoff = maxBackoff
+	}
+}
+
+// WithDataWarehouseErrorType sets the error type data warehouse errors are counted with in the
+// data source refresh errors metric (e.g. metrics.ClickhouseErrorType). Defaults to metrics.BigqueryErrorType.
+func WithDataWarehouseErrorType(errorType string) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		rls.dataWarehouseErrorType = errorType
+	}
 }
 
 func NewRateLimitStore(
+	ctx context.Context,
 	logger polylog.Logger,
 	dataWarehouseDriver dataWarehouseDriver,
 	accountPortalAppStore accountPortalAppStore,
 	rateLimitUpdateInterval time.Duration,
+	opts ...RateLimitStoreOption,
 ) (*rateLimitStore, error) {
 	rls := &rateLimitStore{
 		logger: logger.With("component", "rate_limit_store"),
@@ -54,34 +221,224 @@ func NewRateLimitStore(
 		accountPortalAppStore: accountPortalAppStore,
 		dataWarehouseDriver:   dataWarehouseDriver,
 
-		rateLimitedAccounts: make(map[store.AccountID]bool),
+		thresholds: DefaultThresholds,
+
+		accountDecisions: make(map[store.AccountID]Decision),
+		accountUsage:     make(map[store.AccountID]dwh.AccountUsage),
+
+		rateLimitedPortalApps: make(map[store.PortalAppID]struct{}),
+
+		staleAfter: staleIntervalMultiplier * rateLimitUpdateInterval,
+
+		initialLoadMaxAttempts: 1,
+
+		dataWarehouseErrorType: metrics.BigqueryErrorType,
+	}
+	for _, opt := range opts {
+		opt(rls)
 	}
 
-	// Run initial check immediately
-	if err := rls.updateRateLimitedAccounts(); err != nil {
-		rls.logger.Error().
-			Err(err).
-			Msg("Failed to perform initial rate limit check")
-		// Set initial metrics to zero if initial check fails
-		rls.updateStoreMetrics(0, 0)
+	if rls.warmupTimeout > 0 {
+		// Block until the first successful update or the warm-up timeout
+		if err := rls.warmup(); err != nil {
+			return nil, err
+		}
+	} else {
+		// Run initial check immediately, retrying transient failures if configured
+		if err := rls.initialLoad(ctx); err != nil {
+			rls.logger.Error().
+				Err(err).
+				Msg("Failed to perform initial rate limit check")
+			// Set initial metrics to zero if initial check fails
+			rls.updateStoreMetrics(0, nil)
+		}
 	}
 
-	// Start the background rate limit monitoring
-	go rls.startRateLimitMonitoring(rateLimitUpdateInterval)
+	// Start the background rate limit monitoring, until the context is canceled
+	go rls.startRateLimitMonitoring(ctx, rateLimitUpdateInterval)
 
 	return rls, nil
 }
 
-// IsAccountRateLimited checks if an account is currently rate limited.
+// warmup performs the initial rate limit update, retrying until it succeeds or the warm-up timeout elapses.
+func (rls *rateLimitStore) warmup() error {
+	deadline := time.Now().Add(rls.warmupTimeout)
+
+	// Always query the data warehouse on startup, rather than a cached result
+	ctx := dwh.ContextWithUsageCacheBypass(context.Background())
+
+	for attempt := 1; ; attempt++ {
+		err := rls.updateRateLimitedAccounts(ctx)
+		if err == nil {
+			rls.logger.Info().Int("attempt", attempt).Msg("🔥 Rate limit store warm-up completed")
+			return nil
+		}
+
+		if time.Now().Add(rls.warmupRetryInterval).After(deadline) {
+			rls.updateStoreMetrics(0, nil)
+			return fmt.Errorf("rate limit store warm-up timed out after %s and %d attempts: %w", rls.warmupTimeout, attempt, err)
+		}
+
+		rls.logger.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("retry_interval", rls.warmupRetryInterval).
+			Msg("Rate limit store warm-up attempt failed, retrying")
+		time.Sleep(rls.warmupRetryInterval)
+	}
+}
+
+// initialLoad performs the initial rate limit update, retrying with exponential backoff
+// until it succeeds, the maximum attempts are exhausted or the context is canceled.
+func (rls *rateLimitStore) initialLoad(ctx context.Context) error {
+	backoff := rls.initialLoadInitialBackoff
+
+	// Always query the data warehouse on startup, rather than a cached result
+	updateCtx := dwh.ContextWithUsageCacheBypass(ctx)
+
+	for attempt := 1; ; attempt++ {
+		err := rls.updateRateLimitedAccounts(updateCtx)
+		if err == nil {
+			if attempt > 1 {
+				rls.logger.Info().Int("attempt", attempt).Msg("✅ Initial rate limit check succeeded after retry")
+			}
+			return nil
+		}
+
+		if attempt >= rls.initialLoadMaxAttempts {
+			return fmt.Errorf("initial rate limit check failed after %d attempts: %w", attempt, err)
+		}
+
+		rls.logger.Warn().
+			Err(err).
+			Int("attempt", attempt).
+			Dur("backoff", backoff).
+			Msg("Initial rate limit check attempt failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("initial rate limit check canceled after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if rls.initialLoadMaxBackoff > 0 && backoff > rls.initialLoadMaxBackoff {
+			backoff = rls.initialLoadMaxBackoff
+		}
+	}
+}
+
+// IsAccountRateLimited checks if an account is currently rate limited (blocked).
 func (rls *rateLimitStore) IsAccountRateLimited(accountID store.AccountID) bool {
-	rls.rateLimitedAccountsMu.RLock()
-	defer rls.rateLimitedAccountsMu.RUnlock()
-	_, ok := rls.rateLimitedAccounts[accountID]
+	return rls.GetAccountRateLimitDecision(accountID) == DecisionBlock
+}
+
+// GetAccountRateLimitDecision returns the current rate limit Decision for an account.
+//   - Returns DecisionOK if the account has not crossed any threshold.
+//   - Returns the cached Decision, if the decision cache is enabled (see WithDecisionCacheTTL).
+func (rls *rateLimitStore) GetAccountRateLimitDecision(accountID store.AccountID) Decision {
+	if rls.decisionCache == nil {
+		return rls.lookupAccountRateLimitDecision(accountID)
+	}
+
+	decision, generation, ok := rls.decisionCache.get(accountID)
+	if ok {
+		return decision
+	}
+	decision = rls.lookupAccountRateLimitDecision(accountID)
+	rls.decisionCache.set(accountID, decision, generation)
+	return decision
+}
+
+// lookupAccountRateLimitDecision returns the account's Decision from the account decisions map.
+func (rls *rateLimitStore) lookupAccountRateLimitDecision(accountID store.AccountID) Decision {
+	rls.accountDecisionsMu.RLock()
+	defer rls.accountDecisionsMu.RUnlock()
+	decision, ok := rls.accountDecisions[accountID]
+	if !ok {
+		return DecisionOK
+	}
+	return decision
+}
+
+// AccountRateLimitStatus is the current rate limit state of an account, for debugging.
+type AccountRateLimitStatus struct {
+	PlanType store.PlanType
+	// RateLimit is the account's monthly relay limit; 0 if the account is not rate limited by usage.
+	RateLimit int32
+	// Usage is the account's last fetched month-to-date usage, with failed relays weighted by plan type;
+	// 0 if the account was under the minimum relay threshold of the last update.
+	Usage    int64
+	Decision Decision
+}
+
+// GetAccountRateLimitStatus returns the current rate limit state of an account.
+//   - Returns false if the account has no portal app in the portal app store.
+//   - The Decision is read from the last update, bypassing the decision cache.
+func (rls *rateLimitStore) GetAccountRateLimitStatus(accountID store.AccountID) (AccountRateLimitStatus, bool) {
+	portalApp, exists := rls.accountPortalAppStore.GetAccountPortalApp(accountID)
+	if !exists {
+		return AccountRateLimitStatus{}, false
+	}
+
+	rls.accountDecisionsMu.RLock()
+	accountUsage := rls.accountUsage[accountID]
+	decision, ok := rls.accountDecisions[accountID]
+	rls.accountDecisionsMu.RUnlock()
+	if !ok {
+		decision = DecisionOK
+	}
+
+	return AccountRateLimitStatus{
+		PlanType:  portalApp.PlanType,
+		RateLimit: rls.getRateLimit(portalApp),
+		Usage:     rls.failedRelayWeights.weightedUsage(portalApp.PlanType, accountUsage),
+		Decision:  decision,
+	}, true
+}
+
+// IsAccountRateLimitable returns true if the portal app's account is subject to monthly rate limit enforcement:
+//   - It has a monthly relay limit, from its plan or its own monthly user limit (see getRateLimit)
+//   - Or it is rate limited because of its unknown plan type, in strict mode
+//
+// A RateLimit with only limits enforced outside of PEAS (e.g. a daily user limit) does not make the account rate-limitable.
+func (rls *rateLimitStore) IsAccountRateLimitable(portalApp *store.PortalApp) bool {
+	return rls.getRateLimit(portalApp) > 0 || rls.blocksUnknownPlan(portalApp, true)
+}
+
+// IsPortalAppRateLimited checks if a portal app is currently rate limited (blocked) by its own monthly limit.
+//   - Independent of the account's Decision: an account within its limit may have a portal app over its own limit.
+func (rls *rateLimitStore) IsPortalAppRateLimited(portalAppID store.PortalAppID) bool {
+	rls.accountDecisionsMu.RLock()
+	defer rls.accountDecisionsMu.RUnlock()
+	_, ok := rls.rateLimitedPortalApps[portalAppID]
 	return ok
 }
 
-// startRateLimitMonitoring runs the periodic rate limit check in a background goroutine.
-func (rls *rateLimitStore) startRateLimitMonitoring(rateLimitUpdateInterval time.Duration) {
+// IsAvailable returns true if the store's rate limit data can be trusted.
+//   - Returns false if no update has ever succeeded (e.g. the data warehouse was unreachable on startup).
+//   - Returns false if the last successful update is older than the stale threshold.
+func (rls *rateLimitStore) IsAvailable() bool {
+	rls.accountDecisionsMu.RLock()
+	defer rls.accountDecisionsMu.RUnlock()
+
+	if rls.lastUpdated.IsZero() {
+		return false
+	}
+	return time.Since(rls.lastUpdated) <= rls.staleAfter
+}
+
+// HasLoaded returns true once any rate limit update has succeeded.
+//   - Unlike IsAvailable, stays true if the data later becomes stale, so it only identifies the cold start.
+func (rls *rateLimitStore) HasLoaded() bool {
+	rls.accountDecisionsMu.RLock()
+	defer rls.accountDecisionsMu.RUnlock()
+
+	return !rls.lastUpdated.IsZero()
+}
+
+// startRateLimitMonitoring runs the periodic rate limit check in a background goroutine, until the context is canceled.
+func (rls *rateLimitStore) startRateLimitMonitoring(ctx context.Context, rateLimitUpdateInterval time.Duration) {
 	rls.logger.Info().
 		Dur("update_interval", rateLimitUpdateInterval).
 		Msg("🚦 Starting rate limit monitoring")
@@ -89,35 +446,63 @@ func (rls *rateLimitStore) startRateLimitMonitoring(rateLimitUpdateInterval time
 	ticker := time.NewTicker(rateLimitUpdateInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if err := rls.updateRateLimitedAccounts(); err != nil {
-			rls.logger.Error().
-				Err(err).
-				Msg("Failed to update rate limited accounts")
+	for {
+		select {
+		case <-ctx.Done():
+			rls.logger.Info().Msg("Stopping rate limit monitoring")
+			return
+		case <-ticker.C:
+			if err := rls.updateRateLimitedAccounts(ctx); err != nil {
+				rls.logger.Error().
+					Err(err).
+					Msg("Failed to update rate limited accounts")
+			}
 		}
 	}
 }
 
+// Refresh immediately re-evaluates rate limits for all accounts from the latest
+// data warehouse usage, outside of the rate limit update interval.
+//
+// Used to force a reload without restarting (e.g. on SIGHUP), so the data warehouse usage cache is bypassed.
+func (rls *rateLimitStore) Refresh() error {
+	return rls.updateRateLimitedAccounts(dwh.ContextWithUsageCacheBypass(context.Background()))
+}
+
 // updateRateLimitedAccounts fetches usage data and updates the rate limited accounts map.
-func (rls *rateLimitStore) updateRateLimitedAccounts() error {
+func (rls *rateLimitStore) updateRateLimitedAccounts(ctx context.Context) error {
 	startTime := time.Now()
 	rls.logger.Debug().Msg("🔍 Checking account rate limits")
 
+	// Load the latest plan limits before determining which accounts to fetch
+	rls.refreshPlanLimits()
+
 	// Get month-to-date usage for accounts over the threshold
 	accountUsageOverMonthlyRelayLimit, err := rls.dataWarehouseDriver.GetMonthToMomentUsage(
-		context.Background(),
-		FreeMonthlyRelays,
+		ctx,
+		rls.minRelayThreshold(),
+		rls.getAccountIDsFilter(),
 	)
 	if err != nil {
-		metrics.RecordDataSourceRefreshError(metrics.RateLimitStoreSourceType, metrics.BigqueryErrorType)
+		metrics.RecordDataSourceRefreshError(metrics.RateLimitStoreSourceType, rls.dataWarehouseErrorType)
 		return fmt.Errorf("failed to get monthly usage data: %w", err)
 	}
 
-	// Build new rate limited accounts map
-	newRateLimitedAccounts := make(map[store.AccountID]bool)
+	// Get month-to-date usage of portal apps with a limit of their own
+	newRateLimitedPortalApps, err := rls.getRateLimitedPortalApps(ctx)
+	if err != nil {
+		metrics.RecordDataSourceRefreshError(metrics.RateLimitStoreSourceType, rls.dataWarehouseErrorType)
+		return fmt.Errorf("failed to get portal app monthly usage data: %w", err)
+	}
+
+	// Build new account decisions map
+	newAccountDecisions := make(map[store.AccountID]Decision)
+	newAccountUsage := make(map[store.AccountID]dwh.AccountUsage, len(accountUsageOverMonthlyRelayLimit))
+	decisionCounts := make(map[Decision]int)
 
-	for accountIDStr, usage := range accountUsageOverMonthlyRelayLimit {
+	for accountIDStr, accountUsage := range accountUsageOverMonthlyRelayLimit {
 		accountID := store.AccountID(accountIDStr)
+		newAccountUsage[accountID] = accountUsage
 
 		// Get the account's portal app
 		portalApp, exists := rls.accountPortalAppStore.GetAccountPortalApp(accountID)
@@ -132,6 +517,10 @@ func (rls *rateLimitStore) updateRateLimitedAccounts() error {
 		// Will return 0 if no rate limit is configured
 		rateLimit := rls.getRateLimit(portalApp)
 		if rateLimit == 0 {
+			if rls.recordAllAccountUsage {
+				usage := rls.failedRelayWeights.weightedUsage(portalApp.PlanType, accountUsage)
+				metrics.UpdateAccountUsage(string(accountID), string(portalApp.PlanType), float64(usage), 0)
+			}
 			rls.logger.Debug().
 				Str("account_id", string(accountID)).
 				Str("plan_type", string(portalApp.PlanType)).
@@ -139,14 +528,33 @@ func (rls *rateLimitStore) updateRateLimitedAccounts() error {
 			continue
 		}
 
+		// Weight failed relays according to the account's plan type
+		usage := rls.failedRelayWeights.weightedUsage(portalApp.PlanType, accountUsage)
+
 		// Update account usage metrics for accounts over monthly limit
 		planType := string(portalApp.PlanType)
 		metrics.UpdateAccountUsage(string(accountID), planType, float64(usage), rateLimit)
 
-		// Check if account should be rate limited based on plan type
-		shouldLimit := rls.shouldLimitAccount(rateLimit, usage)
-		if shouldLimit {
-			newRateLimitedAccounts[accountID] = true
+		// Determine the account's decision based on the configured thresholds
+		decision := rls.evaluateUsage(rateLimit, usage)
+		if decision == DecisionOK {
+			continue
+		}
+
+		// Downgrade the decision of a blocked account not yet in the enforcement rollout
+		if enforcedDecision := rls.enforcementRollout.applyDecision(accountID, decision); enforcedDecision != decision {
+			rls.logger.Info().
+				Str("account_id", string(accountID)).
+				Str("plan_type", string(portalApp.PlanType)).
+				Int64("usage", usage).
+				Int32("rate_limit", rateLimit).
+				Msg("🐢 Account over rate limit but not yet in enforcement rollout")
+			decision = enforcedDecision
+		}
+		newAccountDecisions[accountID] = decision
+		decisionCounts[decision]++
+
+		if decision == DecisionBlock {
 			metrics.UpdateRateLimitedAccounts(string(accountID), planType, float64(usage), rateLimit)
 			rls.logger.Info().
 				Str("account_id", string(accountID)).
@@ -154,62 +562,345 @@ func (rls *rateLimitStore) updateRateLimitedAccounts() error {
 				Int64("usage", usage).
 				Int32("rate_limit", rateLimit).
 				Msg("🤚 Account rate limited")
+			continue
 		}
+
+		rls.logger.Debug().
+			Str("account_id", string(accountID)).
+			Str("plan_type", planType).
+			Str("decision", string(decision)).
+			Int64("usage", usage).
+			Int32("rate_limit", rateLimit).
+			Msg("⚠️ Account approaching rate limit")
 	}
 
-	// Update the rate limited accounts map atomically
-	rls.rateLimitedAccountsMu.Lock()
-	rls.rateLimitedAccounts = newRateLimitedAccounts
-	rls.rateLimitedAccountsMu.Unlock()
+	// Rate limit accounts with an unknown plan type regardless of usage, if strict mode is enabled
+	if rls.strictUnknownPlans {
+		rls.blockUnknownPlanAccounts(newAccountDecisions, decisionCounts)
+	}
+
+	// Update the account decisions map atomically
+	rls.accountDecisionsMu.Lock()
+	rls.accountDecisions = newAccountDecisions
+	rls.accountUsage = newAccountUsage
+	rls.rateLimitedPortalApps = newRateLimitedPortalApps
+	rls.lastUpdated = time.Now()
+	rls.accountDecisionsMu.Unlock()
+	rls.invalidateDecisionCache()
 
 	// Update store size metrics
-	rls.updateStoreMetrics(len(accountUsageOverMonthlyRelayLimit), len(newRateLimitedAccounts))
+	rls.updateStoreMetrics(len(accountUsageOverMonthlyRelayLimit), decisionCounts)
+	metrics.UpdateStoreSize(metrics.RateLimitedPortalAppsStoreType, float64(len(newRateLimitedPortalApps)))
 
 	updateDuration := time.Since(startTime)
 	rls.logger.Info().
 		Int("total_accounts_over_monthly_relay_limit", len(accountUsageOverMonthlyRelayLimit)).
-		Int("rate_limited_accounts", len(newRateLimitedAccounts)).
+		Int("rate_limited_accounts", decisionCounts[DecisionBlock]).
+		Int("throttled_accounts", decisionCounts[DecisionThrottle]).
+		Int("warned_accounts", decisionCounts[DecisionWarn]).
+		Int("rate_limited_portal_apps", len(newRateLimitedPortalApps)).
 		Int64("update_duration_ms", updateDuration.Milliseconds()).
 		Msg("✅ Rate limit check completed")
 
 	return nil
 }
 
+// getRateLimitedPortalApps fetches the usage of every portal app with a monthly limit of its own
+// and returns the portal apps over their limit.
+//   - Only DecisionBlock applies to portal apps: warning and throttling are based on the account's usage.
+//   - Failed relays are weighted and the enforcement rollout applied as for the portal app's account.
+//   - Does not query the data warehouse if no portal app has a limit of its own.
+func (rls *rateLimitStore) getRateLimitedPortalApps(ctx context.Context) (map[store.PortalAppID]struct{}, error) {
+	rateLimitedPortalApps := make(map[store.PortalAppID]struct{})
+
+	portalApps := rls.accountPortalAppStore.GetPortalAppsWithMonthlyLimit()
+	if len(portalApps) == 0 {
+		return rateLimitedPortalApps, nil
+	}
+
+	portalAppIDs := make([]string, len(portalApps))
+	for i, portalApp := range portalApps {
+		portalAppIDs[i] = string(portalApp.ID)
+	}
+
+	portalAppUsage, err := rls.dataWarehouseDriver.GetPortalAppMonthToMomentUsage(ctx, portalAppIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, portalApp := range portalApps {
+		rateLimit := portalApp.RateLimit.PortalAppMonthlyLimit
+		usage := rls.failedRelayWeights.weightedUsage(portalApp.PlanType, portalAppUsage[string(portalApp.ID)])
+
+		decision := rls.enforcementRollout.applyDecision(portalApp.AccountID, rls.evaluateUsage(rateLimit, usage))
+		if decision != DecisionBlock {
+			continue
+		}
+
+		rateLimitedPortalApps[portalApp.ID] = struct{}{}
+		rls.logger.Info().
+			Str("portal_app_id", string(portalApp.ID)).
+			Str("account_id", string(portalApp.AccountID)).
+			Int64("usage", usage).
+			Int32("rate_limit", rateLimit).
+			Msg("🤚 Portal app rate limited")
+	}
+
+	return rateLimitedPortalApps, nil
+}
+
+// getAccountIDsFilter returns the accounts to restrict the data warehouse usage query to.
+//   - Returns nil (no filter) if the rate-limitable account filter is disabled.
+func (rls *rateLimitStore) getAccountIDsFilter() []string {
+	if !rls.filterRateLimitableAccounts {
+		return nil
+	}
+
+	rateLimitableAccountIDs := rls.accountPortalAppStore.GetRateLimitableAccountIDs(rls.IsAccountRateLimitable)
+	accountIDs := make([]string, len(rateLimitableAccountIDs))
+	for i, accountID := range rateLimitableAccountIDs {
+		accountIDs[i] = string(accountID)
+	}
+	return accountIDs
+}
+
+// ReevaluateAccounts immediately re-evaluates the Decision for the given accounts
+// using their current portal app settings and last fetched monthly usage.
+//
+// Intended to be registered as the portal app store's account plan change handler,
+// so accounts that upgrade their plan or limit are un-limited without waiting for
+// the next data warehouse refresh.
+func (rls *rateLimitStore) ReevaluateAccounts(accountIDs []store.AccountID) {
+	rls.accountDecisionsMu.Lock()
+	defer rls.accountDecisionsMu.Unlock()
+
+	for _, accountID := range accountIDs {
+		decision := DecisionOK
+		if portalApp, exists := rls.accountPortalAppStore.GetAccountPortalApp(accountID); rls.blocksUnknownPlan(portalApp, exists) {
+			decision = DecisionBlock
+		} else if exists {
+			usage := rls.failedRelayWeights.weightedUsage(portalApp.PlanType, rls.accountUsage[accountID])
+			decision = rls.enforcementRollout.applyDecision(accountID, rls.evaluateUsage(rls.getRateLimit(portalApp), usage))
+		}
+
+		previousDecision, ok := rls.accountDecisions[accountID]
+		if !ok {
+			previousDecision = DecisionOK
+		}
+
+		if decision == DecisionOK {
+			delete(rls.accountDecisions, accountID)
+		} else {
+			rls.accountDecisions[accountID] = decision
+		}
+
+		if decision != previousDecision {
+			rls.logger.Info().
+				Str("account_id", string(accountID)).
+				Str("previous_decision", string(previousDecision)).
+				Str("decision", string(decision)).
+				Msg("🔄 Account rate limit re-evaluated after plan change")
+		}
+	}
+
+	rls.invalidateDecisionCache()
+
+	// Update store size metrics to reflect the re-evaluated decisions
+	decisionCounts := make(map[Decision]int)
+	for _, decision := range rls.accountDecisions {
+		decisionCounts[decision]++
+	}
+	rls.updateStoreMetrics(len(rls.accountUsage), decisionCounts)
+}
+
+// invalidateDecisionCache removes every cached Decision after the account decisions changed, if the decision cache is enabled.
+func (rls *rateLimitStore) invalidateDecisionCache() {
+	if rls.decisionCache != nil {
+		rls.decisionCache.invalidate()
+	}
+}
+
 // getRateLimit gets the rate limit for an account based on its plan type and rate limit configuration.
+//   - Returns 0 if the account has no monthly limit.
+//   - Plan limits also apply to portal apps with no RateLimit, as the data sources only set one
+//     for portal apps with limits of their own (e.g. a PLAN_UNLIMITED portal app with no monthly user limit).
 func (rls *rateLimitStore) getRateLimit(portalApp *store.PortalApp) int32 {
-	if portalApp.RateLimit == nil {
-		return 0
+	var rateLimit store.RateLimit
+	if portalApp.RateLimit != nil {
+		rateLimit = *portalApp.RateLimit
 	}
 
 	switch portalApp.PlanType {
 	case grovedb.PlanFree_DatabaseType:
-		// For free plan, return the free tier limit
-		return FreeMonthlyRelays
+		// The data sources always set a RateLimit for free plan portal apps: one without is not rate limited
+		if portalApp.RateLimit == nil {
+			return 0
+		}
+		// For free plan, return the free tier limit plus any bonus relays granted to the account
+		freeMonthlyRelays := rls.getFreeMonthlyRelays()
+		if rateLimit.FreeMonthlyRelayBonus > 0 {
+			// Saturate rather than overflow to a negative limit, which would block the account regardless of usage
+			return int32(min(int64(freeMonthlyRelays)+int64(rateLimit.FreeMonthlyRelayBonus), math.MaxInt32))
+		}
+		return freeMonthlyRelays
 
 	case grovedb.PlanUnlimited_DatabaseType:
 		// For unlimited plan, check against the account's specific monthly limit (if set)
-		if portalApp.RateLimit.MonthlyUserLimit > 0 {
-			return portalApp.RateLimit.MonthlyUserLimit
+		if rateLimit.MonthlyUserLimit > 0 {
+			return rateLimit.MonthlyUserLimit
 		}
-		// If no limit is set for unlimited plan, don't rate limit
-		return 0
+		// If no limit is set for unlimited plan, fall back to the plan limit (if loaded), otherwise don't rate limit
+		planLimit, _ := rls.getPlanLimit(portalApp.PlanType)
+		return planLimit
 
 	default:
+		// For other plans, return the plan limit (if loaded)
+		if planLimit, ok := rls.getPlanLimit(portalApp.PlanType); ok {
+			return planLimit
+		}
+		// For unknown plans in strict mode, check against the account's specific monthly limit (if set),
+		// as accounts with no limit set are rate limited regardless of usage (see blocksUnknownPlan)
+		if rls.strictUnknownPlans {
+			return rateLimit.MonthlyUserLimit
+		}
+		// Otherwise, don't rate limit
 		return 0
 	}
 }
 
-// shouldLimitAccount determines if an account should be rate limited based on its rate limit and usage.
-func (rls *rateLimitStore) shouldLimitAccount(rateLimit int32, usage int64) bool {
+// blocksUnknownPlan returns true if the account must be rate limited because of its unknown plan type.
+//   - Only applies in strict mode, including to accounts with no rate limit configured.
+//   - Does not apply to accounts with an explicit monthly user limit, which are limited to it instead (see getRateLimit).
+func (rls *rateLimitStore) blocksUnknownPlan(portalApp *store.PortalApp, exists bool) bool {
+	if !rls.strictUnknownPlans || !exists {
+		return false
+	}
+	if portalApp.RateLimit != nil && portalApp.RateLimit.MonthlyUserLimit > 0 {
+		return false
+	}
+	return rls.isUnknownPlan(portalApp.PlanType)
+}
+
+// isUnknownPlan returns true if the plan type is neither PLAN_FREE, PLAN_UNLIMITED nor has a loaded plan limit.
+func (rls *rateLimitStore) isUnknownPlan(planType store.PlanType) bool {
+	switch planType {
+	case grovedb.PlanFree_DatabaseType, grovedb.PlanUnlimited_DatabaseType:
+		return false
+	default:
+		_, ok := rls.getPlanLimit(planType)
+		return !ok
+	}
+}
+
+// blockUnknownPlanAccounts sets DecisionBlock for every account with an unknown plan type and no explicit monthly user limit.
+//   - Checks all rate-limitable accounts, as accounts with an unknown plan type may not have been fetched from the data warehouse.
+//   - Updates the unknown plan rate limited accounts metric.
+func (rls *rateLimitStore) blockUnknownPlanAccounts(accountDecisions map[store.AccountID]Decision, decisionCounts map[Decision]int) {
+	var numUnknownPlanAccounts int
+	for _, accountID := range rls.accountPortalAppStore.GetRateLimitableAccountIDs(rls.IsAccountRateLimitable) {
+		portalApp, exists := rls.accountPortalAppStore.GetAccountPortalApp(accountID)
+		if !rls.blocksUnknownPlan(portalApp, exists) {
+			continue
+		}
+
+		numUnknownPlanAccounts++
+		accountDecisions[accountID] = DecisionBlock
+		decisionCounts[DecisionBlock]++
+
+		rls.logger.Warn().
+			Str("account_id", string(accountID)).
+			Str("plan_type", string(portalApp.PlanType)).
+			Msg("🤚 Account rate limited: unknown plan type")
+	}
+
+	metrics.UpdateUnknownPlanRateLimitedAccounts(float64(numUnknownPlanAccounts))
+}
+
+// refreshPlanLimits loads the latest plan limits from the plan limits source, if one is configured.
+//   - Keeps the previously loaded limits if loading fails, so a transient error does not reset limits to the defaults.
+func (rls *rateLimitStore) refreshPlanLimits() {
+	if rls.planLimitsSource == nil {
+		return
+	}
+
+	planLimits, err := rls.planLimitsSource.GetPlanLimits()
+	if err != nil {
+		metrics.RecordDataSourceRefreshError(metrics.RateLimitStoreSourceType, metrics.PostgresErrorType)
+		rls.logger.Error().Err(err).Msg("Failed to load plan limits: keeping the previously loaded plan limits")
+		return
+	}
+
+	rls.planLimitsMu.Lock()
+	rls.planLimits = planLimits
+	rls.planLimitsMu.Unlock()
+
+	rls.logger.Debug().Int("num_plan_limits", len(planLimits)).Msg("📋 Loaded plan limits")
+}
+
+// getPlanLimit returns the loaded monthly relay limit of the plan type.
+//   - Returns false if no limit is loaded for the plan type.
+func (rls *rateLimitStore) getPlanLimit(planType store.PlanType) (int32, bool) {
+	rls.planLimitsMu.RLock()
+	defer rls.planLimitsMu.RUnlock()
+	planLimit, ok := rls.planLimits[planType]
+	return planLimit, ok
+}
+
+// getFreeMonthlyRelays returns the PLAN_FREE monthly relay limit.
+//   - Returns FreeMonthlyRelays if no PLAN_FREE limit is loaded.
+func (rls *rateLimitStore) getFreeMonthlyRelays() int32 {
+	if planLimit, ok := rls.getPlanLimit(grovedb.PlanFree_DatabaseType); ok {
+		return planLimit
+	}
+	return FreeMonthlyRelays
+}
+
+// evaluateUsage determines an account's Decision based on its rate limit and usage.
+//   - Returns the most severe Decision whose threshold the usage has crossed.
+//   - Returns DecisionOK if no threshold was crossed or the rate limit is 0 (unlimited).
+func (rls *rateLimitStore) evaluateUsage(rateLimit int32, usage int64) Decision {
 	// If rate limit is 0, don't rate limit (unlimited)
 	if rateLimit == 0 {
-		return false
+		return DecisionOK
+	}
+
+	decision := DecisionOK
+	for _, threshold := range rls.thresholds {
+		if float64(usage) > float64(rateLimit)*threshold.UsageRatio {
+			decision = threshold.Decision
+		}
+	}
+	return decision
+}
+
+// minRelayThreshold returns the minimum monthly usage an account must have to cross any threshold.
+// Accounts below this value are not fetched from the data warehouse.
+//   - Based on the lowest positive limit of PLAN_FREE and the loaded plan limits, or FreeMonthlyRelays if none is positive.
+func (rls *rateLimitStore) minRelayThreshold() int64 {
+	var minPlanLimit int32
+	if freeMonthlyRelays := rls.getFreeMonthlyRelays(); freeMonthlyRelays > 0 {
+		minPlanLimit = freeMonthlyRelays
 	}
-	return usage > int64(rateLimit)
+
+	rls.planLimitsMu.RLock()
+	for _, planLimit := range rls.planLimits {
+		if planLimit > 0 && (minPlanLimit == 0 || planLimit < minPlanLimit) {
+			minPlanLimit = planLimit
+		}
+	}
+	rls.planLimitsMu.RUnlock()
+
+	if minPlanLimit == 0 {
+		minPlanLimit = FreeMonthlyRelays
+	}
+
+	return int64(float64(minPlanLimit) * minUsageRatio(rls.thresholds))
 }
 
 // updateStoreMetrics updates the Prometheus metrics for rate limit store sizes.
-func (rls *rateLimitStore) updateStoreMetrics(accountsOverLimit, rateLimitedAccounts int) {
+func (rls *rateLimitStore) updateStoreMetrics(accountsOverLimit int, decisionCounts map[Decision]int) {
 	metrics.UpdateStoreSize(metrics.AccountsOverMonthlyLimitStoreType, float64(accountsOverLimit))
-	metrics.UpdateStoreSize(metrics.RateLimitedAccountsStoreType, float64(rateLimitedAccounts))
+	metrics.UpdateStoreSize(metrics.RateLimitedAccountsStoreType, float64(decisionCounts[DecisionBlock]))
+	metrics.UpdateStoreSize(metrics.ThrottledAccountsStoreType, float64(decisionCounts[DecisionThrottle]))
+	metrics.UpdateStoreSize(metrics.WarnedAccountsStoreType, float64(decisionCounts[DecisionWarn]))
 }
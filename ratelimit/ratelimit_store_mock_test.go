@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	dwh "github.com/buildwithgrove/path-external-auth-server/dwh"
 	store "github.com/buildwithgrove/path-external-auth-server/store"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -21,7 +22,6 @@ import (
 type MockaccountPortalAppStore struct {
 	ctrl     *gomock.Controller
 	recorder *MockaccountPortalAppStoreMockRecorder
-	isgomock struct{}
 }
 
 // MockaccountPortalAppStoreMockRecorder is the mock recorder for MockaccountPortalAppStore.
@@ -56,11 +56,38 @@ func (mr *MockaccountPortalAppStoreMockRecorder) GetAccountPortalApp(accountID a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountPortalApp", reflect.TypeOf((*MockaccountPortalAppStore)(nil).GetAccountPortalApp), accountID)
 }
 
+// GetPortalAppsWithMonthlyLimit mocks base method.
+func (m *MockaccountPortalAppStore) GetPortalAppsWithMonthlyLimit() []*store.PortalApp {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPortalAppsWithMonthlyLimit")
+	ret0, _ := ret[0].([]*store.PortalApp)
+	return ret0
+}
+
+// GetPortalAppsWithMonthlyLimit indicates an expected call of GetPortalAppsWithMonthlyLimit.
+func (mr *MockaccountPortalAppStoreMockRecorder) GetPortalAppsWithMonthlyLimit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPortalAppsWithMonthlyLimit", reflect.TypeOf((*MockaccountPortalAppStore)(nil).GetPortalAppsWithMonthlyLimit))
+}
+
+// GetRateLimitableAccountIDs mocks base method.
+func (m *MockaccountPortalAppStore) GetRateLimitableAccountIDs(isRateLimitable func(*store.PortalApp) bool) []store.AccountID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRateLimitableAccountIDs", isRateLimitable)
+	ret0, _ := ret[0].([]store.AccountID)
+	return ret0
+}
+
+// GetRateLimitableAccountIDs indicates an expected call of GetRateLimitableAccountIDs.
+func (mr *MockaccountPortalAppStoreMockRecorder) GetRateLimitableAccountIDs(isRateLimitable any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRateLimitableAccountIDs", reflect.TypeOf((*MockaccountPortalAppStore)(nil).GetRateLimitableAccountIDs), isRateLimitable)
+}
+
 // MockdataWarehouseDriver is a mock of dataWarehouseDriver interface.
 type MockdataWarehouseDriver struct {
 	ctrl     *gomock.Controller
 	recorder *MockdataWarehouseDriverMockRecorder
-	isgomock struct{}
 }
 
 // MockdataWarehouseDriverMockRecorder is the mock recorder for MockdataWarehouseDriver.
@@ -81,16 +108,69 @@ func (m *MockdataWarehouseDriver) EXPECT() *MockdataWarehouseDriverMockRecorder
 }
 
 // GetMonthToMomentUsage mocks base method.
-func (m *MockdataWarehouseDriver) GetMonthToMomentUsage(ctx context.Context, minRelayThreshold int64) (map[string]int64, error) {
+func (m *MockdataWarehouseDriver) GetMonthToMomentUsage(ctx context.Context, minRelayThreshold int64, accountIDs []string) (map[string]dwh.AccountUsage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMonthToMomentUsage", ctx, minRelayThreshold)
-	ret0, _ := ret[0].(map[string]int64)
+	ret := m.ctrl.Call(m, "GetMonthToMomentUsage", ctx, minRelayThreshold, accountIDs)
+	ret0, _ := ret[0].(map[string]dwh.AccountUsage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMonthToMomentUsage indicates an expected call of GetMonthToMomentUsage.
-func (mr *MockdataWarehouseDriverMockRecorder) GetMonthToMomentUsage(ctx, minRelayThreshold any) *gomock.Call {
+func (mr *MockdataWarehouseDriverMockRecorder) GetMonthToMomentUsage(ctx, minRelayThreshold, accountIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMonthToMomentUsage", reflect.TypeOf((*MockdataWarehouseDriver)(nil).GetMonthToMomentUsage), ctx, minRelayThreshold, accountIDs)
+}
+
+// GetPortalAppMonthToMomentUsage mocks base method.
+func (m *MockdataWarehouseDriver) GetPortalAppMonthToMomentUsage(ctx context.Context, portalAppIDs []string) (map[string]dwh.AccountUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPortalAppMonthToMomentUsage", ctx, portalAppIDs)
+	ret0, _ := ret[0].(map[string]dwh.AccountUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPortalAppMonthToMomentUsage indicates an expected call of GetPortalAppMonthToMomentUsage.
+func (mr *MockdataWarehouseDriverMockRecorder) GetPortalAppMonthToMomentUsage(ctx, portalAppIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPortalAppMonthToMomentUsage", reflect.TypeOf((*MockdataWarehouseDriver)(nil).GetPortalAppMonthToMomentUsage), ctx, portalAppIDs)
+}
+
+// MockplanLimitsSource is a mock of planLimitsSource interface.
+type MockplanLimitsSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockplanLimitsSourceMockRecorder
+}
+
+// MockplanLimitsSourceMockRecorder is the mock recorder for MockplanLimitsSource.
+type MockplanLimitsSourceMockRecorder struct {
+	mock *MockplanLimitsSource
+}
+
+// NewMockplanLimitsSource creates a new mock instance.
+func NewMockplanLimitsSource(ctrl *gomock.Controller) *MockplanLimitsSource {
+	mock := &MockplanLimitsSource{ctrl: ctrl}
+	mock.recorder = &MockplanLimitsSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockplanLimitsSource) EXPECT() *MockplanLimitsSourceMockRecorder {
+	return m.recorder
+}
+
+// GetPlanLimits mocks base method.
+func (m *MockplanLimitsSource) GetPlanLimits() (map[store.PlanType]int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPlanLimits")
+	ret0, _ := ret[0].(map[store.PlanType]int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPlanLimits indicates an expected call of GetPlanLimits.
+func (mr *MockplanLimitsSourceMockRecorder) GetPlanLimits() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMonthToMomentUsage", reflect.TypeOf((*MockdataWarehouseDriver)(nil).GetMonthToMomentUsage), ctx, minRelayThreshold)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPlanLimits", reflect.TypeOf((*MockplanLimitsSource)(nil).GetPlanLimits))
 }
@@ -1,14 +1,22 @@
 package ratelimit
 
 import (
+	"context"
 	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	"github.com/buildwithgrove/path-external-auth-server/directory"
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
 	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
 	"github.com/buildwithgrove/path-external-auth-server/store"
 )
@@ -20,13 +28,14 @@ func TestNewRateLimitStore(t *testing.T) {
 		expectError             bool
 		expectedInitialUpdate   bool
 		rateLimitUpdateInterval time.Duration
+		opts                    []RateLimitStoreOption
 	}{
 		{
 			name: "should create rate limit store successfully with successful initial update",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
-					Return(map[string]int64{}, nil)
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(map[string]dwh.AccountUsage{}, nil)
 			},
 			expectError:             false,
 			expectedInitialUpdate:   true,
@@ -36,13 +45,75 @@ func TestNewRateLimitStore(t *testing.T) {
 			name: "should create rate limit store successfully even with failed initial update",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(nil, errors.New("dwh connection failed"))
 			},
 			expectError:             false,
 			expectedInitialUpdate:   false,
 			rateLimitUpdateInterval: 1 * time.Minute,
 		},
+		{
+			name: "should create rate limit store after warm-up succeeds on retry",
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				gomock.InOrder(
+					mockDWH.EXPECT().
+						GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+						Return(nil, errors.New("dwh connection failed")).
+						Times(2),
+					mockDWH.EXPECT().
+						GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+						Return(map[string]dwh.AccountUsage{}, nil),
+				)
+			},
+			expectError:             false,
+			expectedInitialUpdate:   true,
+			rateLimitUpdateInterval: 1 * time.Minute,
+			opts:                    []RateLimitStoreOption{WithWarmup(1*time.Second, 10*time.Millisecond)},
+		},
+		{
+			name: "should return error if warm-up times out",
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				mockDWH.EXPECT().
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(nil, errors.New("dwh connection failed")).
+					MinTimes(1)
+			},
+			expectError:             true,
+			expectedInitialUpdate:   false,
+			rateLimitUpdateInterval: 1 * time.Minute,
+			opts:                    []RateLimitStoreOption{WithWarmup(50*time.Millisecond, 10*time.Millisecond)},
+		},
+		{
+			name: "should create rate limit store after initial load fails twice then succeeds on retry",
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				gomock.InOrder(
+					mockDWH.EXPECT().
+						GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+						Return(nil, errors.New("dwh connection failed")).
+						Times(2),
+					mockDWH.EXPECT().
+						GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+						Return(map[string]dwh.AccountUsage{}, nil),
+				)
+			},
+			expectError:             false,
+			expectedInitialUpdate:   true,
+			rateLimitUpdateInterval: 1 * time.Minute,
+			opts:                    []RateLimitStoreOption{WithInitialLoadRetry(3, 5*time.Millisecond, 10*time.Millisecond)},
+		},
+		{
+			name: "should create rate limit store even if every initial load retry fails",
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				mockDWH.EXPECT().
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(nil, errors.New("dwh connection failed")).
+					Times(3)
+			},
+			expectError:             false,
+			expectedInitialUpdate:   false,
+			rateLimitUpdateInterval: 1 * time.Minute,
+			opts:                    []RateLimitStoreOption{WithInitialLoadRetry(3, 5*time.Millisecond, 10*time.Millisecond)},
+		},
 	}
 
 	for _, test := range tests {
@@ -53,15 +124,17 @@ func TestNewRateLimitStore(t *testing.T) {
 			defer ctrl.Finish()
 
 			mockDWH := NewMockdataWarehouseDriver(ctrl)
-			mockAccountStore := NewMockaccountPortalAppStore(ctrl)
+			mockAccountStore := newTestAccountPortalAppStore(ctrl)
 
 			test.setupMocks(mockDWH, mockAccountStore)
 
 			rls, err := NewRateLimitStore(
+				context.Background(),
 				polyzero.NewLogger(),
 				mockDWH,
 				mockAccountStore,
 				test.rateLimitUpdateInterval,
+				test.opts...,
 			)
 
 			if test.expectError {
@@ -70,41 +143,133 @@ func TestNewRateLimitStore(t *testing.T) {
 			} else {
 				c.NoError(err)
 				c.NotNil(rls)
-				c.NotNil(rls.rateLimitedAccounts)
+				c.NotNil(rls.accountDecisions)
+				c.Equal(DefaultThresholds, rls.thresholds)
 				c.NotNil(rls.logger)
 				c.Equal(mockDWH, rls.dataWarehouseDriver)
 				c.Equal(mockAccountStore, rls.accountPortalAppStore)
+				c.Equal(test.expectedInitialUpdate, rls.IsAvailable())
 			}
 		})
 	}
 }
 
+func TestInitialLoad_ContextCanceled(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDWH := NewMockdataWarehouseDriver(ctrl)
+	mockDWH.EXPECT().
+		GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+		Return(nil, errors.New("dwh connection failed")).
+		Times(1)
+
+	rls := &rateLimitStore{
+		logger:                    polyzero.NewLogger(),
+		dataWarehouseDriver:       mockDWH,
+		accountPortalAppStore:     newTestAccountPortalAppStore(ctrl),
+		thresholds:                DefaultThresholds,
+		initialLoadMaxAttempts:    5,
+		initialLoadInitialBackoff: time.Minute,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := rls.initialLoad(ctx)
+	c.ErrorContains(err, "canceled after 1 attempts")
+	c.False(rls.IsAvailable())
+}
+
+func TestStartRateLimitMonitoring_ContextCanceled(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Signal every rate limit update of the monitoring started below
+	updatedCh := make(chan struct{}, 1)
+	mockDWH := NewMockdataWarehouseDriver(ctrl)
+	mockDWH.EXPECT().
+		GetMonthToMomentUsage(gomock.Any(), gomock.Any(), nil).
+		DoAndReturn(func(context.Context, int64, []string) (map[string]dwh.AccountUsage, error) {
+			select {
+			case updatedCh <- struct{}{}:
+			default:
+			}
+			return map[string]dwh.AccountUsage{}, nil
+		}).
+		MinTimes(1)
+
+	rls := &rateLimitStore{
+		logger:                polyzero.NewLogger(),
+		dataWarehouseDriver:   mockDWH,
+		accountPortalAppStore: newTestAccountPortalAppStore(ctrl),
+		accountDecisions:      make(map[store.AccountID]Decision),
+		thresholds:            DefaultThresholds,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rateLimitUpdateInterval := 10 * time.Millisecond
+	doneCh := make(chan struct{})
+	go func() {
+		rls.startRateLimitMonitoring(ctx, rateLimitUpdateInterval)
+		close(doneCh)
+	}()
+
+	select {
+	case <-updatedCh:
+	case <-time.After(50 * rateLimitUpdateInterval):
+		c.Fail("rate limit monitoring did not update the rate limited accounts")
+	}
+
+	cancel()
+
+	select {
+	case <-doneCh:
+	case <-time.After(50 * rateLimitUpdateInterval):
+		c.Fail("rate limit monitoring did not stop after the context was canceled")
+	}
+}
+
 func TestIsAccountRateLimited(t *testing.T) {
 	tests := []struct {
 		name                  string
 		accountID             store.AccountID
-		rateLimitedAccounts   map[store.AccountID]bool
+		accountDecisions      map[store.AccountID]Decision
 		expectedIsRateLimited bool
 	}{
 		{
 			name:      "should return true if account is rate limited",
 			accountID: "rate_limited_account",
-			rateLimitedAccounts: map[store.AccountID]bool{
-				"rate_limited_account": true,
+			accountDecisions: map[store.AccountID]Decision{
+				"rate_limited_account": DecisionBlock,
 			},
 			expectedIsRateLimited: true,
 		},
 		{
 			name:                  "should return false if account is not rate limited",
 			accountID:             "normal_account",
-			rateLimitedAccounts:   map[store.AccountID]bool{},
+			accountDecisions:      map[store.AccountID]Decision{},
 			expectedIsRateLimited: false,
 		},
 		{
 			name:      "should return false if account is not in rate limited map",
 			accountID: "another_account",
-			rateLimitedAccounts: map[store.AccountID]bool{
-				"rate_limited_account": true,
+			accountDecisions: map[store.AccountID]Decision{
+				"rate_limited_account": DecisionBlock,
+			},
+			expectedIsRateLimited: false,
+		},
+		{
+			name:      "should return false if account is only throttled",
+			accountID: "throttled_account",
+			accountDecisions: map[store.AccountID]Decision{
+				"throttled_account": DecisionThrottle,
 			},
 			expectedIsRateLimited: false,
 		},
@@ -115,7 +280,7 @@ func TestIsAccountRateLimited(t *testing.T) {
 			c := require.New(t)
 
 			rls := &rateLimitStore{
-				rateLimitedAccounts: test.rateLimitedAccounts,
+				accountDecisions: test.accountDecisions,
 			}
 
 			result := rls.IsAccountRateLimited(test.accountID)
@@ -124,9 +289,103 @@ func TestIsAccountRateLimited(t *testing.T) {
 	}
 }
 
+func TestGetAccountRateLimitStatus(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAccountStore := newTestAccountPortalAppStore(ctrl)
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(store.AccountID("account_free")).
+		Return(&store.PortalApp{PlanType: grovedb.PlanFree_DatabaseType, RateLimit: &store.RateLimit{}}, true)
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(store.AccountID("account_unlimited")).
+		Return(&store.PortalApp{PlanType: grovedb.PlanUnlimited_DatabaseType}, true)
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(store.AccountID("account_unknown")).
+		Return(nil, false)
+
+	rls := &rateLimitStore{
+		accountPortalAppStore: mockAccountStore,
+		accountDecisions:      map[store.AccountID]Decision{"account_free": DecisionBlock},
+		accountUsage: map[store.AccountID]dwh.AccountUsage{
+			"account_free":      {SuccessfulRelays: FreeMonthlyRelays + 1},
+			"account_unlimited": {SuccessfulRelays: 5_000_000},
+		},
+	}
+
+	status, ok := rls.GetAccountRateLimitStatus("account_free")
+	c.True(ok)
+	c.Equal(AccountRateLimitStatus{
+		PlanType:  grovedb.PlanFree_DatabaseType,
+		RateLimit: FreeMonthlyRelays,
+		Usage:     FreeMonthlyRelays + 1,
+		Decision:  DecisionBlock,
+	}, status)
+
+	status, ok = rls.GetAccountRateLimitStatus("account_unlimited")
+	c.True(ok)
+	c.Equal(AccountRateLimitStatus{
+		PlanType: grovedb.PlanUnlimited_DatabaseType,
+		Usage:    5_000_000,
+		Decision: DecisionOK,
+	}, status)
+
+	_, ok = rls.GetAccountRateLimitStatus("account_unknown")
+	c.False(ok)
+}
+
+func TestIsAvailable(t *testing.T) {
+	tests := []struct {
+		name              string
+		lastUpdated       time.Time
+		staleAfter        time.Duration
+		expectedAvailable bool
+		expectedLoaded    bool
+	}{
+		{
+			name:              "should be unavailable if no update has succeeded",
+			lastUpdated:       time.Time{},
+			staleAfter:        time.Minute,
+			expectedAvailable: false,
+			expectedLoaded:    false,
+		},
+		{
+			name:              "should be available if last update is within the stale threshold",
+			lastUpdated:       time.Now().Add(-30 * time.Second),
+			staleAfter:        time.Minute,
+			expectedAvailable: true,
+			expectedLoaded:    true,
+		},
+		{
+			name:              "should be unavailable but loaded if last update is older than the stale threshold",
+			lastUpdated:       time.Now().Add(-2 * time.Minute),
+			staleAfter:        time.Minute,
+			expectedAvailable: false,
+			expectedLoaded:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			rls := &rateLimitStore{
+				lastUpdated: test.lastUpdated,
+				staleAfter:  test.staleAfter,
+			}
+
+			c.Equal(test.expectedAvailable, rls.IsAvailable())
+			c.Equal(test.expectedLoaded, rls.HasLoaded())
+		})
+	}
+}
+
 func TestUpdateRateLimitedAccounts(t *testing.T) {
 	tests := []struct {
 		name                     string
+		failedRelayWeights       FailedRelayWeights
 		setupMocks               func(*MockdataWarehouseDriver, *MockaccountPortalAppStore)
 		expectedRateLimitedCount int
 		expectError              bool
@@ -134,11 +393,11 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 		{
 			name: "should update rate limited accounts with free plan account over limit",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
-				usageData := map[string]int64{
-					"free_account_over_limit": FreeMonthlyRelays + 1000,
+				usageData := map[string]dwh.AccountUsage{
+					"free_account_over_limit": {SuccessfulRelays: FreeMonthlyRelays + 1000},
 				}
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(usageData, nil)
 
 				mockAccountStore.EXPECT().
@@ -153,14 +412,58 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 			expectedRateLimitedCount: 1,
 			expectError:              false,
 		},
+		{
+			name: "should not rate limit free plan account over global limit but under its bonus limit",
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				usageData := map[string]dwh.AccountUsage{
+					"free_account_with_bonus": {SuccessfulRelays: FreeMonthlyRelays + 1000},
+				}
+				mockDWH.EXPECT().
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(usageData, nil)
+
+				mockAccountStore.EXPECT().
+					GetAccountPortalApp(store.AccountID("free_account_with_bonus")).
+					Return(&store.PortalApp{
+						PlanType: grovedb.PlanFree_DatabaseType,
+						RateLimit: &store.RateLimit{
+							FreeMonthlyRelayBonus: 500_000,
+						},
+					}, true)
+			},
+			expectedRateLimitedCount: 0,
+			expectError:              false,
+		},
+		{
+			name: "should rate limit free plan account over its bonus limit",
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				usageData := map[string]dwh.AccountUsage{
+					"free_account_over_bonus": {SuccessfulRelays: FreeMonthlyRelays + 500_001},
+				}
+				mockDWH.EXPECT().
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(usageData, nil)
+
+				mockAccountStore.EXPECT().
+					GetAccountPortalApp(store.AccountID("free_account_over_bonus")).
+					Return(&store.PortalApp{
+						PlanType: grovedb.PlanFree_DatabaseType,
+						RateLimit: &store.RateLimit{
+							FreeMonthlyRelayBonus: 500_000,
+						},
+					}, true)
+			},
+			expectedRateLimitedCount: 1,
+			expectError:              false,
+		},
 		{
 			name: "should not rate limit free plan account under limit",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
-				usageData := map[string]int64{
-					"free_account_under_limit": FreeMonthlyRelays - 1000,
+				usageData := map[string]dwh.AccountUsage{
+					"free_account_under_limit": {SuccessfulRelays: FreeMonthlyRelays - 1000},
 				}
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(usageData, nil)
 
 				mockAccountStore.EXPECT().
@@ -178,11 +481,11 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 		{
 			name: "should rate limit unlimited plan account over custom limit",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
-				usageData := map[string]int64{
-					"unlimited_account_over_custom_limit": 500_000,
+				usageData := map[string]dwh.AccountUsage{
+					"unlimited_account_over_custom_limit": {SuccessfulRelays: 500_000},
 				}
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(usageData, nil)
 
 				mockAccountStore.EXPECT().
@@ -200,11 +503,11 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 		{
 			name: "should not rate limit unlimited plan account with no limit set",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
-				usageData := map[string]int64{
-					"unlimited_account_no_limit": FreeMonthlyRelays,
+				usageData := map[string]dwh.AccountUsage{
+					"unlimited_account_no_limit": {SuccessfulRelays: FreeMonthlyRelays},
 				}
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(usageData, nil)
 
 				mockAccountStore.EXPECT().
@@ -222,11 +525,11 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 		{
 			name: "should skip accounts without rate limit configuration",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
-				usageData := map[string]int64{
-					"account_without_config": 500_000,
+				usageData := map[string]dwh.AccountUsage{
+					"account_without_config": {SuccessfulRelays: 500_000},
 				}
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(usageData, nil)
 
 				mockAccountStore.EXPECT().
@@ -239,11 +542,11 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 		{
 			name: "should handle unknown plan types gracefully",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
-				usageData := map[string]int64{
-					"account_unknown_plan": 500_000,
+				usageData := map[string]dwh.AccountUsage{
+					"account_unknown_plan": {SuccessfulRelays: 500_000},
 				}
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(usageData, nil)
 
 				mockAccountStore.EXPECT().
@@ -258,11 +561,86 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 			expectedRateLimitedCount: 0,
 			expectError:              false,
 		},
+		{
+			name: "should count failed relays fully toward usage by default",
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				usageData := map[string]dwh.AccountUsage{
+					"free_account_with_failed_relays": {SuccessfulRelays: FreeMonthlyRelays - 1000, FailedRelays: 2000},
+				}
+				mockDWH.EXPECT().
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(usageData, nil)
+
+				mockAccountStore.EXPECT().
+					GetAccountPortalApp(store.AccountID("free_account_with_failed_relays")).
+					Return(&store.PortalApp{
+						PlanType:  grovedb.PlanFree_DatabaseType,
+						RateLimit: &store.RateLimit{},
+					}, true)
+			},
+			expectedRateLimitedCount: 1,
+			expectError:              false,
+		},
+		{
+			name: "should exclude failed relays from usage for plan with zero weight",
+			failedRelayWeights: FailedRelayWeights{
+				grovedb.PlanFree_DatabaseType: 0,
+			},
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				usageData := map[string]dwh.AccountUsage{
+					"free_account_with_failed_relays": {SuccessfulRelays: FreeMonthlyRelays - 1000, FailedRelays: 2000},
+				}
+				mockDWH.EXPECT().
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(usageData, nil)
+
+				mockAccountStore.EXPECT().
+					GetAccountPortalApp(store.AccountID("free_account_with_failed_relays")).
+					Return(&store.PortalApp{
+						PlanType:  grovedb.PlanFree_DatabaseType,
+						RateLimit: &store.RateLimit{},
+					}, true)
+			},
+			expectedRateLimitedCount: 0,
+			expectError:              false,
+		},
+		{
+			name: "should weight failed relays only for the configured plan",
+			failedRelayWeights: FailedRelayWeights{
+				grovedb.PlanFree_DatabaseType: 0.5,
+			},
+			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
+				usageData := map[string]dwh.AccountUsage{
+					"free_account_weighted":          {SuccessfulRelays: FreeMonthlyRelays - 1000, FailedRelays: 1800},
+					"unlimited_account_not_weighted": {SuccessfulRelays: 90_000, FailedRelays: 20_000},
+				}
+				mockDWH.EXPECT().
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+					Return(usageData, nil)
+
+				mockAccountStore.EXPECT().
+					GetAccountPortalApp(store.AccountID("free_account_weighted")).
+					Return(&store.PortalApp{
+						PlanType:  grovedb.PlanFree_DatabaseType,
+						RateLimit: &store.RateLimit{},
+					}, true)
+				mockAccountStore.EXPECT().
+					GetAccountPortalApp(store.AccountID("unlimited_account_not_weighted")).
+					Return(&store.PortalApp{
+						PlanType: grovedb.PlanUnlimited_DatabaseType,
+						RateLimit: &store.RateLimit{
+							MonthlyUserLimit: 100_000,
+						},
+					}, true)
+			},
+			expectedRateLimitedCount: 1,
+			expectError:              false,
+		},
 		{
 			name: "should return error when data warehouse fails",
 			setupMocks: func(mockDWH *MockdataWarehouseDriver, mockAccountStore *MockaccountPortalAppStore) {
 				mockDWH.EXPECT().
-					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+					GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 					Return(nil, errors.New("data warehouse connection failed"))
 			},
 			expectedRateLimitedCount: 0,
@@ -278,7 +656,7 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 			defer ctrl.Finish()
 
 			mockDWH := NewMockdataWarehouseDriver(ctrl)
-			mockAccountStore := NewMockaccountPortalAppStore(ctrl)
+			mockAccountStore := newTestAccountPortalAppStore(ctrl)
 
 			test.setupMocks(mockDWH, mockAccountStore)
 
@@ -286,51 +664,38 @@ func TestUpdateRateLimitedAccounts(t *testing.T) {
 				logger:                polyzero.NewLogger(),
 				dataWarehouseDriver:   mockDWH,
 				accountPortalAppStore: mockAccountStore,
-				rateLimitedAccounts:   make(map[store.AccountID]bool),
+				thresholds:            DefaultThresholds,
+				failedRelayWeights:    test.failedRelayWeights,
+				accountDecisions:      make(map[store.AccountID]Decision),
 			}
 
-			err := rls.updateRateLimitedAccounts()
+			err := rls.updateRateLimitedAccounts(context.Background())
 
 			if test.expectError {
 				c.Error(err)
 			} else {
 				c.NoError(err)
-				c.Equal(test.expectedRateLimitedCount, len(rls.rateLimitedAccounts))
+				c.Equal(test.expectedRateLimitedCount, countDecisions(rls.accountDecisions, DecisionBlock))
 			}
 		})
 	}
 }
 
-func TestShouldLimitAccount(t *testing.T) {
+func TestUpdateRateLimitedAccounts_RateLimitableAccountFilter(t *testing.T) {
 	tests := []struct {
-		name           string
-		rateLimit      int32
-		usage          int64
-		expectedResult bool
+		name               string
+		filterEnabled      bool
+		expectedAccountIDs []string
 	}{
 		{
-			name:           "should limit account over rate limit",
-			rateLimit:      1000_000,
-			usage:          1000_001,
-			expectedResult: true,
-		},
-		{
-			name:           "should not limit account under rate limit",
-			rateLimit:      1000_000,
-			usage:          999_999,
-			expectedResult: false,
-		},
-		{
-			name:           "should not limit account exactly at rate limit",
-			rateLimit:      1000_000,
-			usage:          1000_000,
-			expectedResult: false, // > comparison, so exactly at limit is not limited
+			name:               "should query usage for all accounts if filter is disabled",
+			filterEnabled:      false,
+			expectedAccountIDs: nil,
 		},
 		{
-			name:           "should not limit account with zero rate limit",
-			rateLimit:      0,
-			usage:          500_000,
-			expectedResult: false,
+			name:               "should query usage for rate-limitable accounts only if filter is enabled",
+			filterEnabled:      true,
+			expectedAccountIDs: []string{"account_free"},
 		},
 	}
 
@@ -338,69 +703,55 @@ func TestShouldLimitAccount(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			c := require.New(t)
 
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDWH := NewMockdataWarehouseDriver(ctrl)
+			mockAccountStore := newTestAccountPortalAppStore(ctrl)
+
+			if test.filterEnabled {
+				mockAccountStore.EXPECT().GetRateLimitableAccountIDs(gomock.Any()).Return([]store.AccountID{"account_free"})
+			}
+			mockDWH.EXPECT().
+				GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), test.expectedAccountIDs).
+				Return(map[string]dwh.AccountUsage{}, nil)
+
 			rls := &rateLimitStore{
-				logger: polyzero.NewLogger(),
+				logger:                      polyzero.NewLogger(),
+				dataWarehouseDriver:         mockDWH,
+				accountPortalAppStore:       mockAccountStore,
+				thresholds:                  DefaultThresholds,
+				filterRateLimitableAccounts: test.filterEnabled,
+				accountDecisions:            make(map[store.AccountID]Decision),
 			}
 
-			result := rls.shouldLimitAccount(test.rateLimit, test.usage)
-			c.Equal(test.expectedResult, result)
+			c.NoError(rls.updateRateLimitedAccounts(context.Background()))
 		})
 	}
 }
 
-func TestGetRateLimit(t *testing.T) {
+func TestUpdateRateLimitedAccounts_AllAccountUsageMetrics(t *testing.T) {
 	tests := []struct {
-		name              string
-		portalApp         *store.PortalApp
-		expectedRateLimit int32
+		name                  string
+		recordAllAccountUsage bool
+		// Account IDs are unique per test case, so usage recorded by other test cases is not counted
+		freeAccountID           store.AccountID
+		unlimitedAccountID      store.AccountID
+		expectUnlimitedRecorded bool
 	}{
 		{
-			name: "should return free tier limit for free plan",
-			portalApp: &store.PortalApp{
-				PlanType: grovedb.PlanFree_DatabaseType,
-				RateLimit: &store.RateLimit{
-					MonthlyUserLimit: 0,
-				},
-			},
-			expectedRateLimit: FreeMonthlyRelays,
-		},
-		{
-			name: "should return custom limit for unlimited plan with limit set",
-			portalApp: &store.PortalApp{
-				PlanType: grovedb.PlanUnlimited_DatabaseType,
-				RateLimit: &store.RateLimit{
-					MonthlyUserLimit: 500_000,
-				},
-			},
-			expectedRateLimit: 500_000,
-		},
-		{
-			name: "should return zero for unlimited plan with no limit set",
-			portalApp: &store.PortalApp{
-				PlanType: grovedb.PlanUnlimited_DatabaseType,
-				RateLimit: &store.RateLimit{
-					MonthlyUserLimit: 0,
-				},
-			},
-			expectedRateLimit: 0,
-		},
-		{
-			name: "should return zero for portal app with no rate limit configured",
-			portalApp: &store.PortalApp{
-				PlanType:  grovedb.PlanFree_DatabaseType,
-				RateLimit: nil,
-			},
-			expectedRateLimit: 0,
+			name:                    "should only record usage for accounts with a rate limit if disabled",
+			recordAllAccountUsage:   false,
+			freeAccountID:           "account_usage_free_disabled",
+			unlimitedAccountID:      "account_usage_unlimited_disabled",
+			expectUnlimitedRecorded: false,
 		},
 		{
-			name: "should return zero for unknown plan type",
-			portalApp: &store.PortalApp{
-				PlanType: "PLAN_UNKNOWN",
-				RateLimit: &store.RateLimit{
-					MonthlyUserLimit: 100_000,
-				},
-			},
-			expectedRateLimit: 0,
+			name:                    "should record usage for accounts with no rate limit if enabled",
+			recordAllAccountUsage:   true,
+			freeAccountID:           "account_usage_free_enabled",
+			unlimitedAccountID:      "account_usage_unlimited_enabled",
+			expectUnlimitedRecorded: true,
 		},
 	}
 
@@ -408,36 +759,931 @@ func TestGetRateLimit(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			c := require.New(t)
 
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDWH := NewMockdataWarehouseDriver(ctrl)
+			mockAccountStore := newTestAccountPortalAppStore(ctrl)
+
+			mockDWH.EXPECT().
+				GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+				Return(map[string]dwh.AccountUsage{
+					string(test.freeAccountID):      {SuccessfulRelays: FreeMonthlyRelays + 1000},
+					string(test.unlimitedAccountID): {SuccessfulRelays: 25_000_000, FailedRelays: 1000},
+				}, nil)
+			mockAccountStore.EXPECT().
+				GetAccountPortalApp(test.freeAccountID).
+				Return(&store.PortalApp{
+					PlanType:  grovedb.PlanFree_DatabaseType,
+					RateLimit: &store.RateLimit{},
+				}, true)
+			mockAccountStore.EXPECT().
+				GetAccountPortalApp(test.unlimitedAccountID).
+				Return(&store.PortalApp{
+					PlanType:  grovedb.PlanUnlimited_DatabaseType,
+					RateLimit: &store.RateLimit{},
+				}, true)
+
 			rls := &rateLimitStore{
-				logger: polyzero.NewLogger(),
+				logger:                polyzero.NewLogger(),
+				dataWarehouseDriver:   mockDWH,
+				accountPortalAppStore: mockAccountStore,
+				thresholds:            DefaultThresholds,
+				accountDecisions:      make(map[store.AccountID]Decision),
 			}
+			WithAllAccountUsageMetrics(test.recordAllAccountUsage)(rls)
 
-			result := rls.getRateLimit(test.portalApp)
-			c.Equal(test.expectedRateLimit, result)
+			c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+
+			usage, ok := getAccountUsageMetric(t, test.freeAccountID)
+			c.True(ok)
+			c.Equal(float64(FreeMonthlyRelays+1000), usage)
+
+			usage, ok = getAccountUsageMetric(t, test.unlimitedAccountID)
+			c.Equal(test.expectUnlimitedRecorded, ok)
+			if test.expectUnlimitedRecorded {
+				c.Equal(float64(25_001_000), usage)
+			}
+
+			// Accounts with no rate limit are never rate limited
+			c.Equal(DecisionOK, rls.GetAccountRateLimitDecision(test.unlimitedAccountID))
 		})
 	}
 }
 
-func TestRateLimitStoreIntegration(t *testing.T) {
-	t.Run("should handle complete rate limiting workflow", func(t *testing.T) {
-		c := require.New(t)
-
-		ctrl := gomock.NewController(t)
-		defer ctrl.Finish()
-
-		mockDWH := NewMockdataWarehouseDriver(ctrl)
-		mockAccountStore := NewMockaccountPortalAppStore(ctrl)
+// getAccountUsageMetric returns the recorded account usage gauge value for the account, if any.
+func getAccountUsageMetric(t *testing.T, accountID store.AccountID) (float64, bool) {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
 
-		// Setup initial data - one account over limit, one under
-		initialUsageData := map[string]int64{
-			"free_account_over":  FreeMonthlyRelays + 5000,
-			"free_account_under": FreeMonthlyRelays - 5000,
-			"unlimited_account":  500_000,
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_account_usage_total" {
+			continue
 		}
-
-		// First call during NewRateLimitStore
-		mockDWH.EXPECT().
-			GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays)).
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "account_id" && label.GetValue() == string(accountID) {
+					return metric.GetGauge().GetValue(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestEvaluateUsage(t *testing.T) {
+	tieredThresholds := []Threshold{
+		{Decision: DecisionWarn, UsageRatio: 0.8},
+		{Decision: DecisionThrottle, UsageRatio: 1.0},
+		{Decision: DecisionBlock, UsageRatio: 1.2},
+	}
+
+	tests := []struct {
+		name             string
+		thresholds       []Threshold
+		rateLimit        int32
+		usage            int64
+		expectedDecision Decision
+	}{
+		{
+			name:             "should limit account over rate limit",
+			thresholds:       DefaultThresholds,
+			rateLimit:        1000_000,
+			usage:            1000_001,
+			expectedDecision: DecisionBlock,
+		},
+		{
+			name:             "should not limit account under rate limit",
+			thresholds:       DefaultThresholds,
+			rateLimit:        1000_000,
+			usage:            999_999,
+			expectedDecision: DecisionOK,
+		},
+		{
+			name:             "should not limit account exactly at rate limit",
+			thresholds:       DefaultThresholds,
+			rateLimit:        1000_000,
+			usage:            1000_000,
+			expectedDecision: DecisionOK, // > comparison, so exactly at limit is not limited
+		},
+		{
+			name:             "should not limit account with zero rate limit",
+			thresholds:       DefaultThresholds,
+			rateLimit:        0,
+			usage:            500_000,
+			expectedDecision: DecisionOK,
+		},
+		{
+			name:             "should return ok for tiered account under the warn threshold",
+			thresholds:       tieredThresholds,
+			rateLimit:        1000_000,
+			usage:            800_000,
+			expectedDecision: DecisionOK,
+		},
+		{
+			name:             "should warn tiered account over the warn threshold",
+			thresholds:       tieredThresholds,
+			rateLimit:        1000_000,
+			usage:            800_001,
+			expectedDecision: DecisionWarn,
+		},
+		{
+			name:             "should throttle tiered account over the throttle threshold",
+			thresholds:       tieredThresholds,
+			rateLimit:        1000_000,
+			usage:            1000_001,
+			expectedDecision: DecisionThrottle,
+		},
+		{
+			name:             "should block tiered account over the block threshold",
+			thresholds:       tieredThresholds,
+			rateLimit:        1000_000,
+			usage:            1200_001,
+			expectedDecision: DecisionBlock,
+		},
+		{
+			name:             "should not limit tiered account with zero rate limit",
+			thresholds:       tieredThresholds,
+			rateLimit:        0,
+			usage:            5000_000,
+			expectedDecision: DecisionOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			rls := &rateLimitStore{
+				logger:     polyzero.NewLogger(),
+				thresholds: test.thresholds,
+			}
+
+			result := rls.evaluateUsage(test.rateLimit, test.usage)
+			c.Equal(test.expectedDecision, result)
+		})
+	}
+}
+
+func TestUpdateRateLimitedAccountsWithTieredThresholds(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDWH := NewMockdataWarehouseDriver(ctrl)
+	mockAccountStore := newTestAccountPortalAppStore(ctrl)
+
+	// The lowest threshold (warn at 80%) determines the minimum usage fetched from the data warehouse.
+	mockDWH.EXPECT().
+		GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays*0.8), nil).
+		Return(map[string]dwh.AccountUsage{
+			"free_account_ok":        {SuccessfulRelays: FreeMonthlyRelays * 0.7},
+			"free_account_warned":    {SuccessfulRelays: FreeMonthlyRelays * 0.9},
+			"free_account_throttled": {SuccessfulRelays: FreeMonthlyRelays * 1.1},
+			"free_account_blocked":   {SuccessfulRelays: FreeMonthlyRelays * 1.3},
+		}, nil)
+
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(gomock.Any()).
+		Return(&store.PortalApp{
+			PlanType:  grovedb.PlanFree_DatabaseType,
+			RateLimit: &store.RateLimit{},
+		}, true).
+		Times(4)
+
+	rls := &rateLimitStore{
+		logger:                polyzero.NewLogger(),
+		dataWarehouseDriver:   mockDWH,
+		accountPortalAppStore: mockAccountStore,
+		accountDecisions:      make(map[store.AccountID]Decision),
+	}
+	WithThresholds([]Threshold{
+		{Decision: DecisionBlock, UsageRatio: 1.2},
+		{Decision: DecisionWarn, UsageRatio: 0.8},
+		{Decision: DecisionThrottle, UsageRatio: 1.0},
+	})(rls)
+
+	c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+
+	c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("free_account_ok"))
+	c.Equal(DecisionWarn, rls.GetAccountRateLimitDecision("free_account_warned"))
+	c.Equal(DecisionThrottle, rls.GetAccountRateLimitDecision("free_account_throttled"))
+	c.Equal(DecisionBlock, rls.GetAccountRateLimitDecision("free_account_blocked"))
+
+	c.False(rls.IsAccountRateLimited("free_account_throttled"))
+	c.True(rls.IsAccountRateLimited("free_account_blocked"))
+}
+
+func TestUpdateRateLimitedAccounts_PortalAppLimits(t *testing.T) {
+	freeAccountPortalApp := &store.PortalApp{
+		AccountID: "free_account",
+		PlanType:  grovedb.PlanFree_DatabaseType,
+		RateLimit: &store.RateLimit{},
+	}
+	limitedPortalApp := &store.PortalApp{
+		ID:        "portal_app_limited",
+		AccountID: "free_account",
+		PlanType:  grovedb.PlanFree_DatabaseType,
+		RateLimit: &store.RateLimit{PortalAppMonthlyLimit: 1_000},
+	}
+	otherLimitedPortalApp := &store.PortalApp{
+		ID:        "portal_app_other",
+		AccountID: "free_account",
+		PlanType:  grovedb.PlanFree_DatabaseType,
+		RateLimit: &store.RateLimit{PortalAppMonthlyLimit: 1_000},
+	}
+
+	tests := []struct {
+		name                          string
+		accountUsage                  int64
+		portalAppUsage                map[string]dwh.AccountUsage
+		expectedAccountRateLimited    bool
+		expectedRateLimitedPortalApps []store.PortalAppID
+	}{
+		{
+			name:         "should rate limit a portal app over its own limit while its account is within its limit",
+			accountUsage: FreeMonthlyRelays / 2,
+			portalAppUsage: map[string]dwh.AccountUsage{
+				"portal_app_limited": {SuccessfulRelays: 900, FailedRelays: 200},
+				"portal_app_other":   {SuccessfulRelays: 500},
+			},
+			expectedRateLimitedPortalApps: []store.PortalAppID{"portal_app_limited"},
+		},
+		{
+			name:         "should not rate limit portal apps within their own limit while their account is over its limit",
+			accountUsage: FreeMonthlyRelays * 2,
+			portalAppUsage: map[string]dwh.AccountUsage{
+				"portal_app_limited": {SuccessfulRelays: 500},
+			},
+			expectedAccountRateLimited: true,
+		},
+		{
+			name:         "should not warn or throttle portal apps approaching their own limit",
+			accountUsage: FreeMonthlyRelays / 2,
+			portalAppUsage: map[string]dwh.AccountUsage{
+				"portal_app_limited": {SuccessfulRelays: 950},
+				"portal_app_other":   {SuccessfulRelays: 1_000},
+			},
+		},
+		{
+			name:         "should rate limit every portal app over its own limit",
+			accountUsage: FreeMonthlyRelays * 2,
+			portalAppUsage: map[string]dwh.AccountUsage{
+				"portal_app_limited": {SuccessfulRelays: 2_000},
+				"portal_app_other":   {SuccessfulRelays: 1_001},
+			},
+			expectedAccountRateLimited:    true,
+			expectedRateLimitedPortalApps: []store.PortalAppID{"portal_app_limited", "portal_app_other"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDWH := NewMockdataWarehouseDriver(ctrl)
+			mockAccountStore := NewMockaccountPortalAppStore(ctrl)
+
+			mockDWH.EXPECT().
+				GetMonthToMomentUsage(gomock.Any(), gomock.Any(), nil).
+				Return(map[string]dwh.AccountUsage{"free_account": {SuccessfulRelays: test.accountUsage}}, nil)
+			mockAccountStore.EXPECT().GetAccountPortalApp(store.AccountID("free_account")).Return(freeAccountPortalApp, true)
+
+			// Only portal apps with a limit of their own are queried
+			mockAccountStore.EXPECT().
+				GetPortalAppsWithMonthlyLimit().
+				Return([]*store.PortalApp{limitedPortalApp, otherLimitedPortalApp})
+			mockDWH.EXPECT().
+				GetPortalAppMonthToMomentUsage(gomock.Any(), []string{"portal_app_limited", "portal_app_other"}).
+				Return(test.portalAppUsage, nil)
+
+			rls := &rateLimitStore{
+				logger:                polyzero.NewLogger(),
+				dataWarehouseDriver:   mockDWH,
+				accountPortalAppStore: mockAccountStore,
+				thresholds:            DefaultThresholds,
+				accountDecisions:      make(map[store.AccountID]Decision),
+			}
+
+			c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+
+			c.Equal(test.expectedAccountRateLimited, rls.IsAccountRateLimited("free_account"))
+			for _, portalApp := range []*store.PortalApp{limitedPortalApp, otherLimitedPortalApp} {
+				c.Equal(
+					slices.Contains(test.expectedRateLimitedPortalApps, portalApp.ID),
+					rls.IsPortalAppRateLimited(portalApp.ID),
+					"portal app %s", portalApp.ID,
+				)
+			}
+		})
+	}
+}
+
+func TestUpdateRateLimitedAccounts_PortalAppLimitsError(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDWH := NewMockdataWarehouseDriver(ctrl)
+	mockAccountStore := NewMockaccountPortalAppStore(ctrl)
+
+	mockDWH.EXPECT().GetMonthToMomentUsage(gomock.Any(), gomock.Any(), nil).Return(map[string]dwh.AccountUsage{}, nil)
+	mockAccountStore.EXPECT().
+		GetPortalAppsWithMonthlyLimit().
+		Return([]*store.PortalApp{{ID: "portal_app_limited", RateLimit: &store.RateLimit{PortalAppMonthlyLimit: 1_000}}})
+	mockDWH.EXPECT().GetPortalAppMonthToMomentUsage(gomock.Any(), gomock.Any()).Return(nil, errors.New("query failed"))
+
+	rls := &rateLimitStore{
+		logger:                polyzero.NewLogger(),
+		dataWarehouseDriver:   mockDWH,
+		accountPortalAppStore: mockAccountStore,
+		thresholds:            DefaultThresholds,
+		accountDecisions:      map[store.AccountID]Decision{"free_account": DecisionBlock},
+		rateLimitedPortalApps: map[store.PortalAppID]struct{}{"portal_app_limited": {}},
+	}
+
+	// A failed portal app usage query keeps the previous account and portal app decisions
+	c.Error(rls.updateRateLimitedAccounts(context.Background()))
+	c.True(rls.IsAccountRateLimited("free_account"))
+	c.True(rls.IsPortalAppRateLimited("portal_app_limited"))
+}
+
+func TestUpdateRateLimitedAccounts_PlanLimits(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDWH := NewMockdataWarehouseDriver(ctrl)
+	mockAccountStore := newTestAccountPortalAppStore(ctrl)
+	mockPlanLimitsSource := NewMockplanLimitsSource(ctrl)
+
+	// The first update loads the plan limits, the second fails to load them
+	gomock.InOrder(
+		mockPlanLimitsSource.EXPECT().
+			GetPlanLimits().
+			Return(map[store.PlanType]int32{
+				grovedb.PlanFree_DatabaseType: 500_000,
+				"PLAN_PRO":                    2_000_000,
+			}, nil),
+		mockPlanLimitsSource.EXPECT().
+			GetPlanLimits().
+			Return(nil, errors.New("database error")),
+	)
+
+	// The lowest loaded plan limit determines the minimum usage fetched from the data warehouse,
+	// and the previously loaded plan limits are kept when loading fails.
+	mockDWH.EXPECT().
+		GetMonthToMomentUsage(gomock.Any(), int64(500_000), nil).
+		Return(map[string]dwh.AccountUsage{
+			"free_account": {SuccessfulRelays: 600_000},
+			"pro_account":  {SuccessfulRelays: 1_500_000},
+		}, nil).
+		Times(2)
+
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(store.AccountID("free_account")).
+		Return(&store.PortalApp{
+			PlanType:  grovedb.PlanFree_DatabaseType,
+			RateLimit: &store.RateLimit{},
+		}, true).
+		Times(2)
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(store.AccountID("pro_account")).
+		Return(&store.PortalApp{
+			PlanType:  "PLAN_PRO",
+			RateLimit: &store.RateLimit{},
+		}, true).
+		Times(2)
+
+	rls := &rateLimitStore{
+		logger:                polyzero.NewLogger(),
+		dataWarehouseDriver:   mockDWH,
+		accountPortalAppStore: mockAccountStore,
+		accountDecisions:      make(map[store.AccountID]Decision),
+		thresholds:            DefaultThresholds,
+	}
+	WithPlanLimits(mockPlanLimitsSource)(rls)
+
+	for range 2 {
+		c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+
+		c.Equal(DecisionBlock, rls.GetAccountRateLimitDecision("free_account"))
+		c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("pro_account"))
+	}
+}
+
+func TestUpdateRateLimitedAccounts_StrictUnknownPlans(t *testing.T) {
+	tests := []struct {
+		name                     string
+		strictUnknownPlans       bool
+		expectedUnknownDecision  Decision
+		expectedRateLimitedCount float64
+	}{
+		{
+			name:                    "should not rate limit accounts with an unknown plan type by default",
+			strictUnknownPlans:      false,
+			expectedUnknownDecision: DecisionOK,
+		},
+		{
+			name:                     "should rate limit accounts with an unknown plan type regardless of usage in strict mode",
+			strictUnknownPlans:       true,
+			expectedUnknownDecision:  DecisionBlock,
+			expectedRateLimitedCount: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDWH := NewMockdataWarehouseDriver(ctrl)
+			mockAccountStore := newTestAccountPortalAppStore(ctrl)
+
+			portalApps := map[store.AccountID]*store.PortalApp{
+				// Unknown plan types: over the free limit, and below the minimum usage fetched from the data warehouse
+				"unknown_plan_account_over_limit": {PlanType: "PLAN_ENTERPRISE", RateLimit: &store.RateLimit{}},
+				"unknown_plan_account_no_usage":   {PlanType: "PLAN_ENTERPRISE", RateLimit: &store.RateLimit{}},
+				// Known plan types: built-in and with a loaded plan limit
+				"free_account":      {PlanType: grovedb.PlanFree_DatabaseType, RateLimit: &store.RateLimit{}},
+				"unlimited_account": {PlanType: grovedb.PlanUnlimited_DatabaseType, RateLimit: &store.RateLimit{}},
+				"pro_account":       {PlanType: "PLAN_PRO", RateLimit: &store.RateLimit{}},
+				// Unknown plan type without a rate limit configured
+				"unknown_plan_account_no_rate_limit": {PlanType: "PLAN_ENTERPRISE"},
+				// Unknown plan types with an explicit monthly user limit: over and within it
+				"unknown_plan_account_over_user_limit":   {PlanType: "PLAN_ENTERPRISE", RateLimit: &store.RateLimit{MonthlyUserLimit: 500_000}},
+				"unknown_plan_account_within_user_limit": {PlanType: "PLAN_ENTERPRISE", RateLimit: &store.RateLimit{MonthlyUserLimit: 5_000_000}},
+			}
+
+			mockDWH.EXPECT().
+				GetMonthToMomentUsage(gomock.Any(), gomock.Any(), nil).
+				Return(map[string]dwh.AccountUsage{
+					"unknown_plan_account_over_limit":        {SuccessfulRelays: FreeMonthlyRelays + 1000},
+					"unknown_plan_account_over_user_limit":   {SuccessfulRelays: FreeMonthlyRelays + 1000},
+					"unknown_plan_account_within_user_limit": {SuccessfulRelays: FreeMonthlyRelays + 1000},
+					"free_account":                           {SuccessfulRelays: 1000},
+				}, nil).
+				Times(1)
+
+			mockAccountStore.EXPECT().
+				GetAccountPortalApp(gomock.Any()).
+				DoAndReturn(func(accountID store.AccountID) (*store.PortalApp, bool) {
+					portalApp, ok := portalApps[accountID]
+					return portalApp, ok
+				}).
+				AnyTimes()
+			mockAccountStore.EXPECT().
+				GetRateLimitableAccountIDs(gomock.Any()).
+				DoAndReturn(func(isRateLimitable func(*store.PortalApp) bool) []store.AccountID {
+					var accountIDs []store.AccountID
+					for accountID, portalApp := range portalApps {
+						if isRateLimitable(portalApp) {
+							accountIDs = append(accountIDs, accountID)
+						}
+					}
+					return accountIDs
+				}).
+				AnyTimes()
+
+			rls := &rateLimitStore{
+				logger:                polyzero.NewLogger(),
+				dataWarehouseDriver:   mockDWH,
+				accountPortalAppStore: mockAccountStore,
+				thresholds:            DefaultThresholds,
+				planLimits:            map[store.PlanType]int32{"PLAN_PRO": 2_000_000},
+				accountDecisions:      make(map[store.AccountID]Decision),
+				accountUsage:          make(map[store.AccountID]dwh.AccountUsage),
+			}
+			WithStrictUnknownPlans(test.strictUnknownPlans)(rls)
+
+			c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+
+			c.Equal(test.expectedUnknownDecision, rls.GetAccountRateLimitDecision("unknown_plan_account_over_limit"))
+			c.Equal(test.expectedUnknownDecision, rls.GetAccountRateLimitDecision("unknown_plan_account_no_usage"))
+			c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("free_account"))
+			c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("unlimited_account"))
+			c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("pro_account"))
+			c.Equal(test.expectedUnknownDecision, rls.GetAccountRateLimitDecision("unknown_plan_account_no_rate_limit"))
+			// An explicit monthly user limit is enforced instead of rate limiting regardless of usage
+			c.Equal(test.expectedUnknownDecision, rls.GetAccountRateLimitDecision("unknown_plan_account_over_user_limit"))
+			c.Equal(DecisionOK, rls.GetAccountRateLimitDecision("unknown_plan_account_within_user_limit"))
+
+			// Re-evaluation after a plan change applies the same unknown plan handling
+			rls.ReevaluateAccounts([]store.AccountID{"unknown_plan_account_no_usage"})
+			c.Equal(test.expectedUnknownDecision, rls.GetAccountRateLimitDecision("unknown_plan_account_no_usage"))
+
+			if test.strictUnknownPlans {
+				c.Equal(test.expectedRateLimitedCount, getUnknownPlanRateLimitedAccountsMetric(t))
+			}
+		})
+	}
+}
+
+// getUnknownPlanRateLimitedAccountsMetric returns the recorded unknown plan rate limited accounts gauge value.
+func getUnknownPlanRateLimitedAccountsMetric(t *testing.T) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_unknown_plan_rate_limited_accounts" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			return metric.GetGauge().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestReevaluateAccounts(t *testing.T) {
+	tests := []struct {
+		name             string
+		initialPortalApp *store.PortalApp
+		updatedPortalApp *store.PortalApp
+		usage            int64
+		initialDecision  Decision
+		expectedDecision Decision
+	}{
+		{
+			name: "should un-limit account that upgraded from free to unlimited plan",
+			initialPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			updatedPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			usage:            FreeMonthlyRelays + 1000,
+			initialDecision:  DecisionBlock,
+			expectedDecision: DecisionOK,
+		},
+		{
+			name: "should un-limit account whose unlimited plan limit was raised above its usage",
+			initialPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 2_000_000},
+			},
+			updatedPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{MonthlyUserLimit: 5_000_000},
+			},
+			usage:            3_000_000,
+			initialDecision:  DecisionBlock,
+			expectedDecision: DecisionOK,
+		},
+		{
+			name: "should rate limit account that downgraded from unlimited to free plan while over the free limit",
+			initialPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			updatedPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			usage:            FreeMonthlyRelays + 1000,
+			initialDecision:  DecisionOK,
+			expectedDecision: DecisionBlock,
+		},
+		{
+			name: "should keep free account under its limit allowed after plan change",
+			initialPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			updatedPortalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{FreeMonthlyRelayBonus: 500_000},
+			},
+			usage:            FreeMonthlyRelays + 1000,
+			initialDecision:  DecisionOK,
+			expectedDecision: DecisionOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDWH := NewMockdataWarehouseDriver(ctrl)
+			mockAccountStore := newTestAccountPortalAppStore(ctrl)
+
+			accountID := store.AccountID("account_plan_changed")
+
+			mockDWH.EXPECT().
+				GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
+				Return(map[string]dwh.AccountUsage{string(accountID): {SuccessfulRelays: test.usage}}, nil).
+				Times(1)
+
+			gomock.InOrder(
+				mockAccountStore.EXPECT().GetAccountPortalApp(accountID).Return(test.initialPortalApp, true),
+				mockAccountStore.EXPECT().GetAccountPortalApp(accountID).Return(test.updatedPortalApp, true),
+			)
+
+			rls := &rateLimitStore{
+				logger:                polyzero.NewLogger(),
+				dataWarehouseDriver:   mockDWH,
+				accountPortalAppStore: mockAccountStore,
+				thresholds:            DefaultThresholds,
+				accountDecisions:      make(map[store.AccountID]Decision),
+				accountUsage:          make(map[store.AccountID]dwh.AccountUsage),
+			}
+
+			c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+			c.Equal(test.initialDecision, rls.GetAccountRateLimitDecision(accountID))
+
+			// Re-evaluation must not query the data warehouse again
+			rls.ReevaluateAccounts([]store.AccountID{accountID})
+			c.Equal(test.expectedDecision, rls.GetAccountRateLimitDecision(accountID))
+		})
+	}
+}
+
+func TestGetRateLimit(t *testing.T) {
+	tests := []struct {
+		name              string
+		portalApp         *store.PortalApp
+		planLimits        map[store.PlanType]int32
+		expectedRateLimit int32
+	}{
+		{
+			name: "should return free tier limit for free plan",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{
+					MonthlyUserLimit: 0,
+				},
+			},
+			expectedRateLimit: FreeMonthlyRelays,
+		},
+		{
+			name: "should return free tier limit plus bonus for free plan with bonus relays",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{
+					FreeMonthlyRelayBonus: 250_000,
+				},
+			},
+			expectedRateLimit: FreeMonthlyRelays + 250_000,
+		},
+		{
+			name: "should saturate the limit for free plan with a bonus overflowing the limit",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{
+					FreeMonthlyRelayBonus: math.MaxInt32,
+				},
+			},
+			expectedRateLimit: math.MaxInt32,
+		},
+		{
+			name: "should ignore non-positive bonus for free plan",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{
+					FreeMonthlyRelayBonus: -250_000,
+				},
+			},
+			expectedRateLimit: FreeMonthlyRelays,
+		},
+		{
+			name: "should return custom limit for unlimited plan with limit set",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{
+					MonthlyUserLimit: 500_000,
+				},
+			},
+			expectedRateLimit: 500_000,
+		},
+		{
+			name: "should return zero for unlimited plan with no limit set",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{
+					MonthlyUserLimit: 0,
+				},
+			},
+			expectedRateLimit: 0,
+		},
+		{
+			name: "should return zero for portal app with no rate limit configured",
+			portalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: nil,
+			},
+			expectedRateLimit: 0,
+		},
+		{
+			name: "should return zero for unknown plan type",
+			portalApp: &store.PortalApp{
+				PlanType: "PLAN_UNKNOWN",
+				RateLimit: &store.RateLimit{
+					MonthlyUserLimit: 100_000,
+				},
+			},
+			expectedRateLimit: 0,
+		},
+		{
+			name: "should return loaded plan limit plus bonus for free plan",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{
+					FreeMonthlyRelayBonus: 250_000,
+				},
+			},
+			planLimits:        map[store.PlanType]int32{grovedb.PlanFree_DatabaseType: 500_000},
+			expectedRateLimit: 750_000,
+		},
+		{
+			name: "should return free tier limit for free plan if no free plan limit is loaded",
+			portalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanFree_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			planLimits:        map[store.PlanType]int32{"PLAN_PRO": 5_000_000},
+			expectedRateLimit: FreeMonthlyRelays,
+		},
+		{
+			name: "should return loaded plan limit for unlimited plan with no limit set",
+			portalApp: &store.PortalApp{
+				PlanType:  grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{},
+			},
+			planLimits:        map[store.PlanType]int32{grovedb.PlanUnlimited_DatabaseType: 20_000_000},
+			expectedRateLimit: 20_000_000,
+		},
+		{
+			name: "should prefer custom limit over loaded plan limit for unlimited plan",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanUnlimited_DatabaseType,
+				RateLimit: &store.RateLimit{
+					MonthlyUserLimit: 500_000,
+				},
+			},
+			planLimits:        map[store.PlanType]int32{grovedb.PlanUnlimited_DatabaseType: 20_000_000},
+			expectedRateLimit: 500_000,
+		},
+		{
+			name: "should return loaded plan limit for other plan type",
+			portalApp: &store.PortalApp{
+				PlanType:  "PLAN_PRO",
+				RateLimit: &store.RateLimit{},
+			},
+			planLimits:        map[store.PlanType]int32{"PLAN_PRO": 5_000_000},
+			expectedRateLimit: 5_000_000,
+		},
+		{
+			name: "should return loaded plan limit for unlimited plan with no rate limit configured",
+			portalApp: &store.PortalApp{
+				PlanType: grovedb.PlanUnlimited_DatabaseType,
+			},
+			planLimits:        map[store.PlanType]int32{grovedb.PlanUnlimited_DatabaseType: 20_000_000},
+			expectedRateLimit: 20_000_000,
+		},
+		{
+			name: "should return loaded plan limit for other plan type with no rate limit configured",
+			portalApp: &store.PortalApp{
+				PlanType: "PLAN_PRO",
+			},
+			planLimits:        map[store.PlanType]int32{"PLAN_PRO": 5_000_000},
+			expectedRateLimit: 5_000_000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			rls := &rateLimitStore{
+				logger:     polyzero.NewLogger(),
+				planLimits: test.planLimits,
+			}
+
+			result := rls.getRateLimit(test.portalApp)
+			c.Equal(test.expectedRateLimit, result)
+		})
+	}
+}
+
+func TestIsAccountRateLimitable(t *testing.T) {
+	tests := []struct {
+		name               string
+		file               string
+		planLimits         map[store.PlanType]int32
+		strictUnknownPlans bool
+		expected           bool
+	}{
+		{
+			name:     "should be rate-limitable for free plan",
+			file:     `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_FREE"}`,
+			expected: true,
+		},
+		{
+			name:     "should be rate-limitable for unlimited plan with a monthly relay limit",
+			file:     `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED", "monthly_relay_limit": 500}`,
+			expected: true,
+		},
+		{
+			name:     "should not be rate-limitable for unlimited plan with only a daily relay limit",
+			file:     `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED", "daily_relay_limit": 50}`,
+			expected: false,
+		},
+		{
+			name:     "should not be rate-limitable for unlimited plan with only a burst allowance",
+			file:     `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED", "burst_allowance": 100}`,
+			expected: false,
+		},
+		{
+			name:     "should not be rate-limitable for unlimited plan with only a portal app monthly relay limit",
+			file:     `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED", "portal_app_monthly_relay_limit": 1000}`,
+			expected: false,
+		},
+		{
+			name:     "should not be rate-limitable for unlimited plan with no limit",
+			file:     `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`,
+			expected: false,
+		},
+		{
+			name:       "should be rate-limitable for unlimited plan with no limit if a plan limit is loaded",
+			file:       `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_UNLIMITED"}`,
+			planLimits: map[store.PlanType]int32{grovedb.PlanUnlimited_DatabaseType: 20_000_000},
+			expected:   true,
+		},
+		{
+			name:       "should be rate-limitable for other plan type with no limit if a plan limit is loaded",
+			file:       `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_PRO"}`,
+			planLimits: map[store.PlanType]int32{"PLAN_PRO": 5_000_000},
+			expected:   true,
+		},
+		{
+			name:     "should not be rate-limitable for unknown plan type by default",
+			file:     `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_ENTERPRISE", "monthly_relay_limit": 500}`,
+			expected: false,
+		},
+		{
+			name:               "should be rate-limitable for unknown plan type with no limit in strict mode",
+			file:               `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_ENTERPRISE"}`,
+			strictUnknownPlans: true,
+			expected:           true,
+		},
+		{
+			name:               "should be rate-limitable for unknown plan type with a monthly relay limit in strict mode",
+			file:               `{"id": "portal_app_1", "account_id": "account_1", "plan": "PLAN_ENTERPRISE", "monthly_relay_limit": 500}`,
+			strictUnknownPlans: true,
+			expected:           true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			portalApp := loadDirectoryPortalApp(t, test.file)
+
+			rls := &rateLimitStore{
+				logger:             polyzero.NewLogger(),
+				planLimits:         test.planLimits,
+				strictUnknownPlans: test.strictUnknownPlans,
+			}
+
+			c.Equal(test.expected, rls.IsAccountRateLimitable(portalApp))
+		})
+	}
+}
+
+func TestRateLimitStoreIntegration(t *testing.T) {
+	t.Run("should handle complete rate limiting workflow", func(t *testing.T) {
+		c := require.New(t)
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockDWH := NewMockdataWarehouseDriver(ctrl)
+		mockAccountStore := newTestAccountPortalAppStore(ctrl)
+
+		// Setup initial data - one account over limit, one under
+		initialUsageData := map[string]dwh.AccountUsage{
+			"free_account_over":  {SuccessfulRelays: FreeMonthlyRelays + 5000},
+			"free_account_under": {SuccessfulRelays: FreeMonthlyRelays - 5000},
+			"unlimited_account":  {SuccessfulRelays: 500_000},
+		}
+
+		// First call during NewRateLimitStore
+		mockDWH.EXPECT().
+			GetMonthToMomentUsage(gomock.Any(), int64(FreeMonthlyRelays), nil).
 			Return(initialUsageData, nil)
 
 		mockAccountStore.EXPECT().
@@ -468,6 +1714,7 @@ func TestRateLimitStoreIntegration(t *testing.T) {
 			}, true)
 
 		rls, err := NewRateLimitStore(
+			context.Background(),
 			polyzero.NewLogger(),
 			mockDWH,
 			mockAccountStore,
@@ -489,8 +1736,8 @@ func TestRateLimitStoreConcurrency(t *testing.T) {
 		c := require.New(t)
 
 		rls := &rateLimitStore{
-			rateLimitedAccounts: map[store.AccountID]bool{
-				"test_account": true,
+			accountDecisions: map[store.AccountID]Decision{
+				"test_account": DecisionBlock,
 			},
 		}
 
@@ -509,11 +1756,11 @@ func TestRateLimitStoreConcurrency(t *testing.T) {
 		// Start one goroutine writing to the map
 		go func() {
 			for i := 0; i < 10; i++ {
-				rls.rateLimitedAccountsMu.Lock()
-				rls.rateLimitedAccounts = map[store.AccountID]bool{
-					"new_account": true,
+				rls.accountDecisionsMu.Lock()
+				rls.accountDecisions = map[store.AccountID]Decision{
+					"new_account": DecisionBlock,
 				}
-				rls.rateLimitedAccountsMu.Unlock()
+				rls.accountDecisionsMu.Unlock()
 				time.Sleep(1 * time.Millisecond)
 			}
 			done <- true
@@ -529,3 +1776,42 @@ func TestRateLimitStoreConcurrency(t *testing.T) {
 		c.True(rls.IsAccountRateLimited("new_account"))
 	})
 }
+
+// newTestAccountPortalAppStore returns a mock account portal app store with no portal app monthly limits.
+func newTestAccountPortalAppStore(ctrl *gomock.Controller) *MockaccountPortalAppStore {
+	mockAccountStore := NewMockaccountPortalAppStore(ctrl)
+	mockAccountStore.EXPECT().GetPortalAppsWithMonthlyLimit().Return(nil).AnyTimes()
+	return mockAccountStore
+}
+
+// loadDirectoryPortalApp returns the portal app loaded from the portal app file by the directory driver,
+// so tests use the same RateLimit as a deployment configured from portal app files.
+func loadDirectoryPortalApp(t *testing.T, file string) *store.PortalApp {
+	c := require.New(t)
+
+	dir := t.TempDir()
+	c.NoError(os.WriteFile(filepath.Join(dir, "portal_app.json"), []byte(file), 0o600))
+
+	driver, err := directory.NewDirectoryDriver(polyzero.NewLogger(), dir)
+	c.NoError(err)
+	t.Cleanup(driver.Close)
+
+	portalApps, err := driver.GetPortalApps()
+	c.NoError(err)
+	c.Len(portalApps, 1)
+	for _, portalApp := range portalApps {
+		return portalApp
+	}
+	return nil
+}
+
+// countDecisions returns the number of accounts with the given decision.
+func countDecisions(accountDecisions map[store.AccountID]Decision, decision Decision) int {
+	count := 0
+	for _, d := range accountDecisions {
+		if d == decision {
+			count++
+		}
+	}
+	return count
+}
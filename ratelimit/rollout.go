@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+// rolloutBuckets is the number of buckets accounts are hashed into, so the rollout percentage has a 0.01% resolution.
+const rolloutBuckets = 10_000
+
+// enforcementRollout gradually enforces DecisionBlock for a deterministic, growing subset of accounts.
+//   - The enforced percentage of accounts ramps linearly from 0% at start to 100% at start + window
+//   - Accounts are selected by hashing their account ID, so an enforced account stays enforced as the rollout ramps up
+type enforcementRollout struct {
+	start  time.Time
+	window time.Duration
+
+	// now returns the current time; overridden in tests.
+	now func() time.Time
+}
+
+// WithEnforcementRollout ramps enforcement of DecisionBlock from 0% of accounts at start to 100% over window,
+// so a new limit does not cut off every over-limit account at once.
+//
+// A blocked account not yet in the rollout is downgraded to DecisionWarn, so its requests are allowed with a warning.
+// The enforced percentage is re-evaluated on every rate limit update, so it ramps in steps of the update interval.
+// Defaults to disabled: DecisionBlock is enforced for every account.
+func WithEnforcementRollout(start time.Time, window time.Duration) RateLimitStoreOption {
+	return func(rls *rateLimitStore) {
+		if start.IsZero() {
+			return
+		}
+		rls.enforcementRollout = &enforcementRollout{
+			start:  start,
+			window: window,
+			now:    time.Now,
+		}
+	}
+}
+
+// percentage returns the percentage of accounts for which DecisionBlock is enforced at the given time.
+func (r *enforcementRollout) percentage(now time.Time) float64 {
+	elapsed := now.Sub(r.start)
+	switch {
+	case elapsed < 0:
+		return 0
+	case elapsed >= r.window:
+		return 100
+	default:
+		return 100 * float64(elapsed) / float64(r.window)
+	}
+}
+
+// applyDecision returns the Decision to enforce for the account.
+//   - Downgrades DecisionBlock to DecisionWarn if the account is not yet in the rollout
+//   - Returns every other Decision unchanged
+func (r *enforcementRollout) applyDecision(accountID store.AccountID, decision Decision) Decision {
+	if r == nil || decision != DecisionBlock {
+		return decision
+	}
+	if !isAccountInRollout(accountID, r.percentage(r.now())) {
+		return DecisionWarn
+	}
+	return decision
+}
+
+// isAccountInRollout returns true if the account is in the given percentage of accounts.
+//   - Deterministic: an account in the rollout at a percentage is in it at every higher percentage
+//   - No account is in the rollout at 0%, and every account is at 100%
+func isAccountInRollout(accountID store.AccountID, percentage float64) bool {
+	return float64(rolloutBucket(accountID)) < percentage*rolloutBuckets/100
+}
+
+// rolloutBucket returns the account's bucket in [0, rolloutBuckets), derived from a hash of its account ID.
+func rolloutBucket(accountID store.AccountID) uint64 {
+	sum := sha256.Sum256([]byte(accountID))
+	return binary.BigEndian.Uint64(sum[:8]) % rolloutBuckets
+}
@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/buildwithgrove/path-external-auth-server/dwh"
+	grovedb "github.com/buildwithgrove/path-external-auth-server/postgres/grove"
+	"github.com/buildwithgrove/path-external-auth-server/store"
+)
+
+func TestIsAccountInRollout(t *testing.T) {
+	accountIDs := make([]store.AccountID, 10_000)
+	for i := range accountIDs {
+		accountIDs[i] = store.AccountID(fmt.Sprintf("account_%d", i))
+	}
+
+	tests := []struct {
+		name       string
+		percentage float64
+	}{
+		{name: "should select no accounts at 0%", percentage: 0},
+		{name: "should select about 1% of accounts at 1%", percentage: 1},
+		{name: "should select about 10% of accounts at 10%", percentage: 10},
+		{name: "should select about 25% of accounts at 25%", percentage: 25},
+		{name: "should select about half of the accounts at 50%", percentage: 50},
+		{name: "should select about 90% of accounts at 90%", percentage: 90},
+		{name: "should select every account at 100%", percentage: 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			selected := 0
+			for _, accountID := range accountIDs {
+				inRollout := isAccountInRollout(accountID, test.percentage)
+				if inRollout {
+					selected++
+				}
+
+				// Selection is deterministic, and an account stays selected at every higher percentage
+				c.Equal(inRollout, isAccountInRollout(accountID, test.percentage))
+				if inRollout {
+					c.True(isAccountInRollout(accountID, test.percentage+1))
+				}
+			}
+
+			// Allow a 1% deviation from the expected share of accounts
+			c.InDelta(test.percentage, 100*float64(selected)/float64(len(accountIDs)), 1)
+		})
+	}
+}
+
+func TestEnforcementRollout_percentage(t *testing.T) {
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		window             time.Duration
+		now                time.Time
+		expectedPercentage float64
+	}{
+		{
+			name:               "should enforce no accounts before the start time",
+			window:             24 * time.Hour,
+			now:                start.Add(-time.Minute),
+			expectedPercentage: 0,
+		},
+		{
+			name:               "should enforce no accounts at the start time",
+			window:             24 * time.Hour,
+			now:                start,
+			expectedPercentage: 0,
+		},
+		{
+			name:               "should ramp linearly over the window",
+			window:             24 * time.Hour,
+			now:                start.Add(6 * time.Hour),
+			expectedPercentage: 25,
+		},
+		{
+			name:               "should enforce every account at the end of the window",
+			window:             24 * time.Hour,
+			now:                start.Add(24 * time.Hour),
+			expectedPercentage: 100,
+		},
+		{
+			name:               "should enforce every account after the window",
+			window:             24 * time.Hour,
+			now:                start.Add(48 * time.Hour),
+			expectedPercentage: 100,
+		},
+		{
+			name:               "should enforce every account from the start time if there is no window",
+			now:                start,
+			expectedPercentage: 100,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			rollout := &enforcementRollout{start: start, window: test.window}
+			c.InDelta(test.expectedPercentage, rollout.percentage(test.now), 0.0001)
+		})
+	}
+}
+
+func TestUpdateRateLimitedAccounts_EnforcementRollout(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Find a blocked account in the first 10% of the rollout, and one in the last 10%
+	var earlyAccountID, lateAccountID store.AccountID
+	for i := 0; earlyAccountID == "" || lateAccountID == ""; i++ {
+		accountID := store.AccountID(fmt.Sprintf("free_account_%d", i))
+		switch {
+		case isAccountInRollout(accountID, 10):
+			earlyAccountID = accountID
+		case !isAccountInRollout(accountID, 90):
+			lateAccountID = accountID
+		}
+	}
+
+	mockDWH := NewMockdataWarehouseDriver(ctrl)
+	mockDWH.EXPECT().
+		GetMonthToMomentUsage(gomock.Any(), gomock.Any(), nil).
+		Return(map[string]dwh.AccountUsage{
+			string(earlyAccountID): {SuccessfulRelays: FreeMonthlyRelays * 2},
+			string(lateAccountID):  {SuccessfulRelays: FreeMonthlyRelays * 2},
+		}, nil).
+		AnyTimes()
+
+	mockAccountStore := newTestAccountPortalAppStore(ctrl)
+	mockAccountStore.EXPECT().
+		GetAccountPortalApp(gomock.Any()).
+		Return(&store.PortalApp{
+			PlanType:  grovedb.PlanFree_DatabaseType,
+			RateLimit: &store.RateLimit{},
+		}, true).
+		AnyTimes()
+
+	rls := &rateLimitStore{
+		logger:                polyzero.NewLogger(),
+		dataWarehouseDriver:   mockDWH,
+		accountPortalAppStore: mockAccountStore,
+		accountDecisions:      make(map[store.AccountID]Decision),
+		thresholds:            DefaultThresholds,
+	}
+	start := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	WithEnforcementRollout(start, 100*time.Hour)(rls)
+
+	tests := []struct {
+		now                   time.Time
+		expectedEarlyDecision Decision
+		expectedLateDecision  Decision
+	}{
+		// Before the rollout, blocked accounts are only warned
+		{now: start.Add(-time.Hour), expectedEarlyDecision: DecisionWarn, expectedLateDecision: DecisionWarn},
+		// At 50%, only accounts early in the rollout are blocked
+		{now: start.Add(50 * time.Hour), expectedEarlyDecision: DecisionBlock, expectedLateDecision: DecisionWarn},
+		// After the rollout, every blocked account is blocked
+		{now: start.Add(100 * time.Hour), expectedEarlyDecision: DecisionBlock, expectedLateDecision: DecisionBlock},
+	}
+	for _, test := range tests {
+		rls.enforcementRollout.now = func() time.Time { return test.now }
+
+		c.NoError(rls.updateRateLimitedAccounts(context.Background()))
+		c.Equal(test.expectedEarlyDecision, rls.GetAccountRateLimitDecision(earlyAccountID), "at %s", test.now)
+		c.Equal(test.expectedLateDecision, rls.GetAccountRateLimitDecision(lateAccountID), "at %s", test.now)
+
+		// Re-evaluating the accounts applies the same rollout
+		rls.ReevaluateAccounts([]store.AccountID{earlyAccountID, lateAccountID})
+		c.Equal(test.expectedEarlyDecision, rls.GetAccountRateLimitDecision(earlyAccountID), "at %s", test.now)
+		c.Equal(test.expectedLateDecision, rls.GetAccountRateLimitDecision(lateAccountID), "at %s", test.now)
+	}
+}
+
+func TestWithEnforcementRollout_Disabled(t *testing.T) {
+	c := require.New(t)
+
+	rls := &rateLimitStore{}
+	WithEnforcementRollout(time.Time{}, 24*time.Hour)(rls)
+	c.Nil(rls.enforcementRollout)
+
+	// A nil rollout enforces every decision unchanged
+	c.Equal(DecisionBlock, rls.enforcementRollout.applyDecision("account_1", DecisionBlock))
+}
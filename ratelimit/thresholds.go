@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Decision is the outcome of evaluating an account's monthly usage against its rate limit.
+type Decision string
+
+const (
+	// DecisionOK: usage is below every configured threshold.
+	DecisionOK Decision = "ok"
+	// DecisionWarn: usage is approaching the limit; requests are allowed with a warning header.
+	DecisionWarn Decision = "warn"
+	// DecisionThrottle: usage is over the soft limit; requests are allowed but flagged for throttling.
+	DecisionThrottle Decision = "throttle"
+	// DecisionBlock: usage is over the hard limit; requests are rejected.
+	DecisionBlock Decision = "block"
+)
+
+// decisionSeverity orders decisions from least to most severe.
+var decisionSeverity = map[Decision]int{
+	DecisionOK:       0,
+	DecisionWarn:     1,
+	DecisionThrottle: 2,
+	DecisionBlock:    3,
+}
+
+// Threshold maps a usage ratio (usage / rate limit) to a Decision.
+//
+// A threshold is crossed once usage is strictly greater than rateLimit * UsageRatio.
+type Threshold struct {
+	Decision   Decision
+	UsageRatio float64
+}
+
+// DefaultThresholds preserves the original behavior of blocking
+// an account once its usage exceeds its monthly rate limit.
+var DefaultThresholds = []Threshold{
+	{Decision: DecisionBlock, UsageRatio: 1.0},
+}
+
+// ParseThresholds parses a comma-separated list of `<decision>:<usage ratio>` pairs.
+//
+//   - Example: "warn:0.8,throttle:1.0,block:1.2"
+//   - Valid decisions are "warn", "throttle" and "block", each at most once
+//   - A "block" threshold is required, so accounts are always rejected past some usage
+//   - Ratios must be positive and increase with the severity of the decision
+func ParseThresholds(s string) ([]Threshold, error) {
+	var thresholds []Threshold
+	seen := make(map[Decision]bool)
+
+	for _, pair := range strings.Split(s, ",") {
+		decisionStr, ratioStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid threshold %q: expected <decision>:<usage ratio>", pair)
+		}
+
+		decision := Decision(strings.TrimSpace(decisionStr))
+		if decisionSeverity[decision] == 0 {
+			return nil, fmt.Errorf("invalid threshold decision %q: must be one of warn, throttle, block", decision)
+		}
+		if seen[decision] {
+			return nil, fmt.Errorf("duplicate threshold decision %q", decision)
+		}
+		seen[decision] = true
+
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(ratioStr), 64)
+		if err != nil || ratio <= 0 {
+			return nil, fmt.Errorf("invalid threshold usage ratio %q: must be a positive number", ratioStr)
+		}
+
+		thresholds = append(thresholds, Threshold{Decision: decision, UsageRatio: ratio})
+	}
+
+	if !seen[DecisionBlock] {
+		return nil, fmt.Errorf("missing block threshold: accounts would never be rate limited")
+	}
+
+	sortThresholds(thresholds)
+
+	// Ensure a more severe decision never triggers at a lower usage ratio than a less severe one.
+	for i := 1; i < len(thresholds); i++ {
+		if decisionSeverity[thresholds[i].Decision] < decisionSeverity[thresholds[i-1].Decision] ||
+			thresholds[i].UsageRatio == thresholds[i-1].UsageRatio {
+			return nil, fmt.Errorf("threshold usage ratios must strictly increase with decision severity (warn < throttle < block)")
+		}
+	}
+
+	return thresholds, nil
+}
+
+// sortThresholds sorts thresholds by ascending usage ratio.
+func sortThresholds(thresholds []Threshold) {
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].UsageRatio < thresholds[j].UsageRatio
+	})
+}
+
+// minUsageRatio returns the lowest usage ratio across all thresholds.
+// Used to determine the minimum usage that must be fetched from the data warehouse.
+func minUsageRatio(thresholds []Threshold) float64 {
+	if len(thresholds) == 0 {
+		return 1.0
+	}
+	lowest := thresholds[0].UsageRatio
+	for _, t := range thresholds[1:] {
+		if t.UsageRatio < lowest {
+			lowest = t.UsageRatio
+		}
+	}
+	return lowest
+}
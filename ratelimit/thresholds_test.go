@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		name               string
+		input              string
+		expectedThresholds []Threshold
+		expectError        bool
+	}{
+		{
+			name:  "should parse a single block threshold",
+			input: "block:1.0",
+			expectedThresholds: []Threshold{
+				{Decision: DecisionBlock, UsageRatio: 1.0},
+			},
+		},
+		{
+			name:  "should parse and sort tiered thresholds",
+			input: "block:1.2, warn:0.8 ,throttle:1.0",
+			expectedThresholds: []Threshold{
+				{Decision: DecisionWarn, UsageRatio: 0.8},
+				{Decision: DecisionThrottle, UsageRatio: 1.0},
+				{Decision: DecisionBlock, UsageRatio: 1.2},
+			},
+		},
+		{
+			name:        "should error when no block threshold is configured",
+			input:       "warn:0.8,throttle:1.0",
+			expectError: true,
+		},
+		{
+			name:        "should error on unknown decision",
+			input:       "deny:1.0",
+			expectError: true,
+		},
+		{
+			name:        "should error on ok decision",
+			input:       "ok:0.5",
+			expectError: true,
+		},
+		{
+			name:        "should error on missing ratio",
+			input:       "block",
+			expectError: true,
+		},
+		{
+			name:        "should error on non-positive ratio",
+			input:       "block:0",
+			expectError: true,
+		},
+		{
+			name:        "should error on duplicate decision",
+			input:       "block:1.0,block:1.2",
+			expectError: true,
+		},
+		{
+			name:        "should error when a more severe decision has a lower ratio",
+			input:       "warn:1.2,block:1.0",
+			expectError: true,
+		},
+		{
+			name:        "should error when two decisions share a ratio",
+			input:       "throttle:1.0,block:1.0",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			thresholds, err := ParseThresholds(test.input)
+			if test.expectError {
+				c.Error(err)
+				return
+			}
+			c.NoError(err)
+			c.Equal(test.expectedThresholds, thresholds)
+		})
+	}
+}
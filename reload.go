@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+)
+
+// errReloadTooRecent is returned when a reload is requested within the minimum interval of the previous reload.
+var errReloadTooRecent = errors.New("refresh too recent")
+
+// storeRefresher is implemented by stores that can be refreshed on demand.
+type storeRefresher interface {
+	Refresh() error
+}
+
+// reloadStores refreshes the portal app store, then re-evaluates rate limits for the refreshed portal apps.
+//   - The rate limit store is refreshed even if the portal app store refresh fails, using the previously loaded portal apps.
+func reloadStores(portalAppStore, rateLimitStore storeRefresher) error {
+	var errs []error
+	if err := portalAppStore.Refresh(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to refresh portal app store: %w", err))
+	}
+	if err := rateLimitStore.Refresh(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to refresh rate limit store: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// debounceReload returns a reload function that returns errReloadTooRecent, without calling reload,
+// if called within minInterval of the start of the previous reload.
+//   - Protects the database and data warehouse from repeated on-demand reloads (e.g. a script sending SIGHUP in a loop).
+//   - A minInterval of 0 disables debouncing.
+func debounceReload(reload func() error, minInterval time.Duration) func() error {
+	var mu sync.Mutex
+	var lastReload time.Time
+
+	return func() error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if minInterval > 0 && !lastReload.IsZero() && time.Since(lastReload) < minInterval {
+			return errReloadTooRecent
+		}
+		lastReload = time.Now()
+
+		return reload()
+	}
+}
+
+// handleReloadSignals calls reload for every signal received, logging the outcome, until the signals channel is closed.
+func handleReloadSignals(logger polylog.Logger, signals <-chan os.Signal, reload func() error) {
+	for sig := range signals {
+		startTime := time.Now()
+		logger.Info().Str("signal", sig.String()).Msg("🔁 Received reload signal: refreshing portal app and rate limit stores")
+
+		err := reload()
+		if errors.Is(err, errReloadTooRecent) {
+			logger.Warn().Err(err).Msg("⏳ Skipped reload: the previous reload was within the minimum reload interval")
+			continue
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to reload stores")
+			continue
+		}
+
+		logger.Info().
+			Int64("reload_duration_ms", time.Since(startTime).Milliseconds()).
+			Msg("✅ Successfully reloaded stores")
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStoreRefresher counts calls to Refresh and returns err.
+type fakeStoreRefresher struct {
+	refreshCount int
+	err          error
+}
+
+func (f *fakeStoreRefresher) Refresh() error {
+	f.refreshCount++
+	return f.err
+}
+
+func Test_reloadStores(t *testing.T) {
+	tests := []struct {
+		name              string
+		portalAppStoreErr error
+		rateLimitStoreErr error
+		wantErr           bool
+	}{
+		{
+			name: "should refresh both stores",
+		},
+		{
+			name:              "should refresh rate limit store if portal app store refresh fails",
+			portalAppStoreErr: errors.New("postgres unavailable"),
+			wantErr:           true,
+		},
+		{
+			name:              "should return error if rate limit store refresh fails",
+			rateLimitStoreErr: errors.New("bigquery unavailable"),
+			wantErr:           true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			portalAppStore := &fakeStoreRefresher{err: test.portalAppStoreErr}
+			rateLimitStore := &fakeStoreRefresher{err: test.rateLimitStoreErr}
+
+			err := reloadStores(portalAppStore, rateLimitStore)
+			if test.wantErr {
+				c.Error(err)
+			} else {
+				c.NoError(err)
+			}
+			c.Equal(1, portalAppStore.refreshCount)
+			c.Equal(1, rateLimitStore.refreshCount)
+		})
+	}
+}
+
+func Test_handleReloadSignals(t *testing.T) {
+	c := require.New(t)
+
+	portalAppStore := &fakeStoreRefresher{}
+	rateLimitStore := &fakeStoreRefresher{err: errors.New("bigquery unavailable")}
+
+	// Simulate two SIGHUPs, the first failing, then close the channel to stop the handler
+	signals := make(chan os.Signal, 2)
+	signals <- syscall.SIGHUP
+	signals <- syscall.SIGHUP
+	close(signals)
+
+	handleReloadSignals(polyzero.NewLogger(), signals, func() error {
+		return reloadStores(portalAppStore, rateLimitStore)
+	})
+
+	// A failed reload does not stop later signals from triggering a reload
+	c.Equal(2, portalAppStore.refreshCount)
+	c.Equal(2, rateLimitStore.refreshCount)
+}
+
+func Test_debounceReload(t *testing.T) {
+	tests := []struct {
+		name        string
+		minInterval time.Duration
+		// wait is the time waited between the two reloads
+		wait            time.Duration
+		wantSecondErr   error
+		wantReloadCount int
+	}{
+		{
+			name:            "should skip reload requested within the minimum interval",
+			minInterval:     time.Hour,
+			wantSecondErr:   errReloadTooRecent,
+			wantReloadCount: 1,
+		},
+		{
+			name:            "should reload once the minimum interval has passed",
+			minInterval:     10 * time.Millisecond,
+			wait:            20 * time.Millisecond,
+			wantReloadCount: 2,
+		},
+		{
+			name:            "should not debounce reloads if the minimum interval is 0",
+			minInterval:     0,
+			wantReloadCount: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			reloadCount := 0
+			reload := debounceReload(func() error {
+				reloadCount++
+				return nil
+			}, test.minInterval)
+
+			c.NoError(reload())
+			time.Sleep(test.wait)
+			c.ErrorIs(reload(), test.wantSecondErr)
+			c.Equal(test.wantReloadCount, reloadCount)
+		})
+	}
+}
+
+func Test_debounceReload_FailedReload(t *testing.T) {
+	c := require.New(t)
+
+	reloadCount := 0
+	reload := debounceReload(func() error {
+		reloadCount++
+		return errors.New("postgres unavailable")
+	}, time.Hour)
+
+	// A failed reload still counts toward the minimum interval, so a failing database is not hammered
+	c.EqualError(reload(), "postgres unavailable")
+	c.ErrorIs(reload(), errReloadTooRecent)
+	c.Equal(1, reloadCount)
+}
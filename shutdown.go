@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+	"google.golang.org/grpc"
+)
+
+// gracefulServer is the subset of *grpc.Server used to serve and gracefully stop the auth server.
+type gracefulServer interface {
+	Serve(listener net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// serveUntilShutdown serves gRPC requests on the listener until the context is canceled (e.g. on SIGTERM),
+// then gracefully stops the server.
+//   - In-flight auth checks complete before returning, so a Kubernetes rollout does not drop them.
+//   - Auth checks still in flight after timeout (e.g. stuck on a dependency) are canceled, so PEAS exits
+//     before the pod is killed.
+//   - Returns the error of Serve if it fails before the context is canceled.
+func serveUntilShutdown(
+	ctx context.Context,
+	logger polylog.Logger,
+	server gracefulServer,
+	listener net.Listener,
+	timeout time.Duration,
+) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info().Msg("🛑 Received shutdown signal: gracefully stopping PEAS")
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		logger.Warn().Dur("shutdown_timeout", timeout).
+			Msg("⚠️ In-flight auth checks did not complete before the shutdown timeout: stopping PEAS")
+		// Stop closes the remaining connections and cancels their auth checks' contexts;
+		// it does not wait for auth checks ignoring their context, so PEAS exits regardless
+		server.Stop()
+	}
+
+	// Serve returns once GracefulStop or Stop has stopped the server
+	if err := <-serveErr; err != nil && err != grpc.ErrServerStopped {
+		return err
+	}
+
+	logger.Info().Msg("👋 PEAS stopped")
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	envoy_auth "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// blockingAuthorizationServer blocks every Check until released, signaling when a Check is in flight.
+type blockingAuthorizationServer struct {
+	envoy_auth.UnimplementedAuthorizationServer
+
+	inFlight chan struct{}
+	release  chan struct{}
+}
+
+func (s *blockingAuthorizationServer) Check(context.Context, *envoy_auth.CheckRequest) (*envoy_auth.CheckResponse, error) {
+	s.inFlight <- struct{}{}
+	<-s.release
+	return &envoy_auth.CheckResponse{}, nil
+}
+
+func Test_serveUntilShutdown(t *testing.T) {
+	c := require.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.NoError(err)
+
+	authServer := &blockingAuthorizationServer{inFlight: make(chan struct{}), release: make(chan struct{})}
+	server := grpc.NewServer()
+	envoy_auth.RegisterAuthorizationServer(server, authServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serveUntilShutdown(ctx, polyzero.NewLogger(), server, listener, time.Minute)
+	}()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	c.NoError(err)
+	defer conn.Close()
+
+	// Start an auth check, and wait until it is in flight
+	checkErr := make(chan error, 1)
+	go func() {
+		_, err := envoy_auth.NewAuthorizationClient(conn).Check(context.Background(), &envoy_auth.CheckRequest{})
+		checkErr <- err
+	}()
+	<-authServer.inFlight
+
+	// The server waits for the in-flight check before stopping
+	cancel()
+	select {
+	case err := <-serveErr:
+		c.FailNow("server stopped before the in-flight check completed", "error: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(authServer.release)
+	c.NoError(<-checkErr)
+
+	select {
+	case err := <-serveErr:
+		c.NoError(err)
+	case <-time.After(5 * time.Second):
+		c.FailNow("server did not stop after the context was canceled")
+	}
+
+	// The listener is closed once the server has stopped
+	_, err = net.Dial("tcp", listener.Addr().String())
+	c.Error(err)
+}
+
+func Test_serveUntilShutdown_ServeError(t *testing.T) {
+	c := require.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.NoError(err)
+	c.NoError(listener.Close())
+
+	// Serve fails on a closed listener, so the error is returned without waiting for the context
+	err = serveUntilShutdown(context.Background(), polyzero.NewLogger(), grpc.NewServer(), listener, time.Minute)
+	c.Error(err)
+}
+
+func Test_serveUntilShutdown_Timeout(t *testing.T) {
+	c := require.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.NoError(err)
+
+	authServer := &blockingAuthorizationServer{inFlight: make(chan struct{}), release: make(chan struct{})}
+	defer close(authServer.release)
+	server := grpc.NewServer()
+	envoy_auth.RegisterAuthorizationServer(server, authServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serveUntilShutdown(ctx, polyzero.NewLogger(), server, listener, 100*time.Millisecond)
+	}()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	c.NoError(err)
+	defer conn.Close()
+
+	// Start an auth check that never completes on its own, e.g. stuck on a dependency
+	checkErr := make(chan error, 1)
+	go func() {
+		_, err := envoy_auth.NewAuthorizationClient(conn).Check(context.Background(), &envoy_auth.CheckRequest{})
+		checkErr <- err
+	}()
+	<-authServer.inFlight
+
+	// The server stops once the shutdown timeout elapses, failing the in-flight check
+	cancel()
+	select {
+	case err := <-serveErr:
+		c.NoError(err)
+	case <-time.After(5 * time.Second):
+		c.FailNow("server did not stop after the shutdown timeout")
+	}
+	c.Error(<-checkErr)
+}
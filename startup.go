@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog"
+)
+
+// waitForDependency calls check until it succeeds, retrying every interval until the timeout elapses.
+//   - Lets PEAS wait out a slow dependency (e.g. Postgres starting after PEAS) rather than crash-looping.
+//   - A timeout of 0 calls check once, returning its error unchanged.
+//   - Each attempt's context expires at the timeout, so a hanging check cannot outlast it.
+func waitForDependency(
+	ctx context.Context,
+	logger polylog.Logger,
+	name string,
+	timeout time.Duration,
+	interval time.Duration,
+	check func(context.Context) error,
+) error {
+	if timeout <= 0 {
+		return check(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		err := check(ctx)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info().Str("dependency", name).Int("attempts", attempt).Msg("✅ Dependency is reachable")
+			}
+			return nil
+		}
+
+		logger.Warn().Err(err).
+			Str("dependency", name).
+			Int("attempt", attempt).
+			Dur("retry_interval", interval).
+			Msg("⏳ Dependency is not reachable yet, waiting before retrying")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not reachable within %s after %d attempts: %w", name, timeout, attempt, err)
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+)
+
+// errUnreachable is returned by fake dependency checks that are not reachable yet.
+var errUnreachable = errors.New("connection refused")
+
+// newFakeDependencyCheck returns a check that fails until it has been called failures times, and the number of calls.
+func newFakeDependencyCheck(failures int) (func(context.Context) error, *int) {
+	calls := 0
+	return func(context.Context) error {
+		calls++
+		if calls <= failures {
+			return errUnreachable
+		}
+		return nil
+	}, &calls
+}
+
+func Test_waitForDependency(t *testing.T) {
+	tests := []struct {
+		name          string
+		timeout       time.Duration
+		failures      int
+		expectedCalls int
+		wantErr       bool
+	}{
+		{
+			name:          "should succeed on the first attempt if reachable",
+			timeout:       time.Second,
+			failures:      0,
+			expectedCalls: 1,
+		},
+		{
+			name:          "should retry until the dependency is eventually reachable",
+			timeout:       time.Second,
+			failures:      3,
+			expectedCalls: 4,
+		},
+		{
+			name:          "should call the check once without retrying if the timeout is 0",
+			timeout:       0,
+			failures:      1,
+			expectedCalls: 1,
+			wantErr:       true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			check, calls := newFakeDependencyCheck(test.failures)
+			err := waitForDependency(context.Background(), polyzero.NewLogger(), "postgres", test.timeout, time.Millisecond, check)
+			if test.wantErr {
+				c.ErrorIs(err, errUnreachable)
+			} else {
+				c.NoError(err)
+			}
+			c.Equal(test.expectedCalls, *calls)
+		})
+	}
+}
+
+func Test_waitForDependency_Timeout(t *testing.T) {
+	c := require.New(t)
+
+	// The dependency never becomes reachable
+	check, calls := newFakeDependencyCheck(1_000_000)
+
+	startTime := time.Now()
+	err := waitForDependency(context.Background(), polyzero.NewLogger(), "postgres", 50*time.Millisecond, 10*time.Millisecond, check)
+
+	c.ErrorIs(err, errUnreachable)
+	c.ErrorContains(err, "postgres not reachable within 50ms")
+	c.GreaterOrEqual(time.Since(startTime), 50*time.Millisecond)
+	c.Less(time.Since(startTime), time.Second)
+	c.Greater(*calls, 1)
+}
+
+func Test_waitForDependency_CheckContextExpiresAtTimeout(t *testing.T) {
+	c := require.New(t)
+
+	// A hanging check returns when its context expires at the timeout
+	hangingCheck := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err := waitForDependency(context.Background(), polyzero.NewLogger(), "bigquery", 20*time.Millisecond, time.Millisecond, hangingCheck)
+	c.ErrorIs(err, context.DeadlineExceeded)
+	c.ErrorContains(err, "bigquery not reachable")
+}
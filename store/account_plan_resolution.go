@@ -0,0 +1,146 @@
+package store
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
+)
+
+// AccountPlanResolution determines which portal app's plan and rate limit settings are used for an account
+// whose portal apps disagree on them (a data inconsistency, e.g. a plan upgrade applied to only some of its portal apps).
+type AccountPlanResolution string
+
+const (
+	// AccountPlanResolutionMostPermissive uses the portal app with the most permissive plan,
+	// so an account is never rate limited because of a stale plan on one of its portal apps.
+	AccountPlanResolutionMostPermissive AccountPlanResolution = "most_permissive"
+	// AccountPlanResolutionMostRestrictive uses the portal app with the most restrictive plan,
+	// so an account can never bypass its limit because of one portal app on a better plan.
+	AccountPlanResolutionMostRestrictive AccountPlanResolution = "most_restrictive"
+)
+
+// defaultAccountPlanResolution favors not rate limiting paying accounts over enforcing a possibly stale limit.
+const defaultAccountPlanResolution = AccountPlanResolutionMostPermissive
+
+// The plan types ranked by comparePlanPermissiveness, matching the Grove Portal database plan types.
+const (
+	planTypeFree      PlanType = "PLAN_FREE"
+	planTypeUnlimited PlanType = "PLAN_UNLIMITED"
+)
+
+// ParseAccountPlanResolution parses an AccountPlanResolution.
+//   - Valid values are "most_permissive" and "most_restrictive"
+func ParseAccountPlanResolution(s string) (AccountPlanResolution, error) {
+	switch resolution := AccountPlanResolution(s); resolution {
+	case AccountPlanResolutionMostPermissive, AccountPlanResolutionMostRestrictive:
+		return resolution, nil
+	default:
+		return "", fmt.Errorf("invalid account plan resolution %q: must be one of most_permissive, most_restrictive", s)
+	}
+}
+
+// prefers returns true if the candidate portal app's plan is used for the account instead of the current one's.
+//   - Portal apps with equally permissive plans are resolved to the lowest portal app ID,
+//     so the account's plan does not depend on the order portal apps are loaded in.
+func (r AccountPlanResolution) prefers(candidate, current *PortalApp) bool {
+	order := comparePlanPermissiveness(candidate, current)
+	if order == 0 {
+		return candidate.ID < current.ID
+	}
+	if r == AccountPlanResolutionMostRestrictive {
+		return order < 0
+	}
+	return order > 0
+}
+
+// comparePlanPermissiveness returns a negative number if portal app a's plan is less permissive than b's,
+// a positive number if it is more permissive, and 0 if they are equally permissive. Plans are compared by:
+//   - Rate limit: no rate limit configured is more permissive than any rate limit
+//   - Plan type: PLAN_FREE < any other plan type < PLAN_UNLIMITED
+//   - Monthly user limit: a higher limit is more permissive, and no limit (0) the most permissive
+//   - Free monthly relay bonus: a higher bonus is more permissive
+func comparePlanPermissiveness(a, b *PortalApp) int {
+	if (a.RateLimit == nil) != (b.RateLimit == nil) {
+		if a.RateLimit == nil {
+			return 1
+		}
+		return -1
+	}
+	if order := cmp.Compare(planTypeRank(a.PlanType), planTypeRank(b.PlanType)); order != 0 {
+		return order
+	}
+	if a.RateLimit == nil {
+		return 0
+	}
+	if order := cmp.Compare(monthlyUserLimitRank(a.RateLimit), monthlyUserLimitRank(b.RateLimit)); order != 0 {
+		return order
+	}
+	return cmp.Compare(a.RateLimit.FreeMonthlyRelayBonus, b.RateLimit.FreeMonthlyRelayBonus)
+}
+
+// planTypeRank ranks the plan type from the least (0) to the most (2) permissive.
+func planTypeRank(planType PlanType) int {
+	switch planType {
+	case planTypeFree:
+		return 0
+	case planTypeUnlimited:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// monthlyUserLimitRank ranks the monthly user limit, with no limit (0) ranking above any limit.
+func monthlyUserLimitRank(rateLimit *RateLimit) int64 {
+	if rateLimit.MonthlyUserLimit <= 0 {
+		return math.MaxInt64
+	}
+	return int64(rateLimit.MonthlyUserLimit)
+}
+
+// accountPlansConflict returns true if two portal apps of the same account disagree on the account's plan:
+// its plan type, monthly user limit or free monthly relay bonus.
+//   - Settings of the portal app alone (PortalAppMonthlyLimit) or only enforced outside of PEAS (DailyUserLimit, BurstAllowance)
+//     may legitimately differ between the account's portal apps, so they are not compared.
+//   - A portal app with no RateLimit is compared as one with no limits set.
+func accountPlansConflict(a, b *PortalApp) bool {
+	if a.PlanType != b.PlanType {
+		return true
+	}
+
+	var aRateLimit, bRateLimit RateLimit
+	if a.RateLimit != nil {
+		aRateLimit = *a.RateLimit
+	}
+	if b.RateLimit != nil {
+		bRateLimit = *b.RateLimit
+	}
+	return aRateLimit.MonthlyUserLimit != bRateLimit.MonthlyUserLimit ||
+		aRateLimit.FreeMonthlyRelayBonus != bRateLimit.FreeMonthlyRelayBonus
+}
+
+// reportConflictingAccountPlans logs the accounts whose portal apps disagree on their plan or rate limit settings.
+//   - Updates the count of accounts with conflicting plans metric.
+func (c *portalAppStore) reportConflictingAccountPlans(conflictingAccountIDs map[AccountID]struct{}) {
+	metrics.UpdateStoreSize(metrics.AccountsConflictingPlansStoreType, float64(len(conflictingAccountIDs)))
+
+	if len(conflictingAccountIDs) == 0 {
+		return
+	}
+
+	accountIDs := make([]string, 0, len(conflictingAccountIDs))
+	for accountID := range conflictingAccountIDs {
+		accountIDs = append(accountIDs, string(accountID))
+	}
+	slices.Sort(accountIDs)
+
+	c.logger.Warn().
+		Int("account_count", len(accountIDs)).
+		Str("account_ids", strings.Join(accountIDs, ",")).
+		Str("resolution", string(c.accountPlanResolution)).
+		Msg("⚠️ Found accounts whose portal apps have conflicting plans")
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseAccountPlanResolution(t *testing.T) {
+	c := require.New(t)
+
+	resolution, err := ParseAccountPlanResolution("most_permissive")
+	c.NoError(err)
+	c.Equal(AccountPlanResolutionMostPermissive, resolution)
+
+	resolution, err = ParseAccountPlanResolution("most_restrictive")
+	c.NoError(err)
+	c.Equal(AccountPlanResolutionMostRestrictive, resolution)
+
+	_, err = ParseAccountPlanResolution("first_wins")
+	c.Error(err)
+}
+
+func Test_setPortalAppsByAccountID_ConflictingPlans(t *testing.T) {
+	freeApp := &PortalApp{ID: "portal_app_free", AccountID: "account_1", PlanType: "PLAN_FREE", RateLimit: &RateLimit{}}
+	freeBonusApp := &PortalApp{ID: "portal_app_free_bonus", AccountID: "account_1", PlanType: "PLAN_FREE", RateLimit: &RateLimit{FreeMonthlyRelayBonus: 500_000}}
+	paidApp := &PortalApp{ID: "portal_app_paid", AccountID: "account_1", PlanType: "PLAN_PAID", RateLimit: &RateLimit{}}
+	limitedApp := &PortalApp{ID: "portal_app_limited", AccountID: "account_1", PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 2_000_000}}
+	higherLimitApp := &PortalApp{ID: "portal_app_higher_limit", AccountID: "account_1", PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 5_000_000}}
+	unlimitedApp := &PortalApp{ID: "portal_app_unlimited", AccountID: "account_1", PlanType: "PLAN_UNLIMITED"}
+
+	tests := []struct {
+		name                string
+		resolution          AccountPlanResolution
+		portalApps          []*PortalApp
+		expectedPortalAppID PortalAppID
+		expectedConflicts   float64
+	}{
+		{
+			name:                "should use the unlimited plan with most_permissive",
+			resolution:          AccountPlanResolutionMostPermissive,
+			portalApps:          []*PortalApp{freeApp, unlimitedApp, paidApp},
+			expectedPortalAppID: "portal_app_unlimited",
+			expectedConflicts:   1,
+		},
+		{
+			name:                "should use the free plan with most_restrictive",
+			resolution:          AccountPlanResolutionMostRestrictive,
+			portalApps:          []*PortalApp{freeApp, unlimitedApp, paidApp},
+			expectedPortalAppID: "portal_app_free",
+			expectedConflicts:   1,
+		},
+		{
+			name:                "should use any other plan over the free plan with most_permissive",
+			resolution:          AccountPlanResolutionMostPermissive,
+			portalApps:          []*PortalApp{freeApp, paidApp},
+			expectedPortalAppID: "portal_app_paid",
+			expectedConflicts:   1,
+		},
+		{
+			name:                "should use the higher monthly user limit with most_permissive",
+			resolution:          AccountPlanResolutionMostPermissive,
+			portalApps:          []*PortalApp{limitedApp, higherLimitApp},
+			expectedPortalAppID: "portal_app_higher_limit",
+			expectedConflicts:   1,
+		},
+		{
+			name:                "should use the lower monthly user limit with most_restrictive",
+			resolution:          AccountPlanResolutionMostRestrictive,
+			portalApps:          []*PortalApp{limitedApp, higherLimitApp},
+			expectedPortalAppID: "portal_app_limited",
+			expectedConflicts:   1,
+		},
+		{
+			name:                "should use the free monthly relay bonus with most_permissive",
+			resolution:          AccountPlanResolutionMostPermissive,
+			portalApps:          []*PortalApp{freeApp, freeBonusApp},
+			expectedPortalAppID: "portal_app_free_bonus",
+			expectedConflicts:   1,
+		},
+		{
+			name:                "should use the most permissive plan if no resolution is set",
+			portalApps:          []*PortalApp{freeApp, unlimitedApp},
+			expectedPortalAppID: "portal_app_unlimited",
+			expectedConflicts:   1,
+		},
+		{
+			name:       "should use the lowest portal app ID if the plans agree",
+			resolution: AccountPlanResolutionMostRestrictive,
+			portalApps: []*PortalApp{
+				{ID: "portal_app_b", AccountID: "account_1", PlanType: "PLAN_FREE", RateLimit: &RateLimit{}},
+				{ID: "portal_app_a", AccountID: "account_1", PlanType: "PLAN_FREE", RateLimit: &RateLimit{}},
+				{ID: "portal_app_c", AccountID: "account_1", PlanType: "PLAN_FREE", RateLimit: &RateLimit{}},
+			},
+			expectedPortalAppID: "portal_app_a",
+			expectedConflicts:   0,
+		},
+		{
+			name:       "should not report a conflict if the portal apps only differ in settings of their own",
+			resolution: AccountPlanResolutionMostPermissive,
+			portalApps: []*PortalApp{
+				{ID: "portal_app_b", AccountID: "account_1", PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 2_000_000, DailyUserLimit: 50}},
+				{ID: "portal_app_a", AccountID: "account_1", PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 2_000_000, PortalAppMonthlyLimit: 1_000}},
+				{ID: "portal_app_c", AccountID: "account_1", PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 2_000_000, BurstAllowance: 100}},
+			},
+			expectedPortalAppID: "portal_app_a",
+			expectedConflicts:   0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			portalApps := make(map[PortalAppID]*PortalApp, len(test.portalApps))
+			for _, portalApp := range test.portalApps {
+				portalApps[portalApp.ID] = portalApp
+			}
+
+			// The resolution is deterministic, regardless of the portal apps map iteration order
+			for range 10 {
+				store := &portalAppStore{
+					logger:                polyzero.NewLogger(),
+					accountPlanResolution: test.resolution,
+				}
+				store.setPortalAppsByAccountID(portalApps)
+
+				portalApp, ok := store.GetAccountPortalApp("account_1")
+				c.True(ok)
+				c.Equal(test.expectedPortalAppID, portalApp.ID)
+				c.Equal(test.expectedConflicts, getConflictingAccountPlansStoreSize(t))
+			}
+		})
+	}
+}
+
+// getConflictingAccountPlansStoreSize returns the recorded count of accounts with conflicting plans.
+func getConflictingAccountPlansStoreSize(t *testing.T) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "peas_store_size_total" {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "store_type" && label.GetValue() == "accounts_conflicting_plans" {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package store
+
+import "crypto/sha256"
+
+// apiKeyHash is the SHA-256 hash of a portal app API key.
+// The API key index is keyed by hash so that it holds no plaintext API keys.
+type apiKeyHash [sha256.Size]byte
+
+// hashAPIKey returns the SHA-256 hash of an API key.
+func hashAPIKey(apiKey string) apiKeyHash {
+	return sha256.Sum256([]byte(apiKey))
+}
+
+// buildAPIKeyIndex builds an index of API key hashes to the ID of the portal app using the API key.
+//   - Portal apps without API key auth are not indexed.
+//   - Every API key of a portal app is indexed, so any of its rotated keys identifies it.
+//   - If multiple portal apps share an API key hash, the key cannot identify a single portal app,
+//     so the hash is excluded from the index and the colliding portal app IDs are returned.
+func buildAPIKeyIndex(portalApps map[PortalAppID]*PortalApp) (map[apiKeyHash]PortalAppID, []PortalAppID) {
+	index := make(map[apiKeyHash]PortalAppID, len(portalApps))
+	collisions := make(map[apiKeyHash][]PortalAppID)
+
+	for portalAppID, portalApp := range portalApps {
+		if !portalApp.Auth.HasAPIKey() {
+			continue
+		}
+
+		// A key listed more than once for the same portal app is not a collision
+		portalAppHashes := make(map[apiKeyHash]bool, len(portalApp.Auth.APIKeys))
+		for _, apiKey := range portalApp.Auth.APIKeys {
+			hash := hashAPIKey(apiKey)
+			if apiKey == "" || portalAppHashes[hash] {
+				continue
+			}
+			portalAppHashes[hash] = true
+
+			if colliding, ok := collisions[hash]; ok {
+				collisions[hash] = append(colliding, portalAppID)
+				continue
+			}
+			if existingID, ok := index[hash]; ok {
+				collisions[hash] = []PortalAppID{existingID, portalAppID}
+				delete(index, hash)
+				continue
+			}
+			index[hash] = portalAppID
+		}
+	}
+
+	var collidingPortalAppIDs []PortalAppID
+	for _, portalAppIDs := range collisions {
+		collidingPortalAppIDs = append(collidingPortalAppIDs, portalAppIDs...)
+	}
+
+	return index, collidingPortalAppIDs
+}
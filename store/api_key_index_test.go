@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func Test_buildAPIKeyIndex(t *testing.T) {
+	tests := []struct {
+		name                  string
+		portalApps            map[PortalAppID]*PortalApp
+		expectedIndex         map[apiKeyHash]PortalAppID
+		expectedCollidingApps []PortalAppID
+	}{
+		{
+			name:          "should index portal apps with an API key",
+			portalApps:    getTestPortalApps(),
+			expectedIndex: map[apiKeyHash]PortalAppID{hashAPIKey("api_key_1"): "portal_app_1_static_key"},
+		},
+		{
+			name: "should not index portal apps with an empty API key",
+			portalApps: map[PortalAppID]*PortalApp{
+				"portal_app_empty_key": {ID: "portal_app_empty_key", Auth: &Auth{}},
+			},
+			expectedIndex: map[apiKeyHash]PortalAppID{},
+		},
+		{
+			name: "should index every API key of a portal app with multiple API keys",
+			portalApps: map[PortalAppID]*PortalApp{
+				"portal_app_rotating": {ID: "portal_app_rotating", Auth: &Auth{APIKeys: []string{"new_key", "old_key", "new_key"}}},
+			},
+			expectedIndex: map[apiKeyHash]PortalAppID{
+				hashAPIKey("new_key"): "portal_app_rotating",
+				hashAPIKey("old_key"): "portal_app_rotating",
+			},
+		},
+		{
+			name: "should exclude every portal app sharing an API key",
+			portalApps: map[PortalAppID]*PortalApp{
+				"portal_app_1": {ID: "portal_app_1", Auth: &Auth{APIKeys: []string{"shared_key"}}},
+				"portal_app_2": {ID: "portal_app_2", Auth: &Auth{APIKeys: []string{"shared_key"}}},
+				"portal_app_3": {ID: "portal_app_3", Auth: &Auth{APIKeys: []string{"shared_key"}}},
+				"portal_app_4": {ID: "portal_app_4", Auth: &Auth{APIKeys: []string{"unique_key"}}},
+			},
+			expectedIndex:         map[apiKeyHash]PortalAppID{hashAPIKey("unique_key"): "portal_app_4"},
+			expectedCollidingApps: []PortalAppID{"portal_app_1", "portal_app_2", "portal_app_3"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			index, collidingPortalAppIDs := buildAPIKeyIndex(test.portalApps)
+			c.Equal(test.expectedIndex, index)
+			c.ElementsMatch(test.expectedCollidingApps, collidingPortalAppIDs)
+		})
+	}
+}
+
+func Test_GetPortalAppIDByAPIKey(t *testing.T) {
+	collidingPortalApps := getTestPortalApps()
+	collidingPortalApps["portal_app_shared_key"] = &PortalApp{
+		ID:        "portal_app_shared_key",
+		AccountID: "account_1",
+		Auth:      &Auth{APIKeys: []string{"api_key_1"}},
+	}
+
+	tests := []struct {
+		name                string
+		portalApps          map[PortalAppID]*PortalApp
+		apiKeyIndexEnabled  bool
+		apiKey              string
+		expectedPortalAppID PortalAppID
+		expectedFound       bool
+	}{
+		{
+			name:                "should return portal app ID for an indexed API key",
+			portalApps:          getTestPortalApps(),
+			apiKeyIndexEnabled:  true,
+			apiKey:              "api_key_1",
+			expectedPortalAppID: "portal_app_1_static_key",
+			expectedFound:       true,
+		},
+		{
+			name:               "should return false for an unknown API key",
+			portalApps:         getTestPortalApps(),
+			apiKeyIndexEnabled: true,
+			apiKey:             "unknown_api_key",
+		},
+		{
+			name:               "should return false for an empty API key",
+			portalApps:         getTestPortalApps(),
+			apiKeyIndexEnabled: true,
+			apiKey:             "",
+		},
+		{
+			name:               "should return false for an API key shared by multiple portal apps",
+			portalApps:         collidingPortalApps,
+			apiKeyIndexEnabled: true,
+			apiKey:             "api_key_1",
+		},
+		{
+			name:               "should return false if the API key index is disabled",
+			portalApps:         getTestPortalApps(),
+			apiKeyIndexEnabled: false,
+			apiKey:             "api_key_1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDS := NewMockDataSource(ctrl)
+			mockDS.EXPECT().GetPortalApps().Return(test.portalApps, nil).Times(1)
+
+			store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour, WithAPIKeyIndex(test.apiKeyIndexEnabled))
+			c.NoError(err)
+
+			portalAppID, found := store.GetPortalAppIDByAPIKey(test.apiKey)
+			c.Equal(test.expectedFound, found)
+			c.Equal(test.expectedPortalAppID, portalAppID)
+		})
+	}
+}
+
+func Test_GetPortalAppIDByAPIKey_Refresh(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDS := NewMockDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour, WithAPIKeyIndex(true))
+	c.NoError(err)
+
+	mockDS.EXPECT().GetPortalApps().Return(getUpdatedTestPortalApps(), nil).Times(1)
+	c.NoError(store.refreshStore())
+
+	// The index is rebuilt on refresh, so rotated API keys no longer resolve
+	_, found := store.GetPortalAppIDByAPIKey("api_key_1")
+	c.False(found)
+
+	portalAppID, found := store.GetPortalAppIDByAPIKey("updated_api_key_1")
+	c.True(found)
+	c.Equal(PortalAppID("portal_app_1_static_key"), portalAppID)
+}
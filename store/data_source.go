@@ -5,7 +5,9 @@ package store
 
 // DataSource defines the interface for a data source that provides portal apps.
 //
-// Satisfied by grove.GrovePostgresDriver
+// Satisfied by:
+//   - grove.GrovePostgresDriver
+//   - directory.DirectoryDriver
 type DataSource interface {
 	// FetchInitialData loads the initial set of portal apps.
 	GetPortalApps() (map[PortalAppID]*PortalApp, error)
@@ -13,3 +15,15 @@ type DataSource interface {
 	// Close closes the data source and cleans up any resources.
 	Close()
 }
+
+// AuthSource is optionally implemented by a DataSource that can fetch the
+// authorization settings of a single portal app on demand.
+// Required for lazy auth loading (see WithLazyAuth).
+//
+// Satisfied by:
+//   - grove.GrovePostgresDriver
+type AuthSource interface {
+	// GetAuth fetches the authorization settings of a single portal app.
+	//   - Returns a nil Auth if the portal app does not require authorization or does not exist.
+	GetAuth(portalAppID PortalAppID) (*Auth, error)
+}
@@ -65,3 +65,42 @@ func (mr *MockDataSourceMockRecorder) GetPortalApps() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPortalApps", reflect.TypeOf((*MockDataSource)(nil).GetPortalApps))
 }
+
+// MockAuthSource is a mock of AuthSource interface.
+type MockAuthSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthSourceMockRecorder
+	isgomock struct{}
+}
+
+// MockAuthSourceMockRecorder is the mock recorder for MockAuthSource.
+type MockAuthSourceMockRecorder struct {
+	mock *MockAuthSource
+}
+
+// NewMockAuthSource creates a new mock instance.
+func NewMockAuthSource(ctrl *gomock.Controller) *MockAuthSource {
+	mock := &MockAuthSource{ctrl: ctrl}
+	mock.recorder = &MockAuthSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthSource) EXPECT() *MockAuthSourceMockRecorder {
+	return m.recorder
+}
+
+// GetAuth mocks base method.
+func (m *MockAuthSource) GetAuth(portalAppID PortalAppID) (*Auth, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAuth", portalAppID)
+	ret0, _ := ret[0].(*Auth)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuth indicates an expected call of GetAuth.
+func (mr *MockAuthSourceMockRecorder) GetAuth(portalAppID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuth", reflect.TypeOf((*MockAuthSource)(nil).GetAuth), portalAppID)
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+// errLazyAuthUnsupported is returned when lazy auth is enabled for a data source that does not implement AuthSource.
+var errLazyAuthUnsupported = errors.New("lazy auth requires a data source implementing store.AuthSource")
+
+// lazyAuthCache caches the authorization settings fetched on demand from an AuthSource.
+//   - A nil Auth is cached, so portal apps without authorization are only fetched once per load
+//   - Fetch errors are not cached, so the next request retries the fetch
+//   - On every portal app store load, cached settings are evicted if the portal app's AuthFingerprint changed (or is empty),
+//     so changes are picked up at the refresh interval, as in eager mode
+type lazyAuthCache struct {
+	authSource AuthSource
+
+	auths   map[PortalAppID]lazyAuthEntry
+	authsMu sync.RWMutex
+
+	// generation is incremented on every eviction, so a fetch started before a load is not cached after it
+	generation uint64
+}
+
+// lazyAuthEntry is a portal app's cached authorization settings.
+type lazyAuthEntry struct {
+	auth *Auth
+
+	// authFingerprint: the portal app's AuthFingerprint when its authorization settings were fetched
+	authFingerprint string
+}
+
+func newLazyAuthCache(authSource AuthSource) *lazyAuthCache {
+	return &lazyAuthCache{
+		authSource: authSource,
+		auths:      make(map[PortalAppID]lazyAuthEntry),
+	}
+}
+
+// get returns the portal app's cached authorization settings, fetching them from the auth source on a miss.
+func (l *lazyAuthCache) get(portalApp *PortalApp) (*Auth, error) {
+	l.authsMu.RLock()
+	entry, ok := l.auths[portalApp.ID]
+	generation := l.generation
+	l.authsMu.RUnlock()
+	if ok {
+		return entry.auth, nil
+	}
+
+	auth, err := l.authSource.GetAuth(portalApp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	l.authsMu.Lock()
+	if l.generation == generation {
+		l.auths[portalApp.ID] = lazyAuthEntry{auth: auth, authFingerprint: portalApp.AuthFingerprint}
+	}
+	l.authsMu.Unlock()
+
+	return auth, nil
+}
+
+// evictChanged removes the cached authorization settings of portal apps that were removed,
+// or whose AuthFingerprint changed or is unknown, in the newly loaded portal apps.
+//   - Only cached portal apps are compared, so the cost scales with the cache rather than the store.
+func (l *lazyAuthCache) evictChanged(portalApps map[PortalAppID]*PortalApp) {
+	l.authsMu.Lock()
+	defer l.authsMu.Unlock()
+
+	for portalAppID, entry := range l.auths {
+		portalApp, ok := portalApps[portalAppID]
+		if !ok || entry.authFingerprint == "" || entry.authFingerprint != portalApp.AuthFingerprint {
+			delete(l.auths, portalAppID)
+		}
+	}
+	l.generation++
+}
+
+// getPortalAppWithLazyAuth returns a copy of the portal app with its authorization settings fetched on demand.
+//   - Returns false if the fetch fails, so the request is rejected rather than served without authorization.
+func (c *portalAppStore) getPortalAppWithLazyAuth(portalApp *PortalApp) (*PortalApp, bool) {
+	auth, err := c.lazyAuth.get(portalApp)
+	if err != nil {
+		c.logger.Error().
+			Err(err).
+			Str("portal_app_id", string(portalApp.ID)).
+			Msg("Failed to fetch portal app auth from data source: rejecting request")
+		return nil, false
+	}
+
+	lazyPortalApp := *portalApp
+	lazyPortalApp.Auth = auth
+	return &lazyPortalApp, true
+}
+
+// dropLazyAuth drops the authorization settings of the loaded portal apps, if lazy auth is enabled,
+// so the store does not hold their API keys; they are fetched on demand instead.
+func (c *portalAppStore) dropLazyAuth(portalApps map[PortalAppID]*PortalApp) {
+	if c.lazyAuth == nil {
+		return
+	}
+	for _, portalApp := range portalApps {
+		portalApp.Auth = nil
+	}
+}
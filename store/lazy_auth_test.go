@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
+	"github.com/stretchr/testify/require"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// lazyAuthDataSource is a mock data source that also implements AuthSource.
+type lazyAuthDataSource struct {
+	*MockDataSource
+	*MockAuthSource
+}
+
+func newLazyAuthDataSource(ctrl *gomock.Controller) (*lazyAuthDataSource, *MockDataSource, *MockAuthSource) {
+	mockDS := NewMockDataSource(ctrl)
+	mockAuthSource := NewMockAuthSource(ctrl)
+	return &lazyAuthDataSource{MockDataSource: mockDS, MockAuthSource: mockAuthSource}, mockDS, mockAuthSource
+}
+
+func Test_LazyAuth_GetPortalApp(t *testing.T) {
+	tests := []struct {
+		name                   string
+		portalAppID            PortalAppID
+		fetchedAuth            *Auth
+		fetchErr               error
+		expectFetch            bool
+		expectedAuth           *Auth
+		expectedPortalAppFound bool
+	}{
+		{
+			name:                   "should fetch the auth of a portal app requiring auth",
+			portalAppID:            "portal_app_1_static_key",
+			fetchedAuth:            &Auth{APIKeys: []string{"api_key_1"}},
+			expectFetch:            true,
+			expectedAuth:           &Auth{APIKeys: []string{"api_key_1"}},
+			expectedPortalAppFound: true,
+		},
+		{
+			name:                   "should return no auth for a portal app not requiring auth",
+			portalAppID:            "portal_app_2_no_auth",
+			expectFetch:            true,
+			expectedPortalAppFound: true,
+		},
+		{
+			name:                   "should not fetch auth for a portal app not in the store",
+			portalAppID:            "portal_app_3_static_key",
+			expectedPortalAppFound: false,
+		},
+		{
+			name:                   "should return not found if the auth fetch fails",
+			portalAppID:            "portal_app_1_static_key",
+			fetchErr:               errors.New("connection refused"),
+			expectFetch:            true,
+			expectedPortalAppFound: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			dataSource, mockDS, mockAuthSource := newLazyAuthDataSource(ctrl)
+			mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+			if test.expectFetch {
+				mockAuthSource.EXPECT().GetAuth(test.portalAppID).Return(test.fetchedAuth, test.fetchErr).Times(1)
+			}
+
+			store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), dataSource, 1*time.Hour, WithLazyAuth(true))
+			c.NoError(err)
+
+			portalApp, found := store.GetPortalApp(test.portalAppID)
+			c.Equal(test.expectedPortalAppFound, found)
+			if !test.expectedPortalAppFound {
+				c.Nil(portalApp)
+				return
+			}
+			c.Equal(test.portalAppID, portalApp.ID)
+			c.Equal(test.expectedAuth, portalApp.Auth)
+		})
+	}
+}
+
+func Test_LazyAuth_DropsLoadedAuth(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dataSource, mockDS, _ := newLazyAuthDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), dataSource, 1*time.Hour, WithLazyAuth(true))
+	c.NoError(err)
+
+	// The store does not hold the API keys loaded from the data source
+	for _, portalApp := range store.portalApps {
+		c.Nil(portalApp.Auth)
+	}
+}
+
+func Test_LazyAuth_Caching(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dataSource, mockDS, mockAuthSource := newLazyAuthDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), dataSource, 1*time.Hour, WithLazyAuth(true))
+	c.NoError(err)
+
+	// A failed fetch is not cached, so the next request retries it
+	mockAuthSource.EXPECT().GetAuth(PortalAppID("portal_app_1_static_key")).Return(nil, errors.New("connection refused")).Times(1)
+	_, found := store.GetPortalApp("portal_app_1_static_key")
+	c.False(found)
+
+	// Fetched auth, including no auth, is cached until the next load
+	mockAuthSource.EXPECT().GetAuth(PortalAppID("portal_app_1_static_key")).Return(&Auth{APIKeys: []string{"api_key_1"}}, nil).Times(1)
+	mockAuthSource.EXPECT().GetAuth(PortalAppID("portal_app_2_no_auth")).Return(nil, nil).Times(1)
+	for range 3 {
+		portalApp, found := store.GetPortalApp("portal_app_1_static_key")
+		c.True(found)
+		c.Equal("api_key_1", portalApp.Auth.APIKey())
+
+		portalApp, found = store.GetPortalApp("portal_app_2_no_auth")
+		c.True(found)
+		c.Nil(portalApp.Auth)
+	}
+
+	// Portal apps with no auth fingerprint are evicted on every refresh, so rotated API keys are fetched again
+	mockDS.EXPECT().GetPortalApps().Return(getUpdatedTestPortalApps(), nil).Times(1)
+	c.NoError(store.refreshStore())
+
+	mockAuthSource.EXPECT().GetAuth(PortalAppID("portal_app_1_static_key")).Return(&Auth{APIKeys: []string{"updated_api_key_1"}}, nil).Times(1)
+	for range 2 {
+		portalApp, found := store.GetPortalApp("portal_app_1_static_key")
+		c.True(found)
+		c.Equal("updated_api_key_1", portalApp.Auth.APIKey())
+	}
+}
+
+func Test_LazyAuth_EvictsChangedAuth(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	withFingerprints := func(portalApps map[PortalAppID]*PortalApp, fingerprints map[PortalAppID]string) map[PortalAppID]*PortalApp {
+		for portalAppID, fingerprint := range fingerprints {
+			portalApps[portalAppID].AuthFingerprint = fingerprint
+		}
+		return portalApps
+	}
+
+	dataSource, mockDS, mockAuthSource := newLazyAuthDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(withFingerprints(getTestPortalApps(), map[PortalAppID]string{
+		"portal_app_1_static_key": "fingerprint_1",
+		"portal_app_2_no_auth":    "none",
+	}), nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), dataSource, 1*time.Hour, WithLazyAuth(true))
+	c.NoError(err)
+
+	mockAuthSource.EXPECT().GetAuth(PortalAppID("portal_app_1_static_key")).Return(&Auth{APIKeys: []string{"api_key_1"}}, nil).Times(1)
+	mockAuthSource.EXPECT().GetAuth(PortalAppID("portal_app_2_no_auth")).Return(nil, nil).Times(1)
+	_, found := store.GetPortalApp("portal_app_1_static_key")
+	c.True(found)
+	_, found = store.GetPortalApp("portal_app_2_no_auth")
+	c.True(found)
+
+	// Only the portal app whose fingerprint changed is fetched again after a refresh
+	mockDS.EXPECT().GetPortalApps().Return(withFingerprints(getUpdatedTestPortalApps(), map[PortalAppID]string{
+		"portal_app_1_static_key": "fingerprint_1_rotated",
+		"portal_app_2_no_auth":    "none",
+	}), nil).Times(1)
+	c.NoError(store.refreshStore())
+
+	mockAuthSource.EXPECT().GetAuth(PortalAppID("portal_app_1_static_key")).Return(&Auth{APIKeys: []string{"updated_api_key_1"}}, nil).Times(1)
+	for range 2 {
+		portalApp, found := store.GetPortalApp("portal_app_1_static_key")
+		c.True(found)
+		c.Equal("updated_api_key_1", portalApp.Auth.APIKey())
+
+		portalApp, found = store.GetPortalApp("portal_app_2_no_auth")
+		c.True(found)
+		c.Nil(portalApp.Auth)
+	}
+
+	// A removed portal app is evicted from the cache
+	removedPortalApps := withFingerprints(getUpdatedTestPortalApps(), map[PortalAppID]string{
+		"portal_app_1_static_key": "fingerprint_1_rotated",
+	})
+	delete(removedPortalApps, "portal_app_2_no_auth")
+	mockDS.EXPECT().GetPortalApps().Return(removedPortalApps, nil).Times(1)
+	c.NoError(store.refreshStore())
+
+	store.lazyAuth.authsMu.RLock()
+	defer store.lazyAuth.authsMu.RUnlock()
+	c.Contains(store.lazyAuth.auths, PortalAppID("portal_app_1_static_key"))
+	c.NotContains(store.lazyAuth.auths, PortalAppID("portal_app_2_no_auth"))
+}
+
+func Test_LazyAuth_UnsupportedDataSource(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The data source is not called if it does not implement AuthSource
+	mockDS := NewMockDataSource(ctrl)
+
+	_, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour, WithLazyAuth(true))
+	c.ErrorIs(err, errLazyAuthUnsupported)
+}
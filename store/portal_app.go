@@ -1,11 +1,24 @@
 package store
 
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
 type (
 	PortalAppID string
 	AccountID   string
 	PlanType    string
+
+	// BillingStatus is the billing state of a PortalApp's account.
+	BillingStatus string
 )
 
+// BillingStatusDelinquent is the billing status of an account with an outstanding balance.
+// Requests for PortalApps of delinquent accounts are denied with a 402 Payment Required.
+const BillingStatusDelinquent BillingStatus = "delinquent"
+
 // PortalApp represents a single portal app for a user's account.
 type PortalApp struct {
 	// Unique identifier for the PortalApp.
@@ -17,26 +30,123 @@ type PortalApp struct {
 	// The plan type for the PortalApp's account.
 	PlanType PlanType
 
+	// The human-readable name of the PortalApp's plan (e.g. "Free").
+	// Empty if the data source has no name for the plan.
+	PlanName string
+
+	// The billing status of the PortalApp's account.
+	// Empty if the data source has no billing status, which is treated as current.
+	BillingStatus BillingStatus
+
 	// The authorization settings for the PortalApp.
 	// Auth can be one of:
 	//   - nil: The portal app does not require authorization
-	//   - APIKey: The portal app uses an API key for authorization
+	//   - APIKeys: The portal app uses one of its API keys for authorization
 	Auth *Auth
 
+	// A fingerprint of the PortalApp's authorization settings that changes whenever they do, without holding its API keys.
+	// With lazy auth, cached authorization settings are only evicted on load if the fingerprint changed.
+	// Empty if the data source has no fingerprint, in which case they are evicted on every load.
+	AuthFingerprint string
+
+	// The account-scoped authorization settings for the PortalApp.
+	// An account API key is valid for all of the account's PortalApps, and is only used if Auth is nil.
+	// AccountAuth can be one of:
+	//   - nil: The account has no account-scoped API key
+	//   - APIKeys: Requests for the PortalApp must provide one of the account's API keys
+	AccountAuth *Auth
+
+	// The CIDRs requests for the PortalApp must come from, in addition to any Auth (e.g. "203.0.113.0/24", "2001:db8::/32").
+	// A single IP (e.g. "203.0.113.7") allows only that IP. Empty if requests may come from any IP.
+	AllowedCIDRs []string
+
 	// Rate Limiting settings for the PortalApp.
 	// If the portal app is not rate limited, RateLimit will be nil.
 	RateLimit *RateLimit
+
+	// The TTL hint for caching the PortalApp's authorization decision in GUARD,
+	// overriding the default TTL for public or API key protected portal apps.
+	// Nil if the data source has no cache TTL for the PortalApp; a TTL of zero omits the hint.
+	AuthCacheTTL *time.Duration
 }
 
 // Auth represents the authorization settings for a PortalApp.
 // Only API key auth is supported by the Grove Portal.
 type Auth struct {
-	APIKey string
+	// APIKeys are the valid API keys; a request must provide any one of them.
+	// Multiple keys allow rotating API keys with both the old and new key active.
+	APIKeys []string
+
+	// HMACSecret is the shared secret requests must be signed with, if set.
+	// Takes precedence over APIKeys (see auth.AuthorizerHMAC).
+	HMACSecret string
+}
+
+// APIKey returns the primary (first) API key, or an empty string if there is none.
+// Kept for callers that only support a single API key.
+func (a *Auth) APIKey() string {
+	if a == nil || len(a.APIKeys) == 0 {
+		return ""
+	}
+	return a.APIKeys[0]
+}
+
+// HasAPIKey returns true if at least one non-empty API key is set.
+//   - Returns false for a nil Auth.
+func (a *Auth) HasAPIKey() bool {
+	if a == nil {
+		return false
+	}
+	for _, apiKey := range a.APIKeys {
+		if apiKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAllowedCIDR parses an entry of PortalApp.AllowedCIDRs.
+//   - A single IPv4 or IPv6 address is parsed as a prefix containing only that address
+//   - Example: "203.0.113.0/24", "2001:db8::/32", "203.0.113.7"
+func ParseAllowedCIDR(s string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid allowed CIDR %q: must be a CIDR or IP address", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
 }
 
 // RateLimit contains rate limiting settings for a PortalApp.
 type RateLimit struct {
 	MonthlyUserLimit int32
+
+	// DailyUserLimit is only enforced by the Envoy global rate limiter,
+	// using the "Rl-User-Daily-Limit-<n>" header: PEAS does not track daily usage.
+	DailyUserLimit int32
+
+	// FreeMonthlyRelayBonus is added to the global free monthly relay limit
+	// for PLAN_FREE accounts that have been granted bonus relays.
+	FreeMonthlyRelayBonus int32
+
+	// BurstAllowance is the number of relays GUARD's local rate limiter may allow in a short burst,
+	// using the "Rl-Burst-<n>" header: PEAS does not track bursts. Zero if the account has no burst allowance.
+	BurstAllowance int32
+
+	// PortalAppMonthlyLimit is the monthly relay limit of this portal app alone, enforced in addition to
+	// the account's limit, so a single portal app cannot consume the whole account budget.
+	// Zero if the portal app has no limit of its own.
+	//   - Only set by the directory data source: the Grove Portal database has no such column
+	//   - Does not make the portal app's account monthly rate limited
+	PortalAppMonthlyLimit int32
+}
+
+// HasPortalAppMonthlyLimit returns true if the portal app has a monthly relay limit of its own (see RateLimit.PortalAppMonthlyLimit).
+func (p *PortalApp) HasPortalAppMonthlyLimit() bool {
+	return p.RateLimit != nil && p.RateLimit.PortalAppMonthlyLimit > 0
 }
 
 // PortalAppUpdate represents an update to a portal app in the store
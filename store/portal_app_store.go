@@ -1,7 +1,11 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,27 +30,127 @@ type portalAppStore struct {
 	portalApps   map[PortalAppID]*PortalApp
 	portalAppsMu sync.RWMutex
 
+	// Optional index of API key hashes to portal app IDs, guarded by portalAppsMu; nil if disabled
+	apiKeyIndex        map[apiKeyHash]PortalAppID
+	apiKeyIndexEnabled bool
+
 	// In-memory map of account portal apps for rate limiting (accountID -> PortalApp)
 	accountPortalApps   map[AccountID]*PortalApp
 	accountPortalAppsMu sync.RWMutex
+
+	// Which portal app's plan is used for an account whose portal apps disagree on their plan
+	accountPlanResolution AccountPlanResolution
+
+	// Called with the IDs of accounts whose plan or rate limit changed during a refresh
+	accountPlanChangeHandler   func(accountIDs []AccountID)
+	accountPlanChangeHandlerMu sync.RWMutex
+
+	// Whether portal apps with an empty account ID are excluded from the store
+	excludeMissingAccountID bool
+
+	// Maximum number of portal apps accepted from the data source; 0 is unlimited
+	maxPortalApps int
+
+	// Whether a refresh returning no portal apps is rejected while portal apps are loaded
+	rejectEmptyRefresh bool
+
+	// Cache of authorization settings fetched on demand from the data source; nil if lazy auth is disabled
+	lazyAuth        *lazyAuthCache
+	lazyAuthEnabled bool
+}
+
+// errMaxPortalAppsExceeded is returned when the data source returns more portal apps than the configured maximum.
+var errMaxPortalAppsExceeded = errors.New("data source returned more portal apps than the configured maximum")
+
+// errEmptyRefresh is returned when the data source returns no portal apps while portal apps are loaded.
+var errEmptyRefresh = errors.New("data source returned no portal apps, but portal apps are loaded")
+
+// PortalAppStoreOption configures optional portalAppStore behavior.
+type PortalAppStoreOption func(*portalAppStore)
+
+// WithExcludeMissingAccountID excludes portal apps with an empty account ID from the store,
+// so they are rejected as not found rather than served with broken rate limit and account metadata.
+// Portal apps with an empty account ID are always logged and counted, regardless of this option.
+func WithExcludeMissingAccountID(exclude bool) PortalAppStoreOption {
+	return func(c *portalAppStore) {
+		c.excludeMissingAccountID = exclude
+	}
+}
+
+// WithAccountPlanResolution sets which portal app's plan and rate limit settings are used for an account
+// whose portal apps disagree on them. Defaults to AccountPlanResolutionMostPermissive.
+func WithAccountPlanResolution(resolution AccountPlanResolution) PortalAppStoreOption {
+	return func(c *portalAppStore) {
+		c.accountPlanResolution = resolution
+	}
+}
+
+// WithMaxPortalApps rejects any load from the data source returning more than maxPortalApps portal apps,
+// keeping the previously loaded portal apps. Guards against a runaway query exhausting memory.
+// Defaults to 0 (unlimited).
+func WithMaxPortalApps(maxPortalApps int) PortalAppStoreOption {
+	return func(c *portalAppStore) {
+		c.maxPortalApps = maxPortalApps
+	}
+}
+
+// WithRejectEmptyRefresh rejects any refresh from the data source returning no portal apps while portal apps are loaded,
+// keeping the previously loaded portal apps. Guards against a transient data source failure wiping the store.
+// The initial load is never rejected, so a data source with no portal apps can still be loaded.
+func WithRejectEmptyRefresh(reject bool) PortalAppStoreOption {
+	return func(c *portalAppStore) {
+		c.rejectEmptyRefresh = reject
+	}
+}
+
+// WithAPIKeyIndex builds an index of SHA-256 API key hashes to portal app IDs on every load,
+// so a portal app can be looked up by its API key alone. API keys shared by multiple portal apps are not indexed.
+func WithAPIKeyIndex(enabled bool) PortalAppStoreOption {
+	return func(c *portalAppStore) {
+		c.apiKeyIndexEnabled = enabled
+	}
+}
+
+// WithLazyAuth fetches each portal app's authorization settings from the data source on first use,
+// rather than holding the API keys of every portal app in memory. The data source must implement AuthSource.
+// Fetched settings are cached until the next load; account API keys (AccountAuth) are still loaded eagerly.
+func WithLazyAuth(enabled bool) PortalAppStoreOption {
+	return func(c *portalAppStore) {
+		c.lazyAuthEnabled = enabled
+	}
 }
 
 // NewPortalAppStore creates a new in-memory portal app store.
 //
 // Steps:
 // - Initializes the store with initial data from the data source
-// - Starts a goroutine to listen for live updates from the data source
+// - Starts a goroutine to listen for live updates from the data source, until the context is canceled
 // - Returns the initialized store or error if initialization fails
 func NewPortalAppStore(
+	ctx context.Context,
 	logger polylog.Logger,
 	dataSource DataSource,
 	refreshInterval time.Duration,
+	opts ...PortalAppStoreOption,
 ) (*portalAppStore, error) {
 	store := &portalAppStore{
 		logger:            logger.With("component", "portal_app_data_store"),
 		dataSource:        dataSource,
 		portalApps:        make(map[PortalAppID]*PortalApp),
 		accountPortalApps: make(map[AccountID]*PortalApp),
+
+		accountPlanResolution: defaultAccountPlanResolution,
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	if store.lazyAuthEnabled {
+		authSource, ok := dataSource.(AuthSource)
+		if !ok {
+			return nil, errLazyAuthUnsupported
+		}
+		store.lazyAuth = newLazyAuthCache(authSource)
 	}
 
 	// Fetch initial data from the data source and populate the store
@@ -56,7 +160,7 @@ func NewPortalAppStore(
 	}
 
 	// Start background refresh goroutine
-	go store.startBackgroundRefresh(refreshInterval)
+	go store.startBackgroundRefresh(ctx, refreshInterval)
 
 	return store, nil
 }
@@ -66,12 +170,31 @@ func NewPortalAppStore(
 // Returns:
 // - The PortalApp pointer if found
 // - A bool indicating if the PortalApp exists in the store
+//
+// If lazy auth is enabled, the PortalApp is a copy with its Auth fetched on demand,
+// and a failed fetch is reported as not found.
 func (c *portalAppStore) GetPortalApp(portalAppID PortalAppID) (*PortalApp, bool) {
+	c.portalAppsMu.RLock()
+	portalApp, ok := c.portalApps[portalAppID]
+	c.portalAppsMu.RUnlock()
+
+	if !ok || c.lazyAuth == nil {
+		return portalApp, ok
+	}
+	return c.getPortalAppWithLazyAuth(portalApp)
+}
+
+// GetPortalAppIDByAPIKey retrieves the ID of the portal app using the API key.
+//
+// Returns:
+// - The PortalAppID if the API key hash is indexed
+// - A bool indicating if the API key identifies a single portal app; always false if the index is disabled
+func (c *portalAppStore) GetPortalAppIDByAPIKey(apiKey string) (PortalAppID, bool) {
 	c.portalAppsMu.RLock()
 	defer c.portalAppsMu.RUnlock()
 
-	portalApp, ok := c.portalApps[portalAppID]
-	return portalApp, ok
+	portalAppID, ok := c.apiKeyIndex[hashAPIKey(apiKey)]
+	return portalAppID, ok
 }
 
 // GetAccountPortalApp retrieves a PortalApp from the store by its account ID.
@@ -87,13 +210,77 @@ func (c *portalAppStore) GetAccountPortalApp(accountID AccountID) (*PortalApp, b
 	return portalApp, ok
 }
 
+// GetRateLimitableAccountIDs returns the IDs of all accounts whose portal app is rate-limitable, as determined by isRateLimitable.
+//
+// Used to restrict data warehouse usage queries to accounts that can actually be rate limited.
+// The rate limit store decides which accounts are rate-limitable, as it depends on its plan limits.
+func (c *portalAppStore) GetRateLimitableAccountIDs(isRateLimitable func(*PortalApp) bool) []AccountID {
+	c.accountPortalAppsMu.RLock()
+	defer c.accountPortalAppsMu.RUnlock()
+
+	accountIDs := make([]AccountID, 0, len(c.accountPortalApps))
+	for accountID, portalApp := range c.accountPortalApps {
+		if accountID != "" && isRateLimitable(portalApp) {
+			accountIDs = append(accountIDs, accountID)
+		}
+	}
+	return accountIDs
+}
+
+// GetPortalAppsWithMonthlyLimit returns all PortalApps with a monthly relay limit of their own (see RateLimit.PortalAppMonthlyLimit).
+//
+// Used to restrict per-portal-app data warehouse usage queries to portal apps that can be rate limited on their own;
+// the returned PortalApps must not be modified.
+func (c *portalAppStore) GetPortalAppsWithMonthlyLimit() []*PortalApp {
+	c.portalAppsMu.RLock()
+	defer c.portalAppsMu.RUnlock()
+
+	var portalApps []*PortalApp
+	for _, portalApp := range c.portalApps {
+		if portalApp.HasPortalAppMonthlyLimit() {
+			portalApps = append(portalApps, portalApp)
+		}
+	}
+	return portalApps
+}
+
+// ListPortalApps returns all PortalApps in the store, sorted by ID.
+//
+// Used to dump the store contents for debugging; the returned PortalApps must not be modified.
+// If lazy auth is enabled, the PortalApps have no Auth, as it is only fetched on demand.
+func (c *portalAppStore) ListPortalApps() []*PortalApp {
+	c.portalAppsMu.RLock()
+	portalApps := make([]*PortalApp, 0, len(c.portalApps))
+	for _, portalApp := range c.portalApps {
+		portalApps = append(portalApps, portalApp)
+	}
+	c.portalAppsMu.RUnlock()
+
+	sort.Slice(portalApps, func(i, j int) bool {
+		return portalApps[i].ID < portalApps[j].ID
+	})
+	return portalApps
+}
+
+// SetAccountPlanChangeHandler registers a handler called after each refresh with the
+// IDs of accounts whose plan type or rate limit settings changed.
+//
+// Used to immediately re-evaluate rate limits for affected accounts (e.g. an account
+// upgrading from PLAN_FREE to PLAN_UNLIMITED mid-month), rather than waiting for the
+// next rate limit store refresh.
+func (c *portalAppStore) SetAccountPlanChangeHandler(handler func(accountIDs []AccountID)) {
+	c.accountPlanChangeHandlerMu.Lock()
+	defer c.accountPlanChangeHandlerMu.Unlock()
+	c.accountPlanChangeHandler = handler
+}
+
 // initializeStore fetches the initial set of PortalApps from the data source and populates the in-memory store.
 func (c *portalAppStore) initializeStore() error {
 	c.logger.Info().Msg("Fetching initial data from data source ...")
 
 	err := c.setStoreData()
 	if err != nil {
-		metrics.RecordDataSourceRefreshError(metrics.PortalAppStoreSourceType, metrics.PostgresErrorType)
+		metrics.RecordDataSourceRefreshError(metrics.PortalAppStoreSourceType, getRefreshErrorType(err))
 		return fmt.Errorf("failed to set initial store data: %w", err)
 	}
 
@@ -104,8 +291,8 @@ func (c *portalAppStore) initializeStore() error {
 	return nil
 }
 
-// startBackgroundRefresh starts a goroutine that periodically refreshes the portal apps from the data source.
-func (c *portalAppStore) startBackgroundRefresh(refreshInterval time.Duration) {
+// startBackgroundRefresh periodically refreshes the portal apps from the data source, until the context is canceled.
+func (c *portalAppStore) startBackgroundRefresh(ctx context.Context, refreshInterval time.Duration) {
 	c.logger.Info().
 		Dur("refresh_interval", refreshInterval).
 		Msg("🗄️ Starting background refresh for portal apps")
@@ -113,15 +300,28 @@ func (c *portalAppStore) startBackgroundRefresh(refreshInterval time.Duration) {
 	ticker := time.NewTicker(refreshInterval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		if err := c.refreshStore(); err != nil {
-			c.logger.Error().
-				Err(err).
-				Msg("Failed to refresh portal apps from data source")
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info().Msg("Stopping background refresh for portal apps")
+			return
+		case <-ticker.C:
+			if err := c.refreshStore(); err != nil {
+				c.logger.Error().
+					Err(err).
+					Msg("Failed to refresh portal apps from data source")
+			}
 		}
 	}
 }
 
+// Refresh immediately refreshes the portal apps from the data source, outside of the background refresh interval.
+//
+// Used to force a reload without restarting (e.g. on SIGHUP).
+func (c *portalAppStore) Refresh() error {
+	return c.refreshStore()
+}
+
 // refreshStore fetches the latest PortalApps from the data source and updates the in-memory store.
 func (c *portalAppStore) refreshStore() error {
 	startTime := time.Now()
@@ -129,7 +329,7 @@ func (c *portalAppStore) refreshStore() error {
 
 	err := c.setStoreData()
 	if err != nil {
-		metrics.RecordDataSourceRefreshError(metrics.PortalAppStoreSourceType, metrics.PostgresErrorType)
+		metrics.RecordDataSourceRefreshError(metrics.PortalAppStoreSourceType, getRefreshErrorType(err))
 		return fmt.Errorf("failed to refresh store data: %w", err)
 	}
 
@@ -153,15 +353,115 @@ func (c *portalAppStore) setStoreData() error {
 		return fmt.Errorf("failed to get portal apps from data source: %w", err)
 	}
 
+	// Reject the load before replacing the store data, so the previously loaded portal apps are kept
+	if c.maxPortalApps > 0 && len(portalApps) > c.maxPortalApps {
+		return fmt.Errorf("%w: got %d, max %d", errMaxPortalAppsExceeded, len(portalApps), c.maxPortalApps)
+	}
+	if c.rejectEmptyRefresh && len(portalApps) == 0 {
+		c.portalAppsMu.RLock()
+		loadedCount := len(c.portalApps)
+		c.portalAppsMu.RUnlock()
+		if loadedCount > 0 {
+			return fmt.Errorf("%w: %d portal apps loaded", errEmptyRefresh, loadedCount)
+		}
+	}
+
+	portalApps = c.validatePortalApps(portalApps)
+	apiKeyIndex := c.getAPIKeyIndex(portalApps)
+	c.dropLazyAuth(portalApps)
+
+	// Swap the portal apps and API key index together so lookups never see mismatched data
 	c.portalAppsMu.Lock()
 	c.portalApps = portalApps
+	c.apiKeyIndex = apiKeyIndex
+	if c.lazyAuth != nil {
+		c.lazyAuth.evictChanged(portalApps)
+	}
 	c.portalAppsMu.Unlock()
 
-	c.setPortalAppsByAccountID(portalApps)
+	changedAccountIDs := c.setPortalAppsByAccountID(portalApps)
+	if len(changedAccountIDs) > 0 {
+		c.logger.Info().
+			Int("changed_account_count", len(changedAccountIDs)).
+			Msg("🔄 Detected account plan changes")
+		c.notifyAccountPlanChange(changedAccountIDs)
+	}
 
 	return nil
 }
 
+// validatePortalApps flags portal apps with an empty account ID, for which rate limiting and account headers are meaningless.
+//   - Updates the count of portal apps missing an account ID metric.
+//   - Removes them from the portal apps map if excludeMissingAccountID is set.
+func (c *portalAppStore) validatePortalApps(portalApps map[PortalAppID]*PortalApp) map[PortalAppID]*PortalApp {
+	var missingAccountIDs []string
+	for portalAppID, portalApp := range portalApps {
+		if portalApp.AccountID != "" {
+			continue
+		}
+		missingAccountIDs = append(missingAccountIDs, string(portalAppID))
+		if c.excludeMissingAccountID {
+			delete(portalApps, portalAppID)
+		}
+	}
+
+	metrics.UpdateStoreSize(metrics.PortalAppsMissingAccountIDStoreType, float64(len(missingAccountIDs)))
+
+	if len(missingAccountIDs) > 0 {
+		c.logger.Warn().
+			Int("portal_app_count", len(missingAccountIDs)).
+			Str("portal_app_ids", strings.Join(missingAccountIDs, ",")).
+			Bool("excluded", c.excludeMissingAccountID).
+			Msg("⚠️ Found portal apps with no account ID")
+	}
+
+	return portalApps
+}
+
+// getAPIKeyIndex builds the API key index for the portal apps, if enabled.
+//   - Logs the portal apps excluded from the index because they share an API key with another portal app.
+func (c *portalAppStore) getAPIKeyIndex(portalApps map[PortalAppID]*PortalApp) map[apiKeyHash]PortalAppID {
+	if !c.apiKeyIndexEnabled {
+		return nil
+	}
+
+	index, collidingPortalAppIDs := buildAPIKeyIndex(portalApps)
+	if len(collidingPortalAppIDs) > 0 {
+		ids := make([]string, len(collidingPortalAppIDs))
+		for i, portalAppID := range collidingPortalAppIDs {
+			ids[i] = string(portalAppID)
+		}
+		c.logger.Warn().
+			Int("portal_app_count", len(ids)).
+			Str("portal_app_ids", strings.Join(ids, ",")).
+			Msg("⚠️ Found portal apps sharing an API key: excluding them from the API key index")
+	}
+
+	return index
+}
+
+// getRefreshErrorType returns the data source refresh error metric type for a setStoreData error.
+func getRefreshErrorType(err error) string {
+	if errors.Is(err, errMaxPortalAppsExceeded) {
+		return metrics.MaxPortalAppsExceededErrorType
+	}
+	if errors.Is(err, errEmptyRefresh) {
+		return metrics.EmptyRefreshErrorType
+	}
+	return metrics.PostgresErrorType
+}
+
+// notifyAccountPlanChange calls the registered account plan change handler, if any.
+func (c *portalAppStore) notifyAccountPlanChange(accountIDs []AccountID) {
+	c.accountPlanChangeHandlerMu.RLock()
+	handler := c.accountPlanChangeHandler
+	c.accountPlanChangeHandlerMu.RUnlock()
+
+	if handler != nil {
+		handler(accountIDs)
+	}
+}
+
 // updateStoreMetrics updates the Prometheus metrics for store sizes.
 func (c *portalAppStore) updateStoreMetrics() {
 	c.portalAppsMu.RLock()
@@ -180,15 +480,53 @@ func (c *portalAppStore) updateStoreMetrics() {
 // setPortalAppsByAccountID stores portal apps by account ID.
 // This i required because rate limits are applied at the account level, not the portal app level.
 //
-// Only sets account data if it's not already set for the same account ID to avoid unnecessary updates.
-func (c *portalAppStore) setPortalAppsByAccountID(portalApps map[PortalAppID]*PortalApp) {
+// The account map is rebuilt on every refresh so that plan and rate limit changes are picked up.
+// Returns the IDs of previously known accounts whose plan type or rate limit settings changed.
+//
+// All portal apps of an account should share the account's plan and rate limit, so one portal app is stored per account.
+// If they disagree, the account's portal app is chosen by the accountPlanResolution (see AccountPlanResolution.prefers).
+func (c *portalAppStore) setPortalAppsByAccountID(portalApps map[PortalAppID]*PortalApp) []AccountID {
+	newAccountPortalApps := make(map[AccountID]*PortalApp, len(portalApps))
+	conflictingAccountIDs := make(map[AccountID]struct{})
+	for _, portalApp := range portalApps {
+		current, exists := newAccountPortalApps[portalApp.AccountID]
+		if !exists {
+			newAccountPortalApps[portalApp.AccountID] = portalApp
+			continue
+		}
+		// Portal apps with no account ID do not share an account, so they cannot conflict
+		if portalApp.AccountID != "" && accountPlansConflict(current, portalApp) {
+			conflictingAccountIDs[portalApp.AccountID] = struct{}{}
+		}
+		if c.accountPlanResolution.prefers(portalApp, current) {
+			newAccountPortalApps[portalApp.AccountID] = portalApp
+		}
+	}
+	c.reportConflictingAccountPlans(conflictingAccountIDs)
+
 	c.accountPortalAppsMu.Lock()
 	defer c.accountPortalAppsMu.Unlock()
 
-	for _, portalApp := range portalApps {
-		// Only set if not already present for this account ID
-		if _, exists := c.accountPortalApps[portalApp.AccountID]; !exists {
-			c.accountPortalApps[portalApp.AccountID] = portalApp
+	var changedAccountIDs []AccountID
+	for accountID, portalApp := range newAccountPortalApps {
+		previous, exists := c.accountPortalApps[accountID]
+		if exists && accountPlanChanged(previous, portalApp) {
+			changedAccountIDs = append(changedAccountIDs, accountID)
 		}
 	}
+
+	c.accountPortalApps = newAccountPortalApps
+
+	return changedAccountIDs
+}
+
+// accountPlanChanged returns true if the plan type or rate limit settings differ between two portal apps.
+func accountPlanChanged(previous, current *PortalApp) bool {
+	if previous.PlanType != current.PlanType {
+		return true
+	}
+	if (previous.RateLimit == nil) != (current.RateLimit == nil) {
+		return true
+	}
+	return previous.RateLimit != nil && *previous.RateLimit != *current.RateLimit
 }
@@ -1,12 +1,16 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/pokt-network/poktroll/pkg/polylog/polyzero"
 	"github.com/stretchr/testify/require"
 	gomock "go.uber.org/mock/gomock"
+
+	"github.com/buildwithgrove/path-external-auth-server/metrics"
 )
 
 func Test_GetPortalApp(t *testing.T) {
@@ -50,7 +54,7 @@ func Test_GetPortalApp(t *testing.T) {
 			mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
 
 			// Create store with a long refresh interval to avoid interference during test
-			store, err := NewPortalAppStore(polyzero.NewLogger(), mockDS, 1*time.Hour)
+			store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour)
 			c.NoError(err)
 
 			portalApp, found := store.GetPortalApp(test.portalAppID)
@@ -61,7 +65,7 @@ func Test_GetPortalApp(t *testing.T) {
 
 				// Compare Auth details if present
 				if test.expectedPortalApp.Auth != nil {
-					c.Equal(test.expectedPortalApp.Auth.APIKey, portalApp.Auth.APIKey)
+					c.Equal(test.expectedPortalApp.Auth.APIKeys, portalApp.Auth.APIKeys)
 				} else {
 					c.Nil(portalApp.Auth)
 				}
@@ -99,13 +103,13 @@ func Test_BackgroundRefresh(t *testing.T) {
 
 	// Create store with short refresh interval for testing
 	refreshInterval := 100 * time.Millisecond
-	store, err := NewPortalAppStore(polyzero.NewLogger(), mockDS, refreshInterval)
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, refreshInterval)
 	c.NoError(err)
 
 	// Verify initial state
 	portalApp, found := store.GetPortalApp("portal_app_1_static_key")
 	c.True(found)
-	c.Equal("api_key_1", portalApp.Auth.APIKey)
+	c.Equal("api_key_1", portalApp.Auth.APIKey())
 
 	// Wait for at least one refresh cycle
 	time.Sleep(refreshInterval + 50*time.Millisecond)
@@ -113,12 +117,148 @@ func Test_BackgroundRefresh(t *testing.T) {
 	// Verify the store was updated with new data
 	portalApp, found = store.GetPortalApp("portal_app_1_static_key")
 	c.True(found)
-	c.Equal("updated_api_key_1", portalApp.Auth.APIKey)
+	c.Equal("updated_api_key_1", portalApp.Auth.APIKey())
 
 	// Verify new app was added
 	newApp, found := store.GetPortalApp("portal_app_3_static_key")
 	c.True(found)
-	c.Equal("new_api_key", newApp.Auth.APIKey)
+	c.Equal("new_api_key", newApp.Auth.APIKey())
+}
+
+func Test_BackgroundRefresh_ContextCanceled(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDS := NewMockDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour)
+	c.NoError(err)
+
+	// Signal every refresh of the background refresh started below
+	refreshedCh := make(chan struct{}, 1)
+	mockDS.EXPECT().GetPortalApps().DoAndReturn(func() (map[PortalAppID]*PortalApp, error) {
+		select {
+		case refreshedCh <- struct{}{}:
+		default:
+		}
+		return getTestPortalApps(), nil
+	}).MinTimes(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	refreshInterval := 10 * time.Millisecond
+	doneCh := make(chan struct{})
+	go func() {
+		store.startBackgroundRefresh(ctx, refreshInterval)
+		close(doneCh)
+	}()
+
+	select {
+	case <-refreshedCh:
+	case <-time.After(50 * refreshInterval):
+		c.Fail("background refresh did not refresh the store")
+	}
+
+	cancel()
+
+	select {
+	case <-doneCh:
+	case <-time.After(50 * refreshInterval):
+		c.Fail("background refresh did not stop after the context was canceled")
+	}
+}
+
+func Test_AccountPlanChangeHandler(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock data source
+	mockDS := NewMockDataSource(ctrl)
+
+	// Initial data load
+	mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+	// Refreshed data where account_2 changes plan and account_1 is unchanged
+	mockDS.EXPECT().GetPortalApps().Return(getPlanChangedTestPortalApps(), nil).MinTimes(1)
+
+	// Create store with short refresh interval for testing
+	refreshInterval := 100 * time.Millisecond
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, refreshInterval)
+	c.NoError(err)
+
+	changedAccountIDsCh := make(chan []AccountID, 1)
+	store.SetAccountPlanChangeHandler(func(accountIDs []AccountID) {
+		select {
+		case changedAccountIDsCh <- accountIDs:
+		default:
+		}
+	})
+
+	select {
+	case changedAccountIDs := <-changedAccountIDsCh:
+		c.Equal([]AccountID{"account_2"}, changedAccountIDs)
+	case <-time.After(5 * refreshInterval):
+		c.Fail("account plan change handler was not called")
+	}
+
+	// Verify the account portal app reflects the new plan
+	portalApp, found := store.GetAccountPortalApp("account_2")
+	c.True(found)
+	c.Equal(PlanType("PLAN_FREE"), portalApp.PlanType)
+	c.Equal(int32(500_000), portalApp.RateLimit.FreeMonthlyRelayBonus)
+}
+
+func Test_accountPlanChanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous *PortalApp
+		current  *PortalApp
+		expected bool
+	}{
+		{
+			name:     "should not detect change if plan and rate limit are equal",
+			previous: &PortalApp{PlanType: "PLAN_FREE", RateLimit: &RateLimit{}},
+			current:  &PortalApp{PlanType: "PLAN_FREE", RateLimit: &RateLimit{}},
+			expected: false,
+		},
+		{
+			name:     "should detect plan type change",
+			previous: &PortalApp{PlanType: "PLAN_FREE", RateLimit: &RateLimit{}},
+			current:  &PortalApp{PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{}},
+			expected: true,
+		},
+		{
+			name:     "should detect monthly user limit change",
+			previous: &PortalApp{PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 1000}},
+			current:  &PortalApp{PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 2000}},
+			expected: true,
+		},
+		{
+			name:     "should detect rate limit removal",
+			previous: &PortalApp{PlanType: "PLAN_UNLIMITED", RateLimit: &RateLimit{MonthlyUserLimit: 1000}},
+			current:  &PortalApp{PlanType: "PLAN_UNLIMITED", RateLimit: nil},
+			expected: true,
+		},
+		{
+			name:     "should not detect change if both rate limits are nil",
+			previous: &PortalApp{PlanType: "PLAN_UNLIMITED"},
+			current:  &PortalApp{PlanType: "PLAN_UNLIMITED"},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+			c.Equal(test.expected, accountPlanChanged(test.previous, test.current))
+		})
+	}
 }
 
 // getTestPortalApps returns a mock response for the initial portal app store data,
@@ -130,7 +270,7 @@ func getTestPortalApps() map[PortalAppID]*PortalApp {
 			AccountID: "account_1",
 			PlanType:  "PLAN_UNLIMITED",
 			Auth: &Auth{
-				APIKey: "api_key_1",
+				APIKeys: []string{"api_key_1"},
 			},
 			RateLimit: &RateLimit{
 				MonthlyUserLimit: 0,
@@ -156,7 +296,7 @@ func getUpdatedTestPortalApps() map[PortalAppID]*PortalApp {
 			AccountID: "account_1",
 			PlanType:  "PLAN_UNLIMITED",
 			Auth: &Auth{
-				APIKey: "updated_api_key_1",
+				APIKeys: []string{"updated_api_key_1"},
 			},
 			RateLimit: &RateLimit{
 				MonthlyUserLimit: 0,
@@ -176,7 +316,7 @@ func getUpdatedTestPortalApps() map[PortalAppID]*PortalApp {
 			AccountID: "account_3",
 			PlanType:  "PLAN_UNLIMITED",
 			Auth: &Auth{
-				APIKey: "new_api_key",
+				APIKeys: []string{"new_api_key"},
 			},
 			RateLimit: &RateLimit{
 				MonthlyUserLimit: 1000,
@@ -184,3 +324,313 @@ func getUpdatedTestPortalApps() map[PortalAppID]*PortalApp {
 		},
 	}
 }
+
+// getPlanChangedTestPortalApps returns refreshed portal app data where account_2 changed plans
+func getPlanChangedTestPortalApps() map[PortalAppID]*PortalApp {
+	portalApps := getTestPortalApps()
+	portalApps["portal_app_2_no_auth"].PlanType = "PLAN_FREE"
+	portalApps["portal_app_2_no_auth"].RateLimit = &RateLimit{
+		FreeMonthlyRelayBonus: 500_000,
+	}
+	return portalApps
+}
+
+func Test_validatePortalApps(t *testing.T) {
+	tests := []struct {
+		name                    string
+		excludeMissingAccountID bool
+		expectedPortalAppIDs    []PortalAppID
+	}{
+		{
+			name:                    "should keep portal apps with no account ID if exclusion is disabled",
+			excludeMissingAccountID: false,
+			expectedPortalAppIDs:    []PortalAppID{"portal_app_1_static_key", "portal_app_2_no_auth", "portal_app_no_account"},
+		},
+		{
+			name:                    "should exclude portal apps with no account ID if exclusion is enabled",
+			excludeMissingAccountID: true,
+			expectedPortalAppIDs:    []PortalAppID{"portal_app_1_static_key", "portal_app_2_no_auth"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			store := &portalAppStore{
+				logger:                  polyzero.NewLogger(),
+				excludeMissingAccountID: test.excludeMissingAccountID,
+			}
+
+			portalApps := store.validatePortalApps(getMissingAccountIDTestPortalApps())
+
+			var portalAppIDs []PortalAppID
+			for portalAppID := range portalApps {
+				portalAppIDs = append(portalAppIDs, portalAppID)
+			}
+			c.ElementsMatch(test.expectedPortalAppIDs, portalAppIDs)
+		})
+	}
+}
+
+func Test_ExcludeMissingAccountID(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDS := NewMockDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(getMissingAccountIDTestPortalApps(), nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour, WithExcludeMissingAccountID(true))
+	c.NoError(err)
+
+	// Portal apps with no account ID are not served
+	_, found := store.GetPortalApp("portal_app_no_account")
+	c.False(found)
+	_, found = store.GetAccountPortalApp("")
+	c.False(found)
+
+	// Portal apps with an account ID are unaffected
+	portalApp, found := store.GetPortalApp("portal_app_1_static_key")
+	c.True(found)
+	c.Equal(AccountID("account_1"), portalApp.AccountID)
+}
+
+func Test_MaxPortalApps(t *testing.T) {
+	tests := []struct {
+		name                   string
+		maxPortalApps          int
+		expectInitialLoadError bool
+		expectRefreshError     bool
+	}{
+		{
+			name:          "should load and refresh portal apps if unlimited",
+			maxPortalApps: 0,
+		},
+		{
+			name:          "should refresh portal apps at exactly the maximum",
+			maxPortalApps: 3,
+		},
+		{
+			name:               "should reject refresh one over the maximum and keep prior data",
+			maxPortalApps:      2,
+			expectRefreshError: true,
+		},
+		{
+			name:                   "should reject initial load one over the maximum",
+			maxPortalApps:          1,
+			expectInitialLoadError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Initial load returns 2 portal apps, refresh returns 3 portal apps
+			mockDS := NewMockDataSource(ctrl)
+			mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+			// Create store with a long refresh interval; the refresh is triggered manually
+			store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour, WithMaxPortalApps(test.maxPortalApps))
+			if test.expectInitialLoadError {
+				c.ErrorIs(err, errMaxPortalAppsExceeded)
+				return
+			}
+			c.NoError(err)
+
+			mockDS.EXPECT().GetPortalApps().Return(getUpdatedTestPortalApps(), nil).Times(1)
+			err = store.refreshStore()
+
+			portalApp, found := store.GetPortalApp("portal_app_1_static_key")
+			c.True(found)
+			_, newAppFound := store.GetPortalApp("portal_app_3_static_key")
+
+			if test.expectRefreshError {
+				c.ErrorIs(err, errMaxPortalAppsExceeded)
+				c.Equal("api_key_1", portalApp.Auth.APIKey())
+				c.False(newAppFound)
+				return
+			}
+			c.NoError(err)
+			c.Equal("updated_api_key_1", portalApp.Auth.APIKey())
+			c.True(newAppFound)
+		})
+	}
+}
+
+func Test_RejectEmptyRefresh(t *testing.T) {
+	tests := []struct {
+		name                string
+		rejectEmptyRefresh  bool
+		refreshPortalApps   map[PortalAppID]*PortalApp
+		refreshErr          error
+		expectedErrorType   string
+		expectPriorDataKept bool
+	}{
+		{
+			name:                "should keep prior data if the refresh fails",
+			rejectEmptyRefresh:  true,
+			refreshErr:          errors.New("connection reset"),
+			expectedErrorType:   metrics.PostgresErrorType,
+			expectPriorDataKept: true,
+		},
+		{
+			name:                "should keep prior data if the refresh fails, even if empty refreshes are accepted",
+			refreshErr:          errors.New("connection reset"),
+			expectedErrorType:   metrics.PostgresErrorType,
+			expectPriorDataKept: true,
+		},
+		{
+			name:                "should reject an empty refresh and keep prior data",
+			rejectEmptyRefresh:  true,
+			refreshPortalApps:   map[PortalAppID]*PortalApp{},
+			expectedErrorType:   metrics.EmptyRefreshErrorType,
+			expectPriorDataKept: true,
+		},
+		{
+			name:              "should clear the store on an empty refresh if empty refreshes are accepted",
+			refreshPortalApps: map[PortalAppID]*PortalApp{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := require.New(t)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockDS := NewMockDataSource(ctrl)
+			mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+			// Create store with a long refresh interval; the refresh is triggered manually
+			store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour, WithRejectEmptyRefresh(test.rejectEmptyRefresh))
+			c.NoError(err)
+
+			mockDS.EXPECT().GetPortalApps().Return(test.refreshPortalApps, test.refreshErr).Times(1)
+			err = store.refreshStore()
+
+			_, found := store.GetPortalApp("portal_app_1_static_key")
+			_, accountFound := store.GetAccountPortalApp("account_1")
+
+			if test.expectPriorDataKept {
+				c.Error(err)
+				c.Equal(test.expectedErrorType, getRefreshErrorType(err))
+				c.True(found)
+				c.True(accountFound)
+				c.Len(store.ListPortalApps(), len(getTestPortalApps()))
+				return
+			}
+			c.NoError(err)
+			c.False(found)
+			c.False(accountFound)
+			c.Empty(store.ListPortalApps())
+		})
+	}
+}
+
+func Test_RejectEmptyRefresh_InitialLoad(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// An empty initial load is accepted, as there is no prior data to keep
+	mockDS := NewMockDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(map[PortalAppID]*PortalApp{}, nil).Times(2)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour, WithRejectEmptyRefresh(true))
+	c.NoError(err)
+	c.Empty(store.ListPortalApps())
+
+	// An empty refresh of an empty store is not rejected either
+	c.NoError(store.refreshStore())
+}
+
+func Test_GetRateLimitableAccountIDs(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	portalApps := getTestPortalApps()
+	portalApps["portal_app_unlimited_no_limit"] = &PortalApp{
+		ID:        "portal_app_unlimited_no_limit",
+		AccountID: "account_unlimited_no_limit",
+		PlanType:  "PLAN_UNLIMITED",
+	}
+
+	mockDS := NewMockDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(portalApps, nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour)
+	c.NoError(err)
+
+	// Accounts are rate-limitable as determined by the given function
+	hasRateLimit := func(portalApp *PortalApp) bool { return portalApp.RateLimit != nil }
+	c.ElementsMatch([]AccountID{"account_1", "account_2"}, store.GetRateLimitableAccountIDs(hasRateLimit))
+}
+
+func Test_GetPortalAppsWithMonthlyLimit(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	portalApps := getTestPortalApps()
+	portalApps["portal_app_limited"] = &PortalApp{
+		ID:        "portal_app_limited",
+		AccountID: "account_1",
+		PlanType:  "PLAN_UNLIMITED",
+		RateLimit: &RateLimit{PortalAppMonthlyLimit: 1_000},
+	}
+
+	mockDS := NewMockDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(portalApps, nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour)
+	c.NoError(err)
+
+	// Only portal apps with a monthly limit of their own are returned, not every rate-limitable portal app
+	limitedPortalApps := store.GetPortalAppsWithMonthlyLimit()
+	c.Len(limitedPortalApps, 1)
+	c.Equal(PortalAppID("portal_app_limited"), limitedPortalApps[0].ID)
+}
+
+func Test_ListPortalApps(t *testing.T) {
+	c := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDS := NewMockDataSource(ctrl)
+	mockDS.EXPECT().GetPortalApps().Return(getTestPortalApps(), nil).Times(1)
+
+	store, err := NewPortalAppStore(context.Background(), polyzero.NewLogger(), mockDS, 1*time.Hour)
+	c.NoError(err)
+
+	// Portal apps are listed sorted by ID
+	portalApps := store.ListPortalApps()
+	c.Len(portalApps, len(getTestPortalApps()))
+	for i := 1; i < len(portalApps); i++ {
+		c.Less(portalApps[i-1].ID, portalApps[i].ID)
+	}
+}
+
+// getMissingAccountIDTestPortalApps returns the test portal apps plus a portal app with no account ID
+func getMissingAccountIDTestPortalApps() map[PortalAppID]*PortalApp {
+	portalApps := getTestPortalApps()
+	portalApps["portal_app_no_account"] = &PortalApp{
+		ID:       "portal_app_no_account",
+		PlanType: "PLAN_FREE",
+		RateLimit: &RateLimit{
+			MonthlyUserLimit: 0,
+		},
+	}
+	return portalApps
+}